@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCryptoToolkit_EndToEnd(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	out := filepath.Join(t.TempDir(), "creds")
+	t.Setenv("CRYPTO_E2E_KEY", "signing-key")
+	// No secret store: generated values come from crypto/rand
+	t.Setenv("SIGIL_SECRETS_FILE", filepath.Join(t.TempDir(), "secrets.enc"))
+
+	// Each value reads variables resolved earlier in the same planning wave.
+	// The hash goes out base64-encoded so the shell leaves its $ signs alone.
+	script := createE2ETestFile(t, `
+var PW = @crypto.password(length=24, charset="hex", label="db")
+var HASH = @crypto.bcrypt(@var.PW, cost=4)
+var HASH64 = @crypto.base64.encode(@var.HASH)
+var KEY = @env.CRYPTO_E2E_KEY
+var MAC = @crypto.hmac(key=@var.KEY, data="payload")
+printf '%s\n%s\n%s\n' @var.PW @var.HASH64 @var.MAC > @file("`+out+`", secrets="passthrough")
+printf '%s' @var.PW
+`)
+
+	stdout := runE2E(t, binPath, "-f", script)
+	if !strings.HasPrefix(stdout, "sigil:") {
+		t.Errorf("password should be scrubbed from output, got %q", stdout)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read sink file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", data)
+	}
+	password, mac := lines[0], lines[2]
+	hash, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatalf("decode hash: %v", err)
+	}
+
+	if diff := cmp.Diff(24, len(password)); diff != "" {
+		t.Errorf("password length mismatch (-want +got):\n%s", diff)
+	}
+	if strings.Trim(password, "0123456789abcdef") != "" {
+		t.Errorf("password should only use hex characters, got %q", password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		t.Errorf("bcrypt hash does not match password: %v", err)
+	}
+	// HMAC-SHA256("signing-key", "payload")
+	if diff := cmp.Diff("4e9296c235e71b4110c5b3f48b807095be0bd0e3035784baec4551106606cd9e", mac); diff != "" {
+		t.Errorf("hmac mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCryptoContractReplansFromStoreSeed(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	dir := t.TempDir()
+	t.Setenv("SIGIL_SECRETS_FILE", filepath.Join(dir, "secrets.enc"))
+	t.Setenv("SIGIL_SECRETS_KEY_FILE", filepath.Join(dir, "store.key"))
+	t.Setenv("SIGIL_SECRETS_KEYRING", "")
+	t.Setenv("SIGIL_SECRETS_PASSPHRASE", "")
+	runE2E(t, binPath, "secrets", "set", "tokens/deploy", "deploy-token-8f3a")
+
+	out := filepath.Join(t.TempDir(), "creds")
+	script := createE2ETestFile(t, `
+var PW = @crypto.password(length=24, charset="hex", label="db")
+var HASH = @crypto.bcrypt(@var.PW, cost=4)
+var HASH64 = @crypto.base64.encode(@var.HASH)
+printf '%s\n%s\n' @var.PW @var.HASH64 > @file("`+out+`", secrets="passthrough")
+`)
+
+	planFile := filepath.Join(t.TempDir(), "test.plan")
+	plan, _ := runE2EWithStderr(t, binPath, "-f", script, "--dry-run", "--resolve")
+	if err := os.WriteFile(planFile, []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	// Verification replans with the contract's plan key; the seed in the
+	// store makes the password and hash come out the same.
+	runE2E(t, binPath, "--plan", planFile, "-f", script)
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read sink file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", data)
+	}
+	if strings.Contains(plan, lines[0]) {
+		t.Errorf("contract should not contain the password %q", lines[0])
+	}
+	hash, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatalf("decode hash: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(lines[0])); err != nil {
+		t.Errorf("bcrypt hash does not match password: %v", err)
+	}
+}
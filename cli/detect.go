@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/runtime/streamscrub"
+	"github.com/builtwithtofu/sigil/runtime/vault"
+)
+
+// credentialScan detects credentials the vault does not know about in
+// command output (--detect-credentials) and records what it redacted.
+type credentialScan struct {
+	enabled  bool     // --detect-credentials
+	failFast bool     // --detect-fail-fast
+	rules    []string // --detect-rule
+	patterns []string // --detect-pattern, as name=regex
+
+	mu     sync.Mutex
+	counts map[string]int
+	first  string                  // rule of the first detection
+	abort  context.CancelCauseFunc // cancels the run in fail-fast mode
+	sink   decorator.EventSink     // the running execution, if any
+}
+
+// customRuleMaxLength bounds matches of --detect-pattern rules, like the
+// longest built-in token rules.
+const customRuleMaxLength = 256
+
+// active reports whether output is scanned.
+func (c *credentialScan) active() bool {
+	return c != nil && (c.enabled || c.failFast || len(c.rules) > 0 || len(c.patterns) > 0)
+}
+
+// provider returns the scrubber's secret provider: the vault's, followed by
+// a credential detector when scanning is active.
+func (c *credentialScan) provider(vlt *vault.Vault) (streamscrub.SecretProvider, error) {
+	if !c.active() {
+		return vlt.SecretProvider(), nil
+	}
+
+	rules, err := c.customRules(streamscrub.DefaultRules())
+	if err != nil {
+		return nil, err
+	}
+	if len(c.rules) > 0 {
+		if rules, err = streamscrub.RulesByName(rules, c.rules); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fail-fast still redacts instead of using streamscrub.WithFailFast: a
+	// scrubber error would stop the stream mid-run, while cancelling keeps
+	// the remaining output (and the abort error) flowing through it.
+	detector := streamscrub.NewCredentialDetector(
+		streamscrub.WithRules(rules...),
+		streamscrub.WithDetectionHandler(c.record))
+	return streamscrub.Chain(vlt.SecretProvider(), detector), nil
+}
+
+// customRules appends the --detect-pattern rules to rules. Names must not
+// collide, so --detect-rule always selects an unambiguous rule.
+func (c *credentialScan) customRules(rules []streamscrub.Rule) ([]streamscrub.Rule, error) {
+	for _, spec := range c.patterns {
+		name, pattern, ok := strings.Cut(spec, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid --detect-pattern %q: want name=regex", spec)
+		}
+		for _, rule := range rules {
+			if rule.Name == name {
+				return nil, fmt.Errorf("invalid --detect-pattern %q: rule %q already exists", spec, name)
+			}
+		}
+		rule, err := streamscrub.NewRule(name, pattern, customRuleMaxLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --detect-pattern: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// record is the detector's handler. It runs with the scrubber locked, so it
+// must not write output.
+func (c *credentialScan) record(d streamscrub.Detection) {
+	c.mu.Lock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+		c.first = d.Rule
+	}
+	c.counts[d.Rule]++
+	if c.failFast && c.abort != nil {
+		c.abort(c.failureLocked())
+	}
+	sink := c.sink
+	c.mu.Unlock()
+
+	if sink != nil {
+		sink.Emit(decorator.Event{
+			Decorator: "scrub",
+			Kind:      "credential",
+			Attrs:     map[string]string{"rule": d.Rule, "length": strconv.Itoa(d.Length)},
+		})
+	}
+}
+
+// attach sends detections to sink as events until the returned detach is
+// called. Output the scrubber still holds back when the run ends is only
+// counted in the report.
+func (c *credentialScan) attach(sink decorator.EventSink) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sink = sink
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.sink = nil
+	}
+}
+
+// watch returns a context that is cancelled on the first detection in
+// fail-fast mode.
+func (c *credentialScan) watch(ctx context.Context) context.Context {
+	if !c.active() || !c.failFast {
+		return ctx
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.abort = cancel
+	if c.counts != nil {
+		cancel(c.failureLocked())
+	}
+	return ctx
+}
+
+// failure returns the fail-fast error once a credential has been detected.
+func (c *credentialScan) failure() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.failFast || c.counts == nil {
+		return nil
+	}
+	return c.failureLocked()
+}
+
+// failureLocked builds the fail-fast error. Assumes mu is held.
+func (c *credentialScan) failureLocked() error {
+	return &CLIError{
+		Type:    "execution",
+		Message: fmt.Sprintf("run aborted: credential detected in output (%s)", c.first),
+		Hint:    "The value was redacted. Load it through the vault (e.g. @env or @secrets) so it is tracked as a secret.",
+	}
+}
+
+// report writes a summary of redacted credentials to w.
+func (c *credentialScan) report(w io.Writer, useColor bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.counts) == 0 {
+		return
+	}
+
+	rules := make([]string, 0, len(c.counts))
+	total := 0
+	for rule, n := range c.counts {
+		rules = append(rules, fmt.Sprintf("%s (%d)", rule, n))
+		total += n
+	}
+	sort.Strings(rules)
+
+	noun := "credentials"
+	if total == 1 {
+		noun = "credential"
+	}
+	_, _ = fmt.Fprintf(w, "%s%d unknown %s redacted from output: %s\n",
+		Colorize("Warning: ", ColorYellow, useColor), total, noun, strings.Join(rules, ", "))
+}
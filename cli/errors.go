@@ -95,11 +95,35 @@ func formatCLIError(w io.Writer, err *CLIError, useColor bool) {
 }
 
 // FormatContractVerificationError formats contract verification failures with diff
-func FormatContractVerificationError(w io.Writer, contractPlan, freshPlan *planfmt.Plan, useColor bool) {
+// and returns the diff so callers can explain the failure.
+func FormatContractVerificationError(w io.Writer, contractPlan, freshPlan *planfmt.Plan, useColor bool) *formatter.DiffResult {
 	_, _ = fmt.Fprintf(w, "%sCONTRACT VERIFICATION FAILED%s\n\n", Colorize("", ColorRed, useColor), ColorReset)
 
 	// Show detailed diff of what changed
 	diff := formatter.Diff(contractPlan, freshPlan)
 	diffOutput := formatter.FormatDiff(diff, useColor)
 	_, _ = fmt.Fprint(w, diffOutput)
+	return diff
+}
+
+// contractDriftReason says why a contract no longer matches. A changed secret
+// also changes the DisplayIDs in the steps that use it, so modified steps
+// alone are blamed on the secrets.
+func contractDriftReason(diff *formatter.DiffResult) string {
+	if diff.TargetChanged != "" || len(diff.Added)+len(diff.Removed) > 0 || len(diff.Secrets) == 0 {
+		return "source file has changed since contract was created"
+	}
+
+	kinds := make(map[formatter.SecretChangeKind]bool)
+	for _, change := range diff.Secrets {
+		kinds[change.Kind] = true
+	}
+	switch {
+	case kinds[formatter.SecretSourceChanged]:
+		return "a secret now comes from a different source"
+	case kinds[formatter.SecretValueChanged]:
+		return "a secret value has changed since contract was created"
+	default:
+		return "a secret was rotated since contract was created"
+	}
 }
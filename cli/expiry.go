@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+)
+
+// secretExpiry collects secrets in the plan whose version expires soon. The
+// warnings are printed once output is restored, like credential detections,
+// so they never end up in a contract written to stdout.
+type secretExpiry struct {
+	within time.Duration // --expiry-warning
+
+	mu       sync.Mutex
+	now      time.Time
+	expiring []planfmt.ExpiringSecret
+}
+
+// check records the plan's secrets that expire within the warning window.
+func (e *secretExpiry) check(plan *planfmt.Plan) {
+	if e == nil || plan == nil || e.within <= 0 {
+		return
+	}
+	now := time.Now()
+	expiring := planfmt.ExpiringSecrets(plan, now, e.within)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now, e.expiring = now, expiring
+}
+
+// report writes one warning per expiring secret to w.
+func (e *secretExpiry) report(w io.Writer, useColor bool) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range e.expiring {
+		source := s.Origin
+		if source == "" {
+			source = "unknown source"
+		}
+		if s.Version != "" {
+			source += " version " + s.Version
+		}
+
+		when := "expires in " + formatExpiryDelay(s.ExpiresAt.Sub(e.now))
+		if s.Expired(e.now) {
+			when = "expired " + formatExpiryDelay(e.now.Sub(s.ExpiresAt)) + " ago"
+		}
+		_, _ = fmt.Fprintf(w, "%ssecret %s (%s) %s (%s)\n",
+			Colorize("Warning: ", ColorYellow, useColor), s.DisplayID, source, when,
+			s.ExpiresAt.Local().Format(time.RFC3339))
+	}
+}
+
+// formatExpiryDelay renders d rounded to its largest unit, e.g. "3d", "5h"
+// or "12m".
+func formatExpiryDelay(d time.Duration) string {
+	for _, unit := range []struct {
+		size time.Duration
+		name string
+	}{{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}} {
+		if d >= unit.size {
+			return fmt.Sprintf("%d%s", (d+unit.size/2)/unit.size, unit.name)
+		}
+	}
+	return "less than a minute"
+}
@@ -15,21 +15,25 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	_ "github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/core/planfmt"
 	"github.com/builtwithtofu/sigil/core/sdk/secret"
-	_ "github.com/builtwithtofu/sigil/runtime/decorators" // Register built-in decorators
+	"github.com/builtwithtofu/sigil/runtime/decorators" // Register built-in decorators
 	"github.com/builtwithtofu/sigil/runtime/executor"
 	"github.com/builtwithtofu/sigil/runtime/lexer"
 	"github.com/builtwithtofu/sigil/runtime/parser"
 	"github.com/builtwithtofu/sigil/runtime/planner"
+	"github.com/builtwithtofu/sigil/runtime/securemem"
 	"github.com/builtwithtofu/sigil/runtime/streamscrub"
 	"github.com/builtwithtofu/sigil/runtime/vault"
 	"github.com/spf13/cobra"
@@ -48,7 +52,12 @@ func main() {
 		debug    bool
 		noColor  bool
 		timing   bool
+		execOpts executionOptions
+		detect   credentialScan
+		expiry   secretExpiry
 	)
+	execOpts.credentials = &detect
+	execOpts.expiry = &expiry
 
 	rootCmd := &cobra.Command{
 		Use:   "sigil [command]",
@@ -71,6 +80,14 @@ DisplayID placeholders for security.`,
 				return fmt.Errorf("failed to create placeholder generator: %w", err)
 			}
 
+			// Keep secrets out of core dumps while the vault holds them.
+			// Best effort: sandboxes may forbid prctl, which must not block runs.
+			if restoreDumps, err := securemem.DisableCoreDumps(); err == nil {
+				defer restoreDumps()
+			} else if debug {
+				fmt.Fprintf(os.Stderr, "warning: core dumps stay enabled: %v\n", err)
+			}
+
 			// Mode 4: Execute from plan file (contract verification)
 			if planFile != "" {
 				if len(args) > 0 {
@@ -91,21 +108,31 @@ DisplayID placeholders for security.`,
 				// Create vault with contract's PlanSalt for deterministic DisplayIDs
 				// CRITICAL: Reusing PlanSalt ensures same DisplayIDs during verification
 				vlt := vault.NewWithPlanKey(contractPlan.PlanSalt)
+				defer vlt.Close() // Runs after the scrubber's final flush
 
 				// Create scrubber with vault's secret provider
+				provider, err := detect.provider(vlt)
+				if err != nil {
+					return err
+				}
 				scrubber := streamscrub.New(&outputBuf,
 					streamscrub.WithPlaceholderFunc(sigilGen.PlaceholderFunc()),
-					streamscrub.WithSecretProvider(vlt.SecretProvider()))
+					streamscrub.WithSecretProvider(provider))
 
 				// Redirect stdout/stderr through scrubber
 				restore := scrubber.LockdownStreams()
 				defer restore()
 
-				exitCode, err := runFromPlan(planFile, file, debug, noColor, vlt, scrubber, &outputBuf)
+				execOpts.secrets = provider
+				exitCode, err := runFromPlan(planFile, file, debug, noColor, execOpts, vlt, scrubber, &outputBuf)
 				if err != nil {
 					cmd.SilenceUsage = true // We've already printed detailed error
 					return err
 				}
+				if abortErr := detect.failure(); abortErr != nil {
+					cmd.SilenceUsage = true
+					return abortErr
+				}
 				if exitCode != 0 {
 					return formatExitCodeError(exitCode)
 				}
@@ -120,11 +147,17 @@ DisplayID placeholders for security.`,
 				return fmt.Errorf("failed to generate plan key: %w", err)
 			}
 			vlt := vault.NewWithPlanKey(planKey)
+			securemem.Wipe(planKey) // The vault keeps its own copy
+			defer vlt.Close()       // Runs after the scrubber's final flush
 
 			// Create scrubber with vault's secret provider
+			provider, err := detect.provider(vlt)
+			if err != nil {
+				return err
+			}
 			scrubber := streamscrub.New(&outputBuf,
 				streamscrub.WithPlaceholderFunc(sigilGen.PlaceholderFunc()),
-				streamscrub.WithSecretProvider(vlt.SecretProvider()))
+				streamscrub.WithSecretProvider(provider))
 
 			// Redirect stdout/stderr through scrubber
 			restore := scrubber.LockdownStreams()
@@ -138,11 +171,16 @@ DisplayID placeholders for security.`,
 			}
 			// else: commandName = "" (script mode)
 
-			exitCode, err := runCommand(cmd, commandName, file, dryRun, resolve, debug, noColor, timing, vlt, scrubber, &outputBuf)
+			execOpts.secrets = provider
+			exitCode, err := runCommand(cmd, commandName, file, dryRun, resolve, debug, noColor, timing, execOpts, vlt, scrubber, &outputBuf)
 			if err != nil {
 				cmd.SilenceUsage = true // We've already printed detailed error
 				return err
 			}
+			if abortErr := detect.failure(); abortErr != nil {
+				cmd.SilenceUsage = true
+				return abortErr
+			}
 			if exitCode != 0 {
 				// Store exit code for later (can't os.Exit here - skips defers)
 				return formatExitCodeError(exitCode)
@@ -153,6 +191,8 @@ DisplayID placeholders for security.`,
 
 	// Add flags
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newMuxCmd())
+	rootCmd.AddCommand(newSecretsCmd())
 
 	rootCmd.PersistentFlags().StringVarP(&file, "file", "f", "commands.sgl", "Path to command definitions file")
 	rootCmd.PersistentFlags().StringVar(&planFile, "plan", "", "Execute from pre-generated plan file (Mode 4)")
@@ -161,10 +201,26 @@ DisplayID placeholders for security.`,
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	rootCmd.PersistentFlags().BoolVar(&timing, "timing", false, "Show pipeline timing breakdown")
+	rootCmd.PersistentFlags().BoolVar(&execOpts.noCache, "no-cache", false, "Force @exec.cache blocks to execute")
+	rootCmd.PersistentFlags().BoolVarP(&execOpts.assumeYes, "yes", "y", false, "Approve every @exec.confirm gate without prompting")
+	rootCmd.PersistentFlags().StringArrayVar(&execOpts.approve, "approve", nil, "Approve @exec.confirm gates whose name matches this pattern (repeatable)")
+	rootCmd.PersistentFlags().IntVar(&execOpts.maxParallel, "max-parallel", 0, "Maximum function dependencies (needs) run concurrently (0 = number of CPUs)")
+	rootCmd.PersistentFlags().BoolVar(&detect.enabled, "detect-credentials", false, "Redact credential-shaped output (API tokens, keys, JWTs) the vault does not know about")
+	rootCmd.PersistentFlags().StringSliceVar(&detect.rules, "detect-rule", nil, "Only use these credential rules (repeatable; implies --detect-credentials)")
+	rootCmd.PersistentFlags().StringArrayVar(&detect.patterns, "detect-pattern", nil, "Add a credential rule as name=regex (repeatable; implies --detect-credentials)")
+	rootCmd.PersistentFlags().BoolVar(&detect.failFast, "detect-fail-fast", false, "Abort the run when a credential is detected in output (implies --detect-credentials)")
+	rootCmd.PersistentFlags().DurationVar(&expiry.within, "expiry-warning", planfmt.DefaultExpiryWarning, "Warn about secrets in the plan that expire within this duration (0 disables)")
 
 	// Execute command and capture exit code
 	exitCode := 0
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	// Output is flushed through the scrubber by now, so every detection is counted
+	detect.report(os.Stderr, !noColor)
+	expiry.report(os.Stderr, !noColor)
+	if abortErr := detect.failure(); abortErr != nil {
+		err = abortErr
+	}
+	if err != nil {
 		// Error messages go through scrubber
 		// Use FormatError for consistent, colored error output
 		FormatError(os.Stderr, err, !noColor)
@@ -188,6 +244,46 @@ func formatExitCodeError(exitCode int) error {
 	return fmt.Errorf("command failed with exit code %d", exitCode)
 }
 
+// executionOptions carries flags that shape decorator behavior at execution time.
+type executionOptions struct {
+	noCache   bool     // --no-cache
+	assumeYes bool     // --yes
+	approve   []string // --approve
+
+	maxParallel int // --max-parallel
+
+	credentials *credentialScan // --detect-credentials and friends
+	expiry      *secretExpiry   // --expiry-warning
+
+	secrets streamscrub.SecretProvider // Scrubs output written outside lockdown, e.g. redirect sinks
+}
+
+// apply attaches the execution options to the execution context.
+func (o executionOptions) apply(ctx context.Context) context.Context {
+	if o.noCache {
+		ctx = decorators.WithCacheDisabled(ctx)
+	}
+	ctx = o.credentials.watch(ctx)
+	return decorators.WithConfirmPolicy(ctx, decorators.ConfirmPolicy{
+		AssumeYes: o.assumeYes,
+		Allow:     o.approve,
+	})
+}
+
+// config returns the executor configuration for these options.
+func (o executionOptions) config(debug executor.DebugLevel, telemetry executor.TelemetryLevel) executor.Config {
+	cfg := executor.Config{
+		Debug:          debug,
+		Telemetry:      telemetry,
+		MaxParallelism: o.maxParallel,
+		SecretProvider: o.secrets,
+	}
+	if o.credentials.active() {
+		cfg.EventSource = o.credentials.attach
+	}
+	return cfg
+}
+
 // newCancellableContext creates a context that cancels on SIGINT/SIGTERM
 // This allows Ctrl+C to propagate through the entire execution chain
 func newCancellableContext() (context.Context, context.CancelFunc) {
@@ -206,7 +302,7 @@ func newCancellableContext() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
-func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, debug, noColor, timing bool, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
+func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, debug, noColor, timing bool, execOpts executionOptions, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
 	// commandName is empty string for script mode, function name for command mode
 
 	// Get input reader based on file options
@@ -328,6 +424,7 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 			return 1, fmt.Errorf("planning failed: %w", err)
 		}
 	}
+	execOpts.expiry.check(plan)
 
 	// Dry-run mode: show plan or generate contract
 	if dryRun {
@@ -376,11 +473,9 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	// Create cancellable context for Ctrl+C handling
 	ctx, cancel := newCancellableContext()
 	defer cancel()
+	ctx = execOpts.apply(ctx)
 
-	result, err := executor.ExecutePlan(ctx, plan, executor.Config{
-		Debug:     execDebug,
-		Telemetry: telemetryLevel,
-	}, vlt)
+	result, err := executor.ExecutePlan(ctx, plan, execOpts.config(execDebug, telemetryLevel), vlt)
 	if err != nil {
 		return 1, fmt.Errorf("execution failed: %w", err)
 	}
@@ -398,6 +493,8 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 		fmt.Fprintf(os.Stderr, "  Steps run: %d/%d\n", result.StepsRun, len(plan.Steps))
 		fmt.Fprintf(os.Stderr, "  Duration: %v\n", result.Duration)
 		fmt.Fprintf(os.Stderr, "  Exit code: %d\n", result.ExitCode)
+		displayCompensations(result)
+		displayEvents(result)
 	}
 
 	// Return exit code to main (don't call os.Exit - skips defers!)
@@ -447,7 +544,7 @@ func hasPipedInput() bool {
 
 // runFromPlan executes with contract verification (Mode 4: Contract Execution)
 // Flow: Load contract → Replan fresh → Compare hashes → Execute if match
-func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
+func runFromPlan(planFile, sourceFile string, debug, noColor bool, execOpts executionOptions, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
 	// Step 1: Load contract from plan file
 	f, err := os.Open(planFile)
 	if err != nil {
@@ -457,6 +554,17 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 
 	target, contractHash, contractPlan, err := planfmt.ReadContract(f)
 	if err != nil {
+		var versionErr *planfmt.VersionError
+		if errors.As(err, &versionErr) {
+			return 1, fmt.Errorf(
+				"contract file '%s' uses plan format version %d, but this sigil reads version %d\n\n"+
+					"Contracts are not migrated between format versions.\n\n"+
+					"To fix:\n"+
+					"  1. Regenerate the contract: sigil plan --mode=contract <file>\n"+
+					"  2. Or use --mode=plan to execute without contract verification",
+				planFile, versionErr.Got, versionErr.Want,
+			)
+		}
 		return 1, fmt.Errorf("failed to read contract: %w", err)
 	}
 
@@ -568,6 +676,7 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 	// Without this, fresh plan gets random PlanSalt (from NewPlan) and hash will never match
 	// The IDFactory uses PlanSalt to generate DisplayIDs, but the plan itself needs the same salt
 	freshPlan.PlanSalt = contractPlan.PlanSalt
+	execOpts.expiry.check(freshPlan)
 
 	// Step 3: Compare hashes (contract verification)
 	var freshHashBuf bytes.Buffer
@@ -578,7 +687,7 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 
 	if freshHash != contractHash {
 		// Use error formatter for consistent output
-		FormatContractVerificationError(os.Stderr, contractPlan, freshPlan, !noColor)
+		diff := FormatContractVerificationError(os.Stderr, contractPlan, freshPlan, !noColor)
 
 		// Show hashes for debugging
 		if debug {
@@ -588,12 +697,12 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 		}
 
 		return 1, fmt.Errorf(
-			"contract verification failed: source file has changed since contract was created\n\n"+
+			"contract verification failed: %s\n\n"+
 				"The differences are shown above. To fix:\n"+
 				"  1. Review the changes to ensure they are intentional\n"+
 				"  2. Regenerate the contract: sigil plan --mode=contract %s\n"+
 				"  3. Or use --mode=plan to execute without verification",
-			planFile,
+			contractDriftReason(diff), planFile,
 		)
 	}
 
@@ -611,11 +720,9 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 	// Create cancellable context for Ctrl+C handling
 	ctx, cancel := newCancellableContext()
 	defer cancel()
+	ctx = execOpts.apply(ctx)
 
-	result, err := executor.ExecutePlan(ctx, freshPlan, executor.Config{
-		Debug:     execDebug,
-		Telemetry: executor.TelemetryBasic,
-	}, vlt)
+	result, err := executor.ExecutePlan(ctx, freshPlan, execOpts.config(execDebug, executor.TelemetryBasic), vlt)
 	if err != nil {
 		return 1, fmt.Errorf("execution failed: %w", err)
 	}
@@ -626,6 +733,8 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 		fmt.Fprintf(os.Stderr, "  Steps run: %d/%d\n", result.StepsRun, len(freshPlan.Steps))
 		fmt.Fprintf(os.Stderr, "  Duration: %v\n", result.Duration)
 		fmt.Fprintf(os.Stderr, "  Exit code: %d\n", result.ExitCode)
+		displayCompensations(result)
+		displayEvents(result)
 	}
 
 	return result.ExitCode, nil
@@ -660,6 +769,39 @@ func displayPipelineTiming(timing struct {
 	fmt.Fprintf(os.Stderr, "  Total:   %v\n", totalTime)
 }
 
+// displayCompensations lists rollback blocks that ran after a failure
+func displayCompensations(result *executor.ExecutionResult) {
+	if result == nil || len(result.Compensations) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "  Compensations: %d\n", len(result.Compensations))
+	for _, c := range result.Compensations {
+		fmt.Fprintf(os.Stderr, "    Step %d (after step %d failed): exit %d in %v\n", c.StepID, c.TriggerStep, c.ExitCode, c.Duration)
+	}
+}
+
+// displayEvents lists auditable decorator events recorded during execution
+func displayEvents(result *executor.ExecutionResult) {
+	if result == nil || len(result.Events) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "  Events: %d\n", len(result.Events))
+	for _, event := range result.Events {
+		keys := make([]string, 0, len(event.Attrs))
+		for key := range event.Attrs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		attrs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			attrs = append(attrs, fmt.Sprintf("%s=%q", key, event.Attrs[key]))
+		}
+		fmt.Fprintf(os.Stderr, "    @%s %s %s\n", event.Decorator, event.Kind, strings.Join(attrs, " "))
+	}
+}
+
 // stripShebang removes shebang line if present (#!/usr/bin/env sigil)
 // TODO: Support shebang properly in parser by adding # as comment character
 func stripShebang(source []byte) []byte {
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/spf13/cobra"
+)
+
+// muxStartTimeout bounds how long `sigil mux start` waits for the agent.
+const muxStartTimeout = 5 * time.Second
+
+func newMuxCmd() *cobra.Command {
+	var socket string
+
+	cmd := &cobra.Command{
+		Use:   "mux",
+		Short: "Share authenticated SSH connections across sigil runs",
+		Long: `Run a local agent that keeps @ssh.connect connections open between
+sigil invocations, like OpenSSH's ControlMaster. While the agent runs,
+sigil reuses its connections instead of dialing and authenticating again.
+
+The agent listens on a unix socket in a directory only the current user can
+access. Set SIGIL_SSH_MUX_SOCKET to use another socket, or to "none" to
+always connect directly.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.PersistentFlags().StringVar(&socket, "socket", decorator.SSHMuxSocketPath(), "Agent socket path")
+
+	cmd.AddCommand(newMuxServeCmd(&socket), newMuxStartCmd(&socket), newMuxStopCmd(&socket), newMuxStatusCmd(&socket))
+	return cmd
+}
+
+func newMuxServeCmd(socket *string) *cobra.Command {
+	var idleTTL time.Duration
+
+	cmd := &cobra.Command{
+		Use:          "serve",
+		Short:        "Run the agent in the foreground",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if *socket == "none" {
+				return errors.New("ssh mux is disabled (socket is \"none\")")
+			}
+			mux, err := decorator.NewSSHMux(decorator.SSHMuxConfig{Socket: *socket, IdleTTL: idleTTL})
+			if err != nil {
+				return err
+			}
+
+			// Outlive the terminal that started it
+			signal.Ignore(syscall.SIGHUP)
+			ctx, cancel := newCancellableContext()
+			defer cancel()
+
+			return mux.Serve(ctx)
+		},
+	}
+	cmd.Flags().DurationVar(&idleTTL, "idle-ttl", decorator.DefaultSSHMuxIdleTTL, "Close connections unused for this long")
+
+	return cmd
+}
+
+func newMuxStartCmd(socket *string) *cobra.Command {
+	var idleTTL time.Duration
+
+	cmd := &cobra.Command{
+		Use:          "start",
+		Short:        "Start the agent in the background",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if *socket == "none" {
+				return errors.New("ssh mux is disabled (socket is \"none\")")
+			}
+			if _, err := decorator.SSHMuxStatus(cmd.Context(), *socket); err == nil {
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "ssh mux already running on %s\n", *socket)
+				return err
+			}
+
+			self, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("locate sigil executable: %w", err)
+			}
+			agent := exec.Command(self, "mux", "serve", "--socket", *socket, "--idle-ttl", idleTTL.String())
+			if err := agent.Start(); err != nil {
+				return fmt.Errorf("start ssh mux: %w", err)
+			}
+			exited := make(chan error, 1)
+			go func() { exited <- agent.Wait() }()
+
+			deadline := time.NewTimer(muxStartTimeout)
+			defer deadline.Stop()
+			for {
+				if _, err := decorator.SSHMuxStatus(cmd.Context(), *socket); err == nil {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "ssh mux started on %s (pid %d)\n", *socket, agent.Process.Pid)
+					return err
+				}
+				select {
+				case err := <-exited:
+					return fmt.Errorf("ssh mux exited during startup: %v", err)
+				case <-deadline.C:
+					_ = agent.Process.Kill()
+					return fmt.Errorf("ssh mux did not start within %s", muxStartTimeout)
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&idleTTL, "idle-ttl", decorator.DefaultSSHMuxIdleTTL, "Close connections unused for this long")
+
+	return cmd
+}
+
+func newMuxStopCmd(socket *string) *cobra.Command {
+	return &cobra.Command{
+		Use:          "stop",
+		Short:        "Close all shared connections and stop the agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := decorator.StopSSHMux(cmd.Context(), *socket); err != nil {
+				return fmt.Errorf("no ssh mux running on %s: %w", *socket, err)
+			}
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), "ssh mux stopped")
+			return err
+		},
+	}
+}
+
+func newMuxStatusCmd(socket *string) *cobra.Command {
+	return &cobra.Command{
+		Use:          "status",
+		Short:        "List the connections held by the agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conns, err := decorator.SSHMuxStatus(cmd.Context(), *socket)
+			if err != nil {
+				return fmt.Errorf("no ssh mux running on %s: %w", *socket, err)
+			}
+
+			out := cmd.OutOrStdout()
+			if _, err := fmt.Fprintf(out, "ssh mux running on %s\n", *socket); err != nil {
+				return err
+			}
+			if len(conns) == 0 {
+				_, err := fmt.Fprintln(out, "no open connections")
+				return err
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "HOST\tTARGET\tSESSIONS\tIDLE")
+			for _, conn := range conns {
+				idle := "-"
+				if conn.Sessions == 0 {
+					idle = conn.Idle.Round(time.Second).String()
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", conn.Host, conn.Target, conn.Sessions, idle)
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMuxCommandLifecycle(t *testing.T) {
+	binPath := buildVersionBinary(t, "test")
+	socket := filepath.Join(t.TempDir(), "mux", "ssh-mux.sock")
+
+	_, stderr, exitCode := runVersionCommand(t, binPath, "mux", "status", "--socket", socket)
+	if diff := cmp.Diff(1, exitCode); diff != "" {
+		t.Fatalf("status without agent exit code mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr, "no ssh mux running") {
+		t.Errorf("expected missing agent error, got %q", stderr)
+	}
+
+	stdout, stderr, exitCode := runVersionCommand(t, binPath, "mux", "start", "--socket", socket, "--idle-ttl", "1m")
+	if exitCode != 0 {
+		t.Fatalf("start failed (exit %d): %s", exitCode, stderr)
+	}
+	t.Cleanup(func() { _, _, _ = runVersionCommand(t, binPath, "mux", "stop", "--socket", socket) })
+	if !strings.HasPrefix(stdout, "ssh mux started on "+socket) {
+		t.Errorf("unexpected start output %q", stdout)
+	}
+
+	stdout, stderr, exitCode = runVersionCommand(t, binPath, "mux", "status", "--socket", socket)
+	if exitCode != 0 {
+		t.Fatalf("status failed (exit %d): %s", exitCode, stderr)
+	}
+	if diff := cmp.Diff("ssh mux running on "+socket+"\nno open connections\n", stdout); diff != "" {
+		t.Errorf("status output mismatch (-want +got):\n%s", diff)
+	}
+
+	stdout, stderr, exitCode = runVersionCommand(t, binPath, "mux", "stop", "--socket", socket)
+	if exitCode != 0 {
+		t.Fatalf("stop failed (exit %d): %s", exitCode, stderr)
+	}
+	if diff := cmp.Diff("ssh mux stopped\n", stdout); diff != "" {
+		t.Errorf("stop output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/types"
+	"github.com/builtwithtofu/sigil/runtime/secretstore"
+	"github.com/spf13/cobra"
+)
+
+// secretsOptions selects the store and key shared by all `sigil secrets` commands.
+type secretsOptions struct {
+	store   string
+	project bool
+	keyFile string
+	keyring string
+}
+
+func newSecretsCmd() *cobra.Command {
+	var opts secretsOptions
+
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the encrypted secret store read by @secrets.get",
+		Long: `Manage the local encrypted secret store. Scripts read its entries at plan
+time with @secrets.get(path="name"); plans show them only as DisplayIDs.
+
+The store is $SIGIL_SECRETS_FILE if set, else the nearest .sigil/secrets.enc
+in the working directory or its parents, else secrets.enc in the user config
+directory. Use --project to create a store in ./.sigil.
+
+The key comes from --key-file, --keyring, $SIGIL_SECRETS_KEY_FILE,
+$SIGIL_SECRETS_KEYRING or $SIGIL_SECRETS_PASSPHRASE, in that order. Key files
+and keyring entries are generated when a new store is created.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.PersistentFlags().StringVar(&opts.store, "store", "", "Secret store path (default: see above)")
+	cmd.PersistentFlags().BoolVar(&opts.project, "project", false, "Use the project store in ./.sigil")
+	cmd.PersistentFlags().StringVar(&opts.keyFile, "key-file", "", "Read the store key from this file")
+	cmd.PersistentFlags().StringVar(&opts.keyring, "keyring", "", "Read the store key from this OS keyring account")
+
+	cmd.AddCommand(
+		newSecretsSetCmd(&opts),
+		newSecretsGetCmd(&opts),
+		newSecretsListCmd(&opts),
+		newSecretsRmCmd(&opts),
+		newSecretsRotateCmd(&opts),
+		newSecretsAuditCmd(),
+	)
+	return cmd
+}
+
+func newSecretsSetCmd(opts *secretsOptions) *cobra.Command {
+	var expiresIn string
+
+	cmd := &cobra.Command{
+		Use:   "set NAME [VALUE]",
+		Short: "Store a secret (reads the value from stdin when omitted)",
+		Long: `Store a secret under NAME, a slash-separated path such as tokens/github.
+Without VALUE the value is read from stdin, with one trailing newline removed;
+prefer this over passing VALUE, which ends up in shell history.
+
+Each set stores a new version. Plans record the version, so a contract fails
+verification once the secret is rotated. With --expires-in the version also
+gets an expiry, and runs warn when it is close.`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var value []byte
+			if len(args) == 2 {
+				value = []byte(args[1])
+			} else {
+				raw, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("read secret value: %w", err)
+				}
+				value = bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+			}
+			if len(value) == 0 {
+				return errors.New("secret value is empty")
+			}
+			var expires time.Time
+			if expiresIn != "" {
+				d, err := types.ParseDuration(expiresIn)
+				if err != nil {
+					return fmt.Errorf("invalid --expires-in: %w", err)
+				}
+				expires = time.Now().Add(time.Duration(d.Nanoseconds()))
+			}
+
+			store, err := opts.open()
+			if err != nil {
+				return err
+			}
+			if err := store.Set(args[0], value); err != nil {
+				return err
+			}
+			if !expires.IsZero() {
+				if err := store.SetExpiry(args[0], expires); err != nil {
+					return err
+				}
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.ErrOrStderr(), "stored %s in %s\n", args[0], store.Path())
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&expiresIn, "expires-in", "", "Expire this version after a duration (e.g. 30d, 12h)")
+	return cmd
+}
+
+func newSecretsGetCmd(opts *secretsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:          "get NAME",
+		Short:        "Print a secret to stdout",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := opts.openExisting()
+			if err != nil {
+				return err
+			}
+			value, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			// Raw bytes for pipes; a newline only when a person reads it
+			if stdoutIsTerminal() {
+				value = append(value, '\n')
+			}
+			_, err = os.Stdout.Write(value)
+			return err
+		},
+	}
+}
+
+func newSecretsListCmd(opts *secretsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:          "list",
+		Short:        "List secret names (values are not shown)",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := opts.openExisting()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tVERSION\tUPDATED\tEXPIRES")
+			for _, entry := range store.List() {
+				expires := "-"
+				if !entry.Expires.IsZero() {
+					expires = entry.Expires.Local().Format(time.RFC3339)
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", entry.Name, entry.Version, entry.Updated.Local().Format(time.RFC3339), expires)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newSecretsRmCmd(opts *secretsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:          "rm NAME...",
+		Short:        "Remove secrets",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := opts.openExisting()
+			if err != nil {
+				return err
+			}
+			for _, name := range args {
+				if err := store.Delete(name); err != nil {
+					return err
+				}
+			}
+			return store.Save()
+		},
+	}
+}
+
+func newSecretsRotateCmd(opts *secretsOptions) *cobra.Command {
+	var (
+		toKeyFile    string
+		toKeyring    string
+		toPassphrase string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt the store under a new key",
+		Long: `Re-encrypt the store with a fresh salt, so the derived key changes even
+when the key source stays the same. Pass one of the --to flags to move the
+store to a different key source.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var newKeys secretstore.KeySource
+			switch {
+			case toKeyFile != "" && toKeyring == "" && toPassphrase == "":
+				newKeys = secretstore.KeyFile(toKeyFile)
+			case toKeyring != "" && toKeyFile == "" && toPassphrase == "":
+				ring, err := secretstore.SystemKeyring()
+				if err != nil {
+					return err
+				}
+				newKeys = secretstore.KeyringKey(ring, toKeyring)
+			case toPassphrase != "" && toKeyFile == "" && toKeyring == "":
+				passphrase := os.Getenv(toPassphrase)
+				if passphrase == "" {
+					return fmt.Errorf("new passphrase variable $%s is not set", toPassphrase)
+				}
+				newKeys = secretstore.Passphrase([]byte(passphrase))
+			case toKeyFile == "" && toKeyring == "" && toPassphrase == "":
+			default:
+				return errors.New("use only one of --to-key-file, --to-keyring and --to-passphrase-env")
+			}
+
+			store, err := opts.openExisting()
+			if err != nil {
+				return err
+			}
+			if err := store.Rotate(newKeys); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.ErrOrStderr(), "rotated key for %s\n", store.Path())
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&toKeyFile, "to-key-file", "", "Move the store to this key file (generated if missing)")
+	cmd.Flags().StringVar(&toKeyring, "to-keyring", "", "Move the store to this OS keyring account")
+	cmd.Flags().StringVar(&toPassphrase, "to-passphrase-env", "", "Move the store to the passphrase in this environment variable")
+
+	return cmd
+}
+
+// path returns the store selected by the flags and environment.
+func (o *secretsOptions) path() (string, error) {
+	if o.store != "" {
+		return o.store, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("locate secret store: %w", err)
+	}
+	if o.project {
+		return secretstore.ProjectPath(wd), nil
+	}
+	return secretstore.DefaultPath(wd)
+}
+
+func (o *secretsOptions) keys() (secretstore.KeySource, error) {
+	switch {
+	case o.keyFile != "":
+		return secretstore.KeyFile(o.keyFile), nil
+	case o.keyring != "":
+		ring, err := secretstore.SystemKeyring()
+		if err != nil {
+			return nil, err
+		}
+		return secretstore.KeyringKey(ring, o.keyring), nil
+	default:
+		return secretstore.KeySourceFromEnv()
+	}
+}
+
+// open opens the store, creating it on the next save if it does not exist.
+func (o *secretsOptions) open() (*secretstore.Store, error) {
+	path, err := o.path()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := o.keys()
+	if err != nil {
+		return nil, err
+	}
+	return secretstore.Open(path, keys)
+}
+
+// openExisting opens the store, failing if it does not exist yet so that
+// read-only commands never generate key material.
+func (o *secretsOptions) openExisting() (*secretstore.Store, error) {
+	path, err := o.path()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no secret store at %s (create one with `sigil secrets set`)", path)
+	}
+	keys, err := o.keys()
+	if err != nil {
+		return nil, err
+	}
+	return secretstore.Open(path, keys)
+}
+
+func stdoutIsTerminal() bool {
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
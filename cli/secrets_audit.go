@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/spf13/cobra"
+)
+
+func newSecretsAuditCmd() *cobra.Command {
+	var baseline string
+
+	cmd := &cobra.Command{
+		Use:   "audit CONTRACT",
+		Short: "List the secrets a contract uses and where (values are not shown)",
+		Long: `List every secret a contract unwraps: its DisplayID, where its value comes
+from (e.g. @env.DB_PASSWORD), and each site authorized to use it, with the
+transport the site runs in and whether it is part of a pipeline or redirect.
+
+With --baseline, also compare against an older contract and report secret
+flows (origin, consuming decorator parameter, transport and context) that are
+new or no longer present. The command exits with status 1 when there are new
+flows, so it can gate reviews in CI.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			audit, err := readSecretAudit(args[0])
+			if err != nil {
+				return err
+			}
+			if err := writeSecretAudit(cmd.OutOrStdout(), audit); err != nil {
+				return err
+			}
+			if baseline == "" {
+				return nil
+			}
+
+			old, err := readSecretAudit(baseline)
+			if err != nil {
+				return err
+			}
+			added, removed := planfmt.CompareSecretFlows(old, audit)
+			writeSecretFlows(cmd.OutOrStdout(), added, removed)
+			if len(added) > 0 {
+				return &CLIError{
+					Type:    "contract",
+					Message: fmt.Sprintf("%d new secret flow(s) since %s", len(added), baseline),
+					Hint:    "Review the flows marked + above.",
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&baseline, "baseline", "", "Report secret flows that are new since this contract")
+	return cmd
+}
+
+func readSecretAudit(path string) (*planfmt.SecretAudit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open contract: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	_, _, plan, err := planfmt.ReadContract(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract %s: %w", path, err)
+	}
+	return planfmt.AuditSecrets(plan), nil
+}
+
+func writeSecretAudit(out io.Writer, audit *planfmt.SecretAudit) error {
+	if len(audit.Secrets) == 0 {
+		_, _ = fmt.Fprintln(out, "No secrets used.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SECRET\tORIGIN\tSITE\tTRANSPORT\tCONTEXT")
+	for _, secret := range audit.Secrets {
+		origin := secret.Origin
+		if origin == "" {
+			origin = "-"
+		}
+		for _, use := range secret.Uses {
+			transport := use.Transport
+			if transport == "" {
+				transport = "?" // site not found in the plan
+			}
+			context := use.Context()
+			if context == "" {
+				context = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", secret.DisplayID, origin, use.Site, transport, context)
+		}
+	}
+	return w.Flush()
+}
+
+func writeSecretFlows(out io.Writer, added, removed []planfmt.SecretFlow) {
+	_, _ = fmt.Fprintln(out)
+	if len(added) == 0 && len(removed) == 0 {
+		_, _ = fmt.Fprintln(out, "No secret flow changes since baseline.")
+		return
+	}
+	for _, flow := range added {
+		_, _ = fmt.Fprintf(out, "+ %s\n", flow)
+	}
+	for _, flow := range removed {
+		_, _ = fmt.Fprintf(out, "- %s\n", flow)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSecretsAudit_EndToEnd(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	dir := t.TempDir()
+	t.Setenv("AUDIT_TOKEN", "tok-5b1e9c77")
+	t.Setenv("AUDIT_DB_PASSWORD", "pw-0d4c2a18")
+
+	writeContract := func(name, script string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		out := runE2E(t, binPath, "-f", createE2ETestFile(t, script), "--dry-run", "--resolve")
+		if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+			t.Fatalf("failed to write contract: %v", err)
+		}
+		return path
+	}
+
+	base := writeContract("base.contract", `echo "@env.AUDIT_TOKEN" | wc -c
+`)
+	current := writeContract("current.contract", `echo "@env.AUDIT_TOKEN" | wc -c
+echo "@env.AUDIT_DB_PASSWORD" > /dev/null
+`)
+
+	stdout := runE2E(t, binPath, "secrets", "audit", current)
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n")[1:] {
+		fields := strings.Fields(line)
+		if !strings.HasPrefix(fields[0], "sigil:") {
+			t.Errorf("expected a DisplayID first, got %q", line)
+		}
+		// DisplayIDs vary per plan; rows are ordered by origin, then site
+		rows = append(rows, append([]string{}, fields[1:]...))
+	}
+	want := [][]string{
+		{"@env.AUDIT_DB_PASSWORD", "root/step-2/@shell[0]/params/command", "local", "redirect"},
+		{"@env.AUDIT_TOKEN", "root/step-1/@shell[0]/params/command", "local", "pipeline"},
+	}
+	if diff := cmp.Diff(want, rows); diff != "" {
+		t.Errorf("audit rows mismatch (-want +got):\n%s", diff)
+	}
+	if strings.Contains(stdout, "tok-5b1e9c77") || strings.Contains(stdout, "pw-0d4c2a18") {
+		t.Errorf("audit leaked a value: %q", stdout)
+	}
+
+	stdout, stderr, exitCode := runVersionCommand(t, binPath, "secrets", "audit", current, "--baseline", base, "--no-color")
+	if diff := cmp.Diff(1, exitCode); diff != "" {
+		t.Errorf("new flow exit code mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stdout, "+ @env.AUDIT_DB_PASSWORD -> @shell.command on local (redirect)\n") {
+		t.Errorf("expected new flow, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "1 new secret flow(s) since") {
+		t.Errorf("expected new flow error, got %q", stderr)
+	}
+
+	stdout = runE2E(t, binPath, "secrets", "audit", base, "--baseline", base)
+	if !strings.Contains(stdout, "No secret flow changes since baseline.") {
+		t.Errorf("expected no changes, got %q", stdout)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSecretsCommandLifecycle(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	dir := t.TempDir()
+	t.Setenv("SIGIL_SECRETS_FILE", filepath.Join(dir, "secrets.enc"))
+	t.Setenv("SIGIL_SECRETS_KEY_FILE", filepath.Join(dir, "store.key"))
+	t.Setenv("SIGIL_SECRETS_KEYRING", "")
+	t.Setenv("SIGIL_SECRETS_PASSPHRASE", "")
+
+	_, stderr, exitCode := runVersionCommand(t, binPath, "secrets", "list")
+	if diff := cmp.Diff(1, exitCode); diff != "" {
+		t.Fatalf("list without store exit code mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr, "no secret store") {
+		t.Errorf("expected missing store error, got %q", stderr)
+	}
+
+	runE2E(t, binPath, "secrets", "set", "tokens/deploy", "deploy-token-8f3a")
+	runE2E(t, binPath, "secrets", "set", "db/password", "correct-horse")
+
+	stdout := runE2E(t, binPath, "secrets", "list")
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n")[1:] {
+		names = append(names, strings.Fields(line)[0])
+	}
+	if diff := cmp.Diff([]string{"db/password", "tokens/deploy"}, names); diff != "" {
+		t.Errorf("list mismatch (-want +got):\n%s", diff)
+	}
+	if strings.Contains(stdout, "deploy-token-8f3a") {
+		t.Errorf("list leaked a value: %q", stdout)
+	}
+
+	if diff := cmp.Diff("deploy-token-8f3a", runE2E(t, binPath, "secrets", "get", "tokens/deploy")); diff != "" {
+		t.Errorf("get mismatch (-want +got):\n%s", diff)
+	}
+
+	// Plans show a DisplayID; execution uses the value but output is scrubbed
+	script := createE2ETestFile(t, `
+var token = @secrets.get(path="tokens/deploy")
+echo "token=@var.token"
+`)
+	plan := runE2E(t, binPath, "-f", script, "--dry-run", "--no-color")
+	if strings.Contains(plan, "deploy-token-8f3a") || !strings.Contains(plan, "sigil:") {
+		t.Errorf("plan should mask the stored secret, got %q", plan)
+	}
+	out := runE2E(t, binPath, "-f", script)
+	if strings.Contains(out, "deploy-token-8f3a") || !strings.HasPrefix(out, "token=sigil:") {
+		t.Errorf("output should scrub the stored secret, got %q", out)
+	}
+
+	t.Setenv("NEW_STORE_PASSPHRASE", "battery staple")
+	runE2E(t, binPath, "secrets", "rotate", "--to-passphrase-env", "NEW_STORE_PASSPHRASE")
+	_, stderr, exitCode = runVersionCommand(t, binPath, "secrets", "get", "tokens/deploy")
+	if exitCode == 0 || !strings.Contains(stderr, "encrypted with a passphrase key") {
+		t.Errorf("old key should no longer open the store (exit %d): %q", exitCode, stderr)
+	}
+
+	t.Setenv("SIGIL_SECRETS_KEY_FILE", "")
+	t.Setenv("SIGIL_SECRETS_PASSPHRASE", "battery staple")
+	runE2E(t, binPath, "secrets", "rm", "tokens/deploy")
+	_, stderr, exitCode = runVersionCommand(t, binPath, "secrets", "get", "tokens/deploy")
+	if exitCode == 0 || !strings.Contains(stderr, "secret not found") {
+		t.Errorf("get after rm should fail (exit %d): %q", exitCode, stderr)
+	}
+}
+
+func TestSecretsRotationAndExpiry_EndToEnd(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	dir := t.TempDir()
+	t.Setenv("SIGIL_SECRETS_FILE", filepath.Join(dir, "secrets.enc"))
+	t.Setenv("SIGIL_SECRETS_KEY_FILE", filepath.Join(dir, "store.key"))
+	t.Setenv("SIGIL_SECRETS_KEYRING", "")
+	t.Setenv("SIGIL_SECRETS_PASSPHRASE", "")
+
+	runE2E(t, binPath, "secrets", "set", "--expires-in", "2d", "tokens/deploy", "deploy-token-v1")
+	stdout := runE2E(t, binPath, "secrets", "list")
+	if fields := strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]); len(fields) != 4 || fields[1] != "1" || fields[3] == "-" {
+		t.Errorf("list should show version 1 with an expiry, got %q", stdout)
+	}
+
+	script := createE2ETestFile(t, `
+var token = @secrets.get(path="tokens/deploy")
+echo "token=@var.token"
+`)
+
+	// The warning goes to stderr, so the contract on stdout stays readable
+	contract, stderr := runE2EWithStderr(t, binPath, "-f", script, "--dry-run", "--resolve", "--no-color")
+	if !strings.Contains(stderr, "Warning: secret sigil:") || !strings.Contains(stderr, `(@secrets.get(path="tokens/deploy") version 1) expires in 2d`) {
+		t.Errorf("expected expiry warning, got stderr %q", stderr)
+	}
+	contractFile := filepath.Join(dir, "deploy.plan")
+	if err := os.WriteFile(contractFile, []byte(contract), 0o600); err != nil {
+		t.Fatalf("write contract: %v", err)
+	}
+
+	out := runE2E(t, binPath, "--plan", contractFile, "-f", script)
+	if !strings.HasPrefix(out, "token=sigil:") {
+		t.Errorf("unrotated contract should run, got %q", out)
+	}
+
+	runE2E(t, binPath, "secrets", "set", "tokens/deploy", "deploy-token-v2")
+	stdout, stderr, exitCode := runVersionCommand(t, binPath, "--plan", contractFile, "-f", script, "--no-color")
+	if diff := cmp.Diff(1, exitCode); diff != "" {
+		t.Fatalf("rotated contract exit code mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stdout, `rotated: @secrets.get(path="tokens/deploy") version 1 -> 2`) {
+		t.Errorf("diff should report the rotation, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "a secret was rotated since contract was created") {
+		t.Errorf("error should explain the rotation, got %q", stderr)
+	}
+	if strings.Contains(stderr, "expires in") {
+		t.Errorf("the new version has no expiry, got %q", stderr)
+	}
+}
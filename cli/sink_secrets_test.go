@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSinkSecrets_EndToEnd(t *testing.T) {
+	binPath := buildE2EBinary(t)
+	dir := t.TempDir()
+	t.Setenv("SINK_TOKEN", "sink-token-7f3e1a")
+
+	scrubbed := filepath.Join(dir, "report.txt")
+	passed := filepath.Join(dir, "kubeconfig")
+	appended := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(appended, []byte("started\n"), 0o644); err != nil {
+		t.Fatalf("failed to create existing sink: %v", err)
+	}
+	script := createE2ETestFile(t, fmt.Sprintf(`
+echo "token=@env.SINK_TOKEN" > %s
+echo "token=@env.SINK_TOKEN" > @file(%q, secrets="passthrough")
+echo "token=@env.SINK_TOKEN" >> %s
+`, scrubbed, passed, appended))
+
+	plan := runE2E(t, binPath, "-f", script, "--dry-run", "--no-color")
+	if !strings.Contains(plan, "secrets=passthrough") {
+		t.Errorf("plan should show the passthrough sink, got %q", plan)
+	}
+
+	runE2E(t, binPath, "-f", script)
+	data, err := os.ReadFile(scrubbed)
+	if err != nil {
+		t.Fatalf("failed to read scrubbed sink: %v", err)
+	}
+	if strings.Contains(string(data), "sink-token-7f3e1a") || !strings.HasPrefix(string(data), "token=sigil:") {
+		t.Errorf("redirect sink should be scrubbed, got %q", data)
+	}
+	// Appending to a file sigil did not create is scrubbed too; what the
+	// file already held is left alone
+	data, err = os.ReadFile(appended)
+	if err != nil {
+		t.Fatalf("failed to read appended sink: %v", err)
+	}
+	if strings.Contains(string(data), "sink-token-7f3e1a") || !strings.HasPrefix(string(data), "started\ntoken=sigil:") {
+		t.Errorf("appended sink should keep its content and be scrubbed, got %q", data)
+	}
+	data, err = os.ReadFile(passed)
+	if err != nil {
+		t.Fatalf("failed to read passthrough sink: %v", err)
+	}
+	if diff := cmp.Diff("token=sink-token-7f3e1a\n", string(data)); diff != "" {
+		t.Errorf("passthrough sink mismatch (-want +got):\n%s", diff)
+	}
+
+	failed := filepath.Join(dir, "artifact.txt")
+	script = createE2ETestFile(t, fmt.Sprintf(`echo "token=@env.SINK_TOKEN" > @file(%q, secrets="fail")
+`, failed))
+	stdout, stderr, exitCode := runVersionCommand(t, binPath, "-f", script, "--no-color")
+	if diff := cmp.Diff(1, exitCode); diff != "" {
+		t.Errorf("fail sink exit code mismatch (-want +got):\n%s", diff)
+	}
+	// Run output, including executor errors, is captured through the scrubber
+	if !strings.Contains(stdout+stderr, "output contains a secret (sink policy is fail)") {
+		t.Errorf("expected sink policy error, got stdout %q, stderr %q", stdout, stderr)
+	}
+	if data, _ := os.ReadFile(failed); strings.Contains(string(data), "sink-token-7f3e1a") {
+		t.Errorf("fail sink wrote the secret: %q", data)
+	}
+}
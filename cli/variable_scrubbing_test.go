@@ -22,7 +22,7 @@ func TestVariableScrubbing_EndToEnd(t *testing.T) {
 	opalFile := filepath.Join(tmpDir, "test.sgl")
 
 	secretValue := "my-secret-value"
-	source := `var SECRET = "my-secret-value"
+	source := `var SECRET secret = "my-secret-value"
 echo "The secret is: @var.SECRET"`
 
 	err := os.WriteFile(opalFile, []byte(source), 0o644)
@@ -54,7 +54,7 @@ echo "The secret is: @var.SECRET"`
 
 	// Run command (script mode - no command name)
 	cmd := &cobra.Command{}
-	exitCode, err := runCommand(cmd, "", opalFile, false, false, false, true, false, vlt, scrubber, &outputBuf)
+	exitCode, err := runCommand(cmd, "", opalFile, false, false, false, true, false, executionOptions{}, vlt, scrubber, &outputBuf)
 	if err != nil {
 		t.Fatalf("runCommand failed: %v", err)
 	}
@@ -89,9 +89,9 @@ func TestVariableScrubbing_MultipleVariables(t *testing.T) {
 	tmpDir := t.TempDir()
 	opalFile := filepath.Join(tmpDir, "multiple.sgl")
 
-	source := `var API_KEY = "sk-secret-123"
-var TOKEN = "token-456"
-var PASSWORD = "pass-789"
+	source := `var API_KEY secret = "sk-secret-123"
+var TOKEN secret = "token-456"
+var PASSWORD secret = "pass-789"
 echo "API: @var.API_KEY, Token: @var.TOKEN, Pass: @var.PASSWORD"`
 
 	err := os.WriteFile(opalFile, []byte(source), 0o644)
@@ -121,7 +121,7 @@ echo "API: @var.API_KEY, Token: @var.TOKEN, Pass: @var.PASSWORD"`
 	// Executor doesn't yet support DisplayID resolution, so we can't execute
 	cmd := &cobra.Command{}
 	dryRun := true
-	exitCode, err := runCommand(cmd, "", opalFile, dryRun, false, false, true, false, vlt, scrubber, &outputBuf)
+	exitCode, err := runCommand(cmd, "", opalFile, dryRun, false, false, true, false, executionOptions{}, vlt, scrubber, &outputBuf)
 	if err != nil {
 		t.Fatalf("runCommand failed: %v", err)
 	}
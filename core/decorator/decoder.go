@@ -341,6 +341,12 @@ func decodeIntoStruct(target reflect.Value, canonical map[string]any) error {
 			continue
 		}
 
+		// Plans carry int64; Go callers commonly pass int.
+		if isIntKind(field.Kind()) && isIntKind(refValue.Kind()) && !field.OverflowInt(refValue.Int()) {
+			field.SetInt(refValue.Int())
+			continue
+		}
+
 		if !refValue.Type().AssignableTo(field.Type()) {
 			return fmt.Errorf("cannot assign parameter %q (%T) to field %q (%s)", key, value, targetType.Field(index).Name, field.Type())
 		}
@@ -409,7 +415,10 @@ func isStrictInt(value any) bool {
 	if t == nil {
 		return false
 	}
-	kind := t.Kind()
+	return isIntKind(t.Kind())
+}
+
+func isIntKind(kind reflect.Kind) bool {
 	return kind >= reflect.Int && kind <= reflect.Int64
 }
 
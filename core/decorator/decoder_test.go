@@ -384,3 +384,35 @@ func TestDecodeIntoStructMissingField(t *testing.T) {
 		t.Errorf("error = %q, want %q", err.Error(), want)
 	}
 }
+
+func TestDecodeIntoStructConvertsIntWidths(t *testing.T) {
+	type dialConfig struct {
+		Port    int64 `decorator:"port"`
+		Retries int8  `decorator:"retries"`
+	}
+
+	schema := NewDescriptor("dial").
+		ParamInt("port", "Port").
+		Done().
+		ParamInt("retries", "Retries").
+		Done().
+		Build().Schema
+
+	cfg, _, err := DecodeInto[dialConfig](schema, nil, map[string]any{
+		"port":    2222,
+		"retries": int64(3),
+	})
+	if err != nil {
+		t.Fatalf("DecodeInto() error = %v", err)
+	}
+
+	want := dialConfig{Port: 2222, Retries: 3}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("decoded config mismatch (-want +got):\n%s", diff)
+	}
+
+	_, _, err = DecodeInto[dialConfig](schema, nil, map[string]any{"retries": 300})
+	if err == nil {
+		t.Fatal("DecodeInto() error = nil, want overflow error")
+	}
+}
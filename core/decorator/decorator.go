@@ -54,6 +54,10 @@ type Descriptor struct {
 
 	// Capabilities define execution constraints and properties
 	Capabilities Capabilities
+
+	// Sensitivity is the default level for values this decorator produces.
+	// Default: SensitivitySecret (safe default - scrub unless declared otherwise)
+	Sensitivity Sensitivity
 }
 
 // TransportScope defines where a decorator can be used.
@@ -219,6 +223,12 @@ func (b *DescriptorBuilder) Idempotent() *DescriptorBuilder {
 	return b
 }
 
+// Sensitivity sets the default sensitivity of produced values.
+func (b *DescriptorBuilder) Sensitivity(level Sensitivity) *DescriptorBuilder {
+	b.desc.Sensitivity = level
+	return b
+}
+
 // Block sets the parser-level block requirement in schema metadata.
 // This is not a runtime capability.
 func (b *DescriptorBuilder) Block(req BlockRequirement) *DescriptorBuilder {
@@ -0,0 +1,340 @@
+package decorator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerAPIVersion pins the Engine API version so responses keep a stable shape.
+	dockerAPIVersion    = "v1.41"
+	defaultDockerSocket = "/var/run/docker.sock"
+
+	dockerStreamStdin  = 0
+	dockerStreamStdout = 1
+	dockerStreamStderr = 2
+)
+
+// dockerExecPollInterval is how often exec inspection is retried while Docker
+// still reports the exec as running after its output stream has closed.
+var dockerExecPollInterval = 10 * time.Millisecond
+
+// dockerAPI is a minimal Docker Engine API client that talks HTTP over the
+// daemon's unix socket. The socket is dialed through the parent session's
+// network dialer, so a daemon behind @ssh.connect is reached the same way.
+type dockerAPI struct {
+	socket string
+	dialer NetworkDialer
+	client *http.Client
+}
+
+// dockerContainerInfo is the subset of GET /containers/{id}/json that sessions use.
+type dockerContainerInfo struct {
+	ID       string `json:"Id"`
+	Name     string `json:"Name"`
+	Platform string `json:"Platform"`
+	State    struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+	Config struct {
+		Env        []string `json:"Env"`
+		WorkingDir string   `json:"WorkingDir"`
+		User       string   `json:"User"`
+	} `json:"Config"`
+}
+
+// dockerExecConfig is the body of POST /containers/{id}/exec.
+type dockerExecConfig struct {
+	Cmd          []string `json:"Cmd"`
+	Env          []string `json:"Env,omitempty"`
+	User         string   `json:"User,omitempty"`
+	WorkingDir   string   `json:"WorkingDir,omitempty"`
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+	Tty          bool     `json:"Tty"`
+}
+
+// dockerExecInfo is the subset of GET /exec/{id}/json that sessions use.
+type dockerExecInfo struct {
+	Running  bool `json:"Running"`
+	ExitCode int  `json:"ExitCode"`
+}
+
+func newDockerAPI(socket string, dialer NetworkDialer) *dockerAPI {
+	api := &dockerAPI{socket: socket, dialer: dialer}
+	api.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return api.dial(ctx)
+			},
+			DisableCompression: true,
+		},
+	}
+	return api
+}
+
+// dockerSocketPath picks the Engine API socket: the explicit param, then a
+// unix:// DOCKER_HOST, then the daemon's default location.
+func dockerSocketPath(params map[string]any) (string, error) {
+	if socket, ok := params["socket"].(string); ok && socket != "" {
+		return strings.TrimPrefix(socket, "unix://"), nil
+	}
+
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		return defaultDockerSocket, nil
+	}
+	if !strings.HasPrefix(host, "unix://") {
+		return "", fmt.Errorf("DOCKER_HOST %q is not supported: @docker.exec only talks to the Engine API over a unix socket (set socket=... to override)", host)
+	}
+	return strings.TrimPrefix(host, "unix://"), nil
+}
+
+func (a *dockerAPI) dial(ctx context.Context) (net.Conn, error) {
+	dialCtx, cancel := withDefaultDialDeadline(ctx)
+	defer cancel()
+	return a.dialer.DialContext(dialCtx, "unix", a.socket)
+}
+
+func (a *dockerAPI) endpoint(path string, query url.Values) string {
+	u := url.URL{Scheme: "http", Host: "docker", Path: "/" + dockerAPIVersion + path}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// do sends a request and returns the response when the status is 2xx.
+// Error responses are decoded from Docker's {"message": ...} body.
+func (a *dockerAPI) do(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.endpoint(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, dockerResponseError(resp)
+	}
+	return resp, nil
+}
+
+func (a *dockerAPI) doJSON(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := a.do(ctx, method, path, nil, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// dockerAPIError is an error status returned by the Engine API.
+type dockerAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *dockerAPIError) Error() string {
+	return fmt.Sprintf("docker API %d: %s", e.StatusCode, e.Message)
+}
+
+func dockerResponseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var payload struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+		message = payload.Message
+	}
+	if message == "" {
+		message = resp.Status
+	}
+	return &dockerAPIError{StatusCode: resp.StatusCode, Message: message}
+}
+
+func isDockerNotFound(err error) bool {
+	var apiErr *dockerAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (a *dockerAPI) inspectContainer(ctx context.Context, container string) (dockerContainerInfo, error) {
+	var info dockerContainerInfo
+	err := a.doJSON(ctx, http.MethodGet, "/containers/"+url.PathEscape(container)+"/json", nil, &info)
+	return info, err
+}
+
+func (a *dockerAPI) createExec(ctx context.Context, container string, config dockerExecConfig) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := a.doJSON(ctx, http.MethodPost, "/containers/"+url.PathEscape(container)+"/exec", config, &created); err != nil {
+		return "", err
+	}
+	if created.ID == "" {
+		return "", errors.New("docker API returned an empty exec id")
+	}
+	return created.ID, nil
+}
+
+func (a *dockerAPI) inspectExec(ctx context.Context, execID string) (dockerExecInfo, error) {
+	var info dockerExecInfo
+	err := a.doJSON(ctx, http.MethodGet, "/exec/"+url.PathEscape(execID)+"/json", nil, &info)
+	return info, err
+}
+
+// waitExec returns the exit code once Docker reports the exec as finished.
+// The output stream closes slightly before the daemon records the exit code.
+func (a *dockerAPI) waitExec(ctx context.Context, execID string) (int, error) {
+	for {
+		info, err := a.inspectExec(ctx, execID)
+		if err != nil {
+			return 0, err
+		}
+		if !info.Running {
+			return info.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(dockerExecPollInterval):
+		}
+	}
+}
+
+// startExec starts an exec and hijacks the connection, as the Engine API
+// requires for attached streams. The returned reader yields the multiplexed
+// stdout/stderr stream; writes go to the process stdin.
+func (a *dockerAPI) startExec(ctx context.Context, execID string) (net.Conn, *bufio.Reader, error) {
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := []byte(`{"Detach":false,"Tty":false}`)
+	req, err := http.NewRequest(http.MethodPost, a.endpoint("/exec/"+url.PathEscape(execID)+"/start", nil), bytes.NewReader(body))
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		err := dockerResponseError(resp)
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, reader, nil
+}
+
+func (a *dockerAPI) putArchive(ctx context.Context, container, dir string, archive io.Reader) error {
+	query := url.Values{"path": {dir}}
+	resp, err := a.do(ctx, http.MethodPut, "/containers/"+url.PathEscape(container)+"/archive", query, "application/x-tar", archive)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (a *dockerAPI) getArchive(ctx context.Context, container, path string) (io.ReadCloser, error) {
+	query := url.Values{"path": {path}}
+	resp, err := a.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(container)+"/archive", query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// demuxDockerStream splits Docker's multiplexed attach stream. Each frame is
+// an 8-byte header (stream type, three zero bytes, big-endian payload size)
+// followed by the payload.
+func demuxDockerStream(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		var dst io.Writer
+		switch header[0] {
+		case dockerStreamStdin, dockerStreamStdout:
+			dst = stdout
+		case dockerStreamStderr:
+			dst = stderr
+		default:
+			return fmt.Errorf("unexpected docker stream type %d", header[0])
+		}
+
+		if _, err := io.CopyN(dst, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// writeDockerFrame writes one multiplexed stream frame.
+func writeDockerFrame(w io.Writer, stream byte, payload []byte) error {
+	header := [8]byte{stream}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
@@ -0,0 +1,526 @@
+package decorator
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/invariant"
+)
+
+// dockerShortIDLength matches the container ID length shown by the docker CLI.
+const dockerShortIDLength = 12
+
+// DockerSession implements Session for command execution inside a running
+// Docker container through the Engine API (docker exec / docker cp).
+//
+// The container environment is snapshotted when the session is opened.
+// WithEnv and WithWorkdir return copies that share the API connection; only
+// the session returned by Open closes it.
+type DockerSession struct {
+	api       *dockerAPI
+	container string // Full container ID
+	env       map[string]string
+	delta     map[string]string // Variables passed to each exec on top of the container env
+	cwd       string
+	user      string
+	platform  string
+	root      *DockerSession // nil for the session returned by Open
+}
+
+// NewDockerSession opens a session for a running container on the local
+// Docker daemon.
+func NewDockerSession(params map[string]any) (*DockerSession, error) {
+	return openDockerSession(NewLocalSession(), params)
+}
+
+func openDockerSession(parent Session, params map[string]any) (*DockerSession, error) {
+	container, _ := params["container"].(string)
+	if strings.TrimSpace(container) == "" {
+		return nil, TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: "@docker.exec requires a container name or ID",
+		}
+	}
+
+	socket, err := dockerSocketPath(params)
+	if err != nil {
+		return nil, TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: err.Error(),
+		}
+	}
+
+	dialer, err := getNetworkDialer(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultDialDeadline(context.Background())
+	defer cancel()
+
+	api := newDockerAPI(socket, dialer)
+	info, err := api.inspectContainer(ctx, container)
+	if err != nil {
+		message := fmt.Sprintf("failed to inspect container %q via %s", container, socket)
+		if isDockerNotFound(err) {
+			message = fmt.Sprintf("container %q not found", container)
+		}
+		return nil, TransportError{
+			Code:      TransportErrorCodeConnect,
+			Message:   message,
+			Retryable: !isDockerNotFound(err),
+			Cause:     err,
+		}
+	}
+	if !info.State.Running {
+		return nil, TransportError{
+			Code:    TransportErrorCodeConnect,
+			Message: fmt.Sprintf("container %q is not running", container),
+		}
+	}
+
+	cwd := info.Config.WorkingDir
+	if workdir, ok := params["workdir"].(string); ok && workdir != "" {
+		cwd = workdir
+	}
+	if cwd == "" {
+		cwd = "/"
+	}
+
+	user, _ := params["user"].(string)
+	delta := sshEnvDelta(params)
+	if delta == nil {
+		delta = make(map[string]string)
+	}
+
+	return &DockerSession{
+		api:       api,
+		container: info.ID,
+		env:       parseEnv(strings.Join(info.Config.Env, "\n")),
+		delta:     delta,
+		cwd:       cwd,
+		user:      user,
+		platform:  normalizePlatform(info.Platform),
+	}, nil
+}
+
+// Run executes argv in the container with docker exec.
+func (s *DockerSession) Run(ctx context.Context, argv []string, opts RunOpts) (Result, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(len(argv) > 0, "argv cannot be empty")
+
+	if ctx.Err() != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "command context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	workdir := s.cwd
+	if opts.Dir != "" {
+		workdir = s.resolvePath(opts.Dir)
+	}
+
+	execID, err := s.api.createExec(ctx, s.container, dockerExecConfig{
+		Cmd:          argv,
+		Env:          dockerEnvList(s.delta),
+		User:         s.user,
+		WorkingDir:   workdir,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeExecute,
+			Message:   "failed to create docker exec",
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+
+	conn, stream, err := s.api.startExec(ctx, execID)
+	if err != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeSession,
+			Message:   "failed to start docker exec",
+			Retryable: true,
+			Cause:     err,
+		}
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if opts.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(conn, opts.Stdin)
+			if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+				_ = closer.CloseWrite()
+			}
+		}()
+	}
+
+	var stdout, stderr bytes.Buffer
+	stdoutWriter := io.Writer(&stdout)
+	if opts.Stdout != nil {
+		stdoutWriter = opts.Stdout
+	}
+	stderrWriter := io.Writer(&stderr)
+	if opts.Stderr != nil {
+		stderrWriter = opts.Stderr
+	}
+
+	streamErr := demuxDockerStream(stream, stdoutWriter, stderrWriter)
+	if ctx.Err() != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "command context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+	if streamErr != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read docker exec output",
+			Retryable: false,
+			Cause:     streamErr,
+		}
+	}
+
+	exitCode, err := s.api.waitExec(ctx, execID)
+	if err != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeExecute,
+			Message:   "failed to read docker exec exit code",
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+
+	return Result{
+		ExitCode: exitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, nil
+}
+
+// Put writes data to a file in the container by uploading a one-file tar
+// archive into its parent directory, as docker cp does.
+func (s *DockerSession) Put(ctx context.Context, data []byte, filePath string, mode fs.FileMode) error {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "put context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	dir, name := path.Split(s.resolvePath(filePath))
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	if err := s.api.putArchive(ctx, s.container, dir, &archive); err != nil {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to write container file",
+			Retryable: !isDockerNotFound(err),
+			Cause:     err,
+		}
+	}
+	return nil
+}
+
+// Get reads a file from the container by downloading it as a tar archive.
+func (s *DockerSession) Get(ctx context.Context, filePath string) ([]byte, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "get context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	body, err := s.api.getArchive(ctx, s.container, s.resolvePath(filePath))
+	if err != nil {
+		cause := err
+		if isDockerNotFound(err) {
+			cause = errors.Join(fs.ErrNotExist, err)
+		}
+		return nil, TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read container file",
+			Retryable: !isDockerNotFound(err),
+			Cause:     cause,
+		}
+	}
+	defer func() { _ = body.Close() }()
+
+	tr := tar.NewReader(body)
+	header, err := tr.Next()
+	if err == nil && header.Typeflag != tar.TypeReg {
+		err = fmt.Errorf("%s is not a regular file", filePath)
+	}
+	if err != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read container file",
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read container file",
+			Retryable: true,
+			Cause:     err,
+		}
+	}
+	return data, nil
+}
+
+// PutStream writes src to a file in the container. The upload is a tar archive
+// whose header needs the file size, so src is read into memory first.
+func (s *DockerSession) PutStream(ctx context.Context, src io.Reader, filePath string, mode fs.FileMode) error {
+	return putStreamBuffered(ctx, s, src, filePath, mode)
+}
+
+// GetStream copies a file in the container to dst. The file is read into memory
+// first, as with Get.
+func (s *DockerSession) GetStream(ctx context.Context, filePath string, dst io.Writer) error {
+	return getStreamBuffered(ctx, s, filePath, dst)
+}
+
+// Env returns the container environment captured at open time with this
+// session's delta applied.
+func (s *DockerSession) Env() map[string]string {
+	env := make(map[string]string, len(s.env)+len(s.delta))
+	for k, v := range s.env {
+		env[k] = v
+	}
+	for k, v := range s.delta {
+		env[k] = v
+	}
+	return env
+}
+
+// WithEnv returns a new Session with environment delta applied (copy-on-write).
+func (s *DockerSession) WithEnv(delta map[string]string) Session {
+	merged := make(map[string]string, len(s.delta)+len(delta))
+	for k, v := range s.delta {
+		merged[k] = v
+	}
+	for k, v := range delta {
+		merged[k] = v
+	}
+
+	derived := s.derive()
+	derived.delta = merged
+	return derived
+}
+
+// WithWorkdir returns a new Session with working directory set (copy-on-write).
+func (s *DockerSession) WithWorkdir(dir string) Session {
+	invariant.Precondition(dir != "", "dir cannot be empty")
+
+	derived := s.derive()
+	derived.cwd = s.resolvePath(dir)
+	return derived
+}
+
+// Cwd returns the working directory used for commands in the container.
+func (s *DockerSession) Cwd() string {
+	return s.cwd
+}
+
+// ID returns the session identifier for Docker sessions.
+// Format: "docker:<short container id>".
+func (s *DockerSession) ID() string {
+	id := s.container
+	if len(id) > dockerShortIDLength {
+		id = id[:dockerShortIDLength]
+	}
+	return "docker:" + id
+}
+
+// TransportScope returns the transport scope for Docker sessions.
+func (s *DockerSession) TransportScope() TransportScope {
+	return TransportScopeDocker
+}
+
+// Platform returns the container OS reported by the daemon.
+func (s *DockerSession) Platform() string {
+	return s.platform
+}
+
+// Close releases idle API connections. Derived sessions share the
+// connection with the session they came from, so closing them is a no-op.
+func (s *DockerSession) Close() error {
+	if s.root == nil {
+		s.api.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *DockerSession) UnwrapSession() Session {
+	if s.root == nil {
+		return nil
+	}
+	return s.root
+}
+
+func (s *DockerSession) derive() *DockerSession {
+	root := s.root
+	if root == nil {
+		root = s
+	}
+	return &DockerSession{
+		api:       s.api,
+		container: s.container,
+		env:       s.env,
+		delta:     s.delta,
+		cwd:       s.cwd,
+		user:      s.user,
+		platform:  s.platform,
+		root:      root,
+	}
+}
+
+// resolvePath makes p absolute against the session working directory.
+// Container paths always use forward slashes.
+func (s *DockerSession) resolvePath(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.cwd, p)
+}
+
+func dockerEnvList(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// DockerTransport implements Transport for running commands inside a Docker container.
+type DockerTransport struct{}
+
+func init() {
+	if err := Register("docker.exec", &DockerTransport{}); err != nil {
+		panic(fmt.Sprintf("failed to register @docker.exec decorator: %v", err))
+	}
+}
+
+func (t *DockerTransport) Descriptor() Descriptor {
+	return NewDescriptor("docker.exec").
+		Summary("Run block inside a running Docker container").
+		Roles(RoleBoundary).
+		ParamString("container", "Container name or ID").
+		Required().
+		Examples("app-prod", "3f4e8a9c1b2d").
+		Done().
+		ParamString("user", "User to run commands as (default: the container's user)").
+		Examples("root", "1000:1000").
+		Done().
+		ParamString("workdir", "Working directory inside the container (default: the image WORKDIR)").
+		Examples("/app").
+		Done().
+		ParamString("socket", "Docker Engine API unix socket (default: DOCKER_HOST or /var/run/docker.sock)").
+		Examples("/var/run/docker.sock").
+		Done().
+		ParamObject("env", "Environment variables added to every command").
+		AllowAdditionalProperties().
+		Done().
+		Block(BlockRequired).
+		Build()
+}
+
+func (t *DockerTransport) Capabilities() TransportCaps {
+	return TransportCapFilesystem | TransportCapEnvironment
+}
+
+func (t *DockerTransport) Open(parent Session, params map[string]any) (Session, error) {
+	return openDockerSession(parent, params)
+}
+
+func (t *DockerTransport) Wrap(next ExecNode, params map[string]any) ExecNode {
+	return &dockerTransportNode{
+		next:   next,
+		params: params,
+	}
+}
+
+func (t *DockerTransport) MaterializeSession() bool {
+	return true
+}
+
+func (t *DockerTransport) IsolationContext() IsolationContext {
+	return nil
+}
+
+type dockerTransportNode struct {
+	next   ExecNode
+	params map[string]any
+}
+
+func (n *dockerTransportNode) Execute(ctx ExecContext) (Result, error) {
+	if n.next == nil {
+		return Result{ExitCode: ExitSuccess}, nil
+	}
+
+	// The executor already opened the container session for this transport.
+	if ctx.Session != nil && ctx.Session.TransportScope() == TransportScopeDocker && !strings.HasPrefix(ctx.Session.ID(), "docker:") {
+		return n.next.Execute(ctx)
+	}
+
+	session, err := openDockerSession(ctx.Session, n.params)
+	if err != nil {
+		return Result{ExitCode: ExitFailure}, err
+	}
+	defer func() { _ = session.Close() }()
+
+	return n.next.Execute(ctx.WithSession(session))
+}
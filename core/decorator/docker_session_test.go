@@ -0,0 +1,306 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const dockerTestContainerID = "3f4e8a9c1b2d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+
+func startDockerTestServer(t *testing.T) (*DockerTestServer, string) {
+	t.Helper()
+	workdir := t.TempDir()
+	server := StartDockerTestServer(t,
+		DockerTestContainer{
+			ID:         dockerTestContainerID,
+			Name:       "app",
+			Env:        []string{"PATH=" + os.Getenv("PATH"), "APP_ENV=container"},
+			WorkingDir: workdir,
+			Platform:   "linux",
+			Running:    true,
+		},
+		DockerTestContainer{ID: "stopped0000000", Name: "stopped"},
+	)
+	return server, workdir
+}
+
+func openTestDockerSession(t *testing.T, server *DockerTestServer, params map[string]any) *DockerSession {
+	t.Helper()
+	merged := map[string]any{"container": "app", "socket": server.Socket}
+	for k, v := range params {
+		merged[k] = v
+	}
+	session, err := NewDockerSession(merged)
+	if err != nil {
+		t.Fatalf("open docker session: %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+type execNodeFunc func(ctx ExecContext) (Result, error)
+
+func (f execNodeFunc) Execute(ctx ExecContext) (Result, error) { return f(ctx) }
+
+func TestDockerSessionRunSeparatesStreamsAndExitCode(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, nil)
+
+	result, err := session.Run(context.Background(), []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("exit code: got %d, want 3", result.ExitCode)
+	}
+	if diff := cmp.Diff("out\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("err\n", string(result.Stderr)); diff != "" {
+		t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerSessionRunStreamsStdin(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, nil)
+
+	var stdout bytes.Buffer
+	result, err := session.Run(context.Background(), []string{"tr", "a-z", "A-Z"}, RunOpts{
+		Stdin:  strings.NewReader("hello container\n"),
+		Stdout: &stdout,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("run: exit=%d err=%v", result.ExitCode, err)
+	}
+	if diff := cmp.Diff("HELLO CONTAINER\n", stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerSessionEnvSnapshotAndDelta(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, map[string]any{"env": map[string]any{"REGION": "eu"}})
+
+	if got := session.Env()["APP_ENV"]; got != "container" {
+		t.Errorf("container env snapshot: APP_ENV = %q, want %q", got, "container")
+	}
+	t.Setenv("SIGIL_HOST_ONLY", "1")
+	if _, ok := session.Env()["SIGIL_HOST_ONLY"]; ok {
+		t.Error("host environment must not leak into container env")
+	}
+
+	derived := session.WithEnv(map[string]string{"APP_ENV": "override"})
+	result, err := derived.Run(context.Background(), []string{"sh", "-c", "echo $APP_ENV $REGION"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if diff := cmp.Diff("override eu\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"APP_ENV=override", "REGION=eu"}, server.LastExecEnv()); diff != "" {
+		t.Errorf("exec env mismatch (-want +got):\n%s", diff)
+	}
+	if got := session.Env()["APP_ENV"]; got != "container" {
+		t.Errorf("WithEnv must not modify the parent session, got APP_ENV=%q", got)
+	}
+}
+
+func TestDockerSessionWorkdirAndUser(t *testing.T) {
+	server, workdir := startDockerTestServer(t)
+	sub := filepath.Join(workdir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	session := openTestDockerSession(t, server, map[string]any{"user": "1000:1000"})
+	if session.Cwd() != workdir {
+		t.Errorf("default cwd: got %q, want image workdir %q", session.Cwd(), workdir)
+	}
+
+	result, err := session.WithWorkdir("sub").Run(context.Background(), []string{"pwd"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if diff := cmp.Diff(sub+"\n", string(result.Stdout)); diff != "" {
+		t.Errorf("pwd mismatch (-want +got):\n%s", diff)
+	}
+	if server.LastExecUser() != "1000:1000" {
+		t.Errorf("exec user: got %q, want %q", server.LastExecUser(), "1000:1000")
+	}
+
+	explicit := openTestDockerSession(t, server, map[string]any{"workdir": sub})
+	if explicit.Cwd() != sub {
+		t.Errorf("workdir param: got %q, want %q", explicit.Cwd(), sub)
+	}
+}
+
+func TestDockerSessionPutGet(t *testing.T) {
+	server, workdir := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, nil)
+	ctx := context.Background()
+
+	if err := session.Put(ctx, []byte("#!/bin/sh\necho hi\n"), "deploy.sh", 0o750); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(workdir, "deploy.sh"))
+	if err != nil {
+		t.Fatalf("stat uploaded file: %v", err)
+	}
+	if info.Mode().Perm() != 0o750 {
+		t.Errorf("mode: got %o, want 750", info.Mode().Perm())
+	}
+
+	data, err := session.Get(ctx, filepath.Join(workdir, "deploy.sh"))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if diff := cmp.Diff("#!/bin/sh\necho hi\n", string(data)); diff != "" {
+		t.Errorf("content mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = session.Get(ctx, "missing.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: expected fs.ErrNotExist, got %v", err)
+	}
+
+	if _, err := session.Get(ctx, workdir); err == nil || !strings.Contains(err.Error(), "failed to read container file") {
+		t.Errorf("directory: expected read failure, got %v", err)
+	}
+}
+
+func TestDockerSessionIdentity(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, nil)
+
+	if diff := cmp.Diff("docker:3f4e8a9c1b2d", session.ID()); diff != "" {
+		t.Errorf("ID mismatch (-want +got):\n%s", diff)
+	}
+	if session.TransportScope() != TransportScopeDocker {
+		t.Errorf("scope: got %v, want Docker", session.TransportScope())
+	}
+	if session.Platform() != "linux" {
+		t.Errorf("platform: got %q, want linux", session.Platform())
+	}
+
+	derived := session.WithEnv(map[string]string{"A": "1"})
+	if derived.ID() != session.ID() {
+		t.Errorf("derived ID: got %q, want %q", derived.ID(), session.ID())
+	}
+	if err := derived.Close(); err != nil {
+		t.Fatalf("close derived: %v", err)
+	}
+	if _, err := session.Run(context.Background(), []string{"true"}, RunOpts{}); err != nil {
+		t.Errorf("closing a derived session must not close the parent: %v", err)
+	}
+}
+
+func TestDockerSessionOpenErrors(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{name: "missing container", params: map[string]any{"socket": server.Socket}, want: "requires a container"},
+		{name: "unknown container", params: map[string]any{"container": "nope", "socket": server.Socket}, want: `container "nope" not found`},
+		{name: "stopped container", params: map[string]any{"container": "stopped", "socket": server.Socket}, want: `container "stopped" is not running`},
+		{name: "no daemon", params: map[string]any{"container": "app", "socket": filepath.Join(t.TempDir(), "none.sock")}, want: "failed to inspect container"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDockerSession(tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDockerSocketPathFromDockerHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix:///run/user/1000/docker.sock")
+	socket, err := dockerSocketPath(nil)
+	if err != nil || socket != "/run/user/1000/docker.sock" {
+		t.Errorf("unix DOCKER_HOST: got %q, %v", socket, err)
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.5:2376")
+	if _, err := dockerSocketPath(nil); err == nil || !strings.Contains(err.Error(), "unix socket") {
+		t.Errorf("tcp DOCKER_HOST: expected unsupported error, got %v", err)
+	}
+
+	socket, err = dockerSocketPath(map[string]any{"socket": "unix:///tmp/d.sock"})
+	if err != nil || socket != "/tmp/d.sock" {
+		t.Errorf("socket param must win: got %q, %v", socket, err)
+	}
+}
+
+func TestDockerSessionRunCancellation(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+	session := openTestDockerSession(t, server, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := session.Run(ctx, []string{"sleep", "2"}, RunOpts{})
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Code != TransportErrorCodeContext {
+		t.Fatalf("expected context transport error, got %v", err)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("exit code: got %d, want -1", result.ExitCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took %s", elapsed)
+	}
+}
+
+func TestDockerTransportWrapRunsBlockInContainer(t *testing.T) {
+	server, _ := startDockerTestServer(t)
+
+	var stdout bytes.Buffer
+	node := (&DockerTransport{}).Wrap(execNodeFunc(func(ctx ExecContext) (Result, error) {
+		if ctx.Session.TransportScope() != TransportScopeDocker {
+			t.Errorf("block scope: got %v, want Docker", ctx.Session.TransportScope())
+		}
+		return ctx.Session.Run(ctx.Context, []string{"sh", "-c", "echo $APP_ENV"}, RunOpts{Stdout: ctx.Stdout})
+	}), map[string]any{"container": "app", "socket": server.Socket})
+
+	result, err := node.Execute(ExecContext{
+		Context: context.Background(),
+		Session: NewLocalSession(),
+		Stdout:  &stdout,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("execute: exit=%d err=%v", result.ExitCode, err)
+	}
+	if diff := cmp.Diff("container\n", stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerTransportRegistered(t *testing.T) {
+	transport, ok, _ := Global().GetTransport("docker.exec")
+	if !ok {
+		t.Fatal("docker.exec must be registered as a transport")
+	}
+	caps := transport.Capabilities()
+	if !caps.Has(TransportCapFilesystem) || !caps.Has(TransportCapEnvironment) || caps.Has(TransportCapNetwork) {
+		t.Errorf("unexpected capabilities: %b", caps)
+	}
+	if !transport.MaterializeSession() {
+		t.Error("docker.exec must materialize its own session")
+	}
+}
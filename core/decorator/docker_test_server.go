@@ -0,0 +1,341 @@
+package decorator
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// DockerTestContainer describes a container served by DockerTestServer.
+// The fake container shares the host filesystem: exec working directories
+// and archive paths are host paths.
+type DockerTestContainer struct {
+	ID         string
+	Name       string
+	Env        []string
+	WorkingDir string
+	Platform   string
+	Running    bool
+}
+
+// DockerTestServer is a fake Docker Engine API served on a unix socket.
+// Execs run as local processes so transports can be tested without a daemon.
+type DockerTestServer struct {
+	Socket string
+
+	listener net.Listener
+	server   *http.Server
+	dir      string
+
+	mu         sync.Mutex
+	containers map[string]*DockerTestContainer
+	execs      map[string]*dockerTestExec
+	lastExec   dockerExecConfig
+	nextExecID int
+}
+
+type dockerTestExec struct {
+	container *DockerTestContainer
+	config    dockerExecConfig
+	running   bool
+	exitCode  int
+}
+
+// StartDockerTestServer starts a fake Engine API on a temporary unix socket.
+// The server is stopped when the test finishes.
+func StartDockerTestServer(t *testing.T, containers ...DockerTestContainer) *DockerTestServer {
+	t.Helper()
+
+	// Unix socket paths are length-limited, so avoid the long t.TempDir names.
+	dir, err := os.MkdirTemp("", "sigil-docker")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		t.Skip("unix sockets not available:", err)
+		return nil
+	}
+
+	s := &DockerTestServer{
+		Socket:     socket,
+		listener:   listener,
+		dir:        dir,
+		containers: make(map[string]*DockerTestContainer),
+		execs:      make(map[string]*dockerTestExec),
+	}
+	for i := range containers {
+		c := containers[i]
+		s.containers[c.ID] = &c
+		if c.Name != "" {
+			s.containers[c.Name] = &c
+		}
+	}
+
+	mux := http.NewServeMux()
+	prefix := "/" + dockerAPIVersion
+	mux.HandleFunc("GET "+prefix+"/containers/{id}/json", s.handleInspectContainer)
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/exec", s.handleCreateExec)
+	mux.HandleFunc("POST "+prefix+"/exec/{id}/start", s.handleStartExec)
+	mux.HandleFunc("GET "+prefix+"/exec/{id}/json", s.handleInspectExec)
+	mux.HandleFunc("PUT "+prefix+"/containers/{id}/archive", s.handlePutArchive)
+	mux.HandleFunc("GET "+prefix+"/containers/{id}/archive", s.handleGetArchive)
+
+	s.server = &http.Server{Handler: mux}
+	go func() { _ = s.server.Serve(listener) }()
+	t.Cleanup(s.Stop)
+
+	return s
+}
+
+// Stop shuts the server down and removes its socket.
+func (s *DockerTestServer) Stop() {
+	_ = s.server.Close()
+	_ = os.RemoveAll(s.dir)
+}
+
+// LastExecUser returns the user requested by the most recent exec.
+func (s *DockerTestServer) LastExecUser() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastExec.User
+}
+
+// LastExecEnv returns the extra environment passed to the most recent exec.
+func (s *DockerTestServer) LastExecEnv() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lastExec.Env...)
+}
+
+func (s *DockerTestServer) container(w http.ResponseWriter, r *http.Request) (*DockerTestContainer, bool) {
+	s.mu.Lock()
+	c, ok := s.containers[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		dockerTestError(w, http.StatusNotFound, "No such container: "+r.PathValue("id"))
+	}
+	return c, ok
+}
+
+func (s *DockerTestServer) handleInspectContainer(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.container(w, r)
+	if !ok {
+		return
+	}
+
+	var info dockerContainerInfo
+	info.ID = c.ID
+	info.Name = "/" + c.Name
+	info.Platform = c.Platform
+	info.State.Running = c.Running
+	info.Config.Env = c.Env
+	info.Config.WorkingDir = c.WorkingDir
+	dockerTestJSON(w, http.StatusOK, info)
+}
+
+func (s *DockerTestServer) handleCreateExec(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.container(w, r)
+	if !ok {
+		return
+	}
+	if !c.Running {
+		dockerTestError(w, http.StatusConflict, "Container "+c.ID+" is not running")
+		return
+	}
+
+	var config dockerExecConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		dockerTestError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.nextExecID++
+	id := fmt.Sprintf("exec-%d", s.nextExecID)
+	s.execs[id] = &dockerTestExec{container: c, config: config}
+	s.lastExec = config
+	s.mu.Unlock()
+
+	dockerTestJSON(w, http.StatusCreated, map[string]string{"Id": id})
+}
+
+func (s *DockerTestServer) handleStartExec(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	e, ok := s.execs[r.PathValue("id")]
+	if ok {
+		e.running = true
+	}
+	s.mu.Unlock()
+	if !ok {
+		dockerTestError(w, http.StatusNotFound, "No such exec instance")
+		return
+	}
+
+	// Drain the start options so they are not mistaken for exec stdin.
+	_, _ = io.Copy(io.Discard, r.Body)
+
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		dockerTestError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, _ = io.WriteString(conn, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+
+	cmd := exec.Command(e.config.Cmd[0], e.config.Cmd[1:]...)
+	cmd.Dir = e.config.WorkingDir
+	cmd.Env = append(append([]string{}, e.container.Env...), e.config.Env...)
+	if e.config.AttachStdin {
+		cmd.Stdin = buf
+	}
+	out := &dockerTestFrameWriter{w: conn}
+	cmd.Stdout = out.stream(dockerStreamStdout)
+	cmd.Stderr = out.stream(dockerStreamStderr)
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			exitCode = 126
+			_ = out.write(dockerStreamStderr, []byte(err.Error()+"\n"))
+		}
+	}
+
+	s.mu.Lock()
+	e.running = false
+	e.exitCode = exitCode
+	s.mu.Unlock()
+}
+
+func (s *DockerTestServer) handleInspectExec(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	e, ok := s.execs[r.PathValue("id")]
+	var info dockerExecInfo
+	if ok {
+		info = dockerExecInfo{Running: e.running, ExitCode: e.exitCode}
+	}
+	s.mu.Unlock()
+	if !ok {
+		dockerTestError(w, http.StatusNotFound, "No such exec instance")
+		return
+	}
+	dockerTestJSON(w, http.StatusOK, info)
+}
+
+func (s *DockerTestServer) handlePutArchive(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.container(w, r); !ok {
+		return
+	}
+	dir := r.URL.Query().Get("path")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dockerTestError(w, http.StatusNotFound, "Could not find the file "+dir+" in container")
+		return
+	}
+
+	tr := tar.NewReader(r.Body)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			dockerTestError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		target := filepath.Join(dir, filepath.Clean("/"+header.Name))
+		data, err := io.ReadAll(tr)
+		if err == nil {
+			err = os.WriteFile(target, data, os.FileMode(header.Mode).Perm())
+		}
+		if err == nil {
+			err = os.Chmod(target, os.FileMode(header.Mode).Perm())
+		}
+		if err != nil {
+			dockerTestError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerTestServer) handleGetArchive(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.container(w, r); !ok {
+		return
+	}
+	path := r.URL.Query().Get("path")
+	info, err := os.Stat(path)
+	if err != nil {
+		dockerTestError(w, http.StatusNotFound, "Could not find the file "+path+" in container")
+		return
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		dockerTestError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-tar")
+	tw := tar.NewWriter(w)
+	_ = tw.WriteHeader(header)
+	if info.Mode().IsRegular() {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			_, _ = tw.Write(data)
+		}
+	}
+	_ = tw.Close()
+}
+
+// dockerTestFrameWriter serializes multiplexed frames from stdout and stderr.
+type dockerTestFrameWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (f *dockerTestFrameWriter) write(stream byte, p []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return writeDockerFrame(f.w, stream, p)
+}
+
+func (f *dockerTestFrameWriter) stream(stream byte) io.Writer {
+	return dockerTestStream{frames: f, stream: stream}
+}
+
+type dockerTestStream struct {
+	frames *dockerTestFrameWriter
+	stream byte
+}
+
+func (s dockerTestStream) Write(p []byte) (int, error) {
+	if err := s.frames.write(s.stream, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func dockerTestJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func dockerTestError(w http.ResponseWriter, status int, message string) {
+	dockerTestJSON(w, status, map[string]string{"message": strings.TrimSpace(message)})
+}
@@ -72,3 +72,17 @@ type IOFactory interface {
 	// WithParams creates a new IO instance configured with the given parameters.
 	WithParams(params map[string]any) IO
 }
+
+// SinkSecrets is a sink's policy for secrets in the output redirected to it.
+// Sinks that accept a policy declare it as their SinkSecretsParam parameter,
+// so it is recorded in the plan; the executor enforces it.
+type SinkSecrets string
+
+const (
+	SinkSecretsScrub       SinkSecrets = "scrub"       // Replace secrets with their DisplayIDs (default)
+	SinkSecretsPassthrough SinkSecrets = "passthrough" // Write output unchanged (e.g., a generated kubeconfig)
+	SinkSecretsFail        SinkSecrets = "fail"        // Fail the redirect before a secret is written
+)
+
+// SinkSecretsParam is the redirect target parameter selecting its SinkSecrets policy.
+const SinkSecretsParam = "secrets"
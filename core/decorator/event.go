@@ -0,0 +1,51 @@
+package decorator
+
+import "time"
+
+// Event is an auditable record emitted by a decorator during execution.
+//
+// Events are part of the execution receipt: the runtime collects them in
+// emission order so operators can review decisions (approvals, lock waits,
+// cache hits) after the run. Attrs must never contain secret values.
+type Event struct {
+	Timestamp time.Time         // Set by the runtime when zero
+	Decorator string            // Emitting decorator path (e.g. "exec.confirm")
+	Kind      string            // Event kind within the decorator (e.g. "confirm")
+	Attrs     map[string]string // Event details (non-secret)
+}
+
+// EventSink receives decorator events. Implementations must be safe for
+// concurrent use because parallel branches emit independently.
+type EventSink interface {
+	Emit(event Event)
+}
+
+// Emit records an event on the context's sink. It is a no-op when the
+// runtime did not provide a sink.
+func (c ExecContext) Emit(event Event) {
+	if c.Events == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	c.Events.Emit(event)
+}
+
+// SessionEventSource is an optional interface for sessions that record how
+// they were opened, such as the effective settings of an SSH connection.
+// The runtime emits these events once, when it opens the session.
+type SessionEventSource interface {
+	SessionEvents() []Event
+}
+
+// emitSessionEvents emits the open-time events of session, if it has any.
+func emitSessionEvents(ctx ExecContext, session Session) {
+	source, ok := session.(SessionEventSource)
+	if !ok {
+		return
+	}
+	for _, event := range source.SessionEvents() {
+		ctx.Emit(event)
+	}
+}
@@ -27,6 +27,24 @@ type BranchExecutor interface {
 	ExecuteBranch(index int, ctx ExecContext) (Result, error)
 }
 
+// BlockFingerprinter is an optional extension for block-aware execution nodes.
+// It exposes a deterministic digest of the planned block (decorators, rendered
+// commands, and arguments) so wrapper decorators (like @exec.cache) can key on
+// what the block will run without depending on executor internals.
+// Step IDs and transport IDs are excluded so the digest is stable across plans.
+type BlockFingerprinter interface {
+	ExecNode
+	BlockFingerprint() string
+}
+
+// ParamPinner is an optional interface for exec decorators that derive
+// params at plan time, such as the key of an @exec.cache block. The planner
+// calls PinParams and records the returned params in the plan, so reviewers
+// see them and contract verification covers them.
+type ParamPinner interface {
+	PinParams(params map[string]any) (map[string]any, error)
+}
+
 // ExecContext provides the execution context for command execution.
 type ExecContext struct {
 	// Context is the parent context for cancellation and deadlines
@@ -53,6 +71,23 @@ type ExecContext struct {
 	// Opal runtime creates parent span automatically
 	// Decorators can create child spans for internal tracking
 	Trace Span
+
+	// Events receives auditable decorator events (nil when not collected)
+	Events EventSink
+
+	// Redact replaces known secrets in text a decorator writes past output
+	// lockdown, such as a prompt on the operator's terminal (nil when the
+	// runtime knows no secrets)
+	Redact func(text string) string
+}
+
+// Redacted returns text with known secrets replaced, or text unchanged when
+// the runtime provided no Redact function.
+func (c ExecContext) Redacted(text string) string {
+	if c.Redact == nil {
+		return text
+	}
+	return c.Redact(text)
 }
 
 // WithContext returns a copy with a new cancellation/deadline context.
@@ -0,0 +1,393 @@
+package decorator
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kubernetes exec channel protocols, newest first. v5 adds the close signal
+// that lets the client end stdin without closing the whole stream.
+const (
+	k8sProtocolV5 = "v5.channel.k8s.io"
+	k8sProtocolV4 = "v4.channel.k8s.io"
+
+	k8sChannelStdin  = 0
+	k8sChannelStdout = 1
+	k8sChannelStderr = 2
+	k8sChannelError  = 3
+	k8sChannelClose  = 255
+)
+
+// k8sAPI is a minimal Kubernetes API client: pod lookup and the exec
+// subresource over WebSocket. Connections are dialed through the parent
+// session's network dialer.
+type k8sAPI struct {
+	config *k8sClusterConfig
+	dialer NetworkDialer
+	client *http.Client
+}
+
+// k8sPod is the subset of a Pod object that sessions use.
+type k8sPod struct {
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+		OS *struct {
+			Name string `json:"name"`
+		} `json:"os,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// k8sStatus is a metav1.Status, used for API errors and exec results.
+type k8sStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+	Details *struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details,omitempty"`
+}
+
+// k8sAPIError is an error status returned by the API server.
+type k8sAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *k8sAPIError) Error() string {
+	return fmt.Sprintf("kubernetes API %d: %s", e.StatusCode, e.Message)
+}
+
+func isK8sNotFound(err error) bool {
+	var apiErr *k8sAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func newK8sAPI(config *k8sClusterConfig, dialer NetworkDialer) *k8sAPI {
+	api := &k8sAPI{config: config, dialer: dialer}
+	api.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialCtx, cancel := withDefaultDialDeadline(ctx)
+				defer cancel()
+				return dialer.DialContext(dialCtx, network, addr)
+			},
+			TLSClientConfig:   config.TLS,
+			ForceAttemptHTTP2: false,
+		},
+	}
+	return api
+}
+
+func (a *k8sAPI) endpoint(path string, query url.Values) *url.URL {
+	u := *a.config.Server
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return &u
+}
+
+func (a *k8sAPI) authorize(req *http.Request) {
+	switch {
+	case a.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	case a.config.Username != "":
+		req.SetBasicAuth(a.config.Username, a.config.Password)
+	}
+}
+
+func (a *k8sAPI) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint(path, query).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	a.authorize(req)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		return k8sResponseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func k8sResponseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(data))
+	var status k8sStatus
+	if json.Unmarshal(data, &status) == nil && status.Message != "" {
+		message = status.Message
+	}
+	if message == "" {
+		message = resp.Status
+	}
+	return &k8sAPIError{StatusCode: resp.StatusCode, Message: message}
+}
+
+func k8sPodPath(namespace, pod string) string {
+	return "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods/" + url.PathEscape(pod)
+}
+
+func (a *k8sAPI) getPod(ctx context.Context, namespace, name string) (k8sPod, error) {
+	var pod k8sPod
+	err := a.getJSON(ctx, k8sPodPath(namespace, name), nil, &pod)
+	return pod, err
+}
+
+// listRunningPods returns running pods in namespace matching a label selector.
+func (a *k8sAPI) listRunningPods(ctx context.Context, namespace, selector string) ([]k8sPod, error) {
+	var list struct {
+		Items []k8sPod `json:"items"`
+	}
+	query := url.Values{
+		"labelSelector": {selector},
+		"fieldSelector": {"status.phase=Running"},
+	}
+	err := a.getJSON(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods", query, &list)
+	return list.Items, err
+}
+
+// k8sExecRequest describes one exec subresource call.
+type k8sExecRequest struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// exec runs a command in a pod and returns its exit code. Non-zero exits are
+// reported by the API server on the error channel, not as errors.
+func (a *k8sAPI) exec(ctx context.Context, request k8sExecRequest) (int, error) {
+	query := url.Values{
+		"command": request.Command,
+		"stdout":  {"true"},
+		"stderr":  {"true"},
+	}
+	if request.Container != "" {
+		query.Set("container", request.Container)
+	}
+	if request.Stdin != nil {
+		query.Set("stdin", "true")
+	}
+
+	conn, reader, protocol, err := a.openExecStream(ctx, a.endpoint(k8sPodPath(request.Namespace, request.Pod)+"/exec", query))
+	if err != nil {
+		return -1, err
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	send := func(opcode byte, payload []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return writeWebSocketFrame(conn, opcode, payload, true)
+	}
+
+	if request.Stdin != nil {
+		go func() {
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := request.Stdin.Read(buf)
+				if n > 0 {
+					if send(wsOpBinary, append([]byte{k8sChannelStdin}, buf[:n]...)) != nil {
+						return
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+			if protocol == k8sProtocolV5 {
+				_ = send(wsOpBinary, []byte{k8sChannelClose, k8sChannelStdin})
+			}
+		}()
+	}
+
+	var statusJSON []byte
+	for {
+		message, err := readWebSocketMessage(reader, func(payload []byte) error {
+			return send(wsOpPong, payload)
+		})
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return -1, ctx.Err()
+			}
+			return -1, fmt.Errorf("read exec stream: %w", err)
+		}
+		if len(message) < 2 {
+			continue // Servers open each channel with an empty frame
+		}
+
+		payload := message[1:]
+		switch message[0] {
+		case k8sChannelStdout:
+			if _, err := request.Stdout.Write(payload); err != nil {
+				return -1, err
+			}
+		case k8sChannelStderr:
+			if _, err := request.Stderr.Write(payload); err != nil {
+				return -1, err
+			}
+		case k8sChannelError:
+			statusJSON = append(statusJSON, payload...)
+		}
+	}
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+
+	return k8sExecExitCode(statusJSON)
+}
+
+// k8sExecExitCode decodes the final status from the error channel.
+func k8sExecExitCode(statusJSON []byte) (int, error) {
+	if len(statusJSON) == 0 {
+		return -1, errors.New("exec stream closed without a status")
+	}
+
+	var status k8sStatus
+	if err := json.Unmarshal(statusJSON, &status); err != nil {
+		return -1, fmt.Errorf("decode exec status: %w", err)
+	}
+	if status.Status == "Success" {
+		return 0, nil
+	}
+	if status.Reason == "NonZeroExitCode" && status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			if cause.Reason == "ExitCode" {
+				code, err := strconv.Atoi(cause.Message)
+				if err != nil {
+					return -1, fmt.Errorf("invalid exit code %q in exec status", cause.Message)
+				}
+				return code, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("exec failed: %s", status.Message)
+}
+
+// openExecStream performs the WebSocket handshake for the exec subresource.
+func (a *k8sAPI) openExecStream(ctx context.Context, target *url.URL) (net.Conn, *bufio.Reader, string, error) {
+	addr := target.Host
+	if target.Port() == "" {
+		port := "443"
+		if target.Scheme == "http" {
+			port = "80"
+		}
+		addr = net.JoinHostPort(target.Hostname(), port)
+	}
+
+	dialCtx, cancel := withDefaultDialDeadline(ctx)
+	defer cancel()
+	conn, err := a.dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if target.Scheme == "https" {
+		config := a.config.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName = target.Hostname()
+		}
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+			_ = conn.Close()
+			return nil, nil, "", err
+		}
+		conn = tlsConn
+	}
+
+	key, err := newWebSocketKey()
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, "", err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Protocol", k8sProtocolV5+", "+k8sProtocolV4)
+	a.authorize(req)
+
+	if deadline, ok := dialCtx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, nil, "", err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, "", err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		err := k8sResponseError(resp)
+		_ = conn.Close()
+		return nil, nil, "", err
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		_ = conn.Close()
+		return nil, nil, "", errors.New("exec upgrade returned an invalid Sec-WebSocket-Accept")
+	}
+
+	protocol := resp.Header.Get("Sec-WebSocket-Protocol")
+	if protocol != k8sProtocolV5 && protocol != k8sProtocolV4 {
+		_ = conn.Close()
+		return nil, nil, "", fmt.Errorf("API server selected unsupported exec protocol %q", protocol)
+	}
+
+	return conn, reader, protocol, nil
+}
@@ -0,0 +1,211 @@
+package decorator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeConfig is the subset of a kubeconfig file that @k8s.exec understands.
+type kubeConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string    `yaml:"token"`
+			TokenFile             string    `yaml:"tokenFile"`
+			ClientCertificate     string    `yaml:"client-certificate"`
+			ClientCertificateData string    `yaml:"client-certificate-data"`
+			ClientKey             string    `yaml:"client-key"`
+			ClientKeyData         string    `yaml:"client-key-data"`
+			Username              string    `yaml:"username"`
+			Password              string    `yaml:"password"`
+			Exec                  *struct{} `yaml:"exec"`
+			AuthProvider          *struct{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// k8sClusterConfig is a resolved kubeconfig context: where the API server is,
+// how to trust it, and how to authenticate.
+type k8sClusterConfig struct {
+	Context   string
+	Server    *url.URL
+	Namespace string
+	TLS       *tls.Config
+	Token     string
+	Username  string
+	Password  string
+}
+
+// kubeconfigPath picks the kubeconfig: the explicit param, then the first
+// entry of KUBECONFIG, then ~/.kube/config.
+func kubeconfigPath(params map[string]any) string {
+	if path, ok := params["kubeconfig"].(string); ok && path != "" {
+		return path
+	}
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// loadK8sClusterConfig reads a kubeconfig and resolves the named context
+// (or the current context when name is empty).
+func loadK8sClusterConfig(path, name string) (*k8sClusterConfig, error) {
+	if path == "" {
+		return nil, errors.New("no kubeconfig found: set kubeconfig=... or KUBECONFIG")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig: %w", err)
+	}
+
+	var config kubeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse kubeconfig %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return nil, fmt.Errorf("kubeconfig %s has no current-context: set context=...", path)
+	}
+
+	resolved := &k8sClusterConfig{Context: name}
+	var clusterName, userName string
+	found := false
+	for _, c := range config.Contexts {
+		if c.Name == name {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			resolved.Namespace = c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig %s", name, path)
+	}
+
+	baseDir := filepath.Dir(path)
+	resolved.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	found = false
+	for _, c := range config.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		server, err := url.Parse(c.Cluster.Server)
+		if err != nil || server.Host == "" || (server.Scheme != "https" && server.Scheme != "http") {
+			return nil, fmt.Errorf("cluster %q has invalid server %q", clusterName, c.Cluster.Server)
+		}
+		resolved.Server = server
+		resolved.TLS.ServerName = c.Cluster.TLSServerName
+		resolved.TLS.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+
+		caPEM, err := kubeconfigBytes(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q certificate authority: %w", clusterName, err)
+		}
+		if len(caPEM) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("cluster %q certificate authority contains no PEM certificates", clusterName)
+			}
+			resolved.TLS.RootCAs = pool
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %q for context %q not found in kubeconfig %s", clusterName, name, path)
+	}
+
+	for _, u := range config.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("user %q uses an exec or auth-provider credential plugin, which @k8s.exec does not support: use a token or client certificate", userName)
+		}
+
+		resolved.Token = u.User.Token
+		if resolved.Token == "" && u.User.TokenFile != "" {
+			token, err := os.ReadFile(kubeconfigFile(u.User.TokenFile, baseDir))
+			if err != nil {
+				return nil, fmt.Errorf("user %q token file: %w", userName, err)
+			}
+			resolved.Token = strings.TrimSpace(string(token))
+		}
+		resolved.Username, resolved.Password = u.User.Username, u.User.Password
+
+		certPEM, err := kubeconfigBytes(u.User.ClientCertificateData, u.User.ClientCertificate, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("user %q client certificate: %w", userName, err)
+		}
+		keyPEM, err := kubeconfigBytes(u.User.ClientKeyData, u.User.ClientKey, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("user %q client key: %w", userName, err)
+		}
+		if len(certPEM) > 0 || len(keyPEM) > 0 {
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("user %q client certificate: %w", userName, err)
+			}
+			resolved.TLS.Certificates = []tls.Certificate{cert}
+		}
+		break
+	}
+
+	return resolved, nil
+}
+
+// kubeconfigBytes returns inline base64 data, or the contents of a file
+// relative to the kubeconfig directory.
+func kubeconfigBytes(data, file, baseDir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(kubeconfigFile(file, baseDir))
+}
+
+func kubeconfigFile(file, baseDir string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(baseDir, file)
+}
@@ -0,0 +1,677 @@
+package decorator
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/invariant"
+)
+
+// K8sSession implements Session for command execution inside a Kubernetes
+// pod through the exec subresource. Files are transferred as tar streams
+// through the container's tar binary, as kubectl cp does.
+//
+// Kubernetes exec has no working directory or environment options, so
+// commands are wrapped with sh and env when the session carries either.
+type K8sSession struct {
+	api       *k8sAPI
+	namespace string
+	pod       string
+	container string
+	env       map[string]string // Container environment captured at open time
+	delta     map[string]string
+	cwd       string
+	platform  string
+	root      *K8sSession // nil for the session returned by Open
+}
+
+// NewK8sSession opens a session for a pod using the local kubeconfig.
+func NewK8sSession(params map[string]any) (*K8sSession, error) {
+	return openK8sSession(NewLocalSession(), params)
+}
+
+// k8sTarget is the decoded @k8s.exec parameter set.
+type k8sTarget struct {
+	pod        string
+	selector   string
+	namespace  string
+	container  string
+	context    string
+	kubeconfig string
+}
+
+func k8sTargetFromParams(params map[string]any) k8sTarget {
+	str := func(key string) string {
+		value, _ := params[key].(string)
+		return strings.TrimSpace(value)
+	}
+	return k8sTarget{
+		pod:        str("pod"),
+		selector:   str("selector"),
+		namespace:  str("namespace"),
+		container:  str("container"),
+		context:    str("context"),
+		kubeconfig: kubeconfigPath(params),
+	}
+}
+
+// connect loads the kubeconfig context and builds an API client dialing
+// through parent.
+func (t k8sTarget) connect(parent Session) (*k8sAPI, string, error) {
+	config, err := loadK8sClusterConfig(t.kubeconfig, t.context)
+	if err != nil {
+		return nil, "", TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: err.Error(),
+			Cause:   err,
+		}
+	}
+
+	if parent == nil {
+		parent = NewLocalSession()
+	}
+	dialer, err := getNetworkDialer(parent)
+	if err != nil {
+		return nil, "", err
+	}
+
+	namespace := t.namespace
+	if namespace == "" {
+		namespace = config.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return newK8sAPI(config, dialer), namespace, nil
+}
+
+// selectPod resolves a label selector to the running pod with the
+// lexically smallest name, so repeated plans pick the same pod.
+func selectK8sPod(ctx context.Context, api *k8sAPI, namespace, selector string) (string, error) {
+	pods, err := api.listRunningPods(ctx, namespace, selector)
+	if err != nil {
+		return "", TransportError{
+			Code:      TransportErrorCodeConnect,
+			Message:   fmt.Sprintf("failed to list pods matching %q in namespace %q", selector, namespace),
+			Retryable: true,
+			Cause:     err,
+		}
+	}
+	if len(pods) == 0 {
+		return "", TransportError{
+			Code:    TransportErrorCodeConnect,
+			Message: fmt.Sprintf("no running pod matches selector %q in namespace %q", selector, namespace),
+		}
+	}
+
+	names := make([]string, len(pods))
+	for i, pod := range pods {
+		names[i] = pod.Metadata.Name
+	}
+	sort.Strings(names)
+	return names[0], nil
+}
+
+func openK8sSession(parent Session, params map[string]any) (*K8sSession, error) {
+	target := k8sTargetFromParams(params)
+	if target.pod == "" && target.selector == "" {
+		return nil, TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: "@k8s.exec requires pod=... or selector=...",
+		}
+	}
+
+	api, namespace, err := target.connect(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultDialDeadline(context.Background())
+	defer cancel()
+
+	// A pod pinned at plan time wins over the selector it was resolved from.
+	podName := target.pod
+	if podName == "" {
+		podName, err = selectK8sPod(ctx, api, namespace, target.selector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pod, err := api.getPod(ctx, namespace, podName)
+	if err != nil {
+		message := fmt.Sprintf("failed to get pod %s/%s", namespace, podName)
+		if isK8sNotFound(err) {
+			message = fmt.Sprintf("pod %s/%s not found", namespace, podName)
+		}
+		return nil, TransportError{
+			Code:      TransportErrorCodeConnect,
+			Message:   message,
+			Retryable: !isK8sNotFound(err),
+			Cause:     err,
+		}
+	}
+	if pod.Status.Phase != "Running" {
+		return nil, TransportError{
+			Code:    TransportErrorCodeConnect,
+			Message: fmt.Sprintf("pod %s/%s is %s, not Running", namespace, podName, pod.Status.Phase),
+		}
+	}
+
+	container := target.container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	if !k8sPodHasContainer(pod, container) {
+		return nil, TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: fmt.Sprintf("pod %s/%s has no container %q", namespace, podName, container),
+		}
+	}
+
+	platform := "linux"
+	if pod.Spec.OS != nil && pod.Spec.OS.Name != "" {
+		platform = normalizePlatform(pod.Spec.OS.Name)
+	}
+
+	delta := sshEnvDelta(params)
+	if delta == nil {
+		delta = make(map[string]string)
+	}
+
+	session := &K8sSession{
+		api:       api,
+		namespace: namespace,
+		pod:       podName,
+		container: container,
+		delta:     delta,
+		platform:  platform,
+	}
+	session.cwd, session.env = session.probe(ctx)
+	if workdir, ok := params["workdir"].(string); ok && workdir != "" {
+		session.cwd = session.resolvePath(workdir)
+	}
+	return session, nil
+}
+
+func k8sPodHasContainer(pod k8sPod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// probe captures the container's default working directory and environment.
+// Images without a shell still work; they just report an empty environment.
+func (s *K8sSession) probe(ctx context.Context) (string, map[string]string) {
+	var stdout bytes.Buffer
+	exitCode, err := s.api.exec(ctx, k8sExecRequest{
+		Namespace: s.namespace,
+		Pod:       s.pod,
+		Container: s.container,
+		Command:   []string{"sh", "-c", "pwd; env"},
+		Stdout:    &stdout,
+		Stderr:    io.Discard,
+	})
+	if err != nil || exitCode != 0 {
+		return "/", make(map[string]string)
+	}
+
+	cwd, env, _ := strings.Cut(stdout.String(), "\n")
+	cwd = strings.TrimSpace(cwd)
+	if cwd == "" {
+		cwd = "/"
+	}
+	return cwd, parseEnv(env)
+}
+
+// command wraps argv so the session's working directory and environment
+// delta apply, since the exec subresource supports neither.
+func (s *K8sSession) command(argv []string, dir string) []string {
+	wrapped := argv
+	if len(s.delta) > 0 {
+		wrapped = append(append([]string{"env"}, dockerEnvList(s.delta)...), argv...)
+	}
+	if dir != "" {
+		wrapped = append([]string{"sh", "-c", `cd "$1" && shift && exec "$@"`, "sh", dir}, wrapped...)
+	}
+	return wrapped
+}
+
+// Run executes argv in the pod.
+func (s *K8sSession) Run(ctx context.Context, argv []string, opts RunOpts) (Result, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(len(argv) > 0, "argv cannot be empty")
+
+	if ctx.Err() != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "command context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	dir := s.cwd
+	if opts.Dir != "" {
+		dir = s.resolvePath(opts.Dir)
+	}
+
+	var stdout, stderr bytes.Buffer
+	stdoutWriter := io.Writer(&stdout)
+	if opts.Stdout != nil {
+		stdoutWriter = opts.Stdout
+	}
+	stderrWriter := io.Writer(&stderr)
+	if opts.Stderr != nil {
+		stderrWriter = opts.Stderr
+	}
+
+	exitCode, err := s.api.exec(ctx, k8sExecRequest{
+		Namespace: s.namespace,
+		Pod:       s.pod,
+		Container: s.container,
+		Command:   s.command(argv, dir),
+		Stdin:     opts.Stdin,
+		Stdout:    stdoutWriter,
+		Stderr:    stderrWriter,
+	})
+	if ctx.Err() != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "command context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+	if err != nil {
+		return Result{ExitCode: -1}, TransportError{
+			Code:      TransportErrorCodeExecute,
+			Message:   fmt.Sprintf("failed to exec in pod %s/%s", s.namespace, s.pod),
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+
+	return Result{
+		ExitCode: exitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, nil
+}
+
+// Put writes data to a file in the pod by streaming a one-file tar archive
+// into tar running in the container.
+func (s *K8sSession) Put(ctx context.Context, data []byte, filePath string, mode fs.FileMode) error {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "put context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	dir, name := path.Split(s.resolvePath(filePath))
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     int64(mode.Perm()),
+			Size:     int64(len(data)),
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+
+	// tar applies the umask to extracted modes, so set the mode explicitly.
+	argv := []string{"sh", "-c", `tar -xmf - -C "$1" && chmod "$2" "$1/$3"`, "sh", dir, fmt.Sprintf("%o", mode.Perm()), name}
+	var stderr bytes.Buffer
+	exitCode, err := s.api.exec(ctx, k8sExecRequest{
+		Namespace: s.namespace,
+		Pod:       s.pod,
+		Container: s.container,
+		Command:   argv,
+		Stdin:     reader,
+		Stdout:    io.Discard,
+		Stderr:    &stderr,
+	})
+	_ = reader.Close()
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("tar exited with %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to write pod file",
+			Retryable: true,
+			Cause:     err,
+		}
+	}
+	return nil
+}
+
+// Get reads a file from the pod by streaming it out of tar in the container.
+func (s *K8sSession) Get(ctx context.Context, filePath string) ([]byte, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "get context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	dir, name := path.Split(s.resolvePath(filePath))
+	reader, writer := io.Pipe()
+
+	type extracted struct {
+		data []byte
+		err  error
+	}
+	done := make(chan extracted, 1)
+	go func() {
+		data, err := readSingleTarFile(reader, filePath)
+		// Drain so tar in the pod is never blocked on a full stream.
+		_, _ = io.Copy(io.Discard, reader)
+		done <- extracted{data: data, err: err}
+	}()
+
+	var stderr bytes.Buffer
+	exitCode, execErr := s.api.exec(ctx, k8sExecRequest{
+		Namespace: s.namespace,
+		Pod:       s.pod,
+		Container: s.container,
+		Command:   []string{"tar", "-cf", "-", "-C", dir, name},
+		Stdout:    writer,
+		Stderr:    &stderr,
+	})
+	_ = writer.Close()
+	result := <-done
+
+	if execErr == nil && exitCode != 0 {
+		execErr = fmt.Errorf("tar exited with %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+		if strings.Contains(stderr.String(), "No such file") {
+			execErr = errors.Join(fs.ErrNotExist, execErr)
+		}
+	}
+	if execErr == nil {
+		execErr = result.err
+	}
+	if execErr != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read pod file",
+			Retryable: !errors.Is(execErr, fs.ErrNotExist),
+			Cause:     execErr,
+		}
+	}
+	return result.data, nil
+}
+
+// readSingleTarFile returns the contents of the first entry in a tar stream,
+// which must be a regular file.
+func readSingleTarFile(r io.Reader, name string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+	return io.ReadAll(tr)
+}
+
+// PutStream writes src to a file in the pod. The upload is a tar archive
+// whose header needs the file size, so src is read into memory first.
+func (s *K8sSession) PutStream(ctx context.Context, src io.Reader, filePath string, mode fs.FileMode) error {
+	return putStreamBuffered(ctx, s, src, filePath, mode)
+}
+
+// GetStream copies a file in the pod to dst. The file is read into memory
+// first, as with Get.
+func (s *K8sSession) GetStream(ctx context.Context, filePath string, dst io.Writer) error {
+	return getStreamBuffered(ctx, s, filePath, dst)
+}
+
+// Env returns the container environment captured at open time with this
+// session's delta applied.
+func (s *K8sSession) Env() map[string]string {
+	env := make(map[string]string, len(s.env)+len(s.delta))
+	for k, v := range s.env {
+		env[k] = v
+	}
+	for k, v := range s.delta {
+		env[k] = v
+	}
+	return env
+}
+
+// WithEnv returns a new Session with environment delta applied (copy-on-write).
+func (s *K8sSession) WithEnv(delta map[string]string) Session {
+	merged := make(map[string]string, len(s.delta)+len(delta))
+	for k, v := range s.delta {
+		merged[k] = v
+	}
+	for k, v := range delta {
+		merged[k] = v
+	}
+
+	derived := s.derive()
+	derived.delta = merged
+	return derived
+}
+
+// WithWorkdir returns a new Session with working directory set (copy-on-write).
+func (s *K8sSession) WithWorkdir(dir string) Session {
+	invariant.Precondition(dir != "", "dir cannot be empty")
+
+	derived := s.derive()
+	derived.cwd = s.resolvePath(dir)
+	return derived
+}
+
+// Cwd returns the working directory used for commands in the pod.
+func (s *K8sSession) Cwd() string {
+	return s.cwd
+}
+
+// ID returns the session identifier for Kubernetes sessions.
+// Format: "k8s:<namespace>/<pod>".
+func (s *K8sSession) ID() string {
+	return "k8s:" + s.namespace + "/" + s.pod
+}
+
+// TransportScope returns the transport scope for Kubernetes sessions.
+func (s *K8sSession) TransportScope() TransportScope {
+	return TransportScopeK8s
+}
+
+// Platform returns the pod OS (spec.os.name, defaulting to linux).
+func (s *K8sSession) Platform() string {
+	return s.platform
+}
+
+// Close releases idle API connections. Derived sessions share the
+// connection with the session they came from, so closing them is a no-op.
+func (s *K8sSession) Close() error {
+	if s.root == nil {
+		s.api.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *K8sSession) UnwrapSession() Session {
+	if s.root == nil {
+		return nil
+	}
+	return s.root
+}
+
+func (s *K8sSession) derive() *K8sSession {
+	root := s.root
+	if root == nil {
+		root = s
+	}
+	return &K8sSession{
+		api:       s.api,
+		namespace: s.namespace,
+		pod:       s.pod,
+		container: s.container,
+		env:       s.env,
+		delta:     s.delta,
+		cwd:       s.cwd,
+		platform:  s.platform,
+		root:      root,
+	}
+}
+
+func (s *K8sSession) resolvePath(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.cwd, p)
+}
+
+// K8sTransport implements Transport for running commands inside a Kubernetes pod.
+type K8sTransport struct{}
+
+func init() {
+	if err := Register("k8s.exec", &K8sTransport{}); err != nil {
+		panic(fmt.Sprintf("failed to register @k8s.exec decorator: %v", err))
+	}
+}
+
+func (t *K8sTransport) Descriptor() Descriptor {
+	return NewDescriptor("k8s.exec").
+		Summary("Run block inside a Kubernetes pod").
+		Roles(RoleBoundary).
+		ParamString("pod", "Pod name (mutually exclusive with selector)").
+		Examples("api-0").
+		Done().
+		ParamString("selector", "Label selector resolved to a running pod at plan time").
+		Examples("app=api,tier=backend").
+		Done().
+		ParamString("namespace", "Namespace (default: the kubeconfig context namespace, then default)").
+		Examples("prod").
+		Done().
+		ParamString("container", "Container name (default: the pod's first container)").
+		Examples("app").
+		Done().
+		ParamString("context", "Kubeconfig context (default: current-context)").
+		Examples("prod-eu").
+		Done().
+		ParamString("kubeconfig", "Kubeconfig path (default: KUBECONFIG or ~/.kube/config)").
+		Done().
+		ParamString("workdir", "Working directory inside the container").
+		Examples("/app").
+		Done().
+		ParamObject("env", "Environment variables added to every command").
+		AllowAdditionalProperties().
+		Done().
+		Block(BlockRequired).
+		Build()
+}
+
+func (t *K8sTransport) Capabilities() TransportCaps {
+	return TransportCapFilesystem | TransportCapEnvironment
+}
+
+// ResolveTarget pins a label selector to a concrete pod at plan time so the
+// pod name is recorded in the plan and checked by contract verification.
+func (t *K8sTransport) ResolveTarget(parent Session, params map[string]any) (map[string]any, error) {
+	target := k8sTargetFromParams(params)
+	if target.selector == "" {
+		return nil, nil
+	}
+	if target.pod != "" {
+		return nil, TransportError{
+			Code:    TransportErrorCodeValidationFailed,
+			Message: "@k8s.exec accepts pod=... or selector=..., not both",
+		}
+	}
+
+	api, namespace, err := target.connect(parent)
+	if err != nil {
+		return nil, err
+	}
+	defer api.client.CloseIdleConnections()
+
+	ctx, cancel := withDefaultDialDeadline(context.Background())
+	defer cancel()
+
+	pod, err := selectK8sPod(ctx, api, namespace, target.selector)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"pod": pod}, nil
+}
+
+func (t *K8sTransport) Open(parent Session, params map[string]any) (Session, error) {
+	return openK8sSession(parent, params)
+}
+
+func (t *K8sTransport) Wrap(next ExecNode, params map[string]any) ExecNode {
+	return &k8sTransportNode{
+		next:   next,
+		params: params,
+	}
+}
+
+func (t *K8sTransport) MaterializeSession() bool {
+	return true
+}
+
+func (t *K8sTransport) IsolationContext() IsolationContext {
+	return nil
+}
+
+type k8sTransportNode struct {
+	next   ExecNode
+	params map[string]any
+}
+
+func (n *k8sTransportNode) Execute(ctx ExecContext) (Result, error) {
+	if n.next == nil {
+		return Result{ExitCode: ExitSuccess}, nil
+	}
+
+	// The executor already opened the pod session for this transport.
+	if ctx.Session != nil && ctx.Session.TransportScope() == TransportScopeK8s && !strings.HasPrefix(ctx.Session.ID(), "k8s:") {
+		return n.next.Execute(ctx)
+	}
+
+	session, err := openK8sSession(ctx.Session, n.params)
+	if err != nil {
+		return Result{ExitCode: ExitFailure}, err
+	}
+	defer func() { _ = session.Close() }()
+
+	return n.next.Execute(ctx.WithSession(session))
+}
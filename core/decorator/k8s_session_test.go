@@ -0,0 +1,333 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func startK8sTestServer(t *testing.T) (*K8sTestServer, string) {
+	t.Helper()
+	workdir := t.TempDir()
+	env := []string{"PATH=" + os.Getenv("PATH"), "APP_ENV=pod"}
+	server := StartK8sTestServer(t,
+		K8sTestPod{
+			Name:       "api-0",
+			Namespace:  "prod",
+			Labels:     map[string]string{"app": "api"},
+			Containers: []string{"app", "sidecar"},
+			WorkingDir: workdir,
+			Env:        env,
+		},
+		K8sTestPod{
+			Name:       "api-1",
+			Namespace:  "prod",
+			Labels:     map[string]string{"app": "api"},
+			Containers: []string{"app"},
+			WorkingDir: workdir,
+			Env:        env,
+		},
+		K8sTestPod{Name: "pending-0", Namespace: "prod", Containers: []string{"app"}, Phase: "Pending"},
+	)
+	return server, workdir
+}
+
+func openTestK8sSession(t *testing.T, server *K8sTestServer, params map[string]any) *K8sSession {
+	t.Helper()
+	merged := map[string]any{"pod": "api-0", "namespace": "prod", "kubeconfig": server.Kubeconfig}
+	for k, v := range params {
+		merged[k] = v
+	}
+	session, err := NewK8sSession(merged)
+	if err != nil {
+		t.Fatalf("open k8s session: %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+func TestK8sSessionRunSeparatesStreamsAndExitCode(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	session := openTestK8sSession(t, server, nil)
+
+	result, err := session.Run(context.Background(), []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("exit code: got %d, want 3", result.ExitCode)
+	}
+	if diff := cmp.Diff("out\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("err\n", string(result.Stderr)); diff != "" {
+		t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+	}
+
+	target, _ := server.LastExec()
+	if diff := cmp.Diff("prod/api-0/app", target); diff != "" {
+		t.Errorf("exec target mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestK8sSessionRunStreamsStdin(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	session := openTestK8sSession(t, server, nil)
+
+	var stdout bytes.Buffer
+	result, err := session.Run(context.Background(), []string{"tr", "a-z", "A-Z"}, RunOpts{
+		Stdin:  strings.NewReader("hello pod\n"),
+		Stdout: &stdout,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("run: exit=%d err=%v", result.ExitCode, err)
+	}
+	if diff := cmp.Diff("HELLO POD\n", stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestK8sSessionEnvAndWorkdir(t *testing.T) {
+	server, workdir := startK8sTestServer(t)
+	sub := filepath.Join(workdir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session := openTestK8sSession(t, server, map[string]any{
+		"container": "sidecar",
+		"env":       map[string]any{"REGION": "eu"},
+	})
+
+	if session.Cwd() != workdir {
+		t.Errorf("cwd: got %q, want %q", session.Cwd(), workdir)
+	}
+	if got := session.Env()["APP_ENV"]; got != "pod" {
+		t.Errorf("pod env snapshot: APP_ENV = %q, want %q", got, "pod")
+	}
+
+	derived := session.WithEnv(map[string]string{"APP_ENV": "override"}).WithWorkdir("sub")
+	result, err := derived.Run(context.Background(), []string{"sh", "-c", "echo $APP_ENV $REGION; pwd"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if diff := cmp.Diff("override eu\n"+sub+"\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	if target, _ := server.LastExec(); target != "prod/api-0/sidecar" {
+		t.Errorf("exec target: got %q, want prod/api-0/sidecar", target)
+	}
+	if got := session.Env()["APP_ENV"]; got != "pod" {
+		t.Errorf("WithEnv must not modify the parent session, got APP_ENV=%q", got)
+	}
+}
+
+func TestK8sSessionPutGet(t *testing.T) {
+	server, workdir := startK8sTestServer(t)
+	session := openTestK8sSession(t, server, nil)
+	ctx := context.Background()
+
+	if err := session.Put(ctx, []byte("#!/bin/sh\necho hi\n"), "deploy.sh", 0o750); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(workdir, "deploy.sh"))
+	if err != nil {
+		t.Fatalf("stat uploaded file: %v", err)
+	}
+	if info.Mode().Perm() != 0o750 {
+		t.Errorf("mode: got %o, want 750", info.Mode().Perm())
+	}
+
+	data, err := session.Get(ctx, filepath.Join(workdir, "deploy.sh"))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if diff := cmp.Diff("#!/bin/sh\necho hi\n", string(data)); diff != "" {
+		t.Errorf("content mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = session.Get(ctx, "missing.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestK8sSessionIdentity(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	session := openTestK8sSession(t, server, nil)
+
+	if diff := cmp.Diff("k8s:prod/api-0", session.ID()); diff != "" {
+		t.Errorf("ID mismatch (-want +got):\n%s", diff)
+	}
+	if session.TransportScope() != TransportScopeK8s {
+		t.Errorf("scope: got %v, want K8s", session.TransportScope())
+	}
+	if session.Platform() != "linux" {
+		t.Errorf("platform: got %q, want linux", session.Platform())
+	}
+
+	derived := session.WithEnv(map[string]string{"A": "1"})
+	if derived.ID() != session.ID() {
+		t.Errorf("derived ID: got %q, want %q", derived.ID(), session.ID())
+	}
+	if err := derived.Close(); err != nil {
+		t.Fatalf("close derived: %v", err)
+	}
+	if _, err := session.Run(context.Background(), []string{"true"}, RunOpts{}); err != nil {
+		t.Errorf("closing a derived session must not close the parent: %v", err)
+	}
+}
+
+func TestK8sSessionOpenErrors(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{name: "missing target", params: map[string]any{}, want: "requires pod=... or selector=..."},
+		{name: "unknown pod", params: map[string]any{"pod": "nope"}, want: "pod prod/nope not found"},
+		{name: "pending pod", params: map[string]any{"pod": "pending-0"}, want: "is Pending, not Running"},
+		{name: "unknown container", params: map[string]any{"pod": "api-0", "container": "db"}, want: `has no container "db"`},
+		{name: "unknown context", params: map[string]any{"pod": "api-0", "context": "other"}, want: `context "other" not found`},
+		{name: "no matching pods", params: map[string]any{"selector": "app=web"}, want: `no running pod matches selector "app=web"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]any{"namespace": "prod", "kubeconfig": server.Kubeconfig}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+			_, err := NewK8sSession(params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestK8sSessionRejectsBadToken(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	server.Token = "rotated"
+
+	_, err := NewK8sSession(map[string]any{"pod": "api-0", "namespace": "prod", "kubeconfig": server.Kubeconfig})
+	if err == nil || !strings.Contains(err.Error(), "failed to get pod prod/api-0") {
+		t.Fatalf("expected auth failure, got %v", err)
+	}
+}
+
+func TestK8sTransportResolveTargetPinsPod(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	transport := &K8sTransport{}
+	params := map[string]any{"selector": "app=api", "namespace": "prod", "kubeconfig": server.Kubeconfig}
+
+	pinned, err := transport.ResolveTarget(NewLocalSession(), params)
+	if err != nil {
+		t.Fatalf("resolve target: %v", err)
+	}
+	if diff := cmp.Diff(map[string]any{"pod": "api-0"}, pinned); diff != "" {
+		t.Errorf("pinned params mismatch (-want +got):\n%s", diff)
+	}
+
+	// The pinned pod wins over the selector when the session opens, so a
+	// replaced pod fails instead of silently moving to another one.
+	server.SetPodPhase("prod", "api-0", "Failed")
+	params["pod"] = "api-0"
+	if _, err := transport.Open(NewLocalSession(), params); err == nil || !strings.Contains(err.Error(), "is Failed") {
+		t.Fatalf("expected pinned pod to be used, got %v", err)
+	}
+
+	delete(params, "pod")
+	pinned, err = transport.ResolveTarget(NewLocalSession(), params)
+	if err != nil {
+		t.Fatalf("re-resolve target: %v", err)
+	}
+	if diff := cmp.Diff(map[string]any{"pod": "api-1"}, pinned); diff != "" {
+		t.Errorf("re-pinned params mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestK8sTransportResolveTargetValidation(t *testing.T) {
+	transport := &K8sTransport{}
+
+	pinned, err := transport.ResolveTarget(NewLocalSession(), map[string]any{"pod": "api-0"})
+	if err != nil || pinned != nil {
+		t.Errorf("explicit pod needs no resolution: got %v, %v", pinned, err)
+	}
+
+	_, err = transport.ResolveTarget(NewLocalSession(), map[string]any{"pod": "api-0", "selector": "app=api"})
+	if err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Errorf("expected pod/selector conflict, got %v", err)
+	}
+}
+
+func TestK8sSessionRunCancellation(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+	session := openTestK8sSession(t, server, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := session.Run(ctx, []string{"sleep", "2"}, RunOpts{})
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Code != TransportErrorCodeContext {
+		t.Fatalf("expected context transport error, got %v", err)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("exit code: got %d, want -1", result.ExitCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took %s", elapsed)
+	}
+}
+
+func TestK8sTransportWrapRunsBlockInPod(t *testing.T) {
+	server, _ := startK8sTestServer(t)
+
+	var stdout bytes.Buffer
+	node := (&K8sTransport{}).Wrap(execNodeFunc(func(ctx ExecContext) (Result, error) {
+		if ctx.Session.TransportScope() != TransportScopeK8s {
+			t.Errorf("block scope: got %v, want K8s", ctx.Session.TransportScope())
+		}
+		return ctx.Session.Run(ctx.Context, []string{"sh", "-c", "echo $APP_ENV"}, RunOpts{Stdout: ctx.Stdout})
+	}), map[string]any{"selector": "app=api", "namespace": "prod", "kubeconfig": server.Kubeconfig})
+
+	result, err := node.Execute(ExecContext{
+		Context: context.Background(),
+		Session: NewLocalSession(),
+		Stdout:  &stdout,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("execute: exit=%d err=%v", result.ExitCode, err)
+	}
+	if diff := cmp.Diff("pod\n", stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestK8sTransportRegistered(t *testing.T) {
+	transport, ok, _ := Global().GetTransport("k8s.exec")
+	if !ok {
+		t.Fatal("k8s.exec must be registered as a transport")
+	}
+	if _, ok := transport.(TargetResolver); !ok {
+		t.Error("k8s.exec must resolve selectors at plan time")
+	}
+	caps := transport.Capabilities()
+	if !caps.Has(TransportCapFilesystem) || !caps.Has(TransportCapEnvironment) || caps.Has(TransportCapNetwork) {
+		t.Errorf("unexpected capabilities: %b", caps)
+	}
+	if !transport.MaterializeSession() {
+		t.Error("k8s.exec must materialize its own session")
+	}
+}
@@ -0,0 +1,326 @@
+package decorator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// K8sTestPod describes a pod served by K8sTestServer. Execs run as local
+// processes in WorkingDir with only Env set, so the fake pod shares the host
+// filesystem.
+type K8sTestPod struct {
+	Name       string
+	Namespace  string
+	Labels     map[string]string
+	Containers []string
+	Phase      string
+	OS         string
+	WorkingDir string
+	Env        []string
+}
+
+// K8sTestServer is an in-process fake Kubernetes API server over TLS that
+// serves pod lookups and the exec subresource (v5/v4 channel protocols).
+type K8sTestServer struct {
+	// Kubeconfig is a kubeconfig file for context "test" pointing at the server.
+	Kubeconfig string
+	Token      string
+
+	server *httptest.Server
+
+	mu          sync.Mutex
+	pods        map[string]*K8sTestPod
+	lastCommand []string
+	lastTarget  string
+}
+
+// StartK8sTestServer starts the fake API server and writes a kubeconfig for
+// it. The server is stopped when the test finishes.
+func StartK8sTestServer(t *testing.T, pods ...K8sTestPod) *K8sTestServer {
+	t.Helper()
+
+	s := &K8sTestServer{
+		Token: "test-token",
+		pods:  make(map[string]*K8sTestPod),
+	}
+	for i := range pods {
+		pod := pods[i]
+		if pod.Namespace == "" {
+			pod.Namespace = "default"
+		}
+		if pod.Phase == "" {
+			pod.Phase = "Running"
+		}
+		s.pods[pod.Namespace+"/"+pod.Name] = &pod
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods", s.handleListPods)
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods/{name}", s.handleGetPod)
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods/{name}/exec", s.handleExec)
+
+	s.server = httptest.NewUnstartedServer(s.authenticate(mux))
+	s.server.StartTLS()
+	t.Cleanup(s.server.Close)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+	s.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	config := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: fake
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+users:
+- name: tester
+  user:
+    token: %s
+contexts:
+- name: test
+  context:
+    cluster: fake
+    user: tester
+    namespace: default
+`, s.server.URL, base64.StdEncoding.EncodeToString(caPEM), s.Token)
+	if err := os.WriteFile(s.Kubeconfig, []byte(config), 0o600); err != nil {
+		t.Fatalf("write kubeconfig: %v", err)
+	}
+
+	return s
+}
+
+// SetPodPhase changes a pod's phase, e.g. to simulate a pod being replaced.
+func (s *K8sTestServer) SetPodPhase(namespace, name, phase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pod, ok := s.pods[namespace+"/"+name]; ok {
+		pod.Phase = phase
+	}
+}
+
+// LastExec returns the "namespace/pod/container" target and command of the
+// most recent exec.
+func (s *K8sTestServer) LastExec() (string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastTarget, append([]string(nil), s.lastCommand...)
+}
+
+func (s *K8sTestServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.Token {
+			k8sTestStatus(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *K8sTestServer) handleGetPod(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	pod, ok := s.pods[r.PathValue("ns")+"/"+r.PathValue("name")]
+	var object k8sPod
+	if ok {
+		object = pod.object()
+	}
+	s.mu.Unlock()
+	if !ok {
+		k8sTestStatus(w, http.StatusNotFound, fmt.Sprintf("pods %q not found", r.PathValue("name")))
+		return
+	}
+	k8sTestJSON(w, http.StatusOK, object)
+}
+
+func (s *K8sTestServer) handleListPods(w http.ResponseWriter, r *http.Request) {
+	selector := parseK8sTestSelector(r.URL.Query().Get("labelSelector"))
+	phase := strings.TrimPrefix(r.URL.Query().Get("fieldSelector"), "status.phase=")
+
+	var list struct {
+		Items []k8sPod `json:"items"`
+	}
+	list.Items = []k8sPod{}
+
+	s.mu.Lock()
+	for _, pod := range s.pods {
+		if pod.Namespace != r.PathValue("ns") || (phase != "" && pod.Phase != phase) {
+			continue
+		}
+		matches := true
+		for k, v := range selector {
+			if pod.Labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			list.Items = append(list.Items, pod.object())
+		}
+	}
+	s.mu.Unlock()
+
+	k8sTestJSON(w, http.StatusOK, list)
+}
+
+func (s *K8sTestServer) handleExec(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	pod, ok := s.pods[r.PathValue("ns")+"/"+r.PathValue("name")]
+	s.mu.Unlock()
+	if !ok {
+		k8sTestStatus(w, http.StatusNotFound, fmt.Sprintf("pods %q not found", r.PathValue("name")))
+		return
+	}
+
+	query := r.URL.Query()
+	command := query["command"]
+	if len(command) == 0 {
+		k8sTestStatus(w, http.StatusBadRequest, "you must specify at least 1 command")
+		return
+	}
+
+	protocol := ""
+	for _, offered := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		offered = strings.TrimSpace(offered)
+		if offered == k8sProtocolV5 || (offered == k8sProtocolV4 && protocol == "") {
+			protocol = offered
+		}
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || protocol == "" {
+		k8sTestStatus(w, http.StatusBadRequest, "exec requires a websocket upgrade with a channel protocol")
+		return
+	}
+
+	s.mu.Lock()
+	s.lastTarget = pod.Namespace + "/" + pod.Name + "/" + query.Get("container")
+	s.lastCommand = command
+	s.mu.Unlock()
+
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, _ = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\nSec-WebSocket-Protocol: %s\r\n\r\n",
+		webSocketAccept(r.Header.Get("Sec-WebSocket-Key")), protocol)
+
+	var writeMu sync.Mutex
+	send := func(channel byte, payload []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return writeWebSocketFrame(conn, wsOpBinary, append([]byte{channel}, payload...), false)
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	go func() {
+		defer func() { _ = stdinWriter.Close() }()
+		for {
+			message, err := readWebSocketMessage(buf, nil)
+			if err != nil {
+				return
+			}
+			switch {
+			case len(message) >= 2 && message[0] == k8sChannelClose && message[1] == k8sChannelStdin:
+				_ = stdinWriter.Close()
+			case len(message) >= 1 && message[0] == k8sChannelStdin:
+				_, _ = stdinWriter.Write(message[1:])
+			}
+		}
+	}()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = pod.WorkingDir
+	cmd.Env = pod.Env
+	if query.Get("stdin") == "true" {
+		cmd.Stdin = stdinReader
+	}
+	cmd.Stdout = k8sTestChannel(func(p []byte) error { return send(k8sChannelStdout, p) })
+	cmd.Stderr = k8sTestChannel(func(p []byte) error { return send(k8sChannelStderr, p) })
+
+	status := k8sStatus{Status: "Success"}
+	if err := cmd.Run(); err != nil {
+		status = k8sStatus{Status: "Failure", Message: err.Error()}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status.Reason = "NonZeroExitCode"
+			status.Details = &struct {
+				Causes []struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"causes"`
+			}{Causes: []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			}{{Reason: "ExitCode", Message: strconv.Itoa(exitErr.ExitCode())}}}
+		}
+	}
+	statusJSON, _ := json.Marshal(status)
+	_ = send(k8sChannelError, statusJSON)
+
+	writeMu.Lock()
+	_ = writeWebSocketFrame(conn, wsOpClose, []byte{0x03, 0xE8}, false)
+	writeMu.Unlock()
+}
+
+func (p *K8sTestPod) object() k8sPod {
+	var object k8sPod
+	object.Metadata.Name = p.Name
+	object.Metadata.Namespace = p.Namespace
+	object.Metadata.Labels = p.Labels
+	for _, name := range p.Containers {
+		object.Spec.Containers = append(object.Spec.Containers, struct {
+			Name string `json:"name"`
+		}{Name: name})
+	}
+	if p.OS != "" {
+		object.Spec.OS = &struct {
+			Name string `json:"name"`
+		}{Name: p.OS}
+	}
+	object.Status.Phase = p.Phase
+	return object
+}
+
+// parseK8sTestSelector understands equality-based selectors ("a=b,c=d").
+func parseK8sTestSelector(selector string) map[string]string {
+	labels := make(map[string]string)
+	for _, term := range strings.Split(selector, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(term), "="); ok {
+			labels[strings.TrimSpace(key)] = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		}
+	}
+	return labels
+}
+
+type k8sTestChannel func([]byte) error
+
+func (c k8sTestChannel) Write(p []byte) (int, error) {
+	if err := c(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func k8sTestJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func k8sTestStatus(w http.ResponseWriter, code int, message string) {
+	k8sTestJSON(w, code, k8sStatus{Status: "Failure", Message: message, Code: code})
+}
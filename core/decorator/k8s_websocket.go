@@ -0,0 +1,183 @@
+package decorator
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Minimal RFC 6455 framing for the Kubernetes exec subresource. Only what
+// the channel protocols need is implemented: binary and text messages,
+// continuation frames, ping/pong, and close.
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	// wsMaxPayload bounds a single frame so a misbehaving server cannot make
+	// the client allocate unbounded memory.
+	wsMaxPayload = 16 << 20
+
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// wsFrame is one decoded WebSocket frame.
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// newWebSocketKey returns a random Sec-WebSocket-Key.
+func newWebSocketKey() (string, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key[:]), nil
+}
+
+// webSocketAccept computes the Sec-WebSocket-Accept value for a key.
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeWebSocketFrame writes a single final frame. Clients must mask their
+// frames; servers must not.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = maskBit | byte(n)
+	case n <= 0xFFFF:
+		header[1] = maskBit | 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = maskBit | 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	body := payload
+	if masked {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header = append(header, key[:]...)
+		body = make([]byte, len(payload))
+		for i, b := range payload {
+			body[i] = b ^ key[i%4]
+		}
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readWebSocketFrame reads one frame, unmasking it if needed.
+func readWebSocketFrame(r io.Reader) (wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return wsFrame{}, err
+	}
+
+	frame := wsFrame{fin: head[0]&0x80 != 0, opcode: head[0] & 0x0F}
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxPayload {
+		return wsFrame{}, fmt.Errorf("websocket frame of %d bytes exceeds limit", length)
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return wsFrame{}, err
+		}
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, frame.payload); err != nil {
+		return wsFrame{}, err
+	}
+	if masked {
+		for i := range frame.payload {
+			frame.payload[i] ^= key[i%4]
+		}
+	}
+	return frame, nil
+}
+
+// readWebSocketMessage reads the next data message, reassembling fragments.
+// Pings are answered through pong; a close frame ends the stream with io.EOF.
+func readWebSocketMessage(r io.Reader, pong func([]byte) error) ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		frame, err := readWebSocketFrame(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch frame.opcode {
+		case wsOpPing:
+			if pong != nil {
+				if err := pong(frame.payload); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return nil, io.EOF
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, errors.New("websocket data frame interrupted a fragmented message")
+			}
+			started = true
+		case wsOpContinuation:
+			if !started {
+				return nil, errors.New("websocket continuation frame without a message")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported websocket opcode %#x", frame.opcode)
+		}
+
+		message = append(message, frame.payload...)
+		if frame.fin {
+			return message, nil
+		}
+	}
+}
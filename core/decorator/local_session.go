@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
@@ -155,6 +156,81 @@ func (s *LocalSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// PutStream copies src to a file on the local filesystem. The data goes to
+// a temporary file in the same directory that is renamed into place once
+// complete, so path never holds a partial file.
+func (s *LocalSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	invariant.Precondition(path != "", "path cannot be empty")
+	invariant.NotNil(ctx, "ctx")
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Resolve relative paths against cwd
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.cwd, path)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".sigil-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	counter := newTransferCounter(ctx, "put", path)
+	_, err = io.Copy(tmp, io.TeeReader(src, counter))
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return err
+	}
+
+	counter.done()
+	return nil
+}
+
+// GetStream copies a file on the local filesystem to dst.
+func (s *LocalSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	invariant.Precondition(path != "", "path cannot be empty")
+	invariant.NotNil(ctx, "ctx")
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Resolve relative paths against cwd
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.cwd, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	counter := newTransferCounter(ctx, "get", path)
+	if _, err := io.Copy(io.MultiWriter(counter, dst), file); err != nil {
+		return err
+	}
+
+	counter.done()
+	return nil
+}
+
 // Env returns an immutable snapshot of environment variables.
 func (s *LocalSession) Env() map[string]string {
 	// Return a copy to prevent mutation
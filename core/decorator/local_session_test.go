@@ -223,6 +223,59 @@ func TestLocalSessionGet(t *testing.T) {
 	}
 }
 
+// TestLocalSessionStreams verifies streaming transfers replace files whole
+// and report progress
+func TestLocalSessionStreams(t *testing.T) {
+	session := NewLocalSession().WithWorkdir(t.TempDir())
+	dir := session.Cwd()
+	path := filepath.Join(dir, "out", "data.bin")
+
+	var final TransferProgress
+	ctx := WithTransferProgress(context.Background(), func(p TransferProgress) {
+		final = p
+	})
+
+	if err := session.PutStream(ctx, strings.NewReader("streamed"), "out/data.bin", 0o600); err != nil {
+		t.Fatalf("PutStream failed: %v", err)
+	}
+	want := TransferProgress{
+		Op:     "put",
+		Path:   path,
+		Bytes:  8,
+		Done:   true,
+		SHA256: "97a78c00831554f7cc9745e8f6732edcfb571cf548a8d12b48a6e3fc31e5e3e6",
+	}
+	if final != want {
+		t.Errorf("Progress: got %+v, want %+v", final, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "data.bin" {
+		t.Errorf("expected only data.bin, got %v", entries)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Permissions: got %o, want %o", info.Mode().Perm(), 0o600)
+	}
+
+	var out strings.Builder
+	if err := session.GetStream(ctx, path, &out); err != nil {
+		t.Fatalf("GetStream failed: %v", err)
+	}
+	if out.String() != "streamed" {
+		t.Errorf("Content: got %q, want %q", out.String(), "streamed")
+	}
+	if final.Op != "get" || !final.Done {
+		t.Errorf("expected final get report, got %+v", final)
+	}
+}
+
 // TestLocalSessionCwd verifies working directory
 func TestLocalSessionCwd(t *testing.T) {
 	session := NewLocalSession()
@@ -347,6 +400,14 @@ func (s *wrappedLocalSession) Get(ctx context.Context, path string) ([]byte, err
 	return s.inner.Get(ctx, path)
 }
 
+func (s *wrappedLocalSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.inner.PutStream(ctx, src, path, mode)
+}
+
+func (s *wrappedLocalSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.inner.GetStream(ctx, path, dst)
+}
+
 func (s *wrappedLocalSession) Env() map[string]string {
 	return s.inner.Env()
 }
@@ -341,6 +341,8 @@ func (r *Registry) ResolveValues(
 			Value:     result.Value,
 			Handle:    nil, // TODO: Implement secret handle for v1.0 (see MILESTONE_V1.md lines 206-219)
 			DisplayID: "",  // TODO: Generate deterministic DisplayID format: <length:algorithm:hash>
+			Origin:    result.Origin,
+			Metadata:  result.Metadata,
 		}
 	}
 
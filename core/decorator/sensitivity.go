@@ -0,0 +1,103 @@
+package decorator
+
+import "fmt"
+
+// Sensitivity classifies how much a resolved value must be hidden.
+//
+// The zero value is SensitivitySecret so that decorators which do not
+// declare a level keep the safe behavior: scrubbed everywhere.
+type Sensitivity int
+
+const (
+	// SensitivitySecret values are masked in plans and scrubbed from output.
+	SensitivitySecret Sensitivity = iota
+
+	// SensitivitySensitive values are masked in plans but left in output.
+	// Use for values that should not be recorded in plan artifacts but are
+	// too common to scrub (usernames, hostnames, regions).
+	SensitivitySensitive
+
+	// SensitivityPublic values appear verbatim in plans and output.
+	SensitivityPublic
+)
+
+// String returns the keyword used for the level in source and plans.
+func (s Sensitivity) String() string {
+	switch s {
+	case SensitivitySecret:
+		return "secret"
+	case SensitivitySensitive:
+		return "sensitive"
+	case SensitivityPublic:
+		return "public"
+	default:
+		return fmt.Sprintf("Sensitivity(%d)", int(s))
+	}
+}
+
+// ParseSensitivity parses a sensitivity keyword ("secret", "sensitive" or "public").
+func ParseSensitivity(s string) (Sensitivity, error) {
+	switch s {
+	case "secret":
+		return SensitivitySecret, nil
+	case "sensitive":
+		return SensitivitySensitive, nil
+	case "public":
+		return SensitivityPublic, nil
+	default:
+		return SensitivitySecret, fmt.Errorf("unknown sensitivity %q (expected secret, sensitive or public)", s)
+	}
+}
+
+// Stricter returns the more restrictive of s and other.
+// A value derived from several inputs is as sensitive as its most sensitive input.
+func (s Sensitivity) Stricter(other Sensitivity) Sensitivity {
+	if other < s {
+		return other
+	}
+	return s
+}
+
+// Masked reports whether the value is replaced by a placeholder in plans.
+func (s Sensitivity) Masked() bool {
+	return s != SensitivityPublic
+}
+
+// Scrubbed reports whether the value is scrubbed from output streams.
+func (s Sensitivity) Scrubbed() bool {
+	return s == SensitivitySecret
+}
+
+// Classifier is implemented by value decorators whose sensitivity depends
+// on the call rather than the decorator alone (e.g., @env.GITHUB_TOKEN is
+// secret while @env.HOME is not).
+type Classifier interface {
+	Classify(call ValueCall) Sensitivity
+}
+
+// ValueClassifier is implemented by value decorators that can only judge a
+// value once it is resolved (e.g., @env.DATABASE_URL is secret when the URL
+// carries a password). It can only make the call's classification stricter.
+type ValueClassifier interface {
+	ClassifyValue(call ValueCall, value any) Sensitivity
+}
+
+// Classify returns the sensitivity of the value impl produces for call:
+// the decorator's own classification when it implements Classifier,
+// otherwise the default declared in its Descriptor.
+func Classify(impl Decorator, call ValueCall) Sensitivity {
+	if classifier, ok := impl.(Classifier); ok {
+		return classifier.Classify(call)
+	}
+	return impl.Descriptor().Sensitivity
+}
+
+// ClassifyResolved returns the sensitivity of value, resolved by impl for
+// call: Classify, made stricter by the decorator's ValueClassifier if any.
+func ClassifyResolved(impl Decorator, call ValueCall, value any) Sensitivity {
+	level := Classify(impl, call)
+	if classifier, ok := impl.(ValueClassifier); ok {
+		level = level.Stricter(classifier.ClassifyValue(call, value))
+	}
+	return level
+}
@@ -0,0 +1,88 @@
+package decorator
+
+import "testing"
+
+func TestParseSensitivity(t *testing.T) {
+	for _, level := range []Sensitivity{SensitivitySecret, SensitivitySensitive, SensitivityPublic} {
+		got, err := ParseSensitivity(level.String())
+		if err != nil {
+			t.Fatalf("ParseSensitivity(%q): %v", level, err)
+		}
+		if got != level {
+			t.Errorf("ParseSensitivity(%q) = %v", level, got)
+		}
+	}
+
+	if _, err := ParseSensitivity("private"); err == nil {
+		t.Error("expected error for unknown sensitivity")
+	}
+}
+
+func TestSensitivityStricter(t *testing.T) {
+	tests := []struct {
+		a, b Sensitivity
+		want Sensitivity
+	}{
+		{SensitivityPublic, SensitivityPublic, SensitivityPublic},
+		{SensitivityPublic, SensitivitySensitive, SensitivitySensitive},
+		{SensitivitySecret, SensitivityPublic, SensitivitySecret},
+		{SensitivitySensitive, SensitivitySecret, SensitivitySecret},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Stricter(tt.b); got != tt.want {
+			t.Errorf("%v.Stricter(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+type classifyingDecorator struct{ mockValueDecorator }
+
+func (d *classifyingDecorator) Classify(call ValueCall) Sensitivity {
+	if call.Primary != nil && *call.Primary == "TOKEN" {
+		return SensitivitySecret
+	}
+	return SensitivityPublic
+}
+
+func TestClassify(t *testing.T) {
+	token, home := "TOKEN", "HOME"
+
+	// Without a Classifier the descriptor default applies; the zero value is secret
+	if got := Classify(&mockValueDecorator{path: "plain"}, ValueCall{Primary: &home}); got != SensitivitySecret {
+		t.Errorf("descriptor default: got %v, want secret", got)
+	}
+
+	classifier := &classifyingDecorator{}
+	if got := Classify(classifier, ValueCall{Primary: &token}); got != SensitivitySecret {
+		t.Errorf("classified TOKEN: got %v, want secret", got)
+	}
+	if got := Classify(classifier, ValueCall{Primary: &home}); got != SensitivityPublic {
+		t.Errorf("classified HOME: got %v, want public", got)
+	}
+}
+
+type valueClassifyingDecorator struct{ classifyingDecorator }
+
+func (d *valueClassifyingDecorator) ClassifyValue(_ ValueCall, value any) Sensitivity {
+	if value == "hunter2" {
+		return SensitivitySecret
+	}
+	return SensitivityPublic
+}
+
+func TestClassifyResolved(t *testing.T) {
+	home := "HOME"
+	classifier := &valueClassifyingDecorator{}
+
+	if got := ClassifyResolved(classifier, ValueCall{Primary: &home}, "/root"); got != SensitivityPublic {
+		t.Errorf("plain value: got %v, want public", got)
+	}
+	if got := ClassifyResolved(classifier, ValueCall{Primary: &home}, "hunter2"); got != SensitivitySecret {
+		t.Errorf("credential value: got %v, want secret", got)
+	}
+	// Without a ValueClassifier the call's classification stands
+	if got := ClassifyResolved(&classifyingDecorator{}, ValueCall{Primary: &home}, "hunter2"); got != SensitivityPublic {
+		t.Errorf("no value classifier: got %v, want public", got)
+	}
+}
@@ -24,6 +24,15 @@ type Session interface {
 	// Context controls cancellation and timeouts
 	Get(ctx context.Context, path string) ([]byte, error)
 
+	// PutStream writes src to a file on the session's filesystem without
+	// holding it in memory where the transport allows. Progress is reported
+	// to the callback installed with WithTransferProgress.
+	PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error
+
+	// GetStream copies a file on the session's filesystem to dst.
+	// Progress is reported to the callback installed with WithTransferProgress.
+	GetStream(ctx context.Context, path string, dst io.Writer) error
+
 	// Env returns an immutable snapshot of environment variables
 	Env() map[string]string
 
@@ -66,14 +75,33 @@ type NetworkDialerProvider interface {
 	NetworkDialer() NetworkDialer
 }
 
+// ShellStarter is an optional interface for sessions that can start one
+// long-lived shell and feed it commands on stdin. Shell workers use it to run
+// many commands over a single channel instead of opening one per command.
+type ShellStarter interface {
+	StartShell(argv []string) (ShellProcess, error)
+}
+
+// ShellProcess is a shell started by a ShellStarter.
+type ShellProcess interface {
+	Stdin() io.WriteCloser
+	Stdout() io.Reader
+	Stderr() io.Reader
+
+	// Close stops the shell and releases its channel.
+	Close() error
+}
+
 type sessionUnwrapper interface {
 	UnwrapSession() Session
 }
 
-func GetNetworkDialer(session Session) (NetworkDialer, error) {
+// findSessionCapability returns the first session in the wrapper chain
+// starting at session that implements T.
+func findSessionCapability[T any](session Session) (T, bool) {
 	for session != nil {
-		if provider, ok := session.(NetworkDialerProvider); ok {
-			return provider.NetworkDialer(), nil
+		if capability, ok := session.(T); ok {
+			return capability, true
 		}
 
 		unwrapper, ok := session.(sessionUnwrapper)
@@ -88,9 +116,23 @@ func GetNetworkDialer(session Session) (NetworkDialer, error) {
 		session = next
 	}
 
+	var zero T
+	return zero, false
+}
+
+func GetNetworkDialer(session Session) (NetworkDialer, error) {
+	if provider, ok := findSessionCapability[NetworkDialerProvider](session); ok {
+		return provider.NetworkDialer(), nil
+	}
 	return nil, errors.New("session does not provide network dialer")
 }
 
+// GetShellStarter returns the ShellStarter provided by session or a session
+// it wraps.
+func GetShellStarter(session Session) (ShellStarter, bool) {
+	return findSessionCapability[ShellStarter](session)
+}
+
 func getNetworkDialer(session Session) (NetworkDialer, error) {
 	return GetNetworkDialer(session)
 }
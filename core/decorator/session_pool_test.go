@@ -2,6 +2,7 @@ package decorator
 
 import (
 	"context"
+	"io"
 	"io/fs"
 	"testing"
 )
@@ -244,6 +245,14 @@ func (m *mockSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return nil, nil
 }
 
+func (m *mockSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (m *mockSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (m *mockSession) Env() map[string]string {
 	return nil
 }
@@ -0,0 +1,546 @@
+package decorator
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sshConfigMaxDepth bounds Include recursion, matching OpenSSH.
+const sshConfigMaxDepth = 16
+
+// sshConfig is a parsed OpenSSH client configuration (ssh_config(5)).
+//
+// Only Host blocks are understood. Match blocks are skipped entirely, so
+// options inside them never apply. Include is expanded in place.
+type sshConfig struct {
+	blocks []sshConfigBlock
+}
+
+// sshConfigBlock is a run of options guarded by Host patterns. Options that
+// appear before the first Host line get the pattern "*".
+type sshConfigBlock struct {
+	patterns []string
+	skip     bool // Match block: never applies
+	options  []sshConfigOption
+}
+
+type sshConfigOption struct {
+	key   string // Lowercased keyword
+	value string // Arguments joined by spaces
+	args  []string
+	file  string
+	line  int
+}
+
+// sshSettings are the effective connection settings for one @ssh.connect,
+// after merging explicit params over ~/.ssh/config over defaults.
+type sshSettings struct {
+	Alias          string        // Host as written in the params
+	HostName       string        // Address actually dialed
+	User           string        // Remote user
+	Port           int           // Remote port
+	IdentityFiles  []string      // Candidate private keys, in order
+	CertFiles      []string      // User certificates offered with matching keys
+	Jumps          []sshSettings // Jump hosts, in connection order
+	KnownHostsFile string        // known_hosts path
+	HashKnownHosts bool          // Hash hostnames added to known_hosts
+	TrustedHostKey string        // Fingerprint added by trust on first use
+	ConnectTimeout time.Duration
+	ConfigFile     string            // ssh_config path consulted ("" when none)
+	Mux            string            // Mux agent socket the connection is shared through ("" when direct)
+	Sources        map[string]string // Setting name -> "param", "config" or "default"
+}
+
+// sshConfigPath picks the ssh_config file: the explicit param ("none"
+// disables config lookup, like ssh -F none), then ~/.ssh/config. The second
+// return value is false when the file was not explicitly requested, so a
+// missing default file is not an error.
+func sshConfigPath(params map[string]any) (string, bool) {
+	if path, ok := params["ssh_config"].(string); ok && path != "" {
+		if path == "none" {
+			return "", true
+		}
+		return expandSSHHome(path), true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".ssh", "config"), false
+}
+
+// loadSSHConfig reads and parses an ssh_config file with its Includes.
+func loadSSHConfig(path string) (*sshConfig, error) {
+	config := &sshConfig{}
+	if err := config.parseFile(path, []string{"*"}, false, 0); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parseFile appends the blocks in path. patterns/skip are the enclosing
+// block's guard: an Include inside a Host block is conditional on it, and the
+// guard is restored once the included file ends.
+func (c *sshConfig) parseFile(path string, patterns []string, skip bool, depth int) error {
+	if depth > sshConfigMaxDepth {
+		return fmt.Errorf("%s: Include nested too deeply", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	c.blocks = append(c.blocks, sshConfigBlock{patterns: patterns, skip: skip})
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, args, err := splitSSHConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if key == "" {
+			continue
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("%s:%d: %s requires a value", path, lineNo, key)
+		}
+
+		current := &c.blocks[len(c.blocks)-1]
+		guard, guardSkip := current.patterns, current.skip
+		switch key {
+		case "host":
+			c.blocks = append(c.blocks, sshConfigBlock{patterns: args})
+		case "match":
+			c.blocks = append(c.blocks, sshConfigBlock{skip: true})
+		case "include":
+			for _, pattern := range args {
+				if err := c.include(path, pattern, guard, guardSkip, depth); err != nil {
+					return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+				}
+			}
+			c.blocks = append(c.blocks, sshConfigBlock{patterns: guard, skip: guardSkip})
+		default:
+			current.options = append(current.options, sshConfigOption{
+				key:   key,
+				value: strings.Join(args, " "),
+				args:  args,
+				file:  path,
+				line:  lineNo,
+			})
+		}
+	}
+	return scanner.Err()
+}
+
+// include expands one Include argument. Relative paths are resolved against
+// ~/.ssh, and glob patterns that match nothing are not an error.
+func (c *sshConfig) include(from, pattern string, patterns []string, skip bool, depth int) error {
+	pattern = expandSSHHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("include %s: %w", pattern, err)
+		}
+		pattern = filepath.Join(home, ".ssh", pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("include %s: %w", pattern, err)
+	}
+	for _, match := range matches {
+		if match == from {
+			continue
+		}
+		if err := c.parseFile(match, patterns, skip, depth+1); err != nil {
+			return fmt.Errorf("include %s: %w", match, err)
+		}
+	}
+	return nil
+}
+
+// splitSSHConfigLine splits a config line into a lowercased keyword and its
+// arguments. Both "Key value" and "Key=value" forms are accepted, and
+// double-quoted arguments may contain spaces.
+func splitSSHConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		if rest[0] == '#' {
+			break
+		}
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return "", nil, fmt.Errorf("unterminated quote in %s", key)
+			}
+			args = append(args, rest[1:closing+1])
+			rest = strings.TrimLeft(rest[closing+2:], " \t")
+			continue
+		}
+		next := strings.IndexAny(rest, " \t")
+		if next < 0 {
+			args = append(args, rest)
+			break
+		}
+		args = append(args, rest[:next])
+		rest = strings.TrimLeft(rest[next:], " \t")
+	}
+	return key, args, nil
+}
+
+// lookup returns the options that apply to host. As in OpenSSH, the first
+// value obtained for each keyword wins, except IdentityFile, which
+// accumulates.
+func (c *sshConfig) lookup(host string) map[string][]sshConfigOption {
+	host = strings.ToLower(host)
+	options := make(map[string][]sshConfigOption)
+	for _, block := range c.blocks {
+		if block.skip || !sshHostMatches(host, block.patterns) {
+			continue
+		}
+		for _, option := range block.options {
+			if _, seen := options[option.key]; seen && option.key != "identityfile" && option.key != "certificatefile" {
+				continue
+			}
+			options[option.key] = append(options[option.key], option)
+		}
+	}
+	return options
+}
+
+// sshHostMatches reports whether host matches a Host pattern list: at least
+// one positive pattern matches and no negated pattern does.
+func sshHostMatches(host string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			if sshWildcardMatch(negated, host) {
+				return false
+			}
+			continue
+		}
+		if sshWildcardMatch(pattern, host) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// sshWildcardMatch matches s against a pattern where '*' matches any run of
+// characters and '?' matches exactly one.
+func sshWildcardMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if sshWildcardMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return s == ""
+}
+
+// resolveSSHSettings merges explicit params over the matching ~/.ssh/config
+// entries over built-in defaults.
+func resolveSSHSettings(params map[string]any) (sshSettings, error) {
+	alias, _ := params["host"].(string)
+	settings := sshSettings{
+		Alias:   alias,
+		Sources: make(map[string]string),
+	}
+
+	options := map[string][]sshConfigOption{}
+	if path, explicit := sshConfigPath(params); path != "" {
+		config, err := loadSSHConfig(path)
+		switch {
+		case err == nil:
+			settings.ConfigFile = path
+			options = config.lookup(alias)
+		case explicit || !os.IsNotExist(err):
+			return sshSettings{}, TransportError{
+				Code:    TransportErrorCodeValidationFailed,
+				Message: fmt.Sprintf("failed to read ssh config: %v", err),
+				Cause:   err,
+			}
+		}
+	}
+	first := func(key string) (string, bool) {
+		if values := options[key]; len(values) > 0 {
+			return values[0].value, true
+		}
+		return "", false
+	}
+
+	settings.HostName = alias
+	settings.Sources["hostname"] = "param"
+	if hostName, ok := first("hostname"); ok {
+		settings.HostName = expandSSHTokens(hostName, map[byte]string{'h': alias})
+		settings.Sources["hostname"] = "config"
+	}
+
+	switch user, ok := params["user"].(string); {
+	case ok:
+		settings.User = user
+		settings.Sources["user"] = "param"
+	default:
+		if configUser, found := first("user"); found {
+			settings.User = configUser
+			settings.Sources["user"] = "config"
+		} else {
+			settings.User = os.Getenv("USER")
+			settings.Sources["user"] = "default"
+		}
+	}
+
+	settings.Port = 22
+	settings.Sources["port"] = "default"
+	switch v := params["port"].(type) {
+	case int:
+		settings.Port = v
+		settings.Sources["port"] = "param"
+	case int64:
+		settings.Port = int(v)
+		settings.Sources["port"] = "param"
+	default:
+		if value, ok := first("port"); ok {
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return sshSettings{}, sshConfigValueError(options["port"][0], "invalid port")
+			}
+			settings.Port = port
+			settings.Sources["port"] = "config"
+		}
+	}
+
+	tokens := map[byte]string{
+		'h': settings.HostName,
+		'n': alias,
+		'p': strconv.Itoa(settings.Port),
+		'r': settings.User,
+		'u': os.Getenv("USER"),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		tokens['d'] = home
+	}
+
+	if _, ok := params["key"]; ok {
+		settings.Sources["identity"] = "param"
+	} else if identities := options["identityfile"]; len(identities) > 0 {
+		for _, identity := range identities {
+			if strings.EqualFold(identity.value, "none") {
+				continue
+			}
+			settings.IdentityFiles = append(settings.IdentityFiles, expandSSHHome(expandSSHTokens(identity.value, tokens)))
+		}
+		settings.Sources["identity"] = "config"
+	}
+
+	if cert, ok := params["cert"].(string); ok {
+		settings.CertFiles = []string{expandSSHHome(cert)}
+		settings.Sources["certificate"] = "param"
+	} else if certs := options["certificatefile"]; len(certs) > 0 {
+		for _, cert := range certs {
+			settings.CertFiles = append(settings.CertFiles, expandSSHHome(expandSSHTokens(cert.value, tokens)))
+		}
+		settings.Sources["certificate"] = "config"
+	}
+
+	jumps, explicit, err := sshJumpSpecs(params["jump"])
+	if err != nil {
+		return sshSettings{}, err
+	}
+	if explicit {
+		settings.Sources["jump"] = "param"
+	} else if proxyJump, ok := first("proxyjump"); ok && !strings.EqualFold(proxyJump, "none") {
+		jumps, _, err = sshJumpSpecs(proxyJump)
+		if err != nil {
+			return sshSettings{}, sshConfigValueError(options["proxyjump"][0], "invalid ProxyJump")
+		}
+		settings.Sources["jump"] = "config"
+	}
+	for _, spec := range jumps {
+		hop, err := resolveSSHJumpHop(spec, params)
+		if err != nil {
+			return sshSettings{}, err
+		}
+		settings.Jumps = append(settings.Jumps, hop)
+	}
+
+	settings.KnownHostsFile = os.ExpandEnv("$HOME/.ssh/known_hosts")
+	settings.Sources["known_hosts"] = "default"
+	if path, ok := params["known_hosts_path"].(string); ok {
+		settings.KnownHostsFile = path
+		settings.Sources["known_hosts"] = "param"
+	} else if files, ok := first("userknownhostsfile"); ok && !strings.EqualFold(files, "none") {
+		settings.KnownHostsFile = expandSSHHome(expandSSHTokens(options["userknownhostsfile"][0].args[0], tokens))
+		settings.Sources["known_hosts"] = "config"
+	}
+	if value, ok := first("hashknownhosts"); ok {
+		settings.HashKnownHosts = strings.EqualFold(value, "yes")
+	}
+
+	if value, ok := first("connecttimeout"); ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return sshSettings{}, sshConfigValueError(options["connecttimeout"][0], "invalid ConnectTimeout")
+		}
+		settings.ConnectTimeout = time.Duration(seconds) * time.Second
+	}
+
+	return settings, nil
+}
+
+func sshConfigValueError(option sshConfigOption, message string) error {
+	return TransportError{
+		Code:    TransportErrorCodeValidationFailed,
+		Message: fmt.Sprintf("%s:%d: %s %q", option.file, option.line, message, option.value),
+	}
+}
+
+// expandSSHTokens expands %-tokens (%h, %p, %r, ...) and %%.
+func expandSSHTokens(value string, tokens map[byte]string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		if value[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if expansion, ok := tokens[value[i]]; ok {
+			out.WriteString(expansion)
+			continue
+		}
+		out.WriteByte('%')
+		out.WriteByte(value[i])
+	}
+	return out.String()
+}
+
+// expandSSHHome expands a leading ~/ to the user's home directory.
+func expandSSHHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// Event describes the effective settings for --debug and the execution
+// receipt. Identity file paths are included; key material never is.
+func (s sshSettings) Event() Event {
+	attrs := map[string]string{
+		"host":     s.Alias,
+		"hostname": s.HostName,
+		"user":     s.User,
+		"port":     strconv.Itoa(s.Port),
+		"sources":  s.sourcesString(),
+	}
+	if s.ConfigFile != "" {
+		attrs["config"] = s.ConfigFile
+	}
+	if len(s.IdentityFiles) > 0 {
+		attrs["identity"] = strings.Join(s.IdentityFiles, ",")
+	}
+	if len(s.CertFiles) > 0 {
+		attrs["certificate"] = strings.Join(s.CertFiles, ",")
+	}
+	if len(s.Jumps) > 0 {
+		hops := make([]string, len(s.Jumps))
+		for i, hop := range s.Jumps {
+			hops[i] = hop.target()
+		}
+		attrs["jump"] = strings.Join(hops, ",")
+	}
+	if s.KnownHostsFile != "" {
+		attrs["known_hosts"] = s.KnownHostsFile
+	}
+	if s.ConnectTimeout > 0 {
+		attrs["connect_timeout"] = s.ConnectTimeout.String()
+	}
+	if s.Mux != "" {
+		attrs["mux"] = s.Mux
+	}
+	return Event{Decorator: "ssh.connect", Kind: "connect", Attrs: attrs}
+}
+
+// trustEvent records a host key added to known_hosts while connecting.
+func (s sshSettings) trustEvent() (Event, bool) {
+	if s.TrustedHostKey == "" {
+		return Event{}, false
+	}
+	return Event{
+		Decorator: "ssh.connect",
+		Kind:      "host-key-trusted",
+		Attrs: map[string]string{
+			"host":        s.target(),
+			"fingerprint": s.TrustedHostKey,
+			"known_hosts": s.KnownHostsFile,
+		},
+	}, true
+}
+
+func (s sshSettings) sourcesString() string {
+	names := []string{"hostname", "user", "port", "identity", "certificate", "jump", "known_hosts"}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if source, ok := s.Sources[name]; ok {
+			parts = append(parts, name+"="+source)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// target renders the settings as user@hostname:port.
+func (s sshSettings) target() string {
+	return s.User + "@" + net.JoinHostPort(s.HostName, strconv.Itoa(s.Port))
+}
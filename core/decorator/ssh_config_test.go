@@ -0,0 +1,295 @@
+package decorator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeSSHConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveSSHSettingsFromConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USER", "local-user")
+	path := writeSSHConfig(t, home, ".ssh/config", `
+# Team defaults
+Host prod-bastion
+    HostName bastion.prod.example.com
+    User ops
+    Port 2222
+    IdentityFile ~/.ssh/id_prod
+    ConnectTimeout 5
+
+Host prod-*
+    User nobody
+    IdentityFile ~/.ssh/%h_%r
+
+Host *
+    IdentityFile=~/.ssh/id_default
+    UserKnownHostsFile "~/.ssh/known hosts"
+`)
+
+	settings, err := resolveSSHSettings(map[string]any{"host": "prod-bastion"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if diff := cmp.Diff(path, settings.ConfigFile); diff != "" {
+		t.Errorf("config file mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("bastion.prod.example.com", settings.HostName); diff != "" {
+		t.Errorf("hostname mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("ops", settings.User); diff != "" {
+		t.Errorf("first User must win (-want +got):\n%s", diff)
+	}
+	if settings.Port != 2222 {
+		t.Errorf("port: got %d, want 2222", settings.Port)
+	}
+	wantIdentities := []string{
+		filepath.Join(home, ".ssh/id_prod"),
+		filepath.Join(home, ".ssh/bastion.prod.example.com_ops"),
+		filepath.Join(home, ".ssh/id_default"),
+	}
+	if diff := cmp.Diff(wantIdentities, settings.IdentityFiles); diff != "" {
+		t.Errorf("IdentityFile must accumulate in order (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(filepath.Join(home, ".ssh/known hosts"), settings.KnownHostsFile); diff != "" {
+		t.Errorf("known hosts mismatch (-want +got):\n%s", diff)
+	}
+	if settings.ConnectTimeout != 5*time.Second {
+		t.Errorf("connect timeout: got %s, want 5s", settings.ConnectTimeout)
+	}
+	if diff := cmp.Diff("hostname=config,user=config,port=config,identity=config,known_hosts=config", settings.sourcesString()); diff != "" {
+		t.Errorf("sources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveSSHSettingsExplicitParamsWin(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSSHConfig(t, home, ".ssh/config", `
+Host app
+    HostName app.internal
+    User ops
+    Port 2222
+    IdentityFile ~/.ssh/id_app
+`)
+
+	settings, err := resolveSSHSettings(map[string]any{
+		"host":             "app",
+		"user":             "deploy",
+		"port":             22,
+		"key":              "/keys/deploy",
+		"known_hosts_path": "/etc/known_hosts",
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if settings.HostName != "app.internal" {
+		t.Errorf("host alias must still resolve: got %q", settings.HostName)
+	}
+	if settings.User != "deploy" || settings.Port != 22 {
+		t.Errorf("params must win: user=%q port=%d", settings.User, settings.Port)
+	}
+	if len(settings.IdentityFiles) != 0 {
+		t.Errorf("explicit key must replace config identities, got %v", settings.IdentityFiles)
+	}
+	if settings.KnownHostsFile != "/etc/known_hosts" {
+		t.Errorf("known hosts: got %q", settings.KnownHostsFile)
+	}
+}
+
+func TestResolveSSHSettingsWithoutConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USER", "local-user")
+
+	settings, err := resolveSSHSettings(map[string]any{"host": "example.com"})
+	if err != nil {
+		t.Fatalf("missing default config must not fail: %v", err)
+	}
+	if settings.ConfigFile != "" || settings.HostName != "example.com" || settings.User != "local-user" || settings.Port != 22 {
+		t.Errorf("unexpected defaults: %+v", settings)
+	}
+
+	_, err = resolveSSHSettings(map[string]any{"host": "example.com", "ssh_config": filepath.Join(home, "missing")})
+	if err == nil || !strings.Contains(err.Error(), "failed to read ssh config") {
+		t.Errorf("missing explicit config: expected error, got %v", err)
+	}
+
+	writeSSHConfig(t, home, ".ssh/config", "Host example.com\n    Port 2200\n")
+	settings, err = resolveSSHSettings(map[string]any{"host": "example.com", "ssh_config": "none"})
+	if err != nil || settings.Port != 22 {
+		t.Errorf("ssh_config=none must skip the config: port=%d err=%v", settings.Port, err)
+	}
+}
+
+func TestSSHConfigPatternsMatchAndInclude(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSSHConfig(t, home, ".ssh/conf.d/10-db.conf", `
+Host db-?
+    User dba
+`)
+	writeSSHConfig(t, home, ".ssh/conf.d/20-web.conf", `
+Host web
+    User www
+`)
+	writeSSHConfig(t, home, ".ssh/scoped.conf", `
+Port 2022
+`)
+	path := writeSSHConfig(t, home, ".ssh/config", `
+Include conf.d/*.conf
+
+Host staging-*
+    Include scoped.conf
+    User stage
+
+Host *.example.com !legacy.example.com
+    User modern
+
+Match host legacy.example.com
+    User matched
+
+Host *
+    User fallback
+`)
+
+	config, err := loadSSHConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		user string
+		port string
+	}{
+		{host: "db-1", user: "dba"},
+		{host: "db-10", user: "fallback"},
+		{host: "web", user: "www"},
+		{host: "WEB", user: "www"},
+		{host: "staging-api", user: "stage", port: "2022"},
+		{host: "api.example.com", user: "modern"},
+		{host: "legacy.example.com", user: "fallback"},
+		{host: "other", user: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			options := config.lookup(tt.host)
+			if diff := cmp.Diff(tt.user, options["user"][0].value); diff != "" {
+				t.Errorf("user mismatch (-want +got):\n%s", diff)
+			}
+			port := ""
+			if values := options["port"]; len(values) > 0 {
+				port = values[0].value
+			}
+			if diff := cmp.Diff(tt.port, port); diff != "" {
+				t.Errorf("Include inside Host must stay conditional (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSSHConfigParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "missing value", content: "Host app\n    User\n", want: "config:2: user requires a value"},
+		{name: "unterminated quote", content: "IdentityFile \"~/.ssh/a b\n", want: "unterminated quote"},
+		{name: "include loop", content: "Include " + filepath.Join(dir, "loop") + "\n", want: "nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSSHConfig(t, dir, "config", tt.content)
+			if tt.name == "include loop" {
+				writeSSHConfig(t, dir, "loop", "Include "+path+"\n")
+			}
+			_, err := loadSSHConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	path := writeSSHConfig(t, dir, "bad-port", "Port ssh\n")
+	_, err := resolveSSHSettings(map[string]any{"host": "x", "ssh_config": path})
+	if err == nil || !strings.Contains(err.Error(), `bad-port:1: invalid port "ssh"`) {
+		t.Errorf("bad port: got %v", err)
+	}
+}
+
+func TestSSHSessionConnectsThroughConfigAlias(t *testing.T) {
+	server := getSSHTestServer(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	keyPath := writeSSHConfig(t, home, ".ssh/id_test", string(server.ClientKeyPEM))
+	writeSSHConfig(t, home, ".ssh/config", `
+Host test-alias
+    HostName 127.0.0.1
+    Port `+strconv.Itoa(server.Port)+`
+    User `+os.Getenv("USER")+`
+    IdentityFile ~/.ssh/id_test
+`)
+
+	transport := &SSHTransport{}
+	var events []Event
+	node := transport.Wrap(execNodeFunc(func(ctx ExecContext) (Result, error) {
+		return ctx.Session.Run(ctx.Context, []string{"echo", "via-alias"}, RunOpts{})
+	}), map[string]any{"host": "test-alias", "strict_host_key": false})
+
+	result, err := node.Execute(ExecContext{
+		Context: context.Background(),
+		Session: NewLocalSession(),
+		Events:  eventRecorder(func(event Event) { events = append(events, event) }),
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("execute through alias: exit=%d err=%v", result.ExitCode, err)
+	}
+	if diff := cmp.Diff("via-alias\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected one connect event, got %d", len(events))
+	}
+	want := map[string]string{
+		"host":        "test-alias",
+		"hostname":    "127.0.0.1",
+		"user":        os.Getenv("USER"),
+		"port":        strconv.Itoa(server.Port),
+		"config":      filepath.Join(home, ".ssh/config"),
+		"identity":    keyPath,
+		"known_hosts": filepath.Join(home, ".ssh/known_hosts"),
+		"sources":     "hostname=config,user=config,port=config,identity=config,known_hosts=default",
+	}
+	if diff := cmp.Diff(want, events[0].Attrs); diff != "" {
+		t.Errorf("connect event mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type eventRecorder func(Event)
+
+func (r eventRecorder) Emit(event Event) { r(event) }
@@ -0,0 +1,332 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/types"
+)
+
+// SSHEach implements @ssh.each, which runs its block on every host in a list.
+// The planner expands the block into one @ssh.connect block per host (see
+// FanOut); at execution time SSHEach schedules those blocks concurrently,
+// prefixes their output with the host and prints a per-host summary.
+type SSHEach struct{}
+
+func init() {
+	if err := Register("ssh.each", &SSHEach{}); err != nil {
+		panic(fmt.Sprintf("failed to register @ssh.each decorator: %v", err))
+	}
+}
+
+// sshEachConnectExcluded lists @ssh.connect params @ssh.each does not take:
+// the host comes from hosts, and a fan-out always runs a block.
+var sshEachConnectExcluded = map[string]bool{"host": true, "command": true, "shell": true}
+
+// sshEachDescriptor builds the params that belong to @ssh.each itself.
+func sshEachDescriptor() *DescriptorBuilder {
+	return NewDescriptor("ssh.each").
+		Summary("Run block on every host in a list over SSH").
+		Roles(RoleWrapper).
+		ParamArray("hosts", "Hosts to run the block on, in order").
+		ElementType(types.TypeString).
+		MinLength(1).
+		UniqueItems().
+		Required().
+		Examples(`["web01", "web02"]`).
+		Done().
+		ParamInt("maxConcurrency", "Maximum hosts running at once (0=unlimited)").
+		Min(0).
+		Default(int64(0)).
+		Examples("0", "10").
+		Done().
+		ParamInt("failureThreshold", "Stop starting new hosts after this many failures (0=never)").
+		Min(0).
+		Default(int64(0)).
+		Examples("1", "2").
+		Done().
+		Block(BlockRequired)
+}
+
+// Descriptor returns the @ssh.each params followed by the @ssh.connect
+// connection params, which apply to every host.
+func (d *SSHEach) Descriptor() Descriptor {
+	desc := sshEachDescriptor().Build()
+	connect := (&SSHTransport{}).Descriptor().Schema
+	for _, name := range connect.ParameterOrder {
+		if sshEachConnectExcluded[name] {
+			continue
+		}
+		desc.Schema.Parameters[name] = connect.Parameters[name]
+		desc.Schema.ParameterOrder = append(desc.Schema.ParameterOrder, name)
+	}
+	return desc
+}
+
+// FanOutTargets validates params and splits them into the fan-out settings,
+// kept on @ssh.each, and one set of @ssh.connect params per host.
+func (d *SSHEach) FanOutTargets(params map[string]any) (FanOutPlan, error) {
+	decoder := CompileDecoder(d.Descriptor().Schema)
+	canonical, _, err := decoder.NormalizeArgs(nil, params)
+	if err != nil {
+		return FanOutPlan{}, fmt.Errorf("invalid @ssh.each params: %w", err)
+	}
+	// ValidateArgs fills in defaults; keep them out of the plan so each
+	// @ssh.connect applies its own.
+	if _, err := decoder.ValidateArgs(maps.Clone(canonical)); err != nil {
+		return FanOutPlan{}, fmt.Errorf("invalid @ssh.each params: %w", err)
+	}
+
+	own := make(map[string]any)
+	connect := make(map[string]any)
+	ownSchema := sshEachDescriptor().Build().Schema
+	for name, value := range canonical {
+		if _, ok := ownSchema.Parameters[name]; ok {
+			own[name] = value
+			continue
+		}
+		connect[name] = value
+	}
+
+	hosts, ok := own["hosts"].([]any)
+	if !ok {
+		return FanOutPlan{}, fmt.Errorf("invalid @ssh.each params: hosts must be a list, got %T", own["hosts"])
+	}
+	targets := make([]map[string]any, len(hosts))
+	for i, host := range hosts {
+		if host == "" {
+			return FanOutPlan{}, fmt.Errorf("invalid @ssh.each params: hosts[%d] is empty", i)
+		}
+		target := maps.Clone(connect)
+		target["host"] = host
+		targets[i] = target
+	}
+
+	return FanOutPlan{Transport: "@ssh.connect", Params: own, Targets: targets}, nil
+}
+
+func (d *SSHEach) Wrap(next ExecNode, params map[string]any) ExecNode {
+	return &sshEachNode{next: next, params: params}
+}
+
+type sshEachNode struct {
+	next   ExecNode
+	params map[string]any
+}
+
+type sshEachConfig struct {
+	Hosts            []any `decorator:"hosts"`
+	MaxConcurrency   int   `decorator:"maxConcurrency"`
+	FailureThreshold int   `decorator:"failureThreshold"`
+}
+
+// sshHostStatus is the outcome of one host in an @ssh.each run.
+type sshHostStatus string
+
+const (
+	sshHostOK       sshHostStatus = "ok"
+	sshHostFailed   sshHostStatus = "failed"
+	sshHostCanceled sshHostStatus = "canceled"
+	sshHostSkipped  sshHostStatus = "skipped" // Never started
+)
+
+type sshHostResult struct {
+	Host     string
+	Status   sshHostStatus
+	ExitCode int
+	Duration time.Duration
+	err      error
+}
+
+// Execute runs one block branch per host. A new host starts only while fewer
+// than failureThreshold hosts have failed; hosts already running finish.
+func (n *sshEachNode) Execute(ctx ExecContext) (Result, error) {
+	cfg, _, err := DecodeInto[sshEachConfig](sshEachDescriptor().Build().Schema, nil, n.params)
+	if err != nil {
+		return Result{ExitCode: ExitFailure}, fmt.Errorf("invalid @ssh.each params: %w", err)
+	}
+
+	branches, ok := n.next.(BranchExecutor)
+	if !ok || branches.BranchCount() != len(cfg.Hosts) {
+		return Result{ExitCode: ExitFailure}, fmt.Errorf("@ssh.each: block was not expanded to one branch per host")
+	}
+
+	stdout := ctx.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	stderr := ctx.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	runCtx := ctx.Context
+	if runCtx == nil {
+		runCtx = context.Background()
+	}
+
+	maxConcurrency := len(cfg.Hosts)
+	if cfg.MaxConcurrency > 0 && cfg.MaxConcurrency < maxConcurrency {
+		maxConcurrency = cfg.MaxConcurrency
+	}
+
+	results := make([]sshHostResult, len(cfg.Hosts))
+	for i, host := range cfg.Hosts {
+		results[i] = sshHostResult{Host: fmt.Sprint(host), Status: sshHostSkipped}
+	}
+
+	var outMu, errMu, failMu sync.Mutex
+	failures := 0
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+
+schedule:
+	for i := range results {
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+			break schedule
+		}
+
+		failMu.Lock()
+		stop := cfg.FailureThreshold > 0 && failures >= cfg.FailureThreshold
+		failMu.Unlock()
+		if stop || runCtx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			prefix := "[" + results[i].Host + "] "
+			hostOut := &linePrefixWriter{mu: &outMu, out: stdout, prefix: prefix}
+			hostErr := &linePrefixWriter{mu: &errMu, out: stderr, prefix: prefix}
+
+			start := time.Now()
+			result, err := branches.ExecuteBranch(i, ctx.WithContext(runCtx).WithIO(nil, hostOut, hostErr))
+			hostOut.Flush()
+			hostErr.Flush()
+
+			status := sshHostOK
+			switch {
+			case result.ExitCode == ExitCanceled:
+				status = sshHostCanceled
+			case err != nil || result.ExitCode != 0:
+				status = sshHostFailed
+			}
+			results[i] = sshHostResult{
+				Host:     results[i].Host,
+				Status:   status,
+				ExitCode: result.ExitCode,
+				Duration: time.Since(start),
+				err:      err,
+			}
+
+			if status != sshHostOK {
+				failMu.Lock()
+				failures++
+				failMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	errMu.Lock()
+	writeSSHEachSummary(stderr, results, cfg.FailureThreshold)
+	errMu.Unlock()
+
+	for _, result := range results {
+		if result.Status == sshHostFailed || result.Status == sshHostCanceled {
+			return Result{ExitCode: result.ExitCode}, result.err
+		}
+	}
+	for _, result := range results {
+		if result.Status == sshHostSkipped {
+			// Only reachable through cancellation before the host started.
+			return Result{ExitCode: ExitCanceled}, runCtx.Err()
+		}
+	}
+	return Result{ExitCode: 0}, nil
+}
+
+// writeSSHEachSummary prints the per-host result table.
+func writeSSHEachSummary(w io.Writer, results []sshHostResult, failureThreshold int) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "HOST\tSTATUS\tEXIT\tDURATION")
+	failed, skipped := 0, 0
+	for _, result := range results {
+		exit, duration := "-", "-"
+		switch result.Status {
+		case sshHostSkipped:
+			skipped++
+		case sshHostFailed, sshHostCanceled:
+			failed++
+			fallthrough
+		default:
+			exit = fmt.Sprint(result.ExitCode)
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Host, result.Status, exit, duration)
+	}
+	_ = table.Flush()
+
+	if skipped > 0 && failureThreshold > 0 && failed >= failureThreshold {
+		_, _ = fmt.Fprintf(w, "@ssh.each: stopped after %d failed hosts (failureThreshold=%d); %d hosts not started\n",
+			failed, failureThreshold, skipped)
+	}
+}
+
+// linePrefixWriter writes whole lines to out, each starting with prefix. The
+// mutex is shared by every writer on the same out, so lines from concurrent
+// hosts never interleave.
+type linePrefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+
+	bufMu sync.Mutex
+	buf   []byte
+}
+
+func (w *linePrefixWriter) Write(p []byte) (int, error) {
+	w.bufMu.Lock()
+	defer w.bufMu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing partial line, ending it with a newline.
+func (w *linePrefixWriter) Flush() {
+	w.bufMu.Lock()
+	defer w.bufMu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *linePrefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.out, w.prefix)
+	_, _ = w.out.Write(line)
+}
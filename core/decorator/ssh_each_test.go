@@ -0,0 +1,237 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// hostBranches is a BranchExecutor with one scripted branch per host.
+type hostBranches struct {
+	branches []func(ctx ExecContext) (Result, error)
+
+	mu      sync.Mutex
+	started []int
+}
+
+func (b *hostBranches) Execute(ctx ExecContext) (Result, error) {
+	return Result{}, fmt.Errorf("Execute called on fan-out block")
+}
+
+func (b *hostBranches) BranchCount() int {
+	return len(b.branches)
+}
+
+func (b *hostBranches) ExecuteBranch(index int, ctx ExecContext) (Result, error) {
+	b.mu.Lock()
+	b.started = append(b.started, index)
+	b.mu.Unlock()
+	return b.branches[index](ctx)
+}
+
+func hostsParam(hosts ...string) []any {
+	out := make([]any, len(hosts))
+	for i, host := range hosts {
+		out[i] = host
+	}
+	return out
+}
+
+var summaryDurations = regexp.MustCompile(`\d+(\.\d+)?(ns|µs|ms|s)\b`)
+
+func TestSSHEachFanOutTargets(t *testing.T) {
+	plan, err := (&SSHEach{}).FanOutTargets(map[string]any{
+		"arg1":             hostsParam("web01", "web02"),
+		"failureThreshold": int64(2),
+		"user":             "deploy",
+		"jump":             []any{"bastion"},
+	})
+	if err != nil {
+		t.Fatalf("FanOutTargets: %v", err)
+	}
+
+	want := FanOutPlan{
+		Transport: "@ssh.connect",
+		Params: map[string]any{
+			"hosts":            hostsParam("web01", "web02"),
+			"failureThreshold": int64(2),
+		},
+		Targets: []map[string]any{
+			{"host": "web01", "user": "deploy", "jump": []any{"bastion"}},
+			{"host": "web02", "user": "deploy", "jump": []any{"bastion"}},
+		},
+	}
+	if diff := cmp.Diff(want, plan); diff != "" {
+		t.Errorf("fan-out plan mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHEachFanOutTargetsValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{
+			name:   "missing hosts",
+			params: map[string]any{"user": "deploy"},
+			want:   `missing required parameter "hosts"`,
+		},
+		{
+			name:   "host is not a connect param",
+			params: map[string]any{"hosts": hostsParam("web01"), "host": "web02"},
+			want:   `unknown parameter "host"`,
+		},
+		{
+			name:   "bad connect param",
+			params: map[string]any{"hosts": hostsParam("web01"), "port": int64(0)},
+			want:   `invalid @ssh.each params`,
+		},
+		{
+			name:   "empty host",
+			params: map[string]any{"hosts": hostsParam("web01", "")},
+			want:   `hosts[1] is empty`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&SSHEach{}).FanOutTargets(tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSSHEachPrefixesOutputPerHost(t *testing.T) {
+	next := &hostBranches{branches: []func(ctx ExecContext) (Result, error){
+		func(ctx ExecContext) (Result, error) {
+			_, _ = ctx.Stdout.Write([]byte("stopping\nstart"))
+			_, _ = ctx.Stdout.Write([]byte("ed\npartial"))
+			_, _ = ctx.Stderr.Write([]byte("warning: slow\n"))
+			return Result{ExitCode: 0}, nil
+		},
+		func(ctx ExecContext) (Result, error) {
+			_, _ = ctx.Stdout.Write([]byte("started\n"))
+			return Result{ExitCode: 0}, nil
+		},
+	}}
+	node := (&SSHEach{}).Wrap(next, map[string]any{"hosts": hostsParam("web01", "web02"), "maxConcurrency": int64(1)})
+
+	var stdout, stderr bytes.Buffer
+	result, err := node.Execute(ExecContext{Context: context.Background(), Stdout: &stdout, Stderr: &stderr})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute: exit %d, err %v", result.ExitCode, err)
+	}
+
+	wantStdout := "[web01] stopping\n[web01] started\n[web01] partial\n[web02] started\n"
+	if diff := cmp.Diff(wantStdout, stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	wantStderr := "[web01] warning: slow\n" +
+		"HOST   STATUS  EXIT  DURATION\n" +
+		"web01  ok      0     D\n" +
+		"web02  ok      0     D\n"
+	if diff := cmp.Diff(wantStderr, summaryDurations.ReplaceAllString(stderr.String(), "D")); diff != "" {
+		t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHEachLimitsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	branch := func(ctx ExecContext) (Result, error) {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return Result{ExitCode: 0}, nil
+	}
+	next := &hostBranches{branches: []func(ctx ExecContext) (Result, error){branch, branch, branch, branch, branch}}
+	node := (&SSHEach{}).Wrap(next, map[string]any{
+		"hosts":          hostsParam("h1", "h2", "h3", "h4", "h5"),
+		"maxConcurrency": int64(2),
+	})
+
+	var stderr bytes.Buffer
+	if _, err := node.Execute(ExecContext{Context: context.Background(), Stdout: &bytes.Buffer{}, Stderr: &stderr}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency: got %d, want 2", got)
+	}
+}
+
+func TestSSHEachStopsSchedulingAtFailureThreshold(t *testing.T) {
+	ok := func(ctx ExecContext) (Result, error) { return Result{ExitCode: 0}, nil }
+	fail := func(ctx ExecContext) (Result, error) { return Result{ExitCode: 3}, nil }
+	next := &hostBranches{branches: []func(ctx ExecContext) (Result, error){ok, fail, ok, fail, ok, ok}}
+	node := (&SSHEach{}).Wrap(next, map[string]any{
+		"hosts":            hostsParam("h1", "h2", "h3", "h4", "h5", "h6"),
+		"maxConcurrency":   int64(1),
+		"failureThreshold": int64(2),
+	})
+
+	var stderr bytes.Buffer
+	result, err := node.Execute(ExecContext{Context: context.Background(), Stdout: &bytes.Buffer{}, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if diff := cmp.Diff(3, result.ExitCode); diff != "" {
+		t.Errorf("exit code must be the first failed host's (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{0, 1, 2, 3}, next.started); diff != "" {
+		t.Errorf("started hosts mismatch (-want +got):\n%s", diff)
+	}
+
+	wantStderr := "HOST  STATUS   EXIT  DURATION\n" +
+		"h1    ok       0     D\n" +
+		"h2    failed   3     D\n" +
+		"h3    ok       0     D\n" +
+		"h4    failed   3     D\n" +
+		"h5    skipped  -     -\n" +
+		"h6    skipped  -     -\n" +
+		"@ssh.each: stopped after 2 failed hosts (failureThreshold=2); 2 hosts not started\n"
+	if diff := cmp.Diff(wantStderr, summaryDurations.ReplaceAllString(stderr.String(), "D")); diff != "" {
+		t.Errorf("summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHEachRunsEveryHostWithoutThreshold(t *testing.T) {
+	fail := func(ctx ExecContext) (Result, error) { return Result{ExitCode: 1}, nil }
+	next := &hostBranches{branches: []func(ctx ExecContext) (Result, error){fail, fail, fail}}
+	node := (&SSHEach{}).Wrap(next, map[string]any{"hosts": hostsParam("h1", "h2", "h3"), "maxConcurrency": int64(1)})
+
+	result, _ := node.Execute(ExecContext{Context: context.Background(), Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+	if diff := cmp.Diff(1, result.ExitCode); diff != "" {
+		t.Errorf("exit code mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{0, 1, 2}, next.started); diff != "" {
+		t.Errorf("failureThreshold=0 must run every host (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHEachRequiresExpandedBlock(t *testing.T) {
+	next := &hostBranches{branches: []func(ctx ExecContext) (Result, error){
+		func(ctx ExecContext) (Result, error) { return Result{ExitCode: 0}, nil },
+	}}
+	node := (&SSHEach{}).Wrap(next, map[string]any{"hosts": hostsParam("h1", "h2")})
+
+	result, err := node.Execute(ExecContext{Context: context.Background()})
+	if err == nil || result.ExitCode != ExitFailure {
+		t.Fatalf("expected failure for a block that does not match hosts, got exit %d, err %v", result.ExitCode, err)
+	}
+}
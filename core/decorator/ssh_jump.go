@@ -0,0 +1,323 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshJumpSpecs decodes a jump host list: a list of "[user@]host[:port]"
+// strings, or a comma-separated string as in ProxyJump. "none" and an empty
+// list disable jumping. The bool reports whether value was set at all.
+func sshJumpSpecs(value any) ([]string, bool, error) {
+	var specs []string
+	switch v := value.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		if strings.EqualFold(strings.TrimSpace(v), "none") {
+			return nil, true, nil
+		}
+		specs = strings.Split(v, ",")
+	case []string:
+		specs = v
+	case []any:
+		for _, item := range v {
+			spec, ok := item.(string)
+			if !ok {
+				return nil, true, sshJumpError(fmt.Sprintf("jump hosts must be strings, got %T", item))
+			}
+			specs = append(specs, spec)
+		}
+	default:
+		return nil, true, sshJumpError(fmt.Sprintf("jump must be a list of hosts, got %T", value))
+	}
+
+	cleaned := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			return nil, true, sshJumpError("jump host cannot be empty")
+		}
+		cleaned = append(cleaned, spec)
+	}
+	return cleaned, true, nil
+}
+
+// resolveSSHJumpHop resolves one "[ssh://][user@]host[:port]" hop through
+// ssh config like any other host. Settings that describe how to verify and
+// find hosts (known_hosts, ssh_config) carry over from the final host's
+// params; credentials do not, as with ssh -J. A hop's own ProxyJump is not
+// followed.
+func resolveSSHJumpHop(spec string, params map[string]any) (sshSettings, error) {
+	hopParams := map[string]any{"jump": "none"}
+	for _, key := range []string{"ssh_config", "known_hosts_path"} {
+		if value, ok := params[key]; ok {
+			hopParams[key] = value
+		}
+	}
+
+	rest := strings.TrimPrefix(spec, "ssh://")
+	if user, host, ok := strings.Cut(rest, "@"); ok {
+		if user == "" {
+			return sshSettings{}, sshJumpError(fmt.Sprintf("invalid jump host %q: empty user", spec))
+		}
+		hopParams["user"] = user
+		rest = host
+	}
+
+	host := rest
+	if h, portStr, err := net.SplitHostPort(rest); err == nil {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return sshSettings{}, sshJumpError(fmt.Sprintf("invalid jump host %q: bad port %q", spec, portStr))
+		}
+		host = h
+		hopParams["port"] = port
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		return sshSettings{}, sshJumpError(fmt.Sprintf("invalid jump host %q: empty host", spec))
+	}
+	hopParams["host"] = host
+
+	hop, err := resolveSSHSettings(hopParams)
+	if err != nil {
+		return sshSettings{}, err
+	}
+	if hop.Port < 1 || hop.Port > 65535 {
+		return sshSettings{}, sshJumpError(fmt.Sprintf("invalid jump host %q: port must be between 1 and 65535", spec))
+	}
+	return hop, nil
+}
+
+func sshJumpError(message string) error {
+	return TransportError{
+		Code:      TransportErrorCodeValidationFailed,
+		Message:   message,
+		Retryable: false,
+	}
+}
+
+// sshAuthMethods builds the public key auth for one host: the explicit key
+// param, then IdentityFile entries, then the agent as a fallback. The keys
+// form a single method because the client only tries the first method of
+// each kind. A key with a matching user certificate offers the certificate
+// first.
+func sshAuthMethods(params map[string]any, settings sshSettings) ([]ssh.AuthMethod, error) {
+	certs, err := loadSSHUserCerts(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []ssh.Signer
+	var keyPaths []string
+
+	// Try direct signer first (for testing)
+	switch key := params["key"].(type) {
+	case ssh.Signer:
+		signers = append(signers, key)
+	case string:
+		keyPaths = append(keyPaths, key)
+	}
+
+	// IdentityFile entries from ssh config; missing files are skipped as ssh does
+	keyPaths = append(keyPaths, settings.IdentityFiles...)
+	for _, keyPath := range keyPaths {
+		signer := sshKeySigner(keyPath)
+		if signer == nil {
+			continue
+		}
+		signers = append(signers, signer)
+
+		// Like ssh, pick up a certificate stored next to the key
+		if cert, err := loadSSHUserCert(keyPath + "-cert.pub"); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+
+	if len(signers) > 0 {
+		return []ssh.AuthMethod{ssh.PublicKeys(sshCertSigners(signers, certs)...)}, nil
+	}
+
+	// Fall back to SSH agent
+	if agentSigners := sshAgentSigners(); agentSigners != nil {
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			signers, err := agentSigners()
+			if err != nil {
+				return nil, err
+			}
+			return sshCertSigners(signers, certs), nil
+		})}, nil
+	}
+
+	return nil, nil
+}
+
+// loadSSHUserCerts loads the certificates named by the cert param or
+// CertificateFile. As with ssh, a CertificateFile that does not exist is
+// skipped; an explicit cert param must load.
+func loadSSHUserCerts(settings sshSettings) ([]*ssh.Certificate, error) {
+	var certs []*ssh.Certificate
+	for _, path := range settings.CertFiles {
+		cert, err := loadSSHUserCert(path)
+		if errors.Is(err, fs.ErrNotExist) && settings.Sources["certificate"] == "config" {
+			continue
+		}
+		if err != nil {
+			return nil, TransportError{
+				Code:      TransportErrorCodeValidationFailed,
+				Message:   fmt.Sprintf("SSH certificate %s: %v", path, err),
+				Retryable: false,
+				Cause:     err,
+			}
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func loadSSHUserCert(path string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok || cert.CertType != ssh.UserCert {
+		return nil, errors.New("not a user certificate")
+	}
+	return cert, nil
+}
+
+// sshCertSigners returns signers with a certificate signer placed before
+// each key that one of certs was issued for.
+func sshCertSigners(signers []ssh.Signer, certs []*ssh.Certificate) []ssh.Signer {
+	if len(certs) == 0 {
+		return signers
+	}
+	out := make([]ssh.Signer, 0, len(signers)+len(certs))
+	for _, signer := range signers {
+		for _, cert := range certs {
+			if !keysEqual(cert.Key, signer.PublicKey()) {
+				continue
+			}
+			if certSigner, err := ssh.NewCertSigner(cert, signer); err == nil {
+				out = append(out, certSigner)
+			}
+		}
+		out = append(out, signer)
+	}
+	return out
+}
+
+// connectSSHHost dials one host with dialContext and completes the SSH
+// handshake, verifying its host key against settings.KnownHostsFile. With
+// trust_on_first_use, an unknown host key is shown to the operator and, once
+// confirmed, added to known_hosts before connecting again.
+func connectSSHHost(ctx context.Context, dialContext func(context.Context, string, string) (net.Conn, error), settings *sshSettings, params map[string]any) (*ssh.Client, error) {
+	auth, err := sshAuthMethods(params, *settings)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := handshakeSSHHost(ctx, dialContext, *settings, params, auth)
+	var unknown *sshUnknownHostError
+	if err == nil || !sshTrustOnFirstUse(params) || !errors.As(err, &unknown) {
+		return client, err
+	}
+
+	// Prompt with no connection open, so the handshake cannot time out
+	if err := confirmHostKey(unknown, settings.KnownHostsFile); err != nil {
+		return nil, err
+	}
+	hash := settings.HashKnownHosts
+	if db, err := loadKnownHosts(settings.KnownHostsFile); err == nil && db.hashed {
+		hash = true
+	}
+	if err := appendKnownHost(settings.KnownHostsFile, unknown.Host, unknown.Key, hash); err != nil {
+		return nil, fmt.Errorf("failed to add host key to %s: %w", settings.KnownHostsFile, err)
+	}
+	settings.TrustedHostKey = ssh.FingerprintSHA256(unknown.Key)
+
+	return handshakeSSHHost(ctx, dialContext, *settings, params, auth)
+}
+
+func handshakeSSHHost(ctx context.Context, dialContext func(context.Context, string, string) (net.Conn, error), settings sshSettings, params map[string]any, auth []ssh.AuthMethod) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User:            settings.User,
+		Auth:            auth,
+		HostKeyCallback: getHostKeyCallback(params, settings.KnownHostsFile),
+	}
+
+	handshakeTimeout := getSSHHandshakeTimeout(params)
+
+	addr := net.JoinHostPort(settings.HostName, strconv.Itoa(settings.Port))
+	connectCtx := ctx
+	if settings.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = context.WithTimeout(ctx, settings.ConnectTimeout)
+		defer cancel()
+	}
+	conn, err := dialContext(connectCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConn, chans, reqs, err := sshNewClientConnWithTimeout(ctx, conn, addr, config, handshakeTimeout)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// dialSSHJumps connects through each jump host in order and returns a dialer
+// that reaches the next host through the last hop. The hops stay open until
+// closeHops is called.
+func dialSSHJumps(ctx context.Context, dialContext func(context.Context, string, string) (net.Conn, error), settings *sshSettings, params map[string]any) (func(context.Context, string, string) (net.Conn, error), func(), error) {
+	hops := make([]*ssh.Client, 0, len(settings.Jumps))
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			_ = hops[i].Close()
+		}
+	}
+
+	hopParams := map[string]any{}
+	for _, key := range []string{"strict_host_key", "trust_on_first_use", "handshake_timeout"} {
+		if value, ok := params[key]; ok {
+			hopParams[key] = value
+		}
+	}
+
+	dial := dialContext
+	for i := range settings.Jumps {
+		jump := &settings.Jumps[i]
+		hop, err := connectSSHHost(ctx, dial, jump, hopParams)
+		if err != nil {
+			closeHops()
+			return nil, nil, TransportError{
+				Code:      TransportErrorCodeConnect,
+				Message:   fmt.Sprintf("ssh jump via %s failed", jump.target()),
+				Retryable: true,
+				Cause:     err,
+			}
+		}
+		hops = append(hops, hop)
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return sshClientDialContext(hop, ctx, network, addr)
+		}
+	}
+	return dial, closeHops, nil
+}
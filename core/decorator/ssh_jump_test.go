@@ -0,0 +1,239 @@
+package decorator
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSSHJumpSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		want     []string
+		explicit bool
+		err      string
+	}{
+		{name: "unset", value: nil},
+		{name: "proxyjump string", value: "bastion1, user@bastion2:2222", want: []string{"bastion1", "user@bastion2:2222"}, explicit: true},
+		{name: "list", value: []any{"bastion1", "user@bastion2:2222"}, want: []string{"bastion1", "user@bastion2:2222"}, explicit: true},
+		{name: "none", value: "none", explicit: true},
+		{name: "empty list", value: []any{}, want: []string{}, explicit: true},
+		{name: "non-string hop", value: []any{"a", 3}, explicit: true, err: "jump hosts must be strings"},
+		{name: "empty hop", value: "a,,b", explicit: true, err: "jump host cannot be empty"},
+		{name: "wrong type", value: 42, explicit: true, err: "jump must be a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, explicit, err := sshJumpSpecs(tt.value)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("specs mismatch (-want +got):\n%s", diff)
+			}
+			if explicit != tt.explicit {
+				t.Errorf("explicit: got %v, want %v", explicit, tt.explicit)
+			}
+		})
+	}
+}
+
+func TestResolveSSHSettingsJumpHops(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USER", "local-user")
+	writeSSHConfig(t, home, ".ssh/config", `
+Host bastion1
+    HostName b1.example.com
+    User jumper
+    ProxyJump ignored-for-hops
+
+Host inner
+    HostName 10.0.0.5
+    ProxyJump bastion1,ops@[2001:db8::1]:2200
+`)
+
+	settings, err := resolveSSHSettings(map[string]any{"host": "inner"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	var hops []string
+	for _, hop := range settings.Jumps {
+		hops = append(hops, hop.target())
+	}
+	want := []string{"jumper@b1.example.com:22", "ops@[2001:db8::1]:2200"}
+	if diff := cmp.Diff(want, hops); diff != "" {
+		t.Errorf("config ProxyJump hops mismatch (-want +got):\n%s", diff)
+	}
+	if len(settings.Jumps[0].Jumps) != 0 {
+		t.Errorf("a hop's own ProxyJump must not be followed, got %v", settings.Jumps[0].Jumps)
+	}
+	if settings.Sources["jump"] != "config" {
+		t.Errorf("jump source: got %q, want config", settings.Sources["jump"])
+	}
+
+	settings, err = resolveSSHSettings(map[string]any{"host": "inner", "jump": []any{"ssh://root@bastion1:2022"}})
+	if err != nil {
+		t.Fatalf("resolve with jump param: %v", err)
+	}
+	if len(settings.Jumps) != 1 || settings.Jumps[0].target() != "root@b1.example.com:2022" {
+		t.Errorf("jump param must replace ProxyJump, got %+v", settings.Jumps)
+	}
+
+	settings, err = resolveSSHSettings(map[string]any{"host": "inner", "jump": "none"})
+	if err != nil || len(settings.Jumps) != 0 {
+		t.Errorf("jump=none must disable ProxyJump: jumps=%v err=%v", settings.Jumps, err)
+	}
+
+	_, err = resolveSSHSettings(map[string]any{"host": "inner", "jump": []any{"@bastion"}})
+	if err == nil || !strings.Contains(err.Error(), "empty user") {
+		t.Errorf("invalid hop: got %v", err)
+	}
+}
+
+// jumpTestConfig writes a config that authenticates every hop to the shared
+// test server with its client key.
+func jumpTestConfig(t *testing.T, server *SSHTestServer, extra string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSSHConfig(t, home, ".ssh/id_test", string(server.ClientKeyPEM))
+	writeSSHConfig(t, home, ".ssh/config", extra+`
+Host *
+    User `+os.Getenv("USER")+`
+    IdentityFile ~/.ssh/id_test
+`)
+}
+
+func TestSSHSessionConnectsThroughJumpHosts(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, "")
+	addr := server.Addr()
+	before := len(server.Forwards())
+
+	session, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"key":             server.ClientKey,
+		"jump":            []any{addr, os.Getenv("USER") + "@" + addr},
+		"strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("connect through jumps: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	result, err := session.Run(context.Background(), []string{"echo", "hopped"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if diff := cmp.Diff("hopped\n", string(result.Stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+
+	// Each hop opens one forward: hop1 -> hop2, hop2 -> final host.
+	if diff := cmp.Diff([]string{addr, addr}, server.Forwards()[before:]); diff != "" {
+		t.Errorf("forwarded destinations mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("ssh:127.0.0.1", session.ID()); diff != "" {
+		t.Errorf("ID must name only the final host (-want +got):\n%s", diff)
+	}
+
+	event := session.SessionEvents()[0]
+	wantJump := os.Getenv("USER") + "@" + addr + "," + os.Getenv("USER") + "@" + addr
+	if diff := cmp.Diff(wantJump, event.Attrs["jump"]); diff != "" {
+		t.Errorf("connect event jump mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHSessionConnectsThroughConfigProxyJump(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, `
+Host inner
+    HostName 127.0.0.1
+    Port `+strconv.Itoa(server.Port)+`
+    ProxyJump `+server.Addr()+`
+`)
+	before := len(server.Forwards())
+
+	session, err := NewSSHSession(map[string]any{"host": "inner", "strict_host_key": false})
+	if err != nil {
+		t.Fatalf("connect through ProxyJump: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	if _, err := session.Run(context.Background(), []string{"true"}, RunOpts{}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := len(server.Forwards()) - before; got != 1 {
+		t.Errorf("expected 1 forward through the jump host, got %d", got)
+	}
+}
+
+func TestSSHJumpVerifiesHostKeyPerHop(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, "")
+
+	// Only the jump host's address is trusted.
+	knownHosts := writeSSHConfig(t, t.TempDir(), "known_hosts",
+		"[127.0.0.1]:"+strconv.Itoa(server.Port)+" "+server.HostKey.PublicKey().Type()+" "+base64.StdEncoding.EncodeToString(server.HostKey.PublicKey().Marshal())+"\n")
+	params := map[string]any{
+		"host":             "localhost",
+		"port":             server.Port,
+		"key":              server.ClientKey,
+		"jump":             []any{server.Addr()},
+		"known_hosts_path": knownHosts,
+	}
+
+	_, err := NewSSHSession(params)
+	if err == nil || !strings.Contains(detailedError(err), "host key not found in known_hosts: [localhost]:"+strconv.Itoa(server.Port)) {
+		t.Fatalf("final host must be verified separately, got %v", err)
+	}
+
+	params["jump"] = []any{"localhost:" + strconv.Itoa(server.Port)}
+	params["host"] = "127.0.0.1"
+	_, err = NewSSHSession(params)
+	if err == nil || !strings.Contains(err.Error(), "ssh jump via") || !strings.Contains(detailedError(err), "host key not found") {
+		t.Fatalf("jump host must be verified, got %v", err)
+	}
+}
+
+func TestSSHJumpAuthenticatesPerHop(t *testing.T) {
+	server := getSSHTestServer(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	// The final host's key param is not offered to the jump host.
+	_, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"key":             server.ClientKey,
+		"jump":            []any{server.Addr()},
+		"strict_host_key": false,
+	})
+	if err == nil || !strings.Contains(err.Error(), "ssh jump via") {
+		t.Fatalf("expected jump host auth failure, got %v", err)
+	}
+}
+
+func detailedError(err error) string {
+	var transportErr TransportError
+	if errors.As(err, &transportErr) {
+		return transportErr.DetailedError()
+	}
+	return err.Error()
+}
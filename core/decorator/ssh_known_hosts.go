@@ -0,0 +1,330 @@
+package decorator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// knownHostsDB is a parsed OpenSSH known_hosts file. As in OpenSSH, lines
+// that cannot be parsed are skipped rather than failing the whole file.
+type knownHostsDB struct {
+	entries []knownHostsEntry
+	hashed  bool // At least one entry uses a hashed hostname
+}
+
+type knownHostsEntry struct {
+	marker   string   // "", "@cert-authority" or "@revoked"
+	patterns []string // Host patterns; may be hashed (|1|salt|hash) or negated
+	key      ssh.PublicKey
+}
+
+const (
+	knownHostsMarkerCA      = "@cert-authority"
+	knownHostsMarkerRevoked = "@revoked"
+	knownHostsHashPrefix    = "|1|"
+)
+
+// loadKnownHosts reads a known_hosts file. A missing file is reported with
+// an error wrapping fs.ErrNotExist.
+func loadKnownHosts(path string) (*knownHostsDB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return parseKnownHosts(file)
+}
+
+func parseKnownHosts(r io.Reader) (*knownHostsDB, error) {
+	db := &knownHostsDB{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var entry knownHostsEntry
+		if strings.HasPrefix(fields[0], "@") {
+			entry.marker = fields[0]
+			fields = fields[1:]
+			if entry.marker != knownHostsMarkerCA && entry.marker != knownHostsMarkerRevoked {
+				continue
+			}
+		}
+		if len(fields) < 3 {
+			continue
+		}
+
+		keyBytes, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			continue
+		}
+		key, err := ssh.ParsePublicKey(keyBytes)
+		if err != nil || key.Type() != fields[1] {
+			continue
+		}
+		entry.key = key
+		entry.patterns = strings.Split(fields[0], ",")
+		for _, pattern := range entry.patterns {
+			if strings.HasPrefix(pattern, knownHostsHashPrefix) {
+				db.hashed = true
+			}
+		}
+		db.entries = append(db.entries, entry)
+	}
+	return db, scanner.Err()
+}
+
+// knownHostsAddress renders a dial address the way known_hosts records it:
+// "host" for port 22 and "[host]:port" otherwise.
+func knownHostsAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return strings.ToLower(address)
+	}
+	host = strings.ToLower(host)
+	if port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+// matches reports whether the entry applies to host (as returned by
+// knownHostsAddress): some pattern matches and no negated pattern does.
+func (e knownHostsEntry) matches(host string) bool {
+	matched := false
+	for _, pattern := range e.patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			if knownHostsPatternMatches(negated, host) {
+				return false
+			}
+			continue
+		}
+		if knownHostsPatternMatches(pattern, host) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+func knownHostsPatternMatches(pattern, host string) bool {
+	if salted, ok := strings.CutPrefix(pattern, knownHostsHashPrefix); ok {
+		salt64, hash64, ok := strings.Cut(salted, "|")
+		if !ok {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(salt64)
+		if err != nil {
+			return false
+		}
+		want, err := base64.StdEncoding.DecodeString(hash64)
+		if err != nil {
+			return false
+		}
+		return hmac.Equal(want, knownHostsHash(salt, host))
+	}
+	return sshWildcardMatch(strings.ToLower(pattern), host)
+}
+
+func knownHostsHash(salt []byte, host string) []byte {
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return mac.Sum(nil)
+}
+
+// hashKnownHost returns host as a hashed known_hosts pattern with a fresh salt.
+func hashKnownHost(host string) (string, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return knownHostsHashPrefix + base64.StdEncoding.EncodeToString(salt) + "|" +
+		base64.StdEncoding.EncodeToString(knownHostsHash(salt, host)), nil
+}
+
+// revoked reports whether key is listed with @revoked.
+func (db *knownHostsDB) revoked(key ssh.PublicKey) bool {
+	for _, entry := range db.entries {
+		if entry.marker == knownHostsMarkerRevoked && keysEqual(entry.key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthority reports whether authority is a trusted @cert-authority for host.
+func (db *knownHostsDB) isAuthority(host string, authority ssh.PublicKey) bool {
+	for _, entry := range db.entries {
+		if entry.marker == knownHostsMarkerCA && keysEqual(entry.key, authority) && entry.matches(host) {
+			return true
+		}
+	}
+	return false
+}
+
+func keysEqual(a, b ssh.PublicKey) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+// sshUnknownHostError reports a host with no known_hosts entry. Trust on
+// first use catches it to offer adding the key.
+type sshUnknownHostError struct {
+	Host string // As recorded in known_hosts, e.g. "[host]:2222"
+	Key  ssh.PublicKey
+}
+
+func (e *sshUnknownHostError) Error() string {
+	return "host key not found in known_hosts: " + e.Host
+}
+
+// HostKeyCallback verifies a server's host key. A host certificate is
+// accepted when it is signed by a matching @cert-authority, is valid for the
+// host, and neither it nor its CA is @revoked. Otherwise the key (or the key
+// inside an untrusted certificate, as OpenSSH does) must match a plain entry.
+func (db *knownHostsDB) HostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		host := knownHostsAddress(hostname)
+
+		if cert, ok := key.(*ssh.Certificate); ok {
+			if cert.CertType == ssh.HostCert && db.isAuthority(host, cert.SignatureKey) {
+				checker := &ssh.CertChecker{
+					IsRevoked: func(cert *ssh.Certificate) bool {
+						return db.revoked(cert) || db.revoked(cert.Key) || db.revoked(cert.SignatureKey)
+					},
+				}
+				principal, _, err := net.SplitHostPort(hostname)
+				if err != nil {
+					principal = hostname
+				}
+				if err := checker.CheckCert(principal, cert); err != nil {
+					return fmt.Errorf("host certificate for %s rejected: %w", host, err)
+				}
+				return nil
+			}
+			key = cert.Key
+		}
+
+		if db.revoked(key) {
+			return fmt.Errorf("host key for %s is revoked in known_hosts", host)
+		}
+
+		found := false
+		for _, entry := range db.entries {
+			if entry.marker != "" || !entry.matches(host) {
+				continue
+			}
+			if keysEqual(entry.key, key) {
+				return nil
+			}
+			found = true
+		}
+		if found {
+			return fmt.Errorf("host key mismatch for %s", host)
+		}
+		return &sshUnknownHostError{Host: host, Key: key}
+	}
+}
+
+// appendKnownHost records key for host at the end of the known_hosts file,
+// creating it if needed. The hostname is hashed when hash is set.
+func appendKnownHost(path, host string, key ssh.PublicKey, hash bool) error {
+	pattern := host
+	if hash {
+		hashed, err := hashKnownHost(host)
+		if err != nil {
+			return err
+		}
+		pattern = hashed
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	line := pattern + " " + string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(key))) + "\n"
+
+	// Keep the new entry on its own line if the file lacks a final newline.
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if reader, err := os.Open(path); err == nil {
+			if _, err := reader.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				line = "\n" + line
+			}
+			_ = reader.Close()
+		}
+	}
+
+	_, err = file.WriteString(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sshTrustOnFirstUse reports whether the params opt into trust on first use.
+func sshTrustOnFirstUse(params map[string]any) bool {
+	enabled, _ := params["trust_on_first_use"].(bool)
+	return enabled
+}
+
+// errHostKeyNotTrusted marks a new host key the operator declined.
+var errHostKeyNotTrusted = errors.New("host key not trusted")
+
+// openSSHPromptTTY opens the operator's terminal for host key confirmation.
+// Overridden in tests.
+var openSSHPromptTTY = func() (io.ReadWriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// sshPromptMu serializes host key prompts, so hosts connecting concurrently
+// (as under @ssh.each) ask one at a time.
+var sshPromptMu sync.Mutex
+
+// confirmHostKey asks the operator on the controlling terminal whether to
+// trust a new host key. Without a terminal it fails closed.
+func confirmHostKey(unknown *sshUnknownHostError, knownHostsFile string) error {
+	sshPromptMu.Lock()
+	defer sshPromptMu.Unlock()
+
+	tty, err := openSSHPromptTTY()
+	if err != nil {
+		return fmt.Errorf("%w (trust_on_first_use needs a terminal to confirm the key)", unknown)
+	}
+	defer func() { _ = tty.Close() }()
+
+	_, err = fmt.Fprintf(tty, "The authenticity of host '%s' can't be established.\n%s key fingerprint is %s.\nAdd it to %s and continue connecting? [y/N] ",
+		unknown.Host, unknown.Key.Type(), ssh.FingerprintSHA256(unknown.Key), knownHostsFile)
+	if err != nil {
+		return err
+	}
+
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("%w: %s", errHostKeyNotTrusted, unknown.Host)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", errHostKeyNotTrusted, unknown.Host)
+	}
+}
@@ -0,0 +1,433 @@
+package decorator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+)
+
+func knownHostsLine(pattern string, key ssh.PublicKey) string {
+	return pattern + " " + string(ssh.MarshalAuthorizedKey(key))
+}
+
+func newTestHostCert(t *testing.T, ca ssh.Signer, key ssh.PublicKey, principals ...string) *ssh.Certificate {
+	t.Helper()
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: principals,
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestKnownHostsHostKeyCallback(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		signer, err := newTestSSHSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	key := newSigner().PublicKey()
+	other := newSigner().PublicKey()
+	ca := newSigner()
+	hashed, err := hashKnownHost("[db.example.com]:2222")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newTestHostCert(t, ca, key, "web.example.com")
+
+	tests := []struct {
+		name     string
+		lines    []string
+		hostname string
+		key      ssh.PublicKey
+		err      string
+	}{
+		{name: "plain", lines: []string{knownHostsLine("web.example.com", key)}, hostname: "web.example.com:22", key: key},
+		{name: "case insensitive", lines: []string{knownHostsLine("Web.Example.com", key)}, hostname: "web.example.com:22", key: key},
+		{name: "non-standard port", lines: []string{knownHostsLine("[web.example.com]:2222", key)}, hostname: "web.example.com:2222", key: key},
+		{name: "port must match", lines: []string{knownHostsLine("web.example.com", key)}, hostname: "web.example.com:2222", key: key, err: "host key not found in known_hosts: [web.example.com]:2222"},
+		{name: "wildcard", lines: []string{knownHostsLine("*.example.com,10.0.0.?", key)}, hostname: "10.0.0.7:22", key: key},
+		{name: "negation", lines: []string{knownHostsLine("*.example.com,!web.example.com", key)}, hostname: "web.example.com:22", key: key, err: "host key not found"},
+		{name: "hashed", lines: []string{knownHostsLine(hashed, key)}, hostname: "db.example.com:2222", key: key},
+		{name: "hashed other host", lines: []string{knownHostsLine(hashed, key)}, hostname: "db.example.com:22", key: key, err: "host key not found"},
+		{name: "mismatch", lines: []string{knownHostsLine("web.example.com", other)}, hostname: "web.example.com:22", key: key, err: "host key mismatch for web.example.com"},
+		{name: "second key for host", lines: []string{knownHostsLine("web.example.com", other), knownHostsLine("web.example.com", key)}, hostname: "web.example.com:22", key: key},
+		{name: "revoked", lines: []string{knownHostsLine("web.example.com", key), knownHostsLine("@revoked *", key)}, hostname: "web.example.com:22", key: key, err: "is revoked"},
+		{name: "malformed lines skipped", lines: []string{"garbage\n", "@unknown-marker x ssh-ed25519 AAAA\n", "web.example.com ssh-rsa notbase64!\n", knownHostsLine("web.example.com", key)}, hostname: "web.example.com:22", key: key},
+		{name: "comments skipped", lines: []string{"# " + knownHostsLine("web.example.com", other), knownHostsLine("web.example.com", key)}, hostname: "web.example.com:22", key: key},
+		{name: "cert from authority", lines: []string{knownHostsLine("@cert-authority *.example.com", ca.PublicKey())}, hostname: "web.example.com:22", key: cert},
+		{name: "cert principal mismatch", lines: []string{knownHostsLine("@cert-authority *", ca.PublicKey())}, hostname: "db.example.com:22", key: cert, err: "host certificate for db.example.com rejected"},
+		{name: "cert authority revoked", lines: []string{knownHostsLine("@cert-authority *", ca.PublicKey()), knownHostsLine("@revoked *", ca.PublicKey())}, hostname: "web.example.com:22", key: cert, err: "rejected"},
+		{name: "cert key revoked", lines: []string{knownHostsLine("@cert-authority *", ca.PublicKey()), knownHostsLine("@revoked *", key)}, hostname: "web.example.com:22", key: cert, err: "rejected"},
+		{name: "cert authority for other hosts", lines: []string{knownHostsLine("@cert-authority *.internal", ca.PublicKey())}, hostname: "web.example.com:22", key: cert, err: "host key not found"},
+		{name: "untrusted cert falls back to key", lines: []string{knownHostsLine("web.example.com", key)}, hostname: "web.example.com:22", key: cert},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := parseKnownHosts(strings.NewReader(strings.Join(tt.lines, "")))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			err = db.HostKeyCallback()(tt.hostname, &net.TCPAddr{}, tt.key)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestKnownHostsUnknownHostError(t *testing.T) {
+	signer, err := newTestSSHSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := parseKnownHosts(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.HostKeyCallback()("web.example.com:2222", &net.TCPAddr{}, signer.PublicKey())
+
+	var unknown *sshUnknownHostError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected *sshUnknownHostError, got %T: %v", err, err)
+	}
+	if diff := cmp.Diff("[web.example.com]:2222", unknown.Host); diff != "" {
+		t.Errorf("host mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHSessionVerifiesHostCertificate(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, "")
+
+	knownHosts := writeSSHConfig(t, t.TempDir(), "known_hosts", knownHostsLine("@cert-authority [127.0.0.1]:*,[localhost]:*", server.HostCA.PublicKey()))
+	params := map[string]any{
+		"host":             "127.0.0.1",
+		"port":             server.Port,
+		"known_hosts_path": knownHosts,
+	}
+
+	session, err := NewSSHSession(params)
+	if err != nil {
+		t.Fatalf("host certificate from a trusted CA must be accepted: %v", detailedError(err))
+	}
+	_ = session.Close()
+
+	writeSSHConfig(t, filepath.Dir(knownHosts), "known_hosts",
+		knownHostsLine("@cert-authority [127.0.0.1]:*,[localhost]:*", server.HostCA.PublicKey())+
+			knownHostsLine("@revoked *", server.HostCA.PublicKey()))
+	_, err = NewSSHSession(params)
+	if err == nil || !strings.Contains(detailedError(err), "host certificate for [127.0.0.1]:"+strconv.Itoa(server.Port)+" rejected") {
+		t.Fatalf("revoked CA must be rejected, got %v", err)
+	}
+}
+
+// writeCertifiedKey writes a fresh private key that the test server only
+// accepts with a certificate, and a user certificate for it signed by the
+// server's user CA. It returns the key and certificate paths.
+func writeCertifiedKey(t *testing.T, server *SSHTestServer, dir string) (string, string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           "sigil-test-user",
+		ValidPrincipals: []string{os.Getenv("USER")},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, server.UserCA); err != nil {
+		t.Fatal(err)
+	}
+	keyPath := writeSSHConfig(t, dir, "id_ed25519", string(pem.EncodeToMemory(block)))
+	certPath := writeSSHConfig(t, dir, "user-cert.pub", string(ssh.MarshalAuthorizedKey(cert)))
+	return keyPath, certPath
+}
+
+func TestSSHSessionUserCertificate(t *testing.T) {
+	server := getSSHTestServer(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	keyPath, certPath := writeCertifiedKey(t, server, filepath.Join(home, ".ssh"))
+	base := map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"user":            os.Getenv("USER"),
+		"strict_host_key": false,
+	}
+	connect := func(extra map[string]any) error {
+		params := map[string]any{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+		session, err := NewSSHSession(params)
+		if err == nil {
+			_ = session.Close()
+		}
+		return err
+	}
+
+	if err := connect(map[string]any{"key": keyPath}); err == nil {
+		t.Fatal("the key alone must not be accepted")
+	}
+	if err := connect(map[string]any{"key": keyPath, "cert": certPath}); err != nil {
+		t.Fatalf("cert param: %v", err)
+	}
+
+	writeSSHConfig(t, home, ".ssh/config", `
+Host 127.0.0.1
+    IdentityFile ~/.ssh/id_ed25519
+    CertificateFile ~/.ssh/user-cert.pub
+    CertificateFile ~/.ssh/missing-cert.pub
+`)
+	if err := connect(nil); err != nil {
+		t.Fatalf("CertificateFile: %v", err)
+	}
+	settings, err := resolveSSHSettings(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Sources["certificate"] != "config" {
+		t.Errorf("certificate source: got %q, want config", settings.Sources["certificate"])
+	}
+
+	if err := os.Remove(filepath.Join(home, ".ssh/config")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(certPath, keyPath+"-cert.pub"); err != nil {
+		t.Fatal(err)
+	}
+	if err := connect(map[string]any{"key": keyPath}); err != nil {
+		t.Fatalf("certificate next to the key: %v", err)
+	}
+
+	err = connect(map[string]any{"key": keyPath, "cert": keyPath + ".pub"})
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Code != TransportErrorCodeValidationFailed {
+		t.Fatalf("missing cert param must fail validation, got %v", err)
+	}
+
+	hostCert := writeSSHConfig(t, home, "host-cert.pub", string(ssh.MarshalAuthorizedKey(newTestHostCert(t, server.UserCA, server.ClientKey.PublicKey()))))
+	err = connect(map[string]any{"key": keyPath, "cert": hostCert})
+	if err == nil || !strings.Contains(err.Error(), "not a user certificate") {
+		t.Fatalf("host certificate as cert param: got %v", err)
+	}
+}
+
+func TestSSHAuthMethodsOffersAllKeys(t *testing.T) {
+	server := getSSHTestServer(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	// The first identity is not accepted; the second must still be tried.
+	otherKey, _ := writeCertifiedKey(t, server, filepath.Join(home, "other"))
+	writeSSHConfig(t, home, ".ssh/id_test", string(server.ClientKeyPEM))
+	writeSSHConfig(t, home, ".ssh/config", `
+Host 127.0.0.1
+    IdentityFile `+otherKey+`
+    IdentityFile ~/.ssh/id_test
+`)
+
+	session, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"user":            os.Getenv("USER"),
+		"strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("second IdentityFile must be offered: %v", err)
+	}
+	_ = session.Close()
+}
+
+type promptTTY struct {
+	io.Reader
+	io.Writer
+}
+
+func (promptTTY) Close() error { return nil }
+
+// answerHostKeyPrompts makes host key prompts read answer and returns what
+// was shown. A nil answer simulates having no terminal.
+func answerHostKeyPrompts(t *testing.T, answer *string) *bytes.Buffer {
+	t.Helper()
+	shown := &bytes.Buffer{}
+	previous := openSSHPromptTTY
+	openSSHPromptTTY = func() (io.ReadWriteCloser, error) {
+		if answer == nil {
+			return nil, errors.New("no terminal")
+		}
+		return promptTTY{Reader: strings.NewReader(*answer), Writer: shown}, nil
+	}
+	t.Cleanup(func() { openSSHPromptTTY = previous })
+	return shown
+}
+
+func TestSSHSessionTrustOnFirstUse(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, "")
+	knownHosts := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	params := map[string]any{
+		"host":               "localhost",
+		"port":               server.Port,
+		"known_hosts_path":   knownHosts,
+		"trust_on_first_use": true,
+	}
+	host := "[localhost]:" + strconv.Itoa(server.Port)
+
+	answer := "no\n"
+	shown := answerHostKeyPrompts(t, &answer)
+	_, err := NewSSHSession(params)
+	if !errors.Is(err, errHostKeyNotTrusted) {
+		t.Fatalf("declined key must fail, got %v", err)
+	}
+	if _, statErr := os.Stat(knownHosts); !errors.Is(statErr, os.ErrNotExist) {
+		t.Errorf("declined key must not create known_hosts: %v", statErr)
+	}
+	fingerprint := ssh.FingerprintSHA256(server.HostKey.PublicKey())
+	if !strings.Contains(shown.String(), host) || !strings.Contains(shown.String(), fingerprint) {
+		t.Errorf("prompt must show host and fingerprint, got %q", shown.String())
+	}
+
+	answerHostKeyPrompts(t, nil)
+	if _, err := NewSSHSession(params); err == nil || !strings.Contains(detailedError(err), "needs a terminal") {
+		t.Fatalf("without a terminal the key must be rejected, got %v", err)
+	}
+
+	answer = "yes\n"
+	answerHostKeyPrompts(t, &answer)
+	session, err := NewSSHSession(params)
+	if err != nil {
+		t.Fatalf("accepted key: %v", err)
+	}
+	_ = session.Close()
+
+	data, err := os.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(knownHostsLine(host, server.HostKey.PublicKey()), string(data)); diff != "" {
+		t.Errorf("known_hosts mismatch (-want +got):\n%s", diff)
+	}
+	info, err := os.Stat(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("known_hosts mode: got %o, want 600", info.Mode().Perm())
+	}
+	want := Event{
+		Decorator: "ssh.connect",
+		Kind:      "host-key-trusted",
+		Attrs: map[string]string{
+			"host":        os.Getenv("USER") + "@localhost:" + strconv.Itoa(server.Port),
+			"fingerprint": fingerprint,
+			"known_hosts": knownHosts,
+		},
+	}
+	events := session.SessionEvents()
+	if diff := cmp.Diff(want, events[len(events)-1]); diff != "" {
+		t.Errorf("trust event mismatch (-want +got):\n%s", diff)
+	}
+
+	// Once recorded, the key is verified without asking again.
+	answerHostKeyPrompts(t, nil)
+	session, err = NewSSHSession(params)
+	if err != nil {
+		t.Fatalf("known key must connect without a prompt: %v", err)
+	}
+	if got := len(session.SessionEvents()); got != 1 {
+		t.Errorf("expected no trust event for a known key, got %d events", got)
+	}
+	_ = session.Close()
+}
+
+func TestSSHSessionTrustOnFirstUseHashesLikeExistingEntries(t *testing.T) {
+	server := getSSHTestServer(t)
+	jumpTestConfig(t, server, "")
+
+	other, err := newTestSSHSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := hashKnownHost("other.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No trailing newline, so the new entry must start its own line.
+	existing := strings.TrimSuffix(knownHostsLine(hashed, other.PublicKey()), "\n")
+	knownHosts := writeSSHConfig(t, t.TempDir(), "known_hosts", existing)
+
+	answer := "y\n"
+	answerHostKeyPrompts(t, &answer)
+	session, err := NewSSHSession(map[string]any{
+		"host":               "127.0.0.1",
+		"port":               server.Port,
+		"known_hosts_path":   knownHosts,
+		"trust_on_first_use": true,
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	_ = session.Close()
+
+	data, err := os.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != existing {
+		t.Fatalf("existing entry must be kept, got %q", string(data))
+	}
+	if !strings.HasPrefix(lines[1], knownHostsHashPrefix) || strings.Contains(lines[1], "127.0.0.1") {
+		t.Errorf("new entry must be hashed, got %q", lines[1])
+	}
+
+	db, err := loadKnownHosts(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.HostKeyCallback()(server.Addr(), &net.TCPAddr{}, server.HostKey.PublicKey()); err != nil {
+		t.Errorf("hashed entry must verify the host: %v", err)
+	}
+}
@@ -0,0 +1,795 @@
+package decorator
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// SSHMuxSocketEnv overrides the mux agent socket path. "none" stops sigil
+// from using a running agent, like ssh -S none.
+const SSHMuxSocketEnv = "SIGIL_SSH_MUX_SOCKET"
+
+// DefaultSSHMuxIdleTTL is how long the agent keeps an unused connection open.
+const DefaultSSHMuxIdleTTL = 10 * time.Minute
+
+// sshMuxRequestTimeout bounds the request line and the proxy handshake.
+const sshMuxRequestTimeout = 10 * time.Second
+
+// SSHMuxSocketPath returns the mux agent socket path: $SIGIL_SSH_MUX_SOCKET,
+// else ssh-mux.sock in a per-user directory under $XDG_RUNTIME_DIR or the
+// temp dir.
+func SSHMuxSocketPath() string {
+	if path := os.Getenv(SSHMuxSocketEnv); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sigil", "ssh-mux.sock")
+	}
+	return filepath.Join(os.TempDir(), "sigil-"+strconv.Itoa(os.Getuid()), "ssh-mux.sock")
+}
+
+// checkSSHMuxDir verifies the socket directory is a real directory owned
+// by the current user and closed to everyone else. Socket permissions alone
+// are not enough: whoever can reach the socket can use every connection.
+func checkSSHMuxDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("ssh mux directory %s is not a directory", dir)
+	}
+	if !sshMuxOwnedByCurrentUser(info) {
+		return fmt.Errorf("ssh mux directory %s is not owned by the current user", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("ssh mux directory %s must not be accessible by group or others (mode %04o)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// checkSSHMuxSocket verifies path is a socket owned by the current user in
+// a private directory.
+func checkSSHMuxSocket(path string) error {
+	if err := checkSSHMuxDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("ssh mux socket %s is not a socket", path)
+	}
+	if !sshMuxOwnedByCurrentUser(info) {
+		return fmt.Errorf("ssh mux socket %s is not owned by the current user", path)
+	}
+	return nil
+}
+
+// sshMuxRequest is the one JSON line a client sends after connecting.
+type sshMuxRequest struct {
+	Op     string        `json:"op"` // "connect", "status" or "stop"
+	Target *sshMuxTarget `json:"target,omitempty"`
+	Auth   string        `json:"auth,omitempty"` // Client's sshAuthFingerprint
+}
+
+// sshMuxReply is the agent's one JSON line answer. After a successful
+// connect the socket carries an SSH connection proxied to the target.
+type sshMuxReply struct {
+	Error       string             `json:"error,omitempty"`
+	Reused      bool               `json:"reused,omitempty"`
+	Connections []SSHMuxConnection `json:"connections,omitempty"`
+}
+
+// sshMuxTarget is the serializable subset of @ssh.connect params that
+// choose and authenticate a connection.
+type sshMuxTarget struct {
+	Host             string        `json:"host"`
+	User             string        `json:"user,omitempty"`
+	Port             int           `json:"port,omitempty"`
+	Key              string        `json:"key,omitempty"`
+	Cert             string        `json:"cert,omitempty"`
+	KnownHostsPath   string        `json:"known_hosts_path,omitempty"`
+	SSHConfig        string        `json:"ssh_config,omitempty"`
+	StrictHostKey    bool          `json:"strict_host_key"`
+	HandshakeTimeout time.Duration `json:"handshake_timeout,omitempty"`
+	Jump             []any         `json:"jump,omitempty"`
+}
+
+func newSSHMuxTarget(cfg sshConnectConfig) *sshMuxTarget {
+	return &sshMuxTarget{
+		Host:             cfg.Host,
+		User:             cfg.User,
+		Port:             cfg.Port,
+		Key:              cfg.Key,
+		Cert:             cfg.Cert,
+		KnownHostsPath:   cfg.KnownHostsPath,
+		SSHConfig:        cfg.SSHConfig,
+		StrictHostKey:    cfg.StrictHostKey,
+		HandshakeTimeout: cfg.HandshakeTimeout,
+		Jump:             cfg.Jump,
+	}
+}
+
+// config returns the connect config the agent dials with. The agent has no
+// terminal, so trust on first use is off: an unknown host fails in the
+// agent and the client connects directly, where it can prompt.
+func (t *sshMuxTarget) config() sshConnectConfig {
+	return sshConnectConfig{
+		Host:             t.Host,
+		User:             t.User,
+		Port:             t.Port,
+		Key:              t.Key,
+		Cert:             t.Cert,
+		KnownHostsPath:   t.KnownHostsPath,
+		SSHConfig:        t.SSHConfig,
+		StrictHostKey:    t.StrictHostKey,
+		HandshakeTimeout: t.HandshakeTimeout,
+		Jump:             t.Jump,
+	}
+}
+
+// sshMuxKey identifies a shared connection: the session pool key of the
+// target params plus the client's auth fingerprint.
+func sshMuxKey(target *sshMuxTarget, auth string) string {
+	return sessionKey("ssh.connect", target.config().dialParams()) + ":" + auth
+}
+
+// sshAuthFingerprint identifies where a connection goes and what it
+// authenticates with: the resolved user, address and jump hosts, the
+// contents of every candidate key and certificate file, and the public
+// keys offered by the agent. A rotated key, a new agent identity or an
+// edited ~/.ssh/config therefore never reuses a connection opened before.
+func sshAuthFingerprint(params map[string]any, settings sshSettings) string {
+	h := sha256.New()
+	writeFiles := func(paths ...string) {
+		for _, path := range paths {
+			_, _ = fmt.Fprintf(h, "file:%s\x00", path)
+			if data, err := os.ReadFile(path); err == nil {
+				sum := sha256.Sum256(data)
+				_, _ = h.Write(sum[:])
+			}
+		}
+	}
+
+	if key, ok := params["key"].(string); ok {
+		writeFiles(key)
+	}
+	for _, hop := range append([]sshSettings{settings}, settings.Jumps...) {
+		_, _ = fmt.Fprintf(h, "hop:%s\x00", hop.target())
+		writeFiles(hop.IdentityFiles...)
+		writeFiles(hop.CertFiles...)
+	}
+
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := (&net.Dialer{Timeout: time.Second}).Dial("unix", socket); err == nil {
+			keys, _ := agent.NewClient(conn).List()
+			_ = conn.Close()
+			for _, key := range keys {
+				_, _ = fmt.Fprintf(h, "agent:%s\x00", key.String())
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// sshMuxClientSocket returns the agent socket to use, or false when sharing
+// is disabled or no agent socket passes the permission checks.
+func sshMuxClientSocket() (string, bool) {
+	path := SSHMuxSocketPath()
+	if path == "none" {
+		return "", false
+	}
+	if err := checkSSHMuxSocket(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// openSSHSession connects an @ssh.connect session, through the mux agent
+// when one is running and the connection is eligible, else directly.
+func openSSHSession(ctx context.Context, dialer NetworkDialer, cfg sshConnectConfig) (*SSHSession, error) {
+	if session, ok := openSSHSessionViaMux(ctx, dialer, cfg); ok {
+		return session, nil
+	}
+	return dialSSHSession(ctx, dialer, cfg.dialParams())
+}
+
+// openSSHSessionViaMux asks the mux agent for a shared connection. Only
+// connections dialed from the local host with serializable params are
+// shared; any failure falls back to a direct connection.
+func openSSHSessionViaMux(ctx context.Context, dialer NetworkDialer, cfg sshConnectConfig) (*SSHSession, bool) {
+	if _, local := dialer.(*LocalSession); !local || cfg.signer != nil {
+		return nil, false
+	}
+	socket, ok := sshMuxClientSocket()
+	if !ok {
+		return nil, false
+	}
+
+	params := cfg.dialParams()
+	settings, err := resolveSSHSettings(params)
+	if err != nil {
+		return nil, false
+	}
+
+	client, err := dialSSHMux(ctx, socket, newSSHMuxTarget(cfg), sshAuthFingerprint(params, settings))
+	if err != nil {
+		return nil, false
+	}
+	settings.Mux = socket
+
+	return &SSHSession{
+		client:   client,
+		host:     settings.Alias,
+		settings: settings,
+		platform: detectRemotePlatform(client),
+	}, true
+}
+
+// dialSSHMux connects to the agent and returns a client whose channels the
+// agent proxies to target.
+func dialSSHMux(ctx context.Context, socket string, target *sshMuxTarget, auth string) (*ssh.Client, error) {
+	conn, reader, _, err := sshMuxRoundTrip(ctx, socket, sshMuxRequest{Op: "connect", Target: target, Auth: auth})
+	if err != nil {
+		return nil, err
+	}
+
+	// The socket is private to this user, so the agent's ephemeral host
+	// key is not verified.
+	proxied := &sshMuxConn{Conn: conn, r: reader}
+	sshConn, chans, reqs, err := ssh.NewClientConn(proxied, "sigil-mux", &ssh.ClientConfig{
+		User:            "sigil",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("ssh mux handshake: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// sshMuxRoundTrip sends one request and reads the reply. The connection is
+// returned open, with its deadline still set, for connect requests.
+func sshMuxRoundTrip(ctx context.Context, socket string, req sshMuxRequest) (net.Conn, *bufio.Reader, sshMuxReply, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, nil, sshMuxReply{}, fmt.Errorf("connect to ssh mux: %w", err)
+	}
+
+	deadline := time.Now().Add(sshMuxRequestTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	reader := bufio.NewReader(conn)
+	var reply sshMuxReply
+	err = writeSSHMuxMessage(conn, req)
+	if err == nil {
+		err = readSSHMuxMessage(reader, &reply)
+	}
+	if err == nil && reply.Error != "" {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, sshMuxReply{}, fmt.Errorf("ssh mux %s: %w", req.Op, err)
+	}
+	return conn, reader, reply, nil
+}
+
+func writeSSHMuxMessage(w io.Writer, message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func readSSHMuxMessage(r *bufio.Reader, message any) error {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, message)
+}
+
+// sshMuxConn reads through the buffer left over from the request line, so
+// bytes read ahead of the SSH handshake are not lost.
+type sshMuxConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *sshMuxConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// SSHMuxStatus lists the connections held by the agent at socket.
+func SSHMuxStatus(ctx context.Context, socket string) ([]SSHMuxConnection, error) {
+	conn, _, reply, err := sshMuxRoundTrip(ctx, socket, sshMuxRequest{Op: "status"})
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.Close()
+	return reply.Connections, nil
+}
+
+// StopSSHMux asks the agent at socket to close its connections and exit.
+func StopSSHMux(ctx context.Context, socket string) error {
+	conn, _, _, err := sshMuxRoundTrip(ctx, socket, sshMuxRequest{Op: "stop"})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// SSHMuxConnection describes one connection held by the agent.
+type SSHMuxConnection struct {
+	Host     string        `json:"host"`
+	Target   string        `json:"target"`   // user@hostname:port actually dialed
+	Sessions int           `json:"sessions"` // sigil processes using it now
+	Idle     time.Duration `json:"idle"`     // Time since the last session ended
+}
+
+// SSHMuxConfig configures an SSHMux agent.
+type SSHMuxConfig struct {
+	Socket  string        // Unix socket path ("" = SSHMuxSocketPath())
+	IdleTTL time.Duration // How long an unused connection stays open (0 = DefaultSSHMuxIdleTTL)
+}
+
+// SSHMux is a local agent that keeps authenticated SSH connections open
+// across sigil invocations, like OpenSSH's ControlMaster. Clients send a
+// connect request over a unix socket; the agent dials the target once,
+// then proxies each client's SSH channels onto the shared connection.
+type SSHMux struct {
+	socket  string
+	idleTTL time.Duration
+	server  *ssh.ServerConfig
+
+	mu    sync.Mutex
+	conns map[string]*sshMuxUpstream
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// sshMuxUpstream is one shared connection to a target.
+type sshMuxUpstream struct {
+	key      string
+	ready    chan struct{} // Closed once the dial finished
+	client   *ssh.Client
+	settings sshSettings
+	err      error
+
+	refs      int
+	idleSince time.Time
+	idleTimer *time.Timer
+	downs     map[*ssh.ServerConn]struct{}
+}
+
+// NewSSHMux creates an agent. Call Serve to start it.
+func NewSSHMux(cfg SSHMuxConfig) (*SSHMux, error) {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = SSHMuxSocketPath()
+	}
+	idleTTL := cfg.IdleTTL
+	if idleTTL <= 0 {
+		idleTTL = DefaultSSHMuxIdleTTL
+	}
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ssh mux host key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		return nil, fmt.Errorf("generate ssh mux host key: %w", err)
+	}
+	server := &ssh.ServerConfig{NoClientAuth: true}
+	server.AddHostKey(signer)
+
+	return &SSHMux{
+		socket:  socket,
+		idleTTL: idleTTL,
+		server:  server,
+		conns:   make(map[string]*sshMuxUpstream),
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Socket returns the path the agent listens on.
+func (m *SSHMux) Socket() string {
+	return m.socket
+}
+
+// Serve listens on the socket until ctx is done or a client sends stop,
+// then closes every connection and removes the socket.
+func (m *SSHMux) Serve(ctx context.Context) error {
+	listener, err := m.listen(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(m.socket) }()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-m.stopCh:
+		}
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			m.Stop()
+			m.closeAll()
+			select {
+			case <-m.stopCh:
+				return nil
+			default:
+				return err
+			}
+		}
+		go m.handle(conn)
+	}
+}
+
+// Stop makes Serve return.
+func (m *SSHMux) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// listen creates the private socket directory and the socket, refusing to
+// replace a socket another agent still answers on.
+func (m *SSHMux) listen(ctx context.Context) (net.Listener, error) {
+	dir := filepath.Dir(m.socket)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create ssh mux directory: %w", err)
+	}
+	if err := checkSSHMuxDir(dir); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Lstat(m.socket); err == nil {
+		if _, err := SSHMuxStatus(ctx, m.socket); err == nil {
+			return nil, fmt.Errorf("ssh mux already running on %s", m.socket)
+		}
+		if err := checkSSHMuxSocket(m.socket); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(m.socket); err != nil {
+			return nil, fmt.Errorf("remove stale ssh mux socket: %w", err)
+		}
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "unix", m.socket)
+	if err != nil {
+		return nil, fmt.Errorf("listen on ssh mux socket: %w", err)
+	}
+	if err := os.Chmod(m.socket, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("restrict ssh mux socket: %w", err)
+	}
+	return listener, nil
+}
+
+func (m *SSHMux) handle(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(sshMuxRequestTimeout))
+	reader := bufio.NewReader(conn)
+
+	var req sshMuxRequest
+	if err := readSSHMuxMessage(reader, &req); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	switch req.Op {
+	case "status":
+		_ = writeSSHMuxMessage(conn, sshMuxReply{Connections: m.connections()})
+	case "stop":
+		_ = writeSSHMuxMessage(conn, sshMuxReply{})
+		m.Stop()
+	case "connect":
+		if req.Target == nil || req.Target.Host == "" {
+			_ = writeSSHMuxMessage(conn, sshMuxReply{Error: "connect request without target"})
+			break
+		}
+		m.serveConnect(conn, reader, req)
+	default:
+		_ = writeSSHMuxMessage(conn, sshMuxReply{Error: fmt.Sprintf("unknown request %q", req.Op)})
+	}
+	_ = conn.Close()
+}
+
+// serveConnect hands the client a proxied SSH connection to its target for
+// as long as the client keeps the socket open.
+func (m *SSHMux) serveConnect(conn net.Conn, reader *bufio.Reader, req sshMuxRequest) {
+	upstream, reused, err := m.acquire(sshMuxKey(req.Target, req.Auth), req.Target)
+	if err != nil {
+		_ = writeSSHMuxMessage(conn, sshMuxReply{Error: err.Error()})
+		return
+	}
+	defer m.release(upstream)
+
+	if err := writeSSHMuxMessage(conn, sshMuxReply{Reused: reused}); err != nil {
+		return
+	}
+
+	down, chans, reqs, err := ssh.NewServerConn(&sshMuxConn{Conn: conn, r: reader}, m.server)
+	if err != nil {
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	if !m.track(upstream, down) {
+		_ = down.Close()
+		return
+	}
+	defer m.untrack(upstream, down)
+
+	go forwardSSHGlobalRequests(upstream.client, reqs)
+	for newChannel := range chans {
+		go proxySSHChannel(upstream.client, newChannel)
+	}
+}
+
+// acquire returns the connection for key, dialing it on first use.
+func (m *SSHMux) acquire(key string, target *sshMuxTarget) (*sshMuxUpstream, bool, error) {
+	m.mu.Lock()
+	upstream, found := m.conns[key]
+	if !found {
+		upstream = &sshMuxUpstream{key: key, ready: make(chan struct{}), downs: make(map[*ssh.ServerConn]struct{})}
+		m.conns[key] = upstream
+	}
+	upstream.refs++
+	if upstream.idleTimer != nil {
+		upstream.idleTimer.Stop()
+		upstream.idleTimer = nil
+	}
+	m.mu.Unlock()
+
+	if !found {
+		m.dial(upstream, target)
+	}
+	<-upstream.ready
+
+	if upstream.err != nil {
+		m.release(upstream)
+		return nil, false, upstream.err
+	}
+	return upstream, found, nil
+}
+
+func (m *SSHMux) dial(upstream *sshMuxUpstream, target *sshMuxTarget) {
+	defer close(upstream.ready)
+
+	ctx, cancel := withDefaultDialDeadline(context.Background())
+	defer cancel()
+
+	client, settings, err := dialSSHClient(ctx, (&net.Dialer{}).DialContext, target.config().dialParams())
+	if err != nil {
+		upstream.err = err
+		m.mu.Lock()
+		if m.conns[upstream.key] == upstream {
+			delete(m.conns, upstream.key)
+		}
+		m.mu.Unlock()
+		return
+	}
+	upstream.client = client
+	upstream.settings = settings
+
+	// Forget the connection as soon as the target drops it
+	go func() {
+		_ = client.Wait()
+		m.mu.Lock()
+		if m.conns[upstream.key] == upstream {
+			delete(m.conns, upstream.key)
+		}
+		downs := make([]*ssh.ServerConn, 0, len(upstream.downs))
+		for down := range upstream.downs {
+			downs = append(downs, down)
+		}
+		m.mu.Unlock()
+		for _, down := range downs {
+			_ = down.Close()
+		}
+	}()
+}
+
+// release drops a reference; the last one starts the idle timer.
+func (m *SSHMux) release(upstream *sshMuxUpstream) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upstream.refs--
+	if upstream.refs > 0 || upstream.client == nil {
+		return
+	}
+	upstream.idleSince = time.Now()
+	upstream.idleTimer = time.AfterFunc(m.idleTTL, func() { m.expire(upstream) })
+}
+
+// expire closes a connection that stayed unused for the idle TTL.
+func (m *SSHMux) expire(upstream *sshMuxUpstream) {
+	m.mu.Lock()
+	if upstream.refs > 0 || m.conns[upstream.key] != upstream {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.conns, upstream.key)
+	m.mu.Unlock()
+
+	_ = upstream.client.Close()
+}
+
+func (m *SSHMux) track(upstream *sshMuxUpstream, down *ssh.ServerConn) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conns[upstream.key] != upstream {
+		return false // Upstream already gone
+	}
+	upstream.downs[down] = struct{}{}
+	return true
+}
+
+func (m *SSHMux) untrack(upstream *sshMuxUpstream, down *ssh.ServerConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(upstream.downs, down)
+}
+
+func (m *SSHMux) closeAll() {
+	m.mu.Lock()
+	conns := m.conns
+	m.conns = make(map[string]*sshMuxUpstream)
+	m.mu.Unlock()
+
+	for _, upstream := range conns {
+		<-upstream.ready
+		if upstream.client != nil {
+			_ = upstream.client.Close()
+		}
+	}
+}
+
+func (m *SSHMux) connections() []SSHMuxConnection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]SSHMuxConnection, 0, len(m.conns))
+	for _, upstream := range m.conns {
+		if upstream.client == nil {
+			continue // Still dialing
+		}
+		conn := SSHMuxConnection{
+			Host:     upstream.settings.Alias,
+			Target:   upstream.settings.target(),
+			Sessions: upstream.refs,
+		}
+		if upstream.refs == 0 {
+			conn.Idle = time.Since(upstream.idleSince)
+		}
+		out = append(out, conn)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].Target < out[j].Target
+	})
+	return out
+}
+
+// forwardSSHGlobalRequests relays a client's global requests, such as
+// keepalives, to the shared connection.
+func forwardSSHGlobalRequests(upstream *ssh.Client, reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		ok, payload, err := upstream.SendRequest(req.Type, req.WantReply, req.Payload)
+		if req.WantReply {
+			_ = req.Reply(ok && err == nil, payload)
+		}
+	}
+}
+
+// proxySSHChannel opens the same channel on the shared connection and
+// relays data, stderr and requests both ways. The client side is closed
+// only after the target's output, exit status and EOF have been relayed.
+func proxySSHChannel(upstream *ssh.Client, newChannel ssh.NewChannel) {
+	up, upReqs, err := upstream.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
+	if err != nil {
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			_ = newChannel.Reject(openErr.Reason, openErr.Message)
+		} else {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+	down, downReqs, err := newChannel.Accept()
+	if err != nil {
+		_ = up.Close()
+		return
+	}
+
+	go func() {
+		relaySSHChannelOutput(up, down)
+		_ = up.CloseWrite()
+	}()
+	// A client request may still await its reply when the target closes
+	// the channel; the client side stays open until it has been answered.
+	var replying sync.Mutex
+	go func() {
+		forwardSSHChannelRequests(up, downReqs, &replying)
+		_ = up.Close()
+	}()
+
+	var toClient sync.WaitGroup
+	toClient.Add(2)
+	go func() {
+		defer toClient.Done()
+		relaySSHChannelOutput(down, up)
+		_ = down.CloseWrite()
+	}()
+	go func() {
+		defer toClient.Done()
+		forwardSSHChannelRequests(down, upReqs, new(sync.Mutex))
+	}()
+	toClient.Wait()
+
+	replying.Lock()
+	_ = down.Close()
+	replying.Unlock()
+}
+
+// relaySSHChannelOutput copies data and stderr from src to dst until both
+// reach EOF. The caller sends EOF afterwards: stderr may not follow it.
+func relaySSHChannelOutput(dst, src ssh.Channel) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(dst.Stderr(), src.Stderr())
+	}()
+	_, _ = io.Copy(dst, src)
+	wg.Wait()
+}
+
+// forwardSSHChannelRequests relays channel requests and their replies,
+// holding replying from sending each request until it has been answered.
+func forwardSSHChannelRequests(to ssh.Channel, reqs <-chan *ssh.Request, replying *sync.Mutex) {
+	for req := range reqs {
+		replying.Lock()
+		ok, err := to.SendRequest(req.Type, req.WantReply, req.Payload)
+		if req.WantReply {
+			_ = req.Reply(ok && err == nil, nil)
+		}
+		replying.Unlock()
+	}
+}
@@ -0,0 +1,306 @@
+package decorator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// startSSHMuxAgent serves a mux agent in a private temp directory and
+// points SIGIL_SSH_MUX_SOCKET at it for the rest of the test.
+func startSSHMuxAgent(t *testing.T, idleTTL time.Duration) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "mux", "ssh-mux.sock")
+	t.Setenv(SSHMuxSocketEnv, socket)
+
+	mux, err := NewSSHMux(SSHMuxConfig{Socket: socket, IdleTTL: idleTTL})
+	if err != nil {
+		t.Fatalf("new mux: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- mux.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := SSHMuxStatus(context.Background(), socket); err == nil {
+			return socket
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("mux agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sshMuxTestParams connects to server with a key file, the form the mux
+// can forward.
+func sshMuxTestParams(t *testing.T, server *SSHTestServer) map[string]any {
+	t.Helper()
+	keyPath := writeSSHConfig(t, t.TempDir(), "id_test", string(server.ClientKeyPEM))
+	return map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"user":            os.Getenv("USER"),
+		"key":             keyPath,
+		"ssh_config":      "none",
+		"strict_host_key": false,
+	}
+}
+
+func openSSHMuxTestSession(t *testing.T, params map[string]any) *SSHSession {
+	t.Helper()
+	session, err := (&SSHTransport{}).Open(NewLocalSession(), params)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return session.(*SSHSession)
+}
+
+// waitForSSHMuxConnections polls the agent until its connections satisfy
+// done.
+func waitForSSHMuxConnections(t *testing.T, socket string, done func([]SSHMuxConnection) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conns, err := SSHMuxStatus(context.Background(), socket)
+		if err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		if done(conns) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected mux connections: %+v", conns)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSSHMuxSharesConnectionAcrossSessions(t *testing.T) {
+	server := StartSSHTestServer(t)
+	t.Cleanup(server.Stop) // After the agent lets go of its connections
+	socket := startSSHMuxAgent(t, time.Minute)
+	params := sshMuxTestParams(t, server)
+
+	for i, want := range []string{"first\n", "second\n"} {
+		session := openSSHMuxTestSession(t, params)
+		if diff := cmp.Diff(socket, session.settings.Mux); diff != "" {
+			t.Errorf("session %d: mux socket mismatch (-want +got):\n%s", i, diff)
+		}
+
+		result, err := session.Run(context.Background(), []string{"echo", strings.TrimSpace(want)}, RunOpts{})
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("session %d: run: exit=%d err=%v", i, result.ExitCode, err)
+		}
+		if diff := cmp.Diff(want, string(result.Stdout)); diff != "" {
+			t.Errorf("session %d: stdout mismatch (-want +got):\n%s", i, diff)
+		}
+
+		if err := session.Put(context.Background(), []byte("via mux"), filepath.Join(t.TempDir(), "f"), 0o600); err != nil {
+			t.Fatalf("session %d: put over proxied sftp: %v", i, err)
+		}
+		_ = session.Close()
+	}
+
+	if diff := cmp.Diff(1, server.Logins()); diff != "" {
+		t.Errorf("upstream logins mismatch (-want +got):\n%s", diff)
+	}
+
+	// The agent notices closed sessions asynchronously
+	waitForSSHMuxConnections(t, socket, func(conns []SSHMuxConnection) bool {
+		return len(conns) == 1 && conns[0].Sessions == 0
+	})
+}
+
+func TestSSHMuxClosesIdleConnections(t *testing.T) {
+	server := StartSSHTestServer(t)
+	t.Cleanup(server.Stop) // After the agent lets go of its connections
+	socket := startSSHMuxAgent(t, 50*time.Millisecond)
+	params := sshMuxTestParams(t, server)
+
+	_ = openSSHMuxTestSession(t, params).Close()
+
+	waitForSSHMuxConnections(t, socket, func(conns []SSHMuxConnection) bool {
+		return len(conns) == 0
+	})
+
+	_ = openSSHMuxTestSession(t, params).Close()
+	if diff := cmp.Diff(2, server.Logins()); diff != "" {
+		t.Errorf("expected a new login after the idle TTL (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHMuxKeySeparatesCredentials(t *testing.T) {
+	server := StartSSHTestServer(t)
+	t.Cleanup(server.Stop) // After the agent lets go of its connections
+	socket := startSSHMuxAgent(t, time.Minute)
+	params := sshMuxTestParams(t, server)
+
+	_ = openSSHMuxTestSession(t, params).Close()
+
+	// Same path, rotated contents: the old connection must not be reused
+	keyPath := params["key"].(string)
+	if err := os.WriteFile(keyPath, append(server.ClientKeyPEM, '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = openSSHMuxTestSession(t, params).Close()
+
+	if diff := cmp.Diff(2, server.Logins()); diff != "" {
+		t.Errorf("upstream logins mismatch (-want +got):\n%s", diff)
+	}
+	waitForSSHMuxConnections(t, socket, func(conns []SSHMuxConnection) bool {
+		return len(conns) == 2
+	})
+}
+
+func TestSSHMuxFallsBackToDirectConnection(t *testing.T) {
+	server := StartSSHTestServer(t)
+	defer server.Stop()
+	params := sshMuxTestParams(t, server)
+
+	tests := []struct {
+		name   string
+		socket string
+	}{
+		{name: "disabled", socket: "none"},
+		{name: "no agent", socket: filepath.Join(t.TempDir(), "missing.sock")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(SSHMuxSocketEnv, tt.socket)
+
+			session := openSSHMuxTestSession(t, params)
+			defer func() { _ = session.Close() }()
+			if diff := cmp.Diff("", session.settings.Mux); diff != "" {
+				t.Errorf("expected a direct connection (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckSSHMuxDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, dir string) string
+		wantErr string
+	}{
+		{
+			name: "private directory",
+			setup: func(t *testing.T, dir string) string {
+				if err := os.Chmod(dir, 0o700); err != nil {
+					t.Fatal(err)
+				}
+				return dir
+			},
+		},
+		{
+			name: "group readable",
+			setup: func(t *testing.T, dir string) string {
+				if err := os.Chmod(dir, 0o750); err != nil {
+					t.Fatal(err)
+				}
+				return dir
+			},
+			wantErr: "must not be accessible by group or others",
+		},
+		{
+			name: "symlink",
+			setup: func(t *testing.T, dir string) string {
+				link := filepath.Join(t.TempDir(), "link")
+				if err := os.Symlink(dir, link); err != nil {
+					t.Fatal(err)
+				}
+				return link
+			},
+			wantErr: "is not a directory",
+		},
+		{
+			name: "missing",
+			setup: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "missing")
+			},
+			wantErr: "no such file or directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSSHMuxDir(tt.setup(t, t.TempDir()))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSSHMuxRefusesSecondAgent(t *testing.T) {
+	socket := startSSHMuxAgent(t, time.Minute)
+
+	mux, err := NewSSHMux(SSHMuxConfig{Socket: socket})
+	if err != nil {
+		t.Fatalf("new mux: %v", err)
+	}
+	err = mux.Serve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+}
+
+func TestSSHMuxStop(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "mux", "ssh-mux.sock")
+	mux, err := NewSSHMux(SSHMuxConfig{Socket: socket})
+	if err != nil {
+		t.Fatalf("new mux: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- mux.Serve(context.Background()) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := SSHMuxStatus(context.Background(), socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("mux agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	info, err := os.Lstat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(os.FileMode(0o600), info.Mode().Perm()); diff != "" {
+		t.Errorf("socket mode mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := StopSSHMux(context.Background(), socket); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if _, err := os.Lstat(socket); !os.IsNotExist(err) {
+		t.Errorf("expected socket removed, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package decorator
+
+import (
+	"os"
+	"syscall"
+)
+
+// sshMuxOwnedByCurrentUser reports whether the file belongs to this uid.
+func sshMuxOwnedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
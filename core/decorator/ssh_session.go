@@ -3,26 +3,34 @@ package decorator
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"maps"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 
 	"github.com/builtwithtofu/sigil/core/invariant"
+	"github.com/builtwithtofu/sigil/core/types"
 )
 
 // SSHSession implements Session for remote command execution over SSH.
 type SSHSession struct {
 	client   *ssh.Client
 	host     string
+	settings sshSettings // Effective connection settings, for --debug
 	platform string
+
+	sftpMu sync.Mutex
+	sftp   *sftp.Client // Started on first transfer
 }
 
 const (
@@ -44,7 +52,7 @@ func NewSSHSession(params map[string]any) (*SSHSession, error) {
 	dialCtx, cancel := withDefaultDialDeadline(context.Background())
 	defer cancel()
 
-	client, host, err := dialSSHClient(dialCtx, (&net.Dialer{}).DialContext, params)
+	client, settings, err := dialSSHClient(dialCtx, (&net.Dialer{}).DialContext, params)
 	if err != nil {
 		return nil, err
 	}
@@ -53,49 +61,42 @@ func NewSSHSession(params map[string]any) (*SSHSession, error) {
 
 	return &SSHSession{
 		client:   client,
-		host:     host,
+		host:     settings.Alias,
+		settings: settings,
 		platform: platform,
 	}, nil
 }
 
-func dialSSHClient(ctx context.Context, dialContext func(context.Context, string, string) (net.Conn, error), params map[string]any) (*ssh.Client, string, error) {
+func dialSSHClient(ctx context.Context, dialContext func(context.Context, string, string) (net.Conn, error), params map[string]any) (*ssh.Client, sshSettings, error) {
 	if err := acquireSSHDialSlot(ctx); err != nil {
-		return nil, "", err
+		return nil, sshSettings{}, err
 	}
 	defer releaseSSHDialSlot()
 
-	host, ok := params["host"].(string)
-	if !ok {
-		return nil, "", fmt.Errorf("host parameter required")
-	}
-
-	user, ok := params["user"].(string)
-	if !ok {
-		user = os.Getenv("USER")
-	}
-
-	port := 22
-	switch v := params["port"].(type) {
-	case int:
-		port = v
-	case int64:
-		port = int(v)
+	if _, ok := params["host"].(string); !ok {
+		return nil, sshSettings{}, fmt.Errorf("host parameter required")
 	}
 
 	// Validate host
-	if host == "" || strings.TrimSpace(host) == "" {
-		return nil, "", TransportError{
+	if host, _ := params["host"].(string); strings.TrimSpace(host) == "" {
+		return nil, sshSettings{}, TransportError{
 			Code:      TransportErrorCodeValidationFailed,
 			Message:   "SSH host cannot be empty",
 			Retryable: false,
 		}
 	}
 
+	// Explicit params win over ~/.ssh/config, which wins over defaults
+	settings, err := resolveSSHSettings(params)
+	if err != nil {
+		return nil, sshSettings{}, err
+	}
+
 	// Validate port range (1-65535)
-	if port < 1 || port > 65535 {
-		return nil, "", TransportError{
+	if settings.Port < 1 || settings.Port > 65535 {
+		return nil, sshSettings{}, TransportError{
 			Code:      TransportErrorCodeValidationFailed,
-			Message:   fmt.Sprintf("SSH port must be between 1 and 65535, got %d", port),
+			Message:   fmt.Sprintf("SSH port must be between 1 and 65535, got %d", settings.Port),
 			Retryable: false,
 		}
 	}
@@ -103,7 +104,7 @@ func dialSSHClient(ctx context.Context, dialContext func(context.Context, string
 	// Validate key file if provided as string path
 	if keyStr, ok := params["key"].(string); ok && keyStr != "" {
 		if _, err := os.Stat(keyStr); err != nil {
-			return nil, "", TransportError{
+			return nil, sshSettings{}, TransportError{
 				Code:      TransportErrorCodeValidationFailed,
 				Message:   fmt.Sprintf("SSH key file not accessible: %v", err),
 				Retryable: false,
@@ -112,54 +113,21 @@ func dialSSHClient(ctx context.Context, dialContext func(context.Context, string
 		}
 	}
 
-	// Create SSH client config
-	var authMethods []ssh.AuthMethod
-
-	// Try direct signer first (for testing)
-	switch key := params["key"].(type) {
-	case ssh.Signer:
-		authMethods = append(authMethods, ssh.PublicKeys(key))
-	case string:
-		// Try keyfile auth if string path provided
-		if keyAuth := sshKeyAuth(key); keyAuth != nil {
-			authMethods = append(authMethods, keyAuth)
-		}
-	}
-
-	// Fall back to SSH agent
-	if len(authMethods) == 0 {
-		if agentAuth := sshAgentAuth(); agentAuth != nil {
-			authMethods = append(authMethods, agentAuth)
-		}
-	}
-
-	// Host key verification
-	hostKeyCallback := getHostKeyCallback(params)
-
-	config := &ssh.ClientConfig{
-		User:            user,
-		Auth:            authMethods,
-		HostKeyCallback: hostKeyCallback,
+	// Validate certificates before dialing, so a bad cert is not retried
+	if _, err := loadSSHUserCerts(settings); err != nil {
+		return nil, sshSettings{}, err
 	}
 
-	handshakeTimeout := getSSHHandshakeTimeout(params)
-
-	// Connect
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := dialContext(ctx, "tcp", addr)
+	// Connect through jump hosts, verifying and authenticating each hop
+	dial, closeHops, err := dialSSHJumps(ctx, dialContext, &settings, params)
 	if err != nil {
-		return nil, "", TransportError{
-			Code:      TransportErrorCodeConnect,
-			Message:   "ssh dial failed",
-			Retryable: true,
-			Cause:     err,
-		}
+		return nil, sshSettings{}, err
 	}
 
-	sshConn, chans, reqs, err := sshNewClientConnWithTimeout(ctx, conn, addr, config, handshakeTimeout)
+	client, err := connectSSHHost(ctx, dial, &settings, params)
 	if err != nil {
-		_ = conn.Close()
-		return nil, "", TransportError{
+		closeHops()
+		return nil, sshSettings{}, TransportError{
 			Code:      TransportErrorCodeConnect,
 			Message:   "ssh dial failed",
 			Retryable: true,
@@ -167,9 +135,15 @@ func dialSSHClient(ctx context.Context, dialContext func(context.Context, string
 		}
 	}
 
-	client := ssh.NewClient(sshConn, chans, reqs)
+	// Jump connections live exactly as long as the final connection
+	if len(settings.Jumps) > 0 {
+		go func() {
+			_ = client.Wait()
+			closeHops()
+		}()
+	}
 
-	return client, host, nil
+	return client, settings, nil
 }
 
 // Run executes a command on the remote host.
@@ -187,86 +161,76 @@ func (s *SSHSession) Run(ctx context.Context, argv []string, opts RunOpts) (Resu
 	return runSSHSession(ctx, s.client, cmd, opts, nil)
 }
 
-// Put writes data to a file on the remote host.
-func (s *SSHSession) Put(ctx context.Context, data []byte, path string, mode fs.FileMode) error {
-	invariant.NotNil(ctx, "ctx")
-	invariant.Precondition(path != "", "path cannot be empty")
-
-	if ctx.Err() != nil {
-		return TransportError{
-			Code:      TransportErrorCodeContext,
-			Message:   "put context cancelled",
-			Retryable: false,
-			Cause:     ctx.Err(),
-		}
-	}
+// StartShell starts argv on one SSH channel that stays open until the
+// returned process is closed.
+func (s *SSHSession) StartShell(argv []string) (ShellProcess, error) {
+	invariant.Precondition(len(argv) > 0, "argv cannot be empty")
 
 	session, err := s.client.NewSession()
 	if err != nil {
-		return TransportError{
+		return nil, TransportError{
 			Code:      TransportErrorCodeSession,
 			Message:   "failed to create ssh session",
 			Retryable: true,
 			Cause:     err,
 		}
 	}
-	defer func() { _ = session.Close() }()
-
-	// Use cat to write file
-	cmd := fmt.Sprintf("cat > %s && chmod %o %s", shellQuote(path), mode, shellQuote(path))
-	session.Stdin = bytes.NewReader(data)
 
-	if err := session.Run(cmd); err != nil {
-		return TransportError{
-			Code:      TransportErrorCodeIO,
-			Message:   "failed to write remote file",
-			Retryable: true,
-			Cause:     err,
+	process := &sshShellProcess{session: session}
+	if process.stdin, err = session.StdinPipe(); err == nil {
+		if process.stdout, err = session.StdoutPipe(); err == nil {
+			process.stderr, err = session.StderrPipe()
 		}
 	}
-
-	return nil
-}
-
-// Get reads data from a file on the remote host.
-func (s *SSHSession) Get(ctx context.Context, path string) ([]byte, error) {
-	invariant.NotNil(ctx, "ctx")
-	invariant.Precondition(path != "", "path cannot be empty")
-
-	if ctx.Err() != nil {
-		return nil, TransportError{
-			Code:      TransportErrorCodeContext,
-			Message:   "get context cancelled",
-			Retryable: false,
-			Cause:     ctx.Err(),
-		}
+	if err == nil {
+		err = session.Start(shellEscape(argv))
 	}
-
-	session, err := s.client.NewSession()
 	if err != nil {
+		_ = session.Close()
 		return nil, TransportError{
 			Code:      TransportErrorCodeSession,
-			Message:   "failed to create ssh session",
+			Message:   "failed to start ssh shell",
 			Retryable: true,
 			Cause:     err,
 		}
 	}
-	defer func() { _ = session.Close() }()
 
-	var stdout bytes.Buffer
-	session.Stdout = &stdout
+	return process, nil
+}
 
-	cmd := fmt.Sprintf("cat %s", shellQuote(path))
-	if err := session.Run(cmd); err != nil {
-		return nil, TransportError{
-			Code:      TransportErrorCodeIO,
-			Message:   "failed to read remote file",
-			Retryable: true,
-			Cause:     err,
-		}
+// sshShellProcess is a shell running on its own SSH channel.
+type sshShellProcess struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	stderr  io.Reader
+}
+
+func (p *sshShellProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *sshShellProcess) Stdout() io.Reader     { return p.stdout }
+func (p *sshShellProcess) Stderr() io.Reader     { return p.stderr }
+
+func (p *sshShellProcess) Close() error {
+	_ = p.stdin.Close()
+	_ = p.session.Signal(ssh.SIGKILL)
+	if err := p.session.Close(); err != nil && !errors.Is(err, io.EOF) {
+		return err
 	}
+	return nil
+}
 
-	return stdout.Bytes(), nil
+// Put writes data to a file on the remote host.
+func (s *SSHSession) Put(ctx context.Context, data []byte, path string, mode fs.FileMode) error {
+	return s.PutStream(ctx, bytes.NewReader(data), path, mode)
+}
+
+// Get reads data from a file on the remote host.
+func (s *SSHSession) Get(ctx context.Context, path string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.GetStream(ctx, path, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // Env returns the remote environment variables.
@@ -381,8 +345,29 @@ func (s *SSHSession) NetworkDialer() NetworkDialer {
 	return s
 }
 
+// SessionEvents reports the effective connection settings, so --debug shows
+// what ~/.ssh/config resolved the host to.
+func (s *SSHSession) SessionEvents() []Event {
+	events := []Event{s.settings.Event()}
+	for _, hop := range s.settings.Jumps {
+		if event, ok := hop.trustEvent(); ok {
+			events = append(events, event)
+		}
+	}
+	if event, ok := s.settings.trustEvent(); ok {
+		events = append(events, event)
+	}
+	return events
+}
+
 // Close closes the SSH connection.
 func (s *SSHSession) Close() error {
+	s.sftpMu.Lock()
+	if s.sftp != nil {
+		_ = s.sftp.Close()
+		s.sftp = nil
+	}
+	s.sftpMu.Unlock()
 	return s.client.Close()
 }
 
@@ -419,6 +404,14 @@ func (s *SSHSessionWithEnv) Get(ctx context.Context, path string) ([]byte, error
 	return s.base.Get(ctx, path)
 }
 
+func (s *SSHSessionWithEnv) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.base.PutStream(ctx, src, path, mode)
+}
+
+func (s *SSHSessionWithEnv) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.base.GetStream(ctx, path, dst)
+}
+
 func (s *SSHSessionWithEnv) Env() map[string]string {
 	// Merge base env with delta
 	env := s.base.Env()
@@ -493,9 +486,153 @@ func init() {
 }
 
 func (t *SSHTransport) Descriptor() Descriptor {
-	return Descriptor{
-		Path: "ssh.connect",
+	return NewDescriptor("ssh.connect").
+		Summary("Run block on a remote host over SSH").
+		Roles(RoleBoundary).
+		ParamString("host", "Host name, address or ~/.ssh/config alias").
+		Required().
+		MinLength(1).
+		Examples("web01", "10.0.0.5", "prod-bastion").
+		Done().
+		ParamString("user", "Remote user (default: ssh config User, then the local user)").
+		Examples("deploy").
+		Done().
+		ParamInt("port", "Remote port (default: ssh config Port, then 22)").
+		Min(1).
+		Max(65535).
+		Examples("22", "2222").
+		Done().
+		ParamString("key", "Private key file (default: ssh config IdentityFile, then the agent)").
+		Examples("~/.ssh/id_ed25519").
+		Done().
+		ParamString("cert", "User certificate offered with the matching key (default: ssh config CertificateFile)").
+		Examples("~/.ssh/id_ed25519-cert.pub").
+		Done().
+		ParamString("known_hosts_path", "known_hosts file (default: ssh config UserKnownHostsFile, then ~/.ssh/known_hosts)").
+		Examples("~/.ssh/known_hosts").
+		Done().
+		ParamBool("strict_host_key", "Verify the host key against known_hosts; false accepts any key").
+		Default(true).
+		Done().
+		ParamBool("trust_on_first_use", "Ask before adding an unknown host key to known_hosts").
+		Default(false).
+		Done().
+		ParamDuration("handshake_timeout", "Time allowed for the SSH handshake").
+		Default("10s").
+		Examples("10s", "30s").
+		Done().
+		ParamString("ssh_config", "ssh_config file to resolve host through; \"none\" disables lookup (default: ~/.ssh/config)").
+		Examples("~/.ssh/config", "none").
+		Done().
+		ParamArray("jump", "Jump hosts to connect through, in order; an empty list disables ProxyJump").
+		ElementType(types.TypeString).
+		Examples(`["bastion"]`, `["bastion1", "user@bastion2:2222"]`).
+		Done().
+		ParamObject("env", "Environment variables added to every command").
+		AllowAdditionalProperties().
+		Done().
+		ParamString("workdir", "Working directory on the remote host").
+		Examples("/srv/app").
+		Done().
+		ParamString("command", "Command to run when there is no block").
+		Examples("uptime").
+		Done().
+		ParamString("shell", "Shell that runs command").
+		Default("bash").
+		MinLength(1).
+		Examples("bash", "sh").
+		Done().
+		Block(BlockOptional).
+		Build()
+}
+
+// sshConnectConfig is the decoded form of the @ssh.connect params.
+type sshConnectConfig struct {
+	Host             string         `decorator:"host"`
+	User             string         `decorator:"user"`
+	Port             int            `decorator:"port"`
+	Key              string         `decorator:"key"`
+	Cert             string         `decorator:"cert"`
+	KnownHostsPath   string         `decorator:"known_hosts_path"`
+	StrictHostKey    bool           `decorator:"strict_host_key"`
+	TrustOnFirstUse  bool           `decorator:"trust_on_first_use"`
+	HandshakeTimeout time.Duration  `decorator:"handshake_timeout"`
+	SSHConfig        string         `decorator:"ssh_config"`
+	Jump             []any          `decorator:"jump"`
+	Env              map[string]any `decorator:"env"`
+	Workdir          string         `decorator:"workdir"`
+	Command          string         `decorator:"command"`
+	Shell            string         `decorator:"shell"`
+
+	// signer is a key passed as an ssh.Signer by Go callers such as tests,
+	// which the plan-facing schema does not describe.
+	signer ssh.Signer
+}
+
+// decodeSSHParams validates params against the @ssh.connect schema.
+func decodeSSHParams(params map[string]any) (sshConnectConfig, error) {
+	signer, hasSigner := params["key"].(ssh.Signer)
+	if hasSigner {
+		params = maps.Clone(params)
+		delete(params, "key")
+	}
+
+	cfg, _, err := DecodeInto[sshConnectConfig]((&SSHTransport{}).Descriptor().Schema, nil, params)
+	if err != nil {
+		return sshConnectConfig{}, TransportError{
+			Code:      TransportErrorCodeValidationFailed,
+			Message:   fmt.Sprintf("invalid @ssh.connect params: %v", err),
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+	cfg.signer = signer
+	return cfg, nil
+}
+
+// dialParams returns the connection params in the form dialSSHClient reads.
+// Params left unset stay absent, so ~/.ssh/config can supply them.
+func (c sshConnectConfig) dialParams() map[string]any {
+	params := map[string]any{
+		"host":               c.Host,
+		"strict_host_key":    c.StrictHostKey,
+		"trust_on_first_use": c.TrustOnFirstUse,
+		"handshake_timeout":  c.HandshakeTimeout,
+	}
+	optional := map[string]string{
+		"user":             c.User,
+		"key":              c.Key,
+		"cert":             c.Cert,
+		"known_hosts_path": c.KnownHostsPath,
+		"ssh_config":       c.SSHConfig,
+	}
+	for name, value := range optional {
+		if value != "" {
+			params[name] = value
+		}
+	}
+	if c.signer != nil {
+		params["key"] = c.signer
+	}
+	if c.Port != 0 {
+		params["port"] = c.Port
+	}
+	if c.Jump != nil {
+		params["jump"] = c.Jump
+	}
+	return params
+}
+
+// session applies the env and workdir params to a connected session.
+func (c sshConnectConfig) session(base Session) Session {
+	session := base
+	if env := sshEnvDelta(map[string]any{"env": c.Env}); len(env) > 0 {
+		session = session.WithEnv(env)
 	}
+	if c.Workdir != "" {
+		session = session.WithWorkdir(c.Workdir)
+	}
+	return session
 }
 
 func (t *SSHTransport) Capabilities() TransportCaps {
@@ -503,6 +640,11 @@ func (t *SSHTransport) Capabilities() TransportCaps {
 }
 
 func (t *SSHTransport) Open(parent Session, params map[string]any) (Session, error) {
+	cfg, err := decodeSSHParams(params)
+	if err != nil {
+		return nil, err
+	}
+
 	dialer, err := getNetworkDialer(parent)
 	if err != nil {
 		return nil, err
@@ -511,7 +653,7 @@ func (t *SSHTransport) Open(parent Session, params map[string]any) (Session, err
 	dialCtx, cancel := withDefaultDialDeadline(context.Background())
 	defer cancel()
 
-	sshSession, err := dialSSHSession(dialCtx, dialer, params)
+	sshSession, err := openSSHSession(dialCtx, dialer, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -540,44 +682,18 @@ type sshTransportNode struct {
 }
 
 func (n *sshTransportNode) Execute(ctx ExecContext) (Result, error) {
-	if ctx.Session != nil && ctx.Session.TransportScope() == TransportScopeSSH && !strings.HasPrefix(ctx.Session.ID(), "ssh:") {
-		session := Session(ctx.Session)
-		if env := sshEnvDelta(n.params); len(env) > 0 {
-			session = session.WithEnv(env)
-		}
-		if workdir, ok := n.params["workdir"].(string); ok && workdir != "" {
-			session = session.WithWorkdir(workdir)
-		}
-
-		if n.next != nil {
-			return n.next.Execute(ctx.WithSession(session))
-		}
-
-		command, ok := n.params["command"].(string)
-		if !ok || strings.TrimSpace(command) == "" {
-			return Result{ExitCode: ExitSuccess}, nil
-		}
-
-		shellName := "bash"
-		if v, ok := n.params["shell"].(string); ok && v != "" {
-			shellName = v
-		}
-
-		argv, err := sshShellCommandArgs(shellName, command)
-		if err != nil {
-			return Result{ExitCode: ExitFailure}, err
-		}
+	cfg, err := decodeSSHParams(n.params)
+	if err != nil {
+		return Result{ExitCode: ExitFailure}, err
+	}
 
-		execCtx := ctx.Context
-		if execCtx == nil {
-			execCtx = context.Background()
-		}
+	execCtx := ctx.Context
+	if execCtx == nil {
+		execCtx = context.Background()
+	}
 
-		return session.Run(execCtx, argv, RunOpts{
-			Stdin:  ctx.Stdin,
-			Stdout: ctx.Stdout,
-			Stderr: ctx.Stderr,
-		})
+	if ctx.Session != nil && ctx.Session.TransportScope() == TransportScopeSSH && !strings.HasPrefix(ctx.Session.ID(), "ssh:") {
+		return n.run(ctx, execCtx, cfg.session(ctx.Session), cfg)
 	}
 
 	dialer, err := getNetworkDialer(ctx.Session)
@@ -585,42 +701,30 @@ func (n *sshTransportNode) Execute(ctx ExecContext) (Result, error) {
 		return Result{ExitCode: ExitFailure}, err
 	}
 
-	execCtx := ctx.Context
-	if execCtx == nil {
-		execCtx = context.Background()
-	}
-	execCtx, cancel := withDefaultDialDeadline(execCtx)
+	dialCtx, cancel := withDefaultDialDeadline(execCtx)
 	defer cancel()
 
-	sshSession, err := dialSSHSession(execCtx, dialer, n.params)
+	sshSession, err := openSSHSession(dialCtx, dialer, cfg)
 	if err != nil {
 		return Result{ExitCode: ExitFailure}, err
 	}
 	defer func() { _ = sshSession.Close() }()
+	emitSessionEvents(ctx, sshSession)
 
-	session := Session(sshSession)
-	if env := sshEnvDelta(n.params); len(env) > 0 {
-		session = session.WithEnv(env)
-	}
-	if workdir, ok := n.params["workdir"].(string); ok && workdir != "" {
-		session = session.WithWorkdir(workdir)
-	}
+	return n.run(ctx, dialCtx, cfg.session(sshSession), cfg)
+}
 
+// run executes the block, or the command param when there is no block.
+func (n *sshTransportNode) run(ctx ExecContext, execCtx context.Context, session Session, cfg sshConnectConfig) (Result, error) {
 	if n.next != nil {
 		return n.next.Execute(ctx.WithSession(session))
 	}
 
-	command, ok := n.params["command"].(string)
-	if !ok || strings.TrimSpace(command) == "" {
+	if strings.TrimSpace(cfg.Command) == "" {
 		return Result{ExitCode: ExitSuccess}, nil
 	}
 
-	shellName := "bash"
-	if v, ok := n.params["shell"].(string); ok && v != "" {
-		shellName = v
-	}
-
-	argv, err := sshShellCommandArgs(shellName, command)
+	argv, err := sshShellCommandArgs(cfg.Shell, cfg.Command)
 	if err != nil {
 		return Result{ExitCode: ExitFailure}, err
 	}
@@ -633,7 +737,7 @@ func (n *sshTransportNode) Execute(ctx ExecContext) (Result, error) {
 }
 
 func dialSSHSession(ctx context.Context, dialer NetworkDialer, params map[string]any) (*SSHSession, error) {
-	client, host, err := dialSSHClient(ctx, dialer.DialContext, params)
+	client, settings, err := dialSSHClient(ctx, dialer.DialContext, params)
 	if err != nil {
 		return nil, err
 	}
@@ -642,7 +746,8 @@ func dialSSHSession(ctx context.Context, dialer NetworkDialer, params map[string
 
 	return &SSHSession{
 		client:   client,
-		host:     host,
+		host:     settings.Alias,
+		settings: settings,
 		platform: platform,
 	}, nil
 }
@@ -721,20 +826,18 @@ func runSSHSession(ctx context.Context, client *ssh.Client, cmd string, opts Run
 	}
 }
 
-func getHostKeyCallback(params map[string]any) ssh.HostKeyCallback {
+func getHostKeyCallback(params map[string]any, knownHostsPath string) ssh.HostKeyCallback {
 	// Check if strict host key checking is disabled (opt-in insecure mode)
 	if strictHostKey, ok := params["strict_host_key"].(bool); ok && !strictHostKey {
 		return ssh.InsecureIgnoreHostKey()
 	}
 
-	// Get known_hosts path (default: ~/.ssh/known_hosts)
-	knownHostsPath := os.ExpandEnv("$HOME/.ssh/known_hosts")
-	if path, ok := params["known_hosts_path"].(string); ok {
-		knownHostsPath = path
-	}
-
 	// Try to load known_hosts file
-	callback, err := loadKnownHosts(knownHostsPath)
+	db, err := loadKnownHosts(knownHostsPath)
+	if errors.Is(err, fs.ErrNotExist) && sshTrustOnFirstUse(params) {
+		// Every host is new; trust on first use creates the file
+		db, err = &knownHostsDB{}, nil
+	}
 	if err != nil {
 		// Fail closed: reject connection if host-key verification cannot be established
 		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
@@ -742,69 +845,11 @@ func getHostKeyCallback(params map[string]any) ssh.HostKeyCallback {
 		}
 	}
 
-	return callback
-}
-
-func loadKnownHosts(path string) (ssh.HostKeyCallback, error) {
-	// Read known_hosts file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse known_hosts
-	knownHosts := make(map[string]ssh.PublicKey)
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Parse line: hostname key-type key-data
-		parts := strings.Fields(line)
-		if len(parts) < 3 {
-			continue
-		}
-
-		hostname := parts[0]
-		keyType := parts[1]
-		keyData := parts[2]
-
-		// Decode base64 key
-		keyBytes, err := base64.StdEncoding.DecodeString(keyData)
-		if err != nil {
-			continue
-		}
-
-		pubKey, err := ssh.ParsePublicKey(keyBytes)
-		if err != nil {
-			continue
-		}
-
-		knownHosts[hostname+":"+keyType] = pubKey
-	}
-
-	// Return callback that checks against known_hosts
-	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		// Build lookup key
-		lookupKey := hostname + ":" + key.Type()
-
-		// Check if host key is known
-		knownKey, ok := knownHosts[lookupKey]
-		if !ok {
-			return fmt.Errorf("host key not found in known_hosts: %s", hostname)
-		}
-
-		// Compare keys
-		if !bytes.Equal(key.Marshal(), knownKey.Marshal()) {
-			return fmt.Errorf("host key mismatch for %s", hostname)
-		}
-
-		return nil
-	}, nil
+	return db.HostKeyCallback()
 }
 
-func sshKeyAuth(keyPath string) ssh.AuthMethod {
+// sshKeySigner loads a private key file, or returns nil if it cannot be read.
+func sshKeySigner(keyPath string) ssh.Signer {
 	// Read private key file
 	keyData, err := os.ReadFile(keyPath)
 	if err != nil {
@@ -817,10 +862,12 @@ func sshKeyAuth(keyPath string) ssh.AuthMethod {
 		return nil
 	}
 
-	return ssh.PublicKeys(signer)
+	return signer
 }
 
-func sshAgentAuth() ssh.AuthMethod {
+// sshAgentSigners returns the signers held by the SSH agent, or nil if no
+// agent is reachable.
+func sshAgentSigners() func() ([]ssh.Signer, error) {
 	// Connect to SSH agent
 	socket := os.Getenv("SSH_AUTH_SOCK")
 	if socket == "" {
@@ -833,7 +880,7 @@ func sshAgentAuth() ssh.AuthMethod {
 	}
 
 	agentClient := agent.NewClient(conn)
-	return ssh.PublicKeysCallback(agentClient.Signers)
+	return agentClient.Signers
 }
 
 func parseEnv(output string) map[string]string {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
@@ -24,6 +25,9 @@ func TestMain(m *testing.M) {
 	fakeT := &testing.T{}
 	sshServer = StartSSHTestServer(fakeT)
 
+	// Connect directly even if the developer runs a mux agent
+	_ = os.Setenv(SSHMuxSocketEnv, "none")
+
 	// Run all tests
 	code := m.Run()
 
@@ -101,6 +105,69 @@ func TestSSHSessionRun(t *testing.T) {
 	}
 }
 
+// TestSSHSessionStartShell runs several commands through one shell channel.
+func TestSSHSessionStartShell(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	session, err := NewSSHSession(map[string]any{
+		"host": "127.0.0.1",
+		"port": server.Port,
+		"user": os.Getenv("USER"),
+		"key":  server.ClientKey, "strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	defer session.Close()
+
+	starter, ok := GetShellStarter(&wrappedTestSession{Session: session})
+	if !ok {
+		t.Fatal("expected SSH session to provide a shell starter through wrappers")
+	}
+
+	shell, err := starter.StartShell([]string{"bash", "--noprofile", "--norc"})
+	if err != nil {
+		t.Fatalf("StartShell failed: %v", err)
+	}
+	defer func() { _ = shell.Close() }()
+
+	if _, err := io.WriteString(shell.Stdin(), "x=41\necho $((x + 1))\necho warn >&2\necho done\nexit\n"); err != nil {
+		t.Fatalf("write to shell: %v", err)
+	}
+
+	stdout, err := io.ReadAll(shell.Stdout())
+	if err != nil {
+		t.Fatalf("read shell stdout: %v", err)
+	}
+	stderr, err := io.ReadAll(shell.Stderr())
+	if err != nil {
+		t.Fatalf("read shell stderr: %v", err)
+	}
+
+	if diff := cmp.Diff("42\ndone\n", string(stdout)); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("warn\n", string(stderr)); diff != "" {
+		t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// wrappedTestSession hides the concrete session type behind UnwrapSession.
+type wrappedTestSession struct {
+	Session
+}
+
+func (s *wrappedTestSession) UnwrapSession() Session {
+	return s.Session
+}
+
 func TestNewSSHSession_AcceptsInt64Port(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping SSH integration test in short mode")
@@ -836,6 +903,95 @@ func TestSSHTransportOpenUsesPortFromParams(t *testing.T) {
 	}
 }
 
+func TestSSHTransportDescriptorSchema(t *testing.T) {
+	schema := (&SSHTransport{}).Descriptor().Schema
+
+	var names []string
+	for _, param := range schema.GetOrderedParameters() {
+		names = append(names, param.Name)
+	}
+	want := []string{
+		"host", "user", "port", "key", "cert", "known_hosts_path", "strict_host_key", "trust_on_first_use",
+		"handshake_timeout", "ssh_config", "jump", "env", "workdir", "command", "shell",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+	}
+	if !schema.Parameters["host"].Required {
+		t.Error("host must be required")
+	}
+	if diff := cmp.Diff(true, schema.Parameters["strict_host_key"].Default); diff != "" {
+		t.Errorf("strict_host_key default mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHTransportOpenValidatesParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]any
+		err    string
+	}{
+		{name: "missing host", params: map[string]any{"port": 22}, err: `missing required parameter "host"`},
+		{name: "typo", params: map[string]any{"host": "web", "prot": 2222}, err: `unknown parameter "prot"`},
+		{name: "string port", params: map[string]any{"host": "web", "port": "2222"}, err: `parameter "port" expects integer`},
+		{name: "port out of range", params: map[string]any{"host": "web", "port": int64(70000)}, err: `invalid parameter "port"`},
+		{name: "bad timeout", params: map[string]any{"host": "web", "handshake_timeout": "soon"}, err: `parameter "handshake_timeout" expects duration`},
+		{name: "jump string", params: map[string]any{"host": "web", "jump": "bastion"}, err: `parameter "jump" expects array`},
+		{name: "strict as string", params: map[string]any{"host": "web", "strict_host_key": "no"}, err: `parameter "strict_host_key" expects boolean`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := &sshTransportParentWithDialer{}
+			_, err := (&SSHTransport{}).Open(parent, tt.params)
+
+			var transportErr TransportError
+			if !errors.As(err, &transportErr) || transportErr.Code != TransportErrorCodeValidationFailed {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %q does not mention %q", err.Error(), tt.err)
+			}
+			if parent.gotAddr != "" {
+				t.Errorf("invalid params must not dial, dialed %q", parent.gotAddr)
+			}
+		})
+	}
+}
+
+func TestSSHTransportOpenDecodesPlanParams(t *testing.T) {
+	originalNewClientConn := sshNewClientConn
+	t.Cleanup(func() {
+		sshNewClientConn = originalNewClientConn
+	})
+
+	var gotUser string
+	sshNewClientConn = func(conn net.Conn, addr string, config *ssh.ClientConfig) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
+		gotUser = config.User
+		_ = conn.Close()
+		return nil, nil, nil, errors.New("forced handshake error")
+	}
+
+	// Plans carry positional args as argN and integers as int64.
+	parent := &sshTransportParentWithDialer{}
+	_, err := (&SSHTransport{}).Open(parent, map[string]any{
+		"arg1":            "example.internal",
+		"port":            int64(2202),
+		"user":            "tester",
+		"ssh_config":      "none",
+		"strict_host_key": false,
+	})
+	if err == nil {
+		t.Fatal("Open error: got nil, want non-nil")
+	}
+
+	if diff := cmp.Diff("example.internal:2202", parent.gotAddr); diff != "" {
+		t.Errorf("Open addr mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("tester", gotUser); diff != "" {
+		t.Errorf("Open user mismatch (-want +got):\n%s", diff)
+	}
+}
+
 type sshTransportParentWithoutDialer struct{}
 
 type sshTransportScopedWithoutDialer struct {
@@ -854,6 +1010,14 @@ func (s *sshTransportParentWithoutDialer) Get(ctx context.Context, path string)
 	return nil, nil
 }
 
+func (s *sshTransportParentWithoutDialer) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (s *sshTransportParentWithoutDialer) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (s *sshTransportParentWithoutDialer) Env() map[string]string {
 	return map[string]string{}
 }
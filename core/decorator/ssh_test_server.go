@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -20,15 +24,26 @@ type SSHTestServer struct {
 	Port      int
 	HostKey   ssh.Signer
 	ClientKey ssh.Signer
-	listener  net.Listener
-	t         *testing.T
-	wg        sync.WaitGroup
-	env       map[string]string
+	// ClientKeyPEM is ClientKey as an OpenSSH private key file, for tests
+	// that authenticate through IdentityFile.
+	ClientKeyPEM []byte
+	// HostCA signed the certificate the server also presents for HostKey,
+	// valid for 127.0.0.1 and localhost.
+	HostCA ssh.Signer
+	// UserCA is trusted to sign client certificates for any user.
+	UserCA   ssh.Signer
+	listener net.Listener
+	t        testing.TB
+	wg       sync.WaitGroup
+	env      map[string]string
+	mu       sync.Mutex
+	forwards []string
+	logins   int
 }
 
 // StartSSHTestServer creates and starts a pure Go SSH server.
 // Returns nil if server cannot be started (tests will skip gracefully).
-func StartSSHTestServer(t *testing.T) *SSHTestServer {
+func StartSSHTestServer(t testing.TB) *SSHTestServer {
 	t.Helper()
 
 	// Generate ephemeral host key
@@ -55,6 +70,12 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 		return nil
 	}
 
+	clientPEM, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Skip("Failed to marshal client key:", err)
+		return nil
+	}
+
 	// Convert client public key to SSH format
 	clientSSHPub, err := ssh.NewPublicKey(clientPub)
 	if err != nil {
@@ -62,9 +83,45 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 		return nil
 	}
 
+	hostCA, err := newTestSSHSigner()
+	if err != nil {
+		t.Skip("Failed to generate host CA:", err)
+		return nil
+	}
+	userCA, err := newTestSSHSigner()
+	if err != nil {
+		t.Skip("Failed to generate user CA:", err)
+		return nil
+	}
+	hostCert := &ssh.Certificate{
+		Key:             hostKey.PublicKey(),
+		CertType:        ssh.HostCert,
+		KeyId:           "sigil-test-host",
+		ValidPrincipals: []string{"127.0.0.1", "localhost"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := hostCert.SignCert(rand.Reader, hostCA); err != nil {
+		t.Skip("Failed to sign host certificate:", err)
+		return nil
+	}
+	hostCertSigner, err := ssh.NewCertSigner(hostCert, hostKey)
+	if err != nil {
+		t.Skip("Failed to create host certificate signer:", err)
+		return nil
+	}
+
 	// Configure SSH server
+	userCerts := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), userCA.PublicKey().Marshal())
+		},
+	}
 	config := &ssh.ServerConfig{
 		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			// Accept certificates from the user CA for the connecting user
+			if _, ok := key.(*ssh.Certificate); ok {
+				return userCerts.Authenticate(conn, key)
+			}
 			// Accept only our test client key
 			if bytes.Equal(key.Marshal(), clientSSHPub.Marshal()) {
 				return &ssh.Permissions{}, nil
@@ -73,6 +130,7 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 		},
 	}
 	config.AddHostKey(hostKey)
+	config.AddHostKey(hostCertSigner)
 
 	// Listen on random port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -93,12 +151,15 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 	}
 
 	server := &SSHTestServer{
-		Port:      port,
-		HostKey:   hostKey,
-		ClientKey: clientKey,
-		listener:  listener,
-		t:         t,
-		env:       env,
+		Port:         port,
+		HostKey:      hostKey,
+		ClientKey:    clientKey,
+		ClientKeyPEM: pem.EncodeToMemory(clientPEM),
+		HostCA:       hostCA,
+		UserCA:       userCA,
+		listener:     listener,
+		t:            t,
+		env:          env,
 	}
 
 	// Start accepting connections
@@ -111,6 +172,14 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 	return server
 }
 
+func newTestSSHSigner() (ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(priv)
+}
+
 func (s *SSHTestServer) acceptLoop(config *ssh.ServerConfig) {
 	defer s.wg.Done()
 
@@ -135,6 +204,9 @@ func (s *SSHTestServer) handleConn(netConn net.Conn, config *ssh.ServerConfig) {
 		return
 	}
 	defer func() { _ = sshConn.Close() }()
+	s.mu.Lock()
+	s.logins++
+	s.mu.Unlock()
 
 	// Discard global requests
 	go ssh.DiscardRequests(reqs)
@@ -149,6 +221,10 @@ func (s *SSHTestServer) handleConn(netConn net.Conn, config *ssh.ServerConfig) {
 func (s *SSHTestServer) handleChannel(newChannel ssh.NewChannel) {
 	defer s.wg.Done()
 
+	if newChannel.ChannelType() == "direct-tcpip" {
+		s.handleDirectTCPIP(newChannel)
+		return
+	}
 	if newChannel.ChannelType() != "session" {
 		_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
 		return
@@ -172,6 +248,8 @@ func (s *SSHTestServer) handleChannel(newChannel ssh.NewChannel) {
 			s.handleExec(channel, req, sessionEnv)
 		case "env":
 			s.handleEnv(req, sessionEnv)
+		case "subsystem":
+			s.handleSubsystem(channel, req)
 		default:
 			if req.WantReply {
 				_ = req.Reply(false, nil)
@@ -180,6 +258,66 @@ func (s *SSHTestServer) handleChannel(newChannel ssh.NewChannel) {
 	}
 }
 
+// handleDirectTCPIP forwards a port-forwarding channel (as used by jump
+// hosts) to its destination and records the destination.
+func (s *SSHTestServer) handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload struct {
+		DestAddr string
+		DestPort uint32
+		OrigAddr string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.Prohibited, "malformed direct-tcpip request")
+		return
+	}
+
+	target := net.JoinHostPort(payload.DestAddr, strconv.Itoa(int(payload.DestPort)))
+	s.mu.Lock()
+	s.forwards = append(s.forwards, target)
+	s.mu.Unlock()
+
+	conn, err := net.Dial("tcp", target)
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = channel.Close() }()
+	go ssh.DiscardRequests(requests)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(conn, channel)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// Forwards returns the destinations of all port-forwarding channels opened
+// through the server, in order.
+func (s *SSHTestServer) Forwards() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.forwards...)
+}
+
+// Logins returns how many SSH connections have authenticated so far.
+func (s *SSHTestServer) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
 func (s *SSHTestServer) handleEnv(req *ssh.Request, sessionEnv map[string]string) {
 	// Parse env request: string name, string value
 	var envReq struct {
@@ -194,6 +332,30 @@ func (s *SSHTestServer) handleEnv(req *ssh.Request, sessionEnv map[string]string
 	}
 }
 
+// handleSubsystem serves the sftp subsystem from the local filesystem.
+func (s *SSHTestServer) handleSubsystem(channel ssh.Channel, req *ssh.Request) {
+	var subsystemReq struct {
+		Name string
+	}
+	if err := ssh.Unmarshal(req.Payload, &subsystemReq); err != nil || subsystemReq.Name != "sftp" {
+		if req.WantReply {
+			_ = req.Reply(false, nil)
+		}
+		return
+	}
+	if req.WantReply {
+		_ = req.Reply(true, nil)
+	}
+
+	server, err := sftp.NewServer(channel)
+	if err != nil {
+		_ = channel.Close()
+		return
+	}
+	_ = server.Serve()
+	_ = server.Close()
+}
+
 func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessionEnv map[string]string) {
 	// Parse command from request payload
 	var execReq struct {
@@ -220,12 +382,18 @@ func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessio
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Wire up I/O
+	// Wire up I/O. Stdin is copied outside exec.Cmd so a client that never
+	// closes stdin does not keep the command from completing.
 	cmd.Stdout = channel
 	cmd.Stderr = channel.Stderr()
-
-	// Run command
-	err := cmd.Run()
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		go func() {
+			_, _ = io.Copy(stdin, channel)
+			_ = stdin.Close()
+		}()
+		err = cmd.Run()
+	}
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
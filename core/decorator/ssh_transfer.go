@@ -0,0 +1,223 @@
+package decorator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+
+	"github.com/builtwithtofu/sigil/core/invariant"
+)
+
+// sftpClient returns the session's SFTP client, starting the subsystem on
+// first use. The client is shared by all transfers and closed with the
+// session.
+func (s *SSHSession) sftpClient() (*sftp.Client, error) {
+	s.sftpMu.Lock()
+	defer s.sftpMu.Unlock()
+
+	if s.sftp != nil {
+		return s.sftp, nil
+	}
+	client, err := sftp.NewClient(s.client)
+	if err != nil {
+		return nil, TransportError{
+			Code:      TransportErrorCodeSession,
+			Message:   "failed to start sftp subsystem",
+			Retryable: true,
+			Cause:     err,
+		}
+	}
+	s.sftp = client
+	return client, nil
+}
+
+// PutStream streams src to a file on the remote host over SFTP. The data is
+// written to a temporary file next to path, checked against the remote
+// SHA-256 digest, and renamed into place, so path never holds a partial file.
+func (s *SSHSession) PutStream(ctx context.Context, src io.Reader, filePath string, mode fs.FileMode) error {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "put context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	client, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	tmpPath, err := sshTransferTempPath(filePath)
+	if err != nil {
+		return err
+	}
+	file, err := client.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return sshTransferError("failed to write remote file", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = client.Remove(tmpPath)
+		}
+	}()
+
+	// Pipeline writes so throughput is not bound by round-trip time; src is
+	// still read sequentially, which keeps the running checksum in order.
+	counter := newTransferCounter(ctx, "put", filePath)
+	_, err = file.ReadFromWithConcurrency(io.TeeReader(src, counter), 0)
+	if err == nil {
+		err = file.Chmod(mode.Perm())
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return TransportError{
+				Code:      TransportErrorCodeContext,
+				Message:   "put context cancelled",
+				Retryable: false,
+				Cause:     ctx.Err(),
+			}
+		}
+		return sshTransferError("failed to write remote file", err)
+	}
+
+	if err := s.verifyRemoteChecksum(ctx, tmpPath, counter.sum()); err != nil {
+		return err
+	}
+
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		err = client.PosixRename(tmpPath, filePath)
+	} else {
+		// SFTP v3 rename refuses to replace an existing file.
+		if removeErr := client.Remove(filePath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return sshTransferError("failed to replace remote file", removeErr)
+		}
+		err = client.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		return sshTransferError("failed to replace remote file", err)
+	}
+	committed = true
+
+	counter.done()
+	return nil
+}
+
+// GetStream streams a remote file to dst over SFTP and verifies the bytes
+// received against the remote SHA-256 digest. dst may have received data
+// when an error is returned.
+func (s *SSHSession) GetStream(ctx context.Context, filePath string, dst io.Writer) error {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(filePath != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return TransportError{
+			Code:      TransportErrorCodeContext,
+			Message:   "get context cancelled",
+			Retryable: false,
+			Cause:     ctx.Err(),
+		}
+	}
+
+	client, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	file, err := client.Open(filePath)
+	if err != nil {
+		return sshTransferError("failed to read remote file", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	counter := newTransferCounter(ctx, "get", filePath)
+	if _, err := io.Copy(io.MultiWriter(counter, dst), file); err != nil {
+		if ctx.Err() != nil {
+			return TransportError{
+				Code:      TransportErrorCodeContext,
+				Message:   "get context cancelled",
+				Retryable: false,
+				Cause:     ctx.Err(),
+			}
+		}
+		return sshTransferError("failed to read remote file", err)
+	}
+
+	if err := s.verifyRemoteChecksum(ctx, filePath, counter.sum()); err != nil {
+		return err
+	}
+
+	counter.done()
+	return nil
+}
+
+// verifyRemoteChecksum compares want with the SHA-256 digest of the remote
+// file, computed on the host with sha256sum or shasum.
+func (s *SSHSession) verifyRemoteChecksum(ctx context.Context, filePath, want string) error {
+	quoted := shellQuote(filePath)
+	cmd := fmt.Sprintf("sha256sum < %s 2>/dev/null || shasum -a 256 < %s", quoted, quoted)
+	result, err := runSSHSession(ctx, s.client, cmd, RunOpts{}, nil)
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(result.Stdout))
+	if result.ExitCode != 0 || len(fields) == 0 || len(fields[0]) != sha256HexLen {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to checksum remote file",
+			Retryable: false,
+			Cause:     fmt.Errorf("sha256sum exited with %d: %s", result.ExitCode, strings.TrimSpace(string(result.Stderr))),
+		}
+	}
+
+	if got := strings.ToLower(fields[0]); got != want {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "remote file checksum mismatch",
+			Retryable: true,
+			Cause:     fmt.Errorf("%s: local sha256 %s, remote sha256 %s", filePath, want, got),
+		}
+	}
+	return nil
+}
+
+const sha256HexLen = 64
+
+// sshTransferTempPath returns a unique hidden file next to filePath, so the
+// final rename stays on one filesystem.
+func sshTransferTempPath(filePath string) (string, error) {
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	dir, name := path.Split(filePath)
+	return path.Join(dir, "."+name+".sigil-"+hex.EncodeToString(suffix[:])), nil
+}
+
+func sshTransferError(message string, err error) error {
+	return TransportError{
+		Code:      TransportErrorCodeIO,
+		Message:   message,
+		Retryable: !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission),
+		Cause:     err,
+	}
+}
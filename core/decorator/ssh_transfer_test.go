@@ -0,0 +1,224 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newSSHTransferSession(t *testing.T) *SSHSession {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	session, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"user":            os.Getenv("USER"),
+		"key":             server.ClientKey,
+		"strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+// dirEntries lists the names in dir, to check no temporary files remain.
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestSSHSessionStreamRoundTrip(t *testing.T) {
+	session := newSSHTransferSession(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.bin")
+
+	// Several SFTP packets, so writes are pipelined.
+	data := make([]byte, 3<<20+123)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	wantSum := hex.EncodeToString(digest[:])
+
+	var reports []TransferProgress
+	ctx := WithTransferProgress(context.Background(), func(p TransferProgress) {
+		reports = append(reports, p)
+	})
+
+	// Hide the reader's length so PutStream cannot rely on knowing the size.
+	if err := session.PutStream(ctx, io.MultiReader(bytes.NewReader(data)), path, 0o640); err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("remote content differs: got %d bytes, want %d", len(got), len(data))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode: got %o, want 640", info.Mode().Perm())
+	}
+	if diff := cmp.Diff([]string{"dump.bin"}, dirEntries(t, dir)); diff != "" {
+		t.Errorf("temporary file left behind (-want +got):\n%s", diff)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("expected progress reports before completion, got %d", len(reports))
+	}
+	wantFinal := TransferProgress{Op: "put", Path: path, Bytes: int64(len(data)), Done: true, SHA256: wantSum}
+	if diff := cmp.Diff(wantFinal, reports[len(reports)-1]); diff != "" {
+		t.Errorf("final put report mismatch (-want +got):\n%s", diff)
+	}
+
+	reports = nil
+	var out bytes.Buffer
+	if err := session.GetStream(ctx, path, &out); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	if !bytes.Equal(data, out.Bytes()) {
+		t.Fatalf("downloaded content differs: got %d bytes, want %d", out.Len(), len(data))
+	}
+	wantFinal.Op = "get"
+	if diff := cmp.Diff(wantFinal, reports[len(reports)-1]); diff != "" {
+		t.Errorf("final get report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSSHSessionPutUsesStreaming(t *testing.T) {
+	session := newSSHTransferSession(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := session.Put(context.Background(), []byte("v1\n"), path, 0o600); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := session.Put(context.Background(), []byte("v2\n"), path, 0o600); err != nil {
+		t.Fatalf("Put replacing existing file: %v", err)
+	}
+	got, err := session.Get(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if diff := cmp.Diff("v2\n", string(got)); diff != "" {
+		t.Errorf("content mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSSHSessionPutStreamKeepsOriginalOnFailure(t *testing.T) {
+	session := newSSHTransferSession(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "release.tar")
+	if err := os.WriteFile(path, []byte("original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sourceErr := errors.New("source went away")
+	err := session.PutStream(context.Background(), &failingReader{data: []byte("partial"), err: sourceErr}, path, 0o644)
+	if !errors.Is(err, sourceErr) {
+		t.Fatalf("expected source error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelling := io.MultiReader(strings.NewReader("partial"), readerFunc(func(p []byte) (int, error) {
+		cancel()
+		return copy(p, "more"), nil
+	}))
+	err = session.PutStream(ctx, cancelling, path, 0o644)
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Code != TransportErrorCodeContext {
+		t.Fatalf("expected context error, got %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("original", string(got)); diff != "" {
+		t.Errorf("failed transfer must not touch the target (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"release.tar"}, dirEntries(t, dir)); diff != "" {
+		t.Errorf("temporary file left behind (-want +got):\n%s", diff)
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+func TestSSHSessionGetStreamMissingFile(t *testing.T) {
+	session := newSSHTransferSession(t)
+
+	err := session.GetStream(context.Background(), filepath.Join(t.TempDir(), "missing"), io.Discard)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	var transportErr TransportError
+	if !errors.As(err, &transportErr) || transportErr.Retryable {
+		t.Errorf("missing file must be a non-retryable transport error, got %#v", err)
+	}
+}
+
+func TestSSHSessionVerifyRemoteChecksum(t *testing.T) {
+	session := newSSHTransferSession(t)
+	path := filepath.Join(t.TempDir(), "artifact")
+	if err := os.WriteFile(path, []byte("artifact"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("artifact"))
+
+	if err := session.verifyRemoteChecksum(context.Background(), path, hex.EncodeToString(digest[:])); err != nil {
+		t.Fatalf("matching checksum: %v", err)
+	}
+
+	err := session.verifyRemoteChecksum(context.Background(), path, strings.Repeat("0", sha256HexLen))
+	if err == nil || !strings.Contains(err.Error(), "remote file checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package decorator
 
 import (
 	"context"
+	"io"
 	"io/fs"
 	"sync"
 )
@@ -65,6 +66,20 @@ func (m *MonitoredSession) Get(ctx context.Context, path string) ([]byte, error)
 	return m.wrapped.Get(ctx, path)
 }
 
+func (m *MonitoredSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	m.stats.mu.Lock()
+	m.stats.PutCalls++
+	m.stats.mu.Unlock()
+	return m.wrapped.PutStream(ctx, src, path, mode)
+}
+
+func (m *MonitoredSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	m.stats.mu.Lock()
+	m.stats.GetCalls++
+	m.stats.mu.Unlock()
+	return m.wrapped.GetStream(ctx, path, dst)
+}
+
 func (m *MonitoredSession) Env() map[string]string {
 	m.stats.mu.Lock()
 	m.stats.EnvCalls++
@@ -267,6 +282,14 @@ func (s *testTransportSession) Get(ctx context.Context, path string) ([]byte, er
 	return s.parent.Get(ctx, path)
 }
 
+func (s *testTransportSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *testTransportSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *testTransportSession) Env() map[string]string {
 	return s.parent.Env()
 }
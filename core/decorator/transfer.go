@@ -0,0 +1,117 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+)
+
+// TransferProgress reports how far a PutStream or GetStream has got.
+type TransferProgress struct {
+	Op     string // "put" or "get"
+	Path   string // Path on the session's filesystem
+	Bytes  int64  // Bytes transferred so far
+	Done   bool   // Final report, sent once the transfer completed
+	SHA256 string // Hex digest of the content; set on the final report
+}
+
+// TransferProgressFunc receives progress reports. It is called from the
+// transferring goroutine and must not block.
+type TransferProgressFunc func(TransferProgress)
+
+type transferProgressKey struct{}
+
+// WithTransferProgress returns a context that reports the progress of
+// streaming transfers made with it to fn.
+func WithTransferProgress(ctx context.Context, fn TransferProgressFunc) context.Context {
+	return context.WithValue(ctx, transferProgressKey{}, fn)
+}
+
+// transferCounter counts and hashes the bytes of one transfer as they are
+// written to it, reporting progress to the context's callback. Writes fail
+// once the context is cancelled, which interrupts the copy between chunks.
+type transferCounter struct {
+	ctx      context.Context
+	progress TransferProgress
+	report   TransferProgressFunc
+	hash     hash.Hash
+}
+
+func newTransferCounter(ctx context.Context, op, path string) *transferCounter {
+	report, _ := ctx.Value(transferProgressKey{}).(TransferProgressFunc)
+	return &transferCounter{
+		ctx:      ctx,
+		progress: TransferProgress{Op: op, Path: path},
+		report:   report,
+		hash:     sha256.New(),
+	}
+}
+
+func (c *transferCounter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.hash.Write(p)
+	c.progress.Bytes += int64(len(p))
+	if c.report != nil {
+		c.report(c.progress)
+	}
+	return len(p), nil
+}
+
+// sum returns the hex SHA-256 digest of everything written so far.
+func (c *transferCounter) sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// done sends the final progress report.
+func (c *transferCounter) done() {
+	c.progress.Done = true
+	c.progress.SHA256 = c.sum()
+	if c.report != nil {
+		c.report(c.progress)
+	}
+}
+
+// putStreamBuffered implements PutStream for sessions whose transfer
+// mechanism needs the whole file up front, such as tar archives.
+func putStreamBuffered(ctx context.Context, session Session, src io.Reader, path string, mode fs.FileMode) error {
+	counter := newTransferCounter(ctx, "put", path)
+	data, err := io.ReadAll(io.TeeReader(src, counter))
+	if err != nil {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to read transfer source",
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+	if err := session.Put(ctx, data, path, mode); err != nil {
+		return err
+	}
+	counter.done()
+	return nil
+}
+
+// getStreamBuffered implements GetStream on top of Get.
+func getStreamBuffered(ctx context.Context, session Session, path string, dst io.Writer) error {
+	data, err := session.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	counter := newTransferCounter(ctx, "get", path)
+	if _, err := io.Copy(io.MultiWriter(dst, counter), bytes.NewReader(data)); err != nil {
+		return TransportError{
+			Code:      TransportErrorCodeIO,
+			Message:   "failed to write transfer destination",
+			Retryable: false,
+			Cause:     err,
+		}
+	}
+	counter.done()
+	return nil
+}
@@ -24,6 +24,29 @@ type Transport interface {
 	IsolationContext() IsolationContext
 }
 
+// TargetResolver is an optional interface for transports whose target is
+// chosen dynamically, such as a pod picked by label selector. The planner
+// calls ResolveTarget once and records the returned params in the plan, so
+// the contract pins the concrete target and verification detects drift.
+type TargetResolver interface {
+	ResolveTarget(parent Session, params map[string]any) (map[string]any, error)
+}
+
+// FanOut is an optional interface for exec decorators that run their block
+// once per target through a transport, such as @ssh.each. The planner calls
+// FanOutTargets and expands the block into one transport block per target,
+// so every target gets its own entry in the plan's transport table.
+type FanOut interface {
+	FanOutTargets(params map[string]any) (FanOutPlan, error)
+}
+
+// FanOutPlan describes how the planner expands a fan-out block.
+type FanOutPlan struct {
+	Transport string           // Transport each target runs under, e.g. "@ssh.connect"
+	Params    map[string]any   // Params kept on the fan-out decorator, pinned in the plan
+	Targets   []map[string]any // Transport params, one entry per target, in block order
+}
+
 // IsolationContext provides isolation capabilities for transports.
 type IsolationContext interface {
 	// Isolate applies the specified isolation level.
@@ -1,5 +1,7 @@
 package decorator
 
+import "time"
+
 // Value is the interface for decorators that produce values.
 // Value decorators are pure functions that resolve at plan-time.
 // Examples: @var, @env, @aws.secret
@@ -28,6 +30,24 @@ type ResolveResult struct {
 
 	// Error is the per-call error (nil if successful)
 	Error error
+
+	// Metadata describes the secret's version and lifetime, for decorators
+	// that know it (secret managers, the secret store). Zero if unknown.
+	Metadata SecretMetadata
+}
+
+// SecretMetadata is what a provider knows about the version of a secret it
+// returned. Plans record it so verification can tell a rotated secret from
+// one that now comes from a different source, and warn before expiry.
+type SecretMetadata struct {
+	Version   string    // Provider's version identifier (e.g., "3"); empty if unknown
+	CreatedAt time.Time // When this version was created; zero if unknown
+	ExpiresAt time.Time // When this version stops being valid; zero if never or unknown
+}
+
+// IsZero reports whether no metadata is known.
+func (m SecretMetadata) IsZero() bool {
+	return m.Version == "" && m.CreatedAt.IsZero() && m.ExpiresAt.IsZero()
 }
 
 // ValueEvalContext provides the execution context for value resolution.
@@ -74,4 +94,10 @@ type ResolvedValue struct {
 
 	// DisplayID is the deterministic secret ID (e.g., "sigil:s:3J98t56A")
 	DisplayID string
+
+	// Origin is where the value came from (e.g., "@env.API_KEY"), for audits
+	Origin string
+
+	// Metadata is the secret's version and lifetime, if the decorator reported it
+	Metadata SecretMetadata
 }
@@ -0,0 +1,355 @@
+package planfmt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SecretAudit lists every secret a plan unwraps and where, for security
+// review. It contains DisplayIDs and origins, never values.
+type SecretAudit struct {
+	Secrets []AuditedSecret // Sorted by Origin, then first Site, then DisplayID
+}
+
+// AuditedSecret is one secret and the sites authorized to use it.
+type AuditedSecret struct {
+	DisplayID string       // e.g. "sigil:3J98t56A"
+	Origin    string       // e.g. "@env.DB_PASSWORD"; empty if unknown
+	Uses      []SecretSite // Sorted by Site
+}
+
+// SecretSite describes one use of a secret.
+type SecretSite struct {
+	Site        string // SecretUse.Site, e.g. "root/step-1/@shell[0]/params/command"
+	Decorator   string // Consuming decorator, e.g. "@shell"; empty if the site is not in the plan
+	Param       string // Consuming parameter, e.g. "command"
+	TransportID string // Transport the consuming command runs in
+	Transport   string // That transport's decorator and arguments, or "local"
+	Pipeline    bool   // The command is part of a pipeline
+	Redirect    bool   // The command is a redirect source or sink
+}
+
+// SecretFlow is a use of a secret identified by what it connects rather
+// than by DisplayID and site, which change from one plan to the next.
+type SecretFlow struct {
+	Origin    string
+	Decorator string
+	Param     string
+	Transport string
+	Pipeline  bool
+	Redirect  bool
+}
+
+// String formats the flow as "@env.TOKEN -> @shell.command on local (pipeline)".
+func (f SecretFlow) String() string {
+	origin := f.Origin
+	if origin == "" {
+		origin = "<unknown origin>"
+	}
+	s := fmt.Sprintf("%s -> %s.%s on %s", origin, f.Decorator, f.Param, f.Transport)
+	if ctx := siteContext(f.Pipeline, f.Redirect); ctx != "" {
+		s += " (" + ctx + ")"
+	}
+	return s
+}
+
+// Context describes the shell plumbing around the site: "pipeline",
+// "redirect", "pipeline, redirect" or "".
+func (s SecretSite) Context() string {
+	return siteContext(s.Pipeline, s.Redirect)
+}
+
+func siteContext(pipeline, redirect bool) string {
+	var parts []string
+	if pipeline {
+		parts = append(parts, "pipeline")
+	}
+	if redirect {
+		parts = append(parts, "redirect")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AuditSecrets builds the secret audit of p from its SecretUses, locating
+// each site in the execution tree to find its transport and context.
+func AuditSecrets(p *Plan) *SecretAudit {
+	idx := indexSites(p.Steps)
+	transports := make(map[string]Transport, len(p.Transports))
+	for _, t := range p.Transports {
+		transports[t.ID] = t
+	}
+
+	byID := make(map[string]*AuditedSecret)
+	var ids []string
+	for _, use := range p.SecretUses {
+		secret, ok := byID[use.DisplayID]
+		if !ok {
+			secret = &AuditedSecret{DisplayID: use.DisplayID, Origin: use.Origin}
+			byID[use.DisplayID] = secret
+			ids = append(ids, use.DisplayID)
+		}
+		secret.Uses = append(secret.Uses, idx.locate(use, transports))
+	}
+
+	audit := &SecretAudit{Secrets: make([]AuditedSecret, 0, len(ids))}
+	for _, id := range ids {
+		secret := byID[id]
+		sort.Slice(secret.Uses, func(i, j int) bool { return secret.Uses[i].Site < secret.Uses[j].Site })
+		audit.Secrets = append(audit.Secrets, *secret)
+	}
+
+	// DisplayIDs are random per plan, so order by what stays put between
+	// plans and only break ties by DisplayID.
+	sort.Slice(audit.Secrets, func(i, j int) bool {
+		a, b := audit.Secrets[i], audit.Secrets[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		if a.Uses[0].Site != b.Uses[0].Site {
+			return a.Uses[0].Site < b.Uses[0].Site
+		}
+		return a.DisplayID < b.DisplayID
+	})
+	return audit
+}
+
+// Flows returns the distinct flows in the audit, sorted.
+func (a *SecretAudit) Flows() []SecretFlow {
+	seen := make(map[SecretFlow]bool)
+	var flows []SecretFlow
+	for _, secret := range a.Secrets {
+		for _, use := range secret.Uses {
+			flow := SecretFlow{
+				Origin:    secret.Origin,
+				Decorator: use.Decorator,
+				Param:     use.Param,
+				Transport: use.Transport,
+				Pipeline:  use.Pipeline,
+				Redirect:  use.Redirect,
+			}
+			if !seen[flow] {
+				seen[flow] = true
+				flows = append(flows, flow)
+			}
+		}
+	}
+	sort.Slice(flows, func(i, j int) bool { return flows[i].String() < flows[j].String() })
+	return flows
+}
+
+// CompareSecretFlows returns the flows in current that are not in baseline
+// (added) and the flows in baseline that are no longer in current (removed).
+func CompareSecretFlows(baseline, current *SecretAudit) (added, removed []SecretFlow) {
+	before := make(map[SecretFlow]bool)
+	for _, flow := range baseline.Flows() {
+		before[flow] = true
+	}
+	after := make(map[SecretFlow]bool)
+	for _, flow := range current.Flows() {
+		after[flow] = true
+		if !before[flow] {
+			added = append(added, flow)
+		}
+	}
+	for _, flow := range baseline.Flows() {
+		if !after[flow] {
+			removed = append(removed, flow)
+		}
+	}
+	return added, removed
+}
+
+// siteCommand is a command that can consume secrets, with its context.
+type siteCommand struct {
+	node     *CommandNode
+	pipeline bool
+	redirect bool
+}
+
+// stepSites holds the commands of one step, keyed the way the planner
+// names them in sites: "@shell[0]" is the step's first @shell command.
+// Redirect sinks that are not decorator calls are recorded against the
+// step itself, so they are kept separately.
+type stepSites struct {
+	commands map[string]siteCommand
+	sinks    []siteCommand
+}
+
+type siteIndex map[uint64]*stepSites
+
+func indexSites(steps []Step) siteIndex {
+	idx := make(siteIndex)
+	idx.addSteps(steps)
+	return idx
+}
+
+func (idx siteIndex) addSteps(steps []Step) {
+	for i := range steps {
+		sites := &stepSites{commands: make(map[string]siteCommand)}
+		idx[steps[i].ID] = sites
+		idx.addNode(sites, make(map[string]int), steps[i].Tree, false, false)
+	}
+}
+
+// addNode records the commands of node in emission order (left to right,
+// redirect sources before sinks) and indexes nested blocks as their own steps.
+func (idx siteIndex) addNode(sites *stepSites, counts map[string]int, node ExecutionNode, pipeline, redirect bool) {
+	switch n := node.(type) {
+	case *CommandNode:
+		key := fmt.Sprintf("%s[%d]", n.Decorator, counts[n.Decorator])
+		counts[n.Decorator]++
+		sites.commands[key] = siteCommand{node: n, pipeline: pipeline, redirect: redirect}
+		idx.addSteps(n.Block)
+	case *PipelineNode:
+		for _, cmd := range n.Commands {
+			idx.addNode(sites, counts, cmd, true, redirect)
+		}
+	case *AndNode:
+		idx.addNode(sites, counts, n.Left, pipeline, redirect)
+		idx.addNode(sites, counts, n.Right, pipeline, redirect)
+	case *OrNode:
+		idx.addNode(sites, counts, n.Left, pipeline, redirect)
+		idx.addNode(sites, counts, n.Right, pipeline, redirect)
+	case *SequenceNode:
+		for _, child := range n.Nodes {
+			idx.addNode(sites, counts, child, pipeline, redirect)
+		}
+	case *RedirectNode:
+		idx.addNode(sites, counts, n.Source, pipeline, true)
+		sites.sinks = append(sites.sinks, siteCommand{node: &n.Target, pipeline: pipeline, redirect: true})
+	case *LogicNode:
+		idx.addSteps(n.Block)
+	case *TryNode:
+		idx.addSteps(n.TryBlock)
+		idx.addSteps(n.CatchBlock)
+		idx.addSteps(n.FinallyBlock)
+	case *DAGNode:
+		for i := range n.Tasks {
+			idx.addSteps(n.Tasks[i].Block)
+		}
+	}
+}
+
+// locate finds the command behind a use's site. Sites name the innermost
+// step ("step-3"), then the decorator ("@shell[0]") and parameter
+// ("params/command"); redirect sinks omit the decorator.
+func (idx siteIndex) locate(use SecretUse, transports map[string]Transport) SecretSite {
+	site := SecretSite{Site: use.Site}
+
+	path, param, found := strings.Cut(use.Site, "/params/")
+	if !found {
+		return site
+	}
+	site.Param = param
+
+	segments := strings.Split(path, "/")
+	last := -1
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "step-") {
+			last = i
+		}
+	}
+	if last < 0 {
+		return site
+	}
+	stepID, err := strconv.ParseUint(strings.TrimPrefix(segments[last], "step-"), 10, 64)
+	if err != nil {
+		return site
+	}
+	sites, ok := idx[stepID]
+	if !ok {
+		return site
+	}
+
+	var cmd siteCommand
+	switch rest := segments[last+1:]; len(rest) {
+	case 0:
+		if cmd, ok = sites.sink(use.DisplayID); !ok {
+			return site
+		}
+	case 1:
+		if cmd, ok = sites.commands[rest[0]]; !ok {
+			return site
+		}
+	default:
+		return site
+	}
+
+	site.Decorator = cmd.node.Decorator
+	site.TransportID = cmd.node.TransportID
+	site.Transport = describeTransport(cmd.node.TransportID, transports)
+	site.Pipeline = cmd.pipeline
+	site.Redirect = cmd.redirect
+	return site
+}
+
+// sink returns the redirect sink whose arguments mention displayID, or the
+// step's only sink.
+func (s *stepSites) sink(displayID string) (siteCommand, bool) {
+	for _, sink := range s.sinks {
+		for _, arg := range sink.node.Args {
+			if arg.Val.Kind == ValueString && strings.Contains(arg.Val.Str, displayID) {
+				return sink, true
+			}
+		}
+	}
+	if len(s.sinks) == 1 {
+		return s.sinks[0], true
+	}
+	return siteCommand{}, false
+}
+
+// describeTransport formats a transport as its decorator and arguments,
+// e.g. `@ssh.connect(host="web1", user="deploy")`.
+func describeTransport(id string, transports map[string]Transport) string {
+	t, ok := transports[id]
+	if !ok || t.Decorator == "" || t.Decorator == "local" {
+		return "local"
+	}
+	if len(t.Args) == 0 {
+		return t.Decorator
+	}
+	args := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		args[i] = arg.Key + "=" + describeValue(arg.Val)
+	}
+	return t.Decorator + "(" + strings.Join(args, ", ") + ")"
+}
+
+func describeValue(v Value) string {
+	switch v.Kind {
+	case ValueString:
+		return strconv.Quote(v.Str)
+	case ValueInt:
+		return strconv.FormatInt(v.Int, 10)
+	case ValueBool:
+		return strconv.FormatBool(v.Bool)
+	case ValueFloat:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case ValueDuration:
+		return v.Duration
+	case ValuePlaceholder:
+		return fmt.Sprintf("$%d", v.Ref)
+	case ValueArray:
+		items := make([]string, len(v.Array))
+		for i, item := range v.Array {
+			items[i] = describeValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case ValueMap:
+		keys := make([]string, 0, len(v.Map))
+		for key := range v.Map {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = key + ": " + describeValue(v.Map[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return "?"
+	}
+}
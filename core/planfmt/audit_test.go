@@ -0,0 +1,137 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/google/go-cmp/cmp"
+)
+
+func shellCmd(command, transport string) planfmt.CommandNode {
+	return planfmt.CommandNode{
+		Decorator:   "@shell",
+		TransportID: transport,
+		Args: []planfmt.Arg{
+			{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: command}},
+		},
+	}
+}
+
+func auditPlan() *planfmt.Plan {
+	cat, grep := shellCmd("cat sigil:tok", "local"), shellCmd("grep x", "local")
+	echo, sink := shellCmd("echo hi", "local"), shellCmd("out-sigil:db.txt", "local")
+	deploy := shellCmd("deploy sigil:tok", "transport:ssh")
+
+	return &planfmt.Plan{
+		Target: "deploy",
+		Transports: []planfmt.Transport{
+			{ID: "local", Decorator: "local"},
+			{ID: "transport:ssh", Decorator: "@ssh.connect", ParentID: "local", Args: []planfmt.Arg{
+				{Key: "host", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "web1"}},
+				{Key: "port", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: 22}},
+			}},
+		},
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.PipelineNode{Commands: []planfmt.ExecutionNode{&cat, &grep}}},
+			{ID: 2, Tree: &planfmt.RedirectNode{Source: &echo, Target: sink, Mode: planfmt.RedirectOverwrite}},
+			{ID: 3, Tree: &planfmt.CommandNode{
+				Decorator: "@exec.retry",
+				Args:      []planfmt.Arg{{Key: "times", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: 3}}},
+				Block:     []planfmt.Step{{ID: 4, Tree: &deploy}},
+			}},
+		},
+		SecretUses: []planfmt.SecretUse{
+			{DisplayID: "sigil:tok", SiteID: "a", Site: "root/step-1/@shell[0]/params/command", Origin: "@env.TOKEN"},
+			{DisplayID: "sigil:db", SiteID: "b", Site: "root/step-2/params/command", Origin: `@secrets.get(path="db")`},
+			{DisplayID: "sigil:tok", SiteID: "c", Site: "root/step-3/@exec.retry[0]/step-4/@shell[0]/params/command", Origin: "@env.TOKEN"},
+			{DisplayID: "sigil:old", SiteID: "d", Site: "root/step-9/@shell[0]/params/command"},
+		},
+	}
+}
+
+func TestAuditSecrets(t *testing.T) {
+	got := planfmt.AuditSecrets(auditPlan())
+
+	want := &planfmt.SecretAudit{Secrets: []planfmt.AuditedSecret{
+		{DisplayID: "sigil:old", Uses: []planfmt.SecretSite{
+			{Site: "root/step-9/@shell[0]/params/command", Param: "command"},
+		}},
+		{DisplayID: "sigil:tok", Origin: "@env.TOKEN", Uses: []planfmt.SecretSite{
+			{Site: "root/step-1/@shell[0]/params/command", Decorator: "@shell", Param: "command", TransportID: "local", Transport: "local", Pipeline: true},
+			{Site: "root/step-3/@exec.retry[0]/step-4/@shell[0]/params/command", Decorator: "@shell", Param: "command", TransportID: "transport:ssh", Transport: `@ssh.connect(host="web1", port=22)`},
+		}},
+		{DisplayID: "sigil:db", Origin: `@secrets.get(path="db")`, Uses: []planfmt.SecretSite{
+			{Site: "root/step-2/params/command", Decorator: "@shell", Param: "command", TransportID: "local", Transport: "local", Redirect: true},
+		}},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("AuditSecrets mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAuditSecretsOrderIgnoresDisplayIDs(t *testing.T) {
+	plan := auditPlan()
+	plan.SecretUses = []planfmt.SecretUse{
+		{DisplayID: "sigil:a", SiteID: "a", Site: "root/step-3/@exec.retry[0]/step-4/@shell[0]/params/command", Origin: "@env.TOKEN"},
+		{DisplayID: "sigil:b", SiteID: "b", Site: "root/step-1/@shell[0]/params/command", Origin: "@env.TOKEN"},
+	}
+
+	var got []string
+	for _, secret := range planfmt.AuditSecrets(plan).Secrets {
+		got = append(got, secret.DisplayID)
+	}
+	// Same origin: the secret used first in the plan comes first
+	if diff := cmp.Diff([]string{"sigil:b", "sigil:a"}, got); diff != "" {
+		t.Errorf("audit order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareSecretFlows(t *testing.T) {
+	baseline := auditPlan()
+	current := auditPlan()
+	// Same flows under new DisplayIDs and step numbers are not changes
+	current.SecretUses[0].DisplayID = "sigil:tok2"
+	current.SecretUses[2].DisplayID = "sigil:tok2"
+	// The token now also reaches the redirect sink; the db secret is gone
+	current.SecretUses[1] = planfmt.SecretUse{DisplayID: "sigil:tok2", SiteID: "b", Site: "root/step-2/params/command", Origin: "@env.TOKEN"}
+
+	added, removed := planfmt.CompareSecretFlows(planfmt.AuditSecrets(baseline), planfmt.AuditSecrets(current))
+
+	gotAdded := make([]string, len(added))
+	for i, flow := range added {
+		gotAdded[i] = flow.String()
+	}
+	gotRemoved := make([]string, len(removed))
+	for i, flow := range removed {
+		gotRemoved[i] = flow.String()
+	}
+	if diff := cmp.Diff([]string{"@env.TOKEN -> @shell.command on local (redirect)"}, gotAdded); diff != "" {
+		t.Errorf("added flows mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{`@secrets.get(path="db") -> @shell.command on local (redirect)`}, gotRemoved); diff != "" {
+		t.Errorf("removed flows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSecretUseOriginRoundTrip(t *testing.T) {
+	plan := auditPlan()
+
+	var buf bytes.Buffer
+	if _, err := planfmt.Write(&buf, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, _, err := planfmt.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	origins := make(map[string]string)
+	for _, use := range got.SecretUses {
+		origins[use.SiteID] = use.Origin
+	}
+	want := map[string]string{"a": "@env.TOKEN", "b": `@secrets.get(path="db")`, "c": "@env.TOKEN", "d": ""}
+	if diff := cmp.Diff(want, origins); diff != "" {
+		t.Errorf("origins mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -40,11 +40,13 @@ type CanonicalStep struct {
 type CanonicalSecretUse struct {
 	DisplayID string // Secret identifier (e.g., "sigil:3J98t56A")
 	Site      string // Human-readable path (e.g., "root/step-1/params/command")
+	Origin    string // Value source (e.g., "@env.API_KEY"); a changed source is drift
+	Version   string // Secret version; a rotated secret is drift
 }
 
 // CanonicalNode is a union type for execution tree nodes in canonical form
 type CanonicalNode struct {
-	Type string // "command", "pipeline", "and", "or", "sequence", "redirect", "logic"
+	Type string // "command", "pipeline", "and", "or", "sequence", "redirect", "logic", "dag"
 
 	// CommandNode fields
 	Decorator   string
@@ -71,6 +73,16 @@ type CanonicalNode struct {
 	LogicKind string
 	Condition string
 	Result    string
+
+	// DAGNode fields (dependency edges are part of the hash)
+	Tasks []CanonicalDAGTask
+}
+
+// CanonicalDAGTask represents a DAG task in canonical form
+type CanonicalDAGTask struct {
+	Name  string
+	Needs []string
+	Block []CanonicalStep
 }
 
 // CanonicalArg represents an argument in canonical form
@@ -140,6 +152,8 @@ func (p *Plan) Canonicalize() (*CanonicalPlan, error) {
 		secretUses[i] = CanonicalSecretUse{
 			DisplayID: p.SecretUses[i].DisplayID,
 			Site:      p.SecretUses[i].Site,
+			Origin:    p.SecretUses[i].Origin,
+			Version:   p.SecretUses[i].Version,
 		}
 	}
 	sort.Slice(secretUses, func(i, j int) bool {
@@ -196,6 +210,8 @@ func toCanonicalNode(node ExecutionNode) (CanonicalNode, error) {
 		return canonicalizeRedirectNode(n)
 	case *LogicNode:
 		return canonicalizeLogicNode(n)
+	case *DAGNode:
+		return canonicalizeDAGNode(n)
 	default:
 		return CanonicalNode{}, fmt.Errorf("unknown node type: %T", node)
 	}
@@ -396,6 +412,30 @@ func canonicalizeLogicNode(n *LogicNode) (CanonicalNode, error) {
 	return cn, nil
 }
 
+func canonicalizeDAGNode(n *DAGNode) (CanonicalNode, error) {
+	cn := CanonicalNode{
+		Type:  "dag",
+		Tasks: make([]CanonicalDAGTask, len(n.Tasks)),
+	}
+	for i := range n.Tasks {
+		task := &n.Tasks[i]
+		ct := CanonicalDAGTask{
+			Name:  task.Name,
+			Needs: append([]string{}, task.Needs...),
+			Block: make([]CanonicalStep, len(task.Block)),
+		}
+		for j := range task.Block {
+			step, err := canonicalizeStep(&task.Block[j])
+			if err != nil {
+				return CanonicalNode{}, fmt.Errorf("dag task %q step %d: %w", task.Name, j, err)
+			}
+			ct.Block[j] = step
+		}
+		cn.Tasks[i] = ct
+	}
+	return cn, nil
+}
+
 // MarshalBinary produces deterministic CBOR encoding of the canonical plan.
 // This ensures byte-for-byte stability across multiple runs.
 func (cp *CanonicalPlan) MarshalBinary() ([]byte, error) {
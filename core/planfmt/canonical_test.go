@@ -423,3 +423,48 @@ func TestCanonicalHashIncludesLogicNode(t *testing.T) {
 func bytesEqual(a, b []byte) bool {
 	return bytes.Equal(a, b)
 }
+
+func TestCanonicalHashIncludesDAGEdges(t *testing.T) {
+	dagPlan := func(testNeeds []string) *planfmt.Plan {
+		return &planfmt.Plan{
+			Target: "deploy",
+			Steps: []planfmt.Step{
+				{
+					ID: 1,
+					Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+						{Name: "build", Block: []planfmt.Step{{ID: 2, Tree: &planfmt.CommandNode{
+							Decorator: "@shell",
+							Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "go build"}}},
+						}}}},
+						{Name: "test", Needs: testNeeds, Block: []planfmt.Step{{ID: 3, Tree: &planfmt.CommandNode{
+							Decorator: "@shell",
+							Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "go test"}}},
+						}}}},
+					}},
+				},
+			},
+		}
+	}
+
+	hash := func(plan *planfmt.Plan) [32]byte {
+		t.Helper()
+		canonical, err := plan.Canonicalize()
+		if err != nil {
+			t.Fatalf("canonicalization failed: %v", err)
+		}
+		h, err := canonical.Hash()
+		if err != nil {
+			t.Fatalf("hash failed: %v", err)
+		}
+		return h
+	}
+
+	sequential := hash(dagPlan([]string{"build"}))
+	parallel := hash(dagPlan(nil))
+	if sequential == parallel {
+		t.Errorf("Canonical hash identical despite different dependency edges\nPlan1: %x\nPlan2: %x", sequential, parallel)
+	}
+	if again := hash(dagPlan([]string{"build"})); again != sequential {
+		t.Errorf("Canonical hash not deterministic for DAG plan\nFirst: %x\nSecond: %x", sequential, again)
+	}
+}
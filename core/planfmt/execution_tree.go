@@ -139,3 +139,27 @@ type TryNode struct {
 }
 
 func (*TryNode) isExecutionNode() {}
+
+// DAGNode represents the dependencies of a function declared with `needs`.
+// Tasks are in topological order: every name in a task's Needs refers to an
+// earlier task. Shared dependencies appear once, and tasks whose dependencies
+// have completed may run concurrently.
+//
+// Example:
+//
+//	fun deploy needs build, test { kubectl apply }
+//
+// The plan contains a DAGNode with build and test (independent, run in
+// parallel) followed by the deploy body.
+type DAGNode struct {
+	Tasks []DAGTask
+}
+
+// DAGTask is a single function in a DAGNode.
+type DAGTask struct {
+	Name  string   // Function name
+	Needs []string // Names of tasks that must succeed first
+	Block []Step   // Expanded function body
+}
+
+func (*DAGNode) isExecutionNode() {}
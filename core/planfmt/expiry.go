@@ -0,0 +1,52 @@
+package planfmt
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultExpiryWarning is how far ahead of a secret's expiry tools warn.
+const DefaultExpiryWarning = 7 * 24 * time.Hour
+
+// ExpiringSecret is a secret in a plan whose version expires soon or has
+// already expired.
+type ExpiringSecret struct {
+	DisplayID string
+	Origin    string
+	Version   string
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the secret had expired at now.
+func (s ExpiringSecret) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// ExpiringSecrets returns the secrets in p that expire before now+within,
+// including expired ones, once per DisplayID and soonest first. Secrets
+// without a known expiry are never reported.
+func ExpiringSecrets(p *Plan, now time.Time, within time.Duration) []ExpiringSecret {
+	deadline := now.Add(within)
+	seen := make(map[string]bool)
+	var expiring []ExpiringSecret
+	for _, use := range p.SecretUses {
+		if use.ExpiresAt.IsZero() || !use.ExpiresAt.Before(deadline) || seen[use.DisplayID] {
+			continue
+		}
+		seen[use.DisplayID] = true
+		expiring = append(expiring, ExpiringSecret{
+			DisplayID: use.DisplayID,
+			Origin:    use.Origin,
+			Version:   use.Version,
+			ExpiresAt: use.ExpiresAt,
+		})
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		if !expiring[i].ExpiresAt.Equal(expiring[j].ExpiresAt) {
+			return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+		}
+		return expiring[i].DisplayID < expiring[j].DisplayID
+	})
+	return expiring
+}
@@ -0,0 +1,87 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpiringSecrets(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	plan := &planfmt.Plan{
+		SecretUses: []planfmt.SecretUse{
+			{DisplayID: "sigil:soon", Site: "a", Origin: "@secret.kv", Version: "4", ExpiresAt: now.Add(48 * time.Hour)},
+			{DisplayID: "sigil:soon", Site: "b", Origin: "@secret.kv", Version: "4", ExpiresAt: now.Add(48 * time.Hour)},
+			{DisplayID: "sigil:gone", Site: "c", Origin: `@secrets.get(path="db")`, ExpiresAt: now.Add(-time.Hour)},
+			{DisplayID: "sigil:later", Site: "d", Origin: "@secret.kv", ExpiresAt: now.Add(30 * 24 * time.Hour)},
+			{DisplayID: "sigil:never", Site: "e", Origin: "@env.TOKEN"},
+		},
+	}
+
+	got := planfmt.ExpiringSecrets(plan, now, planfmt.DefaultExpiryWarning)
+	want := []planfmt.ExpiringSecret{
+		{DisplayID: "sigil:gone", Origin: `@secrets.get(path="db")`, ExpiresAt: now.Add(-time.Hour)},
+		{DisplayID: "sigil:soon", Origin: "@secret.kv", Version: "4", ExpiresAt: now.Add(48 * time.Hour)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("expiring secrets mismatch (-want +got):\n%s", diff)
+	}
+	if !got[0].Expired(now) || got[1].Expired(now) {
+		t.Errorf("Expired = %v, %v; want true, false", got[0].Expired(now), got[1].Expired(now))
+	}
+}
+
+func TestSecretUseMetadataRoundTrip(t *testing.T) {
+	created := time.Date(2026, 10, 1, 8, 30, 0, 123, time.UTC)
+	expires := created.Add(90 * 24 * time.Hour)
+	plan := auditPlan()
+	plan.SecretUses[0].Version = "7"
+	plan.SecretUses[0].CreatedAt = created
+	plan.SecretUses[0].ExpiresAt = expires
+
+	var buf bytes.Buffer
+	if _, err := planfmt.Write(&buf, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, _, err := planfmt.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if diff := cmp.Diff(plan.SecretUses, got.SecretUses); diff != "" {
+		t.Errorf("secret uses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSecretUseMetadataClampsOutOfRangeTimes(t *testing.T) {
+	plan := auditPlan()
+	for i := range plan.SecretUses {
+		plan.SecretUses[i].CreatedAt = time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+		plan.SecretUses[i].ExpiresAt = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+	}
+
+	var buf bytes.Buffer
+	if _, err := planfmt.Write(&buf, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, _, err := planfmt.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	for _, use := range got.SecretUses {
+		if diff := cmp.Diff(time.Unix(0, math.MinInt64).UTC(), use.CreatedAt); diff != "" {
+			t.Errorf("%s CreatedAt mismatch (-want +got):\n%s", use.DisplayID, diff)
+		}
+		if diff := cmp.Diff(time.Unix(0, math.MaxInt64).UTC(), use.ExpiresAt); diff != "" {
+			t.Errorf("%s ExpiresAt mismatch (-want +got):\n%s", use.DisplayID, diff)
+		}
+	}
+	if expiring := planfmt.ExpiringSecrets(got, time.Now(), 30*24*time.Hour); len(expiring) != 0 {
+		t.Errorf("a far-future expiry should not be reported, got %v", expiring)
+	}
+}
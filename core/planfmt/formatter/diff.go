@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/builtwithtofu/sigil/core/planfmt"
@@ -9,10 +10,64 @@ import (
 
 // DiffResult represents the differences between two plans.
 type DiffResult struct {
-	TargetChanged string     // Non-empty if target changed (format: "old -> new")
-	Added         []StepDiff // Steps added in actual
-	Removed       []StepDiff // Steps removed from expected
-	Modified      []StepDiff // Steps that changed
+	TargetChanged string         // Non-empty if target changed (format: "old -> new")
+	Added         []StepDiff     // Steps added in actual
+	Removed       []StepDiff     // Steps removed from expected
+	Modified      []StepDiff     // Steps that changed
+	Secrets       []SecretChange // Secret sites whose value or source changed, sorted by Site
+}
+
+// SecretChangeKind classifies why a secret at a site differs between plans.
+type SecretChangeKind string
+
+const (
+	// SecretRotated means the same origin now reports a different version.
+	SecretRotated SecretChangeKind = "rotated"
+
+	// SecretValueChanged means the same origin returned a different value
+	// without reporting versions that explain it.
+	SecretValueChanged SecretChangeKind = "value changed"
+
+	// SecretSourceChanged means the value now comes from a different origin.
+	SecretSourceChanged SecretChangeKind = "source changed"
+)
+
+// SecretChange is a secret site present in both plans whose value, version
+// or origin differs.
+type SecretChange struct {
+	Site            string
+	Kind            SecretChangeKind
+	ExpectedOrigin  string
+	ActualOrigin    string
+	ExpectedVersion string
+	ActualVersion   string
+}
+
+// String formats the change, e.g. "rotated: @secret.kv version 3 -> 4".
+func (c SecretChange) String() string {
+	switch c.Kind {
+	case SecretRotated:
+		return fmt.Sprintf("%s: %s version %s -> %s", c.Kind, originOrUnknown(c.ActualOrigin),
+			versionOrUnknown(c.ExpectedVersion), versionOrUnknown(c.ActualVersion))
+	case SecretSourceChanged:
+		return fmt.Sprintf("%s: %s -> %s", c.Kind, originOrUnknown(c.ExpectedOrigin), originOrUnknown(c.ActualOrigin))
+	default:
+		return fmt.Sprintf("%s: %s", c.Kind, originOrUnknown(c.ActualOrigin))
+	}
+}
+
+func originOrUnknown(origin string) string {
+	if origin == "" {
+		return "<unknown origin>"
+	}
+	return origin
+}
+
+func versionOrUnknown(version string) string {
+	if version == "" {
+		return "?"
+	}
+	return version
 }
 
 // StepDiff represents a difference in a single step.
@@ -74,9 +129,52 @@ func Diff(expected, actual *planfmt.Plan) *DiffResult {
 		}
 	}
 
+	result.Secrets = diffSecrets(expected.SecretUses, actual.SecretUses)
+
 	return result
 }
 
+// diffSecrets compares the secrets used at sites present in both plans.
+// Sites only in one plan show up as step changes.
+func diffSecrets(expected, actual []planfmt.SecretUse) []SecretChange {
+	before := make(map[string]planfmt.SecretUse, len(expected))
+	for _, use := range expected {
+		before[use.Site] = use
+	}
+
+	var changes []SecretChange
+	seen := make(map[string]bool)
+	for _, use := range actual {
+		old, ok := before[use.Site]
+		if !ok || seen[use.Site] {
+			continue
+		}
+		if old.DisplayID == use.DisplayID && old.Origin == use.Origin && old.Version == use.Version {
+			continue
+		}
+		seen[use.Site] = true
+
+		change := SecretChange{
+			Site:            use.Site,
+			Kind:            SecretValueChanged,
+			ExpectedOrigin:  old.Origin,
+			ActualOrigin:    use.Origin,
+			ExpectedVersion: old.Version,
+			ActualVersion:   use.Version,
+		}
+		switch {
+		case old.Origin != use.Origin:
+			change.Kind = SecretSourceChanged
+		case old.Version != use.Version:
+			change.Kind = SecretRotated
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Site < changes[j].Site })
+	return changes
+}
+
 // FormatDiff returns a human-readable diff display.
 // Shows added, removed, and modified steps with optional color coding.
 func FormatDiff(result *DiffResult, useColor bool) string {
@@ -128,8 +226,21 @@ func FormatDiff(result *DiffResult, useColor bool) string {
 		fmt.Fprintln(&b)
 	}
 
+	// Secret changes
+	if len(result.Secrets) > 0 {
+		fmt.Fprintf(&b, "%sSecret changes:%s\n", yellow, reset)
+		for _, change := range result.Secrets {
+			color := yellow
+			if change.Kind == SecretSourceChanged {
+				color = red
+			}
+			fmt.Fprintf(&b, "  %s%s%s\n      at %s\n", color, change, reset, change.Site)
+		}
+		fmt.Fprintln(&b)
+	}
+
 	// Summary
-	if len(result.Modified) == 0 && len(result.Added) == 0 && len(result.Removed) == 0 && result.TargetChanged == "" {
+	if len(result.Modified) == 0 && len(result.Added) == 0 && len(result.Removed) == 0 && len(result.Secrets) == 0 && result.TargetChanged == "" {
 		fmt.Fprintln(&b, "No differences found.")
 	}
 
@@ -1,10 +1,12 @@
 package formatter_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/builtwithtofu/sigil/core/planfmt"
 	"github.com/builtwithtofu/sigil/core/planfmt/formatter"
+	"github.com/google/go-cmp/cmp"
 )
 
 // TestDiff verifies diff comparison logic
@@ -129,6 +131,53 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+// TestDiffSecretChanges verifies that secrets at the same site are
+// classified by what changed: version, origin, or only the value.
+func TestDiffSecretChanges(t *testing.T) {
+	site := func(n int) string { return fmt.Sprintf("root/step-%d/@shell[0]/params/command", n) }
+	expected := &planfmt.Plan{
+		SecretUses: []planfmt.SecretUse{
+			{DisplayID: "sigil:kv3", Site: site(1), Origin: "@secret.kv", Version: "3"},
+			{DisplayID: "sigil:env", Site: site(2), Origin: "@env.TOKEN"},
+			{DisplayID: "sigil:db", Site: site(3), Origin: `@secrets.get(path="db")`, Version: "1"},
+			{DisplayID: "sigil:same", Site: site(4), Origin: "@env.HOME"},
+			{DisplayID: "sigil:gone", Site: site(5), Origin: "@env.OLD"},
+		},
+	}
+	actual := &planfmt.Plan{
+		SecretUses: []planfmt.SecretUse{
+			{DisplayID: "sigil:kv4", Site: site(1), Origin: "@secret.kv", Version: "4"},
+			{DisplayID: "sigil:env2", Site: site(2), Origin: "@env.TOKEN"},
+			{DisplayID: "sigil:file", Site: site(3), Origin: "@secret.file"},
+			{DisplayID: "sigil:same", Site: site(4), Origin: "@env.HOME"},
+			{DisplayID: "sigil:new", Site: site(6), Origin: "@env.NEW"},
+		},
+	}
+
+	got := formatter.Diff(expected, actual).Secrets
+	want := []formatter.SecretChange{
+		{Site: site(1), Kind: formatter.SecretRotated, ExpectedOrigin: "@secret.kv", ActualOrigin: "@secret.kv", ExpectedVersion: "3", ActualVersion: "4"},
+		{Site: site(2), Kind: formatter.SecretValueChanged, ExpectedOrigin: "@env.TOKEN", ActualOrigin: "@env.TOKEN"},
+		{Site: site(3), Kind: formatter.SecretSourceChanged, ExpectedOrigin: `@secrets.get(path="db")`, ActualOrigin: "@secret.file", ExpectedVersion: "1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("secret changes mismatch (-want +got):\n%s", diff)
+	}
+
+	var lines []string
+	for _, change := range got {
+		lines = append(lines, change.String())
+	}
+	wantLines := []string{
+		"rotated: @secret.kv version 3 -> 4",
+		"value changed: @env.TOKEN",
+		`source changed: @secrets.get(path="db") -> @secret.file`,
+	}
+	if diff := cmp.Diff(wantLines, lines); diff != "" {
+		t.Errorf("formatted changes mismatch (-want +got):\n%s", diff)
+	}
+}
+
 // TestFormatDiff verifies diff display formatting
 func TestFormatDiff(t *testing.T) {
 	tests := []struct {
@@ -228,6 +277,26 @@ func TestFormatDiff(t *testing.T) {
     - deploy(prod)
     + deploy(prod, token=sigil:abc123)
 
+`,
+		},
+		{
+			name: "secret rotated",
+			expected: &planfmt.Plan{
+				Target: "deploy",
+				SecretUses: []planfmt.SecretUse{
+					{DisplayID: "sigil:old", Site: "root/step-1/@shell[0]/params/command", Origin: "@secret.kv", Version: "3"},
+				},
+			},
+			actual: &planfmt.Plan{
+				Target: "deploy",
+				SecretUses: []planfmt.SecretUse{
+					{DisplayID: "sigil:new", Site: "root/step-1/@shell[0]/params/command", Origin: "@secret.kv", Version: "4"},
+				},
+			},
+			want: `Secret changes:
+  rotated: @secret.kv version 3 -> 4
+      at root/step-1/@shell[0]/params/command
+
 `,
 		},
 	}
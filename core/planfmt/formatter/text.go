@@ -70,6 +70,8 @@ func formatExecutionNode(node planfmt.ExecutionNode) string {
 		return formatLogicNode(n)
 	case *planfmt.TryNode:
 		return formatTryNode(n)
+	case *planfmt.DAGNode:
+		return formatDAGNode(n)
 	default:
 		return fmt.Sprintf("(unknown: %T)", node)
 	}
@@ -100,6 +102,25 @@ func formatTryNode(try *planfmt.TryNode) string {
 	return strings.Join(parts, " ")
 }
 
+// formatDAGNode formats function dependencies, one task per entry with its edges
+func formatDAGNode(dag *planfmt.DAGNode) string {
+	var parts []string
+	parts = append(parts, "needs {")
+	for _, task := range dag.Tasks {
+		header := task.Name
+		if len(task.Needs) > 0 {
+			header += " after " + strings.Join(task.Needs, ", ")
+		}
+		parts = append(parts, "  "+header+" {")
+		for _, step := range task.Block {
+			parts = append(parts, "    "+formatExecutionNode(step.Tree))
+		}
+		parts = append(parts, "  }")
+	}
+	parts = append(parts, "}")
+	return strings.Join(parts, " ")
+}
+
 // formatCommandNode formats a single command node
 func formatCommandNode(cmd *planfmt.CommandNode) string {
 	// Special case: @shell with single "command" arg - show command directly
@@ -117,6 +117,20 @@ func TestFormatStep(t *testing.T) {
 			},
 			expected: `deploy(prod, token=sigil:abc123)`,
 		},
+		{
+			name: "function dependencies",
+			step: planfmt.Step{
+				ID: 1,
+				Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+					{Name: "build", Block: []planfmt.Step{{ID: 2, Tree: &planfmt.CommandNode{
+						Decorator: "@shell",
+						Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "go build"}}},
+					}}}},
+					{Name: "test", Needs: []string{"build"}},
+				}},
+			},
+			expected: `needs {   build {     @shell go build   }   test after build {   } }`,
+		},
 		{
 			name: "retry decorator",
 			step: planfmt.Step{
@@ -162,6 +162,9 @@ func canInlineIterationStep(step planfmt.Step) bool {
 	if _, ok := step.Tree.(*planfmt.TryNode); ok {
 		return false
 	}
+	if _, ok := step.Tree.(*planfmt.DAGNode); ok {
+		return false
+	}
 	return true
 }
 
@@ -181,6 +184,12 @@ func renderTreeStep(w io.Writer, step planfmt.Step, indent string, isLast, useCo
 		return
 	}
 
+	// Handle DAGNode specially to render each task and its edges
+	if dag, ok := step.Tree.(*planfmt.DAGNode); ok {
+		renderDAGBlock(w, dag, indent, isLast, useColor)
+		return
+	}
+
 	// Render the execution tree
 	treeStr := renderExecutionNode(step.Tree, useColor)
 	_, _ = fmt.Fprintf(w, "%s%s\n", prefix, treeStr)
@@ -240,6 +249,52 @@ func renderTryBlock(w io.Writer, try *planfmt.TryNode, indent string, isLast, us
 	}
 }
 
+// renderDAGBlock renders function dependencies as one branch per task,
+// annotating each task with the tasks it waits for.
+func renderDAGBlock(w io.Writer, dag *planfmt.DAGNode, indent string, isLast, useColor bool) {
+	var prefix string
+	if isLast {
+		prefix = indent + "└─ "
+	} else {
+		prefix = indent + "├─ "
+	}
+	_, _ = fmt.Fprintf(w, "%s%s\n", prefix, renderDAGNode(dag, useColor))
+
+	taskIndent := indent
+	if isLast {
+		taskIndent += "   "
+	} else {
+		taskIndent += "│  "
+	}
+
+	for i := range dag.Tasks {
+		task := &dag.Tasks[i]
+		lastTask := i == len(dag.Tasks)-1
+		taskPrefix := taskIndent + "├─ "
+		blockIndent := taskIndent + "│  "
+		if lastTask {
+			taskPrefix = taskIndent + "└─ "
+			blockIndent = taskIndent + "   "
+		}
+
+		label := task.Name
+		if len(task.Needs) > 0 {
+			label += Colorize(fmt.Sprintf(" (after %s)", strings.Join(task.Needs, ", ")), ColorGray, useColor)
+		}
+		_, _ = fmt.Fprintf(w, "%s%s\n", taskPrefix, label)
+		renderStepList(w, task.Block, blockIndent, useColor)
+	}
+}
+
+// renderDAGNode renders a dependency graph header for inline display
+func renderDAGNode(dag *planfmt.DAGNode, useColor bool) string {
+	names := make([]string, len(dag.Tasks))
+	for i := range dag.Tasks {
+		names[i] = dag.Tasks[i].Name
+	}
+	return Colorize("needs "+strings.Join(names, ", "), ColorYellow, useColor)
+}
+
 // renderExecutionNode renders an execution node to a string
 func renderExecutionNode(node planfmt.ExecutionNode, useColor bool) string {
 	switch n := node.(type) {
@@ -259,6 +314,8 @@ func renderExecutionNode(node planfmt.ExecutionNode, useColor bool) string {
 		return renderLogicNode(n)
 	case *planfmt.TryNode:
 		return renderTryNode(n, useColor)
+	case *planfmt.DAGNode:
+		return renderDAGNode(n, useColor)
 	default:
 		return fmt.Sprintf("(unknown node type: %T)", node)
 	}
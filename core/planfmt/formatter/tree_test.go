@@ -313,6 +313,45 @@ func TestFormatTree_ForLoopGrouped(t *testing.T) {
 	}
 }
 
+func TestFormatTree_DAG(t *testing.T) {
+	shell := func(id uint64, cmd string) planfmt.Step {
+		return planfmt.Step{ID: id, Tree: &planfmt.CommandNode{
+			Decorator: "@shell",
+			Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: cmd}}},
+		}}
+	}
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{
+				ID: 1,
+				Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+					{Name: "build", Block: []planfmt.Step{shell(2, "go build")}},
+					{Name: "lint", Block: []planfmt.Step{shell(3, "golangci-lint run")}},
+					{Name: "test", Needs: []string{"build", "lint"}, Block: []planfmt.Step{shell(4, "go test")}},
+				}},
+			},
+			shell(5, "kubectl apply"),
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatTree(&buf, plan, false)
+
+	expected := "deploy:\n" +
+		"├─ needs build, lint, test\n" +
+		"│  ├─ build\n" +
+		"│  │  └─ @shell go build\n" +
+		"│  ├─ lint\n" +
+		"│  │  └─ @shell golangci-lint run\n" +
+		"│  └─ test (after build, lint)\n" +
+		"│     └─ @shell go test\n" +
+		"└─ @shell kubectl apply\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("Output mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestFormatTree_WithColor(t *testing.T) {
 	plan := &planfmt.Plan{
 		Target: "test",
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/builtwithtofu/sigil/core/invariant"
 )
@@ -71,9 +72,13 @@ type Plan struct {
 // Each SecretUse grants permission for one decorator parameter to unwrap one secret.
 // Site-based authority: secrets accessible ONLY at declared sites, no propagation.
 type SecretUse struct {
-	DisplayID string // Secret identifier (e.g., "sigil:3J98t56A")
-	SiteID    string // Canonical site ID (HMAC-based, unforgeable)
-	Site      string // Human-readable path (e.g., "root/retry[0]/params/apiKey")
+	DisplayID string    // Secret identifier (e.g., "sigil:3J98t56A")
+	SiteID    string    // Canonical site ID (HMAC-based, unforgeable)
+	Site      string    // Human-readable path (e.g., "root/retry[0]/params/apiKey")
+	Origin    string    // Where the value came from (e.g., "@env.API_KEY"), never the value
+	Version   string    // Secret version reported by the origin (e.g., "3"); empty if unknown
+	CreatedAt time.Time // When that version was created; zero if unknown
+	ExpiresAt time.Time // When that version expires; zero if never or unknown
 }
 
 // Transport represents a transport context used in the plan.
@@ -292,6 +297,11 @@ func validateNode(node ExecutionNode, stepID uint64, seen map[uint64]bool) error
 			}
 		}
 
+	case *DAGNode:
+		if err := validateDAGNode(n, stepID, seen); err != nil {
+			return err
+		}
+
 	case *TryNode:
 		for i := range n.TryBlock {
 			if err := n.TryBlock[i].validate(seen); err != nil {
@@ -313,6 +323,33 @@ func validateNode(node ExecutionNode, stepID uint64, seen map[uint64]bool) error
 	return nil
 }
 
+// validateDAGNode checks that task names are unique and that every
+// dependency names an earlier task, which also rules out cycles.
+func validateDAGNode(n *DAGNode, stepID uint64, seen map[uint64]bool) error {
+	defined := make(map[string]bool, len(n.Tasks))
+	for i := range n.Tasks {
+		task := &n.Tasks[i]
+		if task.Name == "" {
+			return fmt.Errorf("step %d: dag task %d has no name", stepID, i)
+		}
+		if defined[task.Name] {
+			return fmt.Errorf("step %d: duplicate dag task %q", stepID, task.Name)
+		}
+		for _, need := range task.Needs {
+			if !defined[need] {
+				return fmt.Errorf("step %d: dag task %q needs %q, which is not an earlier task", stepID, task.Name, need)
+			}
+		}
+		defined[task.Name] = true
+		for j := range task.Block {
+			if err := task.Block[j].validate(seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // sortArgs sorts args in the execution tree
 func (s *Step) sortArgs() {
 	if s.Tree != nil {
@@ -362,6 +399,13 @@ func sortArgsInNode(node ExecutionNode) {
 			n.Block[i].sortArgs()
 		}
 
+	case *DAGNode:
+		for i := range n.Tasks {
+			for j := range n.Tasks[i].Block {
+				n.Tasks[i].Block[j].sortArgs()
+			}
+		}
+
 	case *TryNode:
 		for i := range n.TryBlock {
 			n.TryBlock[i].sortArgs()
@@ -87,6 +87,40 @@ func TestPlanValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "args not sorted",
 		},
+		{
+			name: "dag task needing a later task is invalid",
+			plan: &planfmt.Plan{
+				Target: "deploy",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+							{Name: "test", Needs: []string{"build"}},
+							{Name: "build"},
+						}},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `dag task "test" needs "build", which is not an earlier task`,
+		},
+		{
+			name: "duplicate dag tasks are invalid",
+			plan: &planfmt.Plan{
+				Target: "deploy",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+							{Name: "build"},
+							{Name: "build"},
+						}},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `duplicate dag task "build"`,
+		},
 	}
 
 	for _, tt := range tests {
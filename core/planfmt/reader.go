@@ -5,10 +5,23 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
 
 	"golang.org/x/crypto/blake2b"
 )
 
+// VersionError reports a plan or contract written in a format version this
+// build cannot read. Plans are not migrated between versions; they are
+// regenerated from source.
+type VersionError struct {
+	Got  uint16
+	Want uint16
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("plan format version %d is not supported (expected %d); replan from source to regenerate it", e.Got, e.Want)
+}
+
 // Read reads a plan from r and returns the plan and its hash.
 func Read(r io.Reader) (*Plan, [32]byte, error) {
 	rd := &Reader{r: r}
@@ -43,7 +56,7 @@ func (rd *Reader) ReadPlan() (*Plan, [32]byte, error) {
 	// Read version
 	version := binary.LittleEndian.Uint16(preamble[4:6])
 	if version != Version {
-		return nil, [32]byte{}, fmt.Errorf("unsupported version: got 0x%04x, expected 0x%04x", version, Version)
+		return nil, [32]byte{}, &VersionError{Got: version, Want: Version}
 	}
 
 	// Read flags
@@ -285,9 +298,40 @@ func (rd *Reader) readSecretUse(r io.Reader) (*SecretUse, error) {
 	}
 	use.Site = string(siteBytes)
 
+	origin, err := readString(r, "Origin")
+	if err != nil {
+		return nil, err
+	}
+	use.Origin = origin
+
+	version, err := readString(r, "Version")
+	if err != nil {
+		return nil, err
+	}
+	use.Version = version
+
+	if use.CreatedAt, err = readTime(r, "CreatedAt"); err != nil {
+		return nil, err
+	}
+	if use.ExpiresAt, err = readTime(r, "ExpiresAt"); err != nil {
+		return nil, err
+	}
+
 	return use, nil
 }
 
+// readTime reads int64 Unix nanoseconds; 0 is the zero time.
+func readTime(r io.Reader, field string) (time.Time, error) {
+	var nanos int64
+	if err := binary.Read(r, binary.LittleEndian, &nanos); err != nil {
+		return time.Time{}, fmt.Errorf("read %s: %w", field, err)
+	}
+	if nanos == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, nanos).UTC(), nil
+}
+
 // readTransport reads a single Transport entry.
 func (rd *Reader) readTransport(r io.Reader) (*Transport, error) {
 	transport := &Transport{}
@@ -541,11 +585,58 @@ func (rd *Reader) readExecutionNode(r io.Reader, depth, maxDepth int) (Execution
 			Block:     block,
 		}, nil
 
+	case 0x09: // DAGNode
+		var taskCount uint16
+		if err := binary.Read(r, binary.LittleEndian, &taskCount); err != nil {
+			return nil, fmt.Errorf("read dag task count: %w", err)
+		}
+		tasks := make([]DAGTask, taskCount)
+		for i := 0; i < int(taskCount); i++ {
+			task, err := rd.readDAGTask(r, depth+1, maxDepth)
+			if err != nil {
+				return nil, fmt.Errorf("read dag task %d: %w", i, err)
+			}
+			tasks[i] = *task
+		}
+		return &DAGNode{Tasks: tasks}, nil
+
 	default:
 		return nil, fmt.Errorf("unknown node type: 0x%02x", nodeType)
 	}
 }
 
+func (rd *Reader) readDAGTask(r io.Reader, depth, maxDepth int) (*DAGTask, error) {
+	name, err := readString(r, "dag task name")
+	if err != nil {
+		return nil, err
+	}
+	var needsCount uint16
+	if err := binary.Read(r, binary.LittleEndian, &needsCount); err != nil {
+		return nil, fmt.Errorf("read dag task needs count: %w", err)
+	}
+	var needs []string
+	for i := 0; i < int(needsCount); i++ {
+		need, err := readString(r, "dag task need")
+		if err != nil {
+			return nil, err
+		}
+		needs = append(needs, need)
+	}
+	var blockCount uint16
+	if err := binary.Read(r, binary.LittleEndian, &blockCount); err != nil {
+		return nil, fmt.Errorf("read dag task block count: %w", err)
+	}
+	block := make([]Step, blockCount)
+	for i := 0; i < int(blockCount); i++ {
+		step, err := rd.readStep(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read dag task block step %d: %w", i, err)
+		}
+		block[i] = *step
+	}
+	return &DAGTask{Name: name, Needs: needs, Block: block}, nil
+}
+
 func readString(r io.Reader, fieldName string) (string, error) {
 	var valueLen uint16
 	if err := binary.Read(r, binary.LittleEndian, &valueLen); err != nil {
@@ -796,7 +887,7 @@ func ReadContract(r io.Reader) (target string, planHash [32]byte, plan *Plan, er
 		return "", [32]byte{}, nil, fmt.Errorf("failed to read version: %w", err)
 	}
 	if version != Version {
-		return "", [32]byte{}, nil, fmt.Errorf("unsupported version: %d (expected %d)", version, Version)
+		return "", [32]byte{}, nil, &VersionError{Got: version, Want: Version}
 	}
 
 	// Read and verify type byte
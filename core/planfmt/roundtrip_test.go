@@ -2,6 +2,7 @@ package planfmt_test
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/builtwithtofu/sigil/core/planfmt"
@@ -219,3 +220,38 @@ func TestRoundTripPreservesSemantics(t *testing.T) {
 		t.Errorf("Semantic mismatch after round-trip (-original +decoded):\n%s", diff)
 	}
 }
+
+// TestReadRejectsOtherVersion verifies plans and contracts from another format
+// version fail with a VersionError instead of being misread
+func TestReadRejectsOtherVersion(t *testing.T) {
+	plan := &planfmt.Plan{Target: "deploy", PlanSalt: make([]byte, 32)}
+
+	var planBuf, contractBuf bytes.Buffer
+	if _, err := planfmt.Write(&planBuf, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := planfmt.WriteContract(&contractBuf, "deploy", [32]byte{}, plan); err != nil {
+		t.Fatalf("WriteContract failed: %v", err)
+	}
+
+	for name, data := range map[string][]byte{"plan": planBuf.Bytes(), "contract": contractBuf.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			data[4], data[5] = 0x01, 0x00 // version 1, before DAG nodes and secret origins
+
+			var err error
+			if name == "plan" {
+				_, _, err = planfmt.Read(bytes.NewReader(data))
+			} else {
+				_, _, _, err = planfmt.ReadContract(bytes.NewReader(data))
+			}
+
+			var versionErr *planfmt.VersionError
+			if !errors.As(err, &versionErr) {
+				t.Fatalf("expected VersionError, got %v", err)
+			}
+			if diff := cmp.Diff(&planfmt.VersionError{Got: 1, Want: planfmt.Version}, versionErr); diff != "" {
+				t.Errorf("VersionError mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -222,6 +222,27 @@ func TestTreeRoundTrip(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "plan with dag node tree",
+			plan: &planfmt.Plan{
+				Target: "deploy",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+							{Name: "build", Block: []planfmt.Step{{ID: 2, Tree: &planfmt.CommandNode{
+								Decorator: "@shell",
+								Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "go build"}}},
+							}}}},
+							{Name: "test", Needs: []string{"build"}, Block: []planfmt.Step{{ID: 3, Tree: &planfmt.CommandNode{
+								Decorator: "@shell",
+								Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "go test"}}},
+							}}}},
+						}},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
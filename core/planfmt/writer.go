@@ -7,6 +7,7 @@ import (
 	"io"
 	"math"
 	"sort"
+	"time"
 
 	"golang.org/x/crypto/blake2b"
 
@@ -20,8 +21,11 @@ const (
 	// Version is the format version (uint16, little-endian)
 	// Version scheme: major.minor encoded as single uint16
 	// 0x0001 = version 1.0
+	// 0x0002 = DAG nodes (0x09) and SecretUse.Origin, Version, CreatedAt
+	//          and ExpiresAt added (0x0001 files are rejected with a
+	//          VersionError)
 	// Breaking changes increment major, additions increment minor
-	Version uint16 = 0x0001
+	Version uint16 = 0x0002
 )
 
 // Flags is a bitmask for optional features
@@ -282,9 +286,42 @@ func (wr *Writer) writeSecretUse(buf *bytes.Buffer, use *SecretUse) error {
 		return err
 	}
 
-	return nil
+	if err := writeString(buf, use.Origin, "Origin length"); err != nil {
+		return err
+	}
+	if err := writeString(buf, use.Version, "Version length"); err != nil {
+		return err
+	}
+	if err := writeTime(buf, use.CreatedAt); err != nil {
+		return err
+	}
+	return writeTime(buf, use.ExpiresAt)
+}
+
+// writeTime writes t as int64 Unix nanoseconds, 0 for the zero time.
+// Times outside the int64 range (before 1678 or after 2262) are clamped to
+// its ends, so "never" sentinels such as 9999-12-31 still encode as the
+// latest time.
+func writeTime(buf *bytes.Buffer, t time.Time) error {
+	var nanos int64
+	switch {
+	case t.IsZero():
+	case t.Before(minPlanTime):
+		nanos = math.MinInt64
+	case t.After(maxPlanTime):
+		nanos = math.MaxInt64
+	default:
+		nanos = t.UnixNano()
+	}
+	return binary.Write(buf, binary.LittleEndian, nanos)
 }
 
+// minPlanTime and maxPlanTime bound the times writeTime can encode.
+var (
+	minPlanTime = time.Unix(0, math.MinInt64)
+	maxPlanTime = time.Unix(0, math.MaxInt64)
+)
+
 // writeTransport writes a single Transport entry.
 func (wr *Writer) writeTransport(buf *bytes.Buffer, transport *Transport) error {
 	if err := writeString(buf, transport.ID, "transport ID length"); err != nil {
@@ -337,6 +374,7 @@ const (
 	nodeTypeTry      = 0x06
 	nodeTypeRedirect = 0x07
 	nodeTypeLogic    = 0x08
+	nodeTypeDAG      = 0x09
 )
 
 // writeExecutionNode writes an execution tree node recursively
@@ -507,6 +545,22 @@ func (wr *Writer) writeExecutionNode(buf *bytes.Buffer, node ExecutionNode) erro
 			}
 		}
 
+	case *DAGNode:
+		if err := buf.WriteByte(nodeTypeDAG); err != nil {
+			return err
+		}
+		if err := validateUint16(len(n.Tasks), "dag task count"); err != nil {
+			return err
+		}
+		if err := binary.Write(buf, binary.LittleEndian, uint16(len(n.Tasks))); err != nil {
+			return err
+		}
+		for i := range n.Tasks {
+			if err := wr.writeDAGTask(buf, &n.Tasks[i]); err != nil {
+				return err
+			}
+		}
+
 	default:
 		return io.ErrUnexpectedEOF // Unknown node type
 	}
@@ -514,6 +568,36 @@ func (wr *Writer) writeExecutionNode(buf *bytes.Buffer, node ExecutionNode) erro
 	return nil
 }
 
+// writeDAGTask writes a DAG task: name, dependency names, then block steps
+func (wr *Writer) writeDAGTask(buf *bytes.Buffer, task *DAGTask) error {
+	if err := writeString(buf, task.Name, "dag task name length"); err != nil {
+		return err
+	}
+	if err := validateUint16(len(task.Needs), "dag task needs count"); err != nil {
+		return err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(task.Needs))); err != nil {
+		return err
+	}
+	for _, need := range task.Needs {
+		if err := writeString(buf, need, "dag task need length"); err != nil {
+			return err
+		}
+	}
+	if err := validateUint16(len(task.Block), "dag task block step count"); err != nil {
+		return err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(task.Block))); err != nil {
+		return err
+	}
+	for i := range task.Block {
+		if err := wr.writeStep(buf, &task.Block[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // writeCommand writes a single command
 func (wr *Writer) writeCommand(buf *bytes.Buffer, cmd *CommandNode) error {
 	// Write decorator (2-byte length + string)
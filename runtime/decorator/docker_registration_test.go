@@ -0,0 +1,34 @@
+package decorator_test
+
+import (
+	"testing"
+
+	coredecorator "github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDockerTransportRegistration(t *testing.T) {
+	entry, ok := coredecorator.Global().Lookup("docker.exec")
+	if !ok {
+		t.Fatalf("docker.exec transport not found in registry")
+	}
+
+	transport, typeOK, reason := coredecorator.Global().GetTransport("docker.exec")
+	if typeOK {
+		_, typeOK = transport.(*coredecorator.DockerTransport)
+	} else if reason != "" {
+		t.Fatalf("unexpected transport lookup reason: %s", reason)
+	}
+	if diff := cmp.Diff(true, typeOK); diff != "" {
+		t.Fatalf("registered docker.exec transport type mismatch (-want +got):\n%s", diff)
+	}
+
+	descriptor := entry.Impl.Descriptor()
+	if diff := cmp.Diff("docker.exec", descriptor.Path); diff != "" {
+		t.Fatalf("descriptor path mismatch (-want +got):\n%s", diff)
+	}
+	container, ok := descriptor.Schema.Parameters["container"]
+	if !ok || !container.Required {
+		t.Fatalf("container parameter must be required, got %+v", container)
+	}
+}
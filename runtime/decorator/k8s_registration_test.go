@@ -0,0 +1,35 @@
+package decorator_test
+
+import (
+	"testing"
+
+	coredecorator "github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestK8sTransportRegistration(t *testing.T) {
+	entry, ok := coredecorator.Global().Lookup("k8s.exec")
+	if !ok {
+		t.Fatalf("k8s.exec transport not found in registry")
+	}
+
+	transport, typeOK, reason := coredecorator.Global().GetTransport("k8s.exec")
+	if typeOK {
+		_, typeOK = transport.(*coredecorator.K8sTransport)
+	} else if reason != "" {
+		t.Fatalf("unexpected transport lookup reason: %s", reason)
+	}
+	if diff := cmp.Diff(true, typeOK); diff != "" {
+		t.Fatalf("registered k8s.exec transport type mismatch (-want +got):\n%s", diff)
+	}
+
+	descriptor := entry.Impl.Descriptor()
+	if diff := cmp.Diff("k8s.exec", descriptor.Path); diff != "" {
+		t.Fatalf("descriptor path mismatch (-want +got):\n%s", diff)
+	}
+	for _, name := range []string{"pod", "selector", "namespace", "container", "context"} {
+		if _, ok := descriptor.Schema.Parameters[name]; !ok {
+			t.Errorf("missing %s parameter", name)
+		}
+	}
+}
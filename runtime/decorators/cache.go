@@ -0,0 +1,542 @@
+package decorators
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/invariant"
+	"github.com/builtwithtofu/sigil/core/types"
+)
+
+// cacheDirName is the project-local directory holding cache manifests.
+const cacheDirName = ".sigil/cache"
+
+// CacheDecorator implements the @exec.cache execution decorator.
+// Skips its block when a previous run with identical inputs left matching outputs.
+//
+// At plan time the decorator pins _cache_digest, a digest of the user-supplied
+// key, the input and output patterns and the environment variable names, so
+// the plan shows which cache entries the block may be skipped for. At run
+// time the entry key adds the planned block (rendered commands and decorator
+// arguments), the content of every input file and the selected environment
+// values. Entries live in a content-addressed store under .sigil/cache
+// relative to the session's working directory.
+type CacheDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *CacheDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("exec.cache").
+		Summary("Skip block when inputs are unchanged and outputs are present").
+		Roles(decorator.RoleWrapper).
+		ParamArray("inputs", "Glob patterns for files the block reads").
+		ElementType(types.TypeString).
+		Examples(`["src/**/*.go", "go.mod"]`).
+		Done().
+		ParamArray("outputs", "Glob patterns for files the block produces").
+		ElementType(types.TypeString).
+		Examples(`["bin/app"]`).
+		Done().
+		ParamString("key", "Extra value mixed into the cache key").
+		Examples("build-v2").
+		Done().
+		ParamArray("env", "Environment variable names that affect the block").
+		ElementType(types.TypeString).
+		Examples(`["GOOS", "GOARCH"]`).
+		Done().
+		ParamString(cacheDigestParam, "Digest of key, inputs, outputs and env names (set by the planner, not by scripts)").
+		Done().
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+// cacheDigestParam is the param the planner pins the plan-time digest to.
+// Scripts cannot set it, so the digest in a plan always describes the
+// block's own params.
+const cacheDigestParam = "_cache_digest"
+
+// PinParams implements decorator.ParamPinner: it records the cache digest in
+// the plan.
+func (d *CacheDecorator) PinParams(params map[string]any) (map[string]any, error) {
+	if _, ok := params[cacheDigestParam]; ok {
+		return nil, fmt.Errorf("%s is set by the planner and cannot be passed to @exec.cache", cacheDigestParam)
+	}
+	cfg, err := decodeCacheConfig(params)
+	if err != nil {
+		return nil, err
+	}
+	key, err := cfg.planKey()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{cacheDigestParam: key}, nil
+}
+
+// Wrap implements the Exec interface.
+func (d *CacheDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &cacheNode{next: next, params: params}
+}
+
+type cacheDisabledKey struct{}
+
+// WithCacheDisabled returns a context that forces @exec.cache blocks to execute.
+// Successful runs still refresh the cache entry.
+func WithCacheDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheDisabledKey{}, true)
+}
+
+func cacheDisabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	disabled, _ := ctx.Value(cacheDisabledKey{}).(bool)
+	return disabled
+}
+
+type cacheNode struct {
+	next   decorator.ExecNode
+	params map[string]any
+}
+
+type cacheConfig struct {
+	Inputs  []any  `decorator:"inputs"`
+	Outputs []any  `decorator:"outputs"`
+	Key     string `decorator:"key"`
+	Env     []any  `decorator:"env"`
+	Digest  string `decorator:"_cache_digest"`
+}
+
+func decodeCacheConfig(params map[string]any) (cacheConfig, error) {
+	cfg, _, err := decorator.DecodeInto[cacheConfig](
+		(&CacheDecorator{}).Descriptor().Schema,
+		nil,
+		params,
+	)
+	return cfg, err
+}
+
+// planKey derives the cache key shown in the plan from the params alone.
+func (c cacheConfig) planKey() (string, error) {
+	inputs, err := stringList("inputs", c.Inputs)
+	if err != nil {
+		return "", err
+	}
+	outputs, err := stringList("outputs", c.Outputs)
+	if err != nil {
+		return "", err
+	}
+	envNames, err := stringList("env", c.Env)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, "sigil-cache-v2\x00")
+	_, _ = fmt.Fprintf(h, "key:%s\x00", c.Key)
+	for _, group := range []struct {
+		kind   string
+		values []string
+	}{{"in", inputs}, {"out", outputs}, {"env", envNames}} {
+		values := append([]string(nil), group.values...)
+		sort.Strings(values)
+		for _, value := range values {
+			_, _ = fmt.Fprintf(h, "%s:%s\x00", group.kind, value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheManifest is the on-disk record of a successful cached run.
+type cacheManifest struct {
+	Key       string            `json:"key"`
+	Outputs   map[string]string `json:"outputs"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Execute implements the ExecNode interface.
+func (n *cacheNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	if n.next == nil {
+		return decorator.Result{ExitCode: 0}, nil
+	}
+
+	invariant.NotNil(ctx.Session, "ctx.Session")
+
+	cfg, err := decodeCacheConfig(n.params)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+
+	if scope := ctx.Session.TransportScope(); scope != decorator.TransportScopeLocal {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.cache requires a local session, got %s", scope)
+	}
+
+	inputs, err := stringList("inputs", cfg.Inputs)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	outputs, err := stringList("outputs", cfg.Outputs)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	envNames, err := stringList("env", cfg.Env)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+
+	root := ctx.Session.Cwd()
+	fingerprint := ""
+	if fp, ok := n.next.(decorator.BlockFingerprinter); ok {
+		fingerprint = fp.BlockFingerprint()
+	}
+
+	planKey := cfg.Digest
+	if planKey == "" {
+		// Plans built without the planner's pinning, e.g. by embedders
+		if planKey, err = cfg.planKey(); err != nil {
+			return decorator.Result{ExitCode: decorator.ExitFailure}, err
+		}
+	}
+
+	key, err := computeCacheKey(root, planKey, fingerprint, inputs, outputs, envNames, ctx.Session.Env())
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.cache: %w", err)
+	}
+
+	store := cacheStore{dir: filepath.Join(root, cacheDirName)}
+	if !cacheDisabled(ctx.Context) {
+		if manifest, ok := store.load(key); ok && outputsMatch(root, manifest.Outputs) {
+			writeNotice(ctx.Stderr, "@exec.cache: hit %s (entry %s), skipping block\n", shortKey(planKey), shortKey(key))
+			return decorator.Result{ExitCode: 0}, nil
+		}
+	}
+
+	result, execErr := n.next.Execute(ctx)
+	if execErr != nil || result.ExitCode != 0 {
+		return result, execErr
+	}
+
+	outputHashes, err := hashMatches(root, outputs)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.cache: %w", err)
+	}
+	for _, pattern := range outputs {
+		if !patternMatchedAny(pattern, outputHashes) {
+			return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.cache: output %q was not produced", pattern)
+		}
+	}
+
+	manifest := cacheManifest{Key: key, Outputs: outputHashes, CreatedAt: time.Now().UTC()}
+	if err := store.save(manifest); err != nil {
+		writeNotice(ctx.Stderr, "Warning: @exec.cache: failed to store entry: %v\n", err)
+	}
+
+	return result, nil
+}
+
+// computeCacheKey derives the content-addressed entry key for a cached block
+// from its plan key and what the block reads at run time.
+func computeCacheKey(root, planKey, fingerprint string, inputs, outputs, envNames []string, env map[string]string) (string, error) {
+	inputHashes, err := hashMatches(root, inputs)
+	if err != nil {
+		return "", err
+	}
+	for _, pattern := range inputs {
+		if !patternMatchedAny(pattern, inputHashes) {
+			return "", fmt.Errorf("input %q matched no files", pattern)
+		}
+	}
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, "sigil-cache-v2\x00")
+	_, _ = fmt.Fprintf(h, "plan:%s\x00block:%s\x00", planKey, fingerprint)
+
+	for _, rel := range sortedKeys(inputHashes) {
+		// Outputs overlapping an input glob would otherwise invalidate the entry they produce.
+		if matchesAnyPattern(outputs, rel) {
+			continue
+		}
+		_, _ = fmt.Fprintf(h, "in:%s=%s\x00", rel, inputHashes[rel])
+	}
+
+	names := append([]string(nil), envNames...)
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := env[name]
+		_, _ = fmt.Fprintf(h, "env:%s=%t:%s\x00", name, ok, value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashMatches expands glob patterns relative to root and hashes every matching
+// regular file. Keys are slash-separated paths relative to root. The walk only
+// enters directories a pattern can match below, so "src/**/*.go" reads src
+// and not node_modules or build output.
+func hashMatches(root string, patterns []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	if len(patterns) == 0 {
+		return hashes, nil
+	}
+
+	for _, pattern := range patterns {
+		if path.IsAbs(filepath.ToSlash(pattern)) {
+			return nil, fmt.Errorf("pattern %q must be relative to the working directory", pattern)
+		}
+		if _, err := path.Match(globSegmentsOnly(pattern), ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if rel == "." {
+				return nil
+			}
+			if rel == ".sigil" || rel == ".git" || !anyPatternBelow(patterns, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if !matchesAnyPattern(patterns, rel) {
+			return nil
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		hashes[rel] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// anyPatternBelow reports whether a pattern can match a file under dir.
+func anyPatternBelow(patterns []string, dir string) bool {
+	segments := strings.Split(dir, "/")
+	for _, pattern := range patterns {
+		if patternBelow(strings.Split(filepath.ToSlash(pattern), "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func patternBelow(pattern, dir []string) bool {
+	for i, segment := range dir {
+		if i < len(pattern) && pattern[i] == "**" {
+			return true
+		}
+		// The last pattern segment names files, not directories
+		if i >= len(pattern)-1 {
+			return false
+		}
+		if ok, err := path.Match(pattern[i], segment); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGlob matches a slash-separated path against a pattern where "**"
+// matches zero or more path segments and other segments use path.Match rules.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// globSegmentsOnly strips "**" segments so the remainder can be syntax-checked by path.Match.
+func globSegmentsOnly(pattern string) string {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment != "**" {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+func matchesAnyPattern(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(filepath.ToSlash(pattern), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func patternMatchedAny(pattern string, hashes map[string]string) bool {
+	for rel := range hashes {
+		if matchGlob(filepath.ToSlash(pattern), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func outputsMatch(root string, outputs map[string]string) bool {
+	for rel, want := range outputs {
+		got, err := hashFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheStore is a content-addressed manifest store: <dir>/<key[:2]>/<key>.json.
+type cacheStore struct {
+	dir string
+}
+
+func (s cacheStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key+".json")
+}
+
+func (s cacheStore) load(key string) (cacheManifest, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return cacheManifest{}, false
+	}
+	var manifest cacheManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Key != key {
+		return cacheManifest{}, false
+	}
+	return manifest, true
+}
+
+// save writes the manifest atomically so concurrent readers never see partial entries.
+func (s cacheStore) save(manifest cacheManifest) error {
+	target := s.path(manifest.Key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".entry-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+func stringList(name string, values []any) ([]string, error) {
+	out := make([]string, 0, len(values))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("@exec.cache %s[%d] must be a string, got %T", name, i, value)
+		}
+		if str == "" {
+			return nil, fmt.Errorf("@exec.cache %s[%d] must not be empty", name, i)
+		}
+		out = append(out, str)
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func shortKey(key string) string {
+	if len(key) > 12 {
+		return key[:12]
+	}
+	return key
+}
+
+func writeNotice(w io.Writer, format string, args ...any) {
+	if w == nil {
+		w = os.Stderr
+	}
+	_, _ = fmt.Fprintf(w, format, args...)
+}
+
+func init() {
+	err := decorator.Register("exec.cache", &CacheDecorator{})
+	invariant.Check(err == nil, "failed to register @exec.cache decorator: %v", err)
+}
@@ -0,0 +1,283 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+type fingerprintExecNode struct {
+	testExecNode
+	fingerprint string
+}
+
+func (n *fingerprintExecNode) BlockFingerprint() string {
+	return n.fingerprint
+}
+
+// buildNode returns a block that writes out.txt from in.txt and counts runs.
+func buildNode(t *testing.T, dir string, runs *int) *fingerprintExecNode {
+	t.Helper()
+	return &fingerprintExecNode{
+		fingerprint: "cp in.txt out.txt",
+		testExecNode: testExecNode{execute: func(ctx decorator.ExecContext) (decorator.Result, error) {
+			*runs++
+			data, err := os.ReadFile(filepath.Join(dir, "in.txt"))
+			if err != nil {
+				return decorator.Result{ExitCode: 1}, err
+			}
+			if err := os.WriteFile(filepath.Join(dir, "out.txt"), data, 0o644); err != nil {
+				return decorator.Result{ExitCode: 1}, err
+			}
+			return decorator.Result{ExitCode: 0}, nil
+		}},
+	}
+}
+
+func cacheParams() map[string]any {
+	return map[string]any{
+		"inputs":  []any{"*.txt"},
+		"outputs": []any{"out.txt"},
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func runCache(t *testing.T, ctx context.Context, dir string, node decorator.ExecNode, params map[string]any) string {
+	t.Helper()
+	var stderr bytes.Buffer
+	wrapped := (&CacheDecorator{}).Wrap(node, params)
+	result, err := wrapped.Execute(decorator.ExecContext{
+		Context: ctx,
+		Session: &testWorkdirSession{cwd: dir},
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("exit code: got %d, want 0", result.ExitCode)
+	}
+	return stderr.String()
+}
+
+func TestCacheSkipsBlockWhenInputsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	runs := 0
+	node := buildNode(t, dir, &runs)
+
+	runCache(t, context.Background(), dir, node, cacheParams())
+	stderr := runCache(t, context.Background(), dir, node, cacheParams())
+
+	if diff := cmp.Diff(1, runs); diff != "" {
+		t.Errorf("runs mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr, "@exec.cache: hit") {
+		t.Errorf("expected hit notice, got %q", stderr)
+	}
+}
+
+func TestCacheRerunsWhenInputChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	runs := 0
+	node := buildNode(t, dir, &runs)
+
+	runCache(t, context.Background(), dir, node, cacheParams())
+	writeFile(t, filepath.Join(dir, "in.txt"), "v2")
+	runCache(t, context.Background(), dir, node, cacheParams())
+
+	if diff := cmp.Diff(2, runs); diff != "" {
+		t.Errorf("runs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheRerunsWhenOutputMissingOrModified(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	runs := 0
+	node := buildNode(t, dir, &runs)
+	params := map[string]any{
+		"inputs":  []any{"in.txt"},
+		"outputs": []any{"out.txt"},
+	}
+
+	runCache(t, context.Background(), dir, node, params)
+	if err := os.Remove(filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	runCache(t, context.Background(), dir, node, params)
+	writeFile(t, filepath.Join(dir, "out.txt"), "tampered")
+	runCache(t, context.Background(), dir, node, params)
+
+	if diff := cmp.Diff(3, runs); diff != "" {
+		t.Errorf("runs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheKeyIncludesBlockFingerprintAndUserKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	runs := 0
+	node := buildNode(t, dir, &runs)
+	params := map[string]any{"inputs": []any{"in.txt"}, "outputs": []any{"out.txt"}}
+
+	runCache(t, context.Background(), dir, node, params)
+	node.fingerprint = "cp in.txt out.txt && strip out.txt"
+	runCache(t, context.Background(), dir, node, params)
+	params["key"] = "v2"
+	runCache(t, context.Background(), dir, node, params)
+
+	if diff := cmp.Diff(3, runs); diff != "" {
+		t.Errorf("runs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheDisabledForcesExecution(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	runs := 0
+	node := buildNode(t, dir, &runs)
+
+	runCache(t, context.Background(), dir, node, cacheParams())
+	runCache(t, WithCacheDisabled(context.Background()), dir, node, cacheParams())
+
+	if diff := cmp.Diff(2, runs); diff != "" {
+		t.Errorf("runs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheDoesNotStoreFailedRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+	node := &testExecNode{execute: func(ctx decorator.ExecContext) (decorator.Result, error) {
+		return decorator.Result{ExitCode: 4}, nil
+	}}
+
+	wrapped := (&CacheDecorator{}).Wrap(node, map[string]any{"inputs": []any{"in.txt"}})
+	result, err := wrapped.Execute(decorator.ExecContext{
+		Context: context.Background(),
+		Session: &testWorkdirSession{cwd: dir},
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if diff := cmp.Diff(4, result.ExitCode); diff != "" {
+		t.Errorf("exit code mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, cacheDirName)); !os.IsNotExist(err) {
+		t.Errorf("expected no cache store after failure, stat err = %v", err)
+	}
+}
+
+func TestCacheInputPatternMustMatch(t *testing.T) {
+	dir := t.TempDir()
+	runs := 0
+	node := buildNode(t, dir, &runs)
+
+	wrapped := (&CacheDecorator{}).Wrap(node, map[string]any{"inputs": []any{"src/**/*.go"}})
+	_, err := wrapped.Execute(decorator.ExecContext{
+		Context: context.Background(),
+		Session: &testWorkdirSession{cwd: dir},
+	})
+	if err == nil || !strings.Contains(err.Error(), `input "src/**/*.go" matched no files`) {
+		t.Fatalf("expected unmatched input error, got %v", err)
+	}
+	if runs != 0 {
+		t.Errorf("block must not run when the cache key cannot be computed")
+	}
+}
+
+func TestCacheKeyTracksSelectedEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "in.txt"), "v1")
+
+	keyFor := func(env map[string]string) string {
+		key, err := computeCacheKey(dir, "", "fp", []string{"in.txt"}, nil, []string{"GOOS"}, env)
+		if err != nil {
+			t.Fatalf("computeCacheKey: %v", err)
+		}
+		return key
+	}
+
+	linux := keyFor(map[string]string{"GOOS": "linux", "HOME": "/a"})
+	if linux != keyFor(map[string]string{"GOOS": "linux", "HOME": "/b"}) {
+		t.Error("unselected env vars must not affect the key")
+	}
+	if linux == keyFor(map[string]string{"GOOS": "darwin"}) {
+		t.Error("selected env vars must affect the key")
+	}
+	if linux == keyFor(map[string]string{}) {
+		t.Error("unset and set env vars must produce different keys")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/sub/main.go", true},
+		{"src/**", "src/a/b.txt", true},
+		{"src/**/*.go", "lib/a.go", false},
+		{"go.mod", "go.mod", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnyPatternBelow(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		dir      string
+		want     bool
+	}{
+		{[]string{"src/*.go"}, "src", true},
+		{[]string{"src/*.go"}, "node_modules", false},
+		{[]string{"src/*.go"}, "src/sub", false},
+		{[]string{"*.go"}, "vendor", false},
+		{[]string{"src/**/*.go"}, "src/a/b", true},
+		{[]string{"src/**/*.go"}, "build", false},
+		{[]string{"**/*.go"}, "node_modules", true},
+		{[]string{"go.mod", "cmd/*/main.go"}, "cmd/app", true},
+	}
+
+	for _, tt := range tests {
+		if got := anyPatternBelow(tt.patterns, tt.dir); got != tt.want {
+			t.Errorf("anyPatternBelow(%q, %q) = %v, want %v", tt.patterns, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCachePinParamsRejectsScriptDigest(t *testing.T) {
+	params := cacheParams()
+	params[cacheDigestParam] = "0000"
+
+	_, err := (&CacheDecorator{}).PinParams(params)
+	if err == nil || !strings.Contains(err.Error(), "set by the planner") {
+		t.Fatalf("expected planner-only digest error, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package decorators
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/invariant"
+)
+
+// ConfirmDecorator implements the @exec.confirm execution decorator.
+// Pauses before its block and asks the operator for approval.
+//
+// The prompt is written to the controlling terminal (/dev/tty), not to stdout
+// or stderr, so it bypasses output lockdown and is never captured by pipes or
+// redirects. The message and name are therefore scrubbed through the
+// execution context before they are shown or recorded. Without a terminal the
+// gate fails closed unless the run carries an approval policy (--yes or an
+// allowlisted gate name).
+type ConfirmDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *ConfirmDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("exec.confirm").
+		Summary("Ask the operator for approval before running the block").
+		Roles(decorator.RoleWrapper).
+		ParamString("message", "Question shown to the operator").
+		Default("Continue?").
+		Examples("Deploy to production?").
+		Done().
+		ParamString("name", "Gate name used by approval allowlists (defaults to message)").
+		Examples("prod-deploy").
+		Done().
+		ParamDuration("timeout", "How long to wait for an answer before denying (0 waits forever)").
+		Default("0s").
+		Examples("30s", "5m").
+		Done().
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+func (d *ConfirmDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &confirmNode{next: next, params: params}
+}
+
+// ConfirmPolicy controls non-interactive approval of @exec.confirm gates.
+type ConfirmPolicy struct {
+	AssumeYes bool     // Approve every gate without prompting (--yes)
+	Allow     []string // Gate name patterns approved without prompting (path.Match syntax)
+}
+
+type confirmPolicyKey struct{}
+
+// WithConfirmPolicy returns a context carrying the approval policy for @exec.confirm.
+func WithConfirmPolicy(ctx context.Context, policy ConfirmPolicy) context.Context {
+	return context.WithValue(ctx, confirmPolicyKey{}, policy)
+}
+
+func confirmPolicyFrom(ctx context.Context) ConfirmPolicy {
+	if ctx == nil {
+		return ConfirmPolicy{}
+	}
+	policy, _ := ctx.Value(confirmPolicyKey{}).(ConfirmPolicy)
+	return policy
+}
+
+// openControllingTTY opens the operator's terminal. Overridden in tests.
+var openControllingTTY = func() (io.ReadWriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// errNotApproved marks a gate the operator declined or let time out.
+var errNotApproved = errors.New("not approved")
+
+type confirmNode struct {
+	next   decorator.ExecNode
+	params map[string]any
+}
+
+type confirmConfig struct {
+	Message string        `decorator:"message"`
+	Name    string        `decorator:"name"`
+	Timeout time.Duration `decorator:"timeout"`
+}
+
+// Confirmation outcomes and how they were reached, as recorded in events.
+const (
+	confirmApproved = "approved"
+	confirmDenied   = "denied"
+
+	confirmViaAssumeYes = "assume-yes"
+	confirmViaAllowlist = "allowlist"
+	confirmViaTTY       = "tty"
+	confirmViaTimeout   = "timeout"
+	confirmViaNoTTY     = "no-tty"
+)
+
+// Execute implements the ExecNode interface.
+func (n *confirmNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	if n.next == nil {
+		return decorator.Result{ExitCode: 0}, nil
+	}
+
+	cfg, _, err := decorator.DecodeInto[confirmConfig](
+		(&ConfirmDecorator{}).Descriptor().Schema,
+		nil,
+		n.params,
+	)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if cfg.Timeout < 0 {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.confirm timeout must be >= 0")
+	}
+
+	message := ctx.Redacted(cfg.Message)
+	name := message
+	if cfg.Name != "" {
+		name = ctx.Redacted(cfg.Name)
+	}
+
+	outcome, via, err := confirmDecision(ctx.Context, confirmPolicyFrom(ctx.Context), name, message, cfg.Timeout)
+	ctx.Emit(decorator.Event{
+		Decorator: "exec.confirm",
+		Kind:      "confirm",
+		Attrs: map[string]string{
+			"name":    name,
+			"outcome": outcome,
+			"via":     via,
+		},
+	})
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return decorator.Result{ExitCode: decorator.ExitCanceled}, err
+		}
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+
+	return n.next.Execute(ctx)
+}
+
+// confirmDecision resolves a gate to an outcome. A nil error means approved.
+func confirmDecision(ctx context.Context, policy ConfirmPolicy, name, message string, timeout time.Duration) (string, string, error) {
+	if policy.AssumeYes {
+		return confirmApproved, confirmViaAssumeYes, nil
+	}
+	for _, pattern := range policy.Allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return confirmApproved, confirmViaAllowlist, nil
+		}
+	}
+
+	tty, err := openControllingTTY()
+	if err != nil {
+		return confirmDenied, confirmViaNoTTY, fmt.Errorf(
+			"@exec.confirm %q requires an interactive terminal (%v); rerun with --yes or --approve=%q",
+			name, err, name)
+	}
+	defer func() { _ = tty.Close() }()
+
+	answer, err := confirmPrompt(ctx, tty, message, timeout)
+	switch {
+	case err == nil && isAffirmative(answer):
+		return confirmApproved, confirmViaTTY, nil
+	case err == nil:
+		return confirmDenied, confirmViaTTY, fmt.Errorf("@exec.confirm %q: %w", name, errNotApproved)
+	case errors.Is(err, errPromptTimeout):
+		_, _ = fmt.Fprintln(tty)
+		return confirmDenied, confirmViaTimeout, fmt.Errorf("@exec.confirm %q: no answer within %s: %w", name, timeout, errNotApproved)
+	default:
+		return confirmDenied, confirmViaTTY, err
+	}
+}
+
+var errPromptTimeout = errors.New("prompt timed out")
+
+// confirmPrompt writes the question and waits for one line, a timeout, or cancellation.
+func confirmPrompt(ctx context.Context, tty io.ReadWriter, message string, timeout time.Duration) (string, error) {
+	invariant.NotNil(tty, "tty")
+
+	if _, err := fmt.Fprintf(tty, "%s [y/N]: ", message); err != nil {
+		return "", fmt.Errorf("@exec.confirm: write prompt: %w", err)
+	}
+
+	type line struct {
+		text string
+		err  error
+	}
+	answers := make(chan line, 1)
+	go func() {
+		text, err := bufio.NewReader(tty).ReadString('\n')
+		if err == io.EOF && text != "" {
+			err = nil
+		}
+		answers <- line{text: text, err: err}
+	}()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case answer := <-answers:
+		if answer.err != nil {
+			return "", fmt.Errorf("@exec.confirm: read answer: %w", answer.err)
+		}
+		return answer.text, nil
+	case <-expired:
+		return "", errPromptTimeout
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func isAffirmative(answer string) bool {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	err := decorator.Register("exec.confirm", &ConfirmDecorator{})
+	invariant.Check(err == nil, "failed to register @exec.confirm decorator: %v", err)
+}
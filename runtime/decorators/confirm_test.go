@@ -0,0 +1,235 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeTTY answers the prompt from a fixed input and captures what was written.
+type fakeTTY struct {
+	io.Reader
+	out    bytes.Buffer
+	closed bool
+}
+
+func (t *fakeTTY) Write(p []byte) (int, error) { return t.out.Write(p) }
+func (t *fakeTTY) Close() error                { t.closed = true; return nil }
+
+// blockingReader never returns, simulating an operator who does not answer.
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) { select {} }
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []decorator.Event
+}
+
+func (s *recordingSink) Emit(event decorator.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func withTTY(t *testing.T, open func() (io.ReadWriteCloser, error)) {
+	t.Helper()
+	original := openControllingTTY
+	openControllingTTY = open
+	t.Cleanup(func() { openControllingTTY = original })
+}
+
+func runConfirm(t *testing.T, ctx context.Context, params map[string]any) (bool, decorator.Result, error, []decorator.Event) {
+	t.Helper()
+	ran := false
+	sink := &recordingSink{}
+	node := (&ConfirmDecorator{}).Wrap(&testExecNode{execute: func(ctx decorator.ExecContext) (decorator.Result, error) {
+		ran = true
+		return decorator.Result{ExitCode: 0}, nil
+	}}, params)
+	result, err := node.Execute(decorator.ExecContext{Context: ctx, Events: sink})
+	return ran, result, err, sink.events
+}
+
+func confirmAttrs(events []decorator.Event) []map[string]string {
+	attrs := make([]map[string]string, 0, len(events))
+	for _, event := range events {
+		attrs = append(attrs, event.Attrs)
+	}
+	return attrs
+}
+
+func TestConfirmApprovedOnTTY(t *testing.T) {
+	tty := &fakeTTY{Reader: strings.NewReader("yes\n")}
+	withTTY(t, func() (io.ReadWriteCloser, error) { return tty, nil })
+
+	ran, result, err, events := runConfirm(t, context.Background(), map[string]any{
+		"message": "Deploy to prod?",
+		"name":    "prod",
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !ran || result.ExitCode != 0 {
+		t.Fatalf("expected block to run, ran=%v exit=%d", ran, result.ExitCode)
+	}
+	if diff := cmp.Diff("Deploy to prod? [y/N]: ", tty.out.String()); diff != "" {
+		t.Errorf("prompt mismatch (-want +got):\n%s", diff)
+	}
+	if !tty.closed {
+		t.Error("expected tty to be closed")
+	}
+
+	want := []map[string]string{{"name": "prod", "outcome": "approved", "via": "tty"}}
+	if diff := cmp.Diff(want, confirmAttrs(events)); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+	if events[0].Decorator != "exec.confirm" || events[0].Timestamp.IsZero() {
+		t.Errorf("unexpected event header: %+v", events[0])
+	}
+}
+
+func TestConfirmRedactsPromptAndName(t *testing.T) {
+	tty := &fakeTTY{Reader: strings.NewReader("y\n")}
+	withTTY(t, func() (io.ReadWriteCloser, error) { return tty, nil })
+
+	sink := &recordingSink{}
+	node := (&ConfirmDecorator{}).Wrap(&testExecNode{execute: func(ctx decorator.ExecContext) (decorator.Result, error) {
+		return decorator.Result{ExitCode: 0}, nil
+	}}, map[string]any{"message": "Rotate token hunter2?", "name": "rotate-hunter2"})
+
+	_, err := node.Execute(decorator.ExecContext{
+		Context: context.Background(),
+		Events:  sink,
+		Redact:  func(text string) string { return strings.ReplaceAll(text, "hunter2", "sigil:ABC") },
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if diff := cmp.Diff("Rotate token sigil:ABC? [y/N]: ", tty.out.String()); diff != "" {
+		t.Errorf("prompt mismatch (-want +got):\n%s", diff)
+	}
+	want := []map[string]string{{"name": "rotate-sigil:ABC", "outcome": "approved", "via": "tty"}}
+	if diff := cmp.Diff(want, confirmAttrs(sink.events)); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfirmDeniedOnTTY(t *testing.T) {
+	for _, answer := range []string{"n\n", "\n", "maybe\n", ""} {
+		withTTY(t, func() (io.ReadWriteCloser, error) {
+			return &fakeTTY{Reader: strings.NewReader(answer)}, nil
+		})
+
+		ran, result, err, events := runConfirm(t, context.Background(), map[string]any{"name": "prod"})
+		if ran {
+			t.Errorf("answer %q: block must not run", answer)
+		}
+		if result.ExitCode != decorator.ExitFailure || err == nil {
+			t.Errorf("answer %q: expected failure, got exit=%d err=%v", answer, result.ExitCode, err)
+		}
+		if len(events) != 1 || events[0].Attrs["outcome"] != "denied" {
+			t.Errorf("answer %q: expected denied event, got %+v", answer, events)
+		}
+	}
+}
+
+func TestConfirmFailsClosedWithoutTTY(t *testing.T) {
+	withTTY(t, func() (io.ReadWriteCloser, error) { return nil, errors.New("no such device") })
+
+	ran, result, err, events := runConfirm(t, context.Background(), map[string]any{"name": "prod"})
+	if ran {
+		t.Fatal("block must not run without a terminal")
+	}
+	if result.ExitCode != decorator.ExitFailure {
+		t.Errorf("exit code: got %d, want %d", result.ExitCode, decorator.ExitFailure)
+	}
+	if err == nil || !strings.Contains(err.Error(), "requires an interactive terminal") || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("expected actionable no-tty error, got %v", err)
+	}
+
+	want := []map[string]string{{"name": "prod", "outcome": "denied", "via": "no-tty"}}
+	if diff := cmp.Diff(want, confirmAttrs(events)); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfirmPolicyApprovesWithoutTTY(t *testing.T) {
+	withTTY(t, func() (io.ReadWriteCloser, error) {
+		t.Fatal("tty must not be opened when policy approves")
+		return nil, nil
+	})
+
+	tests := []struct {
+		name   string
+		policy ConfirmPolicy
+		via    string
+	}{
+		{name: "assume yes", policy: ConfirmPolicy{AssumeYes: true}, via: "assume-yes"},
+		{name: "allowlist", policy: ConfirmPolicy{Allow: []string{"staging", "prod-*"}}, via: "allowlist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithConfirmPolicy(context.Background(), tt.policy)
+			ran, _, err, events := runConfirm(t, ctx, map[string]any{"name": "prod-eu"})
+			if err != nil || !ran {
+				t.Fatalf("expected approval, ran=%v err=%v", ran, err)
+			}
+			want := []map[string]string{{"name": "prod-eu", "outcome": "approved", "via": tt.via}}
+			if diff := cmp.Diff(want, confirmAttrs(events)); diff != "" {
+				t.Errorf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfirmAllowlistDoesNotMatchOtherGates(t *testing.T) {
+	withTTY(t, func() (io.ReadWriteCloser, error) { return nil, errors.New("no tty") })
+
+	ctx := WithConfirmPolicy(context.Background(), ConfirmPolicy{Allow: []string{"staging"}})
+	ran, _, err, _ := runConfirm(t, ctx, map[string]any{"name": "prod"})
+	if ran || err == nil {
+		t.Fatalf("expected gate outside allowlist to fail closed, ran=%v err=%v", ran, err)
+	}
+}
+
+func TestConfirmTimeoutDenies(t *testing.T) {
+	withTTY(t, func() (io.ReadWriteCloser, error) { return &fakeTTY{Reader: blockingReader{}}, nil })
+
+	ran, result, err, events := runConfirm(t, context.Background(), map[string]any{
+		"name":    "prod",
+		"timeout": "20ms",
+	})
+	if ran {
+		t.Fatal("block must not run after timeout")
+	}
+	if result.ExitCode != decorator.ExitFailure || err == nil || !strings.Contains(err.Error(), "no answer within") {
+		t.Errorf("expected timeout failure, got exit=%d err=%v", result.ExitCode, err)
+	}
+	if len(events) != 1 || events[0].Attrs["via"] != "timeout" {
+		t.Errorf("expected timeout event, got %+v", events)
+	}
+}
+
+func TestConfirmCancellationStopsPrompt(t *testing.T) {
+	withTTY(t, func() (io.ReadWriteCloser, error) { return &fakeTTY{Reader: blockingReader{}}, nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran, result, err, _ := runConfirm(t, ctx, map[string]any{"name": "prod"})
+	if ran {
+		t.Fatal("block must not run after cancellation")
+	}
+	if result.ExitCode != decorator.ExitCanceled || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled result, got exit=%d err=%v", result.ExitCode, err)
+	}
+}
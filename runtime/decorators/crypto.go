@@ -1,91 +1,431 @@
 package decorators
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/types"
+	"github.com/builtwithtofu/sigil/runtime/secretstore"
+	"golang.org/x/crypto/hkdf"
 )
 
 func init() {
-	if err := decorator.Register("crypto", &CryptoValueDecorator{}); err != nil {
-		panic(fmt.Sprintf("failed to register @crypto decorator: %v", err))
+	for _, d := range []*CryptoValueDecorator{
+		NewCryptoValueDecorator(cryptoHashDescriptor("sha256"), hashMethod(sha256.New)),
+		NewCryptoValueDecorator(cryptoHashDescriptor("sha512"), hashMethod(sha512.New)),
+		NewCryptoValueDecorator(cryptoHMACDescriptor(), cryptoHMAC),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("base64", "encode"), encodeMethod(base64.StdEncoding.EncodeToString)),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("base64", "decode"), decodeMethod(decodeBase64(base64.StdEncoding))),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("base64url", "encode"), encodeMethod(base64.RawURLEncoding.EncodeToString)),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("base64url", "decode"), decodeMethod(decodeBase64(base64.URLEncoding))),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("hex", "encode"), encodeMethod(hex.EncodeToString)),
+		NewCryptoValueDecorator(cryptoCodecDescriptor("hex", "decode"), decodeMethod(hex.DecodeString)),
+		newRandomCryptoDecorator(cryptoUUIDDescriptor(), cryptoUUID, cryptoSeeded),
+		newRandomCryptoDecorator(cryptoPasswordDescriptor(), cryptoPassword, cryptoSeeded),
+		newRandomCryptoDecorator(cryptoBcryptDescriptor(), cryptoBcrypt, cryptoRemembered),
+		newRandomCryptoDecorator(cryptoArgon2Descriptor(), cryptoArgon2, cryptoSeeded),
+	} {
+		if err := decorator.Register(d.descriptor.Path, d); err != nil {
+			panic(fmt.Sprintf("failed to register @%s decorator: %v", d.descriptor.Path, err))
+		}
 	}
 }
 
-// CryptoValueDecorator provides cryptographic hash functions.
-type CryptoValueDecorator struct{}
+// CryptoValueDecorator implements one @crypto method (@crypto.sha256,
+// @crypto.hmac, @crypto.password, ...). Each method has its own descriptor,
+// so the parser validates its arguments.
+//
+// Methods that need randomness (UUIDs, passwords, argon2 salts) derive it
+// from a seed kept in the secret store, salted with the plan key. The seed
+// never appears in a plan, so a contract alone does not reveal generated
+// values, while replanning it on a machine with the same store reproduces
+// them. Bcrypt draws its own salt, so its hashes are kept in the store
+// instead.
+type CryptoValueDecorator struct {
+	descriptor decorator.Descriptor
+	method     cryptoMethod
+	random     cryptoRandomness
+	secrets    *cryptoSecrets
+}
+
+// cryptoMethod computes the value of one call. random yields the call's
+// random bytes.
+type cryptoMethod func(args cryptoArgs, random io.Reader) (string, error)
+
+// cryptoRandomness says where a method's random bytes come from.
+type cryptoRandomness int
+
+const (
+	cryptoNoRandom   cryptoRandomness = iota // deterministic in its arguments
+	cryptoSeeded                             // derived from the store seed
+	cryptoRemembered                         // crypto/rand, result kept in the store
+)
 
+// NewCryptoValueDecorator wraps method in a value decorator described by desc.
+func NewCryptoValueDecorator(desc decorator.Descriptor, method cryptoMethod) *CryptoValueDecorator {
+	return &CryptoValueDecorator{descriptor: desc, method: method, secrets: defaultCryptoSecrets}
+}
+
+// newRandomCryptoDecorator wraps a method that reads from its random stream.
+func newRandomCryptoDecorator(desc decorator.Descriptor, method cryptoMethod, random cryptoRandomness) *CryptoValueDecorator {
+	d := NewCryptoValueDecorator(desc, method)
+	d.random = random
+	return d
+}
+
+// Descriptor returns the decorator metadata.
 func (d *CryptoValueDecorator) Descriptor() decorator.Descriptor {
-	return decorator.NewDescriptor("crypto").
-		Summary("Cryptographic helper functions").
-		Build()
+	return d.descriptor
 }
 
-// Resolve implements decorator.Value interface
+// Resolve implements decorator.Value.
 func (d *CryptoValueDecorator) Resolve(ctx decorator.ValueEvalContext, calls ...decorator.ValueCall) ([]decorator.ResolveResult, error) {
+	origin := "@" + d.descriptor.Path
 	results := make([]decorator.ResolveResult, len(calls))
 
 	for i, call := range calls {
-		method := d.methodForCall(call)
-		switch {
-		case strings.EqualFold(method, "SHA256"):
-			input, err := d.argumentForCall(call)
-			if err != nil {
-				results[i] = decorator.ResolveResult{Error: err, Origin: "@crypto"}
-				continue
-			}
+		value, err := d.resolve(ctx.PlanHash, call.Params)
+		if err != nil {
+			results[i] = decorator.ResolveResult{Origin: origin, Error: fmt.Errorf("%s: %w", origin, err)}
+			continue
+		}
+		results[i] = decorator.ResolveResult{Value: value, Origin: origin}
+	}
+
+	return results, nil
+}
+
+func (d *CryptoValueDecorator) resolve(planKey []byte, params map[string]any) (string, error) {
+	args := cryptoArgs(params)
+	if d.random == cryptoNoRandom {
+		return d.method(args, nil)
+	}
+
+	seed, err := d.secrets.seed()
+	if err != nil {
+		return "", err
+	}
+	if seed == nil {
+		return d.method(args, rand.Reader)
+	}
+	if d.random == cryptoRemembered {
+		return d.secrets.remember(cryptoRememberedName(seed, d.descriptor.Path, params), func() (string, error) {
+			return d.method(args, rand.Reader)
+		})
+	}
+	return d.method(args, cryptoRandom(seed, planKey, d.descriptor.Path, params))
+}
 
-			hash := sha256.Sum256([]byte(input))
-			results[i] = decorator.ResolveResult{
-				Value:  hex.EncodeToString(hash[:]),
-				Origin: "@crypto",
+// cryptoRandom returns the random stream for one call: HKDF of the store
+// seed, salted with the plan key and bound to the method and its arguments.
+// The arguments enter only through a MAC keyed by the seed, so passwords
+// passed to a method never reach the KDF in the clear.
+func cryptoRandom(seed, planKey []byte, path string, params map[string]any) io.Reader {
+	info := "sigil/" + path + "\x00" + hex.EncodeToString(cryptoParamsMAC(seed, params))
+	return hkdf.New(sha256.New, seed, planKey, []byte(info))
+}
+
+// cryptoRememberedName is the secret store entry that keeps the result of a
+// call to path with params.
+func cryptoRememberedName(seed []byte, path string, params map[string]any) string {
+	return cryptoStorePrefix + strings.TrimPrefix(path, "crypto.") + "/" + hex.EncodeToString(cryptoParamsMAC(seed, params))
+}
+
+// cryptoParamsMAC authenticates params in a canonical order.
+func cryptoParamsMAC(seed []byte, params map[string]any) []byte {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha256.New, seed)
+	for _, key := range keys {
+		fmt.Fprintf(mac, "%s=%v\x00", key, params[key])
+	}
+	return mac.Sum(nil)
+}
+
+// cryptoStorePrefix namespaces @crypto's entries in the secret store.
+const cryptoStorePrefix = "sigil/crypto/"
+
+// cryptoSeedSize is the length of the random seed in bytes.
+const cryptoSeedSize = 32
+
+// cryptoSecrets is the secret store state shared by the @crypto methods:
+// the seed random streams derive from, created on first use, and the
+// results of remembered calls.
+type cryptoSecrets struct {
+	open func() (*secretstore.Store, error)
+
+	mu     sync.Mutex
+	loaded bool
+	store  *secretstore.Store
+	key    []byte
+}
+
+var defaultCryptoSecrets = &cryptoSecrets{open: openDefaultSecretStore}
+
+// seed returns the store seed, creating and saving it if the store has none.
+// It returns nil without a store; generated values then come from
+// crypto/rand and change on every plan, so contracts that use them fail
+// verification.
+func (c *cryptoSecrets) seed() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loaded {
+		return c.key, nil
+	}
+	// Failures are not cached, matching @secrets
+	store, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		key, err := store.Get(cryptoStorePrefix + "seed")
+		if errors.Is(err, secretstore.ErrNotFound) {
+			key = make([]byte, cryptoSeedSize)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("generate crypto seed: %w", err)
 			}
-		default:
-			results[i] = decorator.ResolveResult{
-				Error:  fmt.Errorf("unsupported crypto method: %s", method),
-				Origin: "@crypto",
+			if err := store.Set(cryptoStorePrefix+"seed", key); err != nil {
+				return nil, err
 			}
+			if err := store.Save(); err != nil {
+				return nil, fmt.Errorf("save crypto seed: %w", err)
+			}
+		} else if err != nil {
+			return nil, err
 		}
+		c.store, c.key = store, key
 	}
+	c.loaded = true
+	return c.key, nil
+}
 
-	return results, nil
+// remember returns the value stored under name, computing and saving it on
+// first use. Callers must have loaded the seed, so the store is open.
+func (c *cryptoSecrets) remember(name string, compute func() (string, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value, err := c.store.Get(name); err == nil {
+		return string(value), nil
+	} else if !errors.Is(err, secretstore.ErrNotFound) {
+		return "", err
+	}
+
+	value, err := compute()
+	if err != nil {
+		return "", err
+	}
+	if err := c.store.Set(name, []byte(value)); err != nil {
+		return "", err
+	}
+	if err := c.store.Save(); err != nil {
+		return "", fmt.Errorf("save %s: %w", name, err)
+	}
+	return value, nil
 }
 
-func (d *CryptoValueDecorator) methodForCall(call decorator.ValueCall) string {
-	if call.Primary != nil && *call.Primary != "" {
-		return *call.Primary
+// cryptoArgs reads normalized call parameters. Normalization does not fill
+// in descriptor defaults, so methods pass them as fallbacks, and ranges are
+// checked again because values from variables reach Resolve unvalidated.
+type cryptoArgs map[string]any
+
+func (a cryptoArgs) string(name string) (string, error) {
+	raw, ok := a[name]
+	if !ok || raw == nil {
+		return "", fmt.Errorf("missing required parameter %q", name)
+	}
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("parameter %q must be a string, got %T", name, raw)
 	}
+}
 
-	if method, ok := call.Params["method"].(string); ok && method != "" {
-		return method
+func (a cryptoArgs) optionalString(name, fallback string) (string, error) {
+	if raw, ok := a[name]; !ok || raw == nil {
+		return fallback, nil
 	}
+	return a.string(name)
+}
 
-	return "SHA256"
+func (a cryptoArgs) int(name string, fallback, minVal, maxVal int64) (int64, error) {
+	raw, ok := a[name]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	var value int64
+	switch v := raw.(type) {
+	case int:
+		value = int64(v)
+	case int64:
+		value = v
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("parameter %q must be an integer, got %v", name, raw)
+		}
+		value = int64(v)
+	default:
+		return 0, fmt.Errorf("parameter %q must be an integer, got %T", name, raw)
+	}
+	if value < minVal || value > maxVal {
+		return 0, fmt.Errorf("parameter %q must be between %d and %d, got %d", name, minVal, maxVal, value)
+	}
+	return value, nil
 }
 
-func (d *CryptoValueDecorator) argumentForCall(call decorator.ValueCall) (string, error) {
-	if value, ok := call.Params["arg0"]; ok {
-		return coerceString(value)
+// hashMethod returns a method that hex-encodes the digest of data.
+func hashMethod(newHash func() hash.Hash) cryptoMethod {
+	return func(args cryptoArgs, _ io.Reader) (string, error) {
+		data, err := args.string("data")
+		if err != nil {
+			return "", err
+		}
+		h := newHash()
+		h.Write([]byte(data))
+		return hex.EncodeToString(h.Sum(nil)), nil
 	}
+}
 
-	if value, ok := call.Params["arg1"]; ok {
-		return coerceString(value)
+func cryptoHMAC(args cryptoArgs, _ io.Reader) (string, error) {
+	key, err := args.string("key")
+	if err != nil {
+		return "", err
+	}
+	data, err := args.string("data")
+	if err != nil {
+		return "", err
+	}
+	algorithm, err := args.optionalString("algorithm", "sha256")
+	if err != nil {
+		return "", err
 	}
 
-	if value, ok := call.Params["data"]; ok {
-		return coerceString(value)
+	var newHash func() hash.Hash
+	switch algorithm {
+	case "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return "", fmt.Errorf("unknown algorithm %q (expected sha256 or sha512)", algorithm)
 	}
 
-	return "", fmt.Errorf("@crypto.%s requires one string argument", d.methodForCall(call))
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil)), nil
 }
 
-func coerceString(value any) (string, error) {
-	str, ok := value.(string)
-	if !ok {
-		return "", fmt.Errorf("crypto argument must be string, got %T", value)
+func encodeMethod(encode func([]byte) string) cryptoMethod {
+	return func(args cryptoArgs, _ io.Reader) (string, error) {
+		data, err := args.string("data")
+		if err != nil {
+			return "", err
+		}
+		return encode([]byte(data)), nil
+	}
+}
+
+func decodeMethod(decode func(string) ([]byte, error)) cryptoMethod {
+	return func(args cryptoArgs, _ io.Reader) (string, error) {
+		data, err := args.string("data")
+		if err != nil {
+			return "", err
+		}
+		decoded, err := decode(strings.TrimSpace(data))
+		if err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+		return string(decoded), nil
+	}
+}
+
+// decodeBase64 accepts input with or without padding.
+func decodeBase64(enc *base64.Encoding) func(string) ([]byte, error) {
+	unpadded := enc.WithPadding(base64.NoPadding)
+	return func(data string) ([]byte, error) {
+		return unpadded.DecodeString(strings.TrimRight(data, "="))
+	}
+}
+
+func cryptoHashDescriptor(name string) decorator.Descriptor {
+	return decorator.NewDescriptor("crypto."+name).
+		Summary(fmt.Sprintf("%s digest of a string", strings.ToUpper(name))).
+		Roles(decorator.RoleProvider).
+		ParamString("data", "Input to hash").
+		Required().
+		Examples("hello", "@var.CONFIG").
+		Done().
+		Returns(types.TypeString, "Lowercase hex digest").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func cryptoHMACDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("crypto.hmac").
+		Summary("HMAC of a string").
+		Roles(decorator.RoleProvider).
+		ParamString("key", "Secret key").
+		Required().
+		Examples("@var.SIGNING_KEY").
+		Done().
+		ParamString("data", "Message to authenticate").
+		Required().
+		Examples("payload").
+		Done().
+		ParamEnum("algorithm", "Hash function").
+		Values("sha256", "sha512").
+		Default("sha256").
+		Done().
+		Returns(types.TypeString, "Lowercase hex MAC").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func cryptoCodecDescriptor(codec, direction string) decorator.Descriptor {
+	names := map[string]string{"base64": "base64", "base64url": "unpadded URL-safe base64", "hex": "hex"}
+	summary, input, result := "Encode a string as "+names[codec], "Input to encode", "Encoded string"
+	if direction == "decode" {
+		summary, input, result = "Decode a "+names[codec]+" string", "Encoded input", "Decoded string"
+		if codec != "hex" {
+			input += " (padding optional)"
+		}
 	}
-	return str, nil
+	return decorator.NewDescriptor("crypto."+codec+"."+direction).
+		Summary(summary).
+		Roles(decorator.RoleProvider).
+		ParamString("data", input).
+		Required().
+		Done().
+		Returns(types.TypeString, result).
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
 }
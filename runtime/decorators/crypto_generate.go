@@ -0,0 +1,245 @@
+package decorators
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/types"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCharsets are the named alphabets for @crypto.password. Symbols
+// leave out quotes, backslash, backtick and space so passwords survive shell
+// quoting.
+var passwordCharsets = map[string]string{
+	"alphanumeric": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":        "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"digits":       "0123456789",
+	"hex":          "0123456789abcdef",
+	"symbols":      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~",
+}
+
+func cryptoUUID(args cryptoArgs, random io.Reader) (string, error) {
+	version, err := args.optionalString("version", "v4")
+	if err != nil {
+		return "", err
+	}
+
+	var u [16]byte
+	if _, err := io.ReadFull(random, u[:]); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+	switch version {
+	case "v4":
+		u[6] = u[6]&0x0f | 0x40
+	case "v7":
+		// 48-bit big-endian Unix time in milliseconds, then random bits
+		var ms [8]byte
+		binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+		copy(u[:6], ms[2:])
+		u[6] = u[6]&0x0f | 0x70
+	default:
+		return "", fmt.Errorf("unknown version %q (expected v4 or v7)", version)
+	}
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+func cryptoPassword(args cryptoArgs, random io.Reader) (string, error) {
+	length, err := args.int("length", 32, 4, 256)
+	if err != nil {
+		return "", err
+	}
+	charset, err := args.optionalString("charset", "alphanumeric")
+	if err != nil {
+		return "", err
+	}
+	alphabet, err := args.optionalString("alphabet", "")
+	if err != nil {
+		return "", err
+	}
+	if alphabet == "" {
+		var ok bool
+		if alphabet, ok = passwordCharsets[charset]; !ok {
+			return "", fmt.Errorf("unknown charset %q", charset)
+		}
+	}
+
+	symbols := []rune(alphabet)
+	if len(symbols) < 2 || len(symbols) > 256 {
+		return "", fmt.Errorf("alphabet must have 2 to 256 characters, got %d", len(symbols))
+	}
+
+	// Reject bytes past the largest multiple of the alphabet size, so every
+	// character is equally likely.
+	limit := 256 - 256%len(symbols)
+	password := make([]rune, 0, length)
+	buf := make([]byte, 64)
+	for len(password) < int(length) {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return "", fmt.Errorf("read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(password) < int(length) {
+				password = append(password, symbols[int(b)%len(symbols)])
+			}
+		}
+	}
+	return string(password), nil
+}
+
+// cryptoBcrypt returns a $2a$ bcrypt hash. bcrypt draws its own salt, so
+// Resolve keeps the hash in the secret store to reproduce it on replans.
+func cryptoBcrypt(args cryptoArgs, _ io.Reader) (string, error) {
+	password, err := args.string("password")
+	if err != nil {
+		return "", err
+	}
+	if len(password) > 72 {
+		return "", errors.New("bcrypt passwords are limited to 72 bytes")
+	}
+	cost, err := args.int("cost", 12, 4, 31)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), int(cost))
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// cryptoArgon2 returns an argon2id hash in the PHC string format used by
+// libargon2 and most password libraries.
+func cryptoArgon2(args cryptoArgs, random io.Reader) (string, error) {
+	password, err := args.string("password")
+	if err != nil {
+		return "", err
+	}
+	iterations, err := args.int("iterations", 3, 1, 64)
+	if err != nil {
+		return "", err
+	}
+	memory, err := args.int("memory", 64*1024, 8*1024, 4*1024*1024)
+	if err != nil {
+		return "", err
+	}
+	parallelism, err := args.int("parallelism", 4, 1, 255)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, uint32(iterations), uint32(memory), uint8(parallelism), 32)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, iterations, parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func cryptoUUIDDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("crypto.uuid").
+		Summary("Generate a UUID").
+		Roles(decorator.RoleProvider).
+		ParamEnum("version", "UUID version: v4 (random) or v7 (time-ordered)").
+		Values("v4", "v7").
+		Default("v4").
+		Done().
+		ParamString("label", "Distinguishes otherwise identical calls").
+		Examples("request-id").
+		Done().
+		Returns(types.TypeString, "UUID in canonical form").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivityPublic).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func cryptoPasswordDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("crypto.password").
+		Summary("Generate a random password").
+		Roles(decorator.RoleProvider).
+		ParamInt("length", "Number of characters").
+		Default(32).
+		Min(4).
+		Max(256).
+		Done().
+		ParamEnum("charset", "Characters to draw from").
+		Values("alphanumeric", "alpha", "digits", "hex", "symbols").
+		Default("alphanumeric").
+		Done().
+		ParamString("alphabet", "Custom characters to draw from (overrides charset)").
+		MinLength(2).
+		MaxLength(256).
+		Examples("abcdefghjkmnpqrstuvwxyz23456789").
+		Done().
+		ParamString("label", "Distinguishes otherwise identical calls, e.g. one password per user").
+		Examples("db-admin", "grafana").
+		Done().
+		Returns(types.TypeString, "Password").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func cryptoBcryptDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("crypto.bcrypt").
+		Summary("Hash a password with bcrypt").
+		Roles(decorator.RoleProvider).
+		ParamString("password", "Password to hash (at most 72 bytes)").
+		Required().
+		MaxLength(72).
+		Examples("@var.ADMIN_PASSWORD").
+		Done().
+		ParamInt("cost", "Work factor (log2 of the rounds)").
+		Default(12).
+		Min(4).
+		Max(31).
+		Done().
+		Returns(types.TypeString, "Hash in $2a$ modular crypt format").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func cryptoArgon2Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("crypto.argon2").
+		Summary("Hash a password with argon2id").
+		Roles(decorator.RoleProvider).
+		ParamString("password", "Password to hash").
+		Required().
+		Examples("@var.ADMIN_PASSWORD").
+		Done().
+		ParamInt("iterations", "Passes over memory").
+		Default(3).
+		Min(1).
+		Max(64).
+		Done().
+		ParamInt("memory", "Memory in KiB").
+		Default(64*1024).
+		Min(8*1024).
+		Max(4*1024*1024).
+		Done().
+		ParamInt("parallelism", "Lanes").
+		Default(4).
+		Min(1).
+		Max(255).
+		Done().
+		Returns(types.TypeString, "Hash in PHC string format ($argon2id$...)").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Block(decorator.BlockForbidden).
+		Build()
+}
@@ -1,87 +1,346 @@
 package decorators
 
 import (
+	"encoding/base64"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/runtime/secretstore"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func TestCryptoValueDecorator_Descriptor(t *testing.T) {
-	d := &CryptoValueDecorator{}
-	desc := d.Descriptor()
+var cryptoTestPlanKey = []byte("crypto-test-plan-key")
 
-	if diff := cmp.Diff("crypto", desc.Path); diff != "" {
-		t.Fatalf("descriptor path mismatch (-want +got):\n%s", diff)
-	}
-	if diff := cmp.Diff("Cryptographic helper functions", desc.Summary); diff != "" {
-		t.Fatalf("descriptor summary mismatch (-want +got):\n%s", diff)
-	}
+// useCryptoStore points the registered @crypto methods at store (nil for no
+// store) until the test ends.
+func useCryptoStore(t *testing.T, store *secretstore.Store) {
+	t.Helper()
+	c := defaultCryptoSecrets
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	open := c.open
+	c.open = func() (*secretstore.Store, error) { return store, nil }
+	c.loaded, c.store, c.key = false, nil, nil
+	t.Cleanup(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.open = open
+		c.loaded, c.store, c.key = false, nil, nil
+	})
+}
+
+// openCryptoTestStore opens the secret store kept in dir, creating it empty.
+func openCryptoTestStore(t *testing.T, dir string) *secretstore.Store {
+	t.Helper()
+	store, err := secretstore.Open(filepath.Join(dir, "secrets.enc"), secretstore.KeyFile(filepath.Join(dir, "key")))
+	require.NoError(t, err)
+	return store
 }
 
-func TestCryptoValueDecorator_Resolve_SHA256_Hello(t *testing.T) {
-	d := &CryptoValueDecorator{}
-	fn := "SHA256"
+// resolveCrypto resolves one call to the registered @crypto method at path.
+func resolveCrypto(t *testing.T, path string, planKey []byte, params map[string]any) (string, error) {
+	t.Helper()
+	entry, ok := decorator.Global().Lookup(path)
+	require.True(t, ok, "@%s not registered", path)
+	value, ok := entry.Impl.(decorator.Value)
+	require.True(t, ok, "@%s is not a value decorator", path)
 
-	results, err := d.Resolve(decorator.ValueEvalContext{}, decorator.ValueCall{
-		Path:    "crypto",
-		Primary: &fn,
-		Params: map[string]any{
-			"arg1": "hello",
-		},
-	})
+	results, err := value.Resolve(decorator.ValueEvalContext{PlanHash: planKey}, decorator.ValueCall{Path: path, Params: params})
 	require.NoError(t, err)
 	require.Len(t, results, 1)
-	require.NoError(t, results[0].Error)
+	if results[0].Error != nil {
+		return "", results[0].Error
+	}
+	require.Equal(t, "@"+path, results[0].Origin)
+	str, ok := results[0].Value.(string)
+	require.True(t, ok, "@%s returned %T", path, results[0].Value)
+	return str, nil
+}
+
+func TestCryptoValueDecorator_Resolve(t *testing.T) {
+	tests := []struct {
+		path   string
+		params map[string]any
+		want   string
+	}{
+		{"crypto.sha256", map[string]any{"data": "hello"}, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"crypto.sha256", map[string]any{"data": ""}, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"crypto.sha512", map[string]any{"data": "abc"}, "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		// RFC 4231 test case 2
+		{"crypto.hmac", map[string]any{"key": "Jefe", "data": "what do ya want for nothing?"}, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"crypto.hmac", map[string]any{"key": "Jefe", "data": "what do ya want for nothing?", "algorithm": "sha512"}, "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"},
+		{"crypto.base64.encode", map[string]any{"data": "hi?>"}, "aGk/Pg=="},
+		{"crypto.base64.decode", map[string]any{"data": "aGk/Pg=="}, "hi?>"},
+		{"crypto.base64.decode", map[string]any{"data": "aGk/Pg"}, "hi?>"},
+		{"crypto.base64url.encode", map[string]any{"data": "hi?>"}, "aGk_Pg"},
+		{"crypto.base64url.decode", map[string]any{"data": "aGk_Pg=="}, "hi?>"},
+		{"crypto.hex.encode", map[string]any{"data": "hi"}, "6869"},
+		{"crypto.hex.decode", map[string]any{"data": "6869\n"}, "hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := resolveCrypto(t, tt.path, cryptoTestPlanKey, tt.params)
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("@%s mismatch (-want +got):\n%s", tt.path, diff)
+			}
+		})
+	}
+}
+
+func TestCryptoValueDecorator_ResolveErrors(t *testing.T) {
+	useCryptoStore(t, nil)
+
+	tests := []struct {
+		path    string
+		params  map[string]any
+		wantErr string
+	}{
+		{"crypto.sha256", map[string]any{}, `@crypto.sha256: missing required parameter "data"`},
+		{"crypto.sha256", map[string]any{"data": 42}, `@crypto.sha256: parameter "data" must be a string, got int`},
+		{"crypto.hmac", map[string]any{"key": "k", "data": "d", "algorithm": "md5"}, `@crypto.hmac: unknown algorithm "md5" (expected sha256 or sha512)`},
+		{"crypto.hex.decode", map[string]any{"data": "xyz"}, "@crypto.hex.decode: invalid input: encoding/hex: invalid byte: U+0078 'x'"},
+		{"crypto.password", map[string]any{"length": 2}, `@crypto.password: parameter "length" must be between 4 and 256, got 2`},
+		{"crypto.password", map[string]any{"alphabet": "a"}, "@crypto.password: alphabet must have 2 to 256 characters, got 1"},
+		{"crypto.bcrypt", map[string]any{"password": strings.Repeat("x", 73)}, "@crypto.bcrypt: bcrypt passwords are limited to 72 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := resolveCrypto(t, tt.path, cryptoTestPlanKey, tt.params)
+			require.Error(t, err)
+			if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
+				t.Errorf("error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
 
-	hash, ok := results[0].Value.(string)
+func TestCryptoValueDecorator_Descriptors(t *testing.T) {
+	tests := []struct {
+		path        string
+		sensitivity decorator.Sensitivity
+		params      []string
+	}{
+		{"crypto.sha256", decorator.SensitivitySecret, []string{"data"}},
+		{"crypto.hmac", decorator.SensitivitySecret, []string{"key", "data", "algorithm"}},
+		{"crypto.base64url.decode", decorator.SensitivitySecret, []string{"data"}},
+		{"crypto.uuid", decorator.SensitivityPublic, []string{"version", "label"}},
+		{"crypto.password", decorator.SensitivitySecret, []string{"length", "charset", "alphabet", "label"}},
+		{"crypto.bcrypt", decorator.SensitivitySecret, []string{"password", "cost"}},
+		{"crypto.argon2", decorator.SensitivitySecret, []string{"password", "iterations", "memory", "parallelism"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			entry, ok := decorator.Global().Lookup(tt.path)
+			require.True(t, ok)
+			desc := entry.Impl.Descriptor()
+			if diff := cmp.Diff(tt.sensitivity, desc.Sensitivity); diff != "" {
+				t.Errorf("sensitivity mismatch (-want +got):\n%s", diff)
+			}
+			var params []string
+			for _, param := range desc.Schema.GetOrderedParameters() {
+				params = append(params, param.Name)
+			}
+			if diff := cmp.Diff(tt.params, params); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCryptoValueDecorator_SchemaRejectsUnknownParameter(t *testing.T) {
+	entry, ok := decorator.Global().Lookup("crypto.password")
 	require.True(t, ok)
-	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
-	if diff := cmp.Diff(expected, hash); diff != "" {
-		t.Fatalf("sha256 hash mismatch (-want +got):\n%s", diff)
+
+	_, _, err := decorator.NormalizeArgs(entry.Impl.Descriptor().Schema, nil, map[string]any{"size": 12})
+	require.Error(t, err)
+	if diff := cmp.Diff(`unknown parameter "size"`, err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
 	}
 }
 
-func TestCryptoValueDecorator_Resolve_SHA256_EmptyString(t *testing.T) {
-	d := &CryptoValueDecorator{}
-	fn := "SHA256"
+func TestCryptoPassword(t *testing.T) {
+	useCryptoStore(t, nil)
 
-	results, err := d.Resolve(decorator.ValueEvalContext{}, decorator.ValueCall{
-		Path:    "crypto",
-		Primary: &fn,
-		Params: map[string]any{
-			"arg1": "",
-		},
-	})
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantLen int
+		pattern string
+	}{
+		{"default", map[string]any{}, 32, `^[A-Za-z0-9]+$`},
+		{"digits", map[string]any{"length": 6, "charset": "digits"}, 6, `^[0-9]+$`},
+		{"symbols", map[string]any{"length": 64, "charset": "symbols"}, 64, `^[A-Za-z0-9!#$%&()*+,\-./:;<=>?@\[\]^_{|}~]+$`},
+		{"custom alphabet", map[string]any{"length": 20, "alphabet": "xyzé"}, 20, `^[xyzé]+$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, tt.params)
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.wantLen, len([]rune(got))); diff != "" {
+				t.Errorf("length mismatch (-want +got):\n%s", diff)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(got) {
+				t.Errorf("password %q does not match %s", got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestCryptoPasswordDerivedFromStoreSeed(t *testing.T) {
+	dir := t.TempDir()
+	useCryptoStore(t, openCryptoTestStore(t, dir))
+
+	first, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	again, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	other, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "grafana"})
+	require.NoError(t, err)
+	newPlan, err := resolveCrypto(t, "crypto.password", []byte("another-plan-key"), map[string]any{"label": "db"})
 	require.NoError(t, err)
-	require.Len(t, results, 1)
-	require.NoError(t, results[0].Error)
 
-	hash, ok := results[0].Value.(string)
-	require.True(t, ok)
-	expected := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
-	if diff := cmp.Diff(expected, hash); diff != "" {
-		t.Fatalf("sha256 hash mismatch (-want +got):\n%s", diff)
+	if diff := cmp.Diff(first, again); diff != "" {
+		t.Errorf("same seed and plan key should reproduce the password (-want +got):\n%s", diff)
+	}
+	for name, got := range map[string]string{"label": other, "plan key": newPlan} {
+		if got == first {
+			t.Errorf("a different %s should change the password", name)
+		}
+	}
+
+	// The seed is saved, so a fresh process reading the store reproduces it
+	useCryptoStore(t, openCryptoTestStore(t, dir))
+	replanned, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	if diff := cmp.Diff(first, replanned); diff != "" {
+		t.Errorf("reopened store should reproduce the password (-want +got):\n%s", diff)
+	}
+
+	// Knowing the plan key is not enough: another seed gives another password
+	useCryptoStore(t, openCryptoTestStore(t, t.TempDir()))
+	otherSeed, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	if otherSeed == first {
+		t.Error("a different seed should change the password")
+	}
+}
+
+func TestCryptoPasswordWithoutStoreIsRandom(t *testing.T) {
+	useCryptoStore(t, nil)
+
+	first, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	again, err := resolveCrypto(t, "crypto.password", cryptoTestPlanKey, map[string]any{"label": "db"})
+	require.NoError(t, err)
+	if first == again {
+		t.Error("without a store, passwords should come from crypto/rand")
+	}
+}
+
+func TestCryptoUUID(t *testing.T) {
+	useCryptoStore(t, nil)
+
+	tests := []struct {
+		version string
+		pattern string
+	}{
+		{"v4", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"v7", `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := resolveCrypto(t, "crypto.uuid", cryptoTestPlanKey, map[string]any{"version": tt.version})
+			require.NoError(t, err)
+			if !regexp.MustCompile(tt.pattern).MatchString(got) {
+				t.Errorf("uuid %q does not match %s", got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestCryptoBcrypt(t *testing.T) {
+	store := openCryptoTestStore(t, t.TempDir())
+	useCryptoStore(t, store)
+
+	params := map[string]any{"password": "s3cr3t-admin", "cost": 4}
+	hash, err := resolveCrypto(t, "crypto.bcrypt", cryptoTestPlanKey, params)
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(hash, "$2a$04$"), "unexpected hash %q", hash)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("s3cr3t-admin")))
+	require.Error(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("wrong")))
+
+	again, err := resolveCrypto(t, "crypto.bcrypt", cryptoTestPlanKey, params)
+	require.NoError(t, err)
+	if diff := cmp.Diff(hash, again); diff != "" {
+		t.Errorf("the stored hash should be reused (-want +got):\n%s", diff)
 	}
+
+	var stored []string
+	for _, entry := range store.List() {
+		if strings.HasPrefix(entry.Name, "sigil/crypto/bcrypt/") {
+			stored = append(stored, entry.Name)
+		}
+	}
+	require.Len(t, stored, 1)
+	require.NotContains(t, stored[0], "s3cr3t-admin")
+
+	rehashed, err := resolveCrypto(t, "crypto.bcrypt", cryptoTestPlanKey, map[string]any{"password": "s3cr3t-admin", "cost": 5})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(rehashed, "$2a$05$"), "a new cost should hash again, got %q", rehashed)
 }
 
-func TestCryptoValueDecorator_Resolve_UnknownFunction(t *testing.T) {
-	d := &CryptoValueDecorator{}
-	fn := "Ed25519"
+func TestCryptoArgon2(t *testing.T) {
+	useCryptoStore(t, nil)
 
-	results, err := d.Resolve(decorator.ValueEvalContext{}, decorator.ValueCall{
-		Path:    "crypto",
-		Primary: &fn,
-		Params: map[string]any{
-			"arg1": "hello",
-		},
+	hash, err := resolveCrypto(t, "crypto.argon2", cryptoTestPlanKey, map[string]any{
+		"password": "s3cr3t-admin", "iterations": 1, "memory": 8 * 1024, "parallelism": 1,
 	})
 	require.NoError(t, err)
-	require.Len(t, results, 1)
-	require.Error(t, results[0].Error)
 
-	if diff := cmp.Diff("unsupported crypto method: Ed25519", results[0].Error.Error()); diff != "" {
-		t.Fatalf("error mismatch (-want +got):\n%s", diff)
+	fields := strings.Split(hash, "$")
+	require.Len(t, fields, 6, "unexpected hash %q", hash)
+	if diff := cmp.Diff([]string{"", "argon2id", "v=19", "m=8192,t=1,p=1"}, fields[:4]); diff != "" {
+		t.Errorf("hash parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	require.NoError(t, err)
+	want := argon2.IDKey([]byte("s3cr3t-admin"), salt, 1, 8*1024, 1, 32)
+	if diff := cmp.Diff(base64.RawStdEncoding.EncodeToString(want), fields[5]); diff != "" {
+		t.Errorf("hash does not verify (-want +got):\n%s", diff)
+	}
+}
+
+func TestCryptoRandomBoundToCall(t *testing.T) {
+	seed := []byte("crypto-test-seed")
+	read := func(seed, planKey []byte, path string, params map[string]any) [32]byte {
+		var out [32]byte
+		_, err := cryptoRandom(seed, planKey, path, params).Read(out[:])
+		require.NoError(t, err)
+		return out
+	}
+
+	base := read(seed, cryptoTestPlanKey, "crypto.password", map[string]any{"length": 12, "label": "a"})
+	if base != read(seed, cryptoTestPlanKey, "crypto.password", map[string]any{"label": "a", "length": 12}) {
+		t.Error("parameter order should not change the stream")
+	}
+	if base == read(seed, cryptoTestPlanKey, "crypto.uuid", map[string]any{"length": 12, "label": "a"}) {
+		t.Error("a different method should change the stream")
+	}
+	if base == read([]byte("other-seed"), cryptoTestPlanKey, "crypto.password", map[string]any{"length": 12, "label": "a"}) {
+		t.Error("a different seed should change the stream")
 	}
 }
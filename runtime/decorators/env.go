@@ -2,6 +2,8 @@ package decorators
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/core/invariant"
@@ -31,6 +33,14 @@ import (
 //
 // @env resolves at plan time by reading from the current session's environment.
 // The resolved value is stored in the vault with TransportSensitive=true.
+//
+// # Sensitivity
+//
+// Variables whose names look like credentials (GITHUB_TOKEN, DB_PASSWORD,
+// AWS_SECRET_ACCESS_KEY, DATABASE_DSN) are secret and scrubbed from output, as
+// are values that embed credentials (DATABASE_URL=postgres://app:pw@db/app).
+// All others (HOME, USER, AWS_REGION) are sensitive: masked in plans, visible
+// in output.
 type EnvDecorator struct{}
 
 // Descriptor returns the decorator metadata.
@@ -47,11 +57,70 @@ func (d *EnvDecorator) Descriptor() decorator.Descriptor {
 		Returns(types.TypeString, "Value of the environment variable").
 		TransportScope(decorator.TransportScopeAny).
 		TransportSensitive(). // Values are tied to the session where they were resolved
+		Sensitivity(decorator.SensitivitySensitive).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
 }
 
+// credentialNameParts are name segments that mark an environment variable as a credential.
+var credentialNameParts = map[string]bool{
+	"TOKEN":       true,
+	"SECRET":      true,
+	"PASSWORD":    true,
+	"PASSWD":      true,
+	"PASSPHRASE":  true,
+	"KEY":         true,
+	"APIKEY":      true,
+	"CREDENTIAL":  true,
+	"CREDENTIALS": true,
+	"PRIVATE":     true,
+	"DSN":         true,
+	"COOKIE":      true,
+}
+
+// Classify implements decorator.Classifier: credential-like names are secret.
+func (d *EnvDecorator) Classify(call decorator.ValueCall) decorator.Sensitivity {
+	if call.Primary == nil || !isCredentialName(*call.Primary) {
+		return decorator.SensitivitySensitive
+	}
+	return decorator.SensitivitySecret
+}
+
+// ClassifyValue implements decorator.ValueClassifier: connection strings that
+// carry a password are secret whatever the variable is called.
+func (d *EnvDecorator) ClassifyValue(_ decorator.ValueCall, value any) decorator.Sensitivity {
+	if s, ok := value.(string); ok && hasEmbeddedCredentials(s) {
+		return decorator.SensitivitySecret
+	}
+	return decorator.SensitivityPublic
+}
+
+// embeddedCredentials matches URL userinfo with a password (scheme://user:pw@,
+// redis://:pw@), scheme-less DSNs (user:pw@tcp(host)/db) and key/value
+// connection strings (host=db password=pw).
+var embeddedCredentials = regexp.MustCompile(`(?i)(^|://)[^\s:/@]*:[^\s/@]+@|(^|[\s;])password=[^\s;]`)
+
+// hasEmbeddedCredentials reports whether a value looks like a connection
+// string with a password in it.
+func hasEmbeddedCredentials(value string) bool {
+	return embeddedCredentials.MatchString(value)
+}
+
+// isCredentialName reports whether an environment variable name contains a
+// credential segment, splitting on '_', '-' and '.' (GITHUB_TOKEN, db.password).
+func isCredentialName(name string) bool {
+	parts := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	for _, part := range parts {
+		if credentialNameParts[part] {
+			return true
+		}
+	}
+	return false
+}
+
 // Resolve implements the Value interface with batch support.
 // @env batches all env var lookups into a single Session.Env() call.
 func (d *EnvDecorator) Resolve(ctx decorator.ValueEvalContext, calls ...decorator.ValueCall) ([]decorator.ResolveResult, error) {
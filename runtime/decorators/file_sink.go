@@ -22,6 +22,12 @@ import (
 //	echo "hello" > @file("output.txt")    # Overwrite (atomic)
 //	echo "world" >> @file("output.txt")   # Append
 //	cat < @file("input.txt")              # Read
+//
+// Secrets written to the file are replaced with their DisplayIDs unless the
+// secrets parameter says otherwise:
+//
+//	kubectl config view --raw > @file("kubeconfig", secrets="passthrough")
+//	./report.sh > @file("report.txt", secrets="fail")
 type FileSinkDecorator struct {
 	params map[string]any
 }
@@ -43,6 +49,10 @@ func (d *FileSinkDecorator) Descriptor() decorator.Descriptor {
 		ParamInt("perm", "File permissions (octal, default 0644)").
 		Default(0o644).
 		Done().
+		ParamEnum(decorator.SinkSecretsParam, "Secrets in written output: scrub to DisplayIDs, passthrough unchanged, or fail").
+		Values(string(decorator.SinkSecretsScrub), string(decorator.SinkSecretsPassthrough), string(decorator.SinkSecretsFail)).
+		Default(string(decorator.SinkSecretsScrub)).
+		Done().
 		Block(decorator.BlockForbidden).
 		TransportScope(decorator.TransportScopeAny).
 		Roles(decorator.RoleEndpoint).
@@ -45,6 +45,22 @@ func (s *sessionCallTracker) Get(ctx context.Context, path string) ([]byte, erro
 	return s.delegate.Get(ctx, path)
 }
 
+func (s *sessionCallTracker) PutStream(ctx context.Context, src io.Reader, path string, perm fs.FileMode) error {
+	s.mu.Lock()
+	s.puts = append(s.puts, path)
+	s.mu.Unlock()
+
+	return s.delegate.PutStream(ctx, src, path, perm)
+}
+
+func (s *sessionCallTracker) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	s.mu.Lock()
+	s.gets = append(s.gets, path)
+	s.mu.Unlock()
+
+	return s.delegate.GetStream(ctx, path, dst)
+}
+
 func (s *sessionCallTracker) Env() map[string]string {
 	return s.delegate.Env()
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"strings"
@@ -138,6 +139,14 @@ func (s *isolatedSession) Get(ctx context.Context, path string) ([]byte, error)
 	return s.parent.Get(ctx, path)
 }
 
+func (s *isolatedSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *isolatedSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *isolatedSession) Env() map[string]string {
 	return s.parent.Env()
 }
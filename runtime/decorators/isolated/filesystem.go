@@ -3,6 +3,7 @@ package isolated
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -144,6 +145,14 @@ func (s *filesystemReadonlySession) Get(ctx context.Context, path string) ([]byt
 	return s.parent.Get(ctx, path)
 }
 
+func (s *filesystemReadonlySession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *filesystemReadonlySession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *filesystemReadonlySession) Env() map[string]string {
 	return s.parent.Env()
 }
@@ -188,6 +197,14 @@ func (s *filesystemEphemeralSession) Get(ctx context.Context, path string) ([]by
 	return s.parent.Get(ctx, path)
 }
 
+func (s *filesystemEphemeralSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *filesystemEphemeralSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *filesystemEphemeralSession) Env() map[string]string {
 	return s.parent.Env()
 }
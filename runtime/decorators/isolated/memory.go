@@ -3,6 +3,7 @@ package isolated
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -81,6 +82,14 @@ func (s *memoryLockSession) Get(ctx context.Context, path string) ([]byte, error
 	return s.parent.Get(ctx, path)
 }
 
+func (s *memoryLockSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *memoryLockSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *memoryLockSession) Env() map[string]string {
 	return s.parent.Env()
 }
@@ -3,6 +3,7 @@ package isolated
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -81,6 +82,14 @@ func (s *networkLoopbackSession) Get(ctx context.Context, path string) ([]byte,
 	return s.parent.Get(ctx, path)
 }
 
+func (s *networkLoopbackSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *networkLoopbackSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *networkLoopbackSession) Env() map[string]string {
 	return s.parent.Env()
 }
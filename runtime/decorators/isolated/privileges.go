@@ -3,6 +3,7 @@ package isolated
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -84,6 +85,14 @@ func (s *privilegesDropSession) Get(ctx context.Context, path string) ([]byte, e
 	return s.parent.Get(ctx, path)
 }
 
+func (s *privilegesDropSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *privilegesDropSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *privilegesDropSession) Env() map[string]string {
 	return s.parent.Env()
 }
@@ -3,6 +3,7 @@ package isolated
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"testing"
 
@@ -103,6 +104,14 @@ func (s *recordingSession) Get(context.Context, string) ([]byte, error) {
 	return nil, nil
 }
 
+func (s *recordingSession) PutStream(context.Context, io.Reader, string, fs.FileMode) error {
+	return nil
+}
+
+func (s *recordingSession) GetStream(context.Context, string, io.Writer) error {
+	return nil
+}
+
 func (s *recordingSession) Env() map[string]string {
 	return map[string]string{}
 }
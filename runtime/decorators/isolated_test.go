@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
@@ -291,6 +292,14 @@ func (s *recordingDialerSession) Get(context.Context, string) ([]byte, error) {
 	return nil, nil
 }
 
+func (s *recordingDialerSession) PutStream(context.Context, io.Reader, string, fs.FileMode) error {
+	return nil
+}
+
+func (s *recordingDialerSession) GetStream(context.Context, string, io.Writer) error {
+	return nil
+}
+
 func (s *recordingDialerSession) Env() map[string]string {
 	return map[string]string{}
 }
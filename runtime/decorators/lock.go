@@ -0,0 +1,373 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/invariant"
+)
+
+// LockDecorator implements the @exec.lock execution decorator.
+// Serializes its block across processes that use the same lock name.
+//
+// Local sessions use an advisory file lock in a private per-user directory
+// under the system temp directory, so local runs serialize per user; the
+// kernel releases the lock if the holder dies. Remote sessions (e.g. inside
+// @ssh.connect) create the lock file atomically in the remote TMPDIR; a holder
+// recorded with this host's name and a dead PID is treated as stale and broken.
+type LockDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *LockDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("exec.lock").
+		Summary("Run block while holding a named cross-process lock").
+		Roles(decorator.RoleWrapper).
+		ParamString("name", "Lock name shared by every run that must not overlap").
+		Required().
+		Examples("deploy-prod").
+		Done().
+		ParamDuration("wait", "How long to wait for the lock before failing (0 fails immediately)").
+		Default("0s").
+		Examples("30s", "5m").
+		Done().
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+func (d *LockDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &lockNode{next: next, params: params}
+}
+
+// lockPollInterval is how often a contended lock is retried. Overridden in tests.
+var lockPollInterval = 200 * time.Millisecond
+
+var lockNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+type lockNode struct {
+	next   decorator.ExecNode
+	params map[string]any
+}
+
+type lockConfig struct {
+	Name string        `decorator:"name"`
+	Wait time.Duration `decorator:"wait"`
+}
+
+// lockOwner identifies the process holding a lock. It is stored in the lock
+// file so contenders can name the holder and detect stale locks.
+type lockOwner struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	User       string    `json:"user,omitempty"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	Token      string    `json:"token"`
+
+	raw []byte // Record as read from the lock file
+}
+
+func (o *lockOwner) String() string {
+	if o == nil {
+		return "an unknown holder"
+	}
+	who := o.Hostname
+	if o.User != "" {
+		who = o.User + "@" + o.Hostname
+	}
+	return fmt.Sprintf("%s (pid %d) since %s", who, o.PID, o.AcquiredAt.Format(time.RFC3339))
+}
+
+// lockBackend acquires and releases one named lock.
+type lockBackend interface {
+	// kind names the mechanism for telemetry ("flock", "file").
+	kind() string
+	// tryAcquire makes one non-blocking attempt. When the lock is held by
+	// someone else it returns false and the recorded holder (nil if unknown).
+	tryAcquire(ctx context.Context, owner lockOwner) (bool, *lockOwner, error)
+	// breakStale removes a lock whose holder is known to be dead.
+	breakStale(ctx context.Context, holder lockOwner) error
+	release(ctx context.Context) error
+}
+
+// Execute implements the ExecNode interface.
+func (n *lockNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	if n.next == nil {
+		return decorator.Result{ExitCode: 0}, nil
+	}
+
+	invariant.NotNil(ctx.Session, "ctx.Session")
+
+	cfg, _, err := decorator.DecodeInto[lockConfig](
+		(&LockDecorator{}).Descriptor().Schema,
+		nil,
+		n.params,
+	)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if !lockNamePattern.MatchString(cfg.Name) {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.lock name %q must match %s", cfg.Name, lockNamePattern)
+	}
+	if cfg.Wait < 0 {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.lock wait must be >= 0")
+	}
+
+	var backend lockBackend
+	if ctx.Session.TransportScope() == decorator.TransportScopeLocal {
+		backend = newLocalLock(cfg.Name)
+	} else {
+		backend = &sessionFileLock{session: ctx.Session, path: remoteLockPath(ctx.Session, cfg.Name)}
+	}
+
+	owner, err := currentLockOwner()
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.lock: %w", err)
+	}
+
+	attrs := map[string]string{"name": cfg.Name, "backend": backend.kind()}
+	emit := func() {
+		ctx.Emit(decorator.Event{Decorator: "exec.lock", Kind: "lock", Attrs: attrs})
+	}
+
+	waitStart := time.Now()
+	stale, err := acquireLock(ctx.Context, backend, owner, cfg.Wait)
+	attrs["waited"] = time.Since(waitStart).Round(time.Millisecond).String()
+	if stale != nil {
+		attrs["stale_holder"] = stale.String()
+	}
+	if err != nil {
+		var held *lockHeldError
+		switch {
+		case errors.As(err, &held):
+			attrs["outcome"] = "timeout"
+			attrs["holder"] = held.holder.String()
+			emit()
+			return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.lock %q: %w", cfg.Name, err)
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			attrs["outcome"] = "canceled"
+			emit()
+			return decorator.Result{ExitCode: decorator.ExitCanceled}, err
+		default:
+			attrs["outcome"] = "error"
+			emit()
+			return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@exec.lock %q: %w", cfg.Name, err)
+		}
+	}
+
+	heldStart := time.Now()
+	result, execErr := n.next.Execute(ctx)
+
+	// Release even when the block was canceled so the lock never outlives the run.
+	releaseCtx := context.WithoutCancel(contextOrBackground(ctx.Context))
+	releaseErr := backend.release(releaseCtx)
+	attrs["held"] = time.Since(heldStart).Round(time.Millisecond).String()
+	attrs["outcome"] = "released"
+	emit()
+
+	if releaseErr != nil {
+		writeNotice(ctx.Stderr, "Warning: @exec.lock %q: failed to release: %v\n", cfg.Name, releaseErr)
+	}
+	return result, execErr
+}
+
+// lockHeldError reports that the wait expired while another process held the lock.
+type lockHeldError struct {
+	holder *lockOwner
+	wait   time.Duration
+}
+
+func (e *lockHeldError) Error() string {
+	if e.wait == 0 {
+		return fmt.Sprintf("held by %s", e.holder)
+	}
+	return fmt.Sprintf("held by %s; gave up after %s", e.holder, e.wait)
+}
+
+// acquireLock retries until the lock is taken, the wait expires, or ctx ends.
+// It returns the stale holder it broke, if any.
+func acquireLock(ctx context.Context, backend lockBackend, owner lockOwner, wait time.Duration) (*lockOwner, error) {
+	ctx = contextOrBackground(ctx)
+	deadline := time.Now().Add(wait)
+	var broken *lockOwner
+
+	for {
+		acquired, holder, err := backend.tryAcquire(ctx, owner)
+		if err != nil {
+			return broken, err
+		}
+		if acquired {
+			return broken, nil
+		}
+
+		if holder != nil && broken == nil && holderIsStale(*holder, owner.Hostname) {
+			if err := backend.breakStale(ctx, *holder); err != nil {
+				return broken, fmt.Errorf("break stale lock held by %s: %w", holder, err)
+			}
+			stale := *holder
+			broken = &stale
+			continue
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return broken, &lockHeldError{holder: holder, wait: wait}
+		}
+		if err := waitContext(ctx, min(lockPollInterval, remaining)); err != nil {
+			return broken, err
+		}
+	}
+}
+
+// holderIsStale reports whether holder ran on this host and its process is gone.
+// Holders on other hosts cannot be probed and are never considered stale.
+func holderIsStale(holder lockOwner, hostname string) bool {
+	if holder.Hostname == "" || holder.Hostname != hostname || holder.PID <= 0 {
+		return false
+	}
+	return !processAlive(holder.PID)
+}
+
+func currentLockOwner() (lockOwner, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return lockOwner{}, fmt.Errorf("resolve hostname: %w", err)
+	}
+
+	name := os.Getenv("USER")
+	if name == "" {
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return lockOwner{}, fmt.Errorf("generate lock token: %w", err)
+	}
+
+	return lockOwner{
+		PID:        os.Getpid(),
+		Hostname:   hostname,
+		User:       name,
+		AcquiredAt: time.Now().UTC().Truncate(time.Second),
+		Token:      hex.EncodeToString(token),
+	}, nil
+}
+
+func encodeLockOwner(owner lockOwner) []byte {
+	data, err := json.Marshal(owner)
+	invariant.Check(err == nil, "marshal lock owner: %v", err)
+	return data
+}
+
+func decodeLockOwner(data []byte) *lockOwner {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	var owner lockOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil
+	}
+	owner.raw = data
+	return &owner
+}
+
+// remoteLockPath places the lock in the session's TMPDIR, falling back to
+// /tmp when the remote environment does not set one.
+func remoteLockPath(session decorator.Session, name string) string {
+	dir := session.Env()["TMPDIR"]
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return path.Join(dir, "sigil-"+name+".lock")
+}
+
+// Exit codes used by the remote lock scripts.
+const (
+	remoteLockHeld = 73
+)
+
+// remoteAcquireScript atomically creates the lock file with noclobber and
+// writes the owner record from stdin. It exits 73 when the file already exists.
+const remoteAcquireScript = `if ( set -C; cat > "$1" ) 2>/dev/null; then exit 0; elif [ -e "$1" ]; then exit 73; else exit 1; fi`
+
+// remoteReleaseScript removes the lock only if it still holds the given owner record.
+const remoteReleaseScript = `if [ "$(cat "$1" 2>/dev/null)" = "$2" ]; then rm -f "$1"; fi`
+
+// sessionFileLock is an atomic lock file created through a session, used for
+// remote transports where advisory locks are unavailable.
+type sessionFileLock struct {
+	session decorator.Session
+	path    string
+	owner   []byte
+}
+
+func (l *sessionFileLock) kind() string { return "file" }
+
+func (l *sessionFileLock) tryAcquire(ctx context.Context, owner lockOwner) (bool, *lockOwner, error) {
+	record := encodeLockOwner(owner)
+	result, err := l.session.Run(ctx, []string{"sh", "-c", remoteAcquireScript, "sh", l.path}, decorator.RunOpts{
+		Stdin: bytes.NewReader(record),
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	switch result.ExitCode {
+	case 0:
+		l.owner = record
+		return true, nil, nil
+	case remoteLockHeld:
+		data, err := l.session.Get(ctx, l.path)
+		if err != nil {
+			// Released between the attempt and the read; retry on the next poll.
+			return false, nil, nil
+		}
+		return false, decodeLockOwner(data), nil
+	default:
+		return false, nil, fmt.Errorf("create %s failed with exit code %d: %s", l.path, result.ExitCode, bytes.TrimSpace(result.Stderr))
+	}
+}
+
+func (l *sessionFileLock) breakStale(ctx context.Context, holder lockOwner) error {
+	_, err := l.session.Run(ctx, []string{"sh", "-c", remoteReleaseScript, "sh", l.path, string(holder.raw)}, decorator.RunOpts{})
+	return err
+}
+
+func (l *sessionFileLock) release(ctx context.Context) error {
+	if l.owner == nil {
+		return nil
+	}
+	result, err := l.session.Run(ctx, []string{"sh", "-c", remoteReleaseScript, "sh", l.path, string(l.owner)}, decorator.RunOpts{})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("remove %s failed with exit code %d", l.path, result.ExitCode)
+	}
+	l.owner = nil
+	return nil
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+func init() {
+	err := decorator.Register("exec.lock", &LockDecorator{})
+	invariant.Check(err == nil, "failed to register @exec.lock decorator: %v", err)
+}
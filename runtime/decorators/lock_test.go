@@ -0,0 +1,291 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+func fastLockPolling(t *testing.T) {
+	t.Helper()
+	original := lockPollInterval
+	lockPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { lockPollInterval = original })
+}
+
+func uniqueLockName(t *testing.T) string {
+	t.Helper()
+	return fmt.Sprintf("test-%d-%d", os.Getpid(), time.Now().UnixNano())
+}
+
+func runLocked(ctx context.Context, name, wait string, sink decorator.EventSink, block func() (decorator.Result, error)) (decorator.Result, error) {
+	node := (&LockDecorator{}).Wrap(&testExecNode{execute: func(decorator.ExecContext) (decorator.Result, error) {
+		return block()
+	}}, map[string]any{"name": name, "wait": wait})
+	return node.Execute(decorator.ExecContext{
+		Context: ctx,
+		Session: decorator.NewLocalSession(),
+		Events:  sink,
+	})
+}
+
+// deadPID returns the PID of a process that has already exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot spawn helper process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestLockExcludesConcurrentHolders(t *testing.T) {
+	fastLockPolling(t)
+	name := uniqueLockName(t)
+
+	acquired := make(chan struct{})
+	releaseHolder := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := runLocked(context.Background(), name, "0s", nil, func() (decorator.Result, error) {
+			close(acquired)
+			<-releaseHolder
+			return decorator.Result{}, nil
+		})
+		done <- err
+	}()
+	<-acquired
+
+	ran := false
+	result, err := runLocked(context.Background(), name, "20ms", nil, func() (decorator.Result, error) {
+		ran = true
+		return decorator.Result{}, nil
+	})
+	if ran {
+		t.Fatal("second holder must not run while the lock is held")
+	}
+	if result.ExitCode != decorator.ExitFailure || err == nil {
+		t.Fatalf("expected contention failure, got exit=%d err=%v", result.ExitCode, err)
+	}
+	hostname, _ := os.Hostname()
+	for _, want := range []string{name, "held by", hostname, fmt.Sprintf("pid %d", os.Getpid()), "gave up after 20ms"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+
+	close(releaseHolder)
+	if err := <-done; err != nil {
+		t.Fatalf("holder failed: %v", err)
+	}
+
+	if _, err := runLocked(context.Background(), name, "0s", nil, func() (decorator.Result, error) {
+		ran = true
+		return decorator.Result{}, nil
+	}); err != nil || !ran {
+		t.Fatalf("expected lock to be free after release, ran=%v err=%v", ran, err)
+	}
+}
+
+func TestLockWaitsForRelease(t *testing.T) {
+	fastLockPolling(t)
+	name := uniqueLockName(t)
+
+	acquired := make(chan struct{})
+	go func() {
+		_, _ = runLocked(context.Background(), name, "0s", nil, func() (decorator.Result, error) {
+			close(acquired)
+			time.Sleep(50 * time.Millisecond)
+			return decorator.Result{}, nil
+		})
+	}()
+	<-acquired
+
+	sink := &recordingSink{}
+	_, err := runLocked(context.Background(), name, "5s", sink, func() (decorator.Result, error) {
+		return decorator.Result{}, nil
+	})
+	if err != nil {
+		t.Fatalf("expected lock after waiting, got %v", err)
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("expected one lock event, got %+v", sink.events)
+	}
+	attrs := sink.events[0].Attrs
+	if attrs["outcome"] != "released" || attrs["backend"] != "flock" || attrs["held"] == "" {
+		t.Errorf("unexpected lock event attrs: %v", attrs)
+	}
+	if waited, err := time.ParseDuration(attrs["waited"]); err != nil || waited <= 0 {
+		t.Errorf("expected positive wait duration, got %q", attrs["waited"])
+	}
+}
+
+func TestLockReleasedAfterBlockFailure(t *testing.T) {
+	name := uniqueLockName(t)
+
+	result, _ := runLocked(context.Background(), name, "0s", nil, func() (decorator.Result, error) {
+		return decorator.Result{ExitCode: 7}, nil
+	})
+	if result.ExitCode != 7 {
+		t.Fatalf("exit code: got %d, want 7", result.ExitCode)
+	}
+
+	if _, err := runLocked(context.Background(), name, "0s", nil, func() (decorator.Result, error) {
+		return decorator.Result{}, nil
+	}); err != nil {
+		t.Fatalf("expected lock to be released after failure, got %v", err)
+	}
+}
+
+func TestLockRejectsInvalidName(t *testing.T) {
+	_, err := runLocked(context.Background(), "../etc/passwd", "0s", nil, func() (decorator.Result, error) {
+		t.Fatal("block must not run")
+		return decorator.Result{}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "must match") {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+}
+
+func newTestFileLock(t *testing.T) *sessionFileLock {
+	t.Helper()
+	return &sessionFileLock{
+		session: decorator.NewLocalSession(),
+		path:    filepath.Join(t.TempDir(), "sigil-test.lock"),
+	}
+}
+
+func TestSessionFileLockAcquireAndRelease(t *testing.T) {
+	lock := newTestFileLock(t)
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+
+	ok, holder, err := lock.tryAcquire(context.Background(), owner)
+	if err != nil || !ok || holder != nil {
+		t.Fatalf("first acquire: ok=%v holder=%v err=%v", ok, holder, err)
+	}
+
+	other := &sessionFileLock{session: lock.session, path: lock.path}
+	ok, holder, err = other.tryAcquire(context.Background(), owner)
+	if err != nil || ok {
+		t.Fatalf("second acquire must fail: ok=%v err=%v", ok, err)
+	}
+	if holder == nil || holder.Token != owner.Token {
+		t.Fatalf("expected holder record, got %+v", holder)
+	}
+
+	if err := other.release(context.Background()); err != nil {
+		t.Fatalf("release by non-holder: %v", err)
+	}
+	if _, err := os.Stat(lock.path); err != nil {
+		t.Fatalf("non-holder release must not remove lock: %v", err)
+	}
+
+	if err := lock.release(context.Background()); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := os.Stat(lock.path); !os.IsNotExist(err) {
+		t.Fatalf("expected lock file removed, stat err = %v", err)
+	}
+}
+
+func TestSessionFileLockBreaksStaleLocalHolder(t *testing.T) {
+	lock := newTestFileLock(t)
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+
+	stale := owner
+	stale.PID = deadPID(t)
+	stale.Token = "stale"
+	if err := os.WriteFile(lock.path, encodeLockOwner(stale), 0o644); err != nil {
+		t.Fatalf("write stale lock: %v", err)
+	}
+
+	broken, err := acquireLock(context.Background(), lock, owner, 0)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if broken == nil || broken.PID != stale.PID {
+		t.Fatalf("expected stale holder %d to be broken, got %+v", stale.PID, broken)
+	}
+
+	data, err := os.ReadFile(lock.path)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if got := decodeLockOwner(data); got == nil || got.Token != owner.Token {
+		t.Fatalf("expected lock to be owned by us, got %s", data)
+	}
+}
+
+func TestSessionFileLockKeepsRemoteHostHolder(t *testing.T) {
+	fastLockPolling(t)
+	lock := newTestFileLock(t)
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+
+	other := lockOwner{
+		PID:        deadPID(t),
+		Hostname:   "ci-runner-7",
+		User:       "alice",
+		AcquiredAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Token:      "other",
+	}
+	if err := os.WriteFile(lock.path, encodeLockOwner(other), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	_, err = acquireLock(context.Background(), lock, owner, 15*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected timeout while another host holds the lock")
+	}
+	want := fmt.Sprintf("held by alice@ci-runner-7 (pid %d) since 2026-10-18T09:30:00Z; gave up after 15ms", other.PID)
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLockCancellationWhileWaiting(t *testing.T) {
+	fastLockPolling(t)
+	lock := newTestFileLock(t)
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	if ok, _, err := lock.tryAcquire(context.Background(), owner); !ok || err != nil {
+		t.Fatalf("acquire: ok=%v err=%v", ok, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	waiter := &sessionFileLock{session: lock.session, path: lock.path}
+	if _, err := acquireLock(ctx, waiter, owner, time.Minute); err == nil || ctx.Err() == nil {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
+
+func TestRemoteLockPathUsesSessionTempDir(t *testing.T) {
+	session := decorator.NewLocalSession().WithEnv(map[string]string{"TMPDIR": "/var/tmp/user"})
+	if diff := cmp.Diff("/var/tmp/user/sigil-deploy.lock", remoteLockPath(session, "deploy")); diff != "" {
+		t.Errorf("remote lock path mismatch (-want +got):\n%s", diff)
+	}
+
+	session = decorator.NewLocalSession().WithEnv(map[string]string{"TMPDIR": ""})
+	if diff := cmp.Diff("/tmp/sigil-deploy.lock", remoteLockPath(session, "deploy")); diff != "" {
+		t.Errorf("fallback lock path mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,129 @@
+//go:build !windows
+
+package decorators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// flockLock is an advisory lock on a file in a per-user directory under the
+// system temp directory. The kernel drops the lock when the holding process
+// exits, so a crashed holder never leaves the lock stuck; the owner record is
+// informational.
+//
+// The directory is created 0700 and must be owned by the current user, and
+// the file is opened without following symlinks, so another local user
+// cannot plant a link that makes a lock truncate or overwrite their target.
+type flockLock struct {
+	path string
+	file *os.File
+}
+
+func newLocalLock(name string) lockBackend {
+	dir := filepath.Join(os.TempDir(), "sigil-"+strconv.Itoa(os.Getuid()))
+	return &flockLock{path: filepath.Join(dir, "lock-"+name+".lock")}
+}
+
+func (l *flockLock) kind() string { return "flock" }
+
+func (l *flockLock) tryAcquire(_ context.Context, owner lockOwner) (bool, *lockOwner, error) {
+	f, err := openLockFile(l.path)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		data, _ := io.ReadAll(f)
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, decodeLockOwner(data), nil
+		}
+		return false, nil, err
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt(encodeLockOwner(owner), 0)
+	}
+	l.file = f
+	return true, nil, nil
+}
+
+// openLockFile opens the lock file, creating it and its private directory if
+// they do not exist yet.
+func openLockFile(path string) (*os.File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := checkLockDir(dir); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|syscall.O_NOFOLLOW, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || !ownedByCurrentUser(info) {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock file %s is not a regular file owned by the current user", path)
+	}
+	return f, nil
+}
+
+// checkLockDir verifies the lock directory is a real directory owned by the
+// current user and closed to everyone else.
+func checkLockDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("lock directory %s is not a directory", dir)
+	}
+	if !ownedByCurrentUser(info) {
+		return fmt.Errorf("lock directory %s is not owned by the current user", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("lock directory %s must not be accessible by group or others (mode %04o)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == uint32(os.Getuid())
+}
+
+// breakStale is never needed for flock: a dead holder's lock is already gone.
+func (l *flockLock) breakStale(context.Context, lockOwner) error {
+	return nil
+}
+
+func (l *flockLock) release(context.Context) error {
+	if l.file == nil {
+		return nil
+	}
+	f := l.file
+	l.file = nil
+	_ = f.Truncate(0)
+	unlockErr := syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return errors.Join(unlockErr, f.Close())
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
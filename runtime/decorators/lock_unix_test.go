@@ -0,0 +1,83 @@
+//go:build !windows
+
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlockLockFileIsPrivate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sigil-locks")
+	lock := &flockLock{path: filepath.Join(dir, "lock-private.lock")}
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+
+	ok, _, err := lock.tryAcquire(context.Background(), owner)
+	if err != nil || !ok {
+		t.Fatalf("acquire: ok=%v err=%v", ok, err)
+	}
+	t.Cleanup(func() { _ = lock.release(context.Background()) })
+
+	for path, want := range map[string]os.FileMode{dir: 0o700, lock.path: 0o600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %o, want %o", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestFlockLockRefusesSymlinkedLockFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sigil-locks")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	victim := filepath.Join(t.TempDir(), "victim")
+	if err := os.WriteFile(victim, []byte("keep"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	lock := &flockLock{path: filepath.Join(dir, "lock-link.lock")}
+	if err := os.Symlink(victim, lock.path); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	if ok, _, err := lock.tryAcquire(context.Background(), owner); err == nil || ok {
+		_ = lock.release(context.Background())
+		t.Fatalf("acquire through symlink must fail: ok=%v err=%v", ok, err)
+	}
+	if data, _ := os.ReadFile(victim); string(data) != "keep" {
+		t.Errorf("symlink target was modified: %q", data)
+	}
+}
+
+func TestFlockLockRefusesSharedDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sigil-locks")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	owner, err := currentLockOwner()
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	lock := &flockLock{path: filepath.Join(dir, "lock-shared.lock")}
+	_, _, err = lock.tryAcquire(context.Background(), owner)
+	if err == nil || !strings.Contains(err.Error(), "must not be accessible by group or others") {
+		t.Fatalf("expected shared directory error, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build windows
+
+package decorators
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// fileLock is an exclusively created lock file in the system temp directory.
+// Windows has no flock, so stale holders are detected by PID and hostname.
+type fileLock struct {
+	path  string
+	owned bool
+}
+
+func newLocalLock(name string) lockBackend {
+	return &fileLock{path: filepath.Join(os.TempDir(), "sigil-"+name+".lock")}
+}
+
+func (l *fileLock) kind() string { return "file" }
+
+func (l *fileLock) tryAcquire(_ context.Context, owner lockOwner) (bool, *lockOwner, error) {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		data, readErr := os.ReadFile(l.path)
+		if readErr != nil {
+			return false, nil, nil
+		}
+		return false, decodeLockOwner(data), nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	_, writeErr := f.Write(encodeLockOwner(owner))
+	if err := errors.Join(writeErr, f.Close()); err != nil {
+		_ = os.Remove(l.path)
+		return false, nil, err
+	}
+	l.owned = true
+	return true, nil, nil
+}
+
+func (l *fileLock) breakStale(_ context.Context, holder lockOwner) error {
+	data, err := os.ReadFile(l.path)
+	if err != nil || string(data) != string(holder.raw) {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+func (l *fileLock) release(context.Context) error {
+	if !l.owned {
+		return nil
+	}
+	l.owned = false
+	return os.Remove(l.path)
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
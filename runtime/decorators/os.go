@@ -15,6 +15,7 @@ func (d *osGetDecorator) Descriptor() decorator.Descriptor {
 		Roles(decorator.RoleProvider).
 		Returns(types.TypeString, "Current OS name").
 		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivityPublic).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
@@ -43,6 +44,7 @@ func (d *osLinuxDecorator) Descriptor() decorator.Descriptor {
 		Roles(decorator.RoleProvider).
 		Returns(types.TypeString, "\"true\" on Linux, \"false\" otherwise").
 		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivityPublic).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
@@ -76,6 +78,7 @@ func (d *osMacOSDecorator) Descriptor() decorator.Descriptor {
 		Roles(decorator.RoleProvider).
 		Returns(types.TypeString, "\"true\" on macOS, \"false\" otherwise").
 		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivityPublic).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
@@ -109,6 +112,7 @@ func (d *osWindowsDecorator) Descriptor() decorator.Descriptor {
 		Roles(decorator.RoleProvider).
 		Returns(types.TypeString, "\"true\" on Windows, \"false\" otherwise").
 		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivityPublic).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
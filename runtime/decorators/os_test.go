@@ -2,6 +2,7 @@ package decorators
 
 import (
 	"context"
+	"io"
 	"io/fs"
 	"testing"
 
@@ -29,6 +30,14 @@ func (m *mockOSSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return nil, nil
 }
 
+func (m *mockOSSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (m *mockOSSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (m *mockOSSession) Env() map[string]string {
 	return nil
 }
@@ -0,0 +1,62 @@
+package decorators
+
+import (
+	"fmt"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+)
+
+// RollbackDecorator implements the @exec.rollback execution decorator.
+//
+// A rollback block registers a compensating action for the steps that precede
+// it in the same block. The executor does not run the block when it is reached;
+// it pushes it onto the block's compensation stack instead. If a later step in
+// the same block fails or execution is canceled, registered compensations run
+// in reverse registration order (LIFO) and the original failure propagates.
+//
+//	kubectl apply -f k8s/
+//	@exec.rollback { kubectl rollout undo deployment/app }
+//
+//	kubectl create configmap temp-config
+//	@exec.rollback { kubectl delete configmap temp-config }
+//
+//	curl -f http://app/health   // on failure: delete configmap, then rollout undo
+//
+// Rollback blocks are ordinary plan steps, so they appear in the plan tree and
+// are covered by the contract hash.
+type RollbackDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *RollbackDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("exec.rollback").
+		Summary("Register a compensating action that runs if a later step fails").
+		Roles(decorator.RoleWrapper).
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+// The returned node runs the compensation block; the executor decides when.
+func (d *RollbackDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &rollbackNode{next: next}
+}
+
+// rollbackNode executes a registered compensation block.
+type rollbackNode struct {
+	next decorator.ExecNode
+}
+
+// Execute implements the ExecNode interface.
+func (n *rollbackNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	if n.next == nil {
+		return decorator.Result{ExitCode: 0}, nil
+	}
+	return n.next.Execute(ctx)
+}
+
+// Register @exec.rollback decorator with the global registry
+func init() {
+	if err := decorator.Register("exec.rollback", &RollbackDecorator{}); err != nil {
+		panic(fmt.Sprintf("failed to register @exec.rollback decorator: %v", err))
+	}
+}
@@ -274,6 +274,22 @@ func (s *sandboxSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return parent.Get(ctx, path)
 }
 
+func (s *sandboxSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	parent := s.parent
+	if s.cwd != "" {
+		parent = parent.WithWorkdir(s.cwd)
+	}
+	return parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *sandboxSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	parent := s.parent
+	if s.cwd != "" {
+		parent = parent.WithWorkdir(s.cwd)
+	}
+	return parent.GetStream(ctx, path, dst)
+}
+
 func (s *sandboxSession) Env() map[string]string {
 	return copyEnvMap(s.combinedEnv())
 }
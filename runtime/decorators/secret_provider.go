@@ -0,0 +1,159 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/types"
+	"github.com/builtwithtofu/sigil/runtime/vault/provider"
+)
+
+func init() {
+	for _, d := range []*SecretProviderDecorator{
+		NewSecretProviderDecorator(secretFileDescriptor(), provider.File{}),
+		NewSecretProviderDecorator(secretCmdDescriptor(), provider.Command{}),
+		NewSecretProviderDecorator(secretDotenvDescriptor(), provider.Dotenv{}),
+		NewSecretProviderDecorator(secretKVDescriptor(), provider.KV{}),
+	} {
+		if err := decorator.Register(d.descriptor.Path, d); err != nil {
+			panic(fmt.Sprintf("failed to register @%s decorator: %v", d.descriptor.Path, err))
+		}
+	}
+}
+
+// SecretProviderDecorator exposes a provider.Provider as a value decorator
+// (@secret.file, @secret.cmd, @secret.dotenv, @secret.kv).
+//
+// All calls to one decorator in a plan reach Resolve together and are
+// passed to the provider as one batch, like @env. Values are secret: the
+// planner tracks each as a vault expression, plans show DisplayIDs and
+// output is scrubbed.
+//
+// Providers run on the planning machine, where the secrets are kept, even
+// inside a transport block; the values can then be used in any transport.
+type SecretProviderDecorator struct {
+	descriptor decorator.Descriptor
+	provider   provider.Provider
+}
+
+// NewSecretProviderDecorator wraps p in a value decorator described by desc.
+func NewSecretProviderDecorator(desc decorator.Descriptor, p provider.Provider) *SecretProviderDecorator {
+	return &SecretProviderDecorator{descriptor: desc, provider: p}
+}
+
+// Descriptor returns the decorator metadata.
+func (d *SecretProviderDecorator) Descriptor() decorator.Descriptor {
+	return d.descriptor
+}
+
+// Resolve implements the Value interface with batch support.
+func (d *SecretProviderDecorator) Resolve(ctx decorator.ValueEvalContext, calls ...decorator.ValueCall) ([]decorator.ResolveResult, error) {
+	reqs := make([]provider.Request, len(calls))
+	for i, call := range calls {
+		reqs[i] = provider.Request{Params: call.Params}
+	}
+
+	origin := "@" + d.descriptor.Path
+	fetched := d.provider.Fetch(context.Background(), reqs)
+	results := make([]decorator.ResolveResult, len(calls))
+	for i, result := range fetched {
+		if result.Error != nil {
+			results[i] = decorator.ResolveResult{Origin: origin, Error: fmt.Errorf("%s: %w", origin, result.Error)}
+			continue
+		}
+		results[i] = decorator.ResolveResult{Value: result.Value, Origin: origin, Metadata: result.Metadata}
+	}
+
+	return results, nil
+}
+
+func secretFileDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("secret.file").
+		Summary("Read a secret from a local file").
+		Roles(decorator.RoleProvider).
+		ParamString("path", "File containing the secret").
+		Required().
+		Examples("~/.config/app/token", "/run/secrets/db_password").
+		Done().
+		ParamBool("trim", "Drop one trailing newline").
+		Default(true).
+		Done().
+		Returns(types.TypeString, "File contents").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func secretCmdDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("secret.cmd").
+		Summary("Read a secret from a helper command's stdout").
+		Roles(decorator.RoleProvider).
+		ParamArray("argv", "Helper program and arguments (no shell)").
+		ElementType(types.TypeString).
+		Required().
+		Examples(`["pass", "show", "deploy/token"]`, `["op", "read", "op://prod/db/password"]`).
+		Done().
+		ParamDuration("timeout", "How long the helper may run").
+		Default("30s").
+		Done().
+		Returns(types.TypeString, "Helper stdout without the trailing newline").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func secretDotenvDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("secret.dotenv").
+		Summary("Read a secret from a dotenv file").
+		Roles(decorator.RoleProvider).
+		ParamString("file", "Dotenv file to read").
+		Required().
+		Examples(".env", ".env.production").
+		Done().
+		ParamString("key", "Variable to return").
+		Required().
+		Examples("DATABASE_URL").
+		Done().
+		Returns(types.TypeString, "Variable value").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+func secretKVDescriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("secret.kv").
+		Summary("Read a secret from a Vault-compatible KV v2 engine ($VAULT_TOKEN)").
+		Roles(decorator.RoleProvider).
+		ParamString("path", "Secret path within the mount").
+		Required().
+		Examples("myapp/db").
+		Done().
+		ParamString("key", "Field of the secret").
+		Required().
+		Examples("password").
+		Done().
+		ParamString("mount", "KV engine mount point").
+		Default("secret").
+		Done().
+		ParamInt("version", "Secret version (0 = latest)").
+		Default(0).
+		Done().
+		ParamString("addr", "Server address; must equal $VAULT_ADDR, which receives the token").
+		Examples("https://vault.example.com:8200").
+		Done().
+		ParamBool("insecure", "Allow a plain http server address").
+		Default(false).
+		Done().
+		Returns(types.TypeString, "Field value (non-string fields as JSON)").
+		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
+		Idempotent().
+		Block(decorator.BlockForbidden).
+		Build()
+}
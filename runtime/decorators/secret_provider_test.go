@@ -0,0 +1,73 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/runtime/vault/provider"
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingProvider struct {
+	batches [][]provider.Request
+}
+
+func (p *recordingProvider) Fetch(_ context.Context, reqs []provider.Request) []provider.Result {
+	p.batches = append(p.batches, reqs)
+	results := make([]provider.Result, len(reqs))
+	for i, req := range reqs {
+		name, _ := req.Params["name"].(string)
+		if name == "" {
+			results[i].Error = errors.New("missing name")
+			continue
+		}
+		results[i].Value = "value-of-" + name
+		results[i].Metadata = decorator.SecretMetadata{Version: "v-" + name}
+	}
+	return results
+}
+
+func TestSecretProviderDecorator_ResolvesBatch(t *testing.T) {
+	p := &recordingProvider{}
+	d := NewSecretProviderDecorator(decorator.NewDescriptor("secret.test").Build(), p)
+
+	results, err := d.Resolve(decorator.ValueEvalContext{},
+		decorator.ValueCall{Params: map[string]any{"name": "a"}},
+		decorator.ValueCall{Params: map[string]any{}},
+		decorator.ValueCall{Params: map[string]any{"name": "b"}},
+	)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(p.batches)); diff != "" {
+		t.Fatalf("calls should reach the provider as one batch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("value-of-a", results[0].Value); diff != "" {
+		t.Errorf("first value mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("value-of-b", results[2].Value); diff != "" {
+		t.Errorf("third value mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("v-a", results[0].Metadata.Version); diff != "" {
+		t.Errorf("first version mismatch (-want +got):\n%s", diff)
+	}
+	if results[1].Error == nil || results[1].Error.Error() != "@secret.test: missing name" {
+		t.Errorf("expected prefixed error, got %v", results[1].Error)
+	}
+}
+
+func TestSecretProviderDecorators_AreSecret(t *testing.T) {
+	for _, path := range []string{"secret.file", "secret.cmd", "secret.dotenv", "secret.kv"} {
+		entry, ok := decorator.Global().Lookup(path)
+		if !ok {
+			t.Errorf("@%s not registered", path)
+			continue
+		}
+		if got := entry.Impl.Descriptor().Sensitivity; got != decorator.SensitivitySecret {
+			t.Errorf("@%s sensitivity = %v, want secret", path, got)
+		}
+	}
+}
@@ -2,11 +2,15 @@ package decorators
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"sync"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/core/types"
+	"github.com/builtwithtofu/sigil/runtime/secretstore"
 	"github.com/builtwithtofu/sigil/runtime/vault"
 )
 
@@ -21,20 +25,36 @@ type secretsScope struct {
 	handles map[string]vault.SecretHandle
 }
 
+// SecretsDecorator resolves @secrets.put and @secrets.get.
+//
+// put keeps values in memory for the current plan only. get returns a value
+// put earlier in the same plan, else reads it from the on-disk secret store
+// (see package secretstore), which is opened once per process.
 type SecretsDecorator struct {
 	mu     sync.RWMutex
 	scopes map[string]*secretsScope
+
+	// openStore loads the persistent store; nil store means none exists
+	openStore func() (*secretstore.Store, error)
+	storeMu   sync.Mutex
+	store     *secretstore.Store
 }
 
 func NewSecretsDecorator() *SecretsDecorator {
-	return &SecretsDecorator{scopes: make(map[string]*secretsScope)}
+	return NewSecretsDecoratorWithStore(openDefaultSecretStore)
+}
+
+// NewSecretsDecoratorWithStore creates a decorator that reads persistent
+// secrets from the store returned by open.
+func NewSecretsDecoratorWithStore(open func() (*secretstore.Store, error)) *SecretsDecorator {
+	return &SecretsDecorator{scopes: make(map[string]*secretsScope), openStore: open}
 }
 
 func (d *SecretsDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("secrets").
 		Summary("Store and retrieve encrypted secrets").
 		Roles(decorator.RoleProvider).
-		PrimaryParamString("method", "Operation to perform: put or get").
+		PrimaryParamString("method", "Operation to perform: put (current plan only) or get (also reads the secret store)").
 		Examples("put", "get").
 		Done().
 		ParamString("path", "Logical secret path").
@@ -45,6 +65,7 @@ func (d *SecretsDecorator) Descriptor() decorator.Descriptor {
 		Done().
 		Returns(types.TypeSecretHandle, "Secret handle for put, or []byte for get").
 		TransportScope(decorator.TransportScopeAny).
+		Sensitivity(decorator.SensitivitySecret).
 		Idempotent().
 		Block(decorator.BlockForbidden).
 		Build()
@@ -76,15 +97,15 @@ func (d *SecretsDecorator) Resolve(ctx decorator.ValueEvalContext, calls ...deco
 				continue
 			}
 
-			results[i] = decorator.ResolveResult{Value: handle, Origin: "@secrets", Error: nil}
+			results[i] = decorator.ResolveResult{Value: handle, Origin: secretsOrigin(method, path), Error: nil}
 		case "get":
-			value, err := d.get(scope, path)
+			value, metadata, err := d.get(scope, path)
 			if err != nil {
 				results[i] = decorator.ResolveResult{Value: nil, Origin: "@secrets", Error: err}
 				continue
 			}
 
-			results[i] = decorator.ResolveResult{Value: value, Origin: "@secrets", Error: nil}
+			results[i] = decorator.ResolveResult{Value: value, Origin: secretsOrigin(method, path), Metadata: metadata}
 		default:
 			results[i] = decorator.ResolveResult{
 				Value:  nil,
@@ -115,25 +136,94 @@ func (d *SecretsDecorator) put(scope *secretsScope, path string, value []byte) (
 	return handle, nil
 }
 
-func (d *SecretsDecorator) get(scope *secretsScope, path string) ([]byte, error) {
+// get returns the value at path. Values put in this plan have no metadata.
+func (d *SecretsDecorator) get(scope *secretsScope, path string) ([]byte, decorator.SecretMetadata, error) {
 	d.mu.RLock()
 	handle, ok := scope.handles[path]
 	d.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("secret path %q not found", path)
+		return d.getPersistent(path)
 	}
 
 	encrypted, err := scope.service.Retrieve(handle)
 	if err != nil {
-		return nil, err
+		return nil, decorator.SecretMetadata{}, err
 	}
 
 	plaintext, err := scope.service.Decrypt(encrypted)
+	if err != nil {
+		return nil, decorator.SecretMetadata{}, err
+	}
+
+	return plaintext, decorator.SecretMetadata{}, nil
+}
+
+// getPersistent reads path from the on-disk store, with the entry's version
+// and expiry.
+func (d *SecretsDecorator) getPersistent(path string) ([]byte, decorator.SecretMetadata, error) {
+	store, err := d.persistentStore()
+	if err != nil {
+		return nil, decorator.SecretMetadata{}, err
+	}
+	if store == nil {
+		return nil, decorator.SecretMetadata{}, fmt.Errorf("secret path %q not found (no secret store; add it with `sigil secrets set %s`)", path, path)
+	}
+
+	value, err := store.Get(path)
+	if errors.Is(err, secretstore.ErrNotFound) {
+		return nil, decorator.SecretMetadata{}, fmt.Errorf("secret path %q not found in %s", path, store.Path())
+	}
+	if err != nil {
+		return nil, decorator.SecretMetadata{}, err
+	}
+	entry, err := store.Stat(path)
+	if err != nil {
+		return nil, decorator.SecretMetadata{}, err
+	}
+	return value, decorator.SecretMetadata{
+		Version:   strconv.Itoa(entry.Version),
+		CreatedAt: entry.Updated,
+		ExpiresAt: entry.Expires,
+	}, nil
+}
+
+// persistentStore opens the store on first use. Failures are not cached so
+// a later plan can succeed once the key is configured.
+func (d *SecretsDecorator) persistentStore() (*secretstore.Store, error) {
+	d.storeMu.Lock()
+	defer d.storeMu.Unlock()
+
+	if d.store != nil || d.openStore == nil {
+		return d.store, nil
+	}
+	store, err := d.openStore()
 	if err != nil {
 		return nil, err
 	}
+	d.store = store
+	return store, nil
+}
 
-	return plaintext, nil
+// openDefaultSecretStore opens the store DefaultPath picks for the working
+// directory, with the key source configured in the environment.
+func openDefaultSecretStore() (*secretstore.Store, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("locate secret store: %w", err)
+	}
+	path, err := secretstore.DefaultPath(wd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	keys, err := secretstore.KeySourceFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("open secret store %s: %w", path, err)
+	}
+	return secretstore.Open(path, keys)
 }
 
 func (d *SecretsDecorator) scopeForPlan(planHash []byte) *secretsScope {
@@ -172,6 +262,11 @@ func extractSecretsMethod(call decorator.ValueCall) string {
 	return "get"
 }
 
+// secretsOrigin names a store entry for audits, e.g. @secrets.get(path="tokens/github").
+func secretsOrigin(method, path string) string {
+	return fmt.Sprintf("@secrets.%s(path=%q)", method, path)
+}
+
 func extractSecretsPath(call decorator.ValueCall) (string, error) {
 	path, ok := call.Params["path"].(string)
 	if !ok || path == "" {
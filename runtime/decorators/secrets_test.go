@@ -1,9 +1,12 @@
 package decorators
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/runtime/secretstore"
 	"github.com/builtwithtofu/sigil/runtime/vault"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -67,3 +70,59 @@ func TestSecretsDecorator_GetMissingPath(t *testing.T) {
 	require.Error(t, result[0].Error)
 	assert.Contains(t, result[0].Error.Error(), "not found")
 }
+
+func TestSecretsDecorator_GetFromStore(t *testing.T) {
+	store, err := secretstore.Open(filepath.Join(t.TempDir(), "secrets.enc"), secretstore.KeyFile(filepath.Join(t.TempDir(), "key")))
+	require.NoError(t, err)
+	require.NoError(t, store.Set("tokens/github", []byte("ghp_old")))
+	require.NoError(t, store.Set("tokens/github", []byte("ghp_from_store")))
+	expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetExpiry("tokens/github", expires))
+	require.NoError(t, store.Set("keys/deploy", []byte("stored-deploy-key")))
+
+	opens := 0
+	d := NewSecretsDecoratorWithStore(func() (*secretstore.Store, error) {
+		opens++
+		return store, nil
+	})
+	ctx := decorator.ValueEvalContext{PlanHash: []byte("plan-key")}
+
+	putMethod, getMethod := "put", "get"
+	results, err := d.Resolve(ctx,
+		decorator.ValueCall{Primary: &putMethod, Params: map[string]any{"path": "keys/deploy", "value": "plan-deploy-key"}},
+		decorator.ValueCall{Primary: &getMethod, Params: map[string]any{"path": "tokens/github"}},
+		decorator.ValueCall{Primary: &getMethod, Params: map[string]any{"path": "keys/deploy"}},
+		decorator.ValueCall{Primary: &getMethod, Params: map[string]any{"path": "tokens/missing"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[1].Error)
+	assert.Equal(t, []byte("ghp_from_store"), results[1].Value)
+	assert.Equal(t, "2", results[1].Metadata.Version)
+	assert.Equal(t, expires, results[1].Metadata.ExpiresAt)
+	assert.False(t, results[1].Metadata.CreatedAt.IsZero())
+
+	// A value put in this plan shadows the stored one
+	require.NoError(t, results[2].Error)
+	assert.Equal(t, []byte("plan-deploy-key"), results[2].Value)
+	assert.True(t, results[2].Metadata.IsZero())
+
+	require.Error(t, results[3].Error)
+	assert.Contains(t, results[3].Error.Error(), "not found")
+
+	assert.Equal(t, 1, opens, "store should be opened once")
+}
+
+func TestSecretsDecorator_GetWithoutStore(t *testing.T) {
+	d := NewSecretsDecoratorWithStore(func() (*secretstore.Store, error) { return nil, nil })
+
+	getMethod := "get"
+	results, err := d.Resolve(decorator.ValueEvalContext{}, decorator.ValueCall{
+		Primary: &getMethod,
+		Params:  map[string]any{"path": "tokens/github"},
+	})
+	require.NoError(t, err)
+	require.Error(t, results[0].Error)
+	assert.Contains(t, results[0].Error.Error(), "sigil secrets set tokens/github")
+}
@@ -3,6 +3,7 @@ package decorators
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -36,6 +37,14 @@ func (s *testWorkdirSession) Get(ctx context.Context, path string) ([]byte, erro
 	return nil, nil
 }
 
+func (s *testWorkdirSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (s *testWorkdirSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (s *testWorkdirSession) Env() map[string]string {
 	return map[string]string{}
 }
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+)
+
+// BlockFingerprint implements decorator.BlockFingerprinter.
+//
+// The digest covers node kinds, decorator names, and arguments (including the
+// rendered command). Secret values stay as DisplayIDs, so plaintext never
+// reaches the digest.
+func (n *planBlockNode) BlockFingerprint() string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, "sigil-block-v1\x00")
+	fingerprintSteps(h, n.steps)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func fingerprintSteps(w io.Writer, steps []planfmt.Step) {
+	_, _ = fmt.Fprintf(w, "steps:%d\x00", len(steps))
+	for _, step := range steps {
+		fingerprintTree(w, step.Tree)
+	}
+}
+
+func fingerprintTree(w io.Writer, node planfmt.ExecutionNode) {
+	switch n := node.(type) {
+	case *planfmt.CommandNode:
+		_, _ = fmt.Fprintf(w, "cmd:%s\x00", normalizeDecoratorName(n.Decorator))
+		args := planArgsToMap(n.Args)
+		keys := make([]string, 0, len(args))
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "arg:%s=%T:%v\x00", key, args[key], args[key])
+		}
+		fingerprintSteps(w, n.Block)
+	case *planfmt.PipelineNode:
+		_, _ = fmt.Fprintf(w, "pipe:%d\x00", len(n.Commands))
+		for _, cmd := range n.Commands {
+			fingerprintTree(w, cmd)
+		}
+	case *planfmt.AndNode:
+		_, _ = io.WriteString(w, "and\x00")
+		fingerprintTree(w, n.Left)
+		fingerprintTree(w, n.Right)
+	case *planfmt.OrNode:
+		_, _ = io.WriteString(w, "or\x00")
+		fingerprintTree(w, n.Left)
+		fingerprintTree(w, n.Right)
+	case *planfmt.SequenceNode:
+		_, _ = fmt.Fprintf(w, "seq:%d\x00", len(n.Nodes))
+		for _, child := range n.Nodes {
+			fingerprintTree(w, child)
+		}
+	case *planfmt.RedirectNode:
+		_, _ = fmt.Fprintf(w, "redirect:%d\x00", n.Mode)
+		fingerprintTree(w, n.Source)
+		fingerprintTree(w, &n.Target)
+	case *planfmt.LogicNode:
+		_, _ = fmt.Fprintf(w, "logic:%s:%s:%s\x00", n.Kind, n.Condition, n.Result)
+		fingerprintSteps(w, n.Block)
+	case *planfmt.DAGNode:
+		_, _ = fmt.Fprintf(w, "dag:%d\x00", len(n.Tasks))
+		for _, task := range n.Tasks {
+			_, _ = fmt.Fprintf(w, "task:%s:%s\x00", task.Name, strings.Join(task.Needs, ","))
+			fingerprintSteps(w, task.Block)
+		}
+	case *planfmt.TryNode:
+		_, _ = io.WriteString(w, "try\x00")
+		fingerprintSteps(w, n.TryBlock)
+		_, _ = io.WriteString(w, "catch\x00")
+		fingerprintSteps(w, n.CatchBlock)
+		_, _ = io.WriteString(w, "finally\x00")
+		fingerprintSteps(w, n.FinallyBlock)
+	case nil:
+		_, _ = io.WriteString(w, "nil\x00")
+	default:
+		_, _ = fmt.Fprintf(w, "node:%T\x00", node)
+	}
+}
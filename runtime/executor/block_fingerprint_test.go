@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanBlockNodeImplementsBlockFingerprinter(t *testing.T) {
+	t.Parallel()
+
+	var node decorator.ExecNode = &planBlockNode{}
+	_, ok := node.(decorator.BlockFingerprinter)
+	assert.True(t, ok)
+}
+
+func TestBlockFingerprintIgnoresStepAndTransportIDs(t *testing.T) {
+	t.Parallel()
+
+	a := &planBlockNode{steps: []planfmt.Step{{ID: 1, Tree: shellCmd("go build ./...")}}}
+	remote := shellCmd("go build ./...")
+	remote.TransportID = "transport:abc"
+	b := &planBlockNode{steps: []planfmt.Step{{ID: 42, Tree: remote}}}
+
+	assert.Equal(t, a.BlockFingerprint(), b.BlockFingerprint())
+}
+
+func TestBlockFingerprintChangesWithCommand(t *testing.T) {
+	t.Parallel()
+
+	a := &planBlockNode{steps: []planfmt.Step{{ID: 1, Tree: shellCmd("go build ./...")}}}
+	b := &planBlockNode{steps: []planfmt.Step{{ID: 1, Tree: shellCmd("go build -race ./...")}}}
+	c := &planBlockNode{steps: []planfmt.Step{{ID: 1, Tree: &planfmt.AndNode{
+		Left:  shellCmd("go build ./..."),
+		Right: shellCmd("true"),
+	}}}}
+
+	assert.NotEqual(t, a.BlockFingerprint(), b.BlockFingerprint())
+	assert.NotEqual(t, a.BlockFingerprint(), c.BlockFingerprint())
+}
@@ -36,7 +36,7 @@ func (n *blockNode) childContext(ctx decorator.ExecContext) sdk.ExecutionContext
 func childExecutionContextFromDecorator(base sdk.ExecutionContext, ctx decorator.ExecContext) sdk.ExecutionContext {
 	child := base.WithContext(ctx.Context)
 	if typed, ok := child.(*executionContext); ok {
-		child = typed.withPipes(ctx.Stdin, ctx.Stdout)
+		child = typed.withPipes(ctx.Stdin, ctx.Stdout, ctx.Stderr)
 	}
 	if ctx.Session != nil {
 		if typed, ok := child.(*executionContext); ok {
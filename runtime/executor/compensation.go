@@ -0,0 +1,123 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/builtwithtofu/sigil/core/sdk"
+)
+
+// rollbackDecoratorName is the decorator that registers compensating actions.
+const rollbackDecoratorName = "exec.rollback"
+
+// CompensationRecord describes a registered rollback block that ran after a failure.
+type CompensationRecord struct {
+	StepID      uint64        // Step ID of the @exec.rollback block
+	TriggerStep uint64        // Step ID whose failure triggered the rollback
+	ExitCode    int           // Exit code of the compensation block
+	Duration    time.Duration // Time spent running the compensation block
+}
+
+// compensationStack holds rollback steps registered within a single block.
+// Each block owns its own stack. When the block completes, its compensations
+// pass to the enclosing block's stack, so a later failure there still undoes
+// them; when the block fails, it unwinds them itself.
+type compensationStack struct {
+	mu    sync.Mutex // nested blocks may complete concurrently (parallel branches)
+	steps []planfmt.Step
+}
+
+func (s *compensationStack) push(steps ...planfmt.Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, steps...)
+}
+
+func (s *compensationStack) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.steps) == 0
+}
+
+// compensationsKey carries the innermost block's compensation stack on the
+// Go context, so it follows nested blocks through decorators.
+type compensationsKey struct{}
+
+// withCompensations returns a context whose nested blocks hand their
+// completed compensations to stack (nil to discard them).
+func withCompensations(execCtx sdk.ExecutionContext, stack *compensationStack) sdk.ExecutionContext {
+	parent := execCtx.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	return execCtx.WithContext(context.WithValue(parent, compensationsKey{}, stack))
+}
+
+// enclosingCompensations returns the stack of the block execCtx runs in.
+func enclosingCompensations(execCtx sdk.ExecutionContext) *compensationStack {
+	if ctx := execCtx.Context(); ctx != nil {
+		stack, _ := ctx.Value(compensationsKey{}).(*compensationStack)
+		return stack
+	}
+	return nil
+}
+
+// isRollbackStep reports whether a step registers a compensating action
+// instead of executing immediately.
+func isRollbackStep(step planfmt.Step) bool {
+	cmd, ok := step.Tree.(*planfmt.CommandNode)
+	if !ok {
+		return false
+	}
+	return normalizeDecoratorName(cmd.Decorator) == rollbackDecoratorName
+}
+
+// unwindCompensations runs registered rollback steps in reverse registration order.
+//
+// Compensations run even when execution was canceled, so they detach from the
+// parent context's cancellation. A failing compensation does not stop the
+// remaining ones; every outcome is recorded on the execution receipt.
+func (e *executor) unwindCompensations(execCtx sdk.ExecutionContext, stack *compensationStack, triggerStep uint64) {
+	if stack == nil || stack.empty() {
+		return
+	}
+
+	parent := execCtx.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	// Rollbacks nested in a compensation are not registered with any block
+	rollbackCtx := withCompensations(execCtx.WithContext(context.WithoutCancel(parent)), nil)
+
+	stack.mu.Lock()
+	steps := stack.steps
+	stack.steps = nil
+	stack.mu.Unlock()
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		start := time.Now()
+		exitCode := e.executePlanStep(rollbackCtx, step)
+		duration := time.Since(start)
+
+		if exitCode != 0 {
+			_, _ = fmt.Fprintf(e.stderr, "Warning: rollback step %d failed with exit code %d\n", step.ID, exitCode)
+		}
+
+		e.recordCompensation(CompensationRecord{
+			StepID:      step.ID,
+			TriggerStep: triggerStep,
+			ExitCode:    exitCode,
+			Duration:    duration,
+		})
+	}
+}
+
+func (e *executor) recordCompensation(record CompensationRecord) {
+	e.compensationsMu.Lock()
+	defer e.compensationsMu.Unlock()
+	e.compensations = append(e.compensations, record)
+}
@@ -0,0 +1,237 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rollbackStep(id uint64, block ...planfmt.Step) planfmt.Step {
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.CommandNode{
+			Decorator: "@exec.rollback",
+			Block:     block,
+		},
+	}
+}
+
+func readLog(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Fields(string(data))
+}
+
+func TestRollbackRunsInReverseOrderOnFailure(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo create-ns >> " + logPath)},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo delete-ns >> " + logPath)}),
+			{ID: 4, Tree: shellCmd("echo create-cm >> " + logPath)},
+			rollbackStep(5, planfmt.Step{ID: 6, Tree: shellCmd("echo delete-cm >> " + logPath)}),
+			{ID: 7, Tree: shellCmd("exit 3")},
+			{ID: 8, Tree: shellCmd("echo never >> " + logPath)},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{Stderr: &bytes.Buffer{}}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.ExitCode, "original failure must propagate")
+	assert.Equal(t, []string{"create-ns", "create-cm", "delete-cm", "delete-ns"}, readLog(t, logPath))
+	require.Len(t, result.Compensations, 2)
+	assert.Equal(t, uint64(5), result.Compensations[0].StepID)
+	assert.Equal(t, uint64(2), result.Compensations[1].StepID)
+	for _, c := range result.Compensations {
+		assert.Equal(t, uint64(7), c.TriggerStep)
+		assert.Equal(t, 0, c.ExitCode)
+	}
+}
+
+func TestRollbackDoesNotRunOnSuccess(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo apply >> " + logPath)},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo undo >> " + logPath)}),
+			{ID: 4, Tree: shellCmd("echo verify >> " + logPath)},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, []string{"apply", "verify"}, readLog(t, logPath))
+	assert.Empty(t, result.Compensations)
+}
+
+func TestRollbackOnlyCoversPrecedingSteps(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("exit 1")},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo undo >> " + logPath)}),
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Empty(t, readLog(t, logPath), "rollback registered after the failure must not run")
+	assert.Empty(t, result.Compensations)
+}
+
+func TestRollbackIsBlockScoped(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo outer >> " + logPath)},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo undo-outer >> " + logPath)}),
+			{
+				ID: 4,
+				Tree: &planfmt.LogicNode{
+					Kind: "call",
+					Block: []planfmt.Step{
+						{ID: 5, Tree: shellCmd("echo inner >> " + logPath)},
+						rollbackStep(6, planfmt.Step{ID: 7, Tree: shellCmd("echo undo-inner >> " + logPath)}),
+						{ID: 8, Tree: shellCmd("exit 2")},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{Stderr: &bytes.Buffer{}}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.ExitCode)
+	assert.Equal(t, []string{"outer", "inner", "undo-inner", "undo-outer"}, readLog(t, logPath))
+	require.Len(t, result.Compensations, 2)
+	assert.Equal(t, uint64(8), result.Compensations[0].TriggerStep)
+	assert.Equal(t, uint64(4), result.Compensations[1].TriggerStep)
+}
+
+func TestRollbackInNestedBlockRunsWhenOuterStepFails(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo outer >> " + logPath)},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo undo-outer >> " + logPath)}),
+			{
+				ID: 4,
+				Tree: &planfmt.LogicNode{
+					Kind: "call",
+					Block: []planfmt.Step{
+						{ID: 5, Tree: shellCmd("echo inner >> " + logPath)},
+						rollbackStep(6, planfmt.Step{ID: 7, Tree: shellCmd("echo undo-inner >> " + logPath)}),
+						{
+							ID: 8,
+							Tree: &planfmt.LogicNode{
+								Kind: "call",
+								Block: []planfmt.Step{
+									{ID: 9, Tree: shellCmd("echo innermost >> " + logPath)},
+									rollbackStep(10, planfmt.Step{ID: 11, Tree: shellCmd("echo undo-innermost >> " + logPath)}),
+								},
+							},
+						},
+					},
+				},
+			},
+			{ID: 12, Tree: shellCmd("exit 4")},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{Stderr: &bytes.Buffer{}}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, result.ExitCode)
+	assert.Equal(t, []string{"outer", "inner", "innermost", "undo-innermost", "undo-inner", "undo-outer"}, readLog(t, logPath))
+	require.Len(t, result.Compensations, 3)
+	assert.Equal(t, []uint64{10, 6, 2}, []uint64{result.Compensations[0].StepID, result.Compensations[1].StepID, result.Compensations[2].StepID})
+	for _, c := range result.Compensations {
+		assert.Equal(t, uint64(12), c.TriggerStep)
+	}
+}
+
+func TestRollbackContinuesWhenCompensationFails(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	var stderr bytes.Buffer
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("true")},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo first-undo >> " + logPath)}),
+			rollbackStep(4, planfmt.Step{ID: 5, Tree: shellCmd("exit 9")}),
+			{ID: 6, Tree: shellCmd("exit 1")},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{Stderr: &stderr}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.ExitCode, "compensation failure must not mask original exit code")
+	assert.Equal(t, []string{"first-undo"}, readLog(t, logPath))
+	require.Len(t, result.Compensations, 2)
+	assert.Equal(t, 9, result.Compensations[0].ExitCode)
+	assert.Contains(t, stderr.String(), "rollback step 4 failed with exit code 9")
+}
+
+func TestRollbackRunsAfterCancellation(t *testing.T) {
+	t.Parallel()
+
+	logPath := filepath.Join(t.TempDir(), "log")
+	ctx, cancel := context.WithCancel(context.Background())
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo apply >> " + logPath)},
+			rollbackStep(2, planfmt.Step{ID: 3, Tree: shellCmd("echo undo >> " + logPath)}),
+			{ID: 4, Tree: shellCmd("sleep 10")},
+		},
+	}
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	result, err := ExecutePlan(ctx, plan, Config{Stderr: &bytes.Buffer{}}, testVault())
+	require.NoError(t, err)
+
+	assert.NotEqual(t, 0, result.ExitCode)
+	assert.Equal(t, []string{"apply", "undo"}, readLog(t, logPath))
+	require.Len(t, result.Compensations, 1)
+	assert.Equal(t, uint64(2), result.Compensations[0].StepID)
+}
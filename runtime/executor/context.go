@@ -26,6 +26,7 @@ type executionContext struct {
 	baseWorkdir string            // Base session snapshot for transportID
 	stdin       io.Reader         // Piped input (nil if not piped)
 	stdoutPipe  io.Writer         // Piped output (nil if not piped)
+	stderr      io.Writer         // Set by a wrapper decorator for its block (nil uses the executor's)
 }
 
 // newExecutionContext creates a new execution context for a decorator
@@ -180,6 +181,7 @@ func (e *executionContext) WithContext(ctx context.Context) sdk.ExecutionContext
 		baseWorkdir: e.baseWorkdir,
 		stdin:       e.stdin,      // Preserve pipes
 		stdoutPipe:  e.stdoutPipe, // Preserve pipes
+		stderr:      e.stderr,
 	}
 }
 
@@ -205,6 +207,7 @@ func (e *executionContext) WithEnviron(env map[string]string) sdk.ExecutionConte
 		baseWorkdir: e.baseWorkdir,
 		stdin:       e.stdin,      // Preserve pipes
 		stdoutPipe:  e.stdoutPipe, // Preserve pipes
+		stderr:      e.stderr,
 	}
 }
 
@@ -252,6 +255,7 @@ func (e *executionContext) WithWorkdir(dir string) sdk.ExecutionContext {
 		baseWorkdir: e.baseWorkdir,
 		stdin:       e.stdin,      // Preserve pipes
 		stdoutPipe:  e.stdoutPipe, // Preserve pipes
+		stderr:      e.stderr,
 	}
 }
 
@@ -286,6 +290,7 @@ func (e *executionContext) Clone(
 		baseWorkdir: e.baseWorkdir,
 		stdin:       stdin,      // NEW (may be nil)
 		stdoutPipe:  stdoutPipe, // NEW (may be nil)
+		stderr:      e.stderr,
 	}
 }
 
@@ -323,10 +328,11 @@ func (e *executionContext) withTransportID(transportID string) *executionContext
 		baseWorkdir: baseWorkdir,
 		stdin:       e.stdin,
 		stdoutPipe:  e.stdoutPipe,
+		stderr:      e.stderr,
 	}
 }
 
-func (e *executionContext) withPipes(stdin io.Reader, stdout io.Writer) *executionContext {
+func (e *executionContext) withPipes(stdin io.Reader, stdout, stderr io.Writer) *executionContext {
 	return &executionContext{
 		executor:    e.executor,
 		args:        e.args,
@@ -338,6 +344,7 @@ func (e *executionContext) withPipes(stdin io.Reader, stdout io.Writer) *executi
 		baseWorkdir: e.baseWorkdir,
 		stdin:       stdin,
 		stdoutPipe:  stdout,
+		stderr:      stderr,
 	}
 }
 
@@ -355,7 +362,7 @@ func (e *executionContext) Transport() interface{} {
 	}
 
 	session := sessionForExecutionContext(baseSession, e)
-	return newSessionTransport(session)
+	return newSessionTransport(session, e.executor)
 }
 
 // captureEnviron captures current environment as immutable snapshot
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"runtime"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/invariant"
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/builtwithtofu/sigil/core/sdk"
+)
+
+// maxParallelism returns the number of DAG tasks allowed to run at once.
+func (e *executor) maxParallelism() int {
+	if e.config.MaxParallelism > 0 {
+		return e.config.MaxParallelism
+	}
+	return runtime.NumCPU()
+}
+
+// dagResult reports a finished DAG task back to the scheduler.
+type dagResult struct {
+	index    int
+	exitCode int
+}
+
+// executePlanDAG runs function dependency tasks, starting each task as soon as
+// everything it needs has succeeded, with at most maxParallelism tasks running.
+//
+// After the first failure no new tasks are started; tasks already running are
+// allowed to finish so they are not interrupted halfway. The first failing
+// exit code is returned.
+func (e *executor) executePlanDAG(execCtx sdk.ExecutionContext, dag *planfmt.DAGNode) int {
+	invariant.NotNil(dag, "dag")
+
+	tasks := dag.Tasks
+	if len(tasks) == 0 {
+		return 0
+	}
+
+	index := make(map[string]int, len(tasks))
+	for i := range tasks {
+		index[tasks[i].Name] = i
+	}
+
+	pending := make([]int, len(tasks))      // Unfinished dependencies per task
+	dependents := make([][]int, len(tasks)) // Tasks waiting on each task
+	var ready []int
+	for i := range tasks {
+		for _, need := range tasks[i].Needs {
+			dep, ok := index[need]
+			invariant.Precondition(ok && dep < i, "dag task %q needs unknown or later task %q", tasks[i].Name, need)
+			pending[i]++
+			dependents[dep] = append(dependents[dep], i)
+		}
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	limit := e.maxParallelism()
+	results := make(chan dagResult, len(tasks))
+	running := 0
+	exitCode := 0
+
+	for {
+		for exitCode == 0 && len(ready) > 0 && running < limit {
+			if isExecutionCanceled(execCtx) {
+				exitCode = decorator.ExitCanceled
+				break
+			}
+			next := ready[0]
+			ready = ready[1:]
+			running++
+			go func(i int) {
+				results <- dagResult{index: i, exitCode: e.executePlanBlock(execCtx, tasks[i].Block)}
+			}(next)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		if result.exitCode != 0 {
+			if exitCode == 0 {
+				exitCode = result.exitCode
+			}
+			continue
+		}
+		for _, dependent := range dependents[result.index] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return exitCode
+}
@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dagTask(name string, id uint64, cmd string, needs ...string) planfmt.DAGTask {
+	return planfmt.DAGTask{
+		Name:  name,
+		Needs: needs,
+		Block: []planfmt.Step{{ID: id, Tree: shellCmd(cmd)}},
+	}
+}
+
+// rendezvousCmd marks this task as started and waits for the other one.
+// Both tasks only succeed when they run at the same time.
+func rendezvousCmd(dir, self, other string) string {
+	return fmt.Sprintf(
+		`touch %[1]s/%[2]s; for i in $(seq 1 40); do [ -f %[1]s/%[3]s ] && exit 0; sleep 0.05; done; exit 1`,
+		dir, self, other)
+}
+
+func TestDAGRunsIndependentTasksConcurrently(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+				dagTask("build", 2, rendezvousCmd(dir, "build", "test")),
+				dagTask("test", 3, rendezvousCmd(dir, "test", "build")),
+			}}},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{MaxParallelism: 2}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode, "independent tasks must overlap")
+}
+
+func TestDAGMaxParallelismSerializesTasks(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+				dagTask("build", 2, rendezvousCmd(dir, "build", "test")),
+				dagTask("test", 3, rendezvousCmd(dir, "test", "build")),
+			}}},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{MaxParallelism: 1}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ExitCode, "with one slot the first task cannot meet the second")
+
+	_, err = os.Stat(filepath.Join(dir, "test"))
+	assert.True(t, os.IsNotExist(err), "second task must not start after the first fails")
+}
+
+func TestDAGRunsDependenciesFirst(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "order.log")
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+				dagTask("build", 2, "sleep 0.1; echo build >> "+log),
+				dagTask("lint", 3, "sleep 0.2; echo lint >> "+log),
+				dagTask("test", 4, "echo test >> "+log, "build", "lint"),
+			}}},
+			{ID: 5, Tree: shellCmd("echo deploy >> " + log)},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{MaxParallelism: 4}, testVault())
+	require.NoError(t, err)
+	require.Equal(t, 0, result.ExitCode)
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build", "lint", "test", "deploy"}, strings.Fields(string(data)))
+}
+
+func TestDAGFailureSkipsDependents(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "deployed")
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+				dagTask("build", 2, "exit 3"),
+				dagTask("test", 3, "touch "+marker, "build"),
+			}}},
+			{ID: 4, Tree: shellCmd("touch " + marker)},
+		},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err), "dependents and the function body must not run")
+}
+
+func TestDAGCanceledBeforeStart(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := &executor{config: Config{}, stderr: os.Stderr}
+	execCtx := newExecutionContext(map[string]interface{}{}, e, ctx)
+	exitCode := e.executePlanDAG(execCtx, &planfmt.DAGNode{Tasks: []planfmt.DAGTask{
+		dagTask("build", 2, "touch "+marker),
+	}})
+
+	assert.Equal(t, decorator.ExitCanceled, exitCode)
+	_, err := os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+}
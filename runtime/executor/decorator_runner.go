@@ -23,6 +23,16 @@ func (e *executor) getStderr() io.Writer {
 	return e.stderr
 }
 
+// stderrFor returns the stderr for commands run under execCtx: the stream a
+// wrapper decorator gave its block (such as @ssh.each's per-host prefixer),
+// otherwise the executor's.
+func (e *executor) stderrFor(execCtx sdk.ExecutionContext) io.Writer {
+	if ec, ok := execCtx.(*executionContext); ok && ec.stderr != nil {
+		return ec.stderr
+	}
+	return e.getStderr()
+}
+
 // executeCommandWithPipes executes a command with optional piped stdin/stdout.
 func (e *executor) executeCommandWithPipes(execCtx sdk.ExecutionContext, cmd *sdk.CommandNode, stdin io.Reader, stdout io.Writer) int {
 	invariant.NotNil(execCtx, "execCtx")
@@ -86,14 +96,15 @@ func (e *executor) executeShellCommandWithPipes(execCtx sdk.ExecutionContext, cm
 }
 
 func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params map[string]any, stdin io.Reader, stdout io.Writer) int {
+	stderr := e.stderrFor(execCtx)
 	if params == nil {
-		_, _ = fmt.Fprintln(e.getStderr(), "Error: @shell missing parameters")
+		_, _ = fmt.Fprintln(stderr, "Error: @shell missing parameters")
 		return decorator.ExitFailure
 	}
 
 	command, ok := params["command"].(string)
 	if !ok || command == "" {
-		_, _ = fmt.Fprintln(e.getStderr(), "Error: @shell requires a non-empty string command")
+		_, _ = fmt.Fprintln(stderr, "Error: @shell requires a non-empty string command")
 		return decorator.ExitFailure
 	}
 
@@ -108,7 +119,7 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 	if shellArg, hasShellArg := params["shell"]; hasShellArg {
 		shellStr, ok := shellArg.(string)
 		if !ok {
-			_, _ = fmt.Fprintln(e.getStderr(), "Error: @shell expects 'shell' to be a string when provided")
+			_, _ = fmt.Fprintln(stderr, "Error: @shell expects 'shell' to be a string when provided")
 			return decorator.ExitFailure
 		}
 		explicitShell = shellStr
@@ -117,7 +128,7 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 	transportID := executionTransportID(execCtx)
 	shellName, err := resolveShellName(explicitShell, execCtx.Environ())
 	if err != nil {
-		_, _ = fmt.Fprintf(e.getStderr(), "Error: %v\n", err)
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
 		return decorator.ExitFailure
 	}
 
@@ -134,7 +145,7 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 			environ:     execCtx.Environ(),
 			workdir:     execCtx.Workdir(),
 			stdout:      stdout,
-			stderr:      e.stderr,
+			stderr:      stderr,
 		})
 		if workerErr == nil {
 			return exitCode
@@ -145,16 +156,18 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 		}
 
 		if !canFallbackToSessionRun(workerErr) {
-			_, _ = fmt.Fprintf(e.getStderr(), "Error: shell worker execution failed after command start: %v\n", workerErr)
+			_, _ = fmt.Fprintf(stderr, "Error: shell worker execution failed after command start: %v\n", workerErr)
 			return decorator.ExitFailure
 		}
 
-		_, _ = fmt.Fprintf(e.getStderr(), "Warning: shell worker unavailable before command start, falling back to session run: %v\n", workerErr)
+		if !errors.Is(workerErr, errShellWorkerUnsupported) {
+			_, _ = fmt.Fprintf(stderr, "Warning: shell worker unavailable before command start, falling back to session run: %v\n", workerErr)
+		}
 	}
 
 	baseSession, sessionErr := e.sessions.SessionFor(transportID)
 	if sessionErr != nil {
-		_, _ = fmt.Fprintf(e.getStderr(), "Error creating session: %v\n", sessionErr)
+		_, _ = fmt.Fprintf(stderr, "Error creating session: %v\n", sessionErr)
 		return decorator.ExitFailure
 	}
 
@@ -162,7 +175,7 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 
 	argv, err := shellCommandArgs(shellName, command)
 	if err != nil {
-		_, _ = fmt.Fprintf(e.getStderr(), "Error: %v\n", err)
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
 		return decorator.ExitFailure
 	}
 
@@ -173,10 +186,10 @@ func (e *executor) executeShellWithParams(execCtx sdk.ExecutionContext, params m
 	result, err := session.Run(runCtx, argv, decorator.RunOpts{
 		Stdin:  stdin,
 		Stdout: stdout,
-		Stderr: e.stderr,
+		Stderr: stderr,
 	})
 	if err != nil {
-		_, _ = fmt.Fprintf(e.getStderr(), "Error: %v\n", err)
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
 	}
 
 	return result.ExitCode
@@ -269,7 +282,7 @@ func (e *executor) resolveDisplayIDs(params map[string]any, decoratorName, trans
 			if err != nil {
 				return nil, fmt.Errorf("failed to resolve %s in %s.%s: %w", displayID, decoratorName, key, err)
 			}
-			result = strings.ReplaceAll(result, displayID, fmt.Sprint(actualValue))
+			result = strings.ReplaceAll(result, displayID, interpolatedValue(actualValue))
 		}
 
 		resolved[key] = result
@@ -278,6 +291,16 @@ func (e *executor) resolveDisplayIDs(params map[string]any, decoratorName, trans
 	return resolved, nil
 }
 
+// interpolatedValue renders a resolved value inside a string parameter.
+// Byte values (e.g. @secrets.get) are inserted as text, matching the raw
+// bytes the scrubber looks for.
+func interpolatedValue(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
 // executeDecorator executes a decorator via the Exec interface.
 func (e *executor) executeDecorator(
 	execCtx sdk.ExecutionContext,
@@ -322,6 +345,8 @@ func (e *executor) executeDecorator(
 		Stdout:  stdout,
 		Stderr:  e.stderr,
 		Trace:   nil,
+		Events:  e,
+		Redact:  e.redactText,
 	}
 
 	result, err := node.Execute(decoratorExecCtx)
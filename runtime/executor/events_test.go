@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/builtwithtofu/sigil/runtime/decorators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func confirmStep(id uint64, name string, block ...planfmt.Step) planfmt.Step {
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.CommandNode{
+			Decorator: "@exec.confirm",
+			Args: []planfmt.Arg{
+				{Key: "name", Val: planfmt.Value{Kind: planfmt.ValueString, Str: name}},
+			},
+			Block: block,
+		},
+	}
+}
+
+func TestDecoratorEventsRecordedOnResult(t *testing.T) {
+	t.Parallel()
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			confirmStep(1, "staging", planfmt.Step{ID: 2, Tree: shellCmd("true")}),
+			confirmStep(3, "prod", planfmt.Step{ID: 4, Tree: shellCmd("true")}),
+		},
+	}
+
+	var streamed []decorator.Event
+	ctx := decorators.WithConfirmPolicy(context.Background(), decorators.ConfirmPolicy{AssumeYes: true})
+	result, err := ExecutePlan(ctx, plan, Config{
+		OnEvent: func(event decorator.Event) { streamed = append(streamed, event) },
+	}, testVault())
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.ExitCode)
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, result.Events, streamed, "streamed events must match the receipt")
+	assert.Equal(t, "exec.confirm", result.Events[0].Decorator)
+	assert.Equal(t, "staging", result.Events[0].Attrs["name"])
+	assert.Equal(t, "prod", result.Events[1].Attrs["name"])
+	assert.False(t, result.Events[0].Timestamp.IsZero())
+}
+
+func TestDecoratorEventsRecordAllowlistApproval(t *testing.T) {
+	t.Parallel()
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			confirmStep(1, "prod", planfmt.Step{ID: 2, Tree: shellCmd("true")}),
+		},
+	}
+
+	ctx := decorators.WithConfirmPolicy(context.Background(), decorators.ConfirmPolicy{Allow: []string{"prod"}})
+	result, err := ExecutePlan(ctx, plan, Config{}, testVault())
+	require.NoError(t, err)
+	require.Len(t, result.Events, 1)
+	assert.Equal(t, "approved", result.Events[0].Attrs["outcome"])
+	assert.Equal(t, "allowlist", result.Events[0].Attrs["via"])
+}
+
+func TestEventSourceFeedsResultWhileAttached(t *testing.T) {
+	t.Parallel()
+
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps:  []planfmt.Step{{ID: 1, Tree: shellCmd("true")}},
+	}
+
+	var sink decorator.EventSink
+	var streamed []decorator.Event
+	result, err := ExecutePlan(context.Background(), plan, Config{
+		OnEvent: func(event decorator.Event) { streamed = append(streamed, event) },
+		EventSource: func(s decorator.EventSink) func() {
+			sink = s
+			sink.Emit(decorator.Event{Decorator: "scrub", Kind: "credential", Attrs: map[string]string{"rule": "jwt"}})
+			return func() { sink = nil }
+		},
+	}, testVault())
+	require.NoError(t, err)
+
+	assert.Nil(t, sink, "source must be detached when execution ends")
+	require.Len(t, result.Events, 1)
+	assert.Equal(t, result.Events, streamed)
+	assert.Equal(t, "jwt", result.Events[0].Attrs["rule"])
+	assert.False(t, result.Events[0].Timestamp.IsZero())
+}
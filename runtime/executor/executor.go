@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/core/invariant"
 	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/builtwithtofu/sigil/runtime/streamscrub"
 )
 
 // DisplayIDResolver resolves display IDs in an execution transport context.
@@ -22,6 +24,10 @@ type Config struct {
 	Debug          DebugLevel     // Debug tracing (development only)
 	Telemetry      TelemetryLevel // Telemetry collection (production-safe)
 	Stderr         io.Writer
+	OnEvent        func(decorator.Event)            // Streams decorator events as they are emitted (optional)
+	EventSource    func(decorator.EventSink) func() // Attaches a producer outside the plan, e.g. output scrubbing; returns its detach (optional)
+	MaxParallelism int                              // Max concurrent function dependency tasks (0 = number of CPUs)
+	SecretProvider streamscrub.SecretProvider       // Finds secrets in output written outside lockdown, e.g. redirect sinks (nil = the vault's provider)
 	sessionFactory sessionFactory
 }
 
@@ -45,11 +51,13 @@ const (
 
 // ExecutionResult holds the result of plan execution
 type ExecutionResult struct {
-	ExitCode    int                 // Final exit code (0 = success)
-	Duration    time.Duration       // Total execution time
-	StepsRun    int                 // Number of steps executed
-	Telemetry   *ExecutionTelemetry // Additional metrics (nil if TelemetryOff)
-	DebugEvents []DebugEvent        // Debug events (nil if DebugOff)
+	ExitCode      int                  // Final exit code (0 = success)
+	Duration      time.Duration        // Total execution time
+	StepsRun      int                  // Number of steps executed
+	Compensations []CompensationRecord // Rollback blocks that ran, in execution order
+	Events        []decorator.Event    // Auditable decorator events, in emission order
+	Telemetry     *ExecutionTelemetry  // Additional metrics (nil if TelemetryOff)
+	DebugEvents   []DebugEvent         // Debug events (nil if DebugOff)
 }
 
 // ExecutionTelemetry holds additional execution metrics (optional, production-safe)
@@ -87,6 +95,14 @@ type executor struct {
 	exitCode int
 	stderr   io.Writer
 
+	// Compensations run during rollback (guarded for parallel branches)
+	compensationsMu sync.Mutex
+	compensations   []CompensationRecord
+
+	// Decorator events for the execution receipt (guarded for parallel branches)
+	eventsMu sync.Mutex
+	events   []decorator.Event
+
 	// Observability
 	debugEvents []DebugEvent
 	telemetry   *ExecutionTelemetry
@@ -112,6 +128,7 @@ func ExecutePlan(ctx context.Context, plan *planfmt.Plan, config Config, vlt Dis
 	if e.stderr == nil {
 		e.stderr = os.Stderr
 	}
+	e.sessions.events = e
 	e.workers = newShellWorkerPool(e.sessions)
 	e.sessions.registerPlanTransports(plan.Transports)
 	defer e.sessions.Close()
@@ -130,9 +147,19 @@ func ExecutePlan(ctx context.Context, plan *planfmt.Plan, config Config, vlt Dis
 		e.recordDebugEvent("enter_execute", 0, fmt.Sprintf("steps=%d", len(plan.Steps)))
 	}
 
-	rootExecCtx := newExecutionContext(make(map[string]interface{}), e, ctx)
+	detachEvents := func() {}
+	if config.EventSource != nil {
+		detachEvents = config.EventSource(e)
+	}
 
+	var compensations compensationStack
+	rootExecCtx := withCompensations(newExecutionContext(make(map[string]interface{}), e, ctx), &compensations)
 	for _, step := range plan.Steps {
+		if isRollbackStep(step) {
+			compensations.push(step)
+			continue
+		}
+
 		stepStart := time.Now()
 
 		if config.Debug >= DebugDetailed {
@@ -161,6 +188,10 @@ func ExecutePlan(ctx context.Context, plan *planfmt.Plan, config Config, vlt Dis
 				stepID := step.ID
 				e.telemetry.FailedStep = &stepID
 			}
+			if config.Debug >= DebugPaths && !compensations.empty() {
+				e.recordDebugEvent("rollback_start", step.ID, fmt.Sprintf("compensations=%d", len(compensations.steps)))
+			}
+			e.unwindCompensations(rootExecCtx, &compensations, step.ID)
 			break
 		}
 	}
@@ -169,6 +200,11 @@ func ExecutePlan(ctx context.Context, plan *planfmt.Plan, config Config, vlt Dis
 		e.telemetry.StepsRun = e.stepsRun
 	}
 
+	detachEvents()
+	e.eventsMu.Lock()
+	events := e.events
+	e.eventsMu.Unlock()
+
 	duration := time.Since(e.startTime)
 	if config.Debug >= DebugPaths {
 		e.recordDebugEvent("exit_execute", 0, fmt.Sprintf("steps_run=%d, exit=%d, duration=%v", e.stepsRun, e.exitCode, duration))
@@ -181,14 +217,32 @@ func ExecutePlan(ctx context.Context, plan *planfmt.Plan, config Config, vlt Dis
 	invariant.Postcondition(e.stepsRun <= len(plan.Steps), "steps run cannot exceed total steps")
 
 	return &ExecutionResult{
-		ExitCode:    e.exitCode,
-		Duration:    duration,
-		StepsRun:    e.stepsRun,
-		Telemetry:   e.telemetry,
-		DebugEvents: e.debugEvents,
+		ExitCode:      e.exitCode,
+		Duration:      duration,
+		StepsRun:      e.stepsRun,
+		Compensations: e.compensations,
+		Events:        events,
+		Telemetry:     e.telemetry,
+		DebugEvents:   e.debugEvents,
 	}, nil
 }
 
+// Emit implements decorator.EventSink.
+func (e *executor) Emit(event decorator.Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	e.eventsMu.Lock()
+	e.events = append(e.events, event)
+	onEvent := e.config.OnEvent
+	e.eventsMu.Unlock()
+
+	if onEvent != nil {
+		onEvent(event)
+	}
+}
+
 // recordDebugEvent records a debug event (only if debug enabled)
 func (e *executor) recordDebugEvent(event string, stepID uint64, contextInfo string) {
 	if e.config.Debug == DebugOff {
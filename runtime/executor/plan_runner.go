@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -34,15 +35,28 @@ func (e *executor) executePlanStep(execCtx sdk.ExecutionContext, step planfmt.St
 }
 
 func (e *executor) executePlanBlock(execCtx sdk.ExecutionContext, steps []planfmt.Step) int {
+	var compensations compensationStack
+	blockCtx := withCompensations(execCtx, &compensations)
 	for _, step := range steps {
 		if isExecutionCanceled(execCtx) {
+			e.unwindCompensations(execCtx, &compensations, step.ID)
 			return decorator.ExitCanceled
 		}
-		exitCode := e.executePlanStep(execCtx, step)
+		if isRollbackStep(step) {
+			compensations.push(step)
+			continue
+		}
+		exitCode := e.executePlanStep(blockCtx, step)
 		if exitCode != 0 {
+			e.unwindCompensations(execCtx, &compensations, step.ID)
 			return exitCode
 		}
 	}
+	if parent := enclosingCompensations(execCtx); parent != nil {
+		compensations.mu.Lock()
+		parent.push(compensations.steps...)
+		compensations.mu.Unlock()
+	}
 	return 0
 }
 
@@ -93,6 +107,9 @@ func (e *executor) executePlanTreeIO(execCtx sdk.ExecutionContext, node planfmt.
 	case *planfmt.LogicNode:
 		return e.executePlanBlock(execCtx, n.Block)
 
+	case *planfmt.DAGNode:
+		return e.executePlanDAG(execCtx, n)
+
 	case *planfmt.TryNode:
 		_, _ = fmt.Fprintln(e.stderr, "Error: try/catch/finally execution is not implemented")
 		return decorator.ExitFailure
@@ -251,10 +268,11 @@ func (e *executor) executePlanDecorator(
 		node = next
 	}
 
+	stderr := e.stderrFor(execCtx)
 	transportID := transportIDForPlanDecoratorExecution(execCtx, cmd, execDec)
 	baseSession, sessionErr := e.sessions.SessionFor(transportID)
 	if sessionErr != nil {
-		_, _ = fmt.Fprintf(e.stderr, "Error creating session: %v\n", sessionErr)
+		_, _ = fmt.Fprintf(stderr, "Error creating session: %v\n", sessionErr)
 		return decorator.ExitFailure
 	}
 	session := sessionForExecutionContext(baseSession, execCtx)
@@ -268,13 +286,15 @@ func (e *executor) executePlanDecorator(
 		Session: session,
 		Stdin:   stdin,
 		Stdout:  stdout,
-		Stderr:  e.stderr,
+		Stderr:  stderr,
 		Trace:   nil,
+		Events:  e,
+		Redact:  e.redactText,
 	}
 
 	result, err := node.Execute(decoratorExecCtx)
 	if err != nil {
-		_, _ = fmt.Fprintf(e.stderr, "Error: %v\n", err)
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
 	}
 
 	return result.ExitCode
@@ -341,14 +361,31 @@ func (e *executor) executePlanRedirect(execCtx sdk.ExecutionContext, redirect *p
 		return exitCode
 	}
 
-	writer, err := ioDecorator.OpenWrite(decoratorCtx, redirect.Mode == planfmt.RedirectAppend)
+	policy, err := planSinkSecrets(&redirect.Target)
+	if err != nil {
+		_, _ = fmt.Fprintf(e.stderr, "Error: %v\n", SinkError{SinkID: sinkIdentity, Operation: "validate", TransportID: transportID, Cause: err})
+		return decorator.ExitFailure
+	}
+
+	sink, err := ioDecorator.OpenWrite(decoratorCtx, redirect.Mode == planfmt.RedirectAppend)
 	if err != nil {
 		_, _ = fmt.Fprintf(e.stderr, "Error: %v\n", SinkError{SinkID: sinkIdentity, Operation: "open", TransportID: transportID, Cause: err})
 		return decorator.ExitFailure
 	}
+	writer := e.guardSink(sink, policy)
 
-	exitCode := e.executePlanTreeIO(redirectExecCtx, withPlanRedirectedStderrSource(redirect.Source, stderrOnly), stdin, writer)
-	if closeErr := writer.Close(); closeErr != nil {
+	writeCapture := &sinkWriteErrorCapture{writer: writer}
+	exitCode := e.executePlanTreeIO(redirectExecCtx, withPlanRedirectedStderrSource(redirect.Source, stderrOnly), stdin, writeCapture)
+	closeErr := writer.Close()
+	writeErr := writeCapture.Err()
+	if writeErr == nil && errors.Is(closeErr, errSecretInSink) {
+		writeErr, closeErr = closeErr, nil // found in output held back until close
+	}
+	if writeErr != nil {
+		_, _ = fmt.Fprintf(e.stderr, "Error: %v\n", SinkError{SinkID: sinkIdentity, Operation: "write", TransportID: transportID, Cause: writeErr})
+		return decorator.ExitFailure
+	}
+	if closeErr != nil {
 		_, _ = fmt.Fprintf(e.stderr, "Error: %v\n", SinkError{SinkID: sinkIdentity, Operation: "close", TransportID: transportID, Cause: closeErr})
 		return decorator.ExitFailure
 	}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/builtwithtofu/sigil/runtime/streamscrub"
+)
+
+// secretProviderSource is implemented by vaults that can scrub their values.
+type secretProviderSource interface {
+	SecretProvider() streamscrub.SecretProvider
+}
+
+// secretProvider returns the provider that finds secrets in output written
+// outside output lockdown: Config.SecretProvider, else the vault's, or nil
+// when there are none.
+func (e *executor) secretProvider() streamscrub.SecretProvider {
+	if e.config.SecretProvider != nil {
+		return e.config.SecretProvider
+	}
+	if src, ok := e.vault.(secretProviderSource); ok {
+		return src.SecretProvider()
+	}
+	return nil
+}
+
+// redactText implements decorator.ExecContext.Redact: it scrubs the vault's
+// secrets from text that bypasses output lockdown. Text that cannot be
+// scrubbed is withheld entirely.
+func (e *executor) redactText(text string) string {
+	provider := e.secretProvider()
+	if provider == nil {
+		return text
+	}
+	var buf bytes.Buffer
+	scrubber := streamscrub.New(&buf, streamscrub.WithSecretProvider(provider))
+	_, writeErr := io.WriteString(scrubber, text)
+	if err := errors.Join(writeErr, scrubber.Close()); err != nil {
+		return "<redacted>"
+	}
+	return buf.String()
+}
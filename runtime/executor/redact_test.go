@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedactTextScrubsVaultSecrets(t *testing.T) {
+	const secret = "hunter2-prompt-secret"
+	vlt := testVault()
+	exprID := vlt.DeclareVariable("TOKEN", "literal:"+secret)
+	vlt.StoreUnresolvedValue(exprID, secret)
+	vlt.MarkTouched(exprID)
+	vlt.ResolveAllTouched()
+
+	e := &executor{vault: vlt}
+	got := e.redactText("Deploy with " + secret + "?")
+	if diff := cmp.Diff("Deploy with "+vlt.GetDisplayID(exprID)+"?", got); diff != "" {
+		t.Errorf("redacted text mismatch (-want +got):\n%s", diff)
+	}
+
+	unscrubbed := &executor{}
+	if diff := cmp.Diff("plain", unscrubbed.redactText("plain")); diff != "" {
+		t.Errorf("text without vault mismatch (-want +got):\n%s", diff)
+	}
+}
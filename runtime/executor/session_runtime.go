@@ -34,6 +34,7 @@ type sessionRuntime struct {
 	pooled     map[string]decorator.Session
 	transports map[string]planfmt.Transport
 	factory    sessionFactory
+	events     decorator.EventSink // Receives open-time session events (optional)
 }
 
 func newSessionRuntime(factory sessionFactory) *sessionRuntime {
@@ -164,8 +165,15 @@ func (r *sessionRuntime) createSession(transportID string) (decorator.Session, b
 		return &transportScopedSession{id: transportID, session: pooled}, true, nil
 	}
 	r.pooled[poolKey] = openedSession
+	events := r.events
 	r.mu.Unlock()
 
+	if source, ok := openedSession.(decorator.SessionEventSource); ok && events != nil {
+		for _, event := range source.SessionEvents() {
+			events.Emit(event)
+		}
+	}
+
 	return &transportScopedSession{id: transportID, session: openedSession}, true, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
@@ -204,6 +205,14 @@ func (s *testSSHSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return s.parent.Get(ctx, path)
 }
 
+func (s *testSSHSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *testSSHSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *testSSHSession) Env() map[string]string {
 	return s.parent.Env()
 }
@@ -236,6 +245,10 @@ func (s *testSSHSession) Close() error {
 	return nil
 }
 
+func (s *testSSHSession) SessionEvents() []decorator.Event {
+	return []decorator.Event{{Decorator: "test.transport", Kind: "connect", Attrs: map[string]string{"host": "example.internal"}}}
+}
+
 func (s *closeOrderSession) Run(ctx context.Context, argv []string, opts decorator.RunOpts) (decorator.Result, error) {
 	return decorator.Result{ExitCode: 0}, nil
 }
@@ -248,6 +261,14 @@ func (s *closeOrderSession) Get(ctx context.Context, path string) ([]byte, error
 	return nil, nil
 }
 
+func (s *closeOrderSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (s *closeOrderSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (s *closeOrderSession) Env() map[string]string {
 	return nil
 }
@@ -477,6 +498,14 @@ func (s *multiHopRootSession) Get(ctx context.Context, path string) ([]byte, err
 	return nil, nil
 }
 
+func (s *multiHopRootSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (s *multiHopRootSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (s *multiHopRootSession) Env() map[string]string {
 	return map[string]string{}
 }
@@ -528,6 +557,14 @@ func (s *multiHopSession) Get(ctx context.Context, path string) ([]byte, error)
 	return s.parent.Get(ctx, path)
 }
 
+func (s *multiHopSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.parent.PutStream(ctx, src, path, mode)
+}
+
+func (s *multiHopSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.parent.GetStream(ctx, path, dst)
+}
+
 func (s *multiHopSession) Env() map[string]string {
 	return s.parent.Env()
 }
@@ -577,6 +614,14 @@ func (s *noDialerSession) Get(ctx context.Context, path string) ([]byte, error)
 	return nil, nil
 }
 
+func (s *noDialerSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (s *noDialerSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (s *noDialerSession) Env() map[string]string {
 	return map[string]string{}
 }
@@ -1077,6 +1122,36 @@ func TestSessionRuntimePoolsByParentAndParams(t *testing.T) {
 	}
 }
 
+func TestSessionRuntimeEmitsSessionEventsOncePerOpen(t *testing.T) {
+	registerSessionPoolProbeDecorator(t)
+
+	var events []decorator.Event
+	runtime := newSessionRuntime(scopedLocalSessionFactory)
+	runtime.events = eventSinkFunc(func(event decorator.Event) { events = append(events, event) })
+	defer runtime.Close()
+	args := []planfmt.Arg{{Key: "host", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "events.internal"}}}
+	runtime.registerPlanTransports([]planfmt.Transport{
+		{ID: "parent:A", Decorator: "local", ParentID: ""},
+		{ID: "child:A1", Decorator: "@test.transport.poolprobe", ParentID: "parent:A", Args: args},
+		{ID: "child:A2", Decorator: "@test.transport.poolprobe", ParentID: "parent:A", Args: args},
+	})
+
+	for _, id := range []string{"child:A1", "child:A2"} {
+		if _, err := runtime.SessionFor(id); err != nil {
+			t.Fatalf("session for %s: %v", id, err)
+		}
+	}
+
+	want := []decorator.Event{{Decorator: "test.transport", Kind: "connect", Attrs: map[string]string{"host": "example.internal"}}}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Fatalf("pooled sessions must emit open events once (-want +got):\n%s", diff)
+	}
+}
+
+type eventSinkFunc func(decorator.Event)
+
+func (f eventSinkFunc) Emit(event decorator.Event) { f(event) }
+
 func TestSessionPoolKeyIncludesAuthFingerprint(t *testing.T) {
 	parent := &transportScopedSession{id: "parent:A", session: decorator.NewLocalSession()}
 
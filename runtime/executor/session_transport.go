@@ -6,6 +6,8 @@ import (
 	"errors"
 	"io"
 	"io/fs"
+	"strconv"
+	"time"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
 	sdkexec "github.com/builtwithtofu/sigil/core/sdk/executor"
@@ -13,12 +15,17 @@ import (
 
 type sessionTransport struct {
 	session decorator.Session
+	events  decorator.EventSink // Receives transfer progress; may be nil
 }
 
-func newSessionTransport(session decorator.Session) *sessionTransport {
-	return &sessionTransport{session: session}
+func newSessionTransport(session decorator.Session, events decorator.EventSink) *sessionTransport {
+	return &sessionTransport{session: session, events: events}
 }
 
+// transferProgressInterval bounds how often an in-flight transfer reports
+// progress on the event stream; transfers always report completion.
+const transferProgressInterval = time.Second
+
 func (t *sessionTransport) Exec(ctx context.Context, argv []string, opts sdkexec.ExecOpts) (int, error) {
 	runSession := t.session
 	if opts.Dir != "" {
@@ -37,22 +44,46 @@ func (t *sessionTransport) Exec(ctx context.Context, argv []string, opts sdkexec
 }
 
 func (t *sessionTransport) Put(ctx context.Context, src io.Reader, dst string, mode fs.FileMode) error {
-	data, err := io.ReadAll(src)
-	if err != nil {
-		return err
-	}
-
-	return t.session.Put(ctx, data, dst, mode)
+	return t.session.PutStream(t.withTransferEvents(ctx), src, dst, mode)
 }
 
 func (t *sessionTransport) Get(ctx context.Context, src string, dst io.Writer) error {
-	data, err := t.session.Get(ctx, src)
-	if err != nil {
-		return err
+	return t.session.GetStream(t.withTransferEvents(ctx), src, dst)
+}
+
+// withTransferEvents forwards the progress of transfers made with ctx to
+// the event stream.
+func (t *sessionTransport) withTransferEvents(ctx context.Context) context.Context {
+	if t.events == nil {
+		return ctx
 	}
 
-	_, err = dst.Write(data)
-	return err
+	lastReport := time.Now()
+	return decorator.WithTransferProgress(ctx, func(progress decorator.TransferProgress) {
+		kind := "complete"
+		if !progress.Done {
+			if time.Since(lastReport) < transferProgressInterval {
+				return
+			}
+			kind = "progress"
+		}
+		lastReport = time.Now()
+
+		attrs := map[string]string{
+			"op":      progress.Op,
+			"path":    progress.Path,
+			"session": t.session.ID(),
+			"bytes":   strconv.FormatInt(progress.Bytes, 10),
+		}
+		if progress.Done {
+			attrs["sha256"] = progress.SHA256
+		}
+		t.events.Emit(decorator.Event{
+			Decorator: "transfer",
+			Kind:      kind,
+			Attrs:     attrs,
+		})
+	})
 }
 
 func (t *sessionTransport) OpenFileWriter(ctx context.Context, path string, mode sdkexec.RedirectMode, perm fs.FileMode) (io.WriteCloser, error) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -15,7 +16,7 @@ func TestSessionTransportOpenFileReader(t *testing.T) {
 
 	workdir := t.TempDir()
 	session := decorator.NewLocalSession().WithWorkdir(workdir)
-	transport := newSessionTransport(session)
+	transport := newSessionTransport(session, nil)
 
 	path := filepath.Join(workdir, "input.txt")
 	if err := session.Put(context.Background(), []byte("input-data\n"), path, 0o644); err != nil {
@@ -44,8 +45,40 @@ func TestSessionTransportOpenFileReader(t *testing.T) {
 func TestSessionTransportOpenFileReaderMissingPath(t *testing.T) {
 	t.Parallel()
 
-	transport := newSessionTransport(decorator.NewLocalSession())
+	transport := newSessionTransport(decorator.NewLocalSession(), nil)
 	if _, err := transport.OpenFileReader(context.Background(), filepath.Join(t.TempDir(), "missing.txt")); err == nil {
 		t.Fatal("expected open file reader to fail for missing path")
 	}
 }
+
+func TestSessionTransportStreamsWithTransferEvents(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "artifact.tar")
+	var events []decorator.Event
+	transport := newSessionTransport(decorator.NewLocalSession(), eventSinkFunc(func(event decorator.Event) {
+		events = append(events, event)
+	}))
+
+	if err := transport.Put(context.Background(), strings.NewReader("payload"), path, 0o644); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	var out strings.Builder
+	if err := transport.Get(context.Background(), path, &out); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if diff := cmp.Diff("payload", out.String()); diff != "" {
+		t.Fatalf("content mismatch (-want +got):\n%s", diff)
+	}
+
+	// Short transfers finish inside the progress interval and only report
+	// completion.
+	sum := "239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5"
+	want := []decorator.Event{
+		{Decorator: "transfer", Kind: "complete", Attrs: map[string]string{"op": "put", "path": path, "session": "local", "bytes": "7", "sha256": sum}},
+		{Decorator: "transfer", Kind: "complete", Attrs: map[string]string{"op": "get", "path": path, "session": "local", "bytes": "7", "sha256": sum}},
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -49,9 +49,16 @@ type shellRunRequest struct {
 	stderr      io.Writer
 }
 
+// errShellWorkerUnsupported reports a transport that can neither fork a
+// local shell nor start a remote one; its commands always run per command.
+var errShellWorkerUnsupported = errors.New("transport does not support shell workers")
+
+var errWorkerShellExited = errors.New("worker shell exited")
+
 type workerRunError struct {
 	cause          error
 	commandStarted bool
+	protocol       bool // Worker broke the marker protocol; stop using workers for its key
 }
 
 func (e *workerRunError) Error() string {
@@ -69,12 +76,25 @@ func newWorkerRunError(cause error, commandStarted bool) error {
 	return &workerRunError{cause: cause, commandStarted: commandStarted}
 }
 
+func newWorkerProtocolError(cause error, commandStarted bool) error {
+	if cause == nil {
+		return nil
+	}
+	return &workerRunError{cause: cause, commandStarted: commandStarted, protocol: true}
+}
+
+func isWorkerProtocolError(err error) bool {
+	var runErr *workerRunError
+	return errors.As(err, &runErr) && runErr.protocol
+}
+
 type shellWorkerPool struct {
 	sessions *sessionRuntime
 
 	mu            sync.Mutex
 	workers       map[shellWorkerKey][]*shellWorker
 	commandCounts map[shellWorkerKey]int
+	disabled      map[shellWorkerKey]bool // Keys whose worker failed to start or broke protocol
 }
 
 func newShellWorkerPool(sessions *sessionRuntime) *shellWorkerPool {
@@ -82,6 +102,7 @@ func newShellWorkerPool(sessions *sessionRuntime) *shellWorkerPool {
 		sessions:      sessions,
 		workers:       make(map[shellWorkerKey][]*shellWorker),
 		commandCounts: make(map[shellWorkerKey]int),
+		disabled:      make(map[shellWorkerKey]bool),
 	}
 }
 
@@ -92,7 +113,15 @@ func (p *shellWorkerPool) shouldUseWorker(transportID, shellName string) bool {
 	defer p.mu.Unlock()
 
 	p.commandCounts[key]++
-	return p.commandCounts[key] > 1
+	return p.commandCounts[key] > 1 && !p.disabled[key]
+}
+
+// disable sends every later command for key through per-command
+// session runs.
+func (p *shellWorkerPool) disable(key shellWorkerKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disabled[key] = true
 }
 
 func (p *shellWorkerPool) Run(ctx context.Context, req shellRunRequest) (int, error) {
@@ -102,11 +131,16 @@ func (p *shellWorkerPool) Run(ctx context.Context, req shellRunRequest) (int, er
 
 	worker, err := p.acquire(req.transportID, req.shellName)
 	if err != nil {
-		return decorator.ExitFailure, newWorkerRunError(err, false)
+		p.disable(shellWorkerKey{transportID: normalizedTransportID(req.transportID), shellName: req.shellName})
+		return decorator.ExitFailure, newWorkerProtocolError(err, false)
 	}
 	defer p.release(worker)
 
-	return worker.run(ctx, req)
+	exitCode, err := worker.run(ctx, req)
+	if isWorkerProtocolError(err) {
+		p.disable(worker.key)
+	}
+	return exitCode, err
 }
 
 func (p *shellWorkerPool) Close() {
@@ -206,12 +240,17 @@ type shellWorker struct {
 	baseCwd  string
 
 	cmd    *exec.Cmd
+	shell  decorator.ShellProcess // Remote shell, when the session starts one
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	stderr io.ReadCloser
 	ctrl   *bufio.Reader
 	ctrlR  *os.File
 
+	// inbandStatus is set for remote shells, which have no control fd: the
+	// status line follows the stdout done marker instead.
+	inbandStatus bool
+
 	streamCh    chan workerStreamChunk
 	streamErrCh chan error
 	closedCh    chan struct{}
@@ -234,19 +273,73 @@ type workerStreamState struct {
 	marker []byte
 	tail   []byte
 	done   bool
+
+	// holdTrailer keeps bytes after the marker in trailer instead of passing
+	// them through, for the in-band status line.
+	holdTrailer bool
+	trailer     []byte
+}
+
+// statusLine returns the first complete line held after the marker.
+func (s *workerStreamState) statusLine() (string, bool) {
+	i := bytes.IndexByte(s.trailer, '\n')
+	if i < 0 {
+		return "", false
+	}
+	return string(s.trailer[:i]), true
 }
 
 func (w *shellWorker) start() error {
-	var cmd *exec.Cmd
+	var argv []string
 	switch w.key.shellName {
 	case "bash":
-		cmd = exec.Command("bash", "--noprofile", "--norc")
+		argv = []string{"bash", "--noprofile", "--norc"}
 	case "pwsh", "cmd":
 		return fmt.Errorf("shell workers do not support %q", w.key.shellName)
 	default:
 		return fmt.Errorf("unsupported shell %q", w.key.shellName)
 	}
 
+	w.baseEnv = w.session.Env()
+	w.baseCwd = w.session.Cwd()
+
+	var err error
+	if starter, ok := decorator.GetShellStarter(w.session); ok {
+		err = w.startRemote(starter, argv)
+	} else if w.session.TransportScope() == decorator.TransportScopeLocal {
+		err = w.startLocal(argv)
+	} else {
+		err = fmt.Errorf("%s: %w", w.key.transportID, errShellWorkerUnsupported)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.streamCh = make(chan workerStreamChunk, 128)
+	w.streamErrCh = make(chan error, 2)
+	w.closedCh = make(chan struct{})
+
+	go w.pumpStream(w.stdout, false)
+	go w.pumpStream(w.stderr, true)
+
+	readyLine := "__OPAL_WORKER_READY_" + strconv.FormatUint(shellWorkerSequence.Add(1), 10) + "__"
+	statusFD := " >&3"
+	if w.inbandStatus {
+		statusFD = ""
+	}
+	bootstrap := fmt.Sprintf("export %s=%s\nprintf '%s\\n'%s\n", workerInstanceEnvVar, quoteShellLiteral(w.instance), readyLine, statusFD)
+	if _, err := io.WriteString(w.stdin, bootstrap); err != nil {
+		w.close()
+		return fmt.Errorf("bootstrap worker: %w", err)
+	}
+
+	return w.awaitReady(readyLine)
+}
+
+// startLocal forks the shell, with fd 3 as the control pipe for status lines.
+func (w *shellWorker) startLocal(argv []string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("worker stdin pipe: %w", err)
@@ -267,8 +360,6 @@ func (w *shellWorker) start() error {
 	}
 
 	cmd.ExtraFiles = []*os.File{ctrlW}
-	w.baseEnv = w.session.Env()
-	w.baseCwd = w.session.Cwd()
 	cmd.Env = toEnvironList(w.baseEnv)
 	cmd.Dir = w.baseCwd
 
@@ -285,31 +376,52 @@ func (w *shellWorker) start() error {
 	w.stderr = stderrPipe
 	w.ctrlR = ctrlR
 	w.ctrl = bufio.NewReader(ctrlR)
-	w.streamCh = make(chan workerStreamChunk, 128)
-	w.streamErrCh = make(chan error, 2)
-	w.closedCh = make(chan struct{})
+	return nil
+}
 
-	go w.pumpStream(stdoutPipe, false)
-	go w.pumpStream(stderrPipe, true)
+// startRemote starts the shell through the session, e.g. on one SSH channel
+// that lives as long as the worker.
+func (w *shellWorker) startRemote(starter decorator.ShellStarter, argv []string) error {
+	shell, err := starter.StartShell(argv)
+	if err != nil {
+		return fmt.Errorf("start remote worker shell: %w", err)
+	}
 
-	readyMarker := strconv.FormatUint(shellWorkerSequence.Add(1), 10)
-	bootstrap := fmt.Sprintf("export %s=%s\nprintf '__OPAL_WORKER_READY_%s__\\n' >&3\n", workerInstanceEnvVar, quoteShellLiteral(w.instance), readyMarker)
-	if _, err := io.WriteString(w.stdin, bootstrap); err != nil {
-		w.close()
-		return fmt.Errorf("bootstrap worker: %w", err)
+	w.shell = shell
+	w.stdin = shell.Stdin()
+	w.stdout = io.NopCloser(shell.Stdout())
+	w.stderr = io.NopCloser(shell.Stderr())
+	w.inbandStatus = true
+	return nil
+}
+
+func (w *shellWorker) awaitReady(readyLine string) error {
+	if !w.inbandStatus {
+		for {
+			line, err := w.ctrl.ReadString('\n')
+			if err != nil {
+				w.close()
+				return fmt.Errorf("read bootstrap marker: %w", err)
+			}
+			if strings.TrimSpace(line) == readyLine {
+				return nil
+			}
+		}
 	}
 
-	for {
-		line, err := w.ctrl.ReadString('\n')
-		if err != nil {
+	state := workerStreamState{marker: []byte(readyLine + "\n")}
+	for !state.done {
+		select {
+		case chunk := <-w.streamCh:
+			if !chunk.stderr {
+				consumeWorkerStream(&state, chunk.data)
+			}
+			releaseWorkerStreamChunk(chunk)
+		case err := <-w.streamErrCh:
 			w.close()
 			return fmt.Errorf("read bootstrap marker: %w", err)
 		}
-		if strings.TrimSpace(line) == "__OPAL_WORKER_READY_"+readyMarker+"__" {
-			break
-		}
 	}
-
 	return nil
 }
 
@@ -327,7 +439,7 @@ func (w *shellWorker) run(ctx context.Context, req shellRunRequest) (int, error)
 
 	if err := w.drainPendingStreams(); err != nil {
 		w.close()
-		return decorator.ExitFailure, newWorkerRunError(err, true)
+		return decorator.ExitFailure, newWorkerProtocolError(err, true)
 	}
 
 	statusMarker := strconv.FormatUint(shellWorkerSequence.Add(1), 10)
@@ -338,10 +450,10 @@ func (w *shellWorker) run(ctx context.Context, req shellRunRequest) (int, error)
 		runReq.workdir = ""
 	}
 
-	script := buildWorkerScript(runReq, statusMarker, stdoutDoneMarker, stderrDoneMarker)
+	script := buildWorkerScript(runReq, statusMarker, stdoutDoneMarker, stderrDoneMarker, w.inbandStatus)
 	if _, err := io.WriteString(w.stdin, script); err != nil {
 		w.close()
-		return decorator.ExitFailure, newWorkerRunError(fmt.Errorf("write worker request: %w", err), false)
+		return decorator.ExitFailure, newWorkerProtocolError(fmt.Errorf("write worker request: %w", err), false)
 	}
 
 	type workerResult struct {
@@ -350,15 +462,17 @@ func (w *shellWorker) run(ctx context.Context, req shellRunRequest) (int, error)
 	}
 
 	resultCh := make(chan workerResult, 1)
-	go func() {
-		exitCode, readErr := w.readStatus(statusMarker)
-		resultCh <- workerResult{exitCode: exitCode, err: readErr}
-	}()
+	if !w.inbandStatus {
+		go func() {
+			exitCode, readErr := w.readStatus(statusMarker)
+			resultCh <- workerResult{exitCode: exitCode, err: readErr}
+		}()
+	}
 
 	var status workerResult
 	statusReady := false
 	var streamErr error
-	stdoutState := workerStreamState{marker: []byte(stdoutDoneMarker + "\n")}
+	stdoutState := workerStreamState{marker: []byte(stdoutDoneMarker + "\n"), holdTrailer: w.inbandStatus}
 	stderrState := workerStreamState{marker: []byte(stderrDoneMarker + "\n")}
 
 	recordStatus := func(result workerResult) {
@@ -369,11 +483,11 @@ func (w *shellWorker) run(ctx context.Context, req shellRunRequest) (int, error)
 	finishIfReady := func() (int, error, bool) {
 		if statusReady && status.err != nil {
 			w.close()
-			return decorator.ExitFailure, newWorkerRunError(status.err, true), true
+			return decorator.ExitFailure, newWorkerProtocolError(status.err, true), true
 		}
 		if streamErr != nil {
 			w.close()
-			return decorator.ExitFailure, newWorkerRunError(streamErr, true), true
+			return decorator.ExitFailure, newWorkerProtocolError(streamErr, true), true
 		}
 		if !statusReady || !stdoutState.done || !stderrState.done {
 			return 0, nil, false
@@ -422,6 +536,12 @@ func (w *shellWorker) run(ctx context.Context, req shellRunRequest) (int, error)
 				w.close()
 				return decorator.ExitFailure, newWorkerRunError(err, true)
 			}
+			if w.inbandStatus && !statusReady {
+				if line, ok := stdoutState.statusLine(); ok {
+					exitCode, parseErr := parseWorkerStatus(line, statusMarker)
+					recordStatus(workerResult{exitCode: exitCode, err: parseErr})
+				}
+			}
 
 		case err := <-w.streamErrCh:
 			if err != nil && streamErr == nil {
@@ -440,19 +560,30 @@ func (w *shellWorker) readStatus(marker string) (int, error) {
 			return decorator.ExitFailure, fmt.Errorf("read worker status: %w", err)
 		}
 
-		trimmed := strings.TrimSpace(line)
-		if !strings.HasPrefix(trimmed, statusPrefix) {
+		if !strings.HasPrefix(strings.TrimSpace(line), statusPrefix) {
 			continue
 		}
 
-		codeStr := strings.TrimPrefix(trimmed, statusPrefix)
-		exitCode, parseErr := strconv.Atoi(codeStr)
-		if parseErr != nil {
-			return decorator.ExitFailure, fmt.Errorf("parse worker exit code %q: %w", codeStr, parseErr)
-		}
+		return parseWorkerStatus(line, marker)
+	}
+}
+
+// parseWorkerStatus parses the status line the worker script prints after
+// the command identified by marker.
+func parseWorkerStatus(line, marker string) (int, error) {
+	statusPrefix := "__OPAL_STATUS_" + marker + ":"
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, statusPrefix) {
+		return decorator.ExitFailure, fmt.Errorf("unexpected worker status line %q", trimmed)
+	}
 
-		return exitCode, nil
+	codeStr := strings.TrimPrefix(trimmed, statusPrefix)
+	exitCode, parseErr := strconv.Atoi(codeStr)
+	if parseErr != nil {
+		return decorator.ExitFailure, fmt.Errorf("parse worker exit code %q: %w", codeStr, parseErr)
 	}
+
+	return exitCode, nil
 }
 
 func (w *shellWorker) close() {
@@ -475,6 +606,9 @@ func (w *shellWorker) close() {
 		if w.stderr != nil {
 			_ = w.stderr.Close()
 		}
+		if w.shell != nil {
+			_ = w.shell.Close()
+		}
 		if w.cmd != nil && w.cmd.Process != nil {
 			_ = w.cmd.Process.Kill()
 		}
@@ -488,12 +622,17 @@ func (w *shellWorker) isAlive() bool {
 	return w.alive.Load()
 }
 
-func buildWorkerScript(req shellRunRequest, statusMarker, stdoutDoneMarker, stderrDoneMarker string) string {
+// buildWorkerScript wraps a command in a subshell followed by the stream
+// done markers and the status line. The status line goes to the control fd,
+// or with inbandStatus to stdout right after the stdout done marker.
+func buildWorkerScript(req shellRunRequest, statusMarker, stdoutDoneMarker, stderrDoneMarker string, inbandStatus bool) string {
 	invariant.Precondition(req.command != "", "worker command cannot be empty")
 
 	var b strings.Builder
 	b.WriteString("(\n")
-	b.WriteString("exec 3>&-\n")
+	if !inbandStatus {
+		b.WriteString("exec 3>&-\n")
+	}
 
 	if req.workdir != "" {
 		b.WriteString("cd -- ")
@@ -527,7 +666,11 @@ func buildWorkerScript(req shellRunRequest, statusMarker, stdoutDoneMarker, stde
 	b.WriteString(" >&2\n")
 	b.WriteString("printf '__OPAL_STATUS_")
 	b.WriteString(statusMarker)
-	b.WriteString(":%d\\n' \"$__opal_status\" >&3\n")
+	if inbandStatus {
+		b.WriteString(":%d\\n' \"$__opal_status\"\n")
+	} else {
+		b.WriteString(":%d\\n' \"$__opal_status\" >&3\n")
+	}
 
 	return b.String()
 }
@@ -563,7 +706,12 @@ func (w *shellWorker) pumpStream(reader io.Reader, stderr bool) {
 		}
 
 		if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
-			return
+			// A remote shell has no control fd to fail on, so stdout
+			// closing is the only sign it exited.
+			if !w.inbandStatus || stderr {
+				return
+			}
+			err = errWorkerShellExited
 		}
 
 		select {
@@ -637,6 +785,10 @@ func consumeWorkerStream(state *workerStreamState, data []byte) []byte {
 		return nil
 	}
 	if state.done {
+		if state.holdTrailer {
+			state.trailer = append(state.trailer, data...)
+			return nil
+		}
 		return append([]byte(nil), data...)
 	}
 
@@ -662,9 +814,16 @@ func consumeWorkerStream(state *workerStreamState, data []byte) []byte {
 		}
 		combined = combined[idx+markerLen:]
 		state.done = true
+		if state.holdTrailer {
+			break
+		}
 	}
 
 	if state.done {
+		if state.holdTrailer {
+			state.trailer = append(state.trailer, combined...)
+			return out
+		}
 		if len(combined) > 0 {
 			out = append(out, combined...)
 		}
@@ -0,0 +1,303 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+const remoteTransportID = "transport:remote"
+
+// remoteShellSessions returns a session runtime whose remoteTransportID
+// session is remote; every other transport is local.
+func remoteShellSessions(remote decorator.Session) *sessionRuntime {
+	sessions := newSessionRuntime(func(transportID string) (decorator.Session, error) {
+		if transportID == remoteTransportID {
+			return &transportScopedSession{id: transportID, session: remote}, nil
+		}
+		return decorator.NewLocalSession(), nil
+	})
+	sessions.registerPlanTransports(localTestTransports(remoteTransportID))
+	return sessions
+}
+
+// startSSHShellSession connects to an in-process SSH server that runs
+// commands on this host.
+func startSSHShellSession(tb testing.TB) decorator.Session {
+	tb.Helper()
+
+	server := decorator.StartSSHTestServer(tb)
+	tb.Cleanup(server.Stop)
+
+	session, err := decorator.NewSSHSession(map[string]any{
+		"host": "127.0.0.1",
+		"port": server.Port,
+		"user": os.Getenv("USER"),
+		"key":  server.ClientKey, "strict_host_key": false,
+	})
+	if err != nil {
+		tb.Fatalf("connect to SSH test server: %v", err)
+	}
+	return session
+}
+
+// pipeShellSession stands in for a remote session: it is not local, and
+// StartShell runs argv (or the requested shell) as a local process.
+type pipeShellSession struct {
+	decorator.Session
+	argv []string
+}
+
+func (s *pipeShellSession) TransportScope() decorator.TransportScope {
+	return decorator.TransportScopeSSH
+}
+
+func (s *pipeShellSession) StartShell(argv []string) (decorator.ShellProcess, error) {
+	if s.argv != nil {
+		argv = s.argv
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pipeShellProcess{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr}, nil
+}
+
+type pipeShellProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr io.Reader
+}
+
+func (p *pipeShellProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *pipeShellProcess) Stdout() io.Reader     { return p.stdout }
+func (p *pipeShellProcess) Stderr() io.Reader     { return p.stderr }
+
+func (p *pipeShellProcess) Close() error {
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+	return nil
+}
+
+func TestShellWorkerRunsOverOneSSHShell(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	sessions := remoteShellSessions(startSSHShellSession(t))
+	defer sessions.Close()
+	pool := newShellWorkerPool(sessions)
+	defer pool.Close()
+
+	workdir := t.TempDir()
+	commands := []struct {
+		command  string
+		environ  map[string]string
+		workdir  string
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{command: "echo \"$" + workerInstanceEnvVar + "\"", exitCode: 0},
+		{command: "printf partial; echo oops >&2; exit 3", exitCode: 3, stdout: "partial", stderr: "oops\n"},
+		{command: "echo \"$GREETING\"", environ: map[string]string{"GREETING": "hello"}, stdout: "hello\n"},
+		{command: "pwd", workdir: workdir, stdout: workdir + "\n"},
+		{command: "echo \"$" + workerInstanceEnvVar + "\"", exitCode: 0},
+	}
+
+	var instances []string
+	for i, tc := range commands {
+		var stdout, stderr bytes.Buffer
+		exitCode, err := pool.Run(context.Background(), shellRunRequest{
+			transportID: remoteTransportID,
+			shellName:   "bash",
+			command:     tc.command,
+			environ:     tc.environ,
+			workdir:     tc.workdir,
+			stdout:      &stdout,
+			stderr:      &stderr,
+		})
+		if err != nil {
+			t.Fatalf("command %d: worker run failed: %v", i, err)
+		}
+		if diff := cmp.Diff(tc.exitCode, exitCode); diff != "" {
+			t.Errorf("command %d: exit code mismatch (-want +got):\n%s", i, diff)
+		}
+		if strings.Contains(tc.command, workerInstanceEnvVar) {
+			instances = append(instances, strings.TrimSpace(stdout.String()))
+			continue
+		}
+		if diff := cmp.Diff(tc.stdout, stdout.String()); diff != "" {
+			t.Errorf("command %d: stdout mismatch (-want +got):\n%s", i, diff)
+		}
+		if diff := cmp.Diff(tc.stderr, stderr.String()); diff != "" {
+			t.Errorf("command %d: stderr mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	if instances[0] == "" || instances[0] != instances[1] {
+		t.Fatalf("expected every command in one remote worker shell, got instances %q", instances)
+	}
+}
+
+func TestShellWorkerDisablesRemoteKeyOnProtocolError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		argv           []string
+		commandStarted bool
+	}{
+		{
+			name:           "shell fails to start",
+			argv:           []string{"/nonexistent/sigil-shell"},
+			commandStarted: false,
+		},
+		{
+			name:           "shell exits before bootstrap",
+			argv:           []string{"sh", "-c", "exit 0"},
+			commandStarted: false,
+		},
+		{
+			name:           "shell exits instead of reporting status",
+			argv:           []string{"bash", "-c", `read -r _; read -r ready; eval "$ready"; read -r _`},
+			commandStarted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sessions := remoteShellSessions(&pipeShellSession{Session: decorator.NewLocalSession(), argv: tt.argv})
+			defer sessions.Close()
+			pool := newShellWorkerPool(sessions)
+			defer pool.Close()
+
+			_ = pool.shouldUseWorker(remoteTransportID, "bash")
+			if diff := cmp.Diff(true, pool.shouldUseWorker(remoteTransportID, "bash")); diff != "" {
+				t.Fatalf("second command should use worker (-want +got):\n%s", diff)
+			}
+
+			_, err := pool.Run(context.Background(), shellRunRequest{
+				transportID: remoteTransportID,
+				shellName:   "bash",
+				command:     "echo hi",
+				stdout:      io.Discard,
+				stderr:      io.Discard,
+			})
+			var runErr *workerRunError
+			if !errors.As(err, &runErr) || !runErr.protocol {
+				t.Fatalf("expected worker protocol error, got %v", err)
+			}
+			if diff := cmp.Diff(tt.commandStarted, runErr.commandStarted); diff != "" {
+				t.Errorf("commandStarted mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(false, pool.shouldUseWorker(remoteTransportID, "bash")); diff != "" {
+				t.Fatalf("commands after a protocol error must run per command (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecuteShellWithParams_RemoteWithoutShellStarterRunsPerCommand(t *testing.T) {
+	t.Parallel()
+
+	e := &executor{sessions: remoteShellSessions(scopeOnlySession{decorator.NewLocalSession()})}
+	e.workers = newShellWorkerPool(e.sessions)
+	defer e.workers.Close()
+	defer e.sessions.Close()
+
+	ctx := newExecutionContext(map[string]interface{}{}, e, context.Background()).(*executionContext).withTransportID(remoteTransportID)
+
+	for i := 0; i < 3; i++ {
+		var stdout, stderr bytes.Buffer
+		exitCode := e.executeShellWithParams(ctx.withPipes(nil, &stdout, &stderr), map[string]any{"command": "echo run"}, nil, &stdout)
+		if diff := cmp.Diff(0, exitCode); diff != "" {
+			t.Fatalf("run %d: exit code mismatch (-want +got):\n%s", i, diff)
+		}
+		if diff := cmp.Diff("run\n", stdout.String()); diff != "" {
+			t.Errorf("run %d: stdout mismatch (-want +got):\n%s", i, diff)
+		}
+		if diff := cmp.Diff("", stderr.String()); diff != "" {
+			t.Errorf("run %d: unsupported transports must fall back silently (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// scopeOnlySession reports a remote scope without offering StartShell.
+type scopeOnlySession struct {
+	decorator.Session
+}
+
+func (s scopeOnlySession) TransportScope() decorator.TransportScope {
+	return decorator.TransportScopeSSH
+}
+
+// BenchmarkRemoteShellCommands compares one SSH channel per command with a
+// persistent worker shell on one channel.
+func BenchmarkRemoteShellCommands(b *testing.B) {
+	const commands = 20
+
+	remote := startSSHShellSession(b)
+
+	b.Run("channel_per_command", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < commands; j++ {
+				result, err := remote.Run(context.Background(), []string{"bash", "-c", ":"}, decorator.RunOpts{Stdout: io.Discard, Stderr: io.Discard})
+				if err != nil || result.ExitCode != 0 {
+					b.Fatalf("run: exit %d, err %v", result.ExitCode, err)
+				}
+			}
+		}
+	})
+
+	b.Run("persistent_worker", func(b *testing.B) {
+		sessions := remoteShellSessions(remote)
+		pool := newShellWorkerPool(sessions)
+		defer pool.Close()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < commands; j++ {
+				exitCode, err := pool.Run(context.Background(), shellRunRequest{
+					transportID: remoteTransportID,
+					shellName:   "bash",
+					command:     ":",
+					stdout:      io.Discard,
+					stderr:      io.Discard,
+				})
+				if err != nil || exitCode != 0 {
+					b.Fatalf("worker run: exit %d, err %v", exitCode, err)
+				}
+			}
+		}
+	})
+
+	_ = remote.Close()
+}
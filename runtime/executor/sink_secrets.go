@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/builtwithtofu/sigil/runtime/streamscrub"
+)
+
+// errSecretInSink is reported when a sink with the fail policy receives a
+// secret. It never carries the secret or its DisplayID.
+var errSecretInSink = errors.New("output contains a secret (sink policy is fail)")
+
+// planSinkSecrets returns the secrets policy of a redirect target.
+func planSinkSecrets(target *planfmt.CommandNode) (decorator.SinkSecrets, error) {
+	for _, arg := range target.Args {
+		if arg.Key != decorator.SinkSecretsParam {
+			continue
+		}
+		if arg.Val.Kind != planfmt.ValueString {
+			return "", fmt.Errorf("%s must be a string", decorator.SinkSecretsParam)
+		}
+		switch policy := decorator.SinkSecrets(arg.Val.Str); policy {
+		case decorator.SinkSecretsScrub, decorator.SinkSecretsPassthrough, decorator.SinkSecretsFail:
+			return policy, nil
+		default:
+			return "", fmt.Errorf("unknown %s policy %q (expected scrub, passthrough or fail)", decorator.SinkSecretsParam, arg.Val.Str)
+		}
+	}
+	return decorator.SinkSecretsScrub, nil
+}
+
+// guardSink applies policy to writes to sink. Without a provider there are
+// no known secrets and sink is returned unchanged.
+func (e *executor) guardSink(sink io.WriteCloser, policy decorator.SinkSecrets) io.WriteCloser {
+	provider := e.secretProvider()
+	if provider == nil || policy == decorator.SinkSecretsPassthrough {
+		return sink
+	}
+	if policy == decorator.SinkSecretsFail {
+		provider = rejectSecrets{provider}
+	}
+	return &scrubbedSink{
+		Scrubber: streamscrub.New(sink, streamscrub.WithSecretProvider(provider)),
+		sink:     sink,
+	}
+}
+
+// scrubbedSink scrubs output before it reaches a sink.
+type scrubbedSink struct {
+	*streamscrub.Scrubber
+	sink io.WriteCloser
+}
+
+// Close flushes output held back for secrets split across writes, then
+// closes the sink.
+func (s *scrubbedSink) Close() error {
+	flushErr := s.Scrubber.Close()
+	closeErr := s.sink.Close()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// rejectSecrets fails chunks that provider would change, so a sink with the
+// fail policy never receives a secret.
+type rejectSecrets struct {
+	provider streamscrub.SecretProvider
+}
+
+func (r rejectSecrets) HandleChunk(chunk []byte) ([]byte, error) {
+	return r.check(chunk, r.provider.HandleChunk)
+}
+
+// HandleFinalChunk implements streamscrub.FinalChunkHandler.
+func (r rejectSecrets) HandleFinalChunk(chunk []byte) ([]byte, error) {
+	if final, ok := r.provider.(streamscrub.FinalChunkHandler); ok {
+		return r.check(chunk, final.HandleFinalChunk)
+	}
+	return r.HandleChunk(chunk)
+}
+
+func (r rejectSecrets) MaxSecretLength() int {
+	return r.provider.MaxSecretLength()
+}
+
+func (r rejectSecrets) check(chunk []byte, handle func([]byte) ([]byte, error)) ([]byte, error) {
+	scrubbed, err := handle(chunk)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(scrubbed, chunk) {
+		return nil, errSecretInSink
+	}
+	return chunk, nil
+}
@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	_ "github.com/builtwithtofu/sigil/runtime/decorators"
+	"github.com/builtwithtofu/sigil/runtime/streamscrub"
+	"github.com/google/go-cmp/cmp"
+)
+
+const sinkTestSecret = "hunter2-sink-secret"
+
+func sinkTestProvider() streamscrub.SecretProvider {
+	return streamscrub.NewPatternProvider(func() []streamscrub.Pattern {
+		return []streamscrub.Pattern{{Value: []byte(sinkTestSecret), Placeholder: []byte("sigil:SINK")}}
+	})
+}
+
+func fileSink(path, policy string) planfmt.CommandNode {
+	args := []planfmt.Arg{{Key: "path", Val: planfmt.Value{Kind: planfmt.ValueString, Str: path}}}
+	if policy != "" {
+		args = append(args, planfmt.Arg{Key: "secrets", Val: planfmt.Value{Kind: planfmt.ValueString, Str: policy}})
+	}
+	return planfmt.CommandNode{Decorator: "@file", Args: args}
+}
+
+func TestPlanRedirectSinkSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string // Defaults to echoing the secret
+		target   func(path string) planfmt.CommandNode
+		wantExit int
+		wantFile string
+		wantErr  string
+	}{
+		{
+			name:     "shell target scrubs by default",
+			target:   func(path string) planfmt.CommandNode { return *shellCmd(path) },
+			wantFile: "token=sigil:SINK\n",
+		},
+		{
+			name:     "file target scrubs by default",
+			target:   func(path string) planfmt.CommandNode { return fileSink(path, "") },
+			wantFile: "token=sigil:SINK\n",
+		},
+		{
+			name:     "passthrough writes the value",
+			target:   func(path string) planfmt.CommandNode { return fileSink(path, "passthrough") },
+			wantFile: "token=" + sinkTestSecret + "\n",
+		},
+		{
+			name:     "fail rejects the output",
+			target:   func(path string) planfmt.CommandNode { return fileSink(path, "fail") },
+			wantExit: 1,
+			wantErr:  "write failed",
+		},
+		{
+			name:     "fail rejects a secret held back until close",
+			source:   "printf token=" + sinkTestSecret,
+			target:   func(path string) planfmt.CommandNode { return fileSink(path, "fail") },
+			wantExit: 1,
+			wantErr:  "write failed",
+		},
+		{
+			name:     "unknown policy fails validation",
+			target:   func(path string) planfmt.CommandNode { return fileSink(path, "redact") },
+			wantExit: 1,
+			wantErr:  `unknown secrets policy "redact"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source == "" {
+				source = "echo token=" + sinkTestSecret
+			}
+			path := filepath.Join(t.TempDir(), "out.txt")
+			plan := &planfmt.Plan{
+				Target: "redirect",
+				Steps: []planfmt.Step{{
+					ID: 1,
+					Tree: &planfmt.RedirectNode{
+						Source: shellCmd(source),
+						Target: tt.target(path),
+						Mode:   planfmt.RedirectOverwrite,
+					},
+				}},
+			}
+
+			var stderr bytes.Buffer
+			result, err := ExecutePlan(context.Background(), plan, Config{Stderr: &stderr, SecretProvider: sinkTestProvider()}, testVault())
+			if err != nil {
+				t.Fatalf("ExecutePlan() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantExit, result.ExitCode); diff != "" {
+				t.Errorf("exit code mismatch (-want +got):\n%s\nstderr: %s", diff, stderr.String())
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+
+			data, _ := os.ReadFile(path)
+			if tt.wantExit == 0 {
+				if diff := cmp.Diff(tt.wantFile, string(data)); diff != "" {
+					t.Errorf("file mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if tt.wantExit != 0 && bytes.Contains(data, []byte(sinkTestSecret)) {
+				t.Errorf("failed redirect wrote the secret: %q", data)
+			}
+			if strings.Contains(stderr.String(), sinkTestSecret) {
+				t.Errorf("stderr leaked the secret: %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestPlanRedirectSinkSecretsUsesVaultProvider(t *testing.T) {
+	vlt := testVault()
+	exprID := vlt.DeclareVariable("TOKEN", "literal:"+sinkTestSecret)
+	vlt.StoreUnresolvedValue(exprID, sinkTestSecret)
+	vlt.MarkTouched(exprID)
+	vlt.ResolveAllTouched()
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	plan := &planfmt.Plan{
+		Target: "redirect",
+		Steps: []planfmt.Step{{
+			ID: 1,
+			Tree: &planfmt.RedirectNode{
+				Source: shellCmd("printf %s " + sinkTestSecret),
+				Target: fileSink(path, ""),
+				Mode:   planfmt.RedirectOverwrite,
+			},
+		}},
+	}
+
+	result, err := ExecutePlan(context.Background(), plan, Config{}, vlt)
+	if err != nil {
+		t.Fatalf("ExecutePlan() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("exit code = %d, want 0", result.ExitCode)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if diff := cmp.Diff(vlt.GetDisplayID(exprID), string(data)); diff != "" {
+		t.Errorf("file mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"io"
 	"io/fs"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -24,6 +25,14 @@ func (s *transportScopedSession) Get(ctx context.Context, path string) ([]byte,
 	return s.session.Get(ctx, path)
 }
 
+func (s *transportScopedSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return s.session.PutStream(ctx, src, path, mode)
+}
+
+func (s *transportScopedSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return s.session.GetStream(ctx, path, dst)
+}
+
 func (s *transportScopedSession) Env() map[string]string {
 	return s.session.Env()
 }
@@ -828,6 +828,107 @@ func TestDecoratorUnknownParameter(t *testing.T) {
 	}
 }
 
+// TestSSHConnectParameterValidation tests that @ssh.connect params are
+// checked against the transport's schema at parse time
+func TestSSHConnectParameterValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantMessage string
+	}{
+		{
+			name:  "valid params",
+			input: `@ssh.connect("web", port=2222, strict_host_key=false) { echo hi }`,
+		},
+		{
+			name:        "misspelled parameter",
+			input:       `@ssh.connect(host="web", prot=2222) { echo hi }`,
+			wantMessage: "unknown parameter 'prot' for @ssh.connect",
+		},
+		{
+			name:        "string port",
+			input:       `@ssh.connect(host="web", port="2222") { echo hi }`,
+			wantMessage: "parameter 'port' expects integer between 1 and 65535, got string",
+		},
+		{
+			name:        "port out of range",
+			input:       `@ssh.connect(host="web", port=70000) { echo hi }`,
+			wantMessage: "invalid value for parameter 'port'",
+		},
+		{
+			name:        "missing host",
+			input:       `@ssh.connect(port=22) { echo hi }`,
+			wantMessage: "missing required parameter 'host'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := Parse([]byte(tt.input))
+
+			if tt.wantMessage == "" {
+				if len(tree.Errors) > 0 {
+					t.Fatalf("Expected no errors, got: %v", tree.Errors)
+				}
+				return
+			}
+			if len(tree.Errors) == 0 {
+				t.Fatal("Expected error but got none")
+			}
+			if tree.Errors[0].Message != tt.wantMessage {
+				t.Errorf("Message mismatch:\ngot:  %q\nwant: %q", tree.Errors[0].Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSSHEachParameterValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantMessage string
+	}{
+		{
+			name:  "valid params",
+			input: `@ssh.each(hosts=["web01", "web02"], maxConcurrency=10, failureThreshold=2, user="deploy") { echo hi }`,
+		},
+		{
+			name:        "host belongs to hosts",
+			input:       `@ssh.each(hosts=["web01"], host="web02") { echo hi }`,
+			wantMessage: "unknown parameter 'host' for @ssh.each",
+		},
+		{
+			name:        "string threshold",
+			input:       `@ssh.each(hosts=["web01"], failureThreshold="2") { echo hi }`,
+			wantMessage: "parameter 'failureThreshold' expects integer >= 0, got string",
+		},
+		{
+			name:        "missing hosts",
+			input:       `@ssh.each(user="deploy") { echo hi }`,
+			wantMessage: "missing required parameter 'hosts'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := Parse([]byte(tt.input))
+
+			if tt.wantMessage == "" {
+				if len(tree.Errors) > 0 {
+					t.Fatalf("Expected no errors, got: %v", tree.Errors)
+				}
+				return
+			}
+			if len(tree.Errors) == 0 {
+				t.Fatal("Expected error but got none")
+			}
+			if tree.Errors[0].Message != tt.wantMessage {
+				t.Errorf("Message mismatch:\ngot:  %q\nwant: %q", tree.Errors[0].Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
 // TestDecoratorWithBlock tests decorator block parsing
 func TestDecoratorWithBlock(t *testing.T) {
 	// First, register a test decorator that accepts blocks
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/runtime/lexer"
+	"github.com/google/go-cmp/cmp"
+)
+
+// needsNames returns the identifiers inside each NodeFunctionNeeds node.
+func needsNames(tree *ParseTree) [][]string {
+	var lists [][]string
+	depth := 0
+	var current []string
+	for _, event := range tree.Events {
+		switch event.Kind {
+		case EventOpen:
+			if NodeKind(event.Data) == NodeFunctionNeeds {
+				depth++
+				current = nil
+			}
+		case EventClose:
+			if NodeKind(event.Data) == NodeFunctionNeeds {
+				depth--
+				lists = append(lists, current)
+			}
+		case EventToken:
+			tok := tree.Tokens[event.Data]
+			if depth > 0 && tok.Type == lexer.IDENTIFIER && string(tok.Text) != "needs" {
+				current = append(current, string(tok.Text))
+			}
+		}
+	}
+	return lists
+}
+
+func TestFunctionNeeds_ParsesDependencyList(t *testing.T) {
+	input := `fun build { echo "build" }
+fun test { echo "test" }
+fun deploy needs build, test {
+	echo "deploy"
+}`
+
+	tree := ParseString(input)
+
+	if len(tree.Errors) != 0 {
+		t.Fatalf("expected no parse errors, got %v", tree.Errors)
+	}
+	if diff := cmp.Diff([][]string{{"build", "test"}}, needsNames(tree)); diff != "" {
+		t.Errorf("needs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFunctionNeeds_AfterParamsAndShorthandBody(t *testing.T) {
+	input := `fun lint = echo "lint"
+fun release(tag String = "dev") needs lint = echo "release"`
+
+	tree := ParseString(input)
+
+	if len(tree.Errors) != 0 {
+		t.Fatalf("expected no parse errors, got %v", tree.Errors)
+	}
+	if diff := cmp.Diff([][]string{{"lint"}}, needsNames(tree)); diff != "" {
+		t.Errorf("needs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(1, countOpenNodesOfKind(tree.Events, NodeParamList)); diff != "" {
+		t.Errorf("param list count mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFunctionNeeds_MissingNameIsError(t *testing.T) {
+	tree := ParseString(`fun deploy needs { echo "deploy" }`)
+
+	if len(tree.Errors) == 0 {
+		t.Fatal("expected parse error for empty needs list")
+	}
+	if !strings.Contains(tree.Errors[0].Message, "after 'needs'") {
+		t.Errorf("unexpected error message: %q", tree.Errors[0].Message)
+	}
+}
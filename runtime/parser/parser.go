@@ -326,7 +326,7 @@ func (p *parser) file() {
 	}
 }
 
-// function parses a function declaration: fun IDENTIFIER ParamList Block
+// function parses a function declaration: fun IDENTIFIER ParamList [needs Names] Block
 func (p *parser) function() {
 	if p.config.debug > DebugOff {
 		p.recordDebugEvent("enter_function", "parsing function")
@@ -351,6 +351,11 @@ func (p *parser) function() {
 		p.paramList()
 	}
 
+	// Parse dependency list (optional): needs build, test
+	if p.at(lexer.IDENTIFIER) && string(p.current().Text) == "needs" {
+		p.functionNeeds()
+	}
+
 	// Parse body: either = expression/shell or block (required)
 	if p.at(lexer.EQUALS) {
 		p.token() // Consume '='
@@ -383,6 +388,34 @@ func (p *parser) function() {
 	}
 }
 
+// functionNeeds parses a function dependency list: needs IDENTIFIER (, IDENTIFIER)*
+func (p *parser) functionNeeds() {
+	kind := p.start(NodeFunctionNeeds)
+
+	// Consume 'needs'
+	p.token()
+
+	for {
+		if !p.at(lexer.IDENTIFIER) {
+			p.errorWithDetails(
+				"expected function name after 'needs'",
+				"function dependencies",
+				"List the functions that must run first, separated by commas",
+			)
+			break
+		}
+		p.token()
+
+		if !p.at(lexer.COMMA) {
+			break
+		}
+		p.token() // Consume ','
+		p.skipNewlines()
+	}
+
+	p.finish(kind)
+}
+
 // structDecl parses a struct declaration: struct IDENTIFIER { fields }
 func (p *parser) structDecl() {
 	kind := p.start(NodeStructDecl)
@@ -1781,7 +1814,7 @@ func (p *parser) varDecl() {
 	}
 }
 
-// varDeclSingle parses a single variable declaration: var IDENTIFIER = expression
+// varDeclSingle parses a single variable declaration: var IDENTIFIER [sensitivity] = expression
 func (p *parser) varDeclSingle() {
 	kind := p.start(NodeVarDecl)
 
@@ -1794,6 +1827,11 @@ func (p *parser) varDeclSingle() {
 		return
 	}
 
+	// Optional sensitivity annotation
+	if p.at(lexer.IDENTIFIER) {
+		p.sensitivity()
+	}
+
 	// Expect '='
 	if !p.expect(lexer.EQUALS, "variable declaration") {
 		p.finish(kind)
@@ -1841,7 +1879,7 @@ func (p *parser) varDeclBlock() {
 	}
 }
 
-// varDeclSingleWithoutVar parses a single variable declaration without the 'var' keyword: IDENTIFIER = expression
+// varDeclSingleWithoutVar parses a single variable declaration without the 'var' keyword: IDENTIFIER [sensitivity] = expression
 func (p *parser) varDeclSingleWithoutVar() {
 	kind := p.start(NodeVarDecl)
 
@@ -1851,6 +1889,11 @@ func (p *parser) varDeclSingleWithoutVar() {
 		return
 	}
 
+	// Optional sensitivity annotation
+	if p.at(lexer.IDENTIFIER) {
+		p.sensitivity()
+	}
+
 	// Expect '='
 	if !p.expect(lexer.EQUALS, "variable declaration") {
 		p.finish(kind)
@@ -1863,6 +1906,22 @@ func (p *parser) varDeclSingleWithoutVar() {
 	p.finish(kind)
 }
 
+// sensitivity parses a variable sensitivity annotation: secret, sensitive or public
+func (p *parser) sensitivity() {
+	kind := p.start(NodeSensitivity)
+
+	if _, err := decorator.ParseSensitivity(string(p.current().Text)); err != nil {
+		p.errorWithDetails(
+			fmt.Sprintf("unknown sensitivity %q", p.current().Text),
+			"variable declaration",
+			"Use secret, sensitive or public: var token secret = \"...\"",
+		)
+	}
+	p.token()
+
+	p.finish(kind)
+}
+
 // assignmentStmt parses an assignment statement: IDENTIFIER OP= expression
 func (p *parser) assignmentStmt() {
 	if p.config.debug > DebugOff {
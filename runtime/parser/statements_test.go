@@ -86,6 +86,46 @@ func TestSimpleVarDecl(t *testing.T) {
 	}
 }
 
+// TestVarDeclSensitivity tests the optional sensitivity annotation: var name level = value
+func TestVarDeclSensitivity(t *testing.T) {
+	tree := Parse([]byte(`var token secret = "abc"`))
+	if len(tree.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", tree.Errors)
+	}
+
+	want := []Event{
+		{Kind: EventOpen, Data: uint32(NodeSource)},
+		{Kind: EventStepEnter, Data: 0},
+		{Kind: EventOpen, Data: uint32(NodeVarDecl)},
+		{Kind: EventToken, Data: 0}, // VAR
+		{Kind: EventToken, Data: 1}, // token
+		{Kind: EventOpen, Data: uint32(NodeSensitivity)},
+		{Kind: EventToken, Data: 2}, // secret
+		{Kind: EventClose, Data: uint32(NodeSensitivity)},
+		{Kind: EventToken, Data: 3}, // =
+		{Kind: EventOpen, Data: uint32(NodeLiteral)},
+		{Kind: EventToken, Data: 4}, // "abc"
+		{Kind: EventClose, Data: uint32(NodeLiteral)},
+		{Kind: EventClose, Data: uint32(NodeVarDecl)},
+		{Kind: EventStepExit, Data: 0},
+		{Kind: EventClose, Data: uint32(NodeSource)},
+	}
+	if diff := cmp.Diff(want, tree.Events); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, input := range []string{"var region sensitive = \"us\"", "var (\n\treplicas public = 3\n)"} {
+		if tree := Parse([]byte(input)); len(tree.Errors) > 0 {
+			t.Errorf("%q: unexpected errors: %v", input, tree.Errors)
+		}
+	}
+
+	tree = Parse([]byte(`var token private = "abc"`))
+	if len(tree.Errors) != 1 || tree.Errors[0].Message != `unknown sensitivity "private"` {
+		t.Errorf("expected unknown sensitivity error, got %v", tree.Errors)
+	}
+}
+
 // TestAssignmentOperators tests assignment operators (+=, -=, *=, /=, %=)
 func TestAssignmentOperators(t *testing.T) {
 	tests := []struct {
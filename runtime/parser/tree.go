@@ -122,6 +122,12 @@ const (
 
 	// Qualified value references - added at end to preserve existing node numbers
 	NodeQualifiedRef // Qualified reference expression: Type.Member
+
+	// Function dependencies - added at end to preserve existing node numbers
+	NodeFunctionNeeds // Function dependency list: needs build, test
+
+	// Value sensitivity - added at end to preserve existing node numbers
+	NodeSensitivity // Sensitivity annotation: var token secret = ...
 )
 
 // ErrorCode represents a structured error code for schema validation errors
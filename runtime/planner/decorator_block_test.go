@@ -219,3 +219,138 @@ func TestDecoratorBlock_NoArguments(t *testing.T) {
 	t.Logf("✓ @exec.parallel decorator created correctly")
 	t.Logf("✓ Block contains %d steps", len(cmd.Block))
 }
+
+// TestDecoratorBlock_RollbackAppearsInPlan verifies @exec.rollback blocks are
+// emitted as ordinary decorator steps so reviewers see compensations in the
+// plan and the contract hash covers them.
+func TestDecoratorBlock_RollbackAppearsInPlan(t *testing.T) {
+	source := `
+kubectl apply -f k8s/
+@exec.rollback {
+    kubectl rollout undo deployment/app
+}
+curl -f http://app/health
+`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	plan := result.Plan
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(plan.Steps))
+	}
+
+	cmd, ok := plan.Steps[1].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("Expected CommandNode, got %T", plan.Steps[1].Tree)
+	}
+	if cmd.Decorator != "@exec.rollback" {
+		t.Errorf("Expected decorator '@exec.rollback', got '%s'", cmd.Decorator)
+	}
+	if len(cmd.Block) != 1 {
+		t.Fatalf("Expected 1 block step in @exec.rollback, got %d", len(cmd.Block))
+	}
+
+	undo, ok := cmd.Block[0].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("Expected undo CommandNode, got %T", cmd.Block[0].Tree)
+	}
+	if got := getCommandArg(undo, "command"); got != "kubectl rollout undo deployment/app" {
+		t.Errorf("Expected rollback command in plan, got %q", got)
+	}
+}
+
+func TestDecoratorBlock_ArgsSortedInPlan(t *testing.T) {
+	source := `
+@exec.retry(times=2, delay=1s) {
+    echo "retrying"
+}
+`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	cmd, ok := result.Plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("Expected CommandNode, got %T", result.Plan.Steps[0].Tree)
+	}
+
+	keys := make([]string, 0, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		keys = append(keys, arg.Key)
+	}
+	want := []string{"delay", "times"}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("Expected args %v (sorted), got %v", want, keys)
+	}
+}
+
+func TestDecoratorBlock_CachePinsKeyInPlan(t *testing.T) {
+	planCache := func(args string) *planfmt.CommandNode {
+		t.Helper()
+		tree := parser.ParseString("@exec.cache(" + args + ") {\n    go build -o bin/app .\n}\n")
+		if len(tree.Errors) > 0 {
+			t.Fatalf("Parse errors: %v", tree.Errors)
+		}
+
+		result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		if len(result.Plan.Steps) != 1 {
+			t.Fatalf("Expected 1 step, got %d", len(result.Plan.Steps))
+		}
+		cmd, ok := result.Plan.Steps[0].Tree.(*planfmt.CommandNode)
+		if !ok {
+			t.Fatalf("Expected CommandNode, got %T", result.Plan.Steps[0].Tree)
+		}
+		return cmd
+	}
+
+	cmd := planCache(`outputs=["bin/app"], inputs=["**/*.go", "go.mod"], key="v1"`)
+	if cmd.Decorator != "@exec.cache" {
+		t.Errorf("Expected decorator '@exec.cache', got '%s'", cmd.Decorator)
+	}
+
+	keys := make([]string, 0, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		keys = append(keys, arg.Key)
+	}
+	want := []string{"_cache_digest", "inputs", "key", "outputs"}
+	if len(keys) != len(want) {
+		t.Fatalf("Expected args %v, got %v", want, keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("Expected args %v (sorted), got %v", want, keys)
+		}
+	}
+	if got := getCommandArg(cmd, "key"); got != "v1" {
+		t.Errorf("Expected cache key 'v1' in plan, got %q", got)
+	}
+
+	cacheKey := getCommandArg(cmd, "_cache_digest")
+	if len(cacheKey) != 64 {
+		t.Errorf("Expected a SHA-256 _cache_digest in plan, got %q", cacheKey)
+	}
+	if got := getCommandArg(planCache(`inputs=["go.mod", "**/*.go"], key="v1", outputs=["bin/app"]`), "_cache_digest"); got != cacheKey {
+		t.Errorf("_cache_digest should not depend on pattern order: %q != %q", got, cacheKey)
+	}
+	if got := getCommandArg(planCache(`outputs=["bin/app"], inputs=["**/*.go", "go.mod"], key="v2"`), "_cache_digest"); got == cacheKey {
+		t.Errorf("_cache_digest should change with key=")
+	}
+}
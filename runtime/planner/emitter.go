@@ -150,6 +150,13 @@ func (e *Emitter) emitStatements(stmts []*StatementIR) ([]planfmt.Step, error) {
 				return nil, err
 			}
 			steps = append(steps, callSteps...)
+
+		case StmtDependencies:
+			dagSteps, err := e.emitDependencies(stmt.Dependencies)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, dagSteps...)
 		}
 
 		i++
@@ -184,6 +191,34 @@ func (e *Emitter) emitCallTrace(trace *CallTraceStmtIR) ([]planfmt.Step, error)
 	return []planfmt.Step{step}, nil
 }
 
+// emitDependencies emits a function dependency graph as a single DAGNode step.
+func (e *Emitter) emitDependencies(graph *DependencyGraphIR) ([]planfmt.Step, error) {
+	if graph == nil || len(graph.Tasks) == 0 {
+		return nil, nil
+	}
+
+	tasks := make([]planfmt.DAGTask, 0, len(graph.Tasks))
+	for _, task := range graph.Tasks {
+		block, err := e.emitStatements(task.Block)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %q: %w", task.Name, err)
+		}
+		tasks = append(tasks, planfmt.DAGTask{
+			Name:  task.Name,
+			Needs: append([]string(nil), task.Needs...),
+			Block: block,
+		})
+	}
+
+	step := planfmt.Step{
+		ID:   e.nextStepID,
+		Tree: &planfmt.DAGNode{Tasks: tasks},
+	}
+	e.nextStepID++
+
+	return []planfmt.Step{step}, nil
+}
+
 // emitCommandChain emits a chain of commands (possibly connected by operators) as a single Step.
 // For a single command, returns a Step with CommandNode.
 // For multiple commands, builds an operator tree (AndNode, OrNode, PipelineNode, SequenceNode).
@@ -268,6 +303,11 @@ func (e *Emitter) buildCommandNode(cmd *CommandStmtIR) (*planfmt.CommandNode, er
 		})
 	}
 
+	// Plan invariant: args are sorted by key regardless of source order.
+	sort.Slice(args, func(i, j int) bool {
+		return args[i].Key < args[j].Key
+	})
+
 	cmdNode := &planfmt.CommandNode{
 		Decorator:   cmd.Decorator,
 		TransportID: e.currentTransportID(),
@@ -420,6 +460,10 @@ func (e *Emitter) buildRedirectDecoratorTargetNode(target *CommandExpr) (*planfm
 		args = append(args, planfmt.Arg{Key: argName, Val: argVal})
 	}
 
+	sort.Slice(args, func(i, j int) bool {
+		return args[i].Key < args[j].Key
+	})
+
 	return &planfmt.CommandNode{
 		Decorator:   decoratorName,
 		TransportID: e.currentTransportID(),
@@ -715,11 +759,7 @@ func (e *Emitter) collectDisplayID(expr *ExprIR, displayIDs map[string]string, p
 		// Look up exprID from scopes
 		if e.scopes != nil {
 			if exprID, ok := e.scopes.Lookup(expr.VarName); ok {
-				if displayID := e.vault.GetDisplayID(exprID); displayID != "" {
-					displayIDs[expr.VarName] = displayID
-					// Record secret use at current site
-					e.recordSecretUse(exprID, displayID, paramName)
-				}
+				e.collectRefDisplay(exprID, expr.VarName, displayIDs, paramName)
 			}
 		}
 
@@ -727,10 +767,7 @@ func (e *Emitter) collectDisplayID(expr *ExprIR, displayIDs map[string]string, p
 		// Build decorator key and look up DisplayID
 		key := decoratorKey(expr.Decorator)
 		if exprID, ok := e.decoratorExprIDs[key]; ok {
-			if displayID := e.vault.GetDisplayID(exprID); displayID != "" {
-				displayIDs[key] = displayID
-				e.recordSecretUse(exprID, displayID, paramName)
-			}
+			e.collectRefDisplay(exprID, key, displayIDs, paramName)
 		}
 
 	case ExprEnumMemberRef:
@@ -760,6 +797,66 @@ func (e *Emitter) collectDisplayID(expr *ExprIR, displayIDs map[string]string, p
 	}
 }
 
+// collectRefDisplay maps a reference to what the plan shows for it: the
+// DisplayID for masked values, the value itself for public scalars.
+// Only masked values are recorded as secret uses.
+func (e *Emitter) collectRefDisplay(exprID, key string, displayIDs map[string]string, paramName string) {
+	displayID := e.vault.GetDisplayID(exprID)
+	if displayID == "" {
+		return
+	}
+	if text, ok := e.publicText(exprID); ok {
+		displayIDs[key] = text
+		return
+	}
+	displayIDs[key] = displayID
+	// Record secret use at current site
+	e.recordSecretUse(exprID, displayID, paramName)
+}
+
+// publicValue returns the value of a public expression.
+// Non-scalar values stay masked: they have no single textual form.
+func (e *Emitter) publicValue(exprID string) (any, bool) {
+	if e.vault.Sensitivity(exprID).Masked() {
+		return nil, false
+	}
+	value, ok := e.vault.GetUnresolvedValue(exprID)
+	if !ok {
+		return nil, false
+	}
+	switch value.(type) {
+	case string, int, int64, float64, bool, durationLiteral:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+// publicText returns the plan text of a public expression.
+func (e *Emitter) publicText(exprID string) (string, bool) {
+	value, ok := e.publicValue(exprID)
+	if !ok {
+		return "", false
+	}
+	return literalToString(value), true
+}
+
+// refExprID returns the expression ID a var or decorator reference points to.
+func (e *Emitter) refExprID(expr *ExprIR) (string, bool) {
+	switch expr.Kind {
+	case ExprVarRef:
+		if e.scopes == nil {
+			return "", false
+		}
+		return e.scopes.Lookup(expr.VarName)
+	case ExprDecoratorRef:
+		exprID, ok := e.decoratorExprIDs[decoratorKey(expr.Decorator)]
+		return exprID, ok
+	default:
+		return "", false
+	}
+}
+
 func (e *Emitter) buildArgValue(expr *ExprIR, displayIDs map[string]string) (planfmt.Value, error) {
 	if expr == nil {
 		return planfmt.Value{Kind: planfmt.ValueString, Str: ""}, nil
@@ -769,6 +866,12 @@ func (e *Emitter) buildArgValue(expr *ExprIR, displayIDs map[string]string) (pla
 	case ExprLiteral:
 		return e.literalToArgValue(expr.Value, displayIDs)
 	case ExprVarRef, ExprDecoratorRef:
+		// Public values keep their type in the plan
+		if exprID, ok := e.refExprID(expr); ok {
+			if value, ok := e.publicValue(exprID); ok {
+				return e.literalToArgValue(value, displayIDs)
+			}
+		}
 		return planfmt.Value{Kind: planfmt.ValueString, Str: RenderExpr(expr, displayIDs)}, nil
 	case ExprBinaryOp, ExprEnumMemberRef:
 		if value, err := EvaluateExpr(expr, e.getValue); err == nil {
@@ -892,11 +995,16 @@ func (e *Emitter) currentDecoratorCounts() map[string]int {
 func (e *Emitter) recordSecretUse(exprID, displayID, paramName string) {
 	site := e.buildSitePath(paramName)
 	siteID := e.computeSiteID(site)
+	metadata := e.vault.Metadata(exprID)
 
 	e.secretUses = append(e.secretUses, planfmt.SecretUse{
 		DisplayID: displayID,
 		SiteID:    siteID,
 		Site:      site,
+		Origin:    e.vault.Origin(exprID),
+		Version:   metadata.Version,
+		CreatedAt: metadata.CreatedAt,
+		ExpiresAt: metadata.ExpiresAt,
 	})
 }
 
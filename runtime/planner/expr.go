@@ -720,6 +720,8 @@ func evaluateExprObject(obj map[string]*ExprIR, getValue ValueLookup) (any, erro
 
 // decoratorKey builds a lookup key for a decorator reference.
 // e.g., DecoratorRef{Name: "env", Selector: ["HOME"]} → "env.HOME"
+// Arguments are part of the key, so calls that differ only in arguments
+// (@secrets.get(path="a") and @secrets.get(path="b")) stay distinct.
 func decoratorKey(d *DecoratorRef) string {
 	if d == nil {
 		return ""
@@ -728,7 +730,58 @@ func decoratorKey(d *DecoratorRef) string {
 	for _, s := range d.Selector {
 		key += "." + s
 	}
-	return key
+	return key + decoratorArgsKey(d)
+}
+
+// decoratorArgsKey renders a decorator's arguments deterministically,
+// e.g. `(file="a.env", key="TOKEN")`. Empty when there are no arguments.
+func decoratorArgsKey(d *DecoratorRef) string {
+	if len(d.Args) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(")
+	for i, arg := range d.Args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if i < len(d.ArgNames) && d.ArgNames[i] != "" {
+			sb.WriteString(d.ArgNames[i])
+			sb.WriteString("=")
+		}
+		sb.WriteString(exprKey(arg))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// exprKey renders an unevaluated expression for use in lookup keys.
+func exprKey(expr *ExprIR) string {
+	if expr == nil {
+		return "nil"
+	}
+
+	switch expr.Kind {
+	case ExprLiteral:
+		return fmt.Sprintf("%#v", expr.Value)
+	case ExprVarRef:
+		return "@var." + expr.VarName
+	case ExprDecoratorRef:
+		return "@" + decoratorKey(expr.Decorator)
+	case ExprEnumMemberRef:
+		return enumMemberRefKey(expr.EnumName, expr.EnumMember)
+	case ExprBinaryOp:
+		return "(" + exprKey(expr.Left) + " " + expr.Op + " " + exprKey(expr.Right) + ")"
+	case ExprTypeCast:
+		key := exprKey(expr.Left) + " as " + expr.TypeName
+		if expr.Optional {
+			key += "?"
+		}
+		return key
+	default:
+		return fmt.Sprintf("expr(%d)", expr.Kind)
+	}
 }
 
 func enumMemberRefKey(enumName, member string) string {
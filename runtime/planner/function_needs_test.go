@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/google/go-cmp/cmp"
+)
+
+type dagTaskSummary struct {
+	Name     string
+	Needs    []string
+	Commands []string
+}
+
+func summarizeDAG(t *testing.T, node planfmt.ExecutionNode) []dagTaskSummary {
+	t.Helper()
+	dag, ok := node.(*planfmt.DAGNode)
+	if !ok {
+		t.Fatalf("expected DAGNode, got %T", node)
+	}
+	summary := make([]dagTaskSummary, 0, len(dag.Tasks))
+	for _, task := range dag.Tasks {
+		s := dagTaskSummary{Name: task.Name, Needs: task.Needs}
+		for _, step := range task.Block {
+			s.Commands = append(s.Commands, getCommandArg(step.Tree, "command"))
+		}
+		summary = append(summary, s)
+	}
+	return summary
+}
+
+func TestFunctionNeeds_BuildsDeduplicatedDAG(t *testing.T) {
+	source := `fun build { echo "build" }
+fun lint { echo "lint" }
+fun test needs build { echo "test" }
+fun deploy needs test, lint, build {
+	echo "deploy"
+}`
+
+	plan, _, graph, err := planWithPipeline(t, source, "deploy")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"test", "lint", "build"}, graph.Functions["deploy"].Needs); diff != "" {
+		t.Errorf("IR needs mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(plan.Steps) != 2 {
+		t.Fatalf("expected dependency step and body step, got %d steps", len(plan.Steps))
+	}
+
+	want := []dagTaskSummary{
+		{Name: "build", Commands: []string{`echo "build"`}},
+		{Name: "test", Needs: []string{"build"}, Commands: []string{`echo "test"`}},
+		{Name: "lint", Commands: []string{`echo "lint"`}},
+	}
+	if diff := cmp.Diff(want, summarizeDAG(t, plan.Steps[0].Tree)); diff != "" {
+		t.Errorf("DAG mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(`echo "deploy"`, getCommandArg(plan.Steps[1].Tree, "command")); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+	if err := plan.Validate(); err != nil {
+		t.Errorf("plan should validate: %v", err)
+	}
+}
+
+func TestFunctionNeeds_RejectsCycleWithPath(t *testing.T) {
+	source := `fun build needs test { echo "build" }
+fun test needs build { echo "test" }
+fun deploy needs build { echo "deploy" }`
+
+	_, _, _, err := planWithPipeline(t, source, "deploy")
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if diff := cmp.Diff("function dependency cycle: build -> test -> build", err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFunctionNeeds_RejectsSelfDependency(t *testing.T) {
+	_, _, _, err := planWithPipeline(t, `fun deploy needs deploy { echo "deploy" }`, "deploy")
+	if err == nil || err.Error() != "function dependency cycle: deploy -> deploy" {
+		t.Fatalf("expected self-cycle error, got %v", err)
+	}
+}
+
+func TestFunctionNeeds_RejectsUnknownFunction(t *testing.T) {
+	_, _, _, err := planWithPipeline(t, `fun deploy needs build { echo "deploy" }`, "deploy")
+	if err == nil || !strings.Contains(err.Error(), `function "deploy" needs unknown function "build"`) {
+		t.Fatalf("expected unknown dependency error, got %v", err)
+	}
+}
+
+func TestFunctionNeeds_RejectsDependencyWithRequiredParams(t *testing.T) {
+	source := `fun build(target String) { echo @var.target }
+fun deploy needs build { echo "deploy" }`
+
+	_, _, _, err := planWithPipeline(t, source, "deploy")
+	if err == nil || !strings.Contains(err.Error(), `function "build" (needed by "deploy")`) {
+		t.Fatalf("expected dependency argument error, got %v", err)
+	}
+}
+
+func TestFunctionNeeds_ExpandedOnCall(t *testing.T) {
+	source := `fun build { echo "build" }
+fun deploy needs build { echo "deploy" }
+deploy()`
+
+	plan, _, _, err := planWithPipeline(t, source, "")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("expected one call step, got %d", len(plan.Steps))
+	}
+	call, ok := plan.Steps[0].Tree.(*planfmt.LogicNode)
+	if !ok || call.Kind != "call" {
+		t.Fatalf("expected call trace, got %T", plan.Steps[0].Tree)
+	}
+	if len(call.Block) != 2 {
+		t.Fatalf("expected dependency step and body step in call, got %d", len(call.Block))
+	}
+	want := []dagTaskSummary{{Name: "build", Commands: []string{`echo "build"`}}}
+	if diff := cmp.Diff(want, summarizeDAG(t, call.Block[0].Tree)); diff != "" {
+		t.Errorf("DAG mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFunctionNeeds_EdgesChangeContractHash(t *testing.T) {
+	hashOf := func(source string) [32]byte {
+		t.Helper()
+		plan, _, _, err := planWithPipeline(t, source, "deploy")
+		if err != nil {
+			t.Fatalf("plan failed: %v", err)
+		}
+		canonical, err := plan.Canonicalize()
+		if err != nil {
+			t.Fatalf("canonicalize: %v", err)
+		}
+		hash, err := canonical.Hash()
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		return hash
+	}
+
+	parallel := hashOf(`fun build { echo "build" }
+fun test { echo "test" }
+fun deploy needs build, test { echo "deploy" }`)
+	ordered := hashOf(`fun build { echo "build" }
+fun test needs build { echo "test" }
+fun deploy needs build, test { echo "deploy" }`)
+
+	if parallel == ordered {
+		t.Error("contract hash must change when dependency edges change")
+	}
+}
@@ -1,5 +1,7 @@
 package planner
 
+import "github.com/builtwithtofu/sigil/core/decorator"
+
 // ExecutionGraph is the complete execution graph built from parser events.
 // Contains all possible execution paths - the Resolver determines which are taken.
 type ExecutionGraph struct {
@@ -42,6 +44,7 @@ type EnumMemberIR struct {
 type FunctionIR struct {
 	Name   string
 	Params []ParamIR // Function parameters
+	Needs  []string  // Functions that must complete first (fun deploy needs build, test)
 	Body   []*StatementIR
 	Span   SourceSpan
 	Scopes *ScopeStack // Scope snapshot for command mode prelude
@@ -64,6 +67,7 @@ const (
 	StmtTry                               // Try/catch/finally error handling
 	StmtFunctionCall                      // Function call statement
 	StmtCallTrace                         // Call provenance wrapper (display-only)
+	StmtDependencies                      // Function dependency graph (fun ... needs ...)
 )
 
 // StatementIR represents a statement in the execution graph.
@@ -79,6 +83,7 @@ type StatementIR struct {
 	Try          *TryIR              // For StmtTry
 	FunctionCall *FunctionCallStmtIR // For StmtFunctionCall
 	CallTrace    *CallTraceStmtIR    // For StmtCallTrace
+	Dependencies *DependencyGraphIR  // For StmtDependencies
 }
 
 // CommandStmtIR represents a command statement.
@@ -105,6 +110,20 @@ type CallTraceStmtIR struct {
 	Block []*StatementIR // Fully resolved expanded statements
 }
 
+// DependencyGraphIR holds the expanded dependencies of a function declared
+// with `needs`. Tasks are in topological order: every name in a task's Needs
+// refers to an earlier task. Shared dependencies appear exactly once.
+type DependencyGraphIR struct {
+	Tasks []DependencyTaskIR
+}
+
+// DependencyTaskIR is one function in a dependency graph.
+type DependencyTaskIR struct {
+	Name  string         // Function name
+	Needs []string       // Direct dependencies, in declaration order
+	Block []*StatementIR // Fully resolved function body
+}
+
 // ArgIR represents a decorator argument.
 type ArgIR struct {
 	Name  string // Parameter name
@@ -116,6 +135,10 @@ type VarDeclIR struct {
 	Name   string  // Variable name (without @var. prefix)
 	Value  *ExprIR // Value expression
 	ExprID string  // Unique expression ID (set during IR building)
+
+	// Sensitivity is the explicit annotation (var token secret = ...).
+	// Nil means the level is inferred from Value.
+	Sensitivity *decorator.Sensitivity
 }
 
 // BlockerKind identifies the type of control flow blocker.
@@ -276,10 +299,27 @@ func DeepCopyStatement(stmt *StatementIR) *StatementIR {
 		result.FunctionCall = deepCopyFunctionCallStmt(stmt.FunctionCall)
 	case StmtCallTrace:
 		result.CallTrace = deepCopyCallTraceStmt(stmt.CallTrace)
+	case StmtDependencies:
+		result.Dependencies = deepCopyDependencyGraph(stmt.Dependencies)
 	}
 	return result
 }
 
+func deepCopyDependencyGraph(graph *DependencyGraphIR) *DependencyGraphIR {
+	if graph == nil {
+		return nil
+	}
+	tasks := make([]DependencyTaskIR, len(graph.Tasks))
+	for i, task := range graph.Tasks {
+		tasks[i] = DependencyTaskIR{
+			Name:  task.Name,
+			Needs: append([]string(nil), task.Needs...),
+			Block: DeepCopyStatements(task.Block),
+		}
+	}
+	return &DependencyGraphIR{Tasks: tasks}
+}
+
 func deepCopyCallTraceStmt(trace *CallTraceStmtIR) *CallTraceStmtIR {
 	if trace == nil {
 		return nil
@@ -334,9 +374,10 @@ func deepCopyVarDecl(decl *VarDeclIR) *VarDeclIR {
 		return nil
 	}
 	return &VarDeclIR{
-		Name:   decl.Name,
-		Value:  deepCopyExpr(decl.Value),
-		ExprID: decl.ExprID,
+		Name:        decl.Name,
+		Value:       deepCopyExpr(decl.Value),
+		ExprID:      decl.ExprID,
+		Sensitivity: decl.Sensitivity,
 	}
 }
 
@@ -382,6 +382,7 @@ func (b *irBuilder) buildFunction() (*FunctionIR, error) {
 
 	var name string
 	var params []ParamIR
+	var needs []string
 	var body []*StatementIR
 
 	// Snapshot outer scopes and use a cloned scope stack for the function body
@@ -417,6 +418,9 @@ func (b *irBuilder) buildFunction() (*FunctionIR, error) {
 				}
 				params = parsedParams
 				continue
+			case parser.NodeFunctionNeeds:
+				needs = b.buildFunctionNeeds()
+				continue
 			case parser.NodeBlock:
 				blockStmts, err := b.buildBlock()
 				if err != nil {
@@ -446,12 +450,40 @@ func (b *irBuilder) buildFunction() (*FunctionIR, error) {
 	return &FunctionIR{
 		Name:   name,
 		Params: params,
+		Needs:  needs,
 		Body:   body,
 		Span:   SourceSpan{Start: startPos, End: b.pos},
 		Scopes: functionScopes,
 	}, nil
 }
 
+// buildFunctionNeeds processes a function dependency list.
+func (b *irBuilder) buildFunctionNeeds() []string {
+	b.pos++ // Move past OPEN NodeFunctionNeeds
+
+	var needs []string
+	sawKeyword := false
+	for b.pos < len(b.events) {
+		evt := b.events[b.pos]
+		b.pos++
+		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeFunctionNeeds {
+			break
+		}
+		if evt.Kind != parser.EventToken {
+			continue
+		}
+		if !sawKeyword {
+			sawKeyword = true // Skip the 'needs' keyword itself
+			continue
+		}
+		if tok := b.tokens[evt.Data]; tok.Type == lexer.IDENTIFIER {
+			needs = append(needs, string(tok.Text))
+		}
+	}
+
+	return needs
+}
+
 // buildParamList processes a function parameter list.
 func (b *irBuilder) buildParamList() ([]ParamIR, error) {
 	b.pos++ // Move past OPEN NodeParamList
@@ -820,6 +852,7 @@ func (b *irBuilder) buildVarDecl() (*StatementIR, error) {
 
 	var name string
 	var value *ExprIR
+	var sensitivity *decorator.Sensitivity
 
 	for b.pos < len(b.events) {
 		evt := b.events[b.pos]
@@ -829,6 +862,15 @@ func (b *irBuilder) buildVarDecl() (*StatementIR, error) {
 			break
 		}
 
+		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeSensitivity {
+			level, err := b.buildSensitivity()
+			if err != nil {
+				return nil, err
+			}
+			sensitivity = &level
+			continue
+		}
+
 		if evt.Kind == parser.EventToken {
 			tok := b.tokens[evt.Data]
 			if tok.Type == lexer.IDENTIFIER && name == "" {
@@ -877,13 +919,34 @@ func (b *irBuilder) buildVarDecl() (*StatementIR, error) {
 		Kind: StmtVarDecl,
 		Span: SourceSpan{Start: startPos, End: b.pos},
 		VarDecl: &VarDeclIR{
-			Name:  name,
-			Value: value,
+			Name:        name,
+			Value:       value,
+			Sensitivity: sensitivity,
 			// ExprID intentionally empty - generated during resolution
 		},
 	}, nil
 }
 
+// buildSensitivity processes a variable sensitivity annotation.
+func (b *irBuilder) buildSensitivity() (decorator.Sensitivity, error) {
+	b.pos++ // Move past OPEN NodeSensitivity
+
+	var keyword string
+	for b.pos < len(b.events) {
+		evt := b.events[b.pos]
+		b.pos++
+
+		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeSensitivity {
+			break
+		}
+		if evt.Kind == parser.EventToken {
+			keyword = string(b.tokens[evt.Data].Text)
+		}
+	}
+
+	return decorator.ParseSensitivity(keyword)
+}
+
 func (b *irBuilder) buildCommandExprParts(closeNode parser.NodeKind, allowShellArgs bool) ([]*ExprIR, error) {
 	var parts []*ExprIR
 
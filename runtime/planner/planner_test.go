@@ -1199,7 +1199,7 @@ func TestVarDeclWithStructuredValues(t *testing.T) {
 // TestBareVariableInterpolation tests that bare @var.HOME gets replaced with a placeholder
 func TestBareVariableInterpolation(t *testing.T) {
 	source := []byte(`
-var HOME = "/home/alice"
+var HOME secret = "/home/alice"
 echo @var.HOME
 `)
 
@@ -1336,7 +1336,7 @@ echo "@var.NAME"`
 // TestContractVerification_SamePlanSalt_SameDisplayIDs verifies that re-planning
 // with the same PlanSalt produces the same DisplayIDs (contract verification).
 func TestContractVerification_SamePlanSalt_SameDisplayIDs(t *testing.T) {
-	source := `var API_KEY = "sk-secret-123"
+	source := `var API_KEY secret = "sk-secret-123"
 echo "Key: @var.API_KEY"`
 
 	tree := parser.ParseString(source)
@@ -1407,7 +1407,7 @@ echo "Key: @var.API_KEY"`
 // TestContractVerification_DifferentPlanSalt_DifferentDisplayIDs verifies that
 // different PlanSalts produce different DisplayIDs (unlinkability).
 func TestContractVerification_DifferentPlanSalt_DifferentDisplayIDs(t *testing.T) {
-	source := `var API_KEY = "sk-secret-123"
+	source := `var API_KEY secret = "sk-secret-123"
 echo "Key: @var.API_KEY"`
 
 	tree := parser.ParseString(source)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -184,6 +185,8 @@ type decoratorCall struct {
 	raw       string        // Raw decorator string (for Vault tracking)
 	exprID    string        // Expression ID in Vault
 	varName   string        // Variable name if this is a var decl (for storing by name)
+
+	sensitivity *decorator.Sensitivity // Explicit var decl annotation (nil = classified by the decorator)
 }
 
 // Resolve processes the execution graph and resolves all expressions.
@@ -299,10 +302,16 @@ func (r *Resolver) resolve() (*ResolveResult, error) {
 	}
 	// stmts may be empty (nil or len==0) which is valid - produces empty plan
 
+	var dependencies *StatementIR
 	if r.activeFunction != nil {
 		if err := r.resolvePrelude(r.activeFunction); err != nil {
 			return nil, err
 		}
+		deps, err := r.resolveFunctionDependencies(r.activeFunction)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = deps
 		if r.scopes != nil {
 			r.scopes.Push()
 			defer r.scopes.Pop()
@@ -317,6 +326,9 @@ func (r *Resolver) resolve() (*ResolveResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if dependencies != nil {
+		resolved = append([]*StatementIR{dependencies}, resolved...)
+	}
 
 	decoratorExprIDs := make(map[string]string, len(r.decoratorExprIDs))
 	for key, exprID := range r.decoratorExprIDs {
@@ -419,8 +431,8 @@ func (r *Resolver) resolveStatements(stmts []*StatementIR) ([]*StatementIR, erro
 			}
 			result = append(result, expanded...)
 
-		case StmtCallTrace:
-			// Call traces are produced by resolver function expansion and already resolved.
+		case StmtCallTrace, StmtDependencies:
+			// Produced by resolver function expansion and already resolved.
 			result = append(result, stmt)
 		}
 	}
@@ -493,8 +505,8 @@ func (r *Resolver) finalizePendingStatements(stmts []*StatementIR) error {
 		case StmtFunctionCall:
 			// Function calls are expanded during resolveStatements.
 			// Pending statements should not contain function calls.
-		case StmtCallTrace:
-			// Call trace blocks are already resolved before this point.
+		case StmtCallTrace, StmtDependencies:
+			// Expanded function blocks are already resolved before this point.
 		}
 	}
 
@@ -553,10 +565,11 @@ func (r *Resolver) evaluateBlockerCollection(blocker *BlockerIR, checkTransport
 	return collection, nil
 }
 
-func (r *Resolver) bindLoopVar(loopVar string, item any) string {
+func (r *Resolver) bindLoopVar(loopVar string, item any, sensitivity decorator.Sensitivity) string {
 	loopVarRaw := fmt.Sprintf("literal:%v", item)
 	loopVarExprID := r.vault.DeclareVariable(loopVar, loopVarRaw)
 
+	r.vault.Classify(loopVarExprID, sensitivity)
 	r.vault.StoreUnresolvedValue(loopVarExprID, item)
 	r.vault.MarkTouched(loopVarExprID)
 	if r.scopes != nil {
@@ -648,7 +661,7 @@ func (r *Resolver) resolveForBlocker(stmt *StatementIR) (*StatementIR, error) {
 				defer r.scopes.Pop()
 			}
 
-			loopVarExprID = r.bindLoopVar(blocker.LoopVar, item)
+			loopVarExprID = r.bindLoopVar(blocker.LoopVar, item, r.exprSensitivity(blocker.Collection))
 
 			// Deep-copy the body for this iteration
 			bodyCopy := DeepCopyStatements(blocker.ThenBranch)
@@ -766,6 +779,13 @@ func (r *Resolver) resolveCommandBlock(cmd *CommandStmtIR) error {
 		return nil
 	}
 
+	if err := r.expandFanOut(cmd); err != nil {
+		return err
+	}
+	if err := r.pinExecParams(cmd); err != nil {
+		return err
+	}
+
 	var restoreTransport string
 	if transportDec, desc, ok := lookupTransportDecorator(cmd.Decorator); ok {
 		platform := ""
@@ -782,6 +802,14 @@ func (r *Resolver) resolveCommandBlock(cmd *CommandStmtIR) error {
 			return err
 		}
 
+		if resolver, ok := transportDec.(decorator.TargetResolver); ok {
+			pinned, err := resolver.ResolveTarget(r.session, params)
+			if err != nil {
+				return fmt.Errorf("failed to resolve target for %q: %w", cmd.Decorator, err)
+			}
+			pinArgs(cmd, params, pinned)
+		}
+
 		parentTransport := r.vault.CurrentTransport()
 		transportID, err := deriveTransportID(r.vault.GetPlanKey(), cmd.Decorator, params, parentTransport)
 		if err != nil {
@@ -831,6 +859,102 @@ func (r *Resolver) resolveCommandBlock(cmd *CommandStmtIR) error {
 	return nil
 }
 
+// expandFanOut replaces the block of a fan-out decorator such as @ssh.each
+// with one transport block per target, each running a copy of the original
+// block. The fan-out params are pinned as literals; transport params keep the
+// expressions they were written with.
+func (r *Resolver) expandFanOut(cmd *CommandStmtIR) error {
+	fanOut, ok := lookupFanOutDecorator(cmd.Decorator)
+	if !ok {
+		return nil
+	}
+
+	params, err := r.evaluateDecoratorArgs(cmd)
+	if err != nil {
+		return err
+	}
+	plan, err := fanOut.FanOutTargets(params)
+	if err != nil {
+		return fmt.Errorf("failed to expand %q: %w", cmd.Decorator, err)
+	}
+
+	written := make(map[string]*ExprIR, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		if arg.Name != "" {
+			written[arg.Name] = arg.Value
+		}
+	}
+
+	template := cmd.Block
+	cmd.Block = make([]*StatementIR, len(plan.Targets))
+	for i, target := range plan.Targets {
+		args := make([]ArgIR, 0, len(target))
+		for _, key := range sortedParamKeys(target) {
+			value := &ExprIR{Kind: ExprLiteral, Value: target[key]}
+			if expr, ok := written[key]; ok {
+				value = deepCopyExpr(expr)
+			}
+			args = append(args, ArgIR{Name: key, Value: value})
+		}
+		cmd.Block[i] = &StatementIR{
+			Kind:         StmtCommand,
+			CreatesScope: true,
+			Command: &CommandStmtIR{
+				Decorator: plan.Transport,
+				Args:      args,
+				Block:     DeepCopyStatements(template),
+			},
+		}
+	}
+
+	cmd.Args = make([]ArgIR, 0, len(plan.Params))
+	for _, key := range sortedParamKeys(plan.Params) {
+		cmd.Args = append(cmd.Args, ArgIR{Name: key, Value: &ExprIR{Kind: ExprLiteral, Value: plan.Params[key]}})
+	}
+	return nil
+}
+
+// pinExecParams records the params an exec decorator derives at plan time,
+// such as the key of an @exec.cache block.
+func (r *Resolver) pinExecParams(cmd *CommandStmtIR) error {
+	pinner, ok := lookupParamPinner(cmd.Decorator)
+	if !ok {
+		return nil
+	}
+
+	params, err := r.evaluateDecoratorArgs(cmd)
+	if err != nil {
+		return err
+	}
+	pinned, err := pinner.PinParams(params)
+	if err != nil {
+		return fmt.Errorf("failed to plan %q: %w", cmd.Decorator, err)
+	}
+	pinArgs(cmd, params, pinned)
+	return nil
+}
+
+// evaluateDecoratorArgs evaluates a decorator's args to params, including
+// list and object literals that variables hold unevaluated.
+func (r *Resolver) evaluateDecoratorArgs(cmd *CommandStmtIR) (map[string]any, error) {
+	params, err := evaluateArgs(cmd.Args, r.getValue)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range params {
+		switch v := value.(type) {
+		case []*ExprIR:
+			params[key], err = evaluateExprArray(v, r.getValue)
+		case map[string]*ExprIR:
+			params[key], err = evaluateExprObject(v, r.getValue)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("evaluate %q arg %q: %w", cmd.Decorator, key, err)
+		}
+	}
+	return params, nil
+}
+
 func (r *Resolver) resolvePrelude(fn *FunctionIR) error {
 	if fn == nil {
 		return nil
@@ -873,7 +997,7 @@ func (r *Resolver) resolvePreludeStatement(stmt *StatementIR) error {
 		return r.resolveVarDeclStatement(stmt)
 	case StmtBlocker:
 		return r.resolvePreludeBlocker(stmt)
-	case StmtCommand, StmtTry, StmtFunctionCall, StmtCallTrace:
+	case StmtCommand, StmtTry, StmtFunctionCall, StmtCallTrace, StmtDependencies:
 		return nil
 	default:
 		return nil
@@ -906,16 +1030,59 @@ func (r *Resolver) resolveFunctionCallStatement(stmt *StatementIR) ([]*Statement
 		return nil, err
 	}
 
-	if cyclePath, hasCycle := r.detectCallCycle(call.Name, resolvedArgs); hasCycle {
+	expanded, err := r.expandFunctionInvocation(fn, resolvedArgs, argExprs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expanded) == 0 {
+		return nil, nil
+	}
+
+	trace := &StatementIR{
+		Kind: StmtCallTrace,
+		CallTrace: &CallTraceStmtIR{
+			Label: r.formatFunctionCallTrace(call.Name, resolvedArgs),
+			Block: expanded,
+		},
+	}
+
+	return []*StatementIR{trace}, nil
+}
+
+// expandFunctionInvocation resolves fn's dependencies and body. Dependencies
+// declared with `needs` become a single dependency graph statement ahead of
+// the body.
+func (r *Resolver) expandFunctionInvocation(fn *FunctionIR, resolvedArgs []FunctionArg, argExprs map[string]*ExprIR) ([]*StatementIR, error) {
+	dependencies, err := r.resolveFunctionDependencies(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := r.expandFunctionBody(fn, resolvedArgs, argExprs)
+	if err != nil {
+		return nil, err
+	}
+
+	if dependencies != nil {
+		expanded = append([]*StatementIR{dependencies}, expanded...)
+	}
+	return expanded, nil
+}
+
+// expandFunctionBody resolves a fresh copy of fn's body with the given
+// arguments bound in a new invocation scope.
+func (r *Resolver) expandFunctionBody(fn *FunctionIR, resolvedArgs []FunctionArg, argExprs map[string]*ExprIR) ([]*StatementIR, error) {
+	if cyclePath, hasCycle := r.detectCallCycle(fn.Name, resolvedArgs); hasCycle {
 		return nil, fmt.Errorf("function call cycle detected: %s", cyclePath)
 	}
 
 	if len(r.callFrames) >= maxFunctionCallDepth {
-		path := r.formatCallPath(call.Name, resolvedArgs)
+		path := r.formatCallPath(fn.Name, resolvedArgs)
 		return nil, fmt.Errorf("function call depth exceeded (%d): %s", maxFunctionCallDepth, path)
 	}
 
-	r.callFrames = append(r.callFrames, callFrame{name: call.Name, args: cloneFunctionArgs(resolvedArgs)})
+	r.callFrames = append(r.callFrames, callFrame{name: fn.Name, args: cloneFunctionArgs(resolvedArgs)})
 	defer func() {
 		r.callFrames = r.callFrames[:len(r.callFrames)-1]
 	}()
@@ -927,7 +1094,7 @@ func (r *Resolver) resolveFunctionCallStatement(stmt *StatementIR) ([]*Statement
 	}()
 
 	var expanded []*StatementIR
-	err = r.withScope(func() error {
+	err := r.withScope(func() error {
 		if err := r.bindFunctionArgumentsWithArgs(fn, resolvedArgs, argExprs); err != nil {
 			return err
 		}
@@ -941,20 +1108,107 @@ func (r *Resolver) resolveFunctionCallStatement(stmt *StatementIR) ([]*Statement
 	if err != nil {
 		return nil, err
 	}
+	return expanded, nil
+}
 
-	if len(expanded) == 0 {
+// resolveFunctionDependencies expands everything fn transitively needs into a
+// dependency graph. A function needed by several others is expanded once.
+// Returns nil when fn declares no dependencies.
+func (r *Resolver) resolveFunctionDependencies(fn *FunctionIR) (*StatementIR, error) {
+	if fn == nil || len(fn.Needs) == 0 {
 		return nil, nil
 	}
 
-	trace := &StatementIR{
-		Kind: StmtCallTrace,
-		CallTrace: &CallTraceStmtIR{
-			Label: r.formatFunctionCallTrace(call.Name, resolvedArgs),
-			Block: expanded,
-		},
+	order, err := r.dependencyOrder(fn)
+	if err != nil {
+		return nil, err
 	}
 
-	return []*StatementIR{trace}, nil
+	graph := &DependencyGraphIR{Tasks: make([]DependencyTaskIR, 0, len(order))}
+	for _, dep := range order {
+		if err := r.checkContext(); err != nil {
+			return nil, err
+		}
+
+		// The dependency's own needs are already part of this graph,
+		// so only its body is expanded here.
+		block, err := r.expandFunctionBody(dep, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("function %q (needed by %q): %w", dep.Name, fn.Name, err)
+		}
+		graph.Tasks = append(graph.Tasks, DependencyTaskIR{
+			Name:  dep.Name,
+			Needs: uniqueNames(dep.Needs),
+			Block: block,
+		})
+	}
+
+	return &StatementIR{Kind: StmtDependencies, Dependencies: graph}, nil
+}
+
+// dependencyOrder returns the transitive dependencies of root in topological
+// order (dependencies before dependents), excluding root itself. Order follows
+// declaration order so the plan is deterministic.
+func (r *Resolver) dependencyOrder(root *FunctionIR) ([]*FunctionIR, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var order []*FunctionIR
+	var path []string
+
+	var visit func(fn *FunctionIR) error
+	visit = func(fn *FunctionIR) error {
+		state[fn.Name] = visiting
+		path = append(path, fn.Name)
+		defer func() { path = path[:len(path)-1] }()
+
+		for _, name := range fn.Needs {
+			switch state[name] {
+			case visited:
+				continue
+			case visiting:
+				start := slices.Index(path, name)
+				cycle := append(append([]string{}, path[start:]...), name)
+				return fmt.Errorf("function dependency cycle: %s", strings.Join(cycle, " -> "))
+			}
+			dep, ok := r.graph.Functions[name]
+			if !ok {
+				return fmt.Errorf("function %q needs unknown function %q", fn.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		state[fn.Name] = visited
+		if fn != root {
+			order = append(order, fn)
+		}
+		return nil
+	}
+
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func uniqueNames(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+	return unique
 }
 
 func (r *Resolver) formatFunctionCallTrace(name string, args []FunctionArg) string {
@@ -1284,7 +1538,7 @@ func (r *Resolver) resolvePreludeFor(blocker *BlockerIR) error {
 				defer r.scopes.Pop()
 			}
 
-			r.bindLoopVar(blocker.LoopVar, item)
+			r.bindLoopVar(blocker.LoopVar, item, r.exprSensitivity(blocker.Collection))
 
 			if err := r.resolvePreludeStatements(blocker.ThenBranch); err != nil {
 				return fmt.Errorf("failed to resolve for-loop iteration %d: %w", i, err)
@@ -2236,6 +2490,7 @@ func (r *Resolver) bindFunctionParam(name string, value any, sourceExpr *ExprIR)
 	} else {
 		exprID = r.vault.DeclareVariable(name, raw)
 	}
+	r.vault.Classify(exprID, r.exprSensitivity(sourceExpr))
 	r.vault.StoreUnresolvedValue(exprID, value)
 	r.vault.MarkTouched(exprID)
 	if r.scopes != nil {
@@ -2273,6 +2528,11 @@ func (r *Resolver) collectVarDecl(decl *VarDeclIR) {
 	// in the loop body.
 	if exprID == "" {
 		raw := r.buildVarDeclRaw(decl)
+		if decl.Sensitivity != nil {
+			// Annotated declarations get their own expression so the
+			// annotation never reclassifies an identical unannotated value
+			raw = decl.Sensitivity.String() + ":" + raw
+		}
 		// Check if value is transport-sensitive and use appropriate declaration method
 		if r.isExprTransportSensitive(decl.Value) {
 			exprID = r.vault.DeclareVariableTransportSensitive(decl.Name, raw)
@@ -2293,8 +2553,16 @@ func (r *Resolver) collectVarDecl(decl *VarDeclIR) {
 	// Record @var resolution for telemetry (declaration)
 	r.recordVarResolution()
 
+	// Decorator values are classified when they resolve; everything else
+	// is known now
+	if decl.Sensitivity != nil {
+		r.vault.Classify(exprID, *decl.Sensitivity)
+	} else if decl.Value == nil || decl.Value.Kind != ExprDecoratorRef {
+		r.vault.Classify(exprID, r.exprSensitivity(decl.Value))
+	}
+
 	// Collect the value expression (may add pending decorator calls)
-	r.collectExprForVar(decl.Value, exprID, decl.Name)
+	r.collectExprForVar(decl.Value, exprID, decl.Name, decl.Sensitivity)
 
 	// Handle different expression types
 	switch decl.Value.Kind {
@@ -2313,6 +2581,12 @@ func (r *Resolver) collectVarDecl(decl *VarDeclIR) {
 			if val, exists := r.vault.GetUnresolvedValue(refExprID); exists {
 				r.vault.StoreUnresolvedValue(exprID, val)
 			}
+			if origin := r.vault.Origin(refExprID); origin != "" {
+				r.vault.SetOrigin(exprID, origin)
+			}
+			if metadata := r.vault.Metadata(refExprID); !metadata.IsZero() {
+				r.vault.SetMetadata(exprID, metadata)
+			}
 			r.vault.MarkTouched(exprID)
 		}
 		// Note: If the variable isn't found, collectExprForVar already added an error
@@ -2392,7 +2666,7 @@ func (r *Resolver) buildVarDeclRaw(decl *VarDeclIR) string {
 
 // collectExprForVar collects an expression that's part of a variable declaration.
 // varName is used to store the resolved value by variable name for condition evaluation.
-func (r *Resolver) collectExprForVar(expr *ExprIR, exprID, varName string) {
+func (r *Resolver) collectExprForVar(expr *ExprIR, exprID, varName string, sensitivity *decorator.Sensitivity) {
 	if expr == nil {
 		return
 	}
@@ -2417,6 +2691,8 @@ func (r *Resolver) collectExprForVar(expr *ExprIR, exprID, varName string) {
 			raw:       raw,
 			exprID:    exprID,
 			varName:   varName, // Track variable name for storing by name later
+
+			sensitivity: sensitivity,
 		})
 	} else {
 		// For non-decorator expressions, use regular collectExpr
@@ -2676,6 +2952,67 @@ func (r *Resolver) isExprTransportSensitive(expr *ExprIR) bool {
 	}
 }
 
+// exprSensitivity infers the sensitivity of an expression from its inputs.
+// Literals are public; references take the level of what they refer to; a
+// value combining several inputs is as sensitive as the most sensitive one.
+func (r *Resolver) exprSensitivity(expr *ExprIR) decorator.Sensitivity {
+	if expr == nil {
+		return decorator.SensitivityPublic
+	}
+
+	switch expr.Kind {
+	case ExprLiteral:
+		level := decorator.SensitivityPublic
+		if arr, ok := expr.Value.([]*ExprIR); ok {
+			for _, item := range arr {
+				level = level.Stricter(r.exprSensitivity(item))
+			}
+		}
+		if obj, ok := expr.Value.(map[string]*ExprIR); ok {
+			for _, item := range obj {
+				level = level.Stricter(r.exprSensitivity(item))
+			}
+		}
+		return level
+
+	case ExprEnumMemberRef:
+		return decorator.SensitivityPublic
+
+	case ExprVarRef:
+		refExprID, ok := r.lookupScopeExprID(expr.VarName)
+		if !ok {
+			return decorator.SensitivitySecret
+		}
+		return r.vault.Sensitivity(refExprID)
+
+	case ExprDecoratorRef:
+		if exprID, ok := r.decoratorExprIDs[decoratorKey(expr.Decorator)]; ok {
+			return r.vault.Sensitivity(exprID)
+		}
+		// Not resolved yet - assume the worst
+		return decorator.SensitivitySecret
+
+	case ExprBinaryOp:
+		return r.exprSensitivity(expr.Left).Stricter(r.exprSensitivity(expr.Right))
+
+	case ExprTypeCast:
+		return r.exprSensitivity(expr.Left)
+
+	default:
+		return decorator.SensitivitySecret
+	}
+}
+
+// classifyCall returns the sensitivity of a resolved decorator call.
+// Unregistered decorators are treated as secret.
+func (r *Resolver) classifyCall(name string, call decorator.ValueCall, value any) decorator.Sensitivity {
+	entry, ok := decorator.Global().Lookup(strings.TrimPrefix(name, "@"))
+	if !ok {
+		return decorator.SensitivitySecret
+	}
+	return decorator.ClassifyResolved(entry.Impl, call, value)
+}
+
 // isDecoratorTransportSensitive checks if a decorator has the TransportSensitive capability.
 func (r *Resolver) isDecoratorTransportSensitive(name string) bool {
 	trimmed := strings.TrimPrefix(name, "@")
@@ -2697,9 +3034,41 @@ func (r *Resolver) batchResolve() error {
 		return nil
 	}
 
-	// Group pending calls by decorator name
+	// Calls whose arguments read variables resolved in this same wave
+	// (var KEY = @env.KEY; @crypto.hmac(@var.KEY, ...)) wait for a later
+	// round. A round without progress resolves the rest, which reports the
+	// missing value.
+	pending := r.pendingCalls
+	r.pendingCalls = nil
+	for len(pending) > 0 {
+		var ready, waiting []decoratorCall
+		for _, call := range pending {
+			if r.callArgsPending(call.decorator) {
+				waiting = append(waiting, call)
+			} else {
+				ready = append(ready, call)
+			}
+		}
+		if len(ready) == 0 {
+			ready, waiting = waiting, nil
+		}
+		if err := r.resolveRound(ready); err != nil {
+			return err
+		}
+		pending = waiting
+	}
+
+	// Generate DisplayIDs for all touched expressions
+	r.vault.ResolveAllTouched()
+
+	return nil
+}
+
+// resolveRound resolves calls in one batch per decorator.
+func (r *Resolver) resolveRound(calls []decoratorCall) error {
+	// Group calls by decorator name
 	groups := make(map[string][]decoratorCall)
-	for _, call := range r.pendingCalls {
+	for _, call := range calls {
 		groups[call.decorator.Name] = append(groups[call.decorator.Name], call)
 	}
 
@@ -2727,13 +3096,56 @@ func (r *Resolver) batchResolve() error {
 		r.recordBatchResolution(decoratorName, len(calls), duration)
 	}
 
-	// Clear pending calls for next wave
-	r.pendingCalls = nil
+	return nil
+}
 
-	// Generate DisplayIDs for all touched expressions
-	r.vault.ResolveAllTouched()
+// callArgsPending reports whether any argument of d reads a variable that
+// has no value yet.
+func (r *Resolver) callArgsPending(d *DecoratorRef) bool {
+	for _, arg := range d.Args {
+		if r.exprPending(arg) {
+			return true
+		}
+	}
+	return false
+}
 
-	return nil
+func (r *Resolver) exprPending(expr *ExprIR) bool {
+	if expr == nil {
+		return false
+	}
+
+	switch expr.Kind {
+	case ExprVarRef:
+		exprID, ok := r.lookupScopeExprID(expr.VarName)
+		if !ok {
+			return false // Reported as undefined when evaluated
+		}
+		_, ok = r.vault.GetUnresolvedValue(exprID)
+		return !ok
+	case ExprLiteral:
+		if arr, ok := expr.Value.([]*ExprIR); ok {
+			for _, item := range arr {
+				if r.exprPending(item) {
+					return true
+				}
+			}
+		}
+		if obj, ok := expr.Value.(map[string]*ExprIR); ok {
+			for _, item := range obj {
+				if r.exprPending(item) {
+					return true
+				}
+			}
+		}
+		return false
+	case ExprBinaryOp:
+		return r.exprPending(expr.Left) || r.exprPending(expr.Right)
+	case ExprTypeCast:
+		return r.exprPending(expr.Left)
+	default:
+		return false
+	}
 }
 
 func (r *Resolver) recordVarResolution() {
@@ -2837,6 +3249,19 @@ func (r *Resolver) resolveBatch(decoratorName string, calls []decoratorCall) err
 		r.vault.StoreUnresolvedValue(exprID, result.Value)
 		r.vault.MarkTouched(exprID)
 
+		if call.sensitivity == nil {
+			r.vault.Classify(exprID, r.classifyCall(decoratorName, valueCalls[i], result.Value))
+		}
+
+		origin := result.Origin
+		if origin == "" {
+			origin = "@" + decoratorName
+		}
+		r.vault.SetOrigin(exprID, origin)
+		if !result.Metadata.IsZero() {
+			r.vault.SetMetadata(exprID, result.Metadata)
+		}
+
 		// Track decorator key → exprID for getValue() lookups
 		// This allows direct decorator refs in conditions (e.g., if @env.HOME == "/root")
 		decKey := decoratorKey(call.decorator)
@@ -2938,6 +3363,10 @@ func buildValueCall(d *DecoratorRef, getValue ValueLookup) (decorator.ValueCall,
 		if err != nil {
 			return decorator.ValueCall{}, fmt.Errorf("failed to evaluate decorator arg %d for @%s: %w", i+1, call.Path, err)
 		}
+		// Decorators receive duration literals as duration strings ("30s")
+		if d, ok := value.(durationLiteral); ok {
+			value = string(d)
+		}
 
 		paramName := fmt.Sprintf("arg%d", i+1)
 		if i < len(d.ArgNames) && d.ArgNames[i] != "" {
@@ -3008,6 +3437,7 @@ func buildDecoratorRaw(d *DecoratorRef) string {
 		sb.WriteString(".")
 		sb.WriteString(s)
 	}
+	sb.WriteString(decoratorArgsKey(d))
 
 	return sb.String()
 }
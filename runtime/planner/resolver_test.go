@@ -2,6 +2,7 @@ package planner
 
 import (
 	"context"
+	"io"
 	"io/fs"
 	"strings"
 	"testing"
@@ -683,6 +684,14 @@ func (m *mockSession) Get(ctx context.Context, path string) ([]byte, error) {
 	return nil, nil
 }
 
+func (m *mockSession) PutStream(ctx context.Context, src io.Reader, path string, mode fs.FileMode) error {
+	return nil
+}
+
+func (m *mockSession) GetStream(ctx context.Context, path string, dst io.Writer) error {
+	return nil
+}
+
 func (m *mockSession) Env() map[string]string {
 	if m.env == nil {
 		return map[string]string{}
@@ -874,6 +883,64 @@ func TestResolveBatch_ResolvesDecoratorGroupsInSortedOrder(t *testing.T) {
 	}
 }
 
+func TestResolveBatch_DefersCallsWithPendingVarArgs(t *testing.T) {
+	const pathA = "test.deps.a"
+	const pathB = "test.deps.b"
+
+	order := []string{}
+	if err := decorator.Register(pathB, &orderedValueDecorator{path: pathB, order: &order}); err != nil {
+		t.Fatalf("Register decorator %q failed: %v", pathB, err)
+	}
+	dec := &captureValueDecorator{path: pathA}
+	if err := decorator.Register(pathA, dec); err != nil {
+		t.Fatalf("Register decorator %q failed: %v", pathA, err)
+	}
+
+	// var KEY = @test.deps.b
+	// var MAC = @test.deps.a.PRIMARY(fallback=@var.KEY)
+	// @test.deps.a sorts first but must wait for KEY.
+	v := vault.NewWithPlanKey([]byte("test-key"))
+	graph := &ExecutionGraph{
+		Statements: []*StatementIR{
+			{
+				Kind: StmtVarDecl,
+				VarDecl: &VarDeclIR{
+					Name:  "KEY",
+					Value: &ExprIR{Kind: ExprDecoratorRef, Decorator: &DecoratorRef{Name: pathB}},
+				},
+			},
+			{
+				Kind: StmtVarDecl,
+				VarDecl: &VarDeclIR{
+					Name: "MAC",
+					Value: &ExprIR{
+						Kind: ExprDecoratorRef,
+						Decorator: &DecoratorRef{
+							Name:     pathA,
+							Selector: []string{"PRIMARY"},
+							Args:     []*ExprIR{{Kind: ExprVarRef, VarName: "KEY"}},
+							ArgNames: []string{"fallback"},
+						},
+					},
+				},
+			},
+		},
+		Scopes: NewScopeStack(),
+	}
+
+	_, err := Resolve(graph, v, &mockSession{}, ResolveConfig{Context: context.Background()})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if len(dec.lastCalls) != 1 {
+		t.Fatalf("Expected 1 call, got %d", len(dec.lastCalls))
+	}
+	if diff := cmp.Diff(pathB, dec.lastCalls[0].Params["fallback"]); diff != "" {
+		t.Errorf("fallback mismatch (-want +got):\n%s", diff)
+	}
+}
+
 // TestResolve_IfBranchPruning verifies that untaken branch expressions are not touched.
 func TestResolve_IfBranchPruning(t *testing.T) {
 	// Create vault
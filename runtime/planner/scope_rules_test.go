@@ -80,8 +80,8 @@ var LATER = "ignored"
 	}
 
 	command := getCommandArg(plan.Steps[0].Tree, "command")
-	if !containsDisplayID(command) {
-		t.Errorf("Expected DisplayID placeholder in command, got %q", command)
+	if command != `echo "prod"` {
+		t.Errorf("Expected prelude value in command, got %q", command)
 	}
 }
 
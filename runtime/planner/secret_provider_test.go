@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSecretProviders_TrackedAsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(envFile, []byte("DB_USER=app-user\nDB_PASSWORD=hunter2-long\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token-123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Calls to one decorator differing only in arguments resolve separately
+	source := fmt.Sprintf(`var user = @secret.dotenv(file=%q, key="DB_USER")
+var pass = @secret.dotenv(file=%q, key="DB_PASSWORD")
+var token = @secret.file(path=%q)
+echo "@var.user @var.pass @var.token"`, envFile, envFile, tokenFile)
+
+	plan, vlt, _, err := planWithPipeline(t, source, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	command := getCommandArg(plan.Steps[0].Tree, "command")
+	ids := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(command, `echo "`), `"`))
+	if len(ids) != 3 || ids[0] == ids[1] {
+		t.Fatalf("expected three distinct DisplayIDs, got %q", command)
+	}
+	for _, id := range ids {
+		if !containsDisplayID(id) {
+			t.Errorf("expected DisplayID, got %q", id)
+		}
+	}
+
+	out, err := vlt.SecretProvider().HandleChunk([]byte("app-user hunter2-long file-token-123"))
+	if err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+	for _, value := range []string{"app-user", "hunter2-long", "file-token-123"} {
+		if strings.Contains(string(out), value) {
+			t.Errorf("value %q not scrubbed: %q", value, out)
+		}
+	}
+	if diff := cmp.Diff(3, strings.Count(string(out), "sigil:")); diff != "" {
+		t.Errorf("placeholder count mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSecretProviders_RecordVersionMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"kv-pass-5"},"metadata":{"version":5,` +
+			`"created_time":"2026-10-01T00:00:00Z","deletion_time":"2026-10-31T00:00:00Z"}}}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	source := `var pass = @secret.kv(path="myapp/db", key="password", insecure=true)
+echo "@var.pass"`
+
+	plan, _, _, err := planWithPipeline(t, source, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if len(plan.SecretUses) != 1 {
+		t.Fatalf("expected 1 secret use, got %+v", plan.SecretUses)
+	}
+	use := plan.SecretUses[0]
+	want := planfmt.SecretUse{
+		DisplayID: use.DisplayID,
+		SiteID:    use.SiteID,
+		Site:      "root/step-1/@shell[0]/params/command",
+		Origin:    "@secret.kv",
+		Version:   "5",
+		CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(want, use); diff != "" {
+		t.Errorf("secret use mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,129 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/builtwithtofu/sigil/core/planfmt"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSensitivity_PlanDisplay(t *testing.T) {
+	t.Setenv("SIGIL_TEST_REGION", "eu-west-1")
+	t.Setenv("SIGIL_TEST_TOKEN", "tok-abcdef")
+
+	tests := []struct {
+		name   string
+		source string
+		want   string // Expected command; empty means the value must be masked
+		value  string // Raw value that must not appear when masked
+	}{
+		{
+			name:   "literal is public",
+			source: "var replicas = 3\necho \"replicas=@var.replicas\"",
+			want:   `echo "replicas=3"`,
+		},
+		{
+			name:   "secret annotation masks literal",
+			source: "var token secret = \"abcd-1234\"\necho \"@var.token\"",
+			value:  "abcd-1234",
+		},
+		{
+			name:   "sensitive annotation masks literal",
+			source: "var region sensitive = \"us-east-1\"\necho \"@var.region\"",
+			value:  "us-east-1",
+		},
+		{
+			name:   "public annotation shows decorator value",
+			source: "var region public = @env.SIGIL_TEST_REGION\necho \"@var.region\"",
+			want:   `echo "eu-west-1"`,
+		},
+		{
+			name:   "env is masked by default",
+			source: "echo \"@env.SIGIL_TEST_REGION\"",
+			value:  "eu-west-1",
+		},
+		{
+			name:   "variable inherits public level",
+			source: "var a = \"x\"\nvar b = @var.a\necho \"@var.b\"",
+			want:   `echo "x"`,
+		},
+		{
+			name:   "variable inherits secret level",
+			source: "var a secret = \"abcd\"\nvar b = @var.a\necho \"@var.b\"",
+			value:  "abcd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, _, _, err := planWithPipeline(t, tt.source, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+
+			command := getCommandArg(plan.Steps[0].Tree, "command")
+			if tt.want != "" {
+				if command != tt.want {
+					t.Errorf("command = %q, want %q", command, tt.want)
+				}
+				return
+			}
+			if !containsDisplayID(command) || strings.Contains(command, tt.value) {
+				t.Errorf("expected %q masked, got %q", tt.value, command)
+			}
+		})
+	}
+}
+
+func TestSensitivity_PublicArgKeepsType(t *testing.T) {
+	source := `var attempts = 2
+@exec.retry(times=@var.attempts, delay=10ms) {
+    echo "hi"
+}`
+
+	plan, _, _, err := planWithPipeline(t, source, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("expected command node, got %T", plan.Steps[0].Tree)
+	}
+	for _, arg := range cmd.Args {
+		if arg.Key == "times" {
+			if diff := cmp.Diff(planfmt.Value{Kind: planfmt.ValueInt, Int: 2}, arg.Val); diff != "" {
+				t.Errorf("times mismatch (-want +got):\n%s", diff)
+			}
+			return
+		}
+	}
+	t.Fatalf("times argument missing: %+v", cmd.Args)
+}
+
+func TestSensitivity_OnlySecretsScrubbed(t *testing.T) {
+	t.Setenv("SIGIL_TEST_REGION", "eu-west-1")
+	t.Setenv("SIGIL_TEST_TOKEN", "tok-abcdef")
+	t.Setenv("SIGIL_TEST_DATABASE_URL", "postgres://app:pw-abcdef@db/app")
+
+	source := `var replicas = 3000
+echo "@var.replicas @env.SIGIL_TEST_REGION @env.SIGIL_TEST_TOKEN @env.SIGIL_TEST_DATABASE_URL"`
+
+	_, vlt, _, err := planWithPipeline(t, source, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	out, err := vlt.SecretProvider().HandleChunk([]byte("3000 eu-west-1 tok-abcdef postgres://app:pw-abcdef@db/app"))
+	if err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+
+	// Credential-named env vars and connection strings with a password are
+	// secret; other env vars and literals are not
+	got := string(out)
+	if !strings.HasPrefix(got, "3000 eu-west-1 sigil:") || strings.Contains(got, "pw-abcdef") {
+		t.Errorf("unexpected scrubbed output %q", got)
+	}
+}
@@ -58,9 +58,9 @@ func TestThreePass_ShadowingWithDeferredResolution(t *testing.T) {
 	// and verifies that we capture exprID at reference time (not resolution time)
 
 	source := []byte(`
-var COUNT = "5"
+var COUNT secret = "5"
 echo "@var.COUNT"
-var COUNT = "10"
+var COUNT secret = "10"
 echo "@var.COUNT"
 `)
 
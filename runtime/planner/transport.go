@@ -125,7 +125,74 @@ func lookupTransportDecorator(name string) (decorator.Transport, decorator.Descr
 	return transport, transport.Descriptor(), true
 }
 
+// lookupFanOutDecorator returns the fan-out implementation of a registered
+// exec decorator, if it has one.
+func lookupFanOutDecorator(name string) (decorator.FanOut, bool) {
+	trimmed := strings.TrimPrefix(name, "@")
+	if trimmed == "" {
+		return nil, false
+	}
+	exec, ok, _ := decorator.Global().GetExec(trimmed)
+	if !ok {
+		return nil, false
+	}
+	fanOut, ok := exec.(decorator.FanOut)
+	return fanOut, ok
+}
+
+// lookupParamPinner returns the plan-time param derivation of a registered
+// exec decorator, if it has one.
+func lookupParamPinner(name string) (decorator.ParamPinner, bool) {
+	trimmed := strings.TrimPrefix(name, "@")
+	if trimmed == "" {
+		return nil, false
+	}
+	exec, ok, _ := decorator.Global().GetExec(trimmed)
+	if !ok {
+		return nil, false
+	}
+	pinner, ok := exec.(decorator.ParamPinner)
+	return pinner, ok
+}
+
 func isTransportDecoratorName(name string) bool {
 	_, _, ok := lookupTransportDecorator(name)
 	return ok
 }
+
+// pinArgs records plan-time params on the command, such as a resolved
+// transport target, so the emitted plan (and a transport's ID) carries them.
+// Pinned keys are added in sorted order to keep the IR deterministic.
+func pinArgs(cmd *CommandStmtIR, params, pinned map[string]any) {
+	keys := make([]string, 0, len(pinned))
+	for key := range pinned {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := &ExprIR{Kind: ExprLiteral, Value: pinned[key]}
+		params[key] = pinned[key]
+
+		replaced := false
+		for i := range cmd.Args {
+			if cmd.Args[i].Name == key {
+				cmd.Args[i].Value = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			cmd.Args = append(cmd.Args, ArgIR{Name: key, Value: value})
+		}
+	}
+}
+
+func sortedParamKeys(params map[string]any) []string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
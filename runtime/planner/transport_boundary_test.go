@@ -258,9 +258,9 @@ var VERSION = "1.0.0"
 		t.Fatal("Expected plan to be created")
 	}
 
-	// Verify SecretUses contains the variable
-	if len(plan.SecretUses) == 0 {
-		t.Error("Expected SecretUses for @var.VERSION")
+	// Literals are public, so the value is rendered inside the transport
+	if command := getCommandString(plan.Steps[0]); command != `echo "Version: 1.0.0"` {
+		t.Errorf("Expected @var.VERSION rendered in command, got %q", command)
 	}
 }
 
@@ -2,6 +2,7 @@ package planner
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/builtwithtofu/sigil/core/decorator"
@@ -46,8 +47,32 @@ func (d *envTransportDecorator) IsolationContext() decorator.IsolationContext {
 	return nil
 }
 
+// pinnedTransportDecorator resolves selector=... to a concrete pod=... at
+// plan time, like @k8s.exec.
+type pinnedTransportDecorator struct {
+	envTransportDecorator
+}
+
+func (d *pinnedTransportDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("test.transport.pinned").
+		Summary("Test transport that pins its target at plan time").
+		Roles(decorator.RoleBoundary).
+		ParamString("selector", "Selector").
+		Done().
+		ParamString("pod", "Pinned pod").
+		Done().
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+func (d *pinnedTransportDecorator) ResolveTarget(parent decorator.Session, params map[string]any) (map[string]any, error) {
+	selector, _ := params["selector"].(string)
+	return map[string]any{"pod": "pod-for-" + selector}, nil
+}
+
 func init() {
 	_ = decorator.Register("test.transport.env", &envTransportDecorator{})
+	_ = decorator.Register("test.transport.pinned", &pinnedTransportDecorator{})
 }
 
 func TestPlanNew_TransportIDs(t *testing.T) {
@@ -169,3 +194,161 @@ func TestResolve_EnvUsesTransportSession(t *testing.T) {
 		t.Errorf("env value mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestPlanNew_TransportTargetPinnedInTable(t *testing.T) {
+	source := `
+@test.transport.pinned(selector="app") {
+    echo "hello"
+}
+`
+	planKey := []byte("plan-key-transport-pinned-000000")
+	v := vault.NewWithPlanKey(planKey)
+
+	tree := parser.Parse([]byte(source))
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{Vault: v})
+	if err != nil {
+		t.Fatalf("PlanNew failed: %v", err)
+	}
+
+	expectedTransportID, err := deriveTransportID(planKey, "@test.transport.pinned",
+		map[string]any{"selector": "app", "pod": "pod-for-app"}, localTransportID(planKey))
+	if err != nil {
+		t.Fatalf("derive transport ID failed: %v", err)
+	}
+
+	var transport planfmt.Transport
+	for _, entry := range result.Plan.Transports {
+		if entry.ID == expectedTransportID {
+			transport = entry
+		}
+	}
+	if transport.ID == "" {
+		t.Fatalf("pinned transport %q not in table: %+v", expectedTransportID, result.Plan.Transports)
+	}
+
+	args := make(map[string]string, len(transport.Args))
+	for _, arg := range transport.Args {
+		args[arg.Key] = arg.Val.Str
+	}
+	if diff := cmp.Diff(map[string]string{"pod": "pod-for-app", "selector": "app"}, args); diff != "" {
+		t.Errorf("transport args mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlanNew_SSHEachExpandsOneTransportPerHost(t *testing.T) {
+	source := `
+var HOSTS = ["web01", "web02"]
+@ssh.each(hosts=@var.HOSTS, maxConcurrency=1, user="deploy") {
+    echo "restart"
+}
+`
+	planKey := []byte("plan-key-transport-ssh-each-0000")
+	v := vault.NewWithPlanKey(planKey)
+
+	tree := parser.Parse([]byte(source))
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{Vault: v})
+	if err != nil {
+		t.Fatalf("PlanNew failed: %v", err)
+	}
+	plan := result.Plan
+	localID := localTransportID(planKey)
+
+	var each *planfmt.CommandNode
+	for _, step := range plan.Steps {
+		if cmd, ok := step.Tree.(*planfmt.CommandNode); ok && cmd.Decorator == "@ssh.each" {
+			each = cmd
+		}
+	}
+	if each == nil {
+		t.Fatalf("@ssh.each step not found in plan")
+	}
+
+	wantEachArgs := []planfmt.Arg{
+		{Key: "hosts", Val: planfmt.Value{Kind: planfmt.ValueArray, Array: []planfmt.Value{
+			{Kind: planfmt.ValueString, Str: "web01"},
+			{Kind: planfmt.ValueString, Str: "web02"},
+		}}},
+		{Key: "maxConcurrency", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: 1}},
+	}
+	if diff := cmp.Diff(wantEachArgs, each.Args); diff != "" {
+		t.Errorf("@ssh.each args mismatch (-want +got):\n%s", diff)
+	}
+	if len(each.Block) != 2 {
+		t.Fatalf("expected one block step per host, got %d", len(each.Block))
+	}
+
+	for i, host := range []string{"web01", "web02"} {
+		transportID, err := deriveTransportID(planKey, "@ssh.connect",
+			map[string]any{"host": host, "user": "deploy"}, localID)
+		if err != nil {
+			t.Fatalf("derive transport ID failed: %v", err)
+		}
+
+		var transport planfmt.Transport
+		for _, entry := range plan.Transports {
+			if entry.ID == transportID {
+				transport = entry
+			}
+		}
+		if diff := cmp.Diff("@ssh.connect", transport.Decorator); diff != "" {
+			t.Errorf("transport for %s not in table (-want +got):\n%s", host, diff)
+		}
+		if diff := cmp.Diff(localID, transport.ParentID); diff != "" {
+			t.Errorf("transport parent for %s mismatch (-want +got):\n%s", host, diff)
+		}
+
+		connect, ok := each.Block[i].Tree.(*planfmt.CommandNode)
+		if !ok || connect.Decorator != "@ssh.connect" || len(connect.Block) != 1 {
+			t.Fatalf("block step %d: expected @ssh.connect with the block, got %#v", i, each.Block[i].Tree)
+		}
+		inner, ok := connect.Block[0].Tree.(*planfmt.CommandNode)
+		if !ok {
+			t.Fatalf("expected inner CommandNode, got %T", connect.Block[0].Tree)
+		}
+		if diff := cmp.Diff(transportID, inner.TransportID); diff != "" {
+			t.Errorf("command on %s transport ID mismatch (-want +got):\n%s", host, diff)
+		}
+	}
+
+	if len(plan.Transports) != 3 {
+		t.Errorf("expected local plus one transport per host, got %d", len(plan.Transports))
+	}
+}
+
+func TestPlanNew_SSHEachRejectsInvalidHosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "duplicate host",
+			source: "var HOSTS = [\"web01\", \"web01\"]\n@ssh.each(hosts=@var.HOSTS) {\n    echo hi\n}\n",
+			want:   "failed to expand \"@ssh.each\"",
+		},
+		{
+			name:   "empty host",
+			source: "var HOSTS = [\"web01\", \"\"]\n@ssh.each(hosts=@var.HOSTS) {\n    echo hi\n}\n",
+			want:   "hosts[1] is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := parser.Parse([]byte(tt.source))
+			if len(tree.Errors) > 0 {
+				t.Fatalf("Parse errors: %v", tree.Errors)
+			}
+			_, err := PlanWithObservability(tree.Events, tree.Tokens, Config{Vault: vault.NewWithPlanKey([]byte("plan-key-transport-ssh-each-0000"))})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -28,9 +28,9 @@ import (
 // execution decorator blocks (@exec.retry, @exec.timeout, etc.) create isolated scopes.
 func TestVarBlockScoping_ExecutionDecorator_IsolatedScope(t *testing.T) {
 	source := `
-var COUNT = "5"
+var COUNT secret = "5"
 @exec.retry {
-    var COUNT = "3"
+    var COUNT secret = "3"
     echo "@var.COUNT"
 }
 echo "@var.COUNT"
@@ -141,7 +141,7 @@ echo "@var.SECRET"
 // parent scope variables are accessible inside execution decorator blocks.
 func TestVarBlockScoping_ExecutionDecorator_ParentReadable(t *testing.T) {
 	source := `
-var API_KEY = "parent-key"
+var API_KEY secret = "parent-key"
 @exec.retry {
     echo "@var.API_KEY"
 }
@@ -186,11 +186,11 @@ var API_KEY = "parent-key"
 // execution decorator blocks.
 func TestVarBlockScoping_NestedExecutionDecorators(t *testing.T) {
 	source := `
-var COUNT = "5"
+var COUNT secret = "5"
 @exec.retry {
-    var COUNT = "3"
+    var COUNT secret = "3"
     @exec.timeout(duration=5s) {
-        var COUNT = "1"
+        var COUNT secret = "1"
         echo "@var.COUNT"
     }
     echo "@var.COUNT"
@@ -316,11 +316,11 @@ echo "@var.A @var.B @var.C"
 func TestVarBlockScoping_SiblingExecutionDecorators(t *testing.T) {
 	source := `
 @exec.retry {
-    var COUNT = "3"
+    var COUNT secret = "3"
     echo "@var.COUNT"
 }
 @exec.retry {
-    var COUNT = "7"
+    var COUNT secret = "7"
     echo "@var.COUNT"
 }
 `
@@ -404,7 +404,7 @@ func TestVarBlockScoping_NoHoisting_InExecutionDecorator(t *testing.T) {
 // execution decorator blocks don't cause errors.
 func TestVarBlockScoping_EmptyExecutionDecorator(t *testing.T) {
 	source := `
-var COUNT = "5"
+var COUNT secret = "5"
 @exec.retry {
 }
 echo "@var.COUNT"
@@ -451,11 +451,11 @@ echo "@var.COUNT"
 // multiple variables in execution decorator block.
 func TestVarBlockScoping_MultipleVariables_InExecutionDecorator(t *testing.T) {
 	source := `
-var A = "outer-a"
-var B = "outer-b"
+var A secret = "outer-a"
+var B secret = "outer-b"
 @exec.retry {
-    var A = "inner-a"
-    var C = "inner-c"
+    var A secret = "inner-a"
+    var C secret = "inner-c"
     echo "@var.A @var.B @var.C"
 }
 echo "@var.A @var.B"
@@ -513,9 +513,9 @@ echo "@var.A @var.B"
 func TestVarBlockScoping_InFunction(t *testing.T) {
 	source := `
 fun test {
-    var COUNT = "5"
+    var COUNT secret = "5"
     @exec.retry {
-        var COUNT = "3"
+        var COUNT secret = "3"
         echo "@var.COUNT"
     }
     echo "@var.COUNT"
@@ -580,7 +580,7 @@ fun test {
 // blocks work correctly (5 levels).
 func TestVarBlockScoping_DeeplyNested(t *testing.T) {
 	source := `
-var COUNT = "5"
+var COUNT secret = "5"
 @exec.retry {
     @exec.parallel {
         @exec.timeout(duration=5s) {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/builtwithtofu/sigil/core/planfmt"
 	"github.com/builtwithtofu/sigil/runtime/parser"
+	"github.com/google/go-cmp/cmp"
 )
 
 // TestVarDeclaration_SimpleString tests that a simple string variable is:
@@ -115,6 +116,9 @@ func TestVarDeclaration_DifferentTypes(t *testing.T) {
 		{"string", `var NAME = "Aled"`},
 		{"number", `var COUNT = 42`},
 		{"boolean", `var ENABLED = true`},
+		{"secret string", `var NAME secret = "Aled"`},
+		{"secret number", `var COUNT secret = 42`},
+		{"secret boolean", `var ENABLED secret = true`},
 	}
 
 	for _, tt := range tests {
@@ -173,6 +177,32 @@ var Z = "same"`
 	t.Logf("Multiple variables with same literal value planned successfully")
 }
 
+// TestVarDeclaration_SameLiteralValue_Secret tests that secret variables sharing
+// a literal value also share one exprID, so every use carries the same DisplayID.
+func TestVarDeclaration_SameLiteralValue_Secret(t *testing.T) {
+	source := `var X secret = "same"
+var Y secret = "same"
+echo "@var.X @var.Y"`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	displayIDs := make(map[string]bool)
+	for _, use := range result.Plan.SecretUses {
+		displayIDs[use.DisplayID] = true
+	}
+	if len(displayIDs) != 1 {
+		t.Errorf("Expected 1 DisplayID for the shared secret literal, got %d: %v", len(displayIDs), result.Plan.SecretUses)
+	}
+}
+
 // TestVarDeclaration_SameLiteralValue_DifferentTypes tests that variables with
 // the same literal value but different types work correctly.
 func TestVarDeclaration_SameLiteralValue_DifferentTypes(t *testing.T) {
@@ -216,7 +246,7 @@ var F = true`
 // 3. Plan output contains `sigil:` placeholder
 // 4. Plan does NOT contain "Aled"
 func TestVarUsage_DisplayIDInPlan(t *testing.T) {
-	source := `var NAME = "Aled"
+	source := `var NAME secret = "Aled"
 echo "Hello, @var.NAME"`
 
 	tree := parser.ParseString(source)
@@ -298,7 +328,7 @@ echo "Hello, @var.NAME"`
 // - Secret.RuntimeValue is NEVER serialized (runtime only, see plan.go:79)
 // - Only DisplayIDs are stored in the contract for scrubbing
 func TestVarUsage_FormattedPlanOutput(t *testing.T) {
-	source := `var NAME = "Aled"
+	source := `var NAME secret = "Aled"
 echo "Hello, @var.NAME"`
 
 	tree := parser.ParseString(source)
@@ -367,7 +397,7 @@ func formatStepForTest(node planfmt.ExecutionNode, indent int) string {
 // - If value changes → DisplayID changes → hash changes → contract invalid
 // - If site changes → SiteID changes → hash changes → contract invalid
 func TestVarUsage_SecretUsesPopulated(t *testing.T) {
-	source := `var NAME = "Aled"
+	source := `var NAME secret = "Aled"
 echo "Hello, @var.NAME"
 echo "Goodbye, @var.NAME"`
 
@@ -421,6 +451,42 @@ echo "Goodbye, @var.NAME"`
 	t.Logf("✓ Plan.SecretUses populated correctly with %d entries", len(result.Plan.SecretUses))
 }
 
+// TestVarUsage_SecretUsesRecordOrigin tests that SecretUses name where each
+// value came from, following variables back to the decorator, and that
+// literals have no origin.
+func TestVarUsage_SecretUsesRecordOrigin(t *testing.T) {
+	t.Setenv("SIGIL_TEST_DB_PASSWORD", "pw-8c1f0e")
+
+	source := `var DB = @env.SIGIL_TEST_DB_PASSWORD
+var PIN secret = "4711-literal"
+echo "@env.SIGIL_TEST_DB_PASSWORD"
+echo "@var.DB"
+echo "@var.PIN"`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	origins := make(map[string]string)
+	for _, use := range result.Plan.SecretUses {
+		origins[use.Site] = use.Origin
+	}
+	want := map[string]string{
+		"root/step-1/@shell[0]/params/command": "@env.SIGIL_TEST_DB_PASSWORD",
+		"root/step-2/@shell[0]/params/command": "@env.SIGIL_TEST_DB_PASSWORD",
+		"root/step-3/@shell[0]/params/command": "",
+	}
+	if diff := cmp.Diff(want, origins); diff != "" {
+		t.Errorf("origins mismatch (-want +got):\n%s", diff)
+	}
+}
+
 // TestVarPruning_UntouchedVariablesNotInPlan tests that variables declared but never used
 // are pruned from the plan's SecretUses.
 //
@@ -429,8 +495,8 @@ echo "Goodbye, @var.NAME"`
 // - Only touched (referenced) variables should appear in SecretUses
 // - Saves API calls and reduces secrets in plan
 func TestVarPruning_UntouchedVariablesNotInPlan(t *testing.T) {
-	source := `var USED = "used-value"
-var UNUSED = "unused-value"
+	source := `var USED secret = "used-value"
+var UNUSED secret = "unused-value"
 echo "Hello, @var.USED"`
 
 	tree := parser.ParseString(source)
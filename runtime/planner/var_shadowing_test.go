@@ -36,8 +36,8 @@ func TestVarShadowing_SameLevelOverride_Works(t *testing.T) {
 	// Later declaration at same scope level should override earlier one
 
 	source := []byte(`
-var COUNT = "5"
-var COUNT = "10"
+var COUNT secret = "5"
+var COUNT secret = "10"
 echo "@var.COUNT"
 `)
 
@@ -97,9 +97,9 @@ func TestVarShadowing_MultipleOverrides_UsesLatest(t *testing.T) {
 	// Multiple overrides should use the latest declaration
 
 	source := []byte(`
-var COUNT = "5"
-var COUNT = "10"
-var COUNT = "15"
+var COUNT secret = "5"
+var COUNT secret = "10"
+var COUNT secret = "15"
 echo "@var.COUNT"
 `)
 
@@ -131,9 +131,9 @@ func TestVarShadowing_BothValuesStored_ForDeduplication(t *testing.T) {
 	// but only the latest is accessible via variable name
 
 	source := []byte(`
-var COUNT = "5"
-var COUNT = "10"
-var ANOTHER = "5"
+var COUNT secret = "5"
+var COUNT secret = "10"
+var ANOTHER secret = "5"
 echo "@var.COUNT"
 echo "@var.ANOTHER"
 `)
@@ -182,10 +182,10 @@ func TestVarShadowing_DifferentSteps_Works(t *testing.T) {
 	// Shadowing should work across different steps (steps are not scopes)
 
 	source := []byte(`
-var COUNT = "5"
+var COUNT secret = "5"
 echo "@var.COUNT"
 
-var COUNT = "10"
+var COUNT secret = "10"
 echo "@var.COUNT"
 `)
 
@@ -232,8 +232,8 @@ func TestVarShadowing_SameValue_SharesExprID(t *testing.T) {
 	// Redeclaring with same value should share exprID (deduplication)
 
 	source := []byte(`
-var COUNT = "5"
-var COUNT = "5"
+var COUNT secret = "5"
+var COUNT secret = "5"
 echo "@var.COUNT"
 `)
 
@@ -264,9 +264,9 @@ func TestVarShadowing_IntermediateUse_UsesCorrectValue(t *testing.T) {
 	// Using variable between declarations should use the value at that point
 
 	source := []byte(`
-var COUNT = "5"
+var COUNT secret = "5"
 echo "@var.COUNT"
-var COUNT = "10"
+var COUNT secret = "10"
 echo "@var.COUNT"
 `)
 
@@ -314,9 +314,9 @@ func TestVarShadowing_ExecutionOrder_Preserved(t *testing.T) {
 	// First echo uses first value, second echo uses second value
 
 	source := []byte(`
-var NAME = "Alice"
+var NAME secret = "Alice"
 echo "@var.NAME"
-var NAME = "Bob"
+var NAME secret = "Bob"
 echo "@var.NAME"
 `)
 
@@ -377,9 +377,9 @@ func TestVarShadowing_CommandsHaveDifferentDisplayIDs(t *testing.T) {
 	// This test will FAIL if Pass 3 does a fresh LookupVariable() instead of using captured exprIDs
 
 	source := []byte(`
-var COUNT = "5"
+var COUNT secret = "5"
 echo "@var.COUNT"
-var COUNT = "10"
+var COUNT secret = "10"
 echo "@var.COUNT"
 `)
 
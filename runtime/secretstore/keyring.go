@@ -0,0 +1,95 @@
+package secretstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// SystemKeyring returns the OS credential store: the login keychain through
+// `security` on macOS, or the Secret Service through `secret-tool` on Linux
+// and the BSDs. Secrets are passed on stdin, never on the command line.
+func SystemKeyring() (Keyring, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, fmt.Errorf("macOS keychain unavailable: %w", err)
+		}
+		return macKeychain{}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, fmt.Errorf("secret service unavailable (install libsecret-tools): %w", err)
+		}
+		return secretService{}, nil
+	default:
+		return nil, fmt.Errorf("no OS keyring support on %s; use %s or %s", runtime.GOOS, EnvKeyFile, EnvPassphrase)
+	}
+}
+
+type macKeychain struct{}
+
+func (macKeychain) Get(service, account string) ([]byte, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 { // errSecItemNotFound
+		return nil, ErrKeyringNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("security find-generic-password: %w", err)
+	}
+	return bytes.TrimRight(out, "\n"), nil
+}
+
+func (macKeychain) Set(service, account string, secret []byte) error {
+	if err := checkKeyringArg(service, account, string(secret)); err != nil {
+		return err
+	}
+
+	// Interactive mode reads the command from stdin, keeping the secret out of argv
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", service, account, strings.TrimSpace(string(secret))))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security add-generic-password: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+type secretService struct{}
+
+func (secretService) Get(service, account string) ([]byte, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(out) == 0 {
+		return nil, ErrKeyringNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secret-tool lookup: %w", err)
+	}
+	return out, nil
+}
+
+func (secretService) Set(service, account string, secret []byte) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "sigil secret store ("+account+")", "service", service, "account", account)
+	cmd.Stdin = bytes.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// checkKeyringArg rejects values that would need quoting in `security -i`.
+func checkKeyringArg(values ...string) error {
+	for _, value := range values {
+		trimmed := strings.TrimSpace(value)
+		if trimmed == "" {
+			return errors.New("keyring service, account and secret must not be empty")
+		}
+		if strings.IndexFunc(trimmed, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+			return errors.New("keyring service, account and secret may only use letters, digits, '.', '_' and '-'")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package secretstore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// EnvPassphrase supplies the store passphrase.
+	EnvPassphrase = "SIGIL_SECRETS_PASSPHRASE"
+
+	// EnvKeyFile names a key file holding the store key material.
+	EnvKeyFile = "SIGIL_SECRETS_KEY_FILE"
+
+	// EnvKeyring names the OS keyring account holding the store key material.
+	EnvKeyring = "SIGIL_SECRETS_KEYRING"
+
+	// KeyringService is the service name sigil uses for keyring entries.
+	KeyringService = "sigil"
+
+	// Argon2id parameters for passphrase-derived keys (RFC 9106 second
+	// recommended option). Changing them requires a new formatVersion.
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+
+	hkdfInfo = "sigil-secrets"
+)
+
+// KeySource produces the key that encrypts a store.
+//
+// Sources are pluggable: passphrases go through Argon2id, while key files
+// and keyring entries already hold random key material and are expanded
+// with HKDF. Every source mixes in the store's salt, so rotating the salt
+// rotates the key.
+type KeySource interface {
+	// Kind names the source in the store header ("passphrase", "keyfile",
+	// "keyring"). Opening a store with a different kind fails early with a
+	// clear error instead of a decryption failure.
+	Kind() string
+
+	// DeriveKey returns the 32-byte store key for salt. create is true when
+	// a store is being written with a new salt; sources that hold generated
+	// key material create it then if it does not exist yet.
+	DeriveKey(salt []byte, create bool) ([]byte, error)
+}
+
+// Passphrase returns a key source that derives the key from passphrase with Argon2id.
+func Passphrase(passphrase []byte) KeySource {
+	return passphraseSource{passphrase: append([]byte(nil), passphrase...)}
+}
+
+type passphraseSource struct {
+	passphrase []byte
+}
+
+func (p passphraseSource) Kind() string { return "passphrase" }
+
+func (p passphraseSource) DeriveKey(salt []byte, create bool) ([]byte, error) {
+	if len(p.passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	return argon2.IDKey(p.passphrase, salt, argonTime, argonMemory, argonThreads, keySize), nil
+}
+
+// KeyFile returns a key source that reads key material from path.
+// The file holds at least 32 bytes, either raw or hex-encoded. When a new
+// store is written and the file does not exist, a random key is generated
+// into it with owner-only permissions.
+func KeyFile(path string) KeySource {
+	return keyFileSource{path: path}
+}
+
+type keyFileSource struct {
+	path string
+}
+
+func (k keyFileSource) Kind() string { return "keyfile" }
+
+func (k keyFileSource) DeriveKey(salt []byte, create bool) ([]byte, error) {
+	raw, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		raw, err = newKeyMaterial()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+			return nil, fmt.Errorf("create key file directory: %w", err)
+		}
+		if err := os.WriteFile(k.path, raw, 0o600); err != nil {
+			return nil, fmt.Errorf("write key file: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	material, err := decodeKeyMaterial(raw)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", k.path, err)
+	}
+	return expandKey(material, salt)
+}
+
+// Keyring stores key material in an OS credential store.
+type Keyring interface {
+	// Get returns the secret for service/account, or ErrKeyringNotFound.
+	Get(service, account string) ([]byte, error)
+
+	// Set creates or replaces the secret for service/account.
+	Set(service, account string, secret []byte) error
+}
+
+// ErrKeyringNotFound is returned by Keyring.Get when no entry exists.
+var ErrKeyringNotFound = errors.New("keyring entry not found")
+
+// KeyringKey returns a key source that keeps key material in ring under
+// KeyringService/account, generating it when a new store is written.
+func KeyringKey(ring Keyring, account string) KeySource {
+	return keyringSource{ring: ring, account: account}
+}
+
+type keyringSource struct {
+	ring    Keyring
+	account string
+}
+
+func (k keyringSource) Kind() string { return "keyring" }
+
+func (k keyringSource) DeriveKey(salt []byte, create bool) ([]byte, error) {
+	raw, err := k.ring.Get(KeyringService, k.account)
+	if errors.Is(err, ErrKeyringNotFound) && create {
+		raw, err = newKeyMaterial()
+		if err != nil {
+			return nil, err
+		}
+		if err := k.ring.Set(KeyringService, k.account, raw); err != nil {
+			return nil, fmt.Errorf("store key in keyring: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("read keyring account %q: %w", k.account, err)
+	}
+
+	material, err := decodeKeyMaterial(raw)
+	if err != nil {
+		return nil, fmt.Errorf("keyring account %q: %w", k.account, err)
+	}
+	return expandKey(material, salt)
+}
+
+// KeySourceFromEnv picks the key source configured in the environment:
+// $SIGIL_SECRETS_KEY_FILE, then $SIGIL_SECRETS_KEYRING, then
+// $SIGIL_SECRETS_PASSPHRASE.
+func KeySourceFromEnv() (KeySource, error) {
+	if path := os.Getenv(EnvKeyFile); path != "" {
+		return KeyFile(path), nil
+	}
+	if account := os.Getenv(EnvKeyring); account != "" {
+		ring, err := SystemKeyring()
+		if err != nil {
+			return nil, err
+		}
+		return KeyringKey(ring, account), nil
+	}
+	if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+		return Passphrase([]byte(passphrase)), nil
+	}
+	return nil, fmt.Errorf("no secret store key configured: set %s, %s or %s", EnvKeyFile, EnvKeyring, EnvPassphrase)
+}
+
+// newKeyMaterial returns 32 random bytes, hex-encoded so key files and
+// keyring entries stay printable.
+func newKeyMaterial() ([]byte, error) {
+	raw := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	return []byte(hex.EncodeToString(raw) + "\n"), nil
+}
+
+func decodeKeyMaterial(raw []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(raw))
+	if decoded, err := hex.DecodeString(text); err == nil && len(decoded) >= keySize {
+		return decoded, nil
+	}
+	if len(raw) < keySize {
+		return nil, fmt.Errorf("key material must be at least %d bytes", keySize)
+	}
+	return raw, nil
+}
+
+func expandKey(material, salt []byte) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, material, salt, []byte(hkdfInfo)), key); err != nil {
+		return nil, fmt.Errorf("expand key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,135 @@
+package secretstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type memoryKeyring map[string][]byte
+
+func (m memoryKeyring) Get(service, account string) ([]byte, error) {
+	secret, ok := m[service+"/"+account]
+	if !ok {
+		return nil, ErrKeyringNotFound
+	}
+	return secret, nil
+}
+
+func (m memoryKeyring) Set(service, account string, secret []byte) error {
+	m[service+"/"+account] = append([]byte(nil), secret...)
+	return nil
+}
+
+func TestKeyFileGeneratedOnCreate(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "keys", "store.key")
+	salt := []byte("0123456789abcdef")
+
+	if _, err := KeyFile(keyPath).DeriveKey(salt, false); err == nil {
+		t.Fatal("expected missing key file to fail when not creating")
+	}
+
+	key, err := KeyFile(keyPath).DeriveKey(salt, true)
+	if err != nil {
+		t.Fatalf("DeriveKey create: %v", err)
+	}
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		t.Fatalf("key file not created: %v", err)
+	}
+	if diff := cmp.Diff(os.FileMode(0o600), info.Mode().Perm()); diff != "" {
+		t.Errorf("key file permissions mismatch (-want +got):\n%s", diff)
+	}
+
+	again, err := KeyFile(keyPath).DeriveKey(salt, false)
+	if err != nil {
+		t.Fatalf("DeriveKey: %v", err)
+	}
+	if diff := cmp.Diff(key, again); diff != "" {
+		t.Errorf("key not stable (-want +got):\n%s", diff)
+	}
+
+	other, err := KeyFile(keyPath).DeriveKey([]byte("fedcba9876543210"), false)
+	if err != nil {
+		t.Fatalf("DeriveKey: %v", err)
+	}
+	if cmp.Equal(key, other) {
+		t.Error("different salts derived the same key")
+	}
+}
+
+func TestKeyFileRejectsShortKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "short.key")
+	if err := os.WriteFile(keyPath, []byte("too short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := KeyFile(keyPath).DeriveKey([]byte("0123456789abcdef"), false); err == nil {
+		t.Error("expected short key file to fail")
+	}
+}
+
+func TestKeyringKey(t *testing.T) {
+	ring := memoryKeyring{}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := Open(path, KeyringKey(ring, "default"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok := ring["sigil/default"]; !ok {
+		t.Fatalf("keyring entry not created: %v", ring)
+	}
+	if err := store.Set("api", []byte("value-1234")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := Open(path, KeyringKey(memoryKeyring{}, "default")); err == nil {
+		t.Error("expected open without keyring entry to fail")
+	}
+
+	reopened, err := Open(path, KeyringKey(ring, "default"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	value, err := reopened.Get("api")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if diff := cmp.Diff("value-1234", string(value)); diff != "" {
+		t.Errorf("value mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKeySourceFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyFile, "")
+	t.Setenv(EnvKeyring, "")
+	t.Setenv(EnvPassphrase, "")
+
+	if _, err := KeySourceFromEnv(); err == nil {
+		t.Error("expected error without configuration")
+	}
+
+	t.Setenv(EnvPassphrase, "correct horse")
+	keys, err := KeySourceFromEnv()
+	if err != nil {
+		t.Fatalf("KeySourceFromEnv: %v", err)
+	}
+	if diff := cmp.Diff("passphrase", keys.Kind()); diff != "" {
+		t.Errorf("kind mismatch (-want +got):\n%s", diff)
+	}
+
+	// A key file takes precedence over a passphrase
+	t.Setenv(EnvKeyFile, filepath.Join(t.TempDir(), "key"))
+	keys, err = KeySourceFromEnv()
+	if err != nil {
+		t.Fatalf("KeySourceFromEnv: %v", err)
+	}
+	if diff := cmp.Diff("keyfile", keys.Kind()); diff != "" {
+		t.Errorf("kind mismatch (-want +got):\n%s", diff)
+	}
+}
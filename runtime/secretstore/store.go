@@ -0,0 +1,404 @@
+// Package secretstore provides the on-disk encrypted secret store behind
+// @secrets.get and the `sigil secrets` commands.
+//
+// A store is a single file holding a small JSON header (format version, key
+// source kind, KDF salt) and one AES-256-GCM ciphertext with every entry.
+// Names and values are both encrypted; only the header is readable without
+// the key. The header is bound to the ciphertext as additional data, so it
+// cannot be swapped or edited without failing decryption.
+package secretstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvFile overrides the store location for both @secrets and `sigil secrets`.
+	EnvFile = "SIGIL_SECRETS_FILE"
+
+	// FileName is the store file name inside a project or user directory.
+	FileName = "secrets.enc"
+
+	// ProjectDir is the per-project directory that holds a project store.
+	ProjectDir = ".sigil"
+
+	formatVersion = 1
+	saltSize      = 16
+	keySize       = 32
+)
+
+// ErrNotFound is returned when a secret name is not in the store.
+var ErrNotFound = errors.New("secret not found")
+
+// Entry describes a stored secret without its value.
+type Entry struct {
+	Name    string
+	Version int       // 1 for a new secret, incremented by each Set
+	Created time.Time // When the name was first stored
+	Updated time.Time // When the current version was stored
+	Expires time.Time // When the current version expires; zero if never
+}
+
+// Store is a decrypted, in-memory view of a store file.
+// Changes are kept in memory until Save is called. A Store is not safe for
+// concurrent use.
+type Store struct {
+	path    string
+	keys    KeySource
+	salt    []byte
+	key     []byte
+	entries map[string]*record
+}
+
+type record struct {
+	Value   []byte    `json:"value"`
+	Version int       `json:"version,omitempty"` // 0 in stores written before versions
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// version treats records from before versions were kept as version 1.
+func (r *record) version() int {
+	return max(r.Version, 1)
+}
+
+func (r *record) entry(name string) Entry {
+	return Entry{Name: name, Version: r.version(), Created: r.Created, Updated: r.Updated, Expires: r.Expires}
+}
+
+// header is the plaintext part of the store file.
+type header struct {
+	Version int    `json:"version"`
+	Keys    string `json:"keys"` // KeySource.Kind() that encrypted the store
+	Salt    []byte `json:"salt"`
+	Data    []byte `json:"data,omitempty"` // nonce || AES-GCM ciphertext
+}
+
+// Open loads the store at path, decrypting it with keys.
+// A missing file yields an empty store that is created on the first Save;
+// key sources that generate key material (key files, keyring entries) create
+// it at that point too.
+func Open(path string, keys KeySource) (*Store, error) {
+	if keys == nil {
+		return nil, errors.New("secret store requires a key source")
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt, err := newSalt()
+		if err != nil {
+			return nil, err
+		}
+		key, err := keys.DeriveKey(salt, true)
+		if err != nil {
+			return nil, fmt.Errorf("derive %s key: %w", keys.Kind(), err)
+		}
+		return &Store{path: path, keys: keys, salt: salt, key: key, entries: make(map[string]*record)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secret store: %w", err)
+	}
+
+	var h header
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, fmt.Errorf("secret store %s is corrupted: %w", path, err)
+	}
+	if h.Version != formatVersion {
+		return nil, fmt.Errorf("secret store %s has unsupported version %d", path, h.Version)
+	}
+	if h.Keys != keys.Kind() {
+		return nil, fmt.Errorf("secret store %s is encrypted with a %s key, not a %s key", path, h.Keys, keys.Kind())
+	}
+	if len(h.Salt) != saltSize {
+		return nil, fmt.Errorf("secret store %s is corrupted: bad salt", path)
+	}
+
+	key, err := keys.DeriveKey(h.Salt, false)
+	if err != nil {
+		return nil, fmt.Errorf("derive %s key: %w", keys.Kind(), err)
+	}
+
+	plaintext, err := decrypt(key, h.Data, h.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secret store %s: wrong key or corrupted file", path)
+	}
+
+	entries := make(map[string]*record)
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("secret store %s is corrupted: %w", path, err)
+	}
+
+	return &Store{path: path, keys: keys, salt: h.Salt, key: key, entries: entries}, nil
+}
+
+// Path returns the file the store is saved to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get returns a copy of the value stored under name.
+func (s *Store) Get(name string) ([]byte, error) {
+	rec, ok := s.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return append([]byte(nil), rec.Value...), nil
+}
+
+// Stat returns the entry for name without its value.
+func (s *Store) Stat(name string) (Entry, error) {
+	rec, ok := s.entries[name]
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return rec.entry(name), nil
+}
+
+// Set stores value under name as a new version, replacing any existing
+// value and its expiry.
+func (s *Store) Set(name string, value []byte) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if rec, ok := s.entries[name]; ok {
+		rec.Value = append([]byte(nil), value...)
+		rec.Version = rec.version() + 1
+		rec.Updated = now
+		rec.Expires = time.Time{}
+		return nil
+	}
+	s.entries[name] = &record{Value: append([]byte(nil), value...), Version: 1, Created: now, Updated: now}
+	return nil
+}
+
+// SetExpiry records when the current version of name expires; the zero
+// time clears it.
+func (s *Store) SetExpiry(name string, expires time.Time) error {
+	rec, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	rec.Expires = expires.UTC()
+	return nil
+}
+
+// Delete removes name from the store.
+func (s *Store) Delete(name string) error {
+	if _, ok := s.entries[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	delete(s.entries, name)
+	return nil
+}
+
+// List returns all entries sorted by name.
+func (s *Store) List() []Entry {
+	entries := make([]Entry, 0, len(s.entries))
+	for name, rec := range s.entries {
+		entries = append(entries, rec.entry(name))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Rotate re-encrypts the store under a fresh salt, and under newKeys when it
+// is not nil, then saves it. Re-running with the same passphrase still
+// changes the derived key, so older copies of the file cannot be decrypted
+// with a key that leaked from memory.
+func (s *Store) Rotate(newKeys KeySource) error {
+	if newKeys == nil {
+		newKeys = s.keys
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return err
+	}
+	key, err := newKeys.DeriveKey(salt, true)
+	if err != nil {
+		return fmt.Errorf("derive %s key: %w", newKeys.Kind(), err)
+	}
+
+	s.keys, s.salt, s.key = newKeys, salt, key
+	return s.Save()
+}
+
+// Save encrypts the store and atomically replaces the file.
+// The file is readable only by the current user.
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("encode secret store: %w", err)
+	}
+
+	h := header{Version: formatVersion, Keys: s.keys.Kind(), Salt: s.salt}
+	h.Data, err = encrypt(s.key, plaintext, h.additionalData())
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode secret store: %w", err)
+	}
+
+	return writeFileAtomic(s.path, append(raw, '\n'))
+}
+
+// ValidateName checks that name is a slash-separated secret path such as
+// "tokens/github". Segments use letters, digits, '.', '_' and '-'.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("secret name must not be empty")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid secret name %q: empty or relative segment", name)
+		}
+		for _, r := range segment {
+			if !isNameRune(r) {
+				return fmt.Errorf("invalid secret name %q: unexpected character %q", name, r)
+			}
+		}
+	}
+	return nil
+}
+
+func isNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-'
+}
+
+// DefaultPath returns the store used from dir: $SIGIL_SECRETS_FILE when set,
+// else the nearest .sigil/secrets.enc in dir or its parents, else the user
+// store in the OS config directory.
+func DefaultPath(dir string) (string, error) {
+	if path := os.Getenv(EnvFile); path != "" {
+		return path, nil
+	}
+
+	for current := dir; ; {
+		candidate := ProjectPath(current)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	return UserPath()
+}
+
+// ProjectPath returns the project store location for a project rooted at dir.
+func ProjectPath(dir string) string {
+	return filepath.Join(dir, ProjectDir, FileName)
+}
+
+// UserPath returns the per-user store location.
+func UserPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate user secret store: %w", err)
+	}
+	return filepath.Join(configDir, "sigil", FileName), nil
+}
+
+func (h header) additionalData() []byte {
+	return fmt.Appendf(nil, "sigil-secrets:v%d:%s:%x", h.Version, h.Keys, h.Salt)
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+func encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func decrypt(key, data, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return gcm, nil
+}
+
+// writeFileAtomic writes data next to path and renames it into place, so a
+// crash never leaves a truncated store behind.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create secret store directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write secret store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package secretstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	keys := KeyFile(filepath.Join(t.TempDir(), "key"))
+
+	store, err := Open(path, keys)
+	if err != nil {
+		t.Fatalf("Open new store: %v", err)
+	}
+	if err := store.Set("tokens/github", []byte("ghp_abcdef123456")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set("db/password", []byte("hunter2-long")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat store: %v", err)
+	}
+	if diff := cmp.Diff(os.FileMode(0o600), info.Mode().Perm()); diff != "" {
+		t.Errorf("store permissions mismatch (-want +got):\n%s", diff)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	for _, plain := range []string{"ghp_abcdef123456", "tokens/github", "db/password"} {
+		if bytes.Contains(raw, []byte(plain)) {
+			t.Errorf("store file contains plaintext %q", plain)
+		}
+	}
+
+	reopened, err := Open(path, keys)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	value, err := reopened.Get("tokens/github")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if diff := cmp.Diff("ghp_abcdef123456", string(value)); diff != "" {
+		t.Errorf("value mismatch (-want +got):\n%s", diff)
+	}
+
+	var names []string
+	for _, entry := range reopened.List() {
+		names = append(names, entry.Name)
+	}
+	if diff := cmp.Diff([]string{"db/password", "tokens/github"}, names); diff != "" {
+		t.Errorf("List mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := reopened.Delete("db/password"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := reopened.Get("db/password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+	if err := reopened.Delete("db/password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreVersionsAndExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	keys := KeyFile(filepath.Join(t.TempDir(), "key"))
+
+	store, err := Open(path, keys)
+	if err != nil {
+		t.Fatalf("Open new store: %v", err)
+	}
+	expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Set("db/password", []byte("v1")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.SetExpiry("db/password", expires); err != nil {
+		t.Fatalf("SetExpiry: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reopened, err := Open(path, keys)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	entry, err := reopened.Stat("db/password")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if entry.Version != 1 || !entry.Expires.Equal(expires) {
+		t.Errorf("Stat = version %d, expires %v; want 1, %v", entry.Version, entry.Expires, expires)
+	}
+
+	// A new value is a new version and drops the old version's expiry
+	if err := reopened.Set("db/password", []byte("v2")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	entry, err = reopened.Stat("db/password")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if entry.Version != 2 || !entry.Expires.IsZero() {
+		t.Errorf("Stat after Set = version %d, expires %v; want 2, zero", entry.Version, entry.Expires)
+	}
+
+	if _, err := reopened.Stat("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat missing: got %v, want ErrNotFound", err)
+	}
+	if err := reopened.SetExpiry("missing", expires); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetExpiry missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreRejectsWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := Open(path, Passphrase([]byte("correct horse")))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Set("api", []byte("value-1234")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	_, err = Open(path, Passphrase([]byte("battery staple")))
+	if err == nil || !strings.Contains(err.Error(), "wrong key or corrupted file") {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+
+	_, err = Open(path, KeyFile(filepath.Join(t.TempDir(), "key")))
+	if err == nil || !strings.Contains(err.Error(), "encrypted with a passphrase key") {
+		t.Errorf("wrong key kind: got %v", err)
+	}
+}
+
+func TestStoreRejectsTamperedHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	keyPath := filepath.Join(t.TempDir(), "key")
+
+	store, err := Open(path, KeyFile(keyPath))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Set("api", []byte("value-1234")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Swap the salt for another valid one: the key changes and so does the
+	// additional data, so decryption must fail rather than yield garbage
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	var h header
+	if err := json.Unmarshal(raw, &h); err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	h.Salt, err = newSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, tampered, 0o600); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+
+	if _, err := Open(path, KeyFile(keyPath)); err == nil {
+		t.Error("expected tampered store to fail")
+	}
+}
+
+func TestStoreRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+
+	store, err := Open(path, Passphrase([]byte("old passphrase")))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Set("api", []byte("value-1234")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	newKeys := KeyFile(filepath.Join(dir, "key"))
+	if err := store.Rotate(newKeys); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	if _, err := Open(path, Passphrase([]byte("old passphrase"))); err == nil {
+		t.Error("old passphrase still opens the rotated store")
+	}
+	rotated, err := Open(path, newKeys)
+	if err != nil {
+		t.Fatalf("Open rotated: %v", err)
+	}
+	value, err := rotated.Get("api")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if diff := cmp.Diff("value-1234", string(value)); diff != "" {
+		t.Errorf("value mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	valid := []string{"api", "tokens/github", "db.prod/pass_word-1"}
+	for _, name := range valid {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q): %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "/api", "api/", "a//b", "../api", "with space", "semi;colon"}
+	for _, name := range invalid {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q): expected error", name)
+		}
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv(EnvFile, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("HOME", t.TempDir())
+
+	project := t.TempDir()
+	nested := filepath.Join(project, "services", "api")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	userPath, err := UserPath()
+	if err != nil {
+		t.Fatalf("UserPath: %v", err)
+	}
+	got, err := DefaultPath(nested)
+	if err != nil {
+		t.Fatalf("DefaultPath: %v", err)
+	}
+	if diff := cmp.Diff(userPath, got); diff != "" {
+		t.Errorf("without project store (-want +got):\n%s", diff)
+	}
+
+	if err := os.MkdirAll(filepath.Join(project, ProjectDir), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ProjectPath(project), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = DefaultPath(nested)
+	if err != nil {
+		t.Fatalf("DefaultPath: %v", err)
+	}
+	if diff := cmp.Diff(ProjectPath(project), got); diff != "" {
+		t.Errorf("with project store (-want +got):\n%s", diff)
+	}
+
+	t.Setenv(EnvFile, "/explicit/secrets.enc")
+	got, err = DefaultPath(nested)
+	if err != nil {
+		t.Fatalf("DefaultPath: %v", err)
+	}
+	if diff := cmp.Diff("/explicit/secrets.enc", got); diff != "" {
+		t.Errorf("with %s (-want +got):\n%s", EnvFile, diff)
+	}
+}
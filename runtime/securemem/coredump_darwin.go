@@ -0,0 +1,10 @@
+//go:build darwin
+
+package securemem
+
+// DisableCoreDumps stops the process from writing core dumps while secrets
+// are loaded by setting RLIMIT_CORE to zero. The returned function restores
+// the previous limit.
+func DisableCoreDumps() (restore func(), err error) {
+	return disableCoreLimit()
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package securemem
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DisableCoreDumps stops the process from writing core dumps while secrets
+// are loaded: RLIMIT_CORE is set to zero and the process is marked
+// non-dumpable, which also blocks ptrace by other processes of the same user.
+// The returned function restores the previous settings.
+func DisableCoreDumps() (restore func(), err error) {
+	restoreLimit, err := disableCoreLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	dumpable, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_GET_DUMPABLE, 0, 0)
+	if errno != 0 {
+		restoreLimit()
+		return nil, fmt.Errorf("prctl(PR_GET_DUMPABLE): %w", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_DUMPABLE, 0, 0); errno != 0 {
+		restoreLimit()
+		return nil, fmt.Errorf("prctl(PR_SET_DUMPABLE): %w", errno)
+	}
+
+	return func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_DUMPABLE, dumpable, 0)
+		restoreLimit()
+	}, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package securemem
+
+// DisableCoreDumps is a no-op on platforms without RLIMIT_CORE.
+func DisableCoreDumps() (restore func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin
+
+package securemem
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// disableCoreLimit sets RLIMIT_CORE to zero and returns a function that
+// restores the previous limit.
+func disableCoreLimit() (restore func(), err error) {
+	var prev syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &prev); err != nil {
+		return nil, fmt.Errorf("getrlimit(RLIMIT_CORE): %w", err)
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: prev.Max}); err != nil {
+		return nil, fmt.Errorf("setrlimit(RLIMIT_CORE): %w", err)
+	}
+	return func() { _ = syscall.Setrlimit(syscall.RLIMIT_CORE, &prev) }, nil
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package securemem
+
+import (
+	"syscall"
+	"testing"
+)
+
+func coreLimit(t *testing.T) uint64 {
+	t.Helper()
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &limit); err != nil {
+		t.Fatalf("getrlimit: %v", err)
+	}
+	return limit.Cur
+}
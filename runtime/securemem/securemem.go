@@ -0,0 +1,99 @@
+// Package securemem holds secret plaintext outside the Go heap.
+//
+// A Buffer's bytes live in their own mapping, locked into RAM where the OS
+// allows it and surrounded by inaccessible guard pages, so an overrun faults
+// instead of reading neighbouring memory. Destroy zeroes the bytes before the
+// memory is released. Platforms without mmap fall back to heap memory that is
+// still zeroed on Destroy.
+package securemem
+
+import (
+	"sync"
+)
+
+// Buffer holds secret bytes until Destroy is called.
+// It is safe for concurrent use.
+type Buffer struct {
+	mu     sync.RWMutex
+	data   []byte // Secret bytes; nil once destroyed
+	region []byte // Whole allocation including guard pages (nil on fallback)
+	locked bool   // True if data is locked into RAM
+}
+
+// New copies secret into a new Buffer. The caller still owns secret and
+// should zero it if it is no longer needed.
+func New(secret []byte) *Buffer {
+	b := allocate(len(secret))
+	copy(b.data, secret)
+	return b
+}
+
+// NewString copies secret into a new Buffer.
+func NewString(secret string) *Buffer {
+	b := allocate(len(secret))
+	copy(b.data, secret)
+	return b
+}
+
+// Bytes returns a copy of the secret, or nil if the buffer was destroyed.
+func (b *Buffer) Bytes() []byte {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.data == nil {
+		return nil
+	}
+	return append([]byte{}, b.data...)
+}
+
+// String returns the secret as a string, or "" if the buffer was destroyed.
+// The string is an ordinary heap copy and cannot be wiped; prefer Bytes.
+func (b *Buffer) String() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return string(b.data)
+}
+
+// Len returns the length of the secret.
+func (b *Buffer) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.data)
+}
+
+// Locked reports whether the secret is locked into RAM.
+func (b *Buffer) Locked() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.locked
+}
+
+// Destroyed reports whether Destroy has been called.
+func (b *Buffer) Destroyed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.data == nil
+}
+
+// Destroy zeroes the secret and releases its memory. It is safe to call more
+// than once.
+func (b *Buffer) Destroy() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.data == nil {
+		return
+	}
+	Wipe(b.data)
+	release(b)
+	b.data, b.region, b.locked = nil, nil, false
+}
+
+// Wipe zeroes p.
+func Wipe(p []byte) {
+	clear(p)
+}
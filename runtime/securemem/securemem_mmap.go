@@ -0,0 +1,53 @@
+//go:build linux || darwin
+
+package securemem
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// allocate maps the secret pages between two guard pages:
+//
+//	[guard][data pages...][guard]
+//
+// The secret is placed at the end of the data pages so an overrun hits the
+// trailing guard page. If the mapping fails, it falls back to the heap.
+func allocate(size int) *Buffer {
+	page := os.Getpagesize()
+	dataPages := (max(size, 1) + page - 1) / page * page
+	region, err := syscall.Mmap(-1, 0, dataPages+2*page,
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return &Buffer{data: make([]byte, size)}
+	}
+
+	_ = syscall.Mprotect(region[:page], syscall.PROT_NONE)
+	_ = syscall.Mprotect(region[page+dataPages:], syscall.PROT_NONE)
+
+	pages := region[page : page+dataPages]
+	b := &Buffer{
+		data:   pages[dataPages-size : dataPages : dataPages],
+		region: region,
+		// Locking can fail under RLIMIT_MEMLOCK; the guard pages and wiping
+		// still apply, so continue unlocked.
+		locked: syscall.Mlock(pages) == nil,
+	}
+	// Wipe buffers that are dropped without Destroy.
+	runtime.SetFinalizer(b, (*Buffer).Destroy)
+	return b
+}
+
+// release unlocks and unmaps b's region. The secret is already zeroed.
+func release(b *Buffer) {
+	runtime.SetFinalizer(b, nil)
+	if b.region == nil {
+		return
+	}
+	page := os.Getpagesize()
+	if b.locked {
+		_ = syscall.Munlock(b.region[page : len(b.region)-page])
+	}
+	_ = syscall.Munmap(b.region)
+}
@@ -0,0 +1,126 @@
+package securemem
+
+import (
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBufferHoldsCopy(t *testing.T) {
+	secret := []byte("hunter2-secret")
+	b := New(secret)
+	defer b.Destroy()
+
+	secret[0] = 'X'
+	if diff := cmp.Diff("hunter2-secret", b.String()); diff != "" {
+		t.Errorf("buffer should not alias its input (-want +got):\n%s", diff)
+	}
+
+	got := b.Bytes()
+	got[0] = 'X'
+	if diff := cmp.Diff([]byte("hunter2-secret"), b.Bytes()); diff != "" {
+		t.Errorf("Bytes should return a copy (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(14, b.Len()); diff != "" {
+		t.Errorf("Len mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDestroyWipesBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "short", secret: "tok"},
+		{name: "empty", secret: ""},
+		{name: "multi page", secret: strings.Repeat("s", 3*syscall.Getpagesize()+7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewString(tt.secret)
+			b.Destroy()
+
+			if !b.Destroyed() {
+				t.Error("Destroyed() = false after Destroy")
+			}
+			if b.Bytes() != nil || b.String() != "" || b.Len() != 0 || b.Locked() {
+				t.Errorf("destroyed buffer still returns data: %q", b.Bytes())
+			}
+			b.Destroy() // Idempotent
+		})
+	}
+}
+
+func TestDestroyZeroesSecret(t *testing.T) {
+	// A heap buffer stays readable after Destroy, so the wipe can be
+	// observed; mapped buffers are unmapped and fault instead.
+	data := []byte("hunter2-secret")
+	b := &Buffer{data: data}
+	b.Destroy()
+
+	if diff := cmp.Diff(make([]byte, len(data)), data); diff != "" {
+		t.Errorf("secret not wiped (-want +got):\n%s", diff)
+	}
+}
+
+func TestBufferEndsAtGuardPage(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("guarded buffers need mmap")
+	}
+	b := NewString("hunter2-secret")
+	defer b.Destroy()
+	if b.region == nil {
+		t.Skip("mmap unavailable")
+	}
+
+	page := syscall.Getpagesize()
+	if diff := cmp.Diff(len(b.region)-page, cap(b.data)+indexIn(b.region, b.data)); diff != "" {
+		t.Errorf("secret should end at the trailing guard page (-want +got):\n%s", diff)
+	}
+}
+
+// indexIn returns the offset of sub within region.
+func indexIn(region, sub []byte) int {
+	for i := range region {
+		if &region[i] == &sub[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestDisableCoreDumps(t *testing.T) {
+	restore, err := DisableCoreDumps()
+	if err != nil {
+		t.Fatalf("DisableCoreDumps() error = %v", err)
+	}
+	defer restore()
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return
+	}
+	if limit := coreLimit(t); limit != 0 {
+		t.Errorf("RLIMIT_CORE = %d, want 0", limit)
+	}
+}
+
+func TestDisableCoreDumpsRestores(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("RLIMIT_CORE not supported")
+	}
+	before := coreLimit(t)
+
+	restore, err := DisableCoreDumps()
+	if err != nil {
+		t.Fatalf("DisableCoreDumps() error = %v", err)
+	}
+	restore()
+
+	if diff := cmp.Diff(before, coreLimit(t)); diff != "" {
+		t.Errorf("RLIMIT_CORE not restored (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,264 @@
+package streamscrub
+
+import (
+	"bytes"
+	"sort"
+)
+
+// denseFanout is the number of children above which a state gets a full
+// 256-entry transition row instead of a sorted edge list. Only the root and
+// a few shallow states qualify, which keeps memory linear in pattern bytes
+// while the hot states near the root cost one lookup per byte.
+const denseFanout = 16
+
+// automaton is an Aho-Corasick automaton over a fixed pattern set that
+// replaces leftmost-longest matches in a single pass.
+//
+// States are stored as parallel slices. Transitions missing from a state
+// fall back along failure links, so the structure stays proportional to the
+// total pattern length even with thousands of encoded variants.
+type automaton struct {
+	patterns []Pattern
+	maxLen   int
+
+	fail  []int32 // Longest proper suffix state
+	depth []int32 // Length of the state's string
+	match []int32 // Longest pattern that is a suffix of the state's string (-1 = none)
+
+	// Sparse transitions: edges of state s are edgeBytes/edgeNext[edgeStart[s]:edgeStart[s+1]], sorted by byte
+	edgeStart []int32
+	edgeBytes []byte
+	edgeNext  []int32
+
+	denseRow []int32      // Index into dense for high-fanout states (-1 = sparse)
+	dense    [][256]int32 // Goto rows (-1 = no edge)
+}
+
+// wipe zeroes the pattern values and trie labels, which together spell out
+// every secret. The automaton must not be used afterwards.
+func (a *automaton) wipe() {
+	for _, p := range a.patterns {
+		clear(p.Value)
+	}
+	clear(a.edgeBytes)
+	a.patterns, a.edgeBytes, a.edgeNext, a.dense = nil, nil, nil, nil
+	a.maxLen = 0
+}
+
+// newAutomaton builds an automaton for patterns. Empty values are ignored;
+// for duplicate values the lowest placeholder wins, so output does not
+// depend on the source's ordering.
+func newAutomaton(patterns []Pattern) *automaton {
+	sorted := make([]Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		if len(p.Value) > 0 {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := bytes.Compare(sorted[i].Value, sorted[j].Value); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(sorted[i].Placeholder, sorted[j].Placeholder) < 0
+	})
+
+	a := &automaton{patterns: sorted}
+
+	// Build the trie. Sorted values make shared prefixes adjacent, so each
+	// insert only walks the common prefix with its predecessor, and every
+	// state's children are created in increasing byte order.
+	parent := []int32{-1}
+	label := []byte{0}
+	terminal := []int32{-1}
+	depth := []int32{0}
+	var path []int32 // States along the previous value, path[i] at depth i+1
+	var prev []byte
+	for idx, p := range sorted {
+		common := 0
+		for common < len(prev) && common < len(p.Value) && prev[common] == p.Value[common] {
+			common++
+		}
+		if common == len(p.Value) && len(prev) == len(p.Value) {
+			continue // Duplicate value; the first placeholder wins
+		}
+		path = path[:common]
+		state := int32(0)
+		if common > 0 {
+			state = path[common-1]
+		}
+		for _, b := range p.Value[common:] {
+			child := int32(len(terminal))
+			parent = append(parent, state)
+			label = append(label, b)
+			terminal = append(terminal, -1)
+			depth = append(depth, depth[state]+1)
+			path = append(path, child)
+			state = child
+		}
+		terminal[state] = int32(idx)
+		prev = p.Value
+		if len(p.Value) > a.maxLen {
+			a.maxLen = len(p.Value)
+		}
+	}
+
+	// Lay the edges out per state. Visiting children in creation order
+	// keeps each state's edges sorted by byte.
+	n := len(terminal)
+	a.edgeStart = make([]int32, n+1)
+	for child := 1; child < n; child++ {
+		a.edgeStart[parent[child]+1]++
+	}
+	for s := 1; s <= n; s++ {
+		a.edgeStart[s] += a.edgeStart[s-1]
+	}
+	a.edgeBytes = make([]byte, n-1)
+	a.edgeNext = make([]int32, n-1)
+	fill := append([]int32(nil), a.edgeStart[:n]...)
+	for child := 1; child < n; child++ {
+		slot := fill[parent[child]]
+		fill[parent[child]]++
+		a.edgeBytes[slot] = label[child]
+		a.edgeNext[slot] = int32(child)
+	}
+
+	a.denseRow = make([]int32, n)
+	for s := 0; s < n; s++ {
+		a.denseRow[s] = -1
+		lo, hi := a.edgeStart[s], a.edgeStart[s+1]
+		if s != 0 && hi-lo < denseFanout {
+			continue
+		}
+		var row [256]int32
+		for i := range row {
+			row[i] = -1
+		}
+		for i := lo; i < hi; i++ {
+			row[a.edgeBytes[i]] = a.edgeNext[i]
+		}
+		a.denseRow[s] = int32(len(a.dense))
+		a.dense = append(a.dense, row)
+	}
+
+	// Failure links and outputs, breadth first so every state's suffix
+	// states are finished before it.
+	a.fail = make([]int32, n)
+	a.depth = depth
+	a.match = make([]int32, n)
+	a.match[0] = -1
+	queue := make([]int32, 0, n)
+	for i := a.edgeStart[0]; i < a.edgeStart[1]; i++ {
+		queue = append(queue, a.edgeNext[i])
+	}
+	for head := 0; head < len(queue); head++ {
+		s := queue[head]
+		if terminal[s] >= 0 {
+			a.match[s] = terminal[s]
+		} else {
+			a.match[s] = a.match[a.fail[s]]
+		}
+		for i := a.edgeStart[s]; i < a.edgeStart[s+1]; i++ {
+			b, child := a.edgeBytes[i], a.edgeNext[i]
+			f := a.fail[s]
+			for {
+				if next := a.goTo(f, b); next >= 0 {
+					a.fail[child] = next
+					break
+				}
+				if f == 0 {
+					a.fail[child] = 0
+					break
+				}
+				f = a.fail[f]
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return a
+}
+
+// goTo returns the trie child of state s for b, or -1.
+func (a *automaton) goTo(s int32, b byte) int32 {
+	if row := a.denseRow[s]; row >= 0 {
+		return a.dense[row][b]
+	}
+	lo, hi := a.edgeStart[s], a.edgeStart[s+1]
+	for lo < hi {
+		mid := int32(uint32(lo+hi) >> 1)
+		switch c := a.edgeBytes[mid]; {
+		case c == b:
+			return a.edgeNext[mid]
+		case c < b:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return -1
+}
+
+// step advances from state s over b, following failure links.
+func (a *automaton) step(s int32, b byte) int32 {
+	for {
+		if next := a.goTo(s, b); next >= 0 {
+			return next
+		}
+		if s == 0 {
+			return 0
+		}
+		s = a.fail[s]
+	}
+}
+
+// replace returns chunk with every leftmost-longest match replaced by its
+// placeholder. Replaced text is never rescanned, so a placeholder cannot
+// itself be matched. chunk is returned unchanged when nothing matches.
+func (a *automaton) replace(chunk []byte) []byte {
+	var out []byte
+	copied := 0 // chunk[:copied] is already in out
+
+	root := &a.dense[a.denseRow[0]]
+	state := int32(0)
+	candStart, candEnd, candPattern := -1, 0, int32(-1)
+	for i := 0; ; {
+		if state == 0 && candStart < 0 {
+			// Skip bytes that cannot start a match
+			for i < len(chunk) && root[chunk[i]] < 0 {
+				i++
+			}
+		}
+
+		if i < len(chunk) {
+			state = a.step(state, chunk[i])
+			i++
+
+			if m := a.match[state]; m >= 0 {
+				start := i - len(a.patterns[m].Value)
+				if candStart < 0 || start < candStart || (start == candStart && i > candEnd) {
+					candStart, candEnd, candPattern = start, i, m
+				}
+			}
+			// Any later match starts at or after i-depth; until that passes
+			// the candidate, a match further left or longer may still come.
+			if candStart < 0 || i-int(a.depth[state]) <= candStart {
+				continue
+			}
+		} else if candStart < 0 {
+			break
+		}
+
+		if out == nil {
+			out = make([]byte, 0, len(chunk))
+		}
+		out = append(out, chunk[copied:candStart]...)
+		out = append(out, a.patterns[candPattern].Placeholder...)
+		copied = candEnd
+		i, state, candStart = candEnd, 0, -1
+	}
+
+	if out == nil {
+		return chunk
+	}
+	return append(out, chunk[copied:]...)
+}
@@ -0,0 +1,239 @@
+package streamscrub
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestAutomatonReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []Pattern
+		input    string
+		want     string
+	}{
+		{
+			name:     "no patterns",
+			patterns: nil,
+			input:    "plain output",
+			want:     "plain output",
+		},
+		{
+			name:     "longest of nested matches",
+			patterns: []Pattern{{Value: []byte("SECRET"), Placeholder: []byte("<s>")}, {Value: []byte("SECRET_EXTENDED"), Placeholder: []byte("<l>")}},
+			input:    "a SECRET_EXTENDED b SECRET_EX",
+			want:     "a <l> b <s>_EX",
+		},
+		{
+			name:     "leftmost of overlapping matches",
+			patterns: []Pattern{{Value: []byte("abcd"), Placeholder: []byte("<1>")}, {Value: []byte("cdef"), Placeholder: []byte("<2>")}},
+			input:    "abcdef",
+			want:     "<1>ef",
+		},
+		{
+			name:     "longer match starting later does not win",
+			patterns: []Pattern{{Value: []byte("ab"), Placeholder: []byte("<1>")}, {Value: []byte("bcdefg"), Placeholder: []byte("<2>")}},
+			input:    "abcdefg",
+			want:     "<1>cdefg",
+		},
+		{
+			name:     "suffix pattern found through failure link",
+			patterns: []Pattern{{Value: []byte("xxxy"), Placeholder: []byte("<1>")}, {Value: []byte("xy"), Placeholder: []byte("<2>")}},
+			input:    "xxxxy",
+			want:     "x<1>",
+		},
+		{
+			name:     "adjacent matches",
+			patterns: []Pattern{{Value: []byte("aa"), Placeholder: []byte("<a>")}},
+			input:    "aaaaa",
+			want:     "<a><a>a",
+		},
+		{
+			name:     "placeholder is not rescanned",
+			patterns: []Pattern{{Value: []byte("key"), Placeholder: []byte("monkey")}, {Value: []byte("mon"), Placeholder: []byte("<m>")}},
+			input:    "key",
+			want:     "monkey",
+		},
+		{
+			name:     "duplicate value uses lowest placeholder",
+			patterns: []Pattern{{Value: []byte("dup"), Placeholder: []byte("sigil:b")}, {Value: []byte("dup"), Placeholder: []byte("sigil:a")}},
+			input:    "dup",
+			want:     "sigil:a",
+		},
+		{
+			name:     "match at end of input",
+			patterns: []Pattern{{Value: []byte("tail"), Placeholder: []byte("<t>")}, {Value: []byte("tails"), Placeholder: []byte("<ts>")}},
+			input:    "the tail",
+			want:     "the <t>",
+		},
+		{
+			name:     "empty values ignored",
+			patterns: []Pattern{{Value: nil, Placeholder: []byte("<e>")}, {Value: []byte("x"), Placeholder: []byte("<x>")}},
+			input:    "axb",
+			want:     "a<x>b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(newAutomaton(tt.patterns).replace([]byte(tt.input)))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutomatonReplace_UnchangedChunkNotCopied(t *testing.T) {
+	chunk := []byte("nothing to see")
+	got := newAutomaton([]Pattern{{Value: []byte("secret"), Placeholder: []byte("x")}}).replace(chunk)
+	if &got[0] != &chunk[0] {
+		t.Error("expected the input chunk to be returned when nothing matches")
+	}
+}
+
+// naiveLeftmostLongest is a reference implementation: at each position,
+// replace the longest pattern starting there.
+func naiveLeftmostLongest(patterns []Pattern, input []byte) []byte {
+	var out []byte
+	for i := 0; i < len(input); {
+		best := -1
+		for j, p := range patterns {
+			if len(p.Value) == 0 || !bytes.HasPrefix(input[i:], p.Value) {
+				continue
+			}
+			if best < 0 || len(p.Value) > len(patterns[best].Value) ||
+				(len(p.Value) == len(patterns[best].Value) && bytes.Compare(p.Placeholder, patterns[best].Placeholder) < 0) {
+				best = j
+			}
+		}
+		if best < 0 {
+			out = append(out, input[i])
+			i++
+			continue
+		}
+		out = append(out, patterns[best].Placeholder...)
+		i += len(patterns[best].Value)
+	}
+	return out
+}
+
+func TestAutomatonReplace_MatchesReference(t *testing.T) {
+	tests := []struct {
+		name        string
+		alphabet    string
+		maxPatterns int
+	}{
+		// A small alphabet forces overlaps and long failure chains
+		{name: "overlapping", alphabet: "abc", maxPatterns: 8},
+		// A wide alphabet gives shallow states enough children for dense rows
+		{name: "high fanout", alphabet: "abcdefghijklmnopqrstuvwxyz", maxPatterns: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			randomBytes := func(n int) []byte {
+				b := make([]byte, n)
+				for i := range b {
+					b[i] = tt.alphabet[rng.Intn(len(tt.alphabet))]
+				}
+				return b
+			}
+
+			for iter := 0; iter < 300; iter++ {
+				patterns := make([]Pattern, 1+rng.Intn(tt.maxPatterns))
+				for i := range patterns {
+					patterns[i] = Pattern{Value: randomBytes(1 + rng.Intn(5)), Placeholder: []byte(fmt.Sprintf("<%d>", i))}
+				}
+				input := randomBytes(rng.Intn(200))
+
+				want := string(naiveLeftmostLongest(patterns, input))
+				got := string(newAutomaton(patterns).replace(input))
+				if got != want {
+					t.Fatalf("iteration %d: patterns %q, input %q\ngot  %q\nwant %q", iter, patterns, input, got, want)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkPatterns returns n random 32-byte hex secrets, the shape of
+// typical tokens.
+func benchmarkPatterns(n int) []Pattern {
+	rng := rand.New(rand.NewSource(int64(n)))
+	patterns := make([]Pattern, n)
+	for i := range patterns {
+		secret := make([]byte, 16)
+		rng.Read(secret)
+		patterns[i] = Pattern{Value: []byte(toHex(secret)), Placeholder: []byte(fmt.Sprintf("sigil:%08d", i))}
+	}
+	return patterns
+}
+
+// benchmarkOutput returns size bytes of build-log-like text with a secret
+// from patterns every 64 KiB.
+func benchmarkOutput(size int, patterns []Pattern) []byte {
+	line := []byte("[build] compiling package github.com/example/service/internal/handlers (0x3fa9c2d1) ok\n")
+	out := make([]byte, 0, size)
+	for i := 0; len(out) < size; i++ {
+		out = append(out, line...)
+		if i%700 == 0 && len(patterns) > 0 {
+			out = append(out, patterns[i%len(patterns)].Value...)
+			out = append(out, '\n')
+		}
+	}
+	return out[:size]
+}
+
+// BenchmarkPatternProvider scrubs 100 MB of output in 64 KiB chunks through
+// a versioned provider with encoding variants, as the vault uses it.
+func BenchmarkPatternProvider(b *testing.B) {
+	const (
+		outputSize = 100 << 20
+		chunkSize  = 64 << 10
+	)
+
+	for _, secrets := range []int{10, 1000, 5000} {
+		patterns := benchmarkPatterns(secrets)
+		output := benchmarkOutput(outputSize, patterns)
+		provider := NewPatternProviderWithVariants(func() []Pattern { return patterns },
+			WithPatternVersion(func() uint64 { return 1 }))
+
+		b.Run(fmt.Sprintf("secrets=%d", secrets), func(b *testing.B) {
+			_, _ = provider.HandleChunk(nil) // Build the automaton outside the timer
+			b.SetBytes(outputSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for off := 0; off < len(output); off += chunkSize {
+					end := min(off+chunkSize, len(output))
+					if _, err := provider.HandleChunk(output[off:end]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAutomatonBuild measures a rebuild after the pattern set changes.
+func BenchmarkAutomatonBuild(b *testing.B) {
+	for _, secrets := range []int{100, 1000, 5000} {
+		base := benchmarkPatterns(secrets)
+		var patterns []Pattern
+		for _, p := range base {
+			patterns = append(patterns, p)
+			patterns = append(patterns, generateVariants(p)...)
+		}
+
+		b.Run(fmt.Sprintf("secrets=%d", secrets), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				newAutomaton(patterns)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package streamscrub
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Rule describes one credential shape recognized by a CredentialDetector.
+type Rule struct {
+	// Name identifies the rule and types its placeholder, e.g.
+	// "aws-access-key" redacts to "<redacted:aws-access-key>".
+	Name string
+
+	// Pattern matches the credential. When it has a capture group, only
+	// the first group is redacted (e.g. the value after "password=").
+	Pattern *regexp.Regexp
+
+	// Keywords skip the pattern for chunks containing none of them
+	// (compared case-insensitively). Empty runs the pattern on every chunk.
+	Keywords []string
+
+	// MaxLength is the longest expected match in bytes. The scrubber holds
+	// back this much output so credentials split across writes are caught;
+	// longer matches are still redacted, but one cut by a write boundary
+	// may be partly written before it is recognized.
+	MaxLength int
+
+	// MinEntropy is the minimum Shannon entropy, in bits per byte, of the
+	// redacted text. Zero accepts every match.
+	MinEntropy float64
+
+	// Body and Close describe block credentials longer than any sensible
+	// hold-back, such as PEM private keys. Pattern then matches the opening
+	// marker, Body each line of the block and Close its last line. A block
+	// still open at the end of a write is redacted up to there and its
+	// marker is carried into the next write with the line the write cut, so
+	// MaxLength only has to cover the marker and one line.
+	Body  *regexp.Regexp
+	Close *regexp.Regexp
+}
+
+// NewRule compiles a rule from a regular expression.
+func NewRule(name, pattern string, maxLength int) (Rule, error) {
+	if name == "" {
+		return Rule{}, fmt.Errorf("rule name cannot be empty")
+	}
+	if maxLength <= 0 {
+		return Rule{}, fmt.Errorf("rule %q: max length must be positive", name)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("rule %q: %w", name, err)
+	}
+	return Rule{Name: name, Pattern: re, MaxLength: maxLength}, nil
+}
+
+const (
+	// pemKeyBegin is the opening marker of a PEM private key block.
+	pemKeyBegin = `-----BEGIN[ A-Z0-9]{0,32} PRIVATE KEY(?: BLOCK)?-----`
+
+	// pemKeyLine is one line of a PEM block body: base64 or a legacy
+	// encryption header.
+	pemKeyLine = `^(?:[A-Za-z0-9+/=]{0,80}|Proc-Type:[ 0-9A-Z,]*|DEK-Info:[ A-Za-z0-9,-]*)$`
+
+	// pemKeyEnd is the closing line of a PEM private key block.
+	pemKeyEnd = `^-----END[ A-Z0-9]{0,32} PRIVATE KEY(?: BLOCK)?-----`
+)
+
+// DefaultRules returns the built-in credential rules. The slice is a fresh
+// copy that callers may filter or extend.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Name:      "aws-access-key",
+			Pattern:   regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`),
+			Keywords:  []string{"akia", "asia", "abia", "acca"},
+			MaxLength: 20,
+		},
+		{
+			Name:      "github-token",
+			Pattern:   regexp.MustCompile(`\b(?:gh[pousr]_[0-9A-Za-z]{36,251}|github_pat_[0-9A-Za-z_]{22,245})\b`),
+			Keywords:  []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"},
+			MaxLength: 256,
+		},
+		{
+			Name:      "gitlab-token",
+			Pattern:   regexp.MustCompile(`\b(?:glpat|glptt|gldt|glrt)-[0-9A-Za-z_-]{20,64}`),
+			Keywords:  []string{"glpat-", "glptt-", "gldt-", "glrt-"},
+			MaxLength: 70,
+		},
+		{
+			Name:      "slack-token",
+			Pattern:   regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,250}`),
+			Keywords:  []string{"xoxa-", "xoxb-", "xoxp-", "xoxo-", "xoxs-", "xoxr-"},
+			MaxLength: 255,
+		},
+		{
+			Name:      "jwt",
+			Pattern:   regexp.MustCompile(`\beyJ[0-9A-Za-z_-]{8,}\.eyJ[0-9A-Za-z_-]{8,}\.[0-9A-Za-z_-]*`),
+			Keywords:  []string{"eyj"},
+			MaxLength: 8192,
+		},
+		{
+			Name:      "private-key",
+			Pattern:   regexp.MustCompile(pemKeyBegin),
+			Keywords:  []string{"private key"},
+			MaxLength: 192,
+			Body:      regexp.MustCompile(pemKeyLine),
+			Close:     regexp.MustCompile(pemKeyEnd),
+		},
+		{
+			Name: "password",
+			// Placeholders never match: the value excludes '<' and '>'
+			Pattern:    regexp.MustCompile(`(?i)\b(?:password|passwd|pwd)\s{0,8}[=:]\s{0,8}["']?([^\s"'<>,;&]{8,256})`),
+			Keywords:   []string{"password", "passwd", "pwd"},
+			MaxLength:  290,
+			MinEntropy: 3.0,
+		},
+	}
+}
+
+// RulesByName selects rules by name, in the order given. It fails on
+// unknown names so typos do not silently disable detection.
+func RulesByName(rules []Rule, names []string) ([]Rule, error) {
+	byName := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		byName[rule.Name] = rule
+	}
+
+	selected := make([]Rule, 0, len(names))
+	for _, name := range names {
+		rule, ok := byName[name]
+		if !ok {
+			known := make([]string, 0, len(rules))
+			for _, r := range rules {
+				known = append(known, r.Name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown credential rule %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+// Detection reports one credential redacted by a CredentialDetector. It
+// never carries the credential itself.
+type Detection struct {
+	Rule   string // Name of the matching rule
+	Length int    // Length of the redacted text in bytes
+}
+
+// DetectionError is returned by a fail-fast CredentialDetector.
+type DetectionError struct {
+	Rule string
+}
+
+func (e *DetectionError) Error() string {
+	return fmt.Sprintf("credential detected in output (%s)", e.Rule)
+}
+
+// DetectorOption configures a CredentialDetector.
+type DetectorOption func(*CredentialDetector)
+
+// WithRules replaces the default rules.
+func WithRules(rules ...Rule) DetectorOption {
+	return func(d *CredentialDetector) {
+		d.rules = append([]Rule(nil), rules...)
+	}
+}
+
+// WithDetectionHandler calls fn for every redacted credential. fn must be
+// safe for concurrent use and must not write to the scrubbed streams.
+func WithDetectionHandler(fn func(Detection)) DetectorOption {
+	return func(d *CredentialDetector) {
+		d.onDetect = fn
+	}
+}
+
+// WithFailFast makes the detector reject chunks containing a credential
+// with a *DetectionError instead of redacting them.
+func WithFailFast() DetectorOption {
+	return func(d *CredentialDetector) {
+		d.failFast = true
+	}
+}
+
+// CredentialDetector is a SecretProvider that recognizes common credential
+// shapes (cloud keys, API tokens, JWTs, private keys) in output, whether or
+// not the vault knows about them. Matches are replaced with a placeholder
+// naming the rule, e.g. "<redacted:github-token>".
+//
+// Combine it with the vault's provider using Chain so known secrets keep
+// their DisplayIDs:
+//
+//	detector := streamscrub.NewCredentialDetector()
+//	scrubber := streamscrub.New(out, streamscrub.WithSecretProvider(
+//	    streamscrub.Chain(vlt.SecretProvider(), detector)))
+//
+// Patterns are heuristics: they reduce leaks of unknown credentials but
+// cannot catch every format.
+type CredentialDetector struct {
+	rules    []Rule
+	onDetect func(Detection)
+	failFast bool
+	maxLen   int
+
+	detected atomic.Int64
+}
+
+// NewCredentialDetector creates a detector using DefaultRules unless
+// WithRules is given.
+func NewCredentialDetector(opts ...DetectorOption) *CredentialDetector {
+	d := &CredentialDetector{rules: DefaultRules()}
+	for _, opt := range opts {
+		opt(d)
+	}
+	for i, rule := range d.rules {
+		keywords := make([]string, len(rule.Keywords))
+		for j, kw := range rule.Keywords {
+			keywords[j] = strings.ToLower(kw)
+		}
+		d.rules[i].Keywords = keywords
+		if rule.MaxLength > d.maxLen {
+			d.maxLen = rule.MaxLength
+		}
+	}
+	return d
+}
+
+// Detected returns how many credentials have been redacted so far.
+func (d *CredentialDetector) Detected() int {
+	return int(d.detected.Load())
+}
+
+// HandleChunk implements SecretProvider. A match reaching the end of chunk
+// is left in place because more output may extend it; the scrubber keeps
+// it in the carry and scans it again with the next write or on Flush.
+func (d *CredentialDetector) HandleChunk(chunk []byte) ([]byte, error) {
+	return d.scan(chunk, false)
+}
+
+// HandleFinalChunk implements FinalChunkHandler.
+func (d *CredentialDetector) HandleFinalChunk(chunk []byte) ([]byte, error) {
+	return d.scan(chunk, true)
+}
+
+// MaxSecretLength implements SecretProvider. It is one more than the
+// longest rule so a match ending at a chunk boundary fits in the carry.
+func (d *CredentialDetector) MaxSecretLength() int {
+	if d.maxLen == 0 {
+		return 0
+	}
+	return d.maxLen + 1
+}
+
+func (d *CredentialDetector) scan(chunk []byte, final bool) ([]byte, error) {
+	var lower []byte
+	result := chunk
+	for _, rule := range d.rules {
+		if len(rule.Keywords) > 0 {
+			if lower == nil {
+				lower = bytes.ToLower(result)
+			}
+			if !containsAny(lower, rule.Keywords) {
+				continue
+			}
+		}
+
+		var err error
+		var changed bool
+		result, changed, err = d.apply(rule, result, final)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			lower = nil
+		}
+	}
+	return result, nil
+}
+
+// apply redacts every match of rule in chunk.
+func (d *CredentialDetector) apply(rule Rule, chunk []byte, final bool) ([]byte, bool, error) {
+	if rule.Close != nil {
+		return d.applyBlock(rule, chunk, final)
+	}
+
+	matches := rule.Pattern.FindAllSubmatchIndex(chunk, -1)
+	if len(matches) == 0 {
+		return chunk, false, nil
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if !final && m[1] == len(chunk) && m[1]-m[0] < rule.MaxLength {
+			continue // may continue in the next write
+		}
+		secret := chunk[start:end]
+		if rule.MinEntropy > 0 && entropy(secret) < rule.MinEntropy {
+			continue
+		}
+		if bytes.HasPrefix(secret, []byte("sigil:")) {
+			continue // already a vault placeholder
+		}
+
+		var err error
+		out, err = d.redact(rule, append(out, chunk[last:start]...), len(secret))
+		if err != nil {
+			return nil, false, err
+		}
+		last = end
+	}
+
+	if out == nil {
+		return chunk, false, nil
+	}
+	return append(out, chunk[last:]...), true, nil
+}
+
+// applyBlock redacts every block of a block rule in chunk, from its opening
+// marker to its closing line or the first line that cannot belong to it.
+func (d *CredentialDetector) applyBlock(rule Rule, chunk []byte, final bool) ([]byte, bool, error) {
+	var out []byte
+	last := 0
+	for {
+		loc := rule.Pattern.FindIndex(chunk[last:])
+		if loc == nil {
+			break
+		}
+		start, marker := last+loc[0], last+loc[1]
+		end, cut, open := blockEnd(rule, chunk, marker, final)
+
+		var err error
+		out, err = d.redact(rule, append(out, chunk[last:start]...), end-start)
+		if err != nil {
+			return nil, false, err
+		}
+		if open {
+			// Keep the marker and the cut line last so the scrubber carries
+			// them into the next write, which redacts the rest of the block
+			out = append(out, chunk[start:marker]...)
+			if cut > marker {
+				out = append(out, '\n')
+			}
+			out = append(out, chunk[cut:]...)
+			last = len(chunk)
+			break
+		}
+		last = end
+	}
+
+	if out == nil {
+		return chunk, false, nil
+	}
+	return append(out, chunk[last:]...), true, nil
+}
+
+// blockEnd returns where the block whose opening marker ends at marker
+// ends in chunk. When the write cut the block, open is true and cut is the
+// start of the unfinished line.
+func blockEnd(rule Rule, chunk []byte, marker int, final bool) (end, cut int, open bool) {
+	end = marker
+	for pos := marker; ; {
+		nl := bytes.IndexByte(chunk[pos:], '\n')
+		if nl < 0 {
+			rest := chunk[pos:]
+			if !final && len(rest) < rule.MaxLength {
+				return len(chunk), pos, true
+			}
+			if loc := rule.Close.FindIndex(rest); loc != nil {
+				return pos + loc[1], 0, false
+			}
+			if rule.Body.Match(rest) {
+				return len(chunk), 0, false
+			}
+			return end, 0, false
+		}
+
+		line := bytes.TrimSuffix(chunk[pos:pos+nl], []byte("\r"))
+		if loc := rule.Close.FindIndex(line); loc != nil {
+			return pos + loc[1], 0, false
+		}
+		if !rule.Body.Match(line) {
+			return end, 0, false
+		}
+		end = pos + len(line)
+		pos += nl + 1
+	}
+}
+
+// redact appends rule's placeholder to out and records the detection. A
+// block continued from the previous write already ends out with the
+// placeholder and is neither repeated nor counted again.
+func (d *CredentialDetector) redact(rule Rule, out []byte, length int) ([]byte, error) {
+	placeholder := "<redacted:" + rule.Name + ">"
+	if rule.Close != nil && bytes.HasSuffix(out, []byte(placeholder)) {
+		return out, nil
+	}
+	if d.failFast {
+		return nil, &DetectionError{Rule: rule.Name}
+	}
+
+	d.detected.Add(1)
+	if d.onDetect != nil {
+		d.onDetect(Detection{Rule: rule.Name, Length: length})
+	}
+	return append(out, placeholder...), nil
+}
+
+func containsAny(haystack []byte, needles []string) bool {
+	for _, needle := range needles {
+		if bytes.Contains(haystack, []byte(needle)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of b in bits per byte.
+func entropy(b []byte) float64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	n := float64(len(b))
+	var h float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -1,9 +1,6 @@
 package streamscrub
 
-import (
-	"bytes"
-	"sort"
-)
+import "sync"
 
 // SecretProvider processes chunks to handle secrets.
 //
@@ -32,14 +29,10 @@ import (
 //
 // # Performance Considerations
 //
-// For optimal performance with Aho-Corasick or similar algorithms:
-//   - Provider can build automaton from all known secrets
-//   - HandleChunk() runs automaton on chunk (O(n) scan)
-//   - Handles longest-match for overlapping secrets internally
-//
-// Current implementations use simple linear search, which is sufficient
-// for typical use cases (10-100 secrets). Future optimization can use
-// Aho-Corasick without changing this interface.
+// NewPatternProvider matches every pattern in one pass with an Aho-Corasick
+// automaton, so cost per chunk depends on the chunk size, not the number of
+// secrets. With WithPatternVersion the automaton is rebuilt only when the
+// pattern set changes.
 //
 // # Example Implementations
 //
@@ -123,6 +116,71 @@ type SecretProvider interface {
 	MaxSecretLength() int
 }
 
+// FinalChunkHandler is implemented by providers whose matches can keep
+// growing as more output arrives, such as CredentialDetector. HandleChunk
+// may leave a match that reaches the end of the chunk for the next call;
+// the scrubber calls HandleFinalChunk instead when no more output follows
+// (Flush, EndFrame), so every match must be handled.
+type FinalChunkHandler interface {
+	HandleFinalChunk(chunk []byte) (processed []byte, err error)
+}
+
+// Wiper is implemented by providers that cache copies of their secrets,
+// such as the matcher built by NewPatternProvider. Wipe zeroes and drops
+// those copies; the provider rebuilds them from its source if used again.
+// It must not be called while a chunk is being handled.
+type Wiper interface {
+	Wipe()
+}
+
+// handleFinalChunk processes the last chunk of a stream with p.
+func handleFinalChunk(p SecretProvider, chunk []byte) ([]byte, error) {
+	if final, ok := p.(FinalChunkHandler); ok {
+		return final.HandleFinalChunk(chunk)
+	}
+	return p.HandleChunk(chunk)
+}
+
+// Chain combines providers into one. Each chunk passes through the
+// providers in order, so earlier providers' placeholders are what later
+// ones see; put the vault's provider first.
+func Chain(providers ...SecretProvider) SecretProvider {
+	return chain(append([]SecretProvider(nil), providers...))
+}
+
+type chain []SecretProvider
+
+// HandleChunk implements SecretProvider.
+func (c chain) HandleChunk(chunk []byte) ([]byte, error) {
+	var err error
+	for _, p := range c {
+		if chunk, err = p.HandleChunk(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return chunk, nil
+}
+
+// HandleFinalChunk implements FinalChunkHandler.
+func (c chain) HandleFinalChunk(chunk []byte) ([]byte, error) {
+	var err error
+	for _, p := range c {
+		if chunk, err = handleFinalChunk(p, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return chunk, nil
+}
+
+// MaxSecretLength implements SecretProvider.
+func (c chain) MaxSecretLength() int {
+	maxLen := 0
+	for _, p := range c {
+		maxLen = max(maxLen, p.MaxSecretLength())
+	}
+	return maxLen
+}
+
 // Pattern represents a secret to find and replace.
 type Pattern struct {
 	Value       []byte // Secret bytes to find
@@ -130,20 +188,33 @@ type Pattern struct {
 }
 
 // PatternSource provides patterns dynamically.
-// This function is called each time HandleChunk is invoked,
+// Without WithPatternVersion it is called each time HandleChunk is invoked,
 // allowing the pattern list to change over time.
 type PatternSource func() []Pattern
 
+// PatternOption configures a pattern provider.
+type PatternOption func(*patternProvider)
+
+// WithPatternVersion caches the matcher built from the pattern source until
+// version returns a different value. version must change whenever the
+// source's patterns do, and must be safe for concurrent calls.
+func WithPatternVersion(version func() uint64) PatternOption {
+	return func(p *patternProvider) {
+		p.version = version
+	}
+}
+
 // NewPatternProvider creates a SecretProvider from a pattern source.
 //
 // This is a helper for the common case where you have a list of
 // patterns (secrets) to find and replace. The provider handles:
-//   - Longest-first matching (prevents partial leakage)
-//   - Efficient replacement (optimized internally)
+//   - Leftmost-longest matching (prevents partial leakage)
+//   - Single-pass replacement with an Aho-Corasick automaton
 //   - Thread-safety (if your source function is thread-safe)
 //
 // The source function is called on each HandleChunk invocation,
-// so patterns can change dynamically.
+// so patterns can change dynamically. Sources with many patterns should
+// pass WithPatternVersion so the automaton is not rebuilt for every chunk.
 //
 // Example:
 //
@@ -158,58 +229,80 @@ type PatternSource func() []Pattern
 //	// Create provider using helper
 //	provider := streamscrub.NewPatternProvider(getSecrets)
 //	scrubber := streamscrub.New(output, streamscrub.WithSecretProvider(provider))
-//
-// Performance: Currently uses simple linear replacement. Future versions
-// may use Aho-Corasick algorithm for better performance with many patterns.
-func NewPatternProvider(source PatternSource) SecretProvider {
-	return &patternProvider{
+func NewPatternProvider(source PatternSource, opts ...PatternOption) SecretProvider {
+	p := &patternProvider{
 		getPatterns: source,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // patternProvider implements SecretProvider using a pattern source.
 type patternProvider struct {
 	getPatterns PatternSource
+	version     func() uint64 // nil = rebuild on every call
+
+	mu       sync.Mutex
+	matcher  *automaton
+	built    uint64 // version the matcher was built at
+	hasBuilt bool
 }
 
-// HandleChunk implements SecretProvider interface.
-func (p *patternProvider) HandleChunk(chunk []byte) ([]byte, error) {
-	// Get current patterns from source
-	patterns := p.getPatterns()
+// automaton returns the matcher for the current patterns, reusing the
+// cached one while the source version is unchanged.
+func (p *patternProvider) automaton() *automaton {
+	if p.version == nil {
+		return newAutomaton(p.getPatterns())
+	}
 
-	if len(patterns) == 0 {
-		return chunk, nil
+	// Read the version before the patterns: if they change in between, the
+	// cache is stamped with the older version and rebuilt on the next call.
+	version := p.version()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.hasBuilt || p.built != version {
+		p.matcher = newAutomaton(p.getPatterns())
+		p.built = version
+		p.hasBuilt = true
 	}
+	return p.matcher
+}
 
-	// Sort by descending length (longest first)
-	// This ensures overlapping secrets use longest match
-	sort.Slice(patterns, func(i, j int) bool {
-		return len(patterns[i].Value) > len(patterns[j].Value)
-	})
-
-	// Replace all patterns (longest first)
-	result := chunk
-	for _, pattern := range patterns {
-		if len(pattern.Value) > 0 {
-			result = bytes.ReplaceAll(result, pattern.Value, pattern.Placeholder)
-		}
+// Wipe implements Wiper by zeroing the cached matcher's pattern bytes,
+// including the values the source returned, so sources must hand out copies.
+func (p *patternProvider) Wipe() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.matcher != nil {
+		p.matcher.wipe()
 	}
+	p.matcher, p.hasBuilt = nil, false
+}
 
-	return result, nil
+// HandleChunk implements SecretProvider interface.
+func (p *patternProvider) HandleChunk(chunk []byte) ([]byte, error) {
+	matcher := p.automaton()
+	if matcher.maxLen == 0 {
+		return chunk, nil
+	}
+	return matcher.replace(chunk), nil
 }
 
 // MaxSecretLength implements SecretProvider interface.
 func (p *patternProvider) MaxSecretLength() int {
-	patterns := p.getPatterns()
-
-	maxLen := 0
-	for _, pattern := range patterns {
-		if len(pattern.Value) > maxLen {
-			maxLen = len(pattern.Value)
+	if p.version == nil {
+		maxLen := 0
+		for _, pattern := range p.getPatterns() {
+			if len(pattern.Value) > maxLen {
+				maxLen = len(pattern.Value)
+			}
 		}
+		return maxLen
 	}
-
-	return maxLen
+	return p.automaton().maxLen
 }
 
 // NewPatternProviderWithVariants creates a SecretProvider that automatically
@@ -233,7 +326,7 @@ func (p *patternProvider) MaxSecretLength() int {
 //	}
 //	provider := streamscrub.NewPatternProviderWithVariants(getSecrets)
 //	// Will also match: "736563726574" (hex), "c2VjcmV0" (base64), etc.
-func NewPatternProviderWithVariants(source PatternSource) SecretProvider {
+func NewPatternProviderWithVariants(source PatternSource, opts ...PatternOption) SecretProvider {
 	expandedSource := func() []Pattern {
 		base := source()
 		var expanded []Pattern
@@ -249,9 +342,7 @@ func NewPatternProviderWithVariants(source PatternSource) SecretProvider {
 		return expanded
 	}
 
-	return &patternProvider{
-		getPatterns: expandedSource,
-	}
+	return NewPatternProvider(expandedSource, opts...)
 }
 
 // generateVariants creates encoding variants of a pattern for defense-in-depth.
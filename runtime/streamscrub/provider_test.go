@@ -565,3 +565,78 @@ func TestNewPatternProviderWithVariants(t *testing.T) {
 		})
 	}
 }
+
+// TestNewPatternProvider_PatternVersion tests that a versioned provider
+// reads its source only when the version changes
+func TestNewPatternProvider_PatternVersion(t *testing.T) {
+	var version uint64 = 1
+	calls := 0
+	patterns := []Pattern{{Value: []byte("first"), Placeholder: []byte("<1>")}}
+	source := func() []Pattern {
+		calls++
+		return patterns
+	}
+
+	provider := NewPatternProviderWithVariants(source, WithPatternVersion(func() uint64 { return version }))
+
+	for i := 0; i < 3; i++ {
+		got, err := provider.HandleChunk([]byte("first second"))
+		if err != nil {
+			t.Fatalf("HandleChunk failed: %v", err)
+		}
+		if string(got) != "<1> second" {
+			t.Errorf("got %q, want %q", got, "<1> second")
+		}
+	}
+	// Longest variant is the percent encoding "%66%69%72%73%74"
+	if got := provider.MaxSecretLength(); got != 15 {
+		t.Errorf("MaxSecretLength = %d, want 15", got)
+	}
+	if calls != 1 {
+		t.Errorf("source called %d times for one version, want 1", calls)
+	}
+
+	patterns = append(patterns, Pattern{Value: []byte("second"), Placeholder: []byte("<2>")})
+	version++
+	got, err := provider.HandleChunk([]byte("first second"))
+	if err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+	if string(got) != "<1> <2>" {
+		t.Errorf("after version change got %q, want %q", got, "<1> <2>")
+	}
+	if calls != 2 {
+		t.Errorf("source called %d times for two versions, want 2", calls)
+	}
+}
+
+// TestNewPatternProvider_Wipe verifies that Wipe zeroes the cached matcher's
+// copies of the secrets and that the provider rebuilds it on next use
+func TestNewPatternProvider_Wipe(t *testing.T) {
+	source := func() []Pattern {
+		return []Pattern{{Value: []byte("hunter22"), Placeholder: []byte("<1>")}}
+	}
+	provider := NewPatternProvider(source, WithPatternVersion(func() uint64 { return 1 }))
+	if _, err := provider.HandleChunk([]byte("hunter22")); err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+
+	matcher := provider.(*patternProvider).matcher
+	value, labels := matcher.patterns[0].Value, matcher.edgeBytes
+	provider.(Wiper).Wipe()
+
+	if !bytes.Equal(value, make([]byte, len(value))) {
+		t.Errorf("pattern value not zeroed: %q", value)
+	}
+	if !bytes.Equal(labels, make([]byte, len(labels))) {
+		t.Errorf("trie labels not zeroed: %q", labels)
+	}
+
+	got, err := provider.HandleChunk([]byte("pw hunter22"))
+	if err != nil {
+		t.Fatalf("HandleChunk after Wipe failed: %v", err)
+	}
+	if string(got) != "pw <1>" {
+		t.Errorf("after Wipe got %q, want %q", got, "pw <1>")
+	}
+}
@@ -141,8 +141,8 @@ func (s *Scrubber) EndFrame() error {
 	// Get frame buffer bytes
 	frameBuf := currentFrame.buf.Bytes()
 
-	// Scrub frame buffer with provider (if available)
-	scrubbed, err := s.scrubAll(frameBuf)
+	// Scrub frame buffer with provider (if available); the frame is complete
+	scrubbed, err := s.scrubFinal(frameBuf)
 	if err != nil {
 		// Provider rejected chunk - do not write unsanitized data
 		// Zeroize buffer and return error
@@ -181,6 +181,16 @@ func (s *Scrubber) scrubAll(buf []byte) ([]byte, error) {
 	return buf, nil
 }
 
+// scrubFinal is scrubAll for the end of the output: providers implementing
+// FinalChunkHandler must handle matches they would otherwise hold back.
+// Assumes mu is held.
+func (s *Scrubber) scrubFinal(buf []byte) ([]byte, error) {
+	if s.provider == nil {
+		return buf, nil
+	}
+	return handleFinalChunk(s.provider, buf)
+}
+
 // scrubAllProvider uses SecretProvider to process chunk.
 // Provider handles all secret detection and replacement internally.
 // Returns an error if the provider rejects the chunk.
@@ -421,7 +431,7 @@ func (s *Scrubber) Flush() error {
 	}
 
 	// Scrub carry one final time (longest-first)
-	result, err := s.scrubAll(s.carry)
+	result, err := s.scrubFinal(s.carry)
 	if err != nil {
 		// Provider rejected chunk - zeroize carry and return error
 		for i := range s.carry {
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command runs a helper such as `pass show` or `op read` and returns its
+// stdout with one trailing newline removed.
+//
+// Params:
+//   - argv (required): program and arguments; no shell is involved
+//   - timeout (default DefaultTimeout): how long the helper may run
+//
+// Each distinct argv runs once per batch. The helper inherits sigil's
+// environment and has no stdin. On failure only the first line of stderr is
+// reported, since helpers may echo more than an error.
+type Command struct{}
+
+// Fetch implements Provider.
+func (Command) Fetch(ctx context.Context, reqs []Request) []Result {
+	results := make([]Result, len(reqs))
+	outputs := make(map[string]Result)
+
+	for i, req := range reqs {
+		argv, err := req.stringListParam("argv")
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		timeout, err := req.durationParam("timeout", DefaultTimeout)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+
+		key := strings.Join(argv, "\x00")
+		output, seen := outputs[key]
+		if !seen {
+			output = runHelper(ctx, argv, timeout)
+			outputs[key] = output
+		}
+		results[i] = output
+	}
+
+	return results
+}
+
+func runHelper(ctx context.Context, argv []string, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return Result{Error: fmt.Errorf("secret helper %s timed out after %s", argv[0], timeout)}
+	}
+	if err != nil {
+		detail, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if detail != "" {
+			return Result{Error: fmt.Errorf("secret helper %s failed: %w: %s", argv[0], err, detail)}
+		}
+		return Result{Error: fmt.Errorf("secret helper %s failed: %w", argv[0], err)}
+	}
+
+	return Result{Value: trimNewline(stdout.String())}
+}
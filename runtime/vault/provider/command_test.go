@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommandFetch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	// Each run appends to the counter file, so batching is observable
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := `echo run >> "$1"; printf 'value-%s\n' "$2"`
+	argv := func(name string) []any { return []any{"sh", "-c", script, "sh", counter, name} }
+
+	results := Command{}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"argv": argv("a")}},
+		{Params: map[string]any{"argv": argv("b")}},
+		{Params: map[string]any{"argv": argv("a")}},
+	})
+
+	var got []string
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		got = append(got, result.Value)
+	}
+	if diff := cmp.Diff([]string{"value-a", "value-b", "value-a"}, got); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(2, strings.Count(string(runs), "run")); diff != "" {
+		t.Errorf("identical argv should run once (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommandFetchErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	results := Command{}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"argv": []any{"sh", "-c", "echo 'item not found' >&2; echo more >&2; exit 3"}}},
+		{Params: map[string]any{"argv": []any{"sleep", "5"}, "timeout": 50 * time.Millisecond}},
+		{Params: map[string]any{"argv": []any{}}},
+	})
+
+	if err := results[0].Error; err == nil || !strings.HasSuffix(err.Error(), ": item not found") {
+		t.Errorf("expected first stderr line in error, got %v", err)
+	}
+	if err := results[1].Error; err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if results[2].Error == nil {
+		t.Error("expected error for empty argv")
+	}
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Dotenv reads one key from a dotenv file.
+//
+// Params:
+//   - file (required): dotenv file to read
+//   - key (required): variable to return
+//
+// Each distinct file is parsed once per batch. The supported syntax is the
+// common subset of dotenv dialects:
+//
+//	# comment
+//	export NAME=value        # trailing comment
+//	NAME='literal $value'
+//	NAME="escapes \n \" \\ are decoded"
+//
+// Variables are not expanded.
+type Dotenv struct{}
+
+// Fetch implements Provider.
+func (Dotenv) Fetch(_ context.Context, reqs []Request) []Result {
+	results := make([]Result, len(reqs))
+
+	type parsed struct {
+		vars map[string]string
+		err  error
+	}
+	files := make(map[string]parsed)
+
+	for i, req := range reqs {
+		file, err := req.stringParam("file", true)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		key, err := req.stringParam("key", true)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+
+		entry, seen := files[file]
+		if !seen {
+			entry.vars, entry.err = parseDotenvFile(file)
+			files[file] = entry
+		}
+		if entry.err != nil {
+			results[i].Error = entry.err
+			continue
+		}
+
+		value, ok := entry.vars[key]
+		if !ok {
+			results[i].Error = fmt.Errorf("key %q not found in %s", key, file)
+			continue
+		}
+		results[i].Value = value
+	}
+
+	return results
+}
+
+func parseDotenvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dotenv file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		name, value, ok, err := parseDotenvLine(scanner.Text())
+		if err != nil {
+			// Never echo the line: it may hold a secret
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if ok {
+			vars[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read dotenv file: %w", err)
+	}
+	return vars, nil
+}
+
+// parseDotenvLine parses one line. ok is false for blank and comment lines.
+func parseDotenvLine(line string) (name, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	name, raw, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false, fmt.Errorf("expected NAME=value")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", false, fmt.Errorf("invalid variable name")
+	}
+
+	raw = strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", "", false, fmt.Errorf("unterminated single quote")
+		}
+		return name, raw[1 : end+1], true, nil
+	case strings.HasPrefix(raw, `"`):
+		value, err := unquoteDouble(raw[1:])
+		if err != nil {
+			return "", "", false, err
+		}
+		return name, value, true, nil
+	default:
+		// Unquoted: a '#' preceded by whitespace starts a comment
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		if idx := strings.Index(raw, "\t#"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		return name, strings.TrimSpace(raw), true, nil
+	}
+}
+
+// unquoteDouble decodes a double-quoted value up to its closing quote.
+func unquoteDouble(s string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return sb.String(), nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated double quote")
+}
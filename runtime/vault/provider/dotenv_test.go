@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDotenvFetch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	content := `# database
+DB_USER=app
+export DB_PASSWORD=hunter2 # rotated monthly
+SINGLE='literal $HOME # kept'
+DOUBLE="line1\nline2 \"quoted\""
+EMPTY=
+HASH=abc#def
+`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"DB_USER", "DB_PASSWORD", "SINGLE", "DOUBLE", "EMPTY", "HASH"}
+	reqs := make([]Request, len(keys))
+	for i, key := range keys {
+		reqs[i] = Request{Params: map[string]any{"file": file, "key": key}}
+	}
+
+	var got []string
+	for i, result := range (Dotenv{}).Fetch(context.Background(), reqs) {
+		if result.Error != nil {
+			t.Fatalf("%s: %v", keys[i], result.Error)
+		}
+		got = append(got, result.Value)
+	}
+	want := []string{"app", "hunter2", "literal $HOME # kept", "line1\nline2 \"quoted\"", "", "abc#def"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDotenvFetchErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".env")
+	if err := os.WriteFile(file, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.env")
+	if err := os.WriteFile(broken, []byte("TOKEN=\"s3cret-unterminated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	results := Dotenv{}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"file": file, "key": "B"}},
+		{Params: map[string]any{"file": broken, "key": "TOKEN"}},
+		{Params: map[string]any{"file": file}},
+	})
+
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), `key "B" not found`) {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+	if err := results[1].Error; err == nil || !strings.Contains(err.Error(), "broken.env:1") {
+		t.Errorf("expected parse error with location, got %v", err)
+	} else if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("parse error leaked the value: %v", err)
+	}
+	if results[2].Error == nil {
+		t.Error("expected error for missing key parameter")
+	}
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// File reads a secret from a local file, one file per request.
+//
+// Params:
+//   - path (required): file to read
+//   - trim (default true): drop one trailing newline
+//
+// Each distinct path is read once per batch.
+type File struct{}
+
+// Fetch implements Provider.
+func (File) Fetch(_ context.Context, reqs []Request) []Result {
+	results := make([]Result, len(reqs))
+	contents := make(map[string]Result)
+
+	for i, req := range reqs {
+		path, err := req.stringParam("path", true)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		trim, err := req.boolParam("trim", true)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+
+		content, seen := contents[path]
+		if !seen {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				content.Error = fmt.Errorf("read secret file: %w", err)
+			} else {
+				content.Value = string(data)
+			}
+			contents[path] = content
+		}
+
+		results[i] = content
+		if trim && content.Error == nil {
+			results[i].Value = trimNewline(content.Value)
+		}
+	}
+
+	return results
+}
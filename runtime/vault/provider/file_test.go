@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileFetch(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	if err := os.WriteFile(token, []byte("s3cr3t-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	results := File{}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"path": token}},
+		{Params: map[string]any{"path": token, "trim": false}},
+		{Params: map[string]any{"path": filepath.Join(dir, "missing")}},
+		{Params: map[string]any{}},
+	})
+
+	if diff := cmp.Diff("s3cr3t-token", results[0].Value); diff != "" || results[0].Error != nil {
+		t.Errorf("trimmed value mismatch (-want +got):\n%s (err %v)", diff, results[0].Error)
+	}
+	if diff := cmp.Diff("s3cr3t-token\n", results[1].Value); diff != "" {
+		t.Errorf("untrimmed value mismatch (-want +got):\n%s", diff)
+	}
+	if results[2].Error == nil {
+		t.Error("expected error for missing file")
+	}
+	if results[3].Error == nil {
+		t.Error("expected error for missing path")
+	}
+}
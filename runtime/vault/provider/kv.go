@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+)
+
+const (
+	// EnvKVAddr is the default server address, as used by the Vault CLI.
+	EnvKVAddr = "VAULT_ADDR"
+
+	// EnvKVToken is the token sent as X-Vault-Token. Tokens are never
+	// accepted as parameters so they cannot end up in plans.
+	EnvKVToken = "VAULT_TOKEN"
+
+	// EnvKVNamespace is the optional namespace sent as X-Vault-Namespace.
+	EnvKVNamespace = "VAULT_NAMESPACE"
+
+	// maxKVResponse bounds how much of a response is read.
+	maxKVResponse = 4 << 20
+)
+
+// KV reads fields from a HashiCorp Vault compatible KV version 2 engine
+// (Vault, OpenBao) over HTTP.
+//
+// Params:
+//   - path (required): secret path within the mount, e.g. "myapp/db"
+//   - key (required): field of the secret to return
+//   - mount (default "secret"): KV engine mount point
+//   - version (default latest): secret version to read
+//   - addr (default $VAULT_ADDR): server address; must equal $VAULT_ADDR
+//   - insecure (default false): allow a plain http server address
+//
+// The token comes from $VAULT_TOKEN and the namespace from $VAULT_NAMESPACE.
+// The token is only ever sent to $VAULT_ADDR, and only over https unless the
+// call opts in with insecure=true, so a script cannot redirect it elsewhere.
+// Each distinct secret (address, mount, path, version) is fetched once per
+// batch, however many of its fields are requested.
+//
+// Results carry the version's number and created_time, and its
+// deletion_time as the expiry when the mount or secret sets
+// delete_version_after.
+type KV struct {
+	// Client sends the requests; nil uses a client with DefaultTimeout.
+	Client *http.Client
+}
+
+type kvSecret struct {
+	fields   map[string]any
+	metadata decorator.SecretMetadata
+	err      error
+}
+
+// Fetch implements Provider.
+func (p KV) Fetch(ctx context.Context, reqs []Request) []Result {
+	token := os.Getenv(EnvKVToken)
+	if token == "" {
+		return fail(len(reqs), fmt.Errorf("%s is not set", EnvKVToken))
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	results := make([]Result, len(reqs))
+	secrets := make(map[string]kvSecret)
+
+	for i, req := range reqs {
+		secretURL, err := kvURL(req)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		key, err := req.stringParam("key", true)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+
+		secret, seen := secrets[secretURL]
+		if !seen {
+			secret.fields, secret.metadata, secret.err = fetchKV(ctx, client, secretURL, token)
+			secrets[secretURL] = secret
+		}
+		if secret.err != nil {
+			results[i].Error = secret.err
+			continue
+		}
+
+		results[i].Value, results[i].Error = kvField(secret.fields, key)
+		results[i].Metadata = secret.metadata
+	}
+
+	return results
+}
+
+// kvURL builds the KV v2 read URL for req.
+func kvURL(req Request) (string, error) {
+	addr := os.Getenv(EnvKVAddr)
+	if addr == "" {
+		return "", fmt.Errorf("no server address: set %s", EnvKVAddr)
+	}
+	pinned, err := req.stringParam("addr", false)
+	if err != nil {
+		return "", err
+	}
+	if pinned != "" && strings.TrimRight(pinned, "/") != strings.TrimRight(addr, "/") {
+		return "", fmt.Errorf("addr %q does not match %s; the token is only sent to %s", pinned, EnvKVAddr, EnvKVAddr)
+	}
+	insecure, err := req.boolParam("insecure", false)
+	if err != nil {
+		return "", err
+	}
+
+	mount, err := req.stringParam("mount", false)
+	if err != nil {
+		return "", err
+	}
+	if mount == "" {
+		mount = "secret"
+	}
+	path, err := req.stringParam("path", true)
+	if err != nil {
+		return "", err
+	}
+	version, err := req.intParam("version", 0)
+	if err != nil {
+		return "", err
+	}
+
+	base, err := url.Parse(strings.TrimRight(addr, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid server address %q", addr)
+	}
+	if base.Scheme != "https" && !insecure {
+		return "", fmt.Errorf("refusing to send %s to %s over %s: use https or pass insecure=true", EnvKVToken, addr, base.Scheme)
+	}
+	u := base.JoinPath("v1", strings.Trim(mount, "/"), "data", strings.Trim(path, "/"))
+	if version > 0 {
+		u.RawQuery = url.Values{"version": {strconv.FormatInt(version, 10)}}.Encode()
+	}
+	return u.String(), nil
+}
+
+func fetchKV(ctx context.Context, client *http.Client, secretURL, token string) (map[string]any, decorator.SecretMetadata, error) {
+	var none decorator.SecretMetadata
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return nil, none, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("X-Vault-Request", "true")
+	if namespace := os.Getenv(EnvKVNamespace); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, none, fmt.Errorf("fetch secret: %w", err) // url.Error already names the URL
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKVResponse))
+	if err != nil {
+		return nil, none, fmt.Errorf("fetch %s: %w", secretURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, none, fmt.Errorf("fetch %s: %s%s", secretURL, resp.Status, kvErrors(body))
+	}
+
+	var payload struct {
+		Data struct {
+			Data     map[string]any `json:"data"`
+			Metadata kvMetadata     `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, none, fmt.Errorf("fetch %s: decode response: %w", secretURL, err)
+	}
+	if payload.Data.Data == nil {
+		return nil, none, fmt.Errorf("fetch %s: secret has no data (deleted or destroyed version?)", secretURL)
+	}
+	return payload.Data.Data, payload.Data.Metadata.secretMetadata(), nil
+}
+
+// kvMetadata is the version metadata returned with a KV v2 read. Times are
+// RFC 3339; deletion_time is empty unless the version is scheduled for
+// deletion.
+type kvMetadata struct {
+	Version      int64  `json:"version"`
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time"`
+}
+
+// secretMetadata converts m, ignoring times the server did not set or that
+// do not parse.
+func (m kvMetadata) secretMetadata() decorator.SecretMetadata {
+	var metadata decorator.SecretMetadata
+	if m.Version > 0 {
+		metadata.Version = strconv.FormatInt(m.Version, 10)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, m.CreatedTime); err == nil {
+		metadata.CreatedAt = t.UTC()
+	}
+	if t, err := time.Parse(time.RFC3339Nano, m.DeletionTime); err == nil {
+		metadata.ExpiresAt = t.UTC()
+	}
+	return metadata
+}
+
+// kvErrors formats the server's error list, e.g. ": permission denied".
+func kvErrors(body []byte) string {
+	var payload struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(body, &payload) != nil || len(payload.Errors) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(payload.Errors, "; ")
+}
+
+// kvField returns a field as a string; non-string fields are JSON-encoded.
+func kvField(fields map[string]any, key string) (string, error) {
+	raw, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret", key)
+	}
+	if str, ok := raw.(string); ok {
+		return str, nil
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("encode secret field %q: %w", key, err)
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newKVServer is a minimal KV v2 stand-in that counts requests per URL.
+func newKVServer(t *testing.T, hits map[string]int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.String()]++
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if diff := cmp.Diff("team-a", r.Header.Get("X-Vault-Namespace")); diff != "" {
+			t.Errorf("namespace mismatch (-want +got):\n%s", diff)
+		}
+
+		switch r.URL.String() {
+		case "/v1/secret/data/myapp/db":
+			_, _ = w.Write([]byte(`{"data":{"data":{"username":"app","password":"hunter2","port":5432},"metadata":{"version":3,"created_time":"2026-10-01T08:30:00.5Z","deletion_time":"2026-12-30T08:30:00.5Z"}}}`))
+		case "/v1/kv/data/myapp/db?version=1":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"old-hunter"},"metadata":{"version":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKVFetch(t *testing.T) {
+	hits := make(map[string]int)
+	server := newKVServer(t, hits)
+	t.Setenv(EnvKVAddr, server.URL)
+	t.Setenv(EnvKVToken, "test-token")
+	t.Setenv(EnvKVNamespace, "team-a")
+
+	results := KV{Client: server.Client()}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"path": "myapp/db", "key": "username"}},
+		{Params: map[string]any{"path": "myapp/db", "key": "password"}},
+		{Params: map[string]any{"path": "myapp/db", "key": "port"}},
+		{Params: map[string]any{"path": "myapp/db", "key": "password", "mount": "kv", "version": int64(1)}},
+		{Params: map[string]any{"path": "myapp/db", "key": "missing"}},
+		{Params: map[string]any{"path": "other/app", "key": "password"}},
+	})
+
+	var got []string
+	for _, result := range results[:4] {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		got = append(got, result.Value)
+	}
+	if diff := cmp.Diff([]string{"app", "hunter2", "5432", "old-hunter"}, got); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+
+	created := time.Date(2026, 10, 1, 8, 30, 0, 5e8, time.UTC)
+	wantMetadata := []decorator.SecretMetadata{
+		{Version: "3", CreatedAt: created, ExpiresAt: created.Add(90 * 24 * time.Hour)},
+		{Version: "3", CreatedAt: created, ExpiresAt: created.Add(90 * 24 * time.Hour)},
+		{Version: "3", CreatedAt: created, ExpiresAt: created.Add(90 * 24 * time.Hour)},
+		{Version: "1"},
+	}
+	var gotMetadata []decorator.SecretMetadata
+	for _, result := range results[:4] {
+		gotMetadata = append(gotMetadata, result.Metadata)
+	}
+	if diff := cmp.Diff(wantMetadata, gotMetadata); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+	if err := results[4].Error; err == nil || !strings.Contains(err.Error(), `key "missing" not found`) {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+	if err := results[5].Error; err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// Fields of the same secret share one request
+	if diff := cmp.Diff(1, hits["/v1/secret/data/myapp/db"]); diff != "" {
+		t.Errorf("secret fetched more than once (-want +got):\n%s", diff)
+	}
+}
+
+func TestKVFetchErrors(t *testing.T) {
+	hits := make(map[string]int)
+	server := newKVServer(t, hits)
+	t.Setenv(EnvKVAddr, "")
+	t.Setenv(EnvKVNamespace, "team-a")
+
+	t.Setenv(EnvKVToken, "")
+	results := KV{}.Fetch(context.Background(), []Request{{Params: map[string]any{"path": "myapp/db", "key": "password", "addr": server.URL}}})
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "VAULT_TOKEN is not set") {
+		t.Errorf("expected missing token error, got %v", err)
+	}
+
+	t.Setenv(EnvKVToken, "wrong-token")
+	results = KV{}.Fetch(context.Background(), []Request{{Params: map[string]any{"path": "myapp/db", "key": "password"}}})
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "no server address") {
+		t.Errorf("expected missing address error, got %v", err)
+	}
+
+	t.Setenv(EnvKVAddr, server.URL)
+	results = KV{Client: server.Client()}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"path": "myapp/db", "key": "password", "addr": server.URL + "/"}},
+	})
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "403 Forbidden: permission denied") {
+		t.Errorf("expected permission error, got %v", err)
+	}
+}
+
+func TestKVFetchSendsTokenOnlyToVaultAddr(t *testing.T) {
+	vaultHits := make(map[string]int)
+	server := newKVServer(t, vaultHits)
+	var leaked []string
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = append(leaked, r.Header.Get("X-Vault-Token"))
+	}))
+	t.Cleanup(other.Close)
+
+	t.Setenv(EnvKVAddr, server.URL)
+	t.Setenv(EnvKVToken, "test-token")
+	t.Setenv(EnvKVNamespace, "team-a")
+
+	results := KV{Client: other.Client()}.Fetch(context.Background(), []Request{
+		{Params: map[string]any{"path": "myapp/db", "key": "password", "addr": other.URL}},
+	})
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "does not match VAULT_ADDR") {
+		t.Errorf("expected addr mismatch error, got %v", err)
+	}
+	if len(leaked) != 0 {
+		t.Errorf("token sent to a server other than VAULT_ADDR: %q", leaked)
+	}
+}
+
+func TestKVFetchRequiresHTTPS(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"hunter2"}}}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(EnvKVAddr, server.URL)
+	t.Setenv(EnvKVToken, "test-token")
+
+	results := KV{}.Fetch(context.Background(), []Request{{Params: map[string]any{"path": "myapp/db", "key": "password"}}})
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "use https or pass insecure=true") {
+		t.Errorf("expected plain http to be refused, got %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("token sent over plain http: %q", tokens)
+	}
+
+	results = KV{}.Fetch(context.Background(), []Request{{Params: map[string]any{"path": "myapp/db", "key": "password", "insecure": true}}})
+	if results[0].Error != nil {
+		t.Fatalf("insecure=true: unexpected error: %v", results[0].Error)
+	}
+	if diff := cmp.Diff("hunter2", results[0].Value); diff != "" {
+		t.Errorf("value mismatch (-want +got):\n%s", diff)
+	}
+}
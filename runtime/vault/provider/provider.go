@@ -0,0 +1,169 @@
+// Package provider fetches secrets from external sources for the @secret.*
+// value decorators.
+//
+// A Provider resolves a whole batch of lookups at once, so work shared by
+// several calls (reading a file, running a helper, fetching a KV path)
+// happens once per plan. Values are returned as strings; the planner tracks
+// every one as a secret expression in the vault, so plans show DisplayIDs
+// and output is scrubbed.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/builtwithtofu/sigil/core/decorator"
+	"github.com/builtwithtofu/sigil/core/types"
+)
+
+// Provider resolves secret lookups against one external source.
+type Provider interface {
+	// Fetch resolves reqs and returns one Result per request, in order.
+	// Failures are reported per request so one bad lookup does not fail
+	// the others.
+	Fetch(ctx context.Context, reqs []Request) []Result
+}
+
+// Request is one secret lookup: the named arguments of the decorator call.
+type Request struct {
+	Params map[string]any
+}
+
+// Result is the outcome of one Request.
+type Result struct {
+	Value string
+	Error error
+
+	// Metadata is the secret's version and lifetime, for sources that
+	// report them; zero otherwise.
+	Metadata decorator.SecretMetadata
+}
+
+// DefaultTimeout bounds helper commands and HTTP requests that do not set
+// their own timeout.
+const DefaultTimeout = 30 * time.Second
+
+// stringParam returns the string parameter name, or an error if required
+// and missing.
+func (r Request) stringParam(name string, required bool) (string, error) {
+	raw, ok := r.Params[name]
+	if !ok || raw == nil {
+		if required {
+			return "", fmt.Errorf("missing required parameter %q", name)
+		}
+		return "", nil
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter %q must be a string, got %T", name, raw)
+	}
+	if required && value == "" {
+		return "", fmt.Errorf("parameter %q must not be empty", name)
+	}
+	return value, nil
+}
+
+// stringListParam returns a non-empty list of strings.
+func (r Request) stringListParam(name string) ([]string, error) {
+	raw, ok := r.Params[name]
+	if !ok || raw == nil {
+		return nil, fmt.Errorf("missing required parameter %q", name)
+	}
+
+	var items []any
+	switch v := raw.(type) {
+	case []any:
+		items = v
+	case []string:
+		return append([]string(nil), v...), nil
+	default:
+		return nil, fmt.Errorf("parameter %q must be a list of strings, got %T", name, raw)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("parameter %q must not be empty", name)
+	}
+
+	out := make([]string, 0, len(items))
+	for i, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be a string, got %T", name, i, item)
+		}
+		out = append(out, str)
+	}
+	return out, nil
+}
+
+// durationParam returns a duration parameter, or fallback when unset.
+// Durations arrive as time.Duration, types.Duration, or sigil duration
+// strings such as "10s" or "1h30m".
+func (r Request) durationParam(name string, fallback time.Duration) (time.Duration, error) {
+	raw, ok := r.Params[name]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	switch v := raw.(type) {
+	case time.Duration:
+		return v, nil
+	case types.Duration:
+		return time.Duration(v.Nanoseconds()), nil
+	case string:
+		d, err := types.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %q: %w", name, err)
+		}
+		return time.Duration(d.Nanoseconds()), nil
+	default:
+		return 0, fmt.Errorf("parameter %q must be a duration, got %T", name, raw)
+	}
+}
+
+// intParam returns an integer parameter, or fallback when unset.
+func (r Request) intParam(name string, fallback int64) (int64, error) {
+	raw, ok := r.Params[name]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	switch v := raw.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), nil
+		}
+	}
+	return 0, fmt.Errorf("parameter %q must be an integer, got %v", name, raw)
+}
+
+// boolParam returns a boolean parameter, or fallback when unset.
+func (r Request) boolParam(name string, fallback bool) (bool, error) {
+	raw, ok := r.Params[name]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	value, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("parameter %q must be a bool, got %T", name, raw)
+	}
+	return value, nil
+}
+
+// trimNewline removes one trailing line ending, the way shells treat
+// command substitution output and files written with `echo`.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+// fail returns n results that all carry err.
+func fail(n int, err error) []Result {
+	results := make([]Result, n)
+	for i := range results {
+		results[i].Error = err
+	}
+	return results
+}
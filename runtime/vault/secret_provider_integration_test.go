@@ -5,6 +5,7 @@ import (
 	"io"
 	"testing"
 
+	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/runtime/streamscrub"
 )
 
@@ -245,3 +246,101 @@ func TestSecretProvider_LongestFirst(t *testing.T) {
 	t.Logf("Input:  %s", testInput)
 	t.Logf("Output: %s", scrubbed)
 }
+
+// TestSecretProvider_TracksPatternVersion tests that a provider created
+// before resolution picks up values resolved and pruned later.
+func TestSecretProvider_TracksPatternVersion(t *testing.T) {
+	v := NewWithPlanKey(make([]byte, 32))
+	provider := v.SecretProvider()
+
+	scrub := func(input string) string {
+		t.Helper()
+		out, err := provider.HandleChunk([]byte(input))
+		if err != nil {
+			t.Fatalf("HandleChunk failed: %v", err)
+		}
+		return string(out)
+	}
+
+	if got := scrub("key=sk-secret-123"); got != "key=sk-secret-123" {
+		t.Fatalf("nothing resolved yet, got %q", got)
+	}
+
+	before := v.patternVersion.Load()
+	exprID := v.DeclareVariable("API_KEY", "literal:sk-secret-123")
+	v.StoreUnresolvedValue(exprID, "sk-secret-123")
+	v.MarkTouched(exprID)
+	v.ResolveAllTouched()
+	if v.patternVersion.Load() == before {
+		t.Fatal("resolving an expression must bump the pattern version")
+	}
+
+	want := "key=" + v.GetDisplayID(exprID)
+	if got := scrub("key=sk-secret-123"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Resolving again without changes keeps the cached matcher
+	before = v.patternVersion.Load()
+	v.ResolveAllTouched()
+	if v.patternVersion.Load() != before {
+		t.Error("re-resolving resolved expressions must not bump the pattern version")
+	}
+
+	v.pruneUnused()
+	if got := scrub("key=sk-secret-123"); got != "key=sk-secret-123" {
+		t.Errorf("pruned expression still scrubbed: %q", got)
+	}
+}
+
+func TestSecretProvider_HonorsSensitivity(t *testing.T) {
+	v := NewWithPlanKey(make([]byte, 32))
+
+	declare := func(name, value string, level decorator.Sensitivity, classify bool) string {
+		exprID := v.DeclareVariable(name, "literal:"+value)
+		if classify {
+			v.Classify(exprID, level)
+		}
+		v.StoreUnresolvedValue(exprID, value)
+		v.MarkTouched(exprID)
+		return exprID
+	}
+	secretID := declare("TOKEN", "tok-1234", decorator.SensitivitySecret, true)
+	declare("REGION", "us-east-1", decorator.SensitivitySensitive, true)
+	declare("REPLICAS", "3000", decorator.SensitivityPublic, true)
+	declare("PIN", "123", decorator.SensitivitySecret, true)
+	unclassifiedID := declare("LEGACY", "legacy-value", 0, false)
+	v.ResolveAllTouched()
+
+	out, err := v.SecretProvider().HandleChunk([]byte("tok-1234 us-east-1 3000 123 legacy-value"))
+	if err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+
+	// Only secrets are scrubbed; unclassified expressions default to secret,
+	// and secrets shorter than MinSecretPatternLength are left alone
+	want := v.GetDisplayID(secretID) + " us-east-1 3000 123 " + v.GetDisplayID(unclassifiedID)
+	if got := string(out); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestClassify_OnlyTightensAfterFirstCall(t *testing.T) {
+	v := NewWithPlanKey(make([]byte, 32))
+	exprID := v.DeclareVariable("X", "literal:x")
+
+	if got := v.Sensitivity(exprID); got != decorator.SensitivitySecret {
+		t.Errorf("unclassified expression: got %v, want secret", got)
+	}
+
+	v.Classify(exprID, decorator.SensitivityPublic)
+	if got := v.Sensitivity(exprID); got != decorator.SensitivityPublic {
+		t.Errorf("first classification: got %v, want public", got)
+	}
+
+	v.Classify(exprID, decorator.SensitivitySensitive)
+	v.Classify(exprID, decorator.SensitivityPublic)
+	if got := v.Sensitivity(exprID); got != decorator.SensitivitySensitive {
+		t.Errorf("after tightening: got %v, want sensitive", got)
+	}
+}
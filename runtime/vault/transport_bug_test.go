@@ -165,8 +165,8 @@ func TestBug3_MissingMarkResolved_NoAPIToSetTransportAtResolution(t *testing.T)
 	if !v.expressions[exprID].Resolved {
 		t.Error("Expression should be marked as resolved")
 	}
-	if v.expressions[exprID].Value != "/home/local-user" {
-		t.Errorf("Expression value = %q, want %q", v.expressions[exprID].Value, "/home/local-user")
+	if v.expressions[exprID].value() != "/home/local-user" {
+		t.Errorf("Expression value = %q, want %q", v.expressions[exprID].value(), "/home/local-user")
 	}
 	if v.expressions[exprID].DeclaredTransport != "local" {
 		t.Errorf("Expression DeclaredTransport = %q, want %q", v.expressions[exprID].DeclaredTransport, "local")
@@ -15,8 +15,11 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/builtwithtofu/sigil/core/invariant"
+	"github.com/builtwithtofu/sigil/runtime/securemem"
 	"github.com/builtwithtofu/sigil/runtime/streamscrub"
 )
 
@@ -33,10 +36,16 @@ import (
 //
 // # Security Model
 //
-// ALL value decorators produce secrets. Vault enforces:
+// ALL value decorators produce tracked values. Vault enforces:
 //  1. Transport boundaries - secrets cannot cross transport boundaries
 //  2. Site authorization - secrets only accessible at authorized sites (HMAC-based SiteID)
 //
+// Each expression also carries a decorator.Sensitivity. It does not relax
+// the checks above; it only decides how the value is displayed. Secret
+// values are scrubbed from output, sensitive values are masked in plans
+// only, and public values are shown verbatim. Unclassified expressions are
+// secret.
+//
 // # Usage
 //
 //	// Pass 1: Declare variables and track references
@@ -56,6 +65,9 @@ import (
 //	vault.pruneUntouched()
 //	uses := vault.buildSecretUses()
 //
+//	// Pass 5: Wipe secrets once execution is done
+//	vault.Close()
+//
 // # Variable Lookup
 //
 //	// Declare at root
@@ -73,6 +85,8 @@ import (
 //  2. Call MarkTouched for expressions in execution path
 //  3. Call PruneUntouched before BuildSecretUses
 //  4. Do not copy Vault after first use
+//  5. Call Close when done; string and []byte values are held in locked
+//     memory (see securemem) until then
 //
 // See docs/ARCHITECTURE.md for complete architecture.
 type Vault struct {
@@ -102,21 +116,88 @@ type Vault struct {
 	secretStore map[string][]byte
 
 	// Secret scrubbing (lazy initialization)
-	provider streamscrub.SecretProvider
+	provider       streamscrub.SecretProvider
+	patternVersion atomic.Uint64 // Bumped whenever getPatterns() would change
 }
 
-// Expression represents a secret-producing expression.
-// In our security model: ALL expressions are secrets.
+// Expression represents a value-producing expression.
+// Every expression is access-controlled; Sensitivity decides how it is displayed.
 type Expression struct {
 	Raw                string // Original source: "@var.X", "@env.HOME", etc.
-	Value              any    // Resolved value (preserves original type: string, int, bool, map, slice)
+	Value              any    // Resolved value (preserves original type: int, bool, map, slice); nil for secret string and []byte values, see secret
 	DisplayID          string // Placeholder ID for plan (e.g., "sigil:3J98t56A")
 	Resolved           bool   // True if expression has been resolved (even if Value is nil)
 	TransportSensitive bool   // True if value cannot cross transport boundaries
 	DeclaredTransport  string // Transport context where expression was declared (for boundary checks)
+
+	Sensitivity decorator.Sensitivity // Display level (zero value: secret)
+	classified  bool                  // True once Classify has set Sensitivity
+
+	Origin   string                   // Where the value came from, for audits (e.g., "@env.API_KEY"); never the value
+	Metadata decorator.SecretMetadata // Version and lifetime reported by the origin, if any
+
+	secret      *securemem.Buffer // Plaintext of secret string and []byte values, wiped by Close
+	secretBytes bool              // True if secret holds a []byte value (else string)
+}
+
+// hasValue reports whether a value has been stored.
+func (e *Expression) hasValue() bool {
+	return e.secret != nil || e.Value != nil
+}
+
+// value returns the stored value, copying string and []byte values out of
+// their locked buffer.
+func (e *Expression) value() any {
+	switch {
+	case e.secret == nil:
+		return e.Value
+	case e.secretBytes:
+		return e.secret.Bytes()
+	default:
+		return e.secret.String()
+	}
+}
+
+// setValue stores value. Strings and byte slices of secret expressions go
+// into locked memory; sensitive and public values are not worth a locked
+// mapping each and stay in Value.
+func (e *Expression) setValue(value any) {
+	if e.Sensitivity == decorator.SensitivitySecret {
+		switch val := value.(type) {
+		case string:
+			e.secret = securemem.NewString(val)
+			return
+		case []byte:
+			e.secret, e.secretBytes = securemem.New(val), true
+			return
+		}
+	}
+	e.Value = value
+}
+
+// setSensitivity changes the display level, moving a stored value into or
+// out of locked memory to match.
+func (e *Expression) setSensitivity(level decorator.Sensitivity) {
+	if level == e.Sensitivity {
+		return
+	}
+	e.Sensitivity = level
+	if !e.hasValue() {
+		return
+	}
+	value := e.value()
+	e.wipe()
+	e.Value, e.secret, e.secretBytes = nil, nil, false
+	e.setValue(value)
+}
+
+// wipe destroys the expression's locked value.
+func (e *Expression) wipe() {
+	if e.secret != nil {
+		e.secret.Destroy()
+	}
 }
 
-// Note: No ExprType, no IsSecret - everything is a secret.
 // Vault stores raw values directly - access control via SiteID + transport checks.
 
 // VaultScope represents a scope in the variable trie.
@@ -171,11 +252,36 @@ func newVault() *Vault {
 // NewWithPlanKey creates a new Vault with a specific plan key for HMAC-based SiteIDs.
 func NewWithPlanKey(planKey []byte) *Vault {
 	v := newVault()
-	v.planKey = planKey
+	v.planKey = append([]byte(nil), planKey...) // Own the copy so Close can wipe it
 	v.masterKey = deriveMasterKey(planKey)
 	return v
 }
 
+// Close wipes the vault's keys, stored secrets, locked values and the
+// scrubbing matcher's copies of them. Values already handed out are copies
+// the vault cannot wipe. The vault must not be
+// used after Close; calling Close again is a no-op.
+func (v *Vault) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	securemem.Wipe(v.planKey)
+	securemem.Wipe(v.masterKey)
+	v.planKey, v.masterKey = nil, nil
+
+	for id, encrypted := range v.secretStore {
+		securemem.Wipe(encrypted)
+		delete(v.secretStore, id)
+	}
+	for _, expr := range v.expressions {
+		expr.wipe()
+	}
+	if w, ok := v.provider.(streamscrub.Wiper); ok {
+		w.Wipe()
+	}
+	v.patternVersion.Add(1)
+}
+
 // SecretHandle identifies encrypted bytes stored in the vault.
 type SecretHandle struct {
 	ID string `json:"id"`
@@ -683,8 +789,12 @@ func (v *Vault) computeDisplayID(value any) string {
 // PruneUnused removes expressions that have no site references.
 // This eliminates variables that were declared but never used.
 func (v *Vault) pruneUnused() {
-	for id := range v.expressions {
+	for id, expr := range v.expressions {
 		if len(v.references[id]) == 0 {
+			if expr.Resolved {
+				v.patternVersion.Add(1)
+			}
+			expr.wipe()
 			delete(v.expressions, id)
 			delete(v.references, id)
 			delete(v.touched, id)
@@ -697,8 +807,8 @@ func (v *Vault) pruneUnused() {
 // 1. Have been resolved (Resolved flag is true) - unresolved are skipped
 // 2. Have at least one site reference - unreferenced are skipped
 // 3. Are marked as touched - untouched are skipped
+// 4. Are masked (not public) - public values appear verbatim in the plan
 //
-// In our security model: ALL value decorators are secrets.
 // Note: Empty string values are valid secrets (e.g., empty env vars).
 //
 // Returns a deterministically sorted slice (by DisplayID, then Site) to ensure
@@ -726,6 +836,11 @@ func (v *Vault) buildSecretUses() []SecretUse {
 			continue
 		}
 
+		// Public values are rendered inline, so there is no placeholder to authorize
+		if !expr.Sensitivity.Masked() {
+			continue
+		}
+
 		// Build SecretUse for each reference site
 		for _, ref := range refs {
 			uses = append(uses, SecretUse{
@@ -778,8 +893,12 @@ func (v *Vault) pruneUntouched() {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	for id := range v.expressions {
+	for id, expr := range v.expressions {
 		if !v.touched[id] {
+			if expr.Resolved {
+				v.patternVersion.Add(1)
+			}
+			expr.wipe()
 			delete(v.expressions, id)
 			delete(v.references, id)
 			delete(v.touched, id)
@@ -824,10 +943,10 @@ func (v *Vault) GetUnresolvedValue(exprID string) (any, bool) {
 	defer v.mu.RUnlock()
 
 	expr, exists := v.expressions[exprID]
-	if !exists || expr.Value == nil {
+	if !exists || !expr.hasValue() {
 		return nil, false
 	}
-	return expr.Value, true
+	return expr.value(), true
 }
 
 // ResolveAllTouched marks all touched expressions as resolved and generates DisplayIDs.
@@ -846,14 +965,15 @@ func (v *Vault) ResolveAllTouched() {
 			continue
 		}
 
-		invariant.Invariant(expr.Value != nil,
+		invariant.Invariant(expr.hasValue(),
 			"ResolveAllTouched: expression %q is touched but has no value stored", exprID)
 
 		// Mark as resolved (transport was already captured in DeclaredTransport at declaration time)
 		expr.Resolved = true
+		v.patternVersion.Add(1)
 
 		// Generate DisplayID from value using HMAC for unlinkability
-		hash := v.computeDisplayID(expr.Value)
+		hash := v.computeDisplayID(expr.value())
 		expr.DisplayID = fmt.Sprintf("sigil:%s", hash)
 
 		// Build reverse index for execution (DisplayID → exprID lookup)
@@ -871,11 +991,98 @@ func (v *Vault) StoreUnresolvedValue(exprID string, value any) {
 	expr, exists := v.expressions[exprID]
 	invariant.Precondition(exists, "StoreUnresolvedValue: expression %q not found", exprID)
 
-	if expr.Value != nil {
+	if expr.hasValue() {
 		return
 	}
 
-	expr.Value = value
+	expr.setValue(value)
+}
+
+// Classify sets the sensitivity of an expression. The first call decides
+// the level; later calls can only make it stricter, because deduplicated
+// expressions may be reached from sources classified differently.
+func (v *Vault) Classify(exprID string, level decorator.Sensitivity) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expr, exists := v.expressions[exprID]
+	invariant.Precondition(exists, "Classify: expression %q not found", exprID)
+
+	if expr.classified {
+		level = expr.Sensitivity.Stricter(level)
+	}
+	if expr.Resolved && level != expr.Sensitivity {
+		v.patternVersion.Add(1)
+	}
+	expr.setSensitivity(level)
+	expr.classified = true
+}
+
+// Sensitivity returns the sensitivity of an expression.
+// Unknown expressions are reported as secret.
+func (v *Vault) Sensitivity(exprID string) decorator.Sensitivity {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	expr, exists := v.expressions[exprID]
+	if !exists {
+		return decorator.SensitivitySecret
+	}
+	return expr.Sensitivity
+}
+
+// SetOrigin records where an expression's value came from, e.g. the
+// ResolveResult.Origin of the decorator that produced it. The first origin
+// set is kept, like the value of a deduplicated expression.
+func (v *Vault) SetOrigin(exprID, origin string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expr, exists := v.expressions[exprID]
+	invariant.Precondition(exists, "SetOrigin: expression %q not found", exprID)
+
+	if expr.Origin == "" {
+		expr.Origin = origin
+	}
+}
+
+// Origin returns where an expression's value came from, or "" if unknown.
+func (v *Vault) Origin(exprID string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	expr, exists := v.expressions[exprID]
+	if !exists {
+		return ""
+	}
+	return expr.Origin
+}
+
+// SetMetadata records the version and lifetime the origin reported for an
+// expression's value. Like the origin, the first metadata set is kept.
+func (v *Vault) SetMetadata(exprID string, metadata decorator.SecretMetadata) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expr, exists := v.expressions[exprID]
+	invariant.Precondition(exists, "SetMetadata: expression %q not found", exprID)
+
+	if expr.Metadata.IsZero() {
+		expr.Metadata = metadata
+	}
+}
+
+// Metadata returns the version and lifetime of an expression's value, or
+// the zero value if unknown.
+func (v *Vault) Metadata(exprID string) decorator.SecretMetadata {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	expr, exists := v.expressions[exprID]
+	if !exists {
+		return decorator.SecretMetadata{}
+	}
+	return expr.Metadata
 }
 
 // GetDisplayID returns the placeholder ID for an expression.
@@ -954,7 +1161,7 @@ func (v *Vault) ResolveDisplayIDWithTransport(displayID, currentTransportID stri
 		)
 	}
 
-	return expr.Value, nil
+	return expr.value(), nil
 }
 
 // checkTransportBoundary checks if expression can be used in current transport.
@@ -1045,15 +1252,22 @@ func (v *Vault) access(exprID, paramName string) (any, error) {
 	}
 
 	// 5. Return value (preserves original type)
-	return expr.Value, nil
+	return expr.value(), nil
 }
 
 // ============================================================================
 // SecretProvider Implementation (for streamscrub integration)
 // ============================================================================
 
-// getPatterns returns all resolved expressions as scrubbing patterns.
-// This is called by the pattern provider on each HandleChunk invocation.
+// MinSecretPatternLength is the shortest secret value scrubbed from output.
+// Shorter values ("1", "on", "dev") would replace every occurrence of common
+// text and make logs unreadable while protecting almost nothing.
+const MinSecretPatternLength = 4
+
+// getPatterns returns resolved secret expressions as scrubbing patterns.
+// Sensitive and public values and secrets shorter than MinSecretPatternLength
+// are left in output.
+// The pattern provider calls it only when patternVersion has changed.
 // Converts values to strings for pattern matching (scrubbing only needs string representation).
 func (v *Vault) getPatterns() []streamscrub.Pattern {
 	v.mu.RLock()
@@ -1062,13 +1276,13 @@ func (v *Vault) getPatterns() []streamscrub.Pattern {
 	var patterns []streamscrub.Pattern
 
 	for _, expr := range v.expressions {
-		if !expr.Resolved {
+		if !expr.Resolved || !expr.Sensitivity.Scrubbed() {
 			continue
 		}
 
 		// Must match computeDisplayID() representation for scrubbing to work
 		var valueBytes []byte
-		switch v := expr.Value.(type) {
+		switch v := expr.value().(type) {
 		case string:
 			valueBytes = []byte(v)
 		case []byte:
@@ -1083,7 +1297,7 @@ func (v *Vault) getPatterns() []streamscrub.Pattern {
 			valueBytes = []byte(valueStr)
 		}
 
-		if len(valueBytes) == 0 {
+		if len(valueBytes) < MinSecretPatternLength {
 			continue
 		}
 
@@ -1097,12 +1311,13 @@ func (v *Vault) getPatterns() []streamscrub.Pattern {
 }
 
 // SecretProvider returns a streamscrub.SecretProvider for this vault.
-// The provider replaces all resolved expression values with their DisplayIDs.
+// The provider replaces resolved secret values with their DisplayIDs.
 //
 // This enables automatic secret scrubbing in output streams without manual
 // registration. The scrubber calls the provider to process each chunk.
 //
-// The provider is lazily initialized on first call and reused.
+// The provider is lazily initialized on first call and reused. Its matcher
+// is rebuilt only after expressions are resolved, reclassified or pruned.
 // Thread-safe: Safe for concurrent calls.
 func (v *Vault) SecretProvider() streamscrub.SecretProvider {
 	// Fast path: check with read lock first
@@ -1119,7 +1334,8 @@ func (v *Vault) SecretProvider() streamscrub.SecretProvider {
 
 	// Double-check after acquiring write lock (another goroutine might have initialized)
 	if v.provider == nil {
-		v.provider = streamscrub.NewPatternProviderWithVariants(v.getPatterns)
+		v.provider = streamscrub.NewPatternProviderWithVariants(v.getPatterns,
+			streamscrub.WithPatternVersion(v.patternVersion.Load))
 	}
 
 	return v.provider
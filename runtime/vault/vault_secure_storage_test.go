@@ -3,6 +3,7 @@ package vault
 import (
 	"testing"
 
+	"github.com/builtwithtofu/sigil/core/decorator"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,3 +47,90 @@ func TestVault_GracefulDegradation_WithoutKey(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, plaintext, decrypted)
 }
+
+func TestVault_StoresStringsInLockedBuffers(t *testing.T) {
+	v := NewWithPlanKey([]byte("plan-key-for-secure-storage"))
+	strID := v.TrackExpression("@env.TOKEN")
+	bytesID := v.TrackExpression("@file.read")
+	intID := v.TrackExpression("@var.COUNT")
+	v.StoreUnresolvedValue(strID, "tok-secret")
+	v.StoreUnresolvedValue(bytesID, []byte("bytes-secret"))
+	v.StoreUnresolvedValue(intID, 3)
+
+	assert.Nil(t, v.expressions[strID].Value, "string plaintext should not be held in Value")
+	assert.Nil(t, v.expressions[bytesID].Value, "[]byte plaintext should not be held in Value")
+	require.NotNil(t, v.expressions[strID].secret)
+
+	got, ok := v.GetUnresolvedValue(strID)
+	require.True(t, ok)
+	assert.Equal(t, "tok-secret", got)
+	got, ok = v.GetUnresolvedValue(bytesID)
+	require.True(t, ok)
+	assert.Equal(t, []byte("bytes-secret"), got)
+	got, ok = v.GetUnresolvedValue(intID)
+	require.True(t, ok)
+	assert.Equal(t, 3, got)
+}
+
+func TestVault_CloseWipesSecrets(t *testing.T) {
+	planKey := []byte("plan-key-for-secure-storage")
+	v := NewWithPlanKey(planKey)
+
+	exprID := v.TrackExpression("@env.TOKEN")
+	v.StoreUnresolvedValue(exprID, "tok-secret")
+	v.MarkTouched(exprID)
+	v.ResolveAllTouched()
+	buf := v.expressions[exprID].secret
+
+	encrypted, err := v.Encrypt([]byte("stored-secret"))
+	require.NoError(t, err)
+	_, err = v.Store(encrypted)
+	require.NoError(t, err)
+	var stored []byte
+	for _, b := range v.secretStore {
+		stored = b
+	}
+	masterKey, ownedPlanKey := v.masterKey, v.planKey
+
+	v.Close()
+
+	assert.True(t, buf.Destroyed(), "expression buffer should be destroyed")
+	assert.Equal(t, make([]byte, len(stored)), stored, "stored secret should be zeroed")
+	assert.Equal(t, make([]byte, len(masterKey)), masterKey, "master key should be zeroed")
+	assert.Equal(t, make([]byte, len(ownedPlanKey)), ownedPlanKey, "plan key copy should be zeroed")
+	assert.Equal(t, []byte("plan-key-for-secure-storage"), planKey, "caller's plan key should be untouched")
+	assert.Empty(t, v.secretStore)
+	assert.Empty(t, v.getPatterns(), "wiped secrets should no longer be scrubbed")
+
+	v.Close() // Idempotent
+}
+
+func TestVault_PruneWipesSecrets(t *testing.T) {
+	v := NewWithPlanKey([]byte("plan-key-for-secure-storage"))
+	exprID := v.TrackExpression("@env.UNUSED")
+	v.StoreUnresolvedValue(exprID, "unused-secret")
+	buf := v.expressions[exprID].secret
+
+	v.pruneUntouched()
+
+	assert.True(t, buf.Destroyed(), "pruned expression buffer should be destroyed")
+}
+
+func TestVault_LocksOnlySecretValues(t *testing.T) {
+	v := NewWithPlanKey([]byte("plan-key-for-secure-storage"))
+	defer v.Close()
+
+	publicID := v.TrackExpression("@os.HOME")
+	v.Classify(publicID, decorator.SensitivityPublic)
+	v.StoreUnresolvedValue(publicID, "/home/user")
+	assert.Nil(t, v.expressions[publicID].secret, "public values should not take a locked buffer")
+	assert.Equal(t, "/home/user", v.expressions[publicID].Value)
+
+	// Reclassifying a stored value moves it into locked memory
+	v.Classify(publicID, decorator.SensitivitySecret)
+	require.NotNil(t, v.expressions[publicID].secret)
+	assert.Nil(t, v.expressions[publicID].Value)
+	got, ok := v.GetUnresolvedValue(publicID)
+	require.True(t, ok)
+	assert.Equal(t, "/home/user", got)
+}